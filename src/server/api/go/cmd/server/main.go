@@ -27,6 +27,9 @@ import (
 	"github.com/memodb-io/Acontext/internal/infra/cache"
 	dbpkg "github.com/memodb-io/Acontext/internal/infra/db"
 	"github.com/memodb-io/Acontext/internal/modules/handler"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/converter"
+	"github.com/memodb-io/Acontext/internal/pkg/imagefetch"
 	"github.com/memodb-io/Acontext/internal/pkg/tokenizer"
 	"github.com/memodb-io/Acontext/internal/router"
 	"github.com/memodb-io/Acontext/internal/telemetry"
@@ -45,6 +48,9 @@ func main() {
 	db := do.MustInvoke[*gorm.DB](inj)
 	rdb := do.MustInvoke[*redis.Client](inj)
 
+	// Apply the image fetch policy to URL images downloaded during message conversion
+	converter.SetImageFetcher(do.MustInvoke[*imagefetch.Fetcher](inj))
+
 	// Initialize tokenizer (vocabulary is already embedded in the package)
 	if err := tokenizer.Init(log); err != nil {
 		log.Sugar().Fatalw("failed to initialize tokenizer", "err", err)
@@ -104,6 +110,11 @@ func main() {
 		SandboxHandler:     sandboxHandler,
 	})
 
+	// Discard direct uploads that were abandoned before completion
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go sweepUploads(sweepCtx, do.MustInvoke[service.ArtifactService](inj), log)
+
 	addr := fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port)
 	srv := &http.Server{
 		Addr:              addr,
@@ -134,3 +145,26 @@ func main() {
 	}
 	log.Sugar().Info("server exited")
 }
+
+// uploadSweepInterval is how often expired direct uploads are discarded
+const uploadSweepInterval = 10 * time.Minute
+
+// sweepUploads discards expired direct uploads until ctx is done. Every replica
+// sweeps; discarding an upload twice is harmless.
+func sweepUploads(ctx context.Context, svc service.ArtifactService, log *zap.Logger) {
+	ticker := time.NewTicker(uploadSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := svc.SweepExpiredUploads(ctx)
+			if err != nil {
+				log.Sugar().Warnw("sweep expired uploads", "err", err)
+			} else if n > 0 {
+				log.Sugar().Infow("discarded expired uploads", "count", n)
+			}
+		}
+	}
+}
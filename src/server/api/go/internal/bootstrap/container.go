@@ -3,6 +3,8 @@ package bootstrap
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -17,6 +19,8 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/embedding"
+	"github.com/memodb-io/Acontext/internal/pkg/imagefetch"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 	"github.com/samber/do"
@@ -53,6 +57,7 @@ func BuildContainer() *do.Injector {
 		//   ALTER TABLE agent_skills DROP COLUMN IF EXISTS asset_meta;
 		//   ALTER TABLE agent_skills DROP COLUMN IF EXISTS file_index;
 		if cfg.Database.AutoMigrate {
+			backfillDiskAssets := !d.Migrator().HasTable(&model.DiskAsset{})
 			_ = d.AutoMigrate(
 				&model.Project{},
 				&model.User{},
@@ -61,11 +66,33 @@ func BuildContainer() *do.Injector {
 				&model.Message{},
 				&model.Disk{},
 				&model.Artifact{},
+				&model.ArtifactVersion{},
+				&model.ArtifactDirectory{},
+				&model.ArtifactUpload{},
+				&model.DiskAsset{},
+				&model.DiskSnapshot{},
+				&model.DiskSnapshotArtifact{},
+				&model.DiskChange{},
+				&model.ArtifactShare{},
 				&model.AssetReference{},
 				&model.Metric{},
 				&model.AgentSkills{},
 				&model.SandboxLog{},
 			)
+			// Disk usage of existing artifacts is counted once, when the table is created
+			if backfillDiskAssets {
+				if err := repo.BackfillDiskAssets(context.Background(), d); err != nil {
+					log.Warn("failed to backfill disk assets, disk usage is undercounted", zap.Error(err))
+				}
+			}
+			// Chunk embeddings need pgvector, which only semantic search requires
+			if cfg.Embedding.Provider != "" {
+				if err := d.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+					log.Warn("pgvector is unavailable, semantic search will fail", zap.Error(err))
+				} else {
+					_ = d.AutoMigrate(&model.ArtifactChunk{})
+				}
+			}
 		}
 
 		// ensure default project exists
@@ -140,6 +167,55 @@ func BuildContainer() *do.Injector {
 		}, nil
 	})
 
+	// Image fetcher for URL images (converters and prefetch on store)
+	do.Provide(inj, func(i *do.Injector) (*imagefetch.Fetcher, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		policy := imagefetch.Policy{
+			AllowHosts:      cfg.ImageFetch.AllowHosts,
+			DenyHosts:       cfg.ImageFetch.DenyHosts,
+			MaxBytes:        cfg.ImageFetch.MaxBytes,
+			Timeout:         time.Duration(cfg.ImageFetch.TimeoutSec) * time.Second,
+			BlockPrivateIPs: cfg.ImageFetch.BlockPrivateIPs,
+		}
+		// Presigned asset URLs point at the object storage, which may live on a private network
+		for _, endpoint := range []string{cfg.S3.Endpoint, cfg.S3.InternalEndpoint} {
+			if u, err := url.Parse(endpoint); err == nil && u.Hostname() != "" {
+				policy.TrustedHosts = append(policy.TrustedHosts, u.Hostname())
+			}
+		}
+
+		var imageCache imagefetch.Cache
+		if cfg.ImageFetch.CacheTTLSec > 0 {
+			imageCache = imagefetch.NewRedisCache(
+				do.MustInvoke[*redis.Client](i),
+				time.Duration(cfg.ImageFetch.CacheTTLSec)*time.Second,
+				cfg.ImageFetch.MaxBytes,
+			)
+		}
+		return imagefetch.New(policy, imageCache), nil
+	})
+
+	// Embedder for semantic search over artifacts, nil when disabled
+	do.Provide(inj, func(i *do.Injector) (embedding.Embedder, error) {
+		cfg := do.MustInvoke[*config.Config](i)
+		switch cfg.Embedding.Provider {
+		case "":
+			return nil, nil
+		case "hashing":
+			return embedding.NewHashing(cfg.Embedding.Dimensions), nil
+		case "openai":
+			return embedding.NewOpenAI(embedding.OpenAIConfig{
+				BaseURL:    cfg.Embedding.BaseURL,
+				APIKey:     cfg.Embedding.APIKey,
+				Model:      cfg.Embedding.Model,
+				Dimensions: cfg.Embedding.Dimensions,
+				Timeout:    time.Duration(cfg.Embedding.TimeoutSec) * time.Second,
+			}), nil
+		default:
+			return nil, fmt.Errorf("unknown embedding provider %q", cfg.Embedding.Provider)
+		}
+	})
+
 	// Core HTTP Client
 	do.Provide(inj, func(i *do.Injector) (*httpclient.CoreClient, error) {
 		cfg := do.MustInvoke[*config.Config](i)
@@ -162,18 +238,33 @@ func BuildContainer() *do.Injector {
 			do.MustInvoke[*zap.Logger](i),
 		), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (repo.DiskChangeNotifier, error) {
+		return service.NewDiskChangePublisher(
+			do.MustInvoke[*mq.Publisher](i),
+			do.MustInvoke[*config.Config](i),
+			do.MustInvoke[*zap.Logger](i),
+		), nil
+	})
 	do.Provide(inj, func(i *do.Injector) (repo.DiskRepo, error) {
 		return repo.NewDiskRepo(
 			do.MustInvoke[*gorm.DB](i),
 			do.MustInvoke[repo.AssetReferenceRepo](i),
+			do.MustInvoke[repo.DiskChangeNotifier](i),
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (repo.ArtifactRepo, error) {
 		return repo.NewArtifactRepo(
 			do.MustInvoke[*gorm.DB](i),
 			do.MustInvoke[repo.AssetReferenceRepo](i),
+			do.MustInvoke[repo.DiskChangeNotifier](i),
 		), nil
 	})
+	do.Provide(inj, func(i *do.Injector) (repo.UsageRepo, error) {
+		return repo.NewUsageRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
+	do.Provide(inj, func(i *do.Injector) (repo.ArtifactChunkRepo, error) {
+		return repo.NewArtifactChunkRepo(do.MustInvoke[*gorm.DB](i)), nil
+	})
 	do.Provide(inj, func(i *do.Injector) (repo.TaskRepo, error) {
 		return repo.NewTaskRepo(do.MustInvoke[*gorm.DB](i)), nil
 	})
@@ -194,20 +285,31 @@ func BuildContainer() *do.Injector {
 		return service.NewSessionService(
 			do.MustInvoke[repo.SessionRepo](i),
 			do.MustInvoke[repo.AssetReferenceRepo](i),
+			do.MustInvoke[repo.UsageRepo](i),
 			do.MustInvoke[*zap.Logger](i),
 			do.MustInvoke[*blob.S3Deps](i),
 			do.MustInvoke[*mq.Publisher](i),
 			do.MustInvoke[*config.Config](i),
 			do.MustInvoke[*redis.Client](i),
+			do.MustInvoke[*imagefetch.Fetcher](i),
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.DiskService, error) {
-		return service.NewDiskService(do.MustInvoke[repo.DiskRepo](i)), nil
+		return service.NewDiskService(
+			do.MustInvoke[repo.DiskRepo](i),
+			do.MustInvoke[repo.UsageRepo](i),
+			do.MustInvoke[*config.Config](i),
+		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.ArtifactService, error) {
 		return service.NewArtifactService(
 			do.MustInvoke[repo.ArtifactRepo](i),
+			do.MustInvoke[repo.ArtifactChunkRepo](i),
+			do.MustInvoke[repo.UsageRepo](i),
 			do.MustInvoke[*blob.S3Deps](i),
+			do.MustInvoke[*config.Config](i),
+			do.MustInvoke[*zap.Logger](i),
+			do.MustInvoke[embedding.Embedder](i),
 		), nil
 	})
 	do.Provide(inj, func(i *do.Injector) (service.TaskService, error) {
@@ -0,0 +1,309 @@
+// Package blobtest provides an in-memory S3 server for tests of code using blob.S3Deps.
+package blobtest
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+)
+
+// Bucket is the bucket of the S3Deps returned by New
+const Bucket = "test-bucket"
+
+// Object is a stored object
+type Object struct {
+	Data        []byte
+	ContentType string
+	Metadata    map[string]string
+	ETag        string
+}
+
+// Server is an in-memory S3 serving the object requests of blob.S3Deps: put, copy,
+// get (with ranges), head, delete and list, without multipart uploads.
+type Server struct {
+	URL string
+
+	mu      sync.Mutex
+	objects map[string]*Object
+}
+
+// New starts a server, closed at the end of the test, and returns S3Deps using it.
+func New(t testing.TB) (*blob.S3Deps, *Server) {
+	t.Helper()
+	srv := &Server{objects: map[string]*Object{}}
+	hs := httptest.NewServer(http.HandlerFunc(srv.serve))
+	t.Cleanup(hs.Close)
+	srv.URL = hs.URL
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(hs.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("test", "test", ""),
+	})
+	return &blob.S3Deps{
+		Client:    client,
+		Uploader:  manager.NewUploader(client),
+		Presigner: s3.NewPresignClient(client),
+		Bucket:    Bucket,
+	}, srv
+}
+
+// Object returns the object at key, or nil.
+func (s *Server) Object(key string) *Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[key]
+}
+
+// Keys returns the keys of the stored objects, sorted.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.objects))
+	for k := range s.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/"+Bucket)
+	key := strings.TrimPrefix(path, "/")
+
+	if key == "" {
+		switch {
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			s.list(w, r)
+		default:
+			s.error(w, http.StatusNotImplemented, "NotImplemented")
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if src := r.Header.Get("X-Amz-Copy-Source"); src != "" {
+			s.copy(w, r, key, src)
+			return
+		}
+		s.put(w, r, key)
+	case http.MethodGet, http.MethodHead:
+		s.get(w, r, key)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, key string) {
+	data, err := readBody(r)
+	if err != nil {
+		s.error(w, http.StatusBadRequest, "InvalidRequest")
+		return
+	}
+	metadata := map[string]string{}
+	for name, values := range r.Header {
+		if m, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok {
+			metadata[m] = values[0]
+		}
+	}
+	sum := md5.Sum(data)
+	obj := &Object{Data: data, ContentType: r.Header.Get("Content-Type"), Metadata: metadata, ETag: hex.EncodeToString(sum[:])}
+
+	s.mu.Lock()
+	if r.Header.Get("If-None-Match") == "*" && s.objects[key] != nil {
+		s.mu.Unlock()
+		s.error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+	s.objects[key] = obj
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", `"`+obj.ETag+`"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) copy(w http.ResponseWriter, r *http.Request, key string, src string) {
+	src = strings.TrimPrefix(strings.TrimPrefix(src, "/"), Bucket+"/")
+	s.mu.Lock()
+	from := s.objects[src]
+	if from == nil {
+		s.mu.Unlock()
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	obj := *from
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		obj.ContentType = r.Header.Get("Content-Type")
+		obj.Metadata = map[string]string{}
+		for name, values := range r.Header {
+			if m, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok {
+				obj.Metadata[m] = values[0]
+			}
+		}
+	}
+	s.objects[key] = &obj
+	s.mu.Unlock()
+
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"CopyObjectResult"`
+		ETag    string   `xml:"ETag"`
+	}{ETag: `"` + obj.ETag + `"`})
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	obj := s.Object(key)
+	if obj == nil {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && strings.Trim(match, `"`) != obj.ETag {
+		s.error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+		return
+	}
+
+	data, status := obj.Data, http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" {
+		start, end, ok := parseRange(rng, int64(len(data)))
+		if !ok {
+			s.error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data, status = data[start:end+1], http.StatusPartialContent
+	}
+
+	w.Header().Set("ETag", `"`+obj.ETag+`"`)
+	w.Header().Set("Content-Type", obj.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	for k, v := range obj.Metadata {
+		w.Header().Set("X-Amz-Meta-"+k, v)
+	}
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(data)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	prefix, after := q.Get("prefix"), q.Get("start-after")
+
+	type content struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+		ETag string `xml:"ETag"`
+	}
+	result := struct {
+		XMLName     xml.Name  `xml:"ListBucketResult"`
+		Name        string    `xml:"Name"`
+		Prefix      string    `xml:"Prefix"`
+		KeyCount    int       `xml:"KeyCount"`
+		IsTruncated bool      `xml:"IsTruncated"`
+		Contents    []content `xml:"Contents"`
+	}{Name: Bucket, Prefix: prefix}
+	for _, key := range s.Keys() {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		obj := s.Object(key)
+		result.Contents = append(result.Contents, content{Key: key, Size: len(obj.Data), ETag: `"` + obj.ETag + `"`})
+	}
+	result.KeyCount = len(result.Contents)
+	writeXML(w, result)
+}
+
+func (s *Server) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: code, Message: code})
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// readBody reads a request body, decoding the aws-chunked encoding the SDK streams with.
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") && r.Header.Get("X-Amz-Decoded-Content-Length") == "" {
+		return io.ReadAll(r.Body)
+	}
+	var out bytes.Buffer
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		if _, err := io.CopyN(&out, br, size); err != nil {
+			return nil, err
+		}
+		if _, err := br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseRange parses "bytes=start-end" or "bytes=start-" into inclusive offsets.
+func parseRange(rng string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(rng, "bytes=")
+	if !ok {
+		return 0, 0, false
+	}
+	from, to, _ := strings.Cut(spec, "-")
+	start, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if to != "" {
+		if end, err = strconv.ParseInt(to, 10, 64); err != nil {
+			return 0, 0, false
+		}
+		end = min(end, size-1)
+	}
+	return start, end, start <= end
+}
@@ -0,0 +1,467 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/envelope"
+)
+
+// KeySourceMaster wraps data keys with a configured master key, standing in for a KMS key
+const KeySourceMaster = "master"
+
+// encryptedPrefixes hold the context data encrypted client-side: "<prefix>/<project ID>/...".
+// The core service reads message parts and artifacts too, and decrypts them with the same master key.
+var encryptedPrefixes = []string{"assets", "disks", "parts"}
+
+// metaEncryption is the object metadata marking objects encrypted client-side, with the format version
+const (
+	metaEncryption = "acontext-encryption"
+	encryptionV1   = "v1"
+)
+
+var (
+	ErrEncryptedObject    = errors.New("object is encrypted client-side and can't be read through a presigned URL")
+	ErrEncryptionDisabled = errors.New("client-side encryption is not enabled")
+)
+
+// keyring holds the data keys of projects, each stored in S3 wrapped by the key-encryption key of its project.
+type keyring struct {
+	source string
+	master []byte
+
+	mu   sync.Mutex
+	keys map[string][]byte // Unwrapped data keys by project ID
+}
+
+// wrappedDataKey is the stored form of a data key
+type wrappedDataKey struct {
+	Source  string `json:"source"`
+	Wrapped []byte `json:"wrapped"`
+}
+
+// newKeyring returns the keyring configured by cfg, or nil when client-side encryption is disabled.
+func newKeyring(cfg config.S3Cfg) (*keyring, error) {
+	k := &keyring{source: cfg.EncryptionKeySource, keys: map[string][]byte{}}
+	switch cfg.EncryptionKeySource {
+	case "":
+		return nil, nil
+	case KeySourceMaster:
+		master, err := base64.StdEncoding.DecodeString(cfg.EncryptionMasterKey)
+		if err != nil || len(master) != envelope.KeySize {
+			return nil, fmt.Errorf("s3 encryption master key must be %d base64-encoded bytes", envelope.KeySize)
+		}
+		k.master = master
+	default:
+		return nil, fmt.Errorf("unknown s3 encryption key source %q", cfg.EncryptionKeySource)
+	}
+	return k, nil
+}
+
+// SetEncryption configures client-side encryption as cfg does, for S3Deps built without NewS3.
+func (u *S3Deps) SetEncryption(cfg config.S3Cfg) error {
+	keys, err := newKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	u.keys = keys
+	return nil
+}
+
+// kek derives the key-encryption key of a project.
+func (k *keyring) kek(projectID string) ([]byte, error) {
+	return envelope.DeriveKEK(k.master, projectID)
+}
+
+// projectOfKey returns the project owning an object of context data, "<prefix>/<project ID>/...".
+func projectOfKey(key string) (string, bool) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 3 {
+		return "", false
+	}
+	if _, err := uuid.Parse(parts[1]); err != nil {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// encrypts reports whether new content at key is encrypted client-side. It only depends
+// on the key and the configuration, so it holds for objects stored before as well.
+func (u *S3Deps) encrypts(key string) bool {
+	if u.keys == nil {
+		return false
+	}
+	if _, ok := projectOfKey(key); !ok {
+		return false
+	}
+	for _, prefix := range encryptedPrefixes {
+		if strings.HasPrefix(key, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func dataKeyKey(projectID string) string {
+	return "keys/" + projectID + "/data-key.json"
+}
+
+// dataKey returns the data key of a project, creating it on first use.
+func (u *S3Deps) dataKey(ctx context.Context, projectID string) ([]byte, error) {
+	k := u.keys
+	k.mu.Lock()
+	key, ok := k.keys[projectID]
+	k.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	kek, err := k.kek(projectID)
+	if err != nil {
+		return nil, err
+	}
+	key, err = u.loadDataKey(ctx, projectID, kek)
+	if errors.Is(err, errNoDataKey) {
+		key, err = u.createDataKey(ctx, projectID, kek)
+		if errors.Is(err, errDataKeyExists) {
+			// Another writer created it first
+			key, err = u.loadDataKey(ctx, projectID, kek)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.keys[projectID] = key
+	k.mu.Unlock()
+	return key, nil
+}
+
+var (
+	errNoDataKey     = errors.New("no data key")
+	errDataKeyExists = errors.New("data key exists")
+)
+
+func (u *S3Deps) loadDataKey(ctx context.Context, projectID string, kek []byte) ([]byte, error) {
+	out, err := u.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &u.Bucket,
+		Key:    aws.String(dataKeyKey(projectID)),
+	})
+	if err != nil {
+		var noKey *s3types.NoSuchKey
+		if errors.As(err, &noKey) {
+			return nil, errNoDataKey
+		}
+		return nil, fmt.Errorf("get data key: %w", err)
+	}
+	defer out.Body.Close()
+
+	var stored wrappedDataKey
+	raw, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read data key: %w", err)
+	}
+	if err := sonic.Unmarshal(raw, &stored); err != nil {
+		return nil, fmt.Errorf("unmarshal data key: %w", err)
+	}
+	if stored.Source != u.keys.source {
+		return nil, fmt.Errorf("data key of project %s is wrapped by the %s key source, not %s", projectID, stored.Source, u.keys.source)
+	}
+	return envelope.Unwrap(kek, stored.Wrapped, projectID)
+}
+
+// createDataKey stores a new data key, unless the project already has one.
+func (u *S3Deps) createDataKey(ctx context.Context, projectID string, kek []byte) ([]byte, error) {
+	key, err := envelope.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := envelope.Wrap(kek, key, projectID)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := sonic.Marshal(wrappedDataKey{Source: u.keys.source, Wrapped: wrapped})
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      &u.Bucket,
+		Key:         aws.String(dataKeyKey(projectID)),
+		Body:        bytes.NewReader(raw),
+		ContentType: aws.String("application/json"),
+		IfNoneMatch: aws.String("*"),
+	}
+	if u.SSE != nil {
+		input.ServerSideEncryption = *u.SSE
+	}
+	if _, err := u.Client.PutObject(ctx, input); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "PreconditionFailed" || apiErr.ErrorCode() == "ConditionalRequestConflict") {
+			return nil, errDataKeyExists
+		}
+		return nil, fmt.Errorf("put data key: %w", err)
+	}
+	return key, nil
+}
+
+// encryptionKey returns the data key new content at key is encrypted with, or nil
+// when it is stored as is.
+func (u *S3Deps) encryptionKey(ctx context.Context, key string) ([]byte, error) {
+	if !u.encrypts(key) {
+		return nil, nil
+	}
+	projectID, _ := projectOfKey(key)
+	return u.dataKey(ctx, projectID)
+}
+
+// decryptionKey returns the data key of an encrypted object.
+func (u *S3Deps) decryptionKey(ctx context.Context, key string) ([]byte, error) {
+	if u.keys == nil {
+		return nil, fmt.Errorf("object %s is encrypted client-side, but no s3 encryption key source is configured", key)
+	}
+	projectID, ok := projectOfKey(key)
+	if !ok {
+		return nil, fmt.Errorf("object %s is encrypted client-side outside of a project prefix", key)
+	}
+	return u.dataKey(ctx, projectID)
+}
+
+func isEncrypted(metadata map[string]string) bool {
+	return metadata[metaEncryption] == encryptionV1
+}
+
+// encryptedMetadata marks metadata as the metadata of an encrypted object.
+func encryptedMetadata(metadata map[string]string) map[string]string {
+	out := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		out[k] = v
+	}
+	out[metaEncryption] = encryptionV1
+	return out
+}
+
+// objectSize is the plaintext size of an object from its stored size.
+func objectSize(metadata map[string]string, storedSize int64) int64 {
+	if isEncrypted(metadata) {
+		if size, err := envelope.PlaintextSize(storedSize); err == nil {
+			return size
+		}
+	}
+	return storedSize
+}
+
+// decryptBody wraps the body of a whole object read with GetObject, decrypting it if needed.
+func (u *S3Deps) decryptBody(ctx context.Context, key string, out *s3.GetObjectOutput) (io.ReadCloser, error) {
+	if !isEncrypted(out.Metadata) {
+		return out.Body, nil
+	}
+	dataKey, err := u.decryptionKey(ctx, key)
+	if err != nil {
+		out.Body.Close()
+		return nil, err
+	}
+	r, err := envelope.NewDecryptReader(dataKey, out.Body)
+	if err != nil {
+		out.Body.Close()
+		return nil, fmt.Errorf("decrypt object %s: %w", key, err)
+	}
+	return readCloser{Reader: r, Closer: out.Body}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openRange streams length plaintext bytes of an object from offset, or up to its end
+// when length is negative. Encrypted objects are read by the chunks holding the range.
+func (u *S3Deps) openRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if u.keys == nil {
+		return u.getRange(ctx, key, nil, offset, length)
+	}
+
+	head, err := u.Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &u.Bucket, Key: &key})
+	if err != nil {
+		return nil, fmt.Errorf("head object: %w", err)
+	}
+	if !isEncrypted(head.Metadata) {
+		return u.getRange(ctx, key, head.ETag, offset, length)
+	}
+
+	dataKey, err := u.decryptionKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	size, err := envelope.PlaintextSize(aws.ToInt64(head.ContentLength))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt object %s: %w", key, err)
+	}
+	if length < 0 || offset+length > size {
+		length = size - offset
+	}
+	if length <= 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	headerBody, err := u.getRange(ctx, key, head.ETag, 0, int64(envelope.HeaderSize))
+	if err != nil {
+		return nil, err
+	}
+	header, err := io.ReadAll(headerBody)
+	headerBody.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	first, _, start, end, skip := envelope.ChunkRange(size, offset, length)
+	body, err := u.getRange(ctx, key, head.ETag, start, end-start)
+	if err != nil {
+		return nil, err
+	}
+	r, err := envelope.NewRangeDecryptReader(dataKey, header, first, envelope.LastChunk(size), body)
+	if err == nil {
+		_, err = io.CopyN(io.Discard, r, skip)
+	}
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("decrypt object %s: %w", key, err)
+	}
+	return readCloser{Reader: io.LimitReader(r, length), Closer: body}, nil
+}
+
+// getRange GETs stored bytes of an object, failing if it no longer has etag when set.
+func (u *S3Deps) getRange(ctx context.Context, key string, etag *string, offset int64, length int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket:  &u.Bucket,
+		Key:     &key,
+		IfMatch: etag,
+	}
+	switch {
+	case length >= 0:
+		rng := fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+		input.Range = &rng
+	case offset > 0:
+		rng := fmt.Sprintf("bytes=%d-", offset)
+		input.Range = &rng
+	}
+	result, err := u.Client.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("get object from S3: %w", err)
+	}
+	return result.Body, nil
+}
+
+// putEncrypted streams the encryption of plaintext to key.
+func (u *S3Deps) putEncrypted(ctx context.Context, key string, dataKey []byte, plaintext io.Reader, contentType string, metadata map[string]string) (string, error) {
+	body, err := envelope.NewEncryptReader(dataKey, plaintext)
+	if err != nil {
+		return "", err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(u.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+		Metadata:    encryptedMetadata(metadata),
+	}
+	if u.SSE != nil {
+		input.ServerSideEncryption = *u.SSE
+	}
+	out, err := u.Uploader.Upload(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	return cleanETag(aws.ToString(out.ETag)), nil
+}
+
+// EncryptProjectObjects encrypts, in place, the objects of a project stored before client-side
+// encryption was enabled. It goes through up to limit objects after cursor, a key returned
+// by the previous call or "" to start, and returns how many it encrypted and the cursor to
+// continue from, "" once all objects were seen.
+func (u *S3Deps) EncryptProjectObjects(ctx context.Context, projectID string, cursor string, limit int) (int, string, error) {
+	if u.keys == nil {
+		return 0, "", ErrEncryptionDisabled
+	}
+	dataKey, err := u.dataKey(ctx, projectID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	encrypted, seen := 0, 0
+	// Prefixes are walked in key order, so a cursor also skips the prefixes already done
+	prefixes := append([]string(nil), encryptedPrefixes...)
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		listInput := &s3.ListObjectsV2Input{
+			Bucket: &u.Bucket,
+			Prefix: aws.String(prefix + "/" + projectID + "/"),
+		}
+		if cursor != "" {
+			listInput.StartAfter = aws.String(cursor)
+		}
+		var continuationToken *string
+		for {
+			listInput.ContinuationToken = continuationToken
+			result, err := u.Client.ListObjectsV2(ctx, listInput)
+			if err != nil {
+				return encrypted, cursor, fmt.Errorf("list objects from S3: %w", err)
+			}
+
+			for _, obj := range result.Contents {
+				if seen >= limit {
+					return encrypted, cursor, nil
+				}
+				key := aws.ToString(obj.Key)
+				done, err := u.encryptObject(ctx, key, dataKey)
+				if err != nil {
+					return encrypted, cursor, err
+				}
+				if done {
+					encrypted++
+				}
+				seen++
+				cursor = key
+			}
+
+			if !aws.ToBool(result.IsTruncated) {
+				break
+			}
+			continuationToken = result.NextContinuationToken
+		}
+	}
+	return encrypted, "", nil
+}
+
+// encryptObject rewrites an object encrypted, unless it already is. Objects are content
+// addressed, so readers see the same plaintext before and after.
+func (u *S3Deps) encryptObject(ctx context.Context, key string, dataKey []byte) (bool, error) {
+	result, err := u.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: &u.Bucket, Key: &key})
+	if err != nil {
+		return false, fmt.Errorf("get object from S3: %w", err)
+	}
+	defer result.Body.Close()
+	if isEncrypted(result.Metadata) {
+		return false, nil
+	}
+
+	if _, err := u.putEncrypted(ctx, key, dataKey, result.Body, aws.ToString(result.ContentType), result.Metadata); err != nil {
+		return false, fmt.Errorf("encrypt object %s: %w", key, err)
+	}
+	return true, nil
+}
@@ -34,6 +34,7 @@ type S3Deps struct {
 	Presigner *s3.PresignClient
 	Bucket    string
 	SSE       *s3types.ServerSideEncryption
+	keys      *keyring // Client-side encryption of context data, nil when disabled
 }
 
 func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
@@ -109,6 +110,11 @@ func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
 		sse = &v
 	}
 
+	keys, err := newKeyring(cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.S3.Bucket == "" {
 		return nil, errors.New("s3 bucket is empty")
 	}
@@ -124,6 +130,7 @@ func NewS3(ctx context.Context, cfg *config.Config) (*S3Deps, error) {
 		Presigner: presigner,
 		Bucket:    cfg.S3.Bucket,
 		SSE:       sse,
+		keys:      keys,
 	}, nil
 }
 
@@ -146,11 +153,15 @@ func (s *S3Deps) PresignPut(ctx context.Context, key, contentType string, expire
 	return ps.URL, nil
 }
 
-// Generate a pre-signed GET URL
+// Generate a pre-signed GET URL. Keys encrypted client-side have none, ErrEncryptedObject:
+// it would serve ciphertext.
 func (s *S3Deps) PresignGet(ctx context.Context, key string, expire time.Duration) (string, error) {
 	if key == "" {
 		return "", errors.New("key is empty")
 	}
+	if s.encrypts(key) {
+		return "", ErrEncryptedObject
+	}
 	ps, err := s.Presigner.PresignGetObject(ctx, &s3.GetObjectInput{
 		Bucket: &s.Bucket,
 		Key:    &key,
@@ -172,19 +183,8 @@ func cleanETag(etag string) string {
 	return strings.Trim(etag, `"`)
 }
 
-// uploadWithDedup performs content-addressed deduplicated upload.
-// It searches for existing objects under keyPrefix that contain the given sumHex in the key.
-// If found, returns its metadata; otherwise uploads the new content using date + sumHex + ext as key.
-func (u *S3Deps) uploadWithDedup(
-	ctx context.Context,
-	keyPrefix string,
-	sumHex string,
-	contentType string,
-	ext string,
-	size int64,
-	body io.Reader,
-	metadata map[string]string,
-) (*model.Asset, error) {
+// findByHash returns the object under keyPrefix whose key contains sumHex, or nil.
+func (u *S3Deps) findByHash(ctx context.Context, keyPrefix string, sumHex string, contentType string) *model.Asset {
 	// Check for existing object with pagination support
 	listInput := &s3.ListObjectsV2Input{
 		Bucket: &u.Bucket,
@@ -212,8 +212,8 @@ func (u *S3Deps) uploadWithDedup(
 							ETag:   cleanETag(*headResult.ETag),
 							SHA256: sumHex,
 							MIME:   contentType,
-							SizeB:  aws.ToInt64(headResult.ContentLength),
-						}, nil
+							SizeB:  objectSize(headResult.Metadata, aws.ToInt64(headResult.ContentLength)),
+						}
 					}
 				}
 			}
@@ -226,9 +226,53 @@ func (u *S3Deps) uploadWithDedup(
 		continuationToken = result.NextContinuationToken
 	}
 
-	// No existing file found, upload new file with date prefix
+	return nil
+}
+
+// contentKey is the content-addressed key of new objects under keyPrefix
+func contentKey(keyPrefix string, sumHex string, ext string) string {
 	datePrefix := time.Now().UTC().Format("2006/01/02")
-	key := fmt.Sprintf("%s/%s/%s%s", keyPrefix, datePrefix, sumHex, ext)
+	return fmt.Sprintf("%s/%s/%s%s", keyPrefix, datePrefix, sumHex, ext)
+}
+
+// uploadWithDedup performs content-addressed deduplicated upload.
+// It searches for existing objects under keyPrefix that contain the given sumHex in the key.
+// If found, returns its metadata; otherwise uploads the new content using date + sumHex + ext as key.
+func (u *S3Deps) uploadWithDedup(
+	ctx context.Context,
+	keyPrefix string,
+	sumHex string,
+	contentType string,
+	ext string,
+	size int64,
+	body io.Reader,
+	metadata map[string]string,
+) (*model.Asset, error) {
+	if existing := u.findByHash(ctx, keyPrefix, sumHex, contentType); existing != nil {
+		return existing, nil
+	}
+
+	// No existing file found, upload new file with date prefix
+	key := contentKey(keyPrefix, sumHex, ext)
+
+	dataKey, err := u.encryptionKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if dataKey != nil {
+		etag, err := u.putEncrypted(ctx, key, dataKey, body, contentType, metadata)
+		if err != nil {
+			return nil, err
+		}
+		return &model.Asset{
+			Bucket: u.Bucket,
+			S3Key:  key,
+			ETag:   etag,
+			SHA256: sumHex,
+			MIME:   contentType,
+			SizeB:  size,
+		}, nil
+	}
 
 	input := &s3.PutObjectInput{
 		Bucket:      aws.String(u.Bucket),
@@ -365,28 +409,43 @@ func (u *S3Deps) UploadFileDirect(ctx context.Context, key string, content []byt
 	h.Write(content)
 	sumHex := hex.EncodeToString(h.Sum(nil))
 
-	input := &s3.PutObjectInput{
-		Bucket:      aws.String(u.Bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(content),
-		ContentType: aws.String(contentType),
-		Metadata: map[string]string{
-			"sha256": sumHex,
-		},
-	}
-	if u.SSE != nil {
-		input.ServerSideEncryption = *u.SSE
+	metadata := map[string]string{
+		"sha256": sumHex,
 	}
 
-	out, err := u.Uploader.Upload(ctx, input)
+	dataKey, err := u.encryptionKey(ctx, key)
 	if err != nil {
 		return nil, err
 	}
+	var etag string
+	if dataKey != nil {
+		etag, err = u.putEncrypted(ctx, key, dataKey, bytes.NewReader(content), contentType, metadata)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		input := &s3.PutObjectInput{
+			Bucket:      aws.String(u.Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(content),
+			ContentType: aws.String(contentType),
+			Metadata:    metadata,
+		}
+		if u.SSE != nil {
+			input.ServerSideEncryption = *u.SSE
+		}
+
+		out, err := u.Uploader.Upload(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		etag = cleanETag(*out.ETag)
+	}
 
 	return &model.Asset{
 		Bucket: u.Bucket,
 		S3Key:  key,
-		ETag:   cleanETag(*out.ETag),
+		ETag:   etag,
 		SHA256: sumHex,
 		MIME:   contentType,
 		SizeB:  int64(len(content)),
@@ -402,11 +461,15 @@ func (u *S3Deps) DownloadJSON(ctx context.Context, key string, target interface{
 	if err != nil {
 		return fmt.Errorf("get object from S3: %w", err)
 	}
-	defer result.Body.Close()
+	body, err := u.decryptBody(ctx, key, result)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
 
 	// Read the response body
 	var buf bytes.Buffer
-	_, err = buf.ReadFrom(result.Body)
+	_, err = buf.ReadFrom(body)
 	if err != nil {
 		return fmt.Errorf("read response body: %w", err)
 	}
@@ -432,11 +495,15 @@ func (u *S3Deps) DownloadFile(ctx context.Context, key string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("get object from S3: %w", err)
 	}
-	defer result.Body.Close()
+	body, err := u.decryptBody(ctx, key, result)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
 
 	// Read the response body
 	var buf bytes.Buffer
-	_, err = buf.ReadFrom(result.Body)
+	_, err = buf.ReadFrom(body)
 	if err != nil {
 		return nil, fmt.Errorf("read response body: %w", err)
 	}
@@ -444,6 +511,51 @@ func (u *S3Deps) DownloadFile(ctx context.Context, key string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// DownloadRange downloads length bytes of an object starting at offset with a ranged GET
+func (u *S3Deps) DownloadRange(ctx context.Context, key string, offset int64, length int64) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+	if offset < 0 || length < 0 {
+		return nil, errors.New("invalid range")
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	body, err := u.openRange(ctx, key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// OpenObject streams an object from offset to its end. Closing the body early
+// stops the transfer, so readers that only need a prefix don't download the rest.
+func (u *S3Deps) OpenObject(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	if key == "" {
+		return nil, errors.New("key is empty")
+	}
+
+	if offset > 0 {
+		return u.openRange(ctx, key, offset, -1)
+	}
+	result, err := u.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &u.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get object from S3: %w", err)
+	}
+	return u.decryptBody(ctx, key, result)
+}
+
 // DeleteObject deletes an object from S3
 func (u *S3Deps) DeleteObject(ctx context.Context, key string) error {
 	if key == "" {
@@ -554,3 +666,213 @@ func (u *S3Deps) DeleteObjectsByPrefix(ctx context.Context, prefix string) error
 
 	return nil
 }
+
+// MaxCopySize is the largest object a single CopyObject request can copy
+const MaxCopySize = 5 << 30
+
+// UploadedPart is a part of a multipart upload, as reported by the client that uploaded it
+type UploadedPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// CreateMultipartUpload starts a multipart upload to key and returns its upload ID
+func (u *S3Deps) CreateMultipartUpload(ctx context.Context, key string, contentType string) (string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      &u.Bucket,
+		Key:         &key,
+		ContentType: &contentType,
+	}
+	if u.SSE != nil {
+		input.ServerSideEncryption = *u.SSE
+	}
+	out, err := u.Client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload: %w", err)
+	}
+	return aws.ToString(out.UploadId), nil
+}
+
+// PresignUploadPart generates a pre-signed PUT URL for one part of a multipart upload
+func (u *S3Deps) PresignUploadPart(ctx context.Context, key string, uploadID string, partNumber int32, expire time.Duration) (string, error) {
+	ps, err := u.Presigner.PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     &u.Bucket,
+		Key:        &key,
+		UploadId:   &uploadID,
+		PartNumber: aws.Int32(partNumber),
+	}, func(po *s3.PresignOptions) {
+		po.Expires = expire
+	})
+	if err != nil {
+		return "", err
+	}
+	return ps.URL, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the object at key
+func (u *S3Deps) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []UploadedPart) error {
+	completed := make([]s3types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		etag := p.ETag
+		if !strings.HasPrefix(etag, `"`) {
+			etag = `"` + etag + `"`
+		}
+		completed = append(completed, s3types.CompletedPart{PartNumber: aws.Int32(p.PartNumber), ETag: aws.String(etag)})
+	}
+	_, err := u.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &u.Bucket,
+		Key:             &key,
+		UploadId:        &uploadID,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// AbortMultipartUpload discards a multipart upload and the parts uploaded so far
+func (u *S3Deps) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	_, err := u.Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &u.Bucket,
+		Key:      &key,
+		UploadId: &uploadID,
+	})
+	if err != nil {
+		return fmt.Errorf("abort multipart upload: %w", err)
+	}
+	return nil
+}
+
+// StatObject returns the size of an object, without downloading it
+func (u *S3Deps) StatObject(ctx context.Context, key string) (int64, error) {
+	out, err := u.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &u.Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("head object: %w", err)
+	}
+	return aws.ToInt64(out.ContentLength), nil
+}
+
+// PromoteObject moves an object uploaded to a staging key to its content-addressed key
+// under keyPrefix, deduplicating like UploadBytes. sumHex must be the verified SHA256 of the object.
+// created reports whether the object was stored by this call rather than found by its hash.
+func (u *S3Deps) PromoteObject(ctx context.Context, srcKey string, keyPrefix string, sumHex string, contentType string, ext string, size int64) (asset *model.Asset, created bool, err error) {
+	asset = u.findByHash(ctx, keyPrefix, sumHex, contentType)
+	if asset == nil {
+		key := contentKey(keyPrefix, sumHex, ext)
+		etag, err := u.promote(ctx, srcKey, key, contentType, size, map[string]string{"sha256": sumHex})
+		if err != nil {
+			return nil, false, err
+		}
+		asset = &model.Asset{
+			Bucket: u.Bucket,
+			S3Key:  key,
+			ETag:   etag,
+			SHA256: sumHex,
+			MIME:   contentType,
+			SizeB:  size,
+		}
+		created = true
+	}
+
+	if err := u.DeleteObject(ctx, srcKey); err != nil {
+		return nil, false, err
+	}
+	return asset, created, nil
+}
+
+// promote copies srcKey to dstKey, encrypting it on the way when dstKey holds encrypted
+// context data, and returns the new ETag
+func (u *S3Deps) promote(ctx context.Context, srcKey string, dstKey string, contentType string, size int64, metadata map[string]string) (string, error) {
+	dataKey, err := u.encryptionKey(ctx, dstKey)
+	if err != nil {
+		return "", err
+	}
+	if dataKey == nil {
+		return u.copyObject(ctx, srcKey, dstKey, contentType, size, metadata)
+	}
+
+	body, err := u.getRange(ctx, srcKey, nil, 0, -1)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	return u.putEncrypted(ctx, dstKey, dataKey, body, contentType, metadata)
+}
+
+// copyObject copies srcKey to dstKey within the bucket, with a multipart copy
+// for objects larger than a single copy request allows, and returns the new ETag
+func (u *S3Deps) copyObject(ctx context.Context, srcKey string, dstKey string, contentType string, size int64, metadata map[string]string) (string, error) {
+	source := (&url.URL{Path: u.Bucket + "/" + srcKey}).EscapedPath()
+
+	if size <= MaxCopySize {
+		input := &s3.CopyObjectInput{
+			Bucket:            &u.Bucket,
+			Key:               &dstKey,
+			CopySource:        &source,
+			ContentType:       &contentType,
+			Metadata:          metadata,
+			MetadataDirective: s3types.MetadataDirectiveReplace,
+		}
+		if u.SSE != nil {
+			input.ServerSideEncryption = *u.SSE
+		}
+		out, err := u.Client.CopyObject(ctx, input)
+		if err != nil {
+			return "", fmt.Errorf("copy object: %w", err)
+		}
+		return cleanETag(aws.ToString(out.CopyObjectResult.ETag)), nil
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      &u.Bucket,
+		Key:         &dstKey,
+		ContentType: &contentType,
+		Metadata:    metadata,
+	}
+	if u.SSE != nil {
+		input.ServerSideEncryption = *u.SSE
+	}
+	mpu, err := u.Client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("create multipart copy: %w", err)
+	}
+	uploadID := aws.ToString(mpu.UploadId)
+
+	const partSize = 1 << 30
+	var parts []UploadedPart
+	for offset, n := int64(0), int32(1); offset < size; offset, n = offset+partSize, n+1 {
+		end := offset + partSize
+		if end > size {
+			end = size
+		}
+		rng := fmt.Sprintf("bytes=%d-%d", offset, end-1)
+		out, err := u.Client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          &u.Bucket,
+			Key:             &dstKey,
+			UploadId:        &uploadID,
+			PartNumber:      aws.Int32(n),
+			CopySource:      &source,
+			CopySourceRange: &rng,
+		})
+		if err != nil {
+			_ = u.AbortMultipartUpload(ctx, dstKey, uploadID)
+			return "", fmt.Errorf("copy object part %d: %w", n, err)
+		}
+		parts = append(parts, UploadedPart{PartNumber: n, ETag: aws.ToString(out.CopyPartResult.ETag)})
+	}
+	if err := u.CompleteMultipartUpload(ctx, dstKey, uploadID, parts); err != nil {
+		_ = u.AbortMultipartUpload(ctx, dstKey, uploadID)
+		return "", err
+	}
+
+	// A multipart ETag isn't an MD5 of the content, but still identifies this object
+	head, err := u.Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &u.Bucket, Key: &dstKey})
+	if err != nil {
+		return "", fmt.Errorf("head object: %w", err)
+	}
+	return cleanETag(aws.ToString(head.ETag)), nil
+}
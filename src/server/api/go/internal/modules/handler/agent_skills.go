@@ -101,7 +101,7 @@ func (h *AgentSkillsHandler) CreateAgentSkill(c *gin.Context) {
 	if err != nil {
 		// Check if error is a validation error (SKILL.md related)
 		errMsg := err.Error()
-		if strings.Contains(errMsg, "SKILL.md") || strings.Contains(errMsg, "name is required") || strings.Contains(errMsg, "description is required") {
+		if errors.Is(err, service.ErrInvalidArchive) || strings.Contains(errMsg, "SKILL.md") || strings.Contains(errMsg, "name is required") || strings.Contains(errMsg, "description is required") {
 			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
 		} else {
 			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
@@ -236,11 +236,14 @@ func (h *AgentSkillsHandler) ListAgentSkills(c *gin.Context) {
 //	@Tags			agent_skills
 //	@Accept			json
 //	@Produce		json
-//	@Param			id			path	string	true	"Agent skill UUID"
-//	@Param			file_path	query	string	true	"File path within the skill (e.g., 'scripts/extract_text.json')"
-//	@Param			expire		query	int		false	"URL expiration in seconds for presigned URL (default 900)"
+//	@Param			id				path	string	true	"Agent skill UUID"
+//	@Param			file_path		query	string	true	"File path within the skill (e.g., 'scripts/extract_text.json')"
+//	@Param			expire			query	int		false	"URL expiration in seconds for presigned URL (default 900)"
+//	@Param			If-None-Match	header	string	false	"ETags of file content the client already holds"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=service.GetFileOutput}
+//	@Header			200,304	{string}	ETag	"Entity tag of the file content"
+//	@Success		304	"The file content still has an ETag from If-None-Match"
 //	@Router			/agent_skills/{id}/file [get]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get a file from a skill (text files return content, binary files return URL)\nfile_resp = client.skills.get_file(\n    skill_id='skill-uuid-here',\n    file_path='scripts/main.py',\n    expire=1800  # URL expires in 30 minutes\n)\n\nprint(f\"File: {file_resp.path} ({file_resp.mime})\")\nif file_resp.content:\n    print(f\"Content: {file_resp.content.raw}\")\nif file_resp.url:\n    print(f\"Download URL: {file_resp.url}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get a file from a skill (text files return content, binary files return URL)\nconst fileResp = await client.skills.getFile({\n  skillId: 'skill-uuid-here',\n  filePath: 'scripts/main.py',\n  expire: 1800  // URL expires in 30 minutes\n});\n\nconsole.log(`File: ${fileResp.path} (${fileResp.mime})`);\nif (fileResp.content) {\n  console.log(`Content: ${fileResp.content.raw}`);\n}\nif (fileResp.url) {\n  console.log(`Download URL: ${fileResp.url}`);\n}\n","label":"JavaScript"}]
 func (h *AgentSkillsHandler) GetAgentSkillFile(c *gin.Context) {
@@ -269,7 +272,12 @@ func (h *AgentSkillsHandler) GetAgentSkillFile(c *gin.Context) {
 		}
 	}
 
-	output, err := h.svc.GetFile(c.Request.Context(), project.ID, id, filePath, expire)
+	output, err := h.svc.GetFile(c.Request.Context(), project.ID, id, filePath, expire, preconditions(c))
+	if errors.Is(err, service.ErrNotModified) {
+		c.Header("ETag", output.ETag)
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, serializer.DBErr("", err))
@@ -279,6 +287,7 @@ func (h *AgentSkillsHandler) GetAgentSkillFile(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", output.ETag)
 	c.JSON(http.StatusOK, serializer.Response{Data: output})
 }
 
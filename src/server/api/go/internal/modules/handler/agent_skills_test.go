@@ -54,8 +54,8 @@ func (m *MockAgentSkillsService) List(ctx context.Context, in service.ListAgentS
 	return args.Get(0).(*service.ListAgentSkillsOutput), args.Error(1)
 }
 
-func (m *MockAgentSkillsService) GetFile(ctx context.Context, projectID uuid.UUID, skillID uuid.UUID, filePath string, expire time.Duration) (*service.GetFileOutput, error) {
-	args := m.Called(ctx, projectID, skillID, filePath, expire)
+func (m *MockAgentSkillsService) GetFile(ctx context.Context, projectID uuid.UUID, skillID uuid.UUID, filePath string, expire time.Duration, cond service.Preconditions) (*service.GetFileOutput, error) {
+	args := m.Called(ctx, projectID, skillID, filePath, expire, cond)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -651,6 +651,7 @@ func TestAgentSkillsHandler_GetAgentSkillFile(t *testing.T) {
 		name           string
 		id             string
 		filePath       string
+		ifNoneMatch    string
 		setup          func(*MockAgentSkillsService)
 		expectedStatus int
 		expectedError  string
@@ -660,7 +661,7 @@ func TestAgentSkillsHandler_GetAgentSkillFile(t *testing.T) {
 			id:       agentSkills.ID.String(),
 			filePath: "file1.json",
 			setup: func(svc *MockAgentSkillsService) {
-				svc.On("GetFile", mock.Anything, projectID, agentSkills.ID, "file1.json", mock.Anything).Return(&service.GetFileOutput{
+				svc.On("GetFile", mock.Anything, projectID, agentSkills.ID, "file1.json", mock.Anything, service.Preconditions{}).Return(&service.GetFileOutput{
 					Path: "file1.json",
 					MIME: "application/json",
 					URL:  &testURL,
@@ -668,6 +669,17 @@ func TestAgentSkillsHandler_GetAgentSkillFile(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:        "file not modified",
+			id:          agentSkills.ID.String(),
+			filePath:    "file1.json",
+			ifNoneMatch: service.ETag("abc"),
+			setup: func(svc *MockAgentSkillsService) {
+				svc.On("GetFile", mock.Anything, projectID, agentSkills.ID, "file1.json", mock.Anything, service.Preconditions{IfNoneMatch: service.ETag("abc")}).
+					Return(&service.GetFileOutput{Path: "file1.json", MIME: "application/json", ETag: service.ETag("abc")}, service.ErrNotModified)
+			},
+			expectedStatus: http.StatusNotModified,
+		},
 		{
 			name:           "invalid ID",
 			id:             "invalid-uuid",
@@ -689,7 +701,7 @@ func TestAgentSkillsHandler_GetAgentSkillFile(t *testing.T) {
 			id:       agentSkills.ID.String(),
 			filePath: "non-existent.json",
 			setup: func(svc *MockAgentSkillsService) {
-				svc.On("GetFile", mock.Anything, projectID, agentSkills.ID, "non-existent.json", mock.Anything).Return(nil, errors.New("file not found"))
+				svc.On("GetFile", mock.Anything, projectID, agentSkills.ID, "non-existent.json", mock.Anything, service.Preconditions{}).Return(nil, errors.New("file not found"))
 			},
 			expectedStatus: http.StatusNotFound,
 		},
@@ -712,11 +724,18 @@ func TestAgentSkillsHandler_GetAgentSkillFile(t *testing.T) {
 				url += "?file_path=" + tt.filePath
 			}
 			req := httptest.NewRequest("GET", url, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
 			w := httptest.NewRecorder()
 
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusNotModified {
+				assert.Equal(t, tt.ifNoneMatch, w.Header().Get("ETag"))
+				assert.Empty(t, w.Body.String())
+			}
 
 			if tt.expectedError != "" {
 				var response map[string]interface{}
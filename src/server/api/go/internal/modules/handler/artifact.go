@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -40,6 +41,23 @@ type CreateArtifactReq struct {
 type GrepArtifactsReq struct {
 	Query string `form:"query" json:"query" binding:"required" example:"TODO.*"`
 	Limit *int   `form:"limit" json:"limit" binding:"omitempty,min=0,max=200" example:"20"`
+
+	// The options below only apply to output=matches
+	Output       string   `form:"output" json:"output" binding:"omitempty,oneof=artifacts matches" example:"matches"`
+	IgnoreCase   bool     `form:"ignore_case" json:"ignore_case"`
+	FixedStrings bool     `form:"fixed_strings" json:"fixed_strings"`
+	Include      []string `form:"include" json:"include" example:"*.py"`
+	Exclude      []string `form:"exclude" json:"exclude" example:"/vendor/**"`
+	Before       *int     `form:"before" json:"before" binding:"omitempty,min=0,max=50" example:"2"`
+	After        *int     `form:"after" json:"after" binding:"omitempty,min=0,max=50" example:"2"`
+	Context      *int     `form:"context" json:"context" binding:"omitempty,min=0,max=50" example:"2"`
+	MaxCount     *int     `form:"max_count" json:"max_count" binding:"omitempty,min=1,max=1000" example:"20"`
+}
+
+// matchesOnly reports whether an option of output=matches is set.
+func (r GrepArtifactsReq) matchesOnly() bool {
+	return r.IgnoreCase || r.FixedStrings || len(r.Include) > 0 || len(r.Exclude) > 0 ||
+		r.Before != nil || r.After != nil || r.Context != nil || r.MaxCount != nil
 }
 
 type GlobArtifactsReq struct {
@@ -58,9 +76,12 @@ type GlobArtifactsReq struct {
 //	@Param			file_path	formData	string	false	"File path in the disk storage (optional, defaults to '/')"
 //	@Param			file		formData	file	true	"File to upload (size must not exceed configured limit)"
 //	@Param			meta		formData	string	false	"Custom metadata as JSON string (optional, system metadata will be stored under '__artifact_info__' key)"
+//	@Param			If-Match	header		string	false	"Only replace the artifact if its content still has one of these ETags; * requires an existing artifact"
 //	@Security		BearerAuth
 //	@Success		201	{object}	serializer.Response{data=model.Artifact}
-//	@Failure		413	{object}	serializer.Response	"File size exceeds maximum allowed size"
+//	@Header			201	{string}	ETag	"Entity tag of the stored content"
+//	@Failure		412	{object}	serializer.Response	"If-Match does not match the current artifact"
+//	@Failure		413	{object}	serializer.Response	"File size exceeds maximum allowed size, or a storage quota would be exceeded"
 //	@Router			/disk/{disk_id}/artifact [post]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Upload a file to disk\nwith open('report.pdf', 'rb') as f:\n    artifact = client.disks.upload_artifact(\n        disk_id='disk-uuid',\n        file=f,\n        file_path='/documents/',\n        meta={'category': 'reports', 'year': 2024}\n    )\nprint(f\"Uploaded artifact: {artifact.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\nimport fs from 'fs';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Upload a file to disk\nconst fileBuffer = fs.readFileSync('report.pdf');\nconst artifact = await client.disks.uploadArtifact('disk-uuid', {\n  file: fileBuffer,\n  filePath: '/documents/',\n  meta: { category: 'reports', year: 2024 }\n});\nconsole.log(`Uploaded artifact: ${artifact.id}`);\n","label":"JavaScript"}]
 func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
@@ -127,36 +148,210 @@ func (h *ArtifactHandler) UpsertArtifact(c *gin.Context) {
 	}
 
 	artifactRecord, err := h.svc.Create(c.Request.Context(), service.CreateArtifactInput{
-		ProjectID:  project.ID,
-		DiskID:     diskID,
-		Path:       filePath,
-		Filename:   actualFilename,
-		FileHeader: file,
-		UserMeta:   userMeta,
+		ProjectID:     project.ID,
+		DiskID:        diskID,
+		Path:          filePath,
+		Filename:      actualFilename,
+		FileHeader:    file,
+		UserMeta:      userMeta,
+		Preconditions: preconditions(c),
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		switch {
+		case errors.Is(err, service.ErrQuotaExceeded):
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+		case errors.Is(err, service.ErrArtifactModified):
+			c.JSON(http.StatusPreconditionFailed, serializer.Err(http.StatusPreconditionFailed, err.Error(), nil))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
 		return
 	}
 
+	setArtifactETag(c, artifactRecord)
 	c.JSON(http.StatusCreated, serializer.Response{Data: artifactRecord})
 }
 
+type CreateUploadURLReq struct {
+	FilePath    string                 `json:"file_path" binding:"required" example:"/videos/demo.mp4"` // File path including filename
+	Size        int64                  `json:"size" binding:"required,min=1" example:"734003200"`       // File size in bytes
+	SHA256      string                 `json:"sha256" binding:"required,len=64,hexadecimal" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	ContentType string                 `json:"content_type" example:"video/mp4"`                            // Content-Type the PUT sends, defaults to application/octet-stream
+	Meta        map[string]interface{} `json:"meta"`                                                        // Custom metadata of the artifact
+	Expire      int                    `json:"expire" binding:"omitempty,min=60,max=604800" example:"3600"` // Expire time in seconds of the upload URLs (default: 3600)
+}
+
+// CreateUploadURL godoc
+//
+//	@Summary		Create presigned upload
+//	@Description	Start a direct-to-S3 upload for files too large for the multipart form upload. The response holds a presigned PUT url, sent with the returned headers, or, for files above the multipart threshold, one url per part of part_size_b bytes. Complete the upload with upload_complete; the size and sha256 declared here are verified then.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.CreateUploadURLReq	true	"Create upload request"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=service.UploadTarget}
+//	@Failure		413	{object}	serializer.Response	"File size exceeds maximum allowed size, or a storage quota would be exceeded"
+//	@Router			/disk/{disk_id}/artifact/upload_url [post]
+func (h *ArtifactHandler) CreateUploadURL(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := CreateUploadURLReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if maxSize := h.config.Artifact.MaxDirectUploadSizeBytes; maxSize > 0 && req.Size > maxSize {
+		maxSizeMB := float64(maxSize) / (1024 * 1024)
+		c.JSON(http.StatusRequestEntityTooLarge, serializer.ParamErr("", fmt.Errorf("file size exceeds maximum allowed size of %.2fMB", maxSizeMB)))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+	if filename == "" {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("file_path must include a filename", nil))
+		return
+	}
+
+	expire := req.Expire
+	if expire == 0 {
+		expire = 3600
+	}
+
+	target, err := h.svc.CreateUpload(c.Request.Context(), service.CreateUploadInput{
+		ProjectID:   project.ID,
+		DiskID:      diskID,
+		Path:        filePath,
+		Filename:    filename,
+		SizeB:       req.Size,
+		SHA256:      req.SHA256,
+		ContentType: req.ContentType,
+		UserMeta:    req.Meta,
+		Expire:      time.Duration(expire) * time.Second,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidUpload) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+			return
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: target})
+}
+
+type CompleteUploadReq struct {
+	UploadID string              `json:"upload_id" binding:"required,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Parts    []blob.UploadedPart `json:"parts"` // Multipart uploads only: every part with the ETag its PUT returned
+}
+
+// CompleteUpload godoc
+//
+//	@Summary		Complete presigned upload
+//	@Description	Finish an upload started with upload_url. The uploaded object must have the declared size and sha256, otherwise the upload is discarded and has to be started again. The text of the file is extracted and the artifact is created, or a new version of the artifact at the same path.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.CompleteUploadReq	true	"Complete upload request"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		404	{object}	serializer.Response	"Upload not found"
+//	@Failure		409	{object}	serializer.Response	"Another request is completing the upload"
+//	@Failure		410	{object}	serializer.Response	"Upload has expired"
+//	@Failure		422	{object}	serializer.Response	"Uploaded object does not match the declared size and sha256"
+//	@Router			/disk/{disk_id}/artifact/upload_complete [post]
+func (h *ArtifactHandler) CompleteUpload(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := CompleteUploadReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	artifact, err := h.svc.CompleteUpload(c.Request.Context(), service.CompleteUploadInput{
+		ProjectID: project.ID,
+		DiskID:    diskID,
+		UploadID:  uuid.MustParse(req.UploadID),
+		Parts:     req.Parts,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUploadNotFound):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+		case errors.Is(err, service.ErrUploadInProgress):
+			c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, err.Error(), nil))
+		case errors.Is(err, service.ErrUploadExpired):
+			c.JSON(http.StatusGone, serializer.Err(http.StatusGone, err.Error(), nil))
+		case errors.Is(err, service.ErrUploadMismatch):
+			c.JSON(http.StatusUnprocessableEntity, serializer.Err(http.StatusUnprocessableEntity, err.Error(), nil))
+		case errors.Is(err, service.ErrInvalidUpload):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	setArtifactETag(c, artifact)
+	c.JSON(http.StatusCreated, serializer.Response{Data: artifact})
+}
+
 type DeleteArtifactReq struct {
-	FilePath string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
+	FilePath  string `form:"file_path" json:"file_path" binding:"required"` // File path including filename, or a directory ending with '/'
+	Recursive bool   `form:"recursive" json:"recursive"`                    // Required to delete a directory and everything under it
+}
+
+type DeleteArtifactResp struct {
+	Deleted int64 `json:"deleted"`
 }
 
 // DeleteArtifact godoc
 //
 //	@Summary		Delete artifact
-//	@Description	Delete an artifact by path and filename
+//	@Description	Delete an artifact by path and filename. A file_path ending with '/' deletes the directory with every artifact, version and subdirectory under it, and requires recursive=true.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
-//	@Param			disk_id		path	string	true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
-//	@Param			file_path	query	string	true	"File path including filename"	example(/documents/report.pdf)
+//	@Param			disk_id		path	string	true	"Disk ID"											Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"						example(/documents/report.pdf)
+//	@Param			recursive	query	boolean	false	"Delete a directory and everything under it"	example(false)
+//	@Param			If-Match	header	string	false	"Only delete the artifact if its content still has one of these ETags (files only)"
 //	@Security		BearerAuth
-//	@Success		200	{object}	serializer.Response{}
+//	@Success		200	{object}	serializer.Response{data=handler.DeleteArtifactResp}	"data is only set for directory deletes"
+//	@Failure		412	{object}	serializer.Response	"If-Match does not match the current artifact"
 //	@Router			/disk/{disk_id}/artifact [delete]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Delete an artifact\nclient.disks.delete_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf'\n)\nprint('Artifact deleted successfully')\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Delete an artifact\nawait client.disks.deleteArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf'\n});\nconsole.log('Artifact deleted successfully');\n","label":"JavaScript"}]
 func (h *ArtifactHandler) DeleteArtifact(c *gin.Context) {
@@ -187,7 +382,30 @@ func (h *ArtifactHandler) DeleteArtifact(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.DeleteByPath(c.Request.Context(), project.ID, diskID, filePath, filename); err != nil {
+	cond := preconditions(c)
+	if filename == "" {
+		if !req.Recursive {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("recursive must be true to delete a directory", errors.New("recursive must be true to delete a directory")))
+			return
+		}
+		if cond.IfMatch != "" {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("If-Match is not supported for directories", errors.New("If-Match is not supported for directories")))
+			return
+		}
+		deleted, err := h.svc.DeleteDirectory(c.Request.Context(), project.ID, diskID, filePath)
+		if err != nil {
+			snapshotErr(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, serializer.Response{Data: DeleteArtifactResp{Deleted: deleted}})
+		return
+	}
+
+	if err := h.svc.DeleteByPath(c.Request.Context(), project.ID, diskID, filePath, filename, cond); err != nil {
+		if errors.Is(err, service.ErrArtifactModified) {
+			c.JSON(http.StatusPreconditionFailed, serializer.Err(http.StatusPreconditionFailed, err.Error(), nil))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
@@ -200,28 +418,52 @@ type GetArtifactReq struct {
 	WithPublicURL bool   `form:"with_public_url,default=true" json:"with_public_url" example:"true"`
 	WithContent   bool   `form:"with_content,default=true" json:"with_content" example:"true"`
 	Expire        int    `form:"expire,default=3600" json:"expire" example:"3600"` // Expire time in seconds for presigned URL
+	// Partial read of the content
+	Unit   string `form:"unit" json:"unit" binding:"omitempty,oneof=lines bytes rows" example:"lines"`
+	Mode   string `form:"mode" json:"mode" binding:"omitempty,oneof=range head tail" example:"range"`
+	Offset int64  `form:"offset" json:"offset" binding:"omitempty,min=0" example:"0"`
+	Limit  int64  `form:"limit" json:"limit" binding:"omitempty,min=1" example:"200"`
+}
+
+// partial reports whether only part of the content was requested
+func (r GetArtifactReq) partial() bool {
+	return r.Unit != "" || r.Mode != "" || r.Offset > 0 || r.Limit > 0
 }
 
 type GetArtifactResp struct {
-	Artifact  *model.Artifact         `json:"artifact"`
-	PublicURL *string                 `json:"public_url,omitempty"`
-	Content   *fileparser.FileContent `json:"content,omitempty"`
+	Artifact     *model.Artifact         `json:"artifact"`
+	PublicURL    *string                 `json:"public_url,omitempty"`
+	Content      *fileparser.FileContent `json:"content,omitempty"`
+	ContentRange *service.ContentRange   `json:"content_range,omitempty"` // Set for partial reads
+
+	// Why public_url is missing when it was requested, e.g. "encrypted"
+	PublicURLUnavailable string `json:"public_url_unavailable,omitempty"`
 }
 
 // GetArtifact godoc
 //
 //	@Summary		Get artifact
 //	@Description	Get artifact information by path and filename. Optionally include a presigned URL for downloading and parsed file content.
+//	@Description	Any of unit, mode, offset or limit reads only part of the content: a range of lines, bytes or CSV rows (the header row is always included), the first (head) or last (tail) limit units.
+//	@Description	Text files are read from storage with ranged requests; documents are sliced from their extracted text. content_range reports the returned range and the totals known for pagination.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
-//	@Param			disk_id			path	string	true	"Disk ID"													Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
-//	@Param			file_path		query	string	true	"File path including filename"								example(/documents/report.pdf)
-//	@Param			with_public_url	query	boolean	false	"Whether to return public URL, default is true"				example(true)
-//	@Param			with_content	query	boolean	false	"Whether to return parsed file content, default is true"	example(true)
-//	@Param			expire			query	int		false	"Expire time in seconds for presigned URL (default: 3600)"	example(3600)
+//	@Param			disk_id			path	string	true	"Disk ID"																	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path		query	string	true	"File path including filename"												example(/documents/report.pdf)
+//	@Param			with_public_url	query	boolean	false	"Whether to return public URL, default is true; replaced by public_url_unavailable for content encrypted client-side"								example(true)
+//	@Param			with_content	query	boolean	false	"Whether to return parsed file content, default is true"					example(true)
+//	@Param			expire			query	int		false	"Expire time in seconds for presigned URL (default: 3600)"					example(3600)
+//	@Param			unit			query	string	false	"Unit of offset and limit: lines (default), bytes or rows (CSV only)"		Enums(lines, bytes, rows)
+//	@Param			mode			query	string	false	"range (default) reads from offset; head and tail read the first or last units"	Enums(range, head, tail)
+//	@Param			offset			query	int		false	"First line, byte or row to read, 0-based (range mode only)"				example(0)
+//	@Param			limit			query	int		false	"Units to read (default 200 lines or rows, 64 KiB; at most 10000 or 10 MiB)"	example(200)
+//	@Param			If-None-Match	header	string	false	"ETags of content the client already holds"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=handler.GetArtifactResp}
+//	@Header			200,304	{string}	ETag	"Entity tag of the response: the artifact, the read options and the content; weak with a public URL. Writes accept it in If-Match for the content it was read with"
+//	@Success		304	"The response still has an ETag from If-None-Match (never with a public URL)"
+//	@Failure		400	{object}	serializer.Response	"Invalid read range"
 //	@Router			/disk/{disk_id}/artifact [get]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Get artifact information\nartifact_info = client.disks.get_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    with_public_url=True,\n    with_content=True,\n    expire=3600\n)\nprint(f\"Artifact: {artifact_info.artifact.filename}\")\nif artifact_info.public_url:\n    print(f\"Download URL: {artifact_info.public_url}\")\nif artifact_info.content:\n    print(f\"Content: {artifact_info.content.text[:100]}...\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Get artifact information\nconst artifactInfo = await client.disks.getArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  withPublicUrl: true,\n  withContent: true,\n  expire: 3600\n});\nconsole.log(`Artifact: ${artifactInfo.artifact.filename}`);\nif (artifactInfo.publicUrl) {\n  console.log(`Download URL: ${artifactInfo.publicUrl}`);\n}\nif (artifactInfo.content) {\n  console.log(`Content: ${artifactInfo.content.text.substring(0, 100)}...`);\n}\n","label":"JavaScript"}]
 func (h *ArtifactHandler) GetArtifact(c *gin.Context) {
@@ -252,20 +494,56 @@ func (h *ArtifactHandler) GetArtifact(c *gin.Context) {
 		return
 	}
 
+	// The response holds the artifact row and the requested read, not only the content,
+	// so its tag covers them all. A presigned URL is new on every call: responses with
+	// one are only weakly equivalent and are never answered from the client's cache.
+	tag := service.RepresentationTag(artifact, c.Request.URL.RawQuery)
+	if req.WithPublicURL {
+		c.Header("ETag", "W/"+service.ETag(tag))
+	} else {
+		c.Header("ETag", service.ETag(tag))
+		if preconditions(c).NotModified(tag) {
+			c.AbortWithStatus(http.StatusNotModified)
+			return
+		}
+	}
+
 	resp := GetArtifactResp{Artifact: artifact}
 
 	// Generate presigned URL if requested
 	if req.WithPublicURL {
 		url, err := h.svc.GetPresignedURL(c.Request.Context(), artifact, time.Duration(req.Expire)*time.Second)
-		if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrEncryptedObject):
+			// Encrypted content is only served decrypted, through with_content
+			resp.PublicURLUnavailable = service.PublicURLEncrypted
+		case err != nil:
 			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 			return
+		default:
+			resp.PublicURL = &url
 		}
-		resp.PublicURL = &url
 	}
 
-	// Parse file content if requested
-	if req.WithContent {
+	// Read part of the file content if requested
+	if req.WithContent && req.partial() {
+		content, rng, err := h.svc.ReadFileContent(c.Request.Context(), artifact, service.ReadContentInput{
+			Unit:   req.Unit,
+			Mode:   req.Mode,
+			Offset: req.Offset,
+			Limit:  req.Limit,
+		})
+		if errors.Is(err, service.ErrInvalidReadRange) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+			return
+		}
+		// As for whole files, unsupported file types just don't include content
+		if err == nil {
+			resp.Content = content
+			resp.ContentRange = rng
+		}
+	} else if req.WithContent {
+		// Parse file content if requested
 		content, err := h.svc.GetFileContent(c.Request.Context(), artifact)
 		// Only set content if parsing succeeded
 		// Unsupported file types (images, binaries, etc.) will not have content
@@ -295,12 +573,21 @@ type UpdateArtifactResp struct {
 //	@Accept			json
 //	@Produce		json
 //	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
-//	@Param			request	body	handler.UpdateArtifactReq	true	"Update artifact request"
+//	@Param			request		body	handler.UpdateArtifactReq	true	"Update artifact request"
+//	@Param			If-Match	header	string						false	"Only update the artifact if its content still has one of these ETags"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=handler.UpdateArtifactResp}
+//	@Header			200	{string}	ETag	"Entity tag of the content"
+//	@Failure		412	{object}	serializer.Response	"If-Match does not match the current artifact"
 //	@Router			/disk/{disk_id}/artifact [put]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Update artifact metadata\nartifact = client.disks.update_artifact(\n    disk_id='disk-uuid',\n    file_path='/documents/report.pdf',\n    meta={'category': 'updated', 'reviewed': True, 'version': 2}\n)\nprint(f\"Updated artifact: {artifact.artifact.id}\")\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Update artifact metadata\nconst artifact = await client.disks.updateArtifact('disk-uuid', {\n  filePath: '/documents/report.pdf',\n  meta: { category: 'updated', reviewed: true, version: 2 }\n});\nconsole.log(`Updated artifact: ${artifact.artifact.id}`);\n","label":"JavaScript"}]
 func (h *ArtifactHandler) UpdateArtifact(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
 	req := UpdateArtifactReq{}
 	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
@@ -339,12 +626,17 @@ func (h *ArtifactHandler) UpdateArtifact(c *gin.Context) {
 	}
 
 	// Update artifact meta
-	artifactRecord, err := h.svc.UpdateArtifactMetaByPath(c.Request.Context(), diskID, filePath, filename, userMeta)
+	artifactRecord, err := h.svc.UpdateArtifactMetaByPath(c.Request.Context(), project.ID, diskID, filePath, filename, userMeta, preconditions(c))
 	if err != nil {
+		if errors.Is(err, service.ErrArtifactModified) {
+			c.JSON(http.StatusPreconditionFailed, serializer.Err(http.StatusPreconditionFailed, err.Error(), nil))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
 
+	setArtifactETag(c, artifactRecord)
 	c.JSON(http.StatusOK, serializer.Response{
 		Data: UpdateArtifactResp{Artifact: artifactRecord},
 	})
@@ -425,15 +717,24 @@ func (h *ArtifactHandler) ListArtifacts(c *gin.Context) {
 // GrepArtifacts godoc
 //
 //	@Summary		Search artifact content with regex
-//	@Description	Search through text-based artifact content using regex patterns
+//	@Description	Search through text-based artifact content using regex patterns. By default the matching artifacts are returned. With output=matches the result is shaped like ripgrep's: per file, the matching lines with their line numbers and match offsets, plus the requested context lines. The other options require output=matches.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
-//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)
-//	@Param			query	query	string	true	"Regex pattern to search for"
-//	@Param			limit	query	int		false	"Maximum number of results (default 100, max 1000)"
+//	@Param			disk_id			path	string		true	"Disk ID"	Format(uuid)
+//	@Param			query			query	string		true	"Regex pattern to search for"
+//	@Param			limit			query	int			false	"Maximum number of results (default 100, max 1000)"
+//	@Param			output			query	string		false	"artifacts (default) or matches"
+//	@Param			ignore_case		query	boolean		false	"Match regardless of case"
+//	@Param			fixed_strings	query	boolean		false	"Match the query literally"
+//	@Param			include			query	[]string	false	"Only search files matching these globs; a glob without '/' matches the filename"
+//	@Param			exclude			query	[]string	false	"Skip files matching these globs"
+//	@Param			before			query	int			false	"Context lines before each match"
+//	@Param			after			query	int			false	"Context lines after each match"
+//	@Param			context			query	int			false	"Context lines before and after each match, unless before or after is set"
+//	@Param			max_count		query	int			false	"Maximum matching lines per file (default 100)"
 //	@Security		BearerAuth
-//	@Success		200	{object}	serializer.Response{data=[]model.Artifact}
+//	@Success		200	{object}	serializer.Response{data=[]model.Artifact}	"With output=matches, data is a service.GrepContentOutput"
 //	@Router			/disk/{disk_id}/artifact/grep [get]
 func (h *ArtifactHandler) GrepArtifacts(c *gin.Context) {
 	project, ok := c.MustGet("project").(*model.Project)
@@ -459,6 +760,15 @@ func (h *ArtifactHandler) GrepArtifacts(c *gin.Context) {
 		limit = *req.Limit
 	}
 
+	if req.Output == "matches" {
+		h.grepMatches(c, project.ID, diskID, req, limit)
+		return
+	}
+	if req.matchesOnly() {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("these options require output=matches")))
+		return
+	}
+
 	artifacts, err := h.svc.GrepArtifacts(c.Request.Context(), project.ID, diskID, req.Query, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
@@ -468,15 +778,52 @@ func (h *ArtifactHandler) GrepArtifacts(c *gin.Context) {
 	c.JSON(http.StatusOK, serializer.Response{Data: artifacts})
 }
 
+func (h *ArtifactHandler) grepMatches(c *gin.Context, projectID uuid.UUID, diskID uuid.UUID, req GrepArtifactsReq, limit int) {
+	in := service.GrepContentInput{
+		ProjectID:    projectID,
+		DiskID:       diskID,
+		Pattern:      req.Query,
+		IgnoreCase:   req.IgnoreCase,
+		FixedStrings: req.FixedStrings,
+		Include:      req.Include,
+		Exclude:      req.Exclude,
+		Limit:        limit,
+	}
+	if req.Context != nil {
+		in.Before, in.After = *req.Context, *req.Context
+	}
+	if req.Before != nil {
+		in.Before = *req.Before
+	}
+	if req.After != nil {
+		in.After = *req.After
+	}
+	if req.MaxCount != nil {
+		in.MaxCount = *req.MaxCount
+	}
+
+	out, err := h.svc.GrepContent(c.Request.Context(), in)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidGrepPattern) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
 // GlobArtifacts godoc
 //
 //	@Summary		Search artifact paths with glob patterns
-//	@Description	Search through artifact file paths using glob patterns (*, ?, etc.)
+//	@Description	Search through artifact file paths using glob patterns. '*' and '?' do not cross '/', '**' as a whole segment matches zero or more directories, [abc] and [!abc] match character classes, {a,b} matches alternatives and '\' escapes a character. Patterns are anchored at the root: '*.txt' only matches root files, '**/*.txt' matches them at any depth.
 //	@Tags			artifact
 //	@Accept			json
 //	@Produce		json
 //	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)
-//	@Param			query	query	string	true	"Glob pattern (e.g., '**/*.py', '*.txt')"
+//	@Param			query	query	string	true	"Glob pattern (e.g., '**/*.py', '/src/*.{go,md}')"
 //	@Param			limit	query	int		false	"Maximum number of results (default 100, max 1000)"
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=[]model.Artifact}
@@ -507,6 +854,10 @@ func (h *ArtifactHandler) GlobArtifacts(c *gin.Context) {
 
 	artifacts, err := h.svc.GlobArtifacts(c.Request.Context(), project.ID, diskID, req.Query, limit)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidGlobPattern) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
 		return
 	}
@@ -685,6 +1036,7 @@ func (h *ArtifactHandler) UploadFromSandbox(c *gin.Context) {
 		Path:      req.FilePath,
 		Filename:  req.SandboxFilename,
 		Content:   content,
+		Actor:     model.DiskChangeActorSandbox,
 	})
 
 	// Cleanup temp S3 file regardless of artifact creation result
@@ -695,5 +1047,860 @@ func (h *ArtifactHandler) UploadFromSandbox(c *gin.Context) {
 		return
 	}
 
+	setArtifactETag(c, artifact)
 	c.JSON(http.StatusCreated, serializer.Response{Data: artifact})
 }
+
+type ListArtifactVersionsReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
+}
+
+type ListArtifactVersionsResp struct {
+	Versions []*model.ArtifactVersion `json:"versions"`
+}
+
+// ListArtifactVersions godoc
+//
+//	@Summary		List artifact versions
+//	@Description	List every version of an artifact, newest first. The first entry is the current version.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"						Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"	example(/documents/report.md)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.ListArtifactVersionsResp}
+//	@Router			/disk/{disk_id}/artifact/versions [get]
+func (h *ArtifactHandler) ListArtifactVersions(c *gin.Context) {
+	req := ListArtifactVersionsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	versions, err := h.svc.ListVersions(c.Request.Context(), diskID, filePath, filename)
+	if err != nil {
+		artifactVersionErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: ListArtifactVersionsResp{Versions: versions}})
+}
+
+type GetArtifactVersionReq struct {
+	FilePath      string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
+	Version       int    `form:"version" json:"version" binding:"required,min=1" example:"1"`
+	WithPublicURL bool   `form:"with_public_url,default=true" json:"with_public_url" example:"true"`
+	WithContent   bool   `form:"with_content,default=true" json:"with_content" example:"true"`
+	Expire        int    `form:"expire,default=3600" json:"expire" example:"3600"` // Expire time in seconds for presigned URL
+}
+
+type GetArtifactVersionResp struct {
+	Version   *model.ArtifactVersion  `json:"version"`
+	PublicURL *string                 `json:"public_url,omitempty"`
+	Content   *fileparser.FileContent `json:"content,omitempty"`
+
+	// Why public_url is missing when it was requested, e.g. "encrypted"
+	PublicURLUnavailable string `json:"public_url_unavailable,omitempty"`
+}
+
+// GetArtifactVersion godoc
+//
+//	@Summary		Get artifact version
+//	@Description	Get a specific version of an artifact. Optionally include a presigned URL for downloading and parsed file content of that version.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id			path	string	true	"Disk ID"													Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path		query	string	true	"File path including filename"								example(/documents/report.md)
+//	@Param			version			query	int		true	"Version number"											example(1)
+//	@Param			with_public_url	query	boolean	false	"Whether to return public URL, default is true; replaced by public_url_unavailable for content encrypted client-side"				example(true)
+//	@Param			with_content	query	boolean	false	"Whether to return parsed file content, default is true"	example(true)
+//	@Param			expire			query	int		false	"Expire time in seconds for presigned URL (default: 3600)"	example(3600)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.GetArtifactVersionResp}
+//	@Router			/disk/{disk_id}/artifact/version [get]
+func (h *ArtifactHandler) GetArtifactVersion(c *gin.Context) {
+	req := GetArtifactVersionReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	version, err := h.svc.GetVersion(c.Request.Context(), diskID, filePath, filename, req.Version)
+	if err != nil {
+		artifactVersionErr(c, err)
+		return
+	}
+
+	setArtifactETag(c, version.AsArtifact())
+	resp := GetArtifactVersionResp{Version: version}
+
+	if req.WithPublicURL {
+		url, err := h.svc.GetPresignedURL(c.Request.Context(), version.AsArtifact(), time.Duration(req.Expire)*time.Second)
+		switch {
+		case errors.Is(err, blob.ErrEncryptedObject):
+			// Encrypted content is only served decrypted, through with_content
+			resp.PublicURLUnavailable = service.PublicURLEncrypted
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+			return
+		default:
+			resp.PublicURL = &url
+		}
+	}
+
+	if req.WithContent {
+		// Unsupported file types simply have no content, as in GetArtifact
+		content, err := h.svc.GetFileContent(c.Request.Context(), version.AsArtifact())
+		if err == nil && content != nil {
+			resp.Content = content
+		}
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: resp})
+}
+
+type DiffArtifactVersionsReq struct {
+	FilePath string `form:"file_path" json:"file_path" binding:"required"` // File path including filename
+	From     int    `form:"from" json:"from" binding:"required,min=1" example:"1"`
+	To       int    `form:"to" json:"to" binding:"omitempty,min=1" example:"2"` // Defaults to the current version
+}
+
+// DiffArtifactVersions godoc
+//
+//	@Summary		Diff artifact versions
+//	@Description	Produce a unified diff between the parsed text content of two versions of a parsable artifact
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"									Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	query	string	true	"File path including filename"				example(/documents/report.md)
+//	@Param			from		query	int		true	"Version to diff from"						example(1)
+//	@Param			to			query	int		false	"Version to diff to (default: current)"	example(2)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ArtifactDiff}
+//	@Router			/disk/{disk_id}/artifact/diff [get]
+func (h *ArtifactHandler) DiffArtifactVersions(c *gin.Context) {
+	req := DiffArtifactVersionsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	out, err := h.svc.DiffVersions(c.Request.Context(), diskID, filePath, filename, req.From, req.To)
+	if err != nil {
+		artifactVersionErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+type RestoreArtifactVersionReq struct {
+	FilePath string `json:"file_path" binding:"required"` // File path including filename
+	Version  int    `json:"version" binding:"required,min=1" example:"1"`
+}
+
+// RestoreArtifactVersion godoc
+//
+//	@Summary		Restore artifact version
+//	@Description	Make an old version of an artifact current again. The restore is recorded as a new version.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string								true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.RestoreArtifactVersionReq	true	"Restore artifact version request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Artifact}
+//	@Router			/disk/{disk_id}/artifact/restore [post]
+func (h *ArtifactHandler) RestoreArtifactVersion(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := RestoreArtifactVersionReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	artifact, err := h.svc.RestoreVersion(c.Request.Context(), project.ID, diskID, filePath, filename, req.Version)
+	if err != nil {
+		artifactVersionErr(c, err)
+		return
+	}
+
+	setArtifactETag(c, artifact)
+	c.JSON(http.StatusOK, serializer.Response{Data: artifact})
+}
+
+type EditArtifactReq struct {
+	FilePath string `json:"file_path" binding:"required" example:"/src/main.go"` // File path including filename
+	Op       string `json:"op" binding:"required,oneof=str_replace insert replace_lines apply_diff" example:"str_replace"`
+	// str_replace: old_str must occur exactly once in the file
+	OldStr string `json:"old_str" example:"foo()"`
+	NewStr string `json:"new_str" example:"bar()"`
+	// insert: text is inserted after this 1-based line, 0 inserts at the start
+	Line int `json:"line" binding:"min=0" example:"0"`
+	// replace_lines: lines start_line through end_line (1-based, inclusive) are replaced by text
+	StartLine int    `json:"start_line" binding:"min=0" example:"3"`
+	EndLine   int    `json:"end_line" binding:"min=0" example:"5"`
+	Text      string `json:"text" example:"new line\n"`
+	// apply_diff: unified diff against the current content
+	Diff string `json:"diff" example:"@@ -1 +1 @@\n-old\n+new\n"`
+	// The edit fails with 412 unless the current content matches
+	ExpectedSHA256 string `json:"expected_sha256" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	ExpectedETag   string `json:"expected_etag" example:"\"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08\""`
+}
+
+// EditArtifact godoc
+//
+//	@Summary		Edit artifact
+//	@Description	Edit a text file in place and store the result as a new version. op selects the edit: str_replace replaces old_str, which must occur exactly once, with new_str; insert adds text after line; replace_lines replaces start_line through end_line with text; apply_diff applies a unified diff. Pass expected_sha256, or expected_etag as returned in the ETag header of a write, so a concurrent edit isn't overwritten; the edit is always applied to the content it was read from.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.EditArtifactReq	true	"Edit artifact request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Artifact}
+//	@Failure		400	{object}	serializer.Response	"The edit does not apply to the current content"
+//	@Failure		412	{object}	serializer.Response	"The artifact was modified since expected_sha256 or expected_etag"
+//	@Router			/disk/{disk_id}/artifact/edit [post]
+func (h *ArtifactHandler) EditArtifact(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := EditArtifactReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	artifact, err := h.svc.EditArtifact(c.Request.Context(), service.EditArtifactInput{
+		ProjectID:      project.ID,
+		DiskID:         diskID,
+		Path:           filePath,
+		Filename:       filename,
+		Op:             req.Op,
+		OldStr:         req.OldStr,
+		NewStr:         req.NewStr,
+		Line:           req.Line,
+		StartLine:      req.StartLine,
+		EndLine:        req.EndLine,
+		Text:           req.Text,
+		Diff:           req.Diff,
+		ExpectedSHA256: req.ExpectedSHA256,
+		ExpectedETag:   req.ExpectedETag,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidEdit):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+		case errors.Is(err, service.ErrQuotaExceeded):
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+		case errors.Is(err, service.ErrArtifactModified):
+			c.JSON(http.StatusPreconditionFailed, serializer.Err(http.StatusPreconditionFailed, err.Error(), nil))
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	setArtifactETag(c, artifact)
+	c.JSON(http.StatusOK, serializer.Response{Data: artifact})
+}
+
+type TransferArtifactsReq struct {
+	SourcePath      string `json:"source_path" binding:"required" example:"/drafts/"`         // File path, or a directory when it ends with '/'
+	DestinationPath string `json:"destination_path" binding:"required" example:"/published/"` // New file path, or the target directory when it ends with '/'
+	OnConflict      string `json:"on_conflict" binding:"omitempty,oneof=fail overwrite skip" example:"fail"`
+	// Copy only: another disk of the project to copy into, defaults to the source disk
+	DestinationDiskID string `json:"destination_disk_id" binding:"omitempty,uuid" example:"223e4567-e89b-12d3-a456-426614174000"`
+}
+
+// MoveArtifacts godoc
+//
+//	@Summary		Move artifacts
+//	@Description	Move or rename a file, or move every artifact under a directory prefix. Only metadata changes: the S3 objects are reused. Directory moves are atomic. on_conflict decides what happens when a destination exists: fail (default) aborts the whole move, overwrite replaces it, skip leaves the source in place.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.TransferArtifactsReq	true	"Move request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.TransferArtifactsOutput}
+//	@Failure		409	{object}	serializer.Response	"Destination already exists"
+//	@Router			/disk/{disk_id}/artifact/move [post]
+func (h *ArtifactHandler) MoveArtifacts(c *gin.Context) {
+	h.transferArtifacts(c, h.svc.MoveArtifacts)
+}
+
+// CopyArtifacts godoc
+//
+//	@Summary		Copy artifacts
+//	@Description	Copy a file, or every artifact under a directory prefix, within the disk or into another disk of the project (destination_disk_id). Copies share the S3 objects of their sources, which are reference-counted. Directory copies are atomic. on_conflict decides what happens when a destination exists: fail (default) aborts the whole copy, overwrite replaces it, skip leaves it untouched.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.TransferArtifactsReq	true	"Copy request"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.TransferArtifactsOutput}
+//	@Failure		409	{object}	serializer.Response	"Destination already exists"
+//	@Router			/disk/{disk_id}/artifact/copy [post]
+func (h *ArtifactHandler) CopyArtifacts(c *gin.Context) {
+	h.transferArtifacts(c, h.svc.CopyArtifacts)
+}
+
+func (h *ArtifactHandler) transferArtifacts(c *gin.Context, transfer func(context.Context, service.TransferArtifactsInput) (*service.TransferArtifactsOutput, error)) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := TransferArtifactsReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	var destDiskID uuid.UUID
+	if req.DestinationDiskID != "" {
+		destDiskID, err = uuid.Parse(req.DestinationDiskID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid destination_disk_id", err))
+			return
+		}
+	}
+
+	out, err := transfer(c.Request.Context(), service.TransferArtifactsInput{
+		ProjectID:         project.ID,
+		DiskID:            diskID,
+		SourcePath:        req.SourcePath,
+		DestinationPath:   req.DestinationPath,
+		DestinationDiskID: destDiskID,
+		OnConflict:        req.OnConflict,
+	})
+	if err != nil {
+		transferErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+// transferErr maps move and copy errors to a response status.
+func transferErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTransfer):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+	case errors.Is(err, service.ErrArtifactConflict):
+		c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, err.Error(), nil))
+	case errors.Is(err, service.ErrQuotaExceeded):
+		c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
+
+// artifactVersionErr maps version lookup and parsing errors to a response status.
+func artifactVersionErr(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+	case strings.Contains(err.Error(), "unsupported file type"):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+	default:
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
+
+type CreateDirectoryReq struct {
+	Path string `json:"path" binding:"required" example:"/documents/drafts/"` // Directory path, both ends must be '/'
+}
+
+// CreateDirectory godoc
+//
+//	@Summary		Create directory
+//	@Description	Create an explicit directory. It is listed by ls and tree even when it holds no artifact, until it is deleted. Creating an existing directory succeeds.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			request	body	handler.CreateDirectoryReq	true	"Directory"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.ArtifactDirectory}
+//	@Router			/disk/{disk_id}/artifact/mkdir [post]
+func (h *ArtifactHandler) CreateDirectory(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := CreateDirectoryReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if dirPath, _ := path.SplitFilePath(req.Path); dirPath != req.Path || dirPath == "/" {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("both ends of the path must be '/'", errors.New("both ends of the path must be '/' and the path cannot be the root")))
+		return
+	}
+	if err := path.ValidatePath(req.Path); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	dir, err := h.svc.CreateDirectory(c.Request.Context(), project.ID, diskID, req.Path)
+	if err != nil {
+		snapshotErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: dir})
+}
+
+type GetArtifactTreeReq struct {
+	Path  string `form:"path" json:"path" example:"/documents/"` // Optional, defaults to "/"
+	Depth int    `form:"depth,default=3" json:"depth" binding:"min=1,max=20" example:"3"`
+}
+
+// GetArtifactTree godoc
+//
+//	@Summary		Get directory tree
+//	@Description	Get the nested tree under a directory. Every directory carries the file count and total size of everything under it, at any depth. Directories deeper than depth are omitted, and so are the files of the deepest directories listed: those directories are marked truncated.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"										Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			path	query	string	false	"Directory path (default: /)"					example(/documents/)
+//	@Param			depth	query	int		false	"Directory levels to expand (default 3, max 20)"	example(3)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ArtifactTreeNode}
+//	@Router			/disk/{disk_id}/artifact/tree [get]
+func (h *ArtifactHandler) GetArtifactTree(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := GetArtifactTreeReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	pathQuery := req.Path
+	if pathQuery == "" {
+		pathQuery = "/"
+	}
+	if dirPath, _ := path.SplitFilePath(pathQuery); dirPath != pathQuery {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("both ends of the path must be '/'", errors.New("both ends of the path must be '/'")))
+		return
+	}
+	if err := path.ValidatePath(pathQuery); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return
+	}
+
+	tree, err := h.svc.GetTree(c.Request.Context(), diskID, pathQuery, req.Depth)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: tree})
+}
+
+type SemanticSearchReq struct {
+	Q     string `form:"q" json:"q" binding:"required" example:"how are deployments configured"`
+	Path  string `form:"path" json:"path" example:"/docs/"` // Optional directory to search under, defaults to "/"
+	Limit int    `form:"limit,default=10" json:"limit" binding:"min=1,max=100" example:"10"`
+}
+
+// SemanticSearchArtifacts godoc
+//
+//	@Summary		Semantic search over artifacts
+//	@Description	Search the text content of artifacts by meaning rather than by pattern. Text content is split into chunks that are embedded when an artifact is written; the chunks closest to the query are returned best first, with their artifact path, character offsets and lines. Requires an embedding provider to be configured.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"								Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			q		query	string	true	"Natural language query"				example(how are deployments configured)
+//	@Param			path	query	string	false	"Directory to search under (default: /)"	example(/docs/)
+//	@Param			limit	query	int		false	"Maximum number of chunks (default 10, max 100)"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=[]service.SemanticSearchHit}
+//	@Failure		501	{object}	serializer.Response	"No embedding provider is configured"
+//	@Router			/disk/{disk_id}/artifact/search [get]
+func (h *ArtifactHandler) SemanticSearchArtifacts(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := SemanticSearchReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	if req.Path != "" {
+		if dirPath, _ := path.SplitFilePath(req.Path); dirPath != req.Path {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("both ends of the path must be '/'", errors.New("both ends of the path must be '/'")))
+			return
+		}
+		if err := path.ValidatePath(req.Path); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+			return
+		}
+	}
+
+	hits, err := h.svc.SemanticSearch(c.Request.Context(), service.SemanticSearchInput{
+		DiskID: diskID,
+		Query:  req.Q,
+		Path:   req.Path,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrSemanticSearchDisabled) {
+			c.JSON(http.StatusNotImplemented, serializer.Err(http.StatusNotImplemented, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: hits})
+}
+
+type CreateSnapshotReq struct {
+	Name string `json:"name" example:"before-refactor"` // Optional label of the snapshot
+}
+
+// CreateSnapshot godoc
+//
+//	@Summary		Create disk snapshot
+//	@Description	Capture the current artifacts and directories of a disk, e.g. before an agent runs destructive operations. Snapshots reference the stored content instead of copying it, and keep it available until the snapshot is deleted.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string						true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			payload	body	handler.CreateSnapshotReq	false	"CreateSnapshot payload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.DiskSnapshot}
+//	@Router			/disk/{disk_id}/snapshot [post]
+func (h *ArtifactHandler) CreateSnapshot(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := CreateSnapshotReq{}
+	// The payload is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+	}
+
+	snapshot, err := h.svc.CreateSnapshot(c.Request.Context(), project.ID, diskID, req.Name)
+	if err != nil {
+		snapshotErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: snapshot})
+}
+
+// ListSnapshots godoc
+//
+//	@Summary		List disk snapshots
+//	@Description	List the snapshots of a disk, newest first
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=[]model.DiskSnapshot}
+//	@Router			/disk/{disk_id}/snapshot [get]
+func (h *ArtifactHandler) ListSnapshots(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	snapshots, err := h.svc.ListSnapshots(c.Request.Context(), project.ID, diskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: snapshots})
+}
+
+// DeleteSnapshot godoc
+//
+//	@Summary		Delete disk snapshot
+//	@Description	Delete a snapshot. Content only it still references is released.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"		Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			snapshot_id	path	string	true	"Snapshot ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{}
+//	@Router			/disk/{disk_id}/snapshot/{snapshot_id} [delete]
+func (h *ArtifactHandler) DeleteSnapshot(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+	snapshotID, err := uuid.Parse(c.Param("snapshot_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid snapshot_id", err))
+		return
+	}
+
+	if err := h.svc.DeleteSnapshot(c.Request.Context(), project.ID, diskID, snapshotID); err != nil {
+		snapshotErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
+
+type RestoreSnapshotReq struct {
+	Snapshot string `form:"snapshot" json:"snapshot" binding:"required,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+// RestoreSnapshot godoc
+//
+//	@Summary		Restore disk snapshot
+//	@Description	Bring the artifacts and directories of a disk back to the state of a snapshot. Artifacts created since are deleted, missing ones are recreated, and changed ones get the snapshot content as a new version, so their current content stays available in the version history.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"		Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			snapshot	query	string	true	"Snapshot ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.RestoreSnapshotOutput}
+//	@Router			/disk/{disk_id}/restore [post]
+func (h *ArtifactHandler) RestoreSnapshot(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := RestoreSnapshotReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	out, err := h.svc.RestoreSnapshot(c.Request.Context(), project.ID, diskID, uuid.MustParse(req.Snapshot))
+	if err != nil {
+		snapshotErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+type EncryptProjectObjectsReq struct {
+	Cursor string `form:"cursor" json:"cursor"`
+	Limit  int    `form:"limit,default=100" json:"limit" binding:"min=1,max=1000" example:"100"`
+}
+
+type EncryptProjectObjectsResp struct {
+	Encrypted  int    `json:"encrypted"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Done       bool   `json:"done"`
+}
+
+// EncryptProjectObjects godoc
+//
+//	@Summary		Encrypt stored objects of the project
+//	@Description	Encrypt artifact, message part and asset objects stored before client-side encryption was enabled. Each call goes through up to limit objects; call it again with next_cursor until done is true.
+//	@Tags			project
+//	@Accept			json
+//	@Produce		json
+//	@Param			cursor	query	string	false	"next_cursor of the previous call"
+//	@Param			limit	query	integer	false	"Maximum number of objects to go through, default 100. Max 1000."
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=handler.EncryptProjectObjectsResp}
+//	@Failure		409	{object}	serializer.Response	"Client-side encryption is not enabled"
+//	@Router			/project/encryption/migrate [post]
+func (h *ArtifactHandler) EncryptProjectObjects(c *gin.Context) {
+	req := EncryptProjectObjectsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	if h.s3 == nil {
+		c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, blob.ErrEncryptionDisabled.Error(), nil))
+		return
+	}
+
+	encrypted, next, err := h.s3.EncryptProjectObjects(c.Request.Context(), project.ID.String(), req.Cursor, req.Limit)
+	if err != nil {
+		if errors.Is(err, blob.ErrEncryptionDisabled) {
+			c.JSON(http.StatusConflict, serializer.Err(http.StatusConflict, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: EncryptProjectObjectsResp{Encrypted: encrypted, NextCursor: next, Done: next == ""}})
+}
+
+// preconditions reads the conditional request headers.
+func preconditions(c *gin.Context) service.Preconditions {
+	return service.Preconditions{
+		IfMatch:     c.GetHeader("If-Match"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+}
+
+// setArtifactETag sets the ETag response header to the entity tag of a's content.
+func setArtifactETag(c *gin.Context, a *model.Artifact) {
+	c.Header("ETag", service.ETag(a.AssetMeta.Data().SHA256))
+}
+
+// snapshotErr maps snapshot errors to a response status.
+func snapshotErr(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrQuotaExceeded) {
+		c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+		return
+	}
+	if strings.Contains(err.Error(), "not found") {
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
+)
+
+type UploadArchiveReq struct {
+	FilePath  string `form:"file_path" json:"file_path" example:"/src/"`                              // Directory to expand the archive under, defaults to "/"
+	Format    string `form:"format" json:"format" binding:"omitempty,oneof=zip tar.gz" example:"zip"` // Detected from the file name when omitted
+	StripRoot bool   `form:"strip_root" json:"strip_root"`                                            // Drop the top-level directory holding every file
+}
+
+// UploadArchive godoc
+//
+//	@Summary		Upload archive
+//	@Description	Expand a zip or tar.gz archive under a directory of a disk, one artifact per file. Existing artifacts get the archived content as a new version. Entries with absolute paths or escaping the directory are rejected before anything is stored; directories, links and macOS metadata are skipped. Each file must fit the upload size limit.
+//	@Tags			artifact
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			disk_id		path		string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			file_path	formData	string	false	"Directory to expand the archive under (defaults to '/')"
+//	@Param			format		formData	string	false	"zip or tar.gz, detected from the file name when omitted"
+//	@Param			strip_root	formData	boolean	false	"Drop the top-level directory when every file is inside it"
+//	@Param			file		formData	file	true	"Archive to upload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=service.ExtractArchiveOutput}
+//	@Failure		400	{object}	serializer.Response	"Unsupported or malformed archive, or unsafe entry paths"
+//	@Failure		413	{object}	serializer.Response	"Archive too large, or a storage quota would be exceeded"
+//	@Router			/disk/{disk_id}/artifact/archive [post]
+func (h *ArtifactHandler) UploadArchive(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := UploadArchiveReq{}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	dir, ok := archiveDir(c, req.FilePath)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("file is required", err))
+		return
+	}
+	if maxSize := h.config.Artifact.MaxArchiveSizeBytes; maxSize > 0 && file.Size > maxSize {
+		maxSizeMB := float64(maxSize) / (1024 * 1024)
+		c.JSON(http.StatusRequestEntityTooLarge, serializer.ParamErr("", fmt.Errorf("archive size exceeds maximum allowed size of %.2fMB", maxSizeMB)))
+		return
+	}
+
+	out, err := h.svc.ExtractArchive(c.Request.Context(), service.ExtractArchiveInput{
+		ProjectID: project.ID,
+		DiskID:    diskID,
+		Path:      dir,
+		Format:    req.Format,
+		File:      file,
+		StripRoot: req.StripRoot,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArchive):
+			c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+		case errors.Is(err, service.ErrArchiveTooLarge), errors.Is(err, service.ErrQuotaExceeded):
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+		default:
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: out})
+}
+
+// DownloadArchive godoc
+//
+//	@Summary		Download archive
+//	@Description	Download every artifact under a directory, at any depth, as a zip archive. Entries are named by their path below the directory. The archive is streamed from storage as it is built.
+//	@Tags			artifact
+//	@Produce		application/zip
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			path	query	string	false	"Directory to download (defaults to '/')"	example(/src/)
+//	@Security		BearerAuth
+//	@Success		200	{file}		binary
+//	@Failure		404	{object}	serializer.Response	"No artifact under the directory"
+//	@Router			/disk/{disk_id}/artifact/archive [get]
+func (h *ArtifactHandler) DownloadArchive(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	dir, ok := archiveDir(c, c.Query("path"))
+	if !ok {
+		return
+	}
+
+	archive, err := h.svc.GetArchive(c.Request.Context(), diskID, dir)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archive.Name + ".zip"}))
+	c.Status(http.StatusOK)
+	if err := h.svc.WriteZip(c.Request.Context(), archive, c.Writer); err != nil {
+		// The response has started: the client sees a truncated archive
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// archiveDir validates the directory of an archive request, "/" when empty,
+// and writes the error response when it isn't a valid directory path.
+func archiveDir(c *gin.Context, dir string) (string, bool) {
+	if dir == "" {
+		return "/", true
+	}
+	if p, _ := path.SplitFilePath(dir); p != dir {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("both ends of the path must be '/'", errors.New("both ends of the path must be '/'")))
+		return "", false
+	}
+	if err := path.ValidatePath(dir); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path", err))
+		return "", false
+	}
+	return dir, true
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArtifactHandler_UploadArchive(t *testing.T) {
+	projectID := uuid.New()
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		fields         map[string]string
+		fileName       string
+		fileSize       int
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+	}{
+		{
+			name:     "expand archive",
+			fields:   map[string]string{"file_path": "/src/", "strip_root": "true"},
+			fileName: "proj.tar.gz",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ExtractArchive", mock.Anything, mock.MatchedBy(func(in service.ExtractArchiveInput) bool {
+					return in.ProjectID == projectID && in.DiskID == diskUUID && in.Path == "/src/" &&
+						in.StripRoot && in.Format == "" && in.File.Filename == "proj.tar.gz"
+				})).Return(&service.ExtractArchiveOutput{Artifacts: []*model.Artifact{{Filename: "main.go"}}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:     "defaults to the root",
+			fields:   map[string]string{"format": "zip"},
+			fileName: "upload.bin",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ExtractArchive", mock.Anything, mock.MatchedBy(func(in service.ExtractArchiveInput) bool {
+					return in.Path == "/" && in.Format == service.ArchiveZip
+				})).Return(&service.ExtractArchiveOutput{}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "unsupported format",
+			fields:         map[string]string{"format": "rar"},
+			fileName:       "files.rar",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "path is not a directory",
+			fields:         map[string]string{"file_path": "/src"},
+			fileName:       "proj.zip",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "archive above size limit",
+			fileName:       "proj.zip",
+			fileSize:       2048,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "unsafe entry",
+			fileName: "proj.zip",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ExtractArchive", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf(`%w: unsafe path "../evil.sh"`, service.ErrInvalidArchive))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "too many files",
+			fileName: "proj.zip",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ExtractArchive", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: more than 10 files", service.ErrArchiveTooLarge))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			cfg := &config.Config{Artifact: config.ArtifactCfg{MaxArchiveSizeBytes: 1024}}
+			handler := NewArtifactHandler(mockSvc, cfg, nil, nil)
+
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			for k, v := range tt.fields {
+				assert.NoError(t, writer.WriteField(k, v))
+			}
+			fileWriter, err := writer.CreateFormFile("file", tt.fileName)
+			assert.NoError(t, err)
+			_, err = fileWriter.Write(make([]byte, max(tt.fileSize, 16)))
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: projectID})
+			req := httptest.NewRequest("POST", "/disk/"+diskID+"/artifact/archive", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			c.Request = req
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.UploadArchive(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_DownloadArchive(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "download directory",
+			url:  "/disk/" + diskID + "/artifact/archive?path=/src/",
+			setupMock: func(svc *MockArtifactService) {
+				archive := &service.ArtifactArchive{Name: "src", Root: "/src/"}
+				svc.On("GetArchive", mock.Anything, diskUUID, "/src/").Return(archive, nil)
+				svc.On("WriteZip", mock.Anything, archive, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+				assert.Equal(t, "attachment; filename=src.zip", w.Header().Get("Content-Disposition"))
+			},
+		},
+		{
+			name: "download disk",
+			url:  "/disk/" + diskID + "/artifact/archive",
+			setupMock: func(svc *MockArtifactService) {
+				archive := &service.ArtifactArchive{Name: diskID, Root: "/"}
+				svc.On("GetArchive", mock.Anything, diskUUID, "/").Return(archive, nil)
+				svc.On("WriteZip", mock.Anything, archive, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "path is not a directory",
+			url:            "/disk/" + diskID + "/artifact/archive?path=/src/main.go",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "empty directory",
+			url:  "/disk/" + diskID + "/artifact/archive?path=/nope/",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GetArchive", mock.Anything, diskUUID, "/nope/").Return(nil, errors.New("path not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.url, nil)
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.DownloadArchive(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
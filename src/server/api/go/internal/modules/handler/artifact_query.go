@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
+)
+
+type QueryArtifactsReq struct {
+	Meta          string    `form:"meta" json:"meta"`   // JSON-encoded object for JSONB containment filter
+	Where         string    `form:"where" json:"where"` // JSON-encoded array of service.MetaCondition
+	MIME          string    `form:"mime" json:"mime" example:"image/*"`
+	MinSize       int64     `form:"min_size" json:"min_size" binding:"min=0" example:"1024"`
+	MaxSize       int64     `form:"max_size" json:"max_size" binding:"min=0" example:"1048576"`
+	UpdatedAfter  time.Time `form:"updated_after" json:"updated_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	UpdatedBefore time.Time `form:"updated_before" json:"updated_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	PathPrefix    string    `form:"path_prefix" json:"path_prefix" example:"/reports/"`
+	Limit         int       `form:"limit,default=20" json:"limit" binding:"required,min=1,max=200" example:"20"`
+	Cursor        string    `form:"cursor" json:"cursor"`
+	TimeDesc      bool      `form:"time_desc,default=false" json:"time_desc" example:"false"`
+}
+
+// QueryArtifacts godoc
+//
+//	@Summary		Query artifacts
+//	@Description	Find the artifacts of a disk by meta, MIME type, size, update time and path prefix, without listing the whole disk. meta matches artifacts whose meta contains the given object. where holds key comparisons: a key, or a dotted path into nested objects, an op among eq, ne, gt, gte, lt, lte and exists, and a value. Ordering ops only match values of the value's JSON type; ne also matches artifacts without the key. Results are ordered by creation time and paginated with a cursor.
+//	@Tags			artifact
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id			path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			meta			query	string	false	"JSON-encoded object for JSONB containment filter. Example: {\"status\":\"draft\"}"
+//	@Param			where			query	string	false	"JSON-encoded array of key comparisons. Example: [{\"key\":\"priority\",\"op\":\"gte\",\"value\":3}]"
+//	@Param			mime			query	string	false	"MIME type, or a type with any subtype such as image/*"
+//	@Param			min_size		query	integer	false	"Minimum size in bytes"
+//	@Param			max_size		query	integer	false	"Maximum size in bytes"
+//	@Param			updated_after	query	string	false	"Only artifacts updated at or after this RFC 3339 time"
+//	@Param			updated_before	query	string	false	"Only artifacts updated before this RFC 3339 time"
+//	@Param			path_prefix		query	string	false	"Only artifacts under this directory, at any depth"	example(/reports/)
+//	@Param			limit			query	integer	false	"Limit of artifacts to return, default 20. Max 200."
+//	@Param			cursor			query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
+//	@Param			time_desc		query	boolean	false	"Order by created_at descending if true, ascending if false (default false)"	example(false)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.QueryArtifactsOutput}
+//	@Failure		400	{object}	serializer.Response	"Malformed filter or cursor"
+//	@Router			/disk/{disk_id}/artifact/query [get]
+func (h *ArtifactHandler) QueryArtifacts(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := QueryArtifactsReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	var meta map[string]interface{}
+	if req.Meta != "" {
+		if err := json.Unmarshal([]byte(req.Meta), &meta); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid meta JSON", err))
+			return
+		}
+	}
+	var where []service.MetaCondition
+	if req.Where != "" {
+		if err := json.Unmarshal([]byte(req.Where), &where); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid where JSON", err))
+			return
+		}
+	}
+	if req.PathPrefix != "" {
+		if p, _ := path.SplitFilePath(req.PathPrefix); p != req.PathPrefix {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("both ends of the path must be '/'", errors.New("both ends of the path must be '/'")))
+			return
+		}
+		if err := path.ValidatePath(req.PathPrefix); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid path_prefix", err))
+			return
+		}
+	}
+
+	out, err := h.svc.QueryArtifacts(c.Request.Context(), service.QueryArtifactsInput{
+		DiskID:        diskID,
+		Meta:          meta,
+		Where:         where,
+		MIME:          req.MIME,
+		MinSizeB:      req.MinSize,
+		MaxSizeB:      req.MaxSize,
+		UpdatedAfter:  req.UpdatedAfter,
+		UpdatedBefore: req.UpdatedBefore,
+		PathPrefix:    req.PathPrefix,
+		Limit:         req.Limit,
+		Cursor:        req.Cursor,
+		TimeDesc:      req.TimeDesc,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArtifactQuery) {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArtifactHandler_QueryArtifacts(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+	after := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          url.Values
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+	}{
+		{
+			name: "all filters",
+			query: url.Values{
+				"meta":          {`{"status":"draft"}`},
+				"where":         {`[{"key":"priority","op":"gte","value":3}]`},
+				"mime":          {"image/*"},
+				"min_size":      {"10"},
+				"max_size":      {"100"},
+				"updated_after": {after.Format(time.RFC3339)},
+				"path_prefix":   {"/reports/"},
+				"limit":         {"50"},
+				"time_desc":     {"true"},
+			},
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("QueryArtifacts", mock.Anything, mock.MatchedBy(func(in service.QueryArtifactsInput) bool {
+					return in.DiskID == diskUUID && in.Meta["status"] == "draft" &&
+						len(in.Where) == 1 && in.Where[0].Key == "priority" && in.Where[0].Op == "gte" && in.Where[0].Value == float64(3) &&
+						in.MIME == "image/*" && in.MinSizeB == 10 && in.MaxSizeB == 100 &&
+						in.UpdatedAfter.Equal(after) && in.UpdatedBefore.IsZero() &&
+						in.PathPrefix == "/reports/" && in.Limit == 50 && in.TimeDesc
+				})).Return(&service.QueryArtifactsOutput{Items: []*model.Artifact{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "defaults",
+			query: url.Values{},
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("QueryArtifacts", mock.Anything, mock.MatchedBy(func(in service.QueryArtifactsInput) bool {
+					return in.Limit == 20 && in.Meta == nil && in.Where == nil && !in.TimeDesc
+				})).Return(&service.QueryArtifactsOutput{Items: []*model.Artifact{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid meta JSON",
+			query:          url.Values{"meta": {"{status"}},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid where JSON",
+			query:          url.Values{"where": {`{"key":"a"}`}},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "path prefix is not a directory",
+			query:          url.Values{"path_prefix": {"/reports"}},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid time",
+			query:          url.Values{"updated_before": {"yesterday"}},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "invalid condition",
+			query: url.Values{"where": {`[{"key":"a","op":"like","value":"x"}]`}},
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("QueryArtifacts", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf(`%w: unknown operator "like"`, service.ErrInvalidArtifactQuery))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/disk/"+diskID+"/artifact/query?"+tt.query.Encode(), nil)
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.QueryArtifacts(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
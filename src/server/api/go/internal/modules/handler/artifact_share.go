@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/path"
+)
+
+// sharePasswordHeader carries the password of a protected share; the password query parameter also works.
+const sharePasswordHeader = "X-Share-Password"
+
+type CreateShareReq struct {
+	FilePath      string `json:"file_path" binding:"required" example:"/reports/q1.pdf"` // An artifact, or a directory when ending with '/'
+	Password      string `json:"password" example:"hunter2"`                             // Optional password required to open the share
+	ExpireSeconds int    `json:"expire_seconds" binding:"min=0" example:"86400"`         // Lifetime of the share, 0 for no expiry
+	MaxDownloads  int    `json:"max_downloads" binding:"min=0" example:"10"`             // Downloads allowed through the share, 0 for no limit
+}
+
+// CreateShare godoc
+//
+//	@Summary		Create share link
+//	@Description	Create a read-only link to an artifact, or to a directory when file_path ends with '/'. The returned token is only shown once; open the share with GET /share/{token}. Shares can expire, require a password and limit their downloads.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			payload	body	handler.CreateShareReq	true	"CreateShare payload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=service.CreateShareOutput}
+//	@Router			/disk/{disk_id}/share [post]
+func (h *ArtifactHandler) CreateShare(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := CreateShareReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	filePath, filename := path.SplitFilePath(req.FilePath)
+	if err := path.ValidatePath(filePath); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid file_path", err))
+		return
+	}
+
+	out, err := h.svc.CreateShare(c.Request.Context(), service.CreateShareInput{
+		ProjectID:    project.ID,
+		DiskID:       diskID,
+		Path:         filePath,
+		Filename:     filename,
+		Password:     req.Password,
+		ExpiresIn:    time.Duration(req.ExpireSeconds) * time.Second,
+		MaxDownloads: req.MaxDownloads,
+	})
+	if err != nil {
+		shareErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: out})
+}
+
+// ListShares godoc
+//
+//	@Summary		List share links
+//	@Description	List the share links of a disk, newest first. Tokens are not included.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=[]model.ArtifactShare}
+//	@Router			/disk/{disk_id}/share [get]
+func (h *ArtifactHandler) ListShares(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	shares, err := h.svc.ListShares(c.Request.Context(), project.ID, diskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: shares})
+}
+
+// DeleteShare godoc
+//
+//	@Summary		Revoke share link
+//	@Description	Revoke a share link. Its token stops working immediately.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id		path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			share_id	path	string	true	"Share ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{}
+//	@Router			/disk/{disk_id}/share/{share_id} [delete]
+func (h *ArtifactHandler) DeleteShare(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+	shareID, err := uuid.Parse(c.Param("share_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid share_id", err))
+		return
+	}
+
+	if err := h.svc.DeleteShare(c.Request.Context(), project.ID, diskID, shareID); err != nil {
+		shareErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{})
+}
+
+type GetSharedReq struct {
+	Path     string `form:"path" json:"path" example:"/q1/summary.pdf"` // Relative to a shared directory
+	Format   string `form:"format" json:"format" binding:"omitempty,oneof=zip" example:"zip"`
+	Password string `form:"password" json:"password"`
+}
+
+// GetShared godoc
+//
+//	@Summary		Open share link
+//	@Description	Open a share link without authentication. A shared artifact is downloaded. For a shared directory, a path ending with '/' (default '/') lists that directory, any other path downloads that file, and format=zip downloads the directory as a zip archive. Downloads count against the share's download limit; listings don't. Protected shares need the password in the X-Share-Password header or the password query parameter.
+//	@Tags			share
+//	@Produce		json
+//	@Produce		octet-stream
+//	@Param			token				path	string	true	"Share token"
+//	@Param			path				query	string	false	"File or directory relative to a shared directory"	example(/q1/summary.pdf)
+//	@Param			format				query	string	false	"zip to download a directory as an archive"
+//	@Param			password			query	string	false	"Password of a protected share"
+//	@Param			X-Share-Password	header	string	false	"Password of a protected share"
+//	@Success		200					{object}	serializer.Response{data=service.SharedListing}	"Or the file or archive content"
+//	@Failure		401					{object}	serializer.Response	"Password missing or incorrect"
+//	@Failure		404					{object}	serializer.Response	"Unknown or revoked share"
+//	@Failure		410					{object}	serializer.Response	"Share expired or download limit reached"
+//	@Router			/share/{token} [get]
+func (h *ArtifactHandler) GetShared(c *gin.Context) {
+	req := GetSharedReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+	password := c.GetHeader(sharePasswordHeader)
+	if password == "" {
+		password = req.Password
+	}
+
+	ctx := c.Request.Context()
+	share, err := h.svc.OpenShare(ctx, c.Param("token"), password)
+	if err != nil {
+		shareErr(c, err)
+		return
+	}
+
+	switch {
+	case req.Format == "zip":
+		archive, err := h.svc.OpenSharedArchive(ctx, share, req.Path)
+		if err != nil {
+			shareErr(c, err)
+			return
+		}
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archive.Name + ".zip"}))
+		c.Status(http.StatusOK)
+		if err := h.svc.WriteZip(ctx, archive, c.Writer); err != nil {
+			// The response has started: the client sees a truncated archive
+			_ = c.Error(err)
+			c.Abort()
+		}
+
+	case share.IsDirectory() && (req.Path == "" || strings.HasSuffix(req.Path, "/")):
+		listing, err := h.svc.ListShared(ctx, share, req.Path)
+		if err != nil {
+			shareErr(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, serializer.Response{Data: listing})
+
+	default:
+		artifact, body, err := h.svc.OpenSharedFile(ctx, share, req.Path)
+		if err != nil {
+			shareErr(c, err)
+			return
+		}
+		defer body.Close()
+
+		asset := artifact.AssetMeta.Data()
+		contentType := asset.MIME
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.DataFromReader(http.StatusOK, asset.SizeB, contentType, body, map[string]string{
+			"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": artifact.Filename}),
+		})
+	}
+}
+
+// shareErr maps share errors to a response status.
+func shareErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSharePassword):
+		c.JSON(http.StatusUnauthorized, serializer.AuthErr(err.Error()))
+	case errors.Is(err, service.ErrShareUnavailable):
+		c.JSON(http.StatusGone, serializer.Err(http.StatusGone, err.Error(), nil))
+	case errors.Is(err, service.ErrInvalidSharePath):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/datatypes"
+)
+
+func TestArtifactHandler_Shares(t *testing.T) {
+	projectID := uuid.New()
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+	shareID := uuid.New()
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		params         gin.Params
+		body           string
+		call           func(*ArtifactHandler, *gin.Context)
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:   "share file",
+			method: "POST",
+			url:    "/disk/" + diskID + "/share",
+			body:   `{"file_path":"/reports/q1.pdf","password":"pw","expire_seconds":60,"max_downloads":3}`,
+			call:   (*ArtifactHandler).CreateShare,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateShare", mock.Anything, mock.MatchedBy(func(in service.CreateShareInput) bool {
+					return in.ProjectID == projectID && in.DiskID == diskUUID && in.Path == "/reports/" &&
+						in.Filename == "q1.pdf" && in.Password == "pw" && in.ExpiresIn.Seconds() == 60 && in.MaxDownloads == 3
+				})).Return(&service.CreateShareOutput{Share: &model.ArtifactShare{ID: shareID}, Token: "tok"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"token":"tok"`)
+			},
+		},
+		{
+			name:   "share directory",
+			method: "POST",
+			url:    "/disk/" + diskID + "/share",
+			body:   `{"file_path":"/reports/"}`,
+			call:   (*ArtifactHandler).CreateShare,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateShare", mock.Anything, mock.MatchedBy(func(in service.CreateShareInput) bool {
+					return in.Path == "/reports/" && in.Filename == ""
+				})).Return(&service.CreateShareOutput{Share: &model.ArtifactShare{ID: shareID}, Token: "tok"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "share with traversal",
+			method:         "POST",
+			url:            "/disk/" + diskID + "/share",
+			body:           `{"file_path":"/../secret/"}`,
+			call:           (*ArtifactHandler).CreateShare,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "share missing artifact",
+			method: "POST",
+			url:    "/disk/" + diskID + "/share",
+			body:   `{"file_path":"/nope.txt"}`,
+			call:   (*ArtifactHandler).CreateShare,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateShare", mock.Anything, mock.Anything).Return(nil, errors.New("artifact not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "list shares",
+			method: "GET",
+			url:    "/disk/" + diskID + "/share",
+			call:   (*ArtifactHandler).ListShares,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ListShares", mock.Anything, projectID, diskUUID).
+					Return([]*model.ArtifactShare{{ID: shareID, TokenHMAC: "secret-hmac", PasswordHashPHC: "secret-phc"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, shareID.String())
+				assert.NotContains(t, body, "secret-hmac")
+				assert.NotContains(t, body, "secret-phc")
+			},
+		},
+		{
+			name:   "revoke share",
+			method: "DELETE",
+			url:    "/disk/" + diskID + "/share/" + shareID.String(),
+			params: gin.Params{{Key: "share_id", Value: shareID.String()}},
+			call:   (*ArtifactHandler).DeleteShare,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DeleteShare", mock.Anything, projectID, diskUUID, shareID).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "revoke unknown share",
+			method: "DELETE",
+			url:    "/disk/" + diskID + "/share/" + shareID.String(),
+			params: gin.Params{{Key: "share_id", Value: shareID.String()}},
+			call:   (*ArtifactHandler).DeleteShare,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DeleteShare", mock.Anything, projectID, diskUUID, shareID).Return(service.ErrShareNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: projectID})
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = append(gin.Params{{Key: "disk_id", Value: diskID}}, tt.params...)
+
+			tt.call(handler, c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_GetShared(t *testing.T) {
+	dirShare := &model.ArtifactShare{ID: uuid.New(), Path: "/reports/"}
+	fileShare := &model.ArtifactShare{ID: uuid.New(), Path: "/reports/", Filename: "q1.csv"}
+	csv := &model.Artifact{
+		Path:      "/reports/",
+		Filename:  "q1.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{MIME: "text/csv", SizeB: 4}),
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		header         string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "download shared file",
+			url:  "/share/tok",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "").Return(fileShare, nil)
+				svc.On("OpenSharedFile", mock.Anything, fileShare, "").Return(csv, io.NopCloser(strings.NewReader("a,b\n")), nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "a,b\n", w.Body.String())
+				assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename=q1.csv`, w.Header().Get("Content-Disposition"))
+			},
+		},
+		{
+			name: "list shared directory",
+			url:  "/share/tok?path=/q1/",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "").Return(dirShare, nil)
+				svc.On("ListShared", mock.Anything, dirShare, "/q1/").
+					Return(&service.SharedListing{Path: "/q1/", Files: []service.SharedFile{{Path: "/q1/", Filename: "a.txt"}}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Contains(t, w.Body.String(), `"filename":"a.txt"`)
+			},
+		},
+		{
+			name:   "download file of shared directory with password header",
+			url:    "/share/tok?path=/q1.csv",
+			header: "pw",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "pw").Return(dirShare, nil)
+				svc.On("OpenSharedFile", mock.Anything, dirShare, "/q1.csv").Return(csv, io.NopCloser(strings.NewReader("a,b\n")), nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "download shared directory as zip",
+			url:  "/share/tok?format=zip&password=pw",
+			setupMock: func(svc *MockArtifactService) {
+				archive := &service.ArtifactArchive{Name: "reports", Root: "/reports/"}
+				svc.On("OpenShare", mock.Anything, "tok", "pw").Return(dirShare, nil)
+				svc.On("OpenSharedArchive", mock.Anything, dirShare, "").Return(archive, nil)
+				svc.On("WriteZip", mock.Anything, archive, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename=reports.zip`, w.Header().Get("Content-Disposition"))
+			},
+		},
+		{
+			name:           "unsupported format",
+			url:            "/share/tok?format=rar",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong password",
+			url:  "/share/tok?password=nope",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "nope").Return(nil, service.ErrSharePassword)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown token",
+			url:  "/share/tok",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "").Return(nil, service.ErrShareNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "download limit reached",
+			url:  "/share/tok",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "").Return(fileShare, nil)
+				svc.On("OpenSharedFile", mock.Anything, fileShare, "").Return(nil, nil, service.ErrShareUnavailable)
+			},
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name: "path outside the share",
+			url:  "/share/tok?path=/../x.txt",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("OpenShare", mock.Anything, "tok", "").Return(dirShare, nil)
+				svc.On("OpenSharedFile", mock.Anything, dirShare, "/../x.txt").Return(nil, nil, service.ErrInvalidSharePath)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set(sharePasswordHeader, tt.header)
+			}
+			c.Request = req
+			c.Params = gin.Params{{Key: "token", Value: "tok"}}
+
+			handler.GetShared(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -4,25 +4,52 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/infra/blob/blobtest"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/grep"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"gorm.io/datatypes"
 )
 
+// artifactRepoStub serves one artifact to a real ArtifactService, for its reads and meta updates
+type artifactRepoStub struct {
+	repo.ArtifactRepo
+	artifact *model.Artifact
+	expected string
+}
+
+func (r *artifactRepoStub) GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error) {
+	a := *r.artifact
+	return &a, nil
+}
+
+func (r *artifactRepoStub) Update(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string) error {
+	r.expected = expectedSHA256
+	r.artifact = a
+	return nil
+}
+
 // MockArtifactService is a mock implementation of ArtifactService
 type MockArtifactService struct {
 	mock.Mock
@@ -68,8 +95,8 @@ func (m *MockArtifactService) GetByDiskID(ctx context.Context, diskID uuid.UUID)
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
-	args := m.Called(ctx, projectID, diskID, path, filename)
+func (m *MockArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, cond service.Preconditions) error {
+	args := m.Called(ctx, projectID, diskID, path, filename, cond)
 	return args.Error(0)
 }
 
@@ -83,8 +110,8 @@ func (m *MockArtifactService) UpdateArtifactByPath(ctx context.Context, diskID u
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error) {
-	args := m.Called(ctx, diskID, path, filename, userMeta)
+func (m *MockArtifactService) UpdateArtifactMetaByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}, cond service.Preconditions) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, userMeta, cond)
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
@@ -96,6 +123,14 @@ func (m *MockArtifactService) GetFileContent(ctx context.Context, artifact *mode
 	return args.Get(0).(*fileparser.FileContent), args.Error(1)
 }
 
+func (m *MockArtifactService) ReadFileContent(ctx context.Context, artifact *model.Artifact, in service.ReadContentInput) (*fileparser.FileContent, *service.ContentRange, error) {
+	args := m.Called(ctx, artifact, in)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*fileparser.FileContent), args.Get(1).(*service.ContentRange), args.Error(2)
+}
+
 func (m *MockArtifactService) GrepArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	args := m.Called(ctx, projectID, diskID, pattern, limit)
 	if args.Get(0) == nil {
@@ -104,6 +139,14 @@ func (m *MockArtifactService) GrepArtifacts(ctx context.Context, projectID uuid.
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactService) GrepContent(ctx context.Context, in service.GrepContentInput) (*service.GrepContentOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.GrepContentOutput), args.Error(1)
+}
+
 func (m *MockArtifactService) GlobArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	args := m.Called(ctx, projectID, diskID, pattern, limit)
 	if args.Get(0) == nil {
@@ -120,6 +163,231 @@ func (m *MockArtifactService) CreateFromBytes(ctx context.Context, in service.Cr
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactService) CreateUpload(ctx context.Context, in service.CreateUploadInput) (*service.UploadTarget, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.UploadTarget), args.Error(1)
+}
+
+func (m *MockArtifactService) CompleteUpload(ctx context.Context, in service.CompleteUploadInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) SweepExpiredUploads(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockArtifactService) ListVersions(ctx context.Context, diskID uuid.UUID, path string, filename string) ([]*model.ArtifactVersion, error) {
+	args := m.Called(ctx, diskID, path, filename)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactVersion), args.Error(1)
+}
+
+func (m *MockArtifactService) GetVersion(ctx context.Context, diskID uuid.UUID, path string, filename string, version int) (*model.ArtifactVersion, error) {
+	args := m.Called(ctx, diskID, path, filename, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactVersion), args.Error(1)
+}
+
+func (m *MockArtifactService) DiffVersions(ctx context.Context, diskID uuid.UUID, path string, filename string, fromVersion int, toVersion int) (*service.ArtifactDiff, error) {
+	args := m.Called(ctx, diskID, path, filename, fromVersion, toVersion)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ArtifactDiff), args.Error(1)
+}
+
+func (m *MockArtifactService) RestoreVersion(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, version int) (*model.Artifact, error) {
+	args := m.Called(ctx, projectID, diskID, path, filename, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) EditArtifact(ctx context.Context, in service.EditArtifactInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) MoveArtifacts(ctx context.Context, in service.TransferArtifactsInput) (*service.TransferArtifactsOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TransferArtifactsOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) CopyArtifacts(ctx context.Context, in service.TransferArtifactsInput) (*service.TransferArtifactsOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.TransferArtifactsOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (int64, error) {
+	args := m.Called(ctx, projectID, diskID, path)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (*model.ArtifactDirectory, error) {
+	args := m.Called(ctx, projectID, diskID, path)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactDirectory), args.Error(1)
+}
+
+func (m *MockArtifactService) GetTree(ctx context.Context, diskID uuid.UUID, path string, depth int) (*service.ArtifactTreeNode, error) {
+	args := m.Called(ctx, diskID, path, depth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ArtifactTreeNode), args.Error(1)
+}
+
+func (m *MockArtifactService) SemanticSearch(ctx context.Context, in service.SemanticSearchInput) ([]*service.SemanticSearchHit, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*service.SemanticSearchHit), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, name string) (*model.DiskSnapshot, error) {
+	args := m.Called(ctx, projectID, diskID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DiskSnapshot), args.Error(1)
+}
+
+func (m *MockArtifactService) ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DiskSnapshot), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error {
+	args := m.Called(ctx, projectID, diskID, snapshotID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactService) RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) (*service.RestoreSnapshotOutput, error) {
+	args := m.Called(ctx, projectID, diskID, snapshotID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.RestoreSnapshotOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateShare(ctx context.Context, in service.CreateShareInput) (*service.CreateShareOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.CreateShareOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactShare), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error {
+	args := m.Called(ctx, projectID, diskID, shareID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactService) OpenShare(ctx context.Context, token string, password string) (*model.ArtifactShare, error) {
+	args := m.Called(ctx, token, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactShare), args.Error(1)
+}
+
+func (m *MockArtifactService) ListShared(ctx context.Context, share *model.ArtifactShare, dir string) (*service.SharedListing, error) {
+	args := m.Called(ctx, share, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.SharedListing), args.Error(1)
+}
+
+func (m *MockArtifactService) OpenSharedFile(ctx context.Context, share *model.ArtifactShare, file string) (*model.Artifact, io.ReadCloser, error) {
+	args := m.Called(ctx, share, file)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*model.Artifact), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockArtifactService) OpenSharedArchive(ctx context.Context, share *model.ArtifactShare, dir string) (*service.ArtifactArchive, error) {
+	args := m.Called(ctx, share, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ArtifactArchive), args.Error(1)
+}
+
+func (m *MockArtifactService) ExtractArchive(ctx context.Context, in service.ExtractArchiveInput) (*service.ExtractArchiveOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ExtractArchiveOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) GetArchive(ctx context.Context, diskID uuid.UUID, dir string) (*service.ArtifactArchive, error) {
+	args := m.Called(ctx, diskID, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ArtifactArchive), args.Error(1)
+}
+
+func (m *MockArtifactService) QueryArtifacts(ctx context.Context, in service.QueryArtifactsInput) (*service.QueryArtifactsOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.QueryArtifactsOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) ListChanges(ctx context.Context, in service.ListDiskChangesInput) (*service.ListDiskChangesOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ListDiskChangesOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) WriteZip(ctx context.Context, archive *service.ArtifactArchive, w io.Writer) error {
+	args := m.Called(ctx, archive, w)
+	return args.Error(0)
+}
+
 // createTestConfig creates a test config with default artifact settings
 func createTestConfig(maxUploadSizeBytes int64) *config.Config {
 	return &config.Config{
@@ -200,6 +468,20 @@ func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 			},
 			expectedStatus: http.StatusRequestEntityTooLarge,
 		},
+		{
+			name:          "storage quota exceeded",
+			diskID:        uuid.New().String(),
+			filePath:      "/test/test.txt",
+			meta:          "",
+			fileContent:   "test content",
+			fileName:      "test.txt",
+			maxUploadSize: 16777216,
+			mockSetup: func(m *MockArtifactService, diskIDStr string, projectID uuid.UUID) {
+				m.On("Create", mock.Anything, mock.Anything).
+					Return((*model.Artifact)(nil), fmt.Errorf("%w: disk would use 2048 of 1024 bytes", service.ErrQuotaExceeded))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
 		{
 			name:          "file size at limit boundary",
 			diskID:        uuid.New().String(),
@@ -314,6 +596,147 @@ func TestArtifactHandler_UpsertArtifact(t *testing.T) {
 	}
 }
 
+func TestArtifactHandler_PresignedUpload(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+	uploadID := uuid.MustParse("223e4567-e89b-12d3-a456-426614174000")
+	sum := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name           string
+		call           func(*ArtifactHandler, *gin.Context)
+		body           string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name: "create single PUT upload",
+			call: (*ArtifactHandler).CreateUploadURL,
+			body: `{"file_path":"/videos/demo.mp4","size":1024,"sha256":"` + sum + `","content_type":"video/mp4","meta":{"k":"v"}}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateUpload", mock.Anything, mock.MatchedBy(func(in service.CreateUploadInput) bool {
+					return in.DiskID == diskUUID && in.Path == "/videos/" && in.Filename == "demo.mp4" &&
+						in.SizeB == 1024 && in.SHA256 == sum && in.ContentType == "video/mp4" &&
+						in.UserMeta["k"] == "v" && in.Expire == time.Hour
+				})).Return(&service.UploadTarget{UploadID: uploadID, URL: "https://s3.example.com/put"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, uploadID.String())
+				assert.Contains(t, body, "https://s3.example.com/put")
+			},
+		},
+		{
+			name:           "create upload too large",
+			call:           (*ArtifactHandler).CreateUploadURL,
+			body:           `{"file_path":"/big.bin","size":1099511627776,"sha256":"` + sum + `"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "create upload without filename",
+			call:           (*ArtifactHandler).CreateUploadURL,
+			body:           `{"file_path":"/videos/","size":10,"sha256":"` + sum + `"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "create upload with bad sha256",
+			call:           (*ArtifactHandler).CreateUploadURL,
+			body:           `{"file_path":"/a.bin","size":10,"sha256":"not-a-hash"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "complete multipart upload",
+			call: (*ArtifactHandler).CompleteUpload,
+			body: `{"upload_id":"` + uploadID.String() + `","parts":[{"part_number":1,"etag":"e1"},{"part_number":2,"etag":"e2"}]}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CompleteUpload", mock.Anything, mock.MatchedBy(func(in service.CompleteUploadInput) bool {
+					return in.DiskID == diskUUID && in.UploadID == uploadID && len(in.Parts) == 2 && in.Parts[1].ETag == "e2"
+				})).Return(&model.Artifact{Path: "/videos/", Filename: "demo.mp4", Version: 1}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, "demo.mp4")
+			},
+		},
+		{
+			name: "complete with mismatching object",
+			call: (*ArtifactHandler).CompleteUpload,
+			body: `{"upload_id":"` + uploadID.String() + `"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CompleteUpload", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: got 10 bytes, expected 1024", service.ErrUploadMismatch))
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "complete expired upload",
+			call: (*ArtifactHandler).CompleteUpload,
+			body: `{"upload_id":"` + uploadID.String() + `"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CompleteUpload", mock.Anything, mock.Anything).Return(nil, service.ErrUploadExpired)
+			},
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name: "complete unknown upload",
+			call: (*ArtifactHandler).CompleteUpload,
+			body: `{"upload_id":"` + uploadID.String() + `"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CompleteUpload", mock.Anything, mock.Anything).Return(nil, service.ErrUploadNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "complete upload another request is completing",
+			call: (*ArtifactHandler).CompleteUpload,
+			body: `{"upload_id":"` + uploadID.String() + `"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CompleteUpload", mock.Anything, mock.Anything).Return(nil, service.ErrUploadInProgress)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "complete with invalid upload_id",
+			call:           (*ArtifactHandler).CompleteUpload,
+			body:           `{"upload_id":"nope"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			cfg := createDefaultTestConfig()
+			cfg.Artifact.MaxDirectUploadSizeBytes = 5 << 30
+			handler := NewArtifactHandler(mockSvc, cfg, nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+
+			req := httptest.NewRequest("POST", "/disk/"+diskID+"/artifact/upload_url", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			tt.call(handler, c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
 func TestArtifactHandler_DeleteArtifact(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -330,7 +753,7 @@ func TestArtifactHandler_DeleteArtifact(t *testing.T) {
 			filePath: "/test/test.txt",
 			mockSetup: func(m *MockArtifactService, diskIDStr string, filePath string, projectID uuid.UUID) {
 				diskID := uuid.MustParse(diskIDStr)
-				m.On("DeleteByPath", mock.Anything, projectID, diskID, "/test/", "test.txt").Return(nil)
+				m.On("DeleteByPath", mock.Anything, projectID, diskID, "/test/", "test.txt", service.Preconditions{}).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -417,7 +840,7 @@ func TestArtifactHandler_UpdateArtifact(t *testing.T) {
 					"description": "Updated report",
 					"version":     "2.0",
 				}
-				m.On("UpdateArtifactMetaByPath", mock.Anything, diskID, "/test/", "report.pdf", expectedMeta).Return(expectedFile, nil)
+				m.On("UpdateArtifactMetaByPath", mock.Anything, diskID, "/test/", "report.pdf", expectedMeta, service.Preconditions{}).Return(expectedFile, nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -492,6 +915,7 @@ func TestArtifactHandler_UpdateArtifact(t *testing.T) {
 			c.Params = []gin.Param{
 				{Key: "disk_id", Value: tt.diskID},
 			}
+			c.Set("project", &model.Project{ID: uuid.New()})
 
 			// Call handler
 			handler.UpdateArtifact(c)
@@ -522,6 +946,7 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 		withPublicURL  bool
 		mockSetup      func(*MockArtifactService, string, string)
 		expectedStatus int
+		unavailable    string // Expected public_url_unavailable
 	}{
 		{
 			name:          "successful artifact retrieval with content",
@@ -598,8 +1023,29 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name:          "invalid disk ID",
-			diskID:        "invalid-uuid",
+			name:          "encrypted artifact has no public URL",
+			diskID:        uuid.New().String(),
+			filePath:      "/test/data.csv",
+			withContent:   false,
+			withPublicURL: true,
+			mockSetup: func(m *MockArtifactService, diskIDStr string, filePath string) {
+				diskID := uuid.MustParse(diskIDStr)
+				expectedFile := &model.Artifact{
+					ID:        uuid.New(),
+					DiskID:    diskID,
+					Path:      "/test/",
+					Filename:  "data.csv",
+					AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "disks/p/data.csv", SHA256: "test-sha256", MIME: "text/csv"}),
+				}
+				m.On("GetByPath", mock.Anything, diskID, "/test/", "data.csv").Return(expectedFile, nil)
+				m.On("GetPresignedURL", mock.Anything, expectedFile, mock.AnythingOfType("time.Duration")).Return("", blob.ErrEncryptedObject)
+			},
+			expectedStatus: http.StatusOK,
+			unavailable:    service.PublicURLEncrypted,
+		},
+		{
+			name:          "invalid disk ID",
+			diskID:        "invalid-uuid",
 			filePath:      "/test/data.csv",
 			withContent:   true,
 			withPublicURL: true,
@@ -663,6 +1109,12 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 					json.Unmarshal(dataBytes, &respData)
 					assert.Contains(t, respData, "content")
 				}
+
+				if tt.unavailable != "" {
+					data := response.Data.(map[string]interface{})
+					assert.Equal(t, tt.unavailable, data["public_url_unavailable"])
+					assert.NotContains(t, data, "public_url")
+				}
 			}
 
 			mockService.AssertExpectations(t)
@@ -670,6 +1122,107 @@ func TestArtifactHandler_GetArtifact(t *testing.T) {
 	}
 }
 
+func TestArtifactHandler_ConditionalRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	projectID := uuid.New()
+	diskID := uuid.New()
+	artifact := &model.Artifact{
+		ID:        uuid.New(),
+		DiskID:    diskID,
+		Path:      "/test/",
+		Filename:  "data.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: "abc", MIME: "text/csv"}),
+	}
+
+	newContext := func(method string, header string, value string) (*gin.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(method, fmt.Sprintf("/disk/%s/artifact?file_path=/test/data.csv&with_public_url=false&with_content=false", diskID), nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = req
+		c.Params = []gin.Param{{Key: "disk_id", Value: diskID.String()}}
+		c.Set("project", &model.Project{ID: projectID})
+		return c, w
+	}
+
+	t.Run("get with a current tag", func(t *testing.T) {
+		m := new(MockArtifactService)
+		m.On("GetByPath", mock.Anything, diskID, "/test/", "data.csv").Return(artifact, nil)
+		c, w := newContext(http.MethodGet, "If-None-Match", "")
+		tag := service.ETag(service.RepresentationTag(artifact, c.Request.URL.RawQuery))
+		c.Request.Header.Set("If-None-Match", tag)
+
+		NewArtifactHandler(m, createDefaultTestConfig(), nil, nil).GetArtifact(c)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Equal(t, tag, w.Header().Get("ETag"))
+		assert.Empty(t, w.Body.String())
+		m.AssertNotCalled(t, "GetFileContent", mock.Anything, mock.Anything)
+	})
+
+	t.Run("get with the content tag only", func(t *testing.T) {
+		m := new(MockArtifactService)
+		m.On("GetByPath", mock.Anything, diskID, "/test/", "data.csv").Return(artifact, nil)
+		c, w := newContext(http.MethodGet, "If-None-Match", `"abc"`)
+
+		NewArtifactHandler(m, createDefaultTestConfig(), nil, nil).GetArtifact(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, service.ETag(service.RepresentationTag(artifact, c.Request.URL.RawQuery)), w.Header().Get("ETag"))
+	})
+
+	t.Run("get with a public URL", func(t *testing.T) {
+		m := new(MockArtifactService)
+		m.On("GetByPath", mock.Anything, diskID, "/test/", "data.csv").Return(artifact, nil)
+		m.On("GetPresignedURL", mock.Anything, artifact, mock.Anything).Return("https://example.com/data.csv", nil)
+		c, w := newContext(http.MethodGet, "If-None-Match", "")
+		c.Request.URL.RawQuery = "file_path=/test/data.csv&with_public_url=true&with_content=false"
+		tag := service.ETag(service.RepresentationTag(artifact, c.Request.URL.RawQuery))
+		c.Request.Header.Set("If-None-Match", "W/"+tag)
+
+		NewArtifactHandler(m, createDefaultTestConfig(), nil, nil).GetArtifact(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "W/"+tag, w.Header().Get("ETag"))
+	})
+
+	// The tag of a read is accepted by writes, with or without a public URL (weak tag)
+	for _, query := range []string{"with_public_url=false&with_content=false", "with_content=false"} {
+		t.Run("get then update with If-Match "+query, func(t *testing.T) {
+			s3, _ := blobtest.New(t)
+			stored := *artifact
+			stored.AssetMeta = datatypes.NewJSONType(model.Asset{SHA256: "abc", MIME: "text/csv", S3Key: "disks/abc.csv"})
+			r := &artifactRepoStub{artifact: &stored}
+			h := NewArtifactHandler(service.NewArtifactService(r, nil, nil, s3, createDefaultTestConfig(), zap.NewNop(), nil), createDefaultTestConfig(), nil, nil)
+
+			c, w := newContext(http.MethodGet, "If-None-Match", "")
+			c.Request.URL.RawQuery = "file_path=/test/data.csv&" + query
+			h.GetArtifact(c)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			tag := w.Header().Get("ETag")
+
+			c, w = newContext(http.MethodPut, "If-Match", tag)
+			c.Request.Body = io.NopCloser(strings.NewReader(`{"file_path":"/test/data.csv","meta":"{\"reviewed\":true}"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			h.UpdateArtifact(c)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, "abc", r.expected, "the update is conditional on the content read")
+		})
+	}
+
+	t.Run("delete with a stale tag", func(t *testing.T) {
+		m := new(MockArtifactService)
+		cond := service.Preconditions{IfMatch: `"old"`}
+		m.On("DeleteByPath", mock.Anything, projectID, diskID, "/test/", "data.csv", cond).Return(service.ErrArtifactModified)
+		c, w := newContext(http.MethodDelete, "If-Match", `"old"`)
+
+		NewArtifactHandler(m, createDefaultTestConfig(), nil, nil).DeleteArtifact(c)
+
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		m.AssertExpectations(t)
+	})
+}
+
 func TestArtifactHandler_GrepArtifacts(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -760,6 +1313,82 @@ func TestArtifactHandler_GrepArtifacts(t *testing.T) {
 	}
 }
 
+func TestArtifactHandler_GrepMatches(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:  "matches with context",
+			query: "query=TODO&output=matches&context=2&after=1&ignore_case=true&include=*.py&include=*.go&max_count=5",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GrepContent", mock.Anything, mock.MatchedBy(func(in service.GrepContentInput) bool {
+					return in.DiskID == diskUUID && in.Pattern == "TODO" && in.IgnoreCase &&
+						in.Before == 2 && in.After == 1 && in.MaxCount == 5 && in.Limit == 100 &&
+						len(in.Include) == 2
+				})).Return(&service.GrepContentOutput{
+					Files: []*service.GrepFile{{
+						Path:    "/main.py",
+						Matches: 1,
+						Lines:   []grep.Line{{Type: grep.LineMatch, LineNumber: 3, Text: "# TODO", Submatches: []grep.Submatch{{Match: "TODO", Start: 2, End: 6}}}},
+					}},
+					Stats: service.GrepStats{Files: 1, Matches: 1},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"line_number":3`)
+				assert.Contains(t, body, `"start":2`)
+			},
+		},
+		{
+			name:  "invalid pattern",
+			query: "query=(x&output=matches",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GrepContent", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: missing closing )", service.ErrInvalidGrepPattern))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "option without output=matches",
+			query:          "query=TODO&context=2",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+
+			c.Request = httptest.NewRequest("GET", "/disk/"+diskID+"/artifact/grep?"+tt.query, nil)
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.GrepArtifacts(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
 func TestArtifactHandler_GlobArtifacts(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -815,6 +1444,17 @@ func TestArtifactHandler_GlobArtifacts(t *testing.T) {
 			setupMock:      func(svc *MockArtifactService) {},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:   "malformed pattern",
+			diskID: "123e4567-e89b-12d3-a456-426614174000",
+			query:  "{a,b",
+			limit:  "10",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GlobArtifacts", mock.Anything, mock.Anything, mock.Anything, "{a,b", 10).
+					Return(nil, fmt.Errorf("%w: syntax error in glob pattern", service.ErrInvalidGlobPattern))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -844,3 +1484,815 @@ func TestArtifactHandler_GlobArtifacts(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifactHandler_ArtifactVersions(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		call           func(*ArtifactHandler, *gin.Context)
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:   "list versions",
+			method: "GET",
+			url:    "/disk/" + diskID + "/artifact/versions?file_path=/notes/todo.md",
+			call:   (*ArtifactHandler).ListArtifactVersions,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ListVersions", mock.Anything, diskUUID, "/notes/", "todo.md").
+					Return([]*model.ArtifactVersion{
+						{Version: 2, Path: "/notes/", Filename: "todo.md", IsCurrent: true},
+						{Version: 1, Path: "/notes/", Filename: "todo.md"},
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"is_current":true`)
+				assert.Contains(t, body, `"version":1`)
+			},
+		},
+		{
+			name:           "list versions without file path",
+			method:         "GET",
+			url:            "/disk/" + diskID + "/artifact/versions",
+			call:           (*ArtifactHandler).ListArtifactVersions,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "get missing version",
+			method: "GET",
+			url:    "/disk/" + diskID + "/artifact/version?file_path=/notes/todo.md&version=9",
+			call:   (*ArtifactHandler).GetArtifactVersion,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GetVersion", mock.Anything, diskUUID, "/notes/", "todo.md", 9).
+					Return(nil, errors.New("artifact version 9 not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "get version without url and content",
+			method: "GET",
+			url:    "/disk/" + diskID + "/artifact/version?file_path=/notes/todo.md&version=1&with_public_url=false&with_content=false",
+			call:   (*ArtifactHandler).GetArtifactVersion,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GetVersion", mock.Anything, diskUUID, "/notes/", "todo.md", 1).
+					Return(&model.ArtifactVersion{Version: 1, Path: "/notes/", Filename: "todo.md"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"version":1`)
+				assert.NotContains(t, body, "public_url")
+			},
+		},
+		{
+			name:   "diff against current",
+			method: "GET",
+			url:    "/disk/" + diskID + "/artifact/diff?file_path=/notes/todo.md&from=1",
+			call:   (*ArtifactHandler).DiffArtifactVersions,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DiffVersions", mock.Anything, diskUUID, "/notes/", "todo.md", 1, 0).
+					Return(&service.ArtifactDiff{FromVersion: 1, ToVersion: 2, Diff: "-old\n+new\n"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"to_version":2`)
+				assert.Contains(t, body, `-old\n+new\n`)
+			},
+		},
+		{
+			name:   "diff unsupported file type",
+			method: "GET",
+			url:    "/disk/" + diskID + "/artifact/diff?file_path=/img/logo.png&from=1&to=2",
+			call:   (*ArtifactHandler).DiffArtifactVersions,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DiffVersions", mock.Anything, diskUUID, "/img/", "logo.png", 1, 2).
+					Return(nil, errors.New("unsupported file type: logo.png (mime: image/png)"))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "restore version",
+			method: "POST",
+			url:    "/disk/" + diskID + "/artifact/restore",
+			body:   `{"file_path":"/notes/todo.md","version":1}`,
+			call:   (*ArtifactHandler).RestoreArtifactVersion,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("RestoreVersion", mock.Anything, mock.Anything, diskUUID, "/notes/", "todo.md", 1).
+					Return(&model.Artifact{Path: "/notes/", Filename: "todo.md", Version: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"version":3`)
+			},
+		},
+		{
+			name:           "restore invalid version",
+			method:         "POST",
+			url:            "/disk/" + diskID + "/artifact/restore",
+			body:           `{"file_path":"/notes/todo.md","version":0}`,
+			call:           (*ArtifactHandler).RestoreArtifactVersion,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			c.Request = req
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			tt.call(handler, c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_MoveCopyArtifacts(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		call           func(*ArtifactHandler, *gin.Context)
+		body           string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name: "move directory",
+			call: (*ArtifactHandler).MoveArtifacts,
+			body: `{"source_path":"/drafts/","destination_path":"/published/"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("MoveArtifacts", mock.Anything, mock.MatchedBy(func(in service.TransferArtifactsInput) bool {
+					return in.DiskID == diskUUID && in.SourcePath == "/drafts/" && in.DestinationPath == "/published/" && in.OnConflict == ""
+				})).Return(&service.TransferArtifactsOutput{
+					Artifacts: []*model.Artifact{{Path: "/published/", Filename: "post.md"}},
+					Skipped:   []string{},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, "post.md")
+			},
+		},
+		{
+			name: "copy conflict",
+			call: (*ArtifactHandler).CopyArtifacts,
+			body: `{"source_path":"/a.txt","destination_path":"/b.txt","on_conflict":"fail"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CopyArtifacts", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: /b.txt", service.ErrArtifactConflict))
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "invalid transfer",
+			call: (*ArtifactHandler).MoveArtifacts,
+			body: `{"source_path":"/a/","destination_path":"/a/b/"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("MoveArtifacts", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: cannot transfer directory /a/ into itself", service.ErrInvalidTransfer))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "missing source",
+			call: (*ArtifactHandler).CopyArtifacts,
+			body: `{"source_path":"/nope/","destination_path":"/b/"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CopyArtifacts", mock.Anything, mock.Anything).
+					Return(nil, errors.New("source /nope/ not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown conflict policy",
+			call:           (*ArtifactHandler).CopyArtifacts,
+			body:           `{"source_path":"/a.txt","destination_path":"/b.txt","on_conflict":"merge"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+
+			req := httptest.NewRequest("POST", "/disk/"+diskID+"/artifact/move", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			tt.call(handler, c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_EditArtifact(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name: "str_replace",
+			body: `{"file_path":"/src/main.go","op":"str_replace","old_str":"foo()","new_str":"bar()","expected_sha256":"abc"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("EditArtifact", mock.Anything, mock.MatchedBy(func(in service.EditArtifactInput) bool {
+					return in.DiskID == diskUUID && in.Path == "/src/" && in.Filename == "main.go" &&
+						in.Op == service.EditOpStrReplace && in.OldStr == "foo()" && in.NewStr == "bar()" && in.ExpectedSHA256 == "abc"
+				})).Return(&model.Artifact{Path: "/src/", Filename: "main.go", Version: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"version":2`)
+			},
+		},
+		{
+			name: "replace lines",
+			body: `{"file_path":"/notes.md","op":"replace_lines","start_line":2,"end_line":4,"text":"x\n"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("EditArtifact", mock.Anything, mock.MatchedBy(func(in service.EditArtifactInput) bool {
+					return in.Path == "/" && in.StartLine == 2 && in.EndLine == 4 && in.Text == "x\n"
+				})).Return(&model.Artifact{Path: "/", Filename: "notes.md", Version: 5}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "edit does not apply",
+			body: `{"file_path":"/src/main.go","op":"str_replace","old_str":"x","new_str":"y"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("EditArtifact", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: old_str occurs 3 times; include more context to make it unique", service.ErrInvalidEdit))
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, "occurs 3 times")
+			},
+		},
+		{
+			name: "modified concurrently",
+			body: `{"file_path":"/src/main.go","op":"apply_diff","diff":"@@ -1 +1 @@\n-a\n+b\n","expected_etag":"e1"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("EditArtifact", mock.Anything, mock.Anything).Return(nil, service.ErrArtifactModified)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name: "artifact not found",
+			body: `{"file_path":"/src/gone.go","op":"insert","text":"x"}`,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("EditArtifact", mock.Anything, mock.Anything).Return(nil, errors.New("record not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown op",
+			body:           `{"file_path":"/src/main.go","op":"append","text":"x"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative line",
+			body:           `{"file_path":"/src/main.go","op":"insert","line":-1,"text":"x"}`,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+
+			req := httptest.NewRequest("POST", "/disk/"+diskID+"/artifact/edit", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.EditArtifact(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_Directories(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		call           func(*ArtifactHandler, *gin.Context)
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:   "delete directory recursively",
+			method: "DELETE",
+			url:    "/disk/" + diskID + "/artifact?file_path=/tmp/&recursive=true",
+			call:   (*ArtifactHandler).DeleteArtifact,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DeleteDirectory", mock.Anything, mock.Anything, diskUUID, "/tmp/").Return(int64(2), nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"deleted":2`)
+			},
+		},
+		{
+			name:   "delete directory on another project's disk",
+			method: "DELETE",
+			url:    "/disk/" + diskID + "/artifact?file_path=/tmp/&recursive=true",
+			call:   (*ArtifactHandler).DeleteArtifact,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DeleteDirectory", mock.Anything, mock.Anything, diskUUID, "/tmp/").Return(int64(0), repo.ErrDiskNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "delete directory without recursive",
+			method:         "DELETE",
+			url:            "/disk/" + diskID + "/artifact?file_path=/tmp/",
+			call:           (*ArtifactHandler).DeleteArtifact,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "create directory",
+			method: "POST",
+			url:    "/disk/" + diskID + "/artifact/mkdir",
+			body:   `{"path":"/drafts/"}`,
+			call:   (*ArtifactHandler).CreateDirectory,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateDirectory", mock.Anything, mock.Anything, diskUUID, "/drafts/").
+					Return(&model.ArtifactDirectory{DiskID: diskUUID, Path: "/drafts/"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "create directory on an unknown disk",
+			method: "POST",
+			url:    "/disk/" + diskID + "/artifact/mkdir",
+			body:   `{"path":"/drafts/"}`,
+			call:   (*ArtifactHandler).CreateDirectory,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateDirectory", mock.Anything, mock.Anything, diskUUID, "/drafts/").
+					Return(nil, repo.ErrDiskNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "create directory with a file path",
+			method:         "POST",
+			url:            "/disk/" + diskID + "/artifact/mkdir",
+			body:           `{"path":"/drafts/a.txt"}`,
+			call:           (*ArtifactHandler).CreateDirectory,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "tree with defaults",
+			method: "GET",
+			url:    "/disk/" + diskID + "/artifact/tree",
+			call:   (*ArtifactHandler).GetArtifactTree,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GetTree", mock.Anything, diskUUID, "/", service.DefaultTreeDepth).Return(&service.ArtifactTreeNode{
+					Name: "/", Path: "/", Type: service.TreeNodeDirectory, FileCount: 1, SizeB: 12,
+					Children: []*service.ArtifactTreeNode{{Name: "a.txt", Path: "/a.txt", Type: service.TreeNodeFile, SizeB: 12}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"file_count":1`)
+				assert.Contains(t, body, "/a.txt")
+			},
+		},
+		{
+			name:           "tree depth out of range",
+			method:         "GET",
+			url:            "/disk/" + diskID + "/artifact/tree?depth=50",
+			call:           (*ArtifactHandler).GetArtifactTree,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			c.Request = req
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			tt.call(handler, c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_SemanticSearchArtifacts(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:  "search with defaults",
+			query: "q=retry+policy",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("SemanticSearch", mock.Anything, service.SemanticSearchInput{DiskID: diskUUID, Query: "retry policy", Limit: 10}).
+					Return([]*service.SemanticSearchHit{{Path: "/docs/", Filename: "ops.md", Content: "retries back off", StartLine: 7, EndLine: 9, Score: 0.82}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"filename":"ops.md"`)
+				assert.Contains(t, body, `"start_line":7`)
+				assert.Contains(t, body, `"score":0.82`)
+			},
+		},
+		{
+			name:  "search under a directory",
+			query: "q=retry&path=/docs/&limit=3",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("SemanticSearch", mock.Anything, service.SemanticSearchInput{DiskID: diskUUID, Query: "retry", Path: "/docs/", Limit: 3}).
+					Return([]*service.SemanticSearchHit{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing query",
+			query:          "",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "path is not a directory",
+			query:          "q=retry&path=/docs/ops.md",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "embeddings not configured",
+			query: "q=retry",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("SemanticSearch", mock.Anything, mock.Anything).Return(nil, service.ErrSemanticSearchDisabled)
+			},
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+			c.Request = httptest.NewRequest("GET", "/disk/"+diskID+"/artifact/search?"+tt.query, nil)
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.SemanticSearchArtifacts(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_GetArtifact_PartialRead(t *testing.T) {
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+	artifact := &model.Artifact{
+		DiskID:    diskUUID,
+		Path:      "/logs/",
+		Filename:  "app.log",
+		AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "k", MIME: "text/plain", SizeB: 4096}),
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:  "tail of lines",
+			query: "&mode=tail&limit=2",
+			setupMock: func(svc *MockArtifactService) {
+				total := int64(120)
+				svc.On("GetByPath", mock.Anything, diskUUID, "/logs/", "app.log").Return(artifact, nil)
+				svc.On("ReadFileContent", mock.Anything, artifact, service.ReadContentInput{Mode: "tail", Limit: 2}).Return(
+					&fileparser.FileContent{Type: "text", Raw: "line 119\nline 120\n"},
+					&service.ContentRange{Unit: "lines", Offset: 118, Count: 2, TotalLines: &total, TotalBytes: 4096},
+					nil,
+				)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"raw":"line 119\nline 120\n"`)
+				assert.Contains(t, body, `"offset":118`)
+				assert.Contains(t, body, `"total_lines":120`)
+			},
+		},
+		{
+			name:  "byte range",
+			query: "&unit=bytes&offset=100&limit=50",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GetByPath", mock.Anything, diskUUID, "/logs/", "app.log").Return(artifact, nil)
+				svc.On("ReadFileContent", mock.Anything, artifact, service.ReadContentInput{Unit: "bytes", Offset: 100, Limit: 50}).Return(
+					&fileparser.FileContent{Type: "text", Raw: "..."},
+					&service.ContentRange{Unit: "bytes", Offset: 100, Count: 50, HasMore: true, TotalBytes: 4096},
+					nil,
+				)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"has_more":true`)
+			},
+		},
+		{
+			name:  "invalid range",
+			query: "&unit=rows",
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("GetByPath", mock.Anything, diskUUID, "/logs/", "app.log").Return(artifact, nil)
+				svc.On("ReadFileContent", mock.Anything, artifact, service.ReadContentInput{Unit: "rows"}).
+					Return(nil, nil, fmt.Errorf("%w: rows can only be read from CSV files", service.ErrInvalidReadRange))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown unit",
+			query:          "&unit=pages",
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: uuid.New()})
+			c.Request = httptest.NewRequest("GET", "/disk/"+diskID+"/artifact?file_path=/logs/app.log&with_public_url=false"+tt.query, nil)
+			c.Params = gin.Params{{Key: "disk_id", Value: diskID}}
+
+			handler.GetArtifact(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_Snapshots(t *testing.T) {
+	projectID := uuid.New()
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+	snapshotID := uuid.New()
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		params         gin.Params
+		body           string
+		call           func(*ArtifactHandler, *gin.Context)
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+		checkBody      func(*testing.T, string)
+	}{
+		{
+			name:   "create named snapshot",
+			method: "POST",
+			url:    "/disk/" + diskID + "/snapshot",
+			body:   `{"name":"before-run"}`,
+			call:   (*ArtifactHandler).CreateSnapshot,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateSnapshot", mock.Anything, projectID, diskUUID, "before-run").
+					Return(&model.DiskSnapshot{ID: snapshotID, DiskID: diskUUID, Name: "before-run", ArtifactCount: 3}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"artifact_count":3`)
+			},
+		},
+		{
+			name:   "create snapshot without payload",
+			method: "POST",
+			url:    "/disk/" + diskID + "/snapshot",
+			call:   (*ArtifactHandler).CreateSnapshot,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateSnapshot", mock.Anything, projectID, diskUUID, "").Return(&model.DiskSnapshot{ID: snapshotID}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "create snapshot of unknown disk",
+			method: "POST",
+			url:    "/disk/" + diskID + "/snapshot",
+			call:   (*ArtifactHandler).CreateSnapshot,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("CreateSnapshot", mock.Anything, projectID, diskUUID, "").Return(nil, errors.New("disk not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "list snapshots",
+			method: "GET",
+			url:    "/disk/" + diskID + "/snapshot",
+			call:   (*ArtifactHandler).ListSnapshots,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ListSnapshots", mock.Anything, projectID, diskUUID).Return([]*model.DiskSnapshot{{ID: snapshotID}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, snapshotID.String())
+			},
+		},
+		{
+			name:   "delete snapshot",
+			method: "DELETE",
+			url:    "/disk/" + diskID + "/snapshot/" + snapshotID.String(),
+			params: gin.Params{{Key: "snapshot_id", Value: snapshotID.String()}},
+			call:   (*ArtifactHandler).DeleteSnapshot,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("DeleteSnapshot", mock.Anything, projectID, diskUUID, snapshotID).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "delete snapshot with invalid id",
+			method:         "DELETE",
+			url:            "/disk/" + diskID + "/snapshot/nope",
+			params:         gin.Params{{Key: "snapshot_id", Value: "nope"}},
+			call:           (*ArtifactHandler).DeleteSnapshot,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "restore snapshot",
+			method: "POST",
+			url:    "/disk/" + diskID + "/restore?snapshot=" + snapshotID.String(),
+			call:   (*ArtifactHandler).RestoreSnapshot,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("RestoreSnapshot", mock.Anything, projectID, diskUUID, snapshotID).
+					Return(&service.RestoreSnapshotOutput{Created: 1, Deleted: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body string) {
+				assert.Contains(t, body, `"deleted":2`)
+			},
+		},
+		{
+			name:           "restore without snapshot",
+			method:         "POST",
+			url:            "/disk/" + diskID + "/restore",
+			call:           (*ArtifactHandler).RestoreSnapshot,
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "restore unknown snapshot",
+			method: "POST",
+			url:    "/disk/" + diskID + "/restore?snapshot=" + snapshotID.String(),
+			call:   (*ArtifactHandler).RestoreSnapshot,
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("RestoreSnapshot", mock.Anything, projectID, diskUUID, snapshotID).Return(nil, errors.New("snapshot not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Set("project", &model.Project{ID: projectID})
+
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = append(gin.Params{{Key: "disk_id", Value: diskID}}, tt.params...)
+
+			tt.call(handler, c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.checkBody != nil {
+				tt.checkBody(t, w.Body.String())
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactHandler_EncryptProjectObjects_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/project/encryption/migrate", nil)
+	c.Set("project", &model.Project{ID: uuid.New()})
+
+	NewArtifactHandler(new(MockArtifactService), createDefaultTestConfig(), nil, nil).EncryptProjectObjects(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
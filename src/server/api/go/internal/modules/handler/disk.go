@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,13 +23,18 @@ func NewDiskHandler(s service.DiskService, userSvc service.UserService) *DiskHan
 }
 
 type CreateDiskReq struct {
-	User string `form:"user" json:"user" example:"alice@acontext.io"`
+	User              string                 `form:"user" json:"user" example:"alice@acontext.io"`
+	Name              string                 `form:"name" json:"name" binding:"max=255" example:"scratch"`
+	Description       string                 `form:"description" json:"description" binding:"max=4096" example:"Scratch space of the research agent"`
+	Meta              map[string]interface{} `form:"meta" json:"meta"`
+	SessionID         string                 `form:"session_id" json:"session_id" binding:"omitempty,uuid" example:"123e4567-e89b-12d3-a456-426614174000"` // Session to link the disk to
+	DeleteWithSession bool                   `form:"delete_with_session" json:"delete_with_session" example:"true"`                                        // Delete the disk when its session is deleted
 }
 
 // CreateDisk godoc
 //
 //	@Summary		Create disk
-//	@Description	Create a disk group under a project. Optionally associate with a user identifier.
+//	@Description	Create a disk group under a project. Optionally associate with a user identifier, give it a name, description and meta, and link it to a session. A disk created with delete_with_session is deleted along with its session; other linked disks are unlinked.
 //	@Tags			disk
 //	@Accept			json
 //	@Produce		json
@@ -60,33 +67,129 @@ func (h *DiskHandler) CreateDisk(c *gin.Context) {
 		userID = &user.ID
 	}
 
-	disk, err := h.svc.Create(c.Request.Context(), project.ID, userID)
+	if err := checkDiskMetaSize(req.Meta); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), nil))
+		return
+	}
+	var sessionID *uuid.UUID
+	if req.SessionID != "" {
+		id := uuid.MustParse(req.SessionID)
+		sessionID = &id
+	}
+
+	disk, err := h.svc.Create(c.Request.Context(), service.CreateDiskInput{
+		ProjectID:         project.ID,
+		UserID:            userID,
+		Name:              req.Name,
+		Description:       req.Description,
+		Meta:              req.Meta,
+		SessionID:         sessionID,
+		DeleteWithSession: req.DeleteWithSession,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		diskErr(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, serializer.Response{Data: disk})
 }
 
+type UpdateDiskReq struct {
+	Name              *string                `json:"name" binding:"omitempty,max=255" example:"scratch"`
+	Description       *string                `json:"description" binding:"omitempty,max=4096" example:"Scratch space of the research agent"`
+	Meta              map[string]interface{} `json:"meta"`                                                      // Merged into the disk meta; a null value deletes its key
+	SessionID         *string                `json:"session_id" example:"123e4567-e89b-12d3-a456-426614174000"` // Session to link the disk to, "" to unlink it
+	DeleteWithSession *bool                  `json:"delete_with_session" example:"true"`
+}
+
+// UpdateDisk godoc
+//
+//	@Summary		Update disk
+//	@Description	Update the name, description, meta or session link of a disk. Only the fields present are changed. Meta is merged into the existing meta; a null value deletes its key. An empty session_id unlinks the disk from its session.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			payload	body	handler.UpdateDiskReq	true	"UpdateDisk payload"
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=model.Disk}
+//	@Router			/disk/{disk_id} [patch]
+func (h *DiskHandler) UpdateDisk(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := UpdateDiskReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	if err := checkDiskMetaSize(req.Meta); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), nil))
+		return
+	}
+	var sessionID *uuid.UUID
+	if req.SessionID != nil {
+		id := uuid.Nil
+		if *req.SessionID != "" {
+			if id, err = uuid.Parse(*req.SessionID); err != nil {
+				c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid session_id", err))
+				return
+			}
+		}
+		sessionID = &id
+	}
+
+	disk, err := h.svc.Update(c.Request.Context(), service.UpdateDiskInput{
+		ProjectID:         project.ID,
+		DiskID:            diskID,
+		Name:              req.Name,
+		Description:       req.Description,
+		Meta:              req.Meta,
+		SessionID:         sessionID,
+		DeleteWithSession: req.DeleteWithSession,
+	})
+	if err != nil {
+		diskErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: disk})
+}
+
 type ListDisksReq struct {
-	User     string `form:"user" json:"user" example:"alice@acontext.io"`
-	Limit    int    `form:"limit,default=20" json:"limit" binding:"required,min=1,max=200" example:"20"`
-	Cursor   string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
-	TimeDesc bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
+	User         string `form:"user" json:"user" example:"alice@acontext.io"`
+	NamePrefix   string `form:"name_prefix" json:"name_prefix" example:"scratch"`
+	FilterByMeta string `form:"filter_by_meta" json:"filter_by_meta"` // JSON-encoded string for JSONB containment filter
+	SessionID    string `form:"session_id" json:"session_id" binding:"omitempty,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Limit        int    `form:"limit,default=20" json:"limit" binding:"required,min=1,max=200" example:"20"`
+	Cursor       string `form:"cursor" json:"cursor" example:"cHJvdGVjdGVkIHZlcnNpb24gdG8gYmUgZXhjbHVkZWQgaW4gcGFyc2luZyB0aGUgY3Vyc29y"`
+	TimeDesc     bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
 }
 
 // ListDisks godoc
 //
 //	@Summary		List disks
-//	@Description	List all disks under a project
+//	@Description	List all disks under a project, optionally filtered by user, name prefix, meta or session
 //	@Tags			disk
 //	@Accept			json
 //	@Produce		json
-//	@Param			user		query	string	false	"User identifier to filter disks"	example(alice@acontext.io)
-//	@Param			limit		query	integer	false	"Limit of disks to return, default 20. Max 200."
-//	@Param			cursor		query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
-//	@Param			time_desc	query	boolean	false	"Order by created_at descending if true, ascending if false (default false)"	example(false)
+//	@Param			user			query	string	false	"User identifier to filter disks"	example(alice@acontext.io)
+//	@Param			name_prefix		query	string	false	"Only disks whose name starts with this prefix"	example(scratch)
+//	@Param			filter_by_meta	query	string	false	"JSON-encoded object for JSONB containment filter. Example: {\"team\":\"research\"}"
+//	@Param			session_id		query	string	false	"Only disks linked to this session"	Format(uuid)
+//	@Param			limit			query	integer	false	"Limit of disks to return, default 20. Max 200."
+//	@Param			cursor			query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
+//	@Param			time_desc		query	boolean	false	"Order by created_at descending if true, ascending if false (default false)"	example(false)
 //	@Security		BearerAuth
 //	@Success		200	{object}	serializer.Response{data=service.ListDisksOutput}
 //	@Router			/disk [get]
@@ -104,12 +207,33 @@ func (h *DiskHandler) ListDisks(c *gin.Context) {
 		return
 	}
 
+	// Parse filter_by_meta JSON string
+	var filterByMeta map[string]interface{}
+	if req.FilterByMeta != "" {
+		if err := json.Unmarshal([]byte(req.FilterByMeta), &filterByMeta); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid filter_by_meta JSON", err))
+			return
+		}
+		// Skip empty object - treat as no filter
+		if len(filterByMeta) == 0 {
+			filterByMeta = nil
+		}
+	}
+	var sessionID *uuid.UUID
+	if req.SessionID != "" {
+		id := uuid.MustParse(req.SessionID)
+		sessionID = &id
+	}
+
 	out, err := h.svc.List(c.Request.Context(), service.ListDisksInput{
-		ProjectID: project.ID,
-		User:      req.User,
-		Limit:     req.Limit,
-		Cursor:    req.Cursor,
-		TimeDesc:  req.TimeDesc,
+		ProjectID:    project.ID,
+		User:         req.User,
+		NamePrefix:   req.NamePrefix,
+		FilterByMeta: filterByMeta,
+		SessionID:    sessionID,
+		Limit:        req.Limit,
+		Cursor:       req.Cursor,
+		TimeDesc:     req.TimeDesc,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
@@ -151,3 +275,166 @@ func (h *DiskHandler) DeleteDisk(c *gin.Context) {
 
 	c.JSON(http.StatusOK, serializer.Response{})
 }
+
+type CloneDiskReq struct {
+	User string `json:"user" example:"alice@acontext.io"` // Owner of the clone, defaults to the source disk's user
+}
+
+// CloneDisk godoc
+//
+//	@Summary		Clone disk
+//	@Description	Create a new disk holding a copy of every artifact of an existing disk, e.g. a scratch disk seeded from a template. S3 content is shared and reference-counted, not duplicated. Version history is not copied.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string					true	"Source disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			payload	body	handler.CloneDiskReq	false	"CloneDisk payload"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.Disk}
+//	@Router			/disk/{disk_id}/clone [post]
+func (h *DiskHandler) CloneDisk(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	req := CloneDiskReq{}
+	// The payload is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+			return
+		}
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	var userID *uuid.UUID
+	if req.User != "" {
+		user, err := h.userSvc.GetOrCreate(c.Request.Context(), project.ID, req.User)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, serializer.DBErr("failed to get or create user", err))
+			return
+		}
+		userID = &user.ID
+	}
+
+	disk, err := h.svc.Clone(c.Request.Context(), project.ID, diskID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: disk})
+}
+
+// GetDiskUsage godoc
+//
+//	@Summary		Get disk usage
+//	@Description	Get the bytes and files stored on a disk, and across the disks of its user, with the configured quotas. Content stored several times counts once; a zero maximum is unlimited.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.DiskUsageOutput}
+//	@Router			/disk/{disk_id}/usage [get]
+func (h *DiskHandler) GetDiskUsage(c *gin.Context) {
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	out, err := h.svc.GetUsage(c.Request.Context(), project.ID, diskID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+type GetProjectUsageReq struct {
+	TopDisks int `form:"top_disks,default=10" json:"top_disks" binding:"min=0,max=100" example:"10"`
+}
+
+// GetProjectUsage godoc
+//
+//	@Summary		Get project usage
+//	@Description	Get the bytes and files stored by the project with the configured quotas, and the disks using the most bytes. Project bytes include message assets; content stored several times counts once.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			top_disks	query	integer	false	"Number of largest disks to return, default 10. Max 100."
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ProjectUsageOutput}
+//	@Router			/project/usage [get]
+func (h *DiskHandler) GetProjectUsage(c *gin.Context) {
+	req := GetProjectUsageReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	out, err := h.svc.GetProjectUsage(c.Request.Context(), project.ID, req.TopDisks)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
+
+// checkDiskMetaSize rejects disk meta larger than MaxMetaSize.
+func checkDiskMetaSize(meta map[string]interface{}) error {
+	if len(meta) == 0 {
+		return nil
+	}
+	metaBytes, _ := json.Marshal(meta)
+	if len(metaBytes) > MaxMetaSize {
+		return errors.New("meta size exceeds 64KB limit")
+	}
+	return nil
+}
+
+// diskErr maps disk errors to a response status.
+func diskErr(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidDisk):
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(err.Error(), err))
+	case strings.Contains(err.Error(), "not found"):
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, err.Error(), nil))
+	default:
+		c.JSON(http.StatusInternalServerError, serializer.DBErr("", err))
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/serializer"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+)
+
+type ListDiskChangesReq struct {
+	Since int64 `form:"since" json:"since" binding:"min=0" example:"0"`
+	Limit int   `form:"limit,default=100" json:"limit" binding:"required,min=1,max=1000" example:"100"`
+}
+
+// ListDiskChanges godoc
+//
+//	@Summary		List disk changes
+//	@Description	List the artifact creates, updates, deletes and moves of a disk after a sequence number, oldest first. Pass next_since as since to resume; sequence numbers have no gaps, so nothing committed is ever skipped. A move across disks is a delete on the source disk and a create on the destination disk.
+//	@Tags			disk
+//	@Accept			json
+//	@Produce		json
+//	@Param			disk_id	path	string	true	"Disk ID"	Format(uuid)	Example(123e4567-e89b-12d3-a456-426614174000)
+//	@Param			since	query	integer	false	"Only changes with a greater sequence number, default 0"	example(0)
+//	@Param			limit	query	integer	false	"Limit of changes to return, default 100. Max 1000."
+//	@Security		BearerAuth
+//	@Success		200	{object}	serializer.Response{data=service.ListDiskChangesOutput}
+//	@Failure		404	{object}	serializer.Response	"Disk not found"
+//	@Router			/disk/{disk_id}/changes [get]
+func (h *ArtifactHandler) ListDiskChanges(c *gin.Context) {
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	diskID, err := uuid.Parse(c.Param("disk_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid disk_id", err))
+		return
+	}
+
+	req := ListDiskChangesReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	out, err := h.svc.ListChanges(c.Request.Context(), service.ListDiskChangesInput{
+		ProjectID: project.ID,
+		DiskID:    diskID,
+		Since:     req.Since,
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		snapshotErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializer.Response{Data: out})
+}
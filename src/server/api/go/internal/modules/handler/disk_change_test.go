@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArtifactHandler_ListDiskChanges(t *testing.T) {
+	projectID := uuid.New()
+	diskID := "123e4567-e89b-12d3-a456-426614174000"
+	diskUUID := uuid.MustParse(diskID)
+
+	tests := []struct {
+		name           string
+		diskID         string
+		query          url.Values
+		setupMock      func(*MockArtifactService)
+		expectedStatus int
+	}{
+		{
+			name:   "since and limit",
+			diskID: diskID,
+			query:  url.Values{"since": {"42"}, "limit": {"10"}},
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ListChanges", mock.Anything, service.ListDiskChangesInput{
+					ProjectID: projectID, DiskID: diskUUID, Since: 42, Limit: 10,
+				}).Return(&service.ListDiskChangesOutput{Items: []*model.DiskChange{}, NextSince: 42}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "defaults",
+			diskID: diskID,
+			query:  url.Values{},
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ListChanges", mock.Anything, service.ListDiskChangesInput{
+					ProjectID: projectID, DiskID: diskUUID, Since: 0, Limit: 100,
+				}).Return(&service.ListDiskChangesOutput{Items: []*model.DiskChange{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "negative since",
+			diskID:         diskID,
+			query:          url.Values{"since": {"-1"}},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "limit too large",
+			diskID:         diskID,
+			query:          url.Values{"limit": {"1001"}},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid disk id",
+			diskID:         "not-a-uuid",
+			query:          url.Values{},
+			setupMock:      func(svc *MockArtifactService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "disk not found",
+			diskID: diskID,
+			query:  url.Values{},
+			setupMock: func(svc *MockArtifactService) {
+				svc.On("ListChanges", mock.Anything, mock.Anything).Return(nil, repo.ErrDiskNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := new(MockArtifactService)
+			tt.setupMock(mockSvc)
+
+			handler := NewArtifactHandler(mockSvc, createDefaultTestConfig(), nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/disk/"+tt.diskID+"/changes?"+tt.query.Encode(), nil)
+			c.Params = gin.Params{{Key: "disk_id", Value: tt.diskID}}
+			c.Set("project", &model.Project{ID: projectID})
+
+			handler.ListDiskChanges(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
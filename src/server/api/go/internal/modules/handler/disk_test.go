@@ -3,8 +3,11 @@ package handler
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,8 +25,16 @@ type MockDiskService struct {
 	mock.Mock
 }
 
-func (m *MockDiskService) Create(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
-	args := m.Called(ctx, projectID, userID)
+func (m *MockDiskService) Create(ctx context.Context, in service.CreateDiskInput) (*model.Disk, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskService) Update(ctx context.Context, in service.UpdateDiskInput) (*model.Disk, error) {
+	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -35,6 +46,14 @@ func (m *MockDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskI
 	return args.Error(0)
 }
 
+func (m *MockDiskService) Clone(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, diskID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
 func (m *MockDiskService) List(ctx context.Context, in service.ListDisksInput) (*service.ListDisksOutput, error) {
 	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
@@ -43,6 +62,22 @@ func (m *MockDiskService) List(ctx context.Context, in service.ListDisksInput) (
 	return args.Get(0).(*service.ListDisksOutput), args.Error(1)
 }
 
+func (m *MockDiskService) GetUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*service.DiskUsageOutput, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.DiskUsageOutput), args.Error(1)
+}
+
+func (m *MockDiskService) GetProjectUsage(ctx context.Context, projectID uuid.UUID, topDisks int) (*service.ProjectUsageOutput, error) {
+	args := m.Called(ctx, projectID, topDisks)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ProjectUsageOutput), args.Error(1)
+}
+
 func setupDiskRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	return gin.New()
@@ -74,14 +109,14 @@ func TestDiskHandler_CreateDisk(t *testing.T) {
 		{
 			name: "successful disk creation",
 			setup: func(svc *MockDiskService) {
-				svc.On("Create", mock.Anything, projectID, mock.Anything).Return(disk, nil)
+				svc.On("Create", mock.Anything, service.CreateDiskInput{ProjectID: projectID}).Return(disk, nil)
 			},
 			expectedStatus: http.StatusCreated,
 		},
 		{
 			name: "service error",
 			setup: func(svc *MockDiskService) {
-				svc.On("Create", mock.Anything, projectID, mock.Anything).Return(nil, errors.New("service error"))
+				svc.On("Create", mock.Anything, service.CreateDiskInput{ProjectID: projectID}).Return(nil, errors.New("service error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -205,6 +240,169 @@ func TestDiskHandler_ListDisks(t *testing.T) {
 	}
 }
 
+func TestDiskHandler_ListDisks_Filters(t *testing.T) {
+	projectID := uuid.New()
+	sessionID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockDiskService)
+		expectedStatus int
+	}{
+		{
+			name:  "name prefix, meta and session",
+			query: "?name_prefix=scratch&filter_by_meta=" + url.QueryEscape(`{"team":"research"}`) + "&session_id=" + sessionID.String(),
+			setup: func(svc *MockDiskService) {
+				svc.On("List", mock.Anything, mock.MatchedBy(func(in service.ListDisksInput) bool {
+					return in.NamePrefix == "scratch" && in.FilterByMeta["team"] == "research" &&
+						in.SessionID != nil && *in.SessionID == sessionID
+				})).Return(&service.ListDisksOutput{Items: []*model.Disk{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "empty meta filter is ignored",
+			query: "?filter_by_meta=%7B%7D",
+			setup: func(svc *MockDiskService) {
+				svc.On("List", mock.Anything, mock.MatchedBy(func(in service.ListDisksInput) bool {
+					return in.FilterByMeta == nil && in.SessionID == nil
+				})).Return(&service.ListDisksOutput{Items: []*model.Disk{}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid meta filter",
+			query:          "?filter_by_meta=" + url.QueryEscape("{team"),
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid session_id",
+			query:          "?session_id=nope",
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			tt.setup(mockService)
+			handler := NewDiskHandler(mockService, &MockUserService{})
+
+			router := setupDiskRouter()
+			router.GET("/disk", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.ListDisks(c)
+			})
+
+			req := httptest.NewRequest("GET", "/disk"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskHandler_UpdateDisk(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	sessionID := uuid.New()
+	disk := createTestDisk()
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func(*MockDiskService)
+		expectedStatus int
+	}{
+		{
+			name: "update name and meta",
+			body: `{"name":"scratch","meta":{"team":"research","old":null}}`,
+			setup: func(svc *MockDiskService) {
+				svc.On("Update", mock.Anything, mock.MatchedBy(func(in service.UpdateDiskInput) bool {
+					_, hasOld := in.Meta["old"]
+					return in.ProjectID == projectID && in.DiskID == diskID &&
+						in.Name != nil && *in.Name == "scratch" && in.Description == nil &&
+						in.Meta["team"] == "research" && hasOld && in.SessionID == nil
+				})).Return(disk, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "link session",
+			body: `{"session_id":"` + sessionID.String() + `","delete_with_session":true}`,
+			setup: func(svc *MockDiskService) {
+				svc.On("Update", mock.Anything, mock.MatchedBy(func(in service.UpdateDiskInput) bool {
+					return in.SessionID != nil && *in.SessionID == sessionID &&
+						in.DeleteWithSession != nil && *in.DeleteWithSession
+				})).Return(disk, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "unlink session",
+			body: `{"session_id":""}`,
+			setup: func(svc *MockDiskService) {
+				svc.On("Update", mock.Anything, mock.MatchedBy(func(in service.UpdateDiskInput) bool {
+					return in.SessionID != nil && *in.SessionID == uuid.Nil
+				})).Return(disk, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid session_id",
+			body:           `{"session_id":"nope"}`,
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "reserved meta key",
+			body: `{"meta":{"__disk_info__":1}}`,
+			setup: func(svc *MockDiskService) {
+				svc.On("Update", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: reserved key '__disk_info__' is not allowed in meta", service.ErrInvalidDisk))
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "disk not found",
+			body: `{"name":"scratch"}`,
+			setup: func(svc *MockDiskService) {
+				svc.On("Update", mock.Anything, mock.Anything).Return(nil, errors.New("disk not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			tt.setup(mockService)
+			handler := NewDiskHandler(mockService, &MockUserService{})
+
+			router := setupDiskRouter()
+			router.PATCH("/disk/:disk_id", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.UpdateDisk(c)
+			})
+
+			req := httptest.NewRequest("PATCH", "/disk/"+diskID.String(), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDiskHandler_DeleteDisk(t *testing.T) {
 	projectID := uuid.New()
 	diskID := uuid.New()
@@ -273,3 +471,211 @@ func TestDiskHandler_DeleteDisk(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskHandler_CloneDisk(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	userID := uuid.New()
+	clone := createTestDisk()
+	clone.ProjectID = projectID
+
+	tests := []struct {
+		name           string
+		diskID         string
+		body           string
+		setup          func(*MockDiskService, *MockUserService)
+		expectedStatus int
+	}{
+		{
+			name:   "clone without payload keeps source user",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService, userSvc *MockUserService) {
+				svc.On("Clone", mock.Anything, projectID, diskID, (*uuid.UUID)(nil)).Return(clone, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "clone for user",
+			diskID: diskID.String(),
+			body:   `{"user":"alice@acontext.io"}`,
+			setup: func(svc *MockDiskService, userSvc *MockUserService) {
+				userSvc.On("GetOrCreate", mock.Anything, projectID, "alice@acontext.io").Return(&model.User{ID: userID}, nil)
+				svc.On("Clone", mock.Anything, projectID, diskID, &userID).Return(clone, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "source disk not found",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService, userSvc *MockUserService) {
+				svc.On("Clone", mock.Anything, projectID, diskID, (*uuid.UUID)(nil)).Return(nil, errors.New("disk not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "quota exceeded",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService, userSvc *MockUserService) {
+				svc.On("Clone", mock.Anything, projectID, diskID, (*uuid.UUID)(nil)).
+					Return(nil, fmt.Errorf("clone disk: %w", service.ErrQuotaExceeded))
+			},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "invalid disk ID",
+			diskID:         "invalid-uuid",
+			setup:          func(svc *MockDiskService, userSvc *MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			mockUserService := &MockUserService{}
+			tt.setup(mockService, mockUserService)
+			handler := NewDiskHandler(mockService, mockUserService)
+
+			router := setupDiskRouter()
+			router.POST("/disk/:disk_id/clone", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.CloneDisk(c)
+			})
+
+			req := httptest.NewRequest("POST", "/disk/"+tt.diskID+"/clone", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+			mockUserService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskHandler_GetDiskUsage(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	tests := []struct {
+		name           string
+		diskID         string
+		setup          func(*MockDiskService)
+		expectedStatus int
+	}{
+		{
+			name:   "usage of disk",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService) {
+				svc.On("GetUsage", mock.Anything, projectID, diskID).Return(&service.DiskUsageOutput{
+					DiskID: diskID,
+					Disk:   service.Usage{Bytes: 2048, Files: 3},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "disk not found",
+			diskID: diskID.String(),
+			setup: func(svc *MockDiskService) {
+				svc.On("GetUsage", mock.Anything, projectID, diskID).Return(nil, errors.New("disk not found"))
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid disk ID",
+			diskID:         "invalid-uuid",
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			tt.setup(mockService)
+			handler := NewDiskHandler(mockService, &MockUserService{})
+
+			router := setupDiskRouter()
+			router.GET("/disk/:disk_id/usage", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.GetDiskUsage(c)
+			})
+
+			req := httptest.NewRequest("GET", "/disk/"+tt.diskID+"/usage", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskHandler_GetProjectUsage(t *testing.T) {
+	projectID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		setup          func(*MockDiskService)
+		expectedStatus int
+	}{
+		{
+			name: "default top disks",
+			setup: func(svc *MockDiskService) {
+				svc.On("GetProjectUsage", mock.Anything, projectID, 10).Return(&service.ProjectUsageOutput{
+					Project:   service.Usage{Bytes: 4096, Files: 7, MaxBytes: 1 << 30},
+					DiskCount: 2,
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:  "custom top disks",
+			query: "?top_disks=3",
+			setup: func(svc *MockDiskService) {
+				svc.On("GetProjectUsage", mock.Anything, projectID, 3).Return(&service.ProjectUsageOutput{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "top disks out of range",
+			query:          "?top_disks=1000",
+			setup:          func(svc *MockDiskService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "service error",
+			setup: func(svc *MockDiskService) {
+				svc.On("GetProjectUsage", mock.Anything, projectID, 10).Return(nil, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &MockDiskService{}
+			tt.setup(mockService)
+			handler := NewDiskHandler(mockService, &MockUserService{})
+
+			router := setupDiskRouter()
+			router.GET("/project/usage", func(c *gin.Context) {
+				c.Set("project", &model.Project{ID: projectID})
+				handler.GetProjectUsage(c)
+			})
+
+			req := httptest.NewRequest("GET", "/project/usage"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
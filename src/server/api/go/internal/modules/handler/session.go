@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/serializer"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/assembler"
 	"github.com/memodb-io/Acontext/internal/pkg/converter"
 	"github.com/memodb-io/Acontext/internal/pkg/editor"
 	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
@@ -185,7 +187,7 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 // DeleteSession godoc
 //
 //	@Summary		Delete session
-//	@Description	Delete a session by id
+//	@Description	Delete a session by id. Disks linked to it with delete_with_session are deleted too; other linked disks are unlinked.
 //	@Tags			session
 //	@Accept			json
 //	@Produce		json
@@ -306,6 +308,7 @@ type StoreMessageReq struct {
 //	@Param			file		formData	file					false	"When uploading files, the field name must correspond to parts[*].file_field."
 //	@Security		BearerAuth
 //	@Success		201	{object}	serializer.Response{data=model.Message}
+//	@Failure		413	{object}	serializer.Response	"Uploaded files would exceed the project storage quota"
 //	@Router			/session/{session_id}/messages [post]
 //	@x-code-samples	[{"lang":"python","source":"from acontext import AcontextClient\nfrom acontext.messages import build_acontext_message\n\nclient = AcontextClient(api_key='sk_project_token')\n\n# Store a message in OpenAI format with user metadata\nclient.sessions.store_message(\n    session_id='session-uuid',\n    blob={'role': 'user', 'content': 'Hello!'},\n    format='openai',\n    meta={'source': 'web', 'request_id': 'abc123'}\n)\n\n# Store a message in Acontext format\nmessage = build_acontext_message(role='user', parts=['Hello!'])\nclient.sessions.store_message(\n    session_id='session-uuid',\n    blob=message,\n    format='acontext'\n)\n","label":"Python"},{"lang":"javascript","source":"import { AcontextClient, MessagePart } from '@acontext/acontext';\n\nconst client = new AcontextClient({ apiKey: 'sk_project_token' });\n\n// Store a message in OpenAI format with user metadata\nawait client.sessions.storeMessage(\n  'session-uuid',\n  { role: 'user', content: 'Hello!' },\n  { format: 'openai', meta: { source: 'web', request_id: 'abc123' } }\n);\n\n// Store a message in Acontext format\nawait client.sessions.storeMessage(\n  'session-uuid',\n  {\n    role: 'user',\n    parts: [MessagePart.textPart('Hello!')]\n  },\n  { format: 'acontext' }\n);\n","label":"JavaScript"}]
 func (h *SessionHandler) StoreMessage(c *gin.Context) {
@@ -424,6 +427,194 @@ func (h *SessionHandler) StoreMessage(c *gin.Context) {
 		MessageMeta: normalizedMeta,
 		Files:       fileMap,
 	})
+	if err != nil {
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, err.Error(), nil))
+			return
+		}
+		c.JSON(http.StatusBadRequest, serializer.DBErr("", err))
+		return
+	}
+
+	// Extract user meta for response (hide internal __user_meta__ wrapper from users)
+	responseMeta := converter.ExtractUserMeta(out.Meta.Data())
+	out.Meta = datatypes.NewJSONType(responseMeta)
+
+	c.JSON(http.StatusCreated, serializer.Response{Data: out})
+}
+
+type StreamMessageReq struct {
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=openai anthropic gemini" example:"openai" enums:"openai,anthropic,gemini"`
+	Meta   string `form:"meta" json:"meta" example:"{\"source\":\"web\"}"` // Optional JSON-encoded user-provided metadata for the message
+}
+
+// maxStreamSize is the maximum size of a message stream body (32MB): its chunks are assembled in memory
+var maxStreamSize int64 = 32 << 20
+
+// streamSnapshotInterval is the minimum delay between two in-progress snapshots of a streamed message
+const streamSnapshotInterval = 500 * time.Millisecond
+
+// StreamMessage godoc
+//
+//	@Summary		Stream message to session
+//	@Description	Store an assistant message from the raw streaming chunks of a provider. The request body is the provider stream, either as Server-Sent Events (data: lines) or as newline-delimited JSON: OpenAI ChatCompletionChunk, Anthropic message stream events, or Gemini GenerateContentResponse chunks. The chunks are assembled server-side into a complete message which is stored once the stream ends. While the stream is open, the partial message is visible via get_messages(include_in_progress=true) with status in_progress.
+//	@Tags			session
+//	@Accept			text/event-stream
+//	@Accept			application/x-ndjson
+//	@Produce		json
+//	@Param			session_id	path	string	true	"Session ID"	Format(uuid)
+//	@Param			format		query	string	false	"Format of the stream chunks (default: openai)"	enums(openai,anthropic,gemini)
+//	@Param			meta		query	string	false	"JSON-encoded user-provided metadata for the message"
+//	@Security		BearerAuth
+//	@Success		201	{object}	serializer.Response{data=model.Message}
+//	@Failure		413	{object}	serializer.Response	"The stream is larger than 32MB"
+//	@Router			/session/{session_id}/messages/stream [post]
+//	@x-code-samples	[{"lang":"python","source":"import httpx\n\n# Forward the raw provider stream to Acontext while consuming it\nwith httpx.stream(\n    'POST',\n    'https://api.acontext.io/api/v1/session/session-uuid/messages/stream?format=openai',\n    headers={'Authorization': 'Bearer sk_project_token', 'Content-Type': 'text/event-stream'},\n    content=provider_stream_bytes(),\n) as resp:\n    print(resp.read())\n","label":"Python"},{"lang":"javascript","source":"// Forward the raw provider stream to Acontext\nconst resp = await fetch(\n  'https://api.acontext.io/api/v1/session/session-uuid/messages/stream?format=anthropic',\n  {\n    method: 'POST',\n    headers: { Authorization: 'Bearer sk_project_token', 'Content-Type': 'text/event-stream' },\n    body: providerStream,\n    duplex: 'half'\n  }\n);\nconsole.log(await resp.json());\n","label":"JavaScript"}]
+func (h *SessionHandler) StreamMessage(c *gin.Context) {
+	req := StreamMessageReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	formatStr := req.Format
+	if formatStr == "" {
+		formatStr = string(model.FormatOpenAI)
+	}
+
+	format, err := converter.ValidateFormat(formatStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid format", err))
+		return
+	}
+
+	asm, err := assembler.GetAssembler(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("unsupported format", err))
+		return
+	}
+
+	norm, err := normalizer.GetNormalizer(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("unsupported format", err))
+		return
+	}
+
+	var userMeta map[string]interface{}
+	if req.Meta != "" {
+		if len(req.Meta) > MaxMetaSize {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("meta size exceeds 64KB limit", nil))
+			return
+		}
+		if err := sonic.Unmarshal([]byte(req.Meta), &userMeta); err != nil {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid meta json", err))
+			return
+		}
+	}
+
+	project, ok := c.MustGet("project").(*model.Project)
+	if !ok {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("project not found")))
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", err))
+		return
+	}
+
+	// Check the session up front: in-progress snapshots are visible before StoreMessage validates it
+	session, err := h.svc.GetByID(c.Request.Context(), &model.Session{ID: sessionID})
+	if err != nil || session.ProjectID != project.ID {
+		c.JSON(http.StatusNotFound, serializer.Err(http.StatusNotFound, "session not found", err))
+		return
+	}
+
+	// Pre-allocate the message ID so the in-progress snapshot and the stored message share it
+	messageID := uuid.New()
+	startedAt := time.Now()
+	defer func() {
+		_ = h.svc.DeleteInProgressMessage(context.WithoutCancel(c.Request.Context()), sessionID, messageID)
+	}()
+
+	buildInput := func(blob json.RawMessage) (service.StoreMessageInput, error) {
+		role, parts, meta, err := norm.Normalize(blob)
+		if err != nil {
+			return service.StoreMessageInput{}, err
+		}
+		if len(userMeta) > 0 {
+			if meta == nil {
+				meta = make(map[string]interface{})
+			}
+			meta[model.UserMetaKey] = userMeta
+		}
+		return service.StoreMessageInput{
+			ProjectID:   project.ID,
+			SessionID:   sessionID,
+			MessageID:   messageID,
+			Role:        role,
+			Parts:       parts,
+			Format:      format,
+			MessageMeta: meta,
+		}, nil
+	}
+
+	var lastSnapshot time.Time
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxStreamSize)
+	err = assembler.ReadChunks(body, func(chunk json.RawMessage) error {
+		if err := asm.AddChunk(chunk); err != nil {
+			return err
+		}
+		if time.Since(lastSnapshot) < streamSnapshotInterval {
+			return nil
+		}
+		lastSnapshot = time.Now()
+
+		// Snapshots are best effort: a partial message that does not normalize yet is skipped
+		blob, err := asm.Message()
+		if err != nil {
+			return nil
+		}
+		in, err := buildInput(blob)
+		if err != nil || len(in.Parts) == 0 {
+			return nil
+		}
+		_ = h.svc.SaveInProgressMessage(c.Request.Context(), in, startedAt)
+		return nil
+	})
+	// The scanner hands over the line cut at the limit before its read error, so ask the body
+	var tooLarge *http.MaxBytesError
+	if _, readErr := body.Read(nil); errors.As(readErr, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, serializer.Err(http.StatusRequestEntityTooLarge, fmt.Sprintf("stream exceeds %d bytes", tooLarge.Limit), nil))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid stream", err))
+		return
+	}
+	if !asm.Done() {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("stream ended before the message was complete")))
+		return
+	}
+
+	blob, err := asm.Message()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("failed to assemble message", err))
+		return
+	}
+
+	in, err := buildInput(blob)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr(fmt.Sprintf("failed to normalize %s message", format), err))
+		return
+	}
+	if len(in.Parts) == 0 {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("", errors.New("stream produced no message content")))
+		return
+	}
+
+	out, err := h.svc.StoreMessage(c.Request.Context(), in)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.DBErr("", err))
 		return
@@ -444,6 +635,8 @@ type GetMessagesReq struct {
 	TimeDesc                      bool   `form:"time_desc,default=false" json:"time_desc" example:"false"`
 	EditStrategies                string `form:"edit_strategies" json:"edit_strategies" example:"[{\"type\":\"remove_tool_result\",\"params\":{\"keep_recent_n_tool_results\":3}}]"`
 	PinEditingStrategiesAtMessage string `form:"pin_editing_strategies_at_message" json:"pin_editing_strategies_at_message" example:""`
+	Repair                        bool   `form:"repair,default=false" json:"repair" example:"false"`
+	IncludeInProgress             bool   `form:"include_in_progress,default=false" json:"include_in_progress" example:"false"`
 }
 
 // GetMessages godoc
@@ -455,6 +648,8 @@ type GetMessagesReq struct {
 //	@Produce		json
 //	@Param			session_id							path	string	true	"Session ID"	format(uuid)
 //	@Param			limit								query	integer	false	"Limit of messages to return. Max 200. If limit is 0 or not provided, all messages will be returned. \n\nWARNING!\n Use `limit` only for read-only/display purposes (pagination, viewing). Do NOT use `limit` to truncate messages before sending to LLM as it may cause tool-call and tool-result unpairing issues. Instead, use the `token_limit` edit strategy in `edit_strategies` parameter to safely manage message context size."
+//	@Param			repair								query	boolean	false	"Repair the history before conversion: synthesize error results for dangling tool-calls, drop orphaned tool-results and, for anthropic and gemini, merge consecutive same-role messages. Requires the full history (no cursor, no further pages); in-progress messages are left as is. The response will include a repair report when anything changed."	example(false)
+//	@Param			include_in_progress					query	boolean	false	"Include messages that are still being streamed via the stream endpoint. They are appended after the newest stored messages and reported with status in_progress in the statuses field."	example(false)
 //	@Param			cursor								query	string	false	"Cursor for pagination. Use the cursor from the previous response to get the next page."
 //	@Param			with_asset_public_url				query	boolean	false	"Whether to return asset public url, default is true"																																																																							example(true)
 //	@Param			format								query	string	false	"Format to convert messages to: acontext (original), openai (default), anthropic, gemini."																																																														enums(acontext,openai,anthropic,gemini)
@@ -493,6 +688,18 @@ func (h *SessionHandler) GetMessages(c *gin.Context) {
 		}
 	}
 
+	// Messages are converted to the specified format (default: openai)
+	formatStr := req.Format
+	if formatStr == "" {
+		formatStr = string(model.FormatOpenAI)
+	}
+
+	format, err := converter.ValidateFormat(formatStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid format", err))
+		return
+	}
+
 	out, err := h.svc.GetMessages(c.Request.Context(), service.GetMessagesInput{
 		SessionID:                     sessionID,
 		Limit:                         limit,
@@ -502,22 +709,26 @@ func (h *SessionHandler) GetMessages(c *gin.Context) {
 		TimeDesc:                      req.TimeDesc,
 		EditStrategies:                editStrategies,
 		PinEditingStrategiesAtMessage: req.PinEditingStrategiesAtMessage,
+		IncludeInProgress:             req.IncludeInProgress,
+		InlineEncryptedAssets:         format != model.FormatAcontext,
 	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, serializer.DBErr("", err))
 		return
 	}
 
-	// Convert messages to specified format (default: openai)
-	formatStr := req.Format
-	if formatStr == "" {
-		formatStr = string(model.FormatOpenAI)
-	}
-
-	format, err := converter.ValidateFormat(formatStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, serializer.ParamErr("invalid format", err))
-		return
+	// Repair dangling tool-calls and role alternation if requested. A page can't tell a
+	// dangling tool-call from one answered on another page, so repair needs the full history.
+	var repairReport *converter.RepairReport
+	if req.Repair {
+		if req.Cursor != "" || out.HasMore {
+			c.JSON(http.StatusBadRequest, serializer.ParamErr("repair requires the full history: omit cursor, and limit or set it above the message count", errors.New("repair requires the full history")))
+			return
+		}
+		// Partially streamed messages may still receive their tool-results
+		stored, inProgress := splitInProgress(out.Items)
+		stored, repairReport = converter.RepairMessages(stored, format)
+		out.Items = append(stored, inProgress...)
 	}
 
 	// Calculate token count for the returned messages
@@ -540,10 +751,26 @@ func (h *SessionHandler) GetMessages(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, serializer.DBErr("failed to convert messages", err))
 		return
 	}
+	if repairReport.Changed() {
+		convertedOut.Repair = repairReport
+	}
+	convertedOut.PublicURLsUnavailable = out.PublicURLsUnavailable
 
 	c.JSON(http.StatusOK, serializer.Response{Data: convertedOut})
 }
 
+// splitInProgress separates partially streamed messages from stored ones, keeping their order.
+func splitInProgress(items []model.Message) (stored []model.Message, inProgress []model.Message) {
+	for _, m := range items {
+		if m.Status == model.MessageStatusInProgress {
+			inProgress = append(inProgress, m)
+		} else {
+			stored = append(stored, m)
+		}
+	}
+	return stored, inProgress
+}
+
 // SessionFlush godoc
 //
 //	@Summary		Flush session
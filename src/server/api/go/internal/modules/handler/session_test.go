@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
@@ -59,6 +60,16 @@ func (m *MockSessionService) StoreMessage(ctx context.Context, in service.StoreM
 	return args.Get(0).(*model.Message), args.Error(1)
 }
 
+func (m *MockSessionService) SaveInProgressMessage(ctx context.Context, in service.StoreMessageInput, startedAt time.Time) error {
+	args := m.Called(ctx, in, startedAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionService) DeleteInProgressMessage(ctx context.Context, sessionID uuid.UUID, messageID uuid.UUID) error {
+	args := m.Called(ctx, sessionID, messageID)
+	return args.Error(0)
+}
+
 func (m *MockSessionService) GetMessages(ctx context.Context, in service.GetMessagesInput) (*service.GetMessagesOutput, error) {
 	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
@@ -1945,6 +1956,41 @@ func TestSessionHandler_GetMessages(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 		},
 
+		{
+			name:           "repair on a page with more messages",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?limit=20&repair=true",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetMessages", mock.Anything, mock.Anything).Return(&service.GetMessagesOutput{
+					Items:   []model.Message{{ID: uuid.New(), SessionID: sessionID, Role: model.RoleUser}},
+					HasMore: true,
+				}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "repair after a cursor",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?limit=20&repair=true&cursor=abc",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetMessages", mock.Anything, mock.Anything).Return(&service.GetMessagesOutput{
+					Items: []model.Message{{ID: uuid.New(), SessionID: sessionID, Role: model.RoleUser}},
+				}, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "repair of the full history",
+			sessionIDParam: sessionID.String(),
+			queryParams:    "?repair=true",
+			setup: func(svc *MockSessionService) {
+				svc.On("GetMessages", mock.Anything, mock.Anything).Return(&service.GetMessagesOutput{
+					Items: []model.Message{{ID: uuid.New(), SessionID: sessionID, Role: model.RoleUser}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+
 		// Additional edge cases and error scenarios for GetMessages
 		{
 			name:           "limit exceeds maximum (201)",
@@ -2501,6 +2547,123 @@ func TestSessionHandler_StoreMessage_InvalidJSON(t *testing.T) {
 	})
 }
 
+func TestSessionHandler_StreamMessage(t *testing.T) {
+	projectID := uuid.New()
+	sessionID := uuid.New()
+
+	openaiStream := "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hel\"}}]}\n\n" +
+		"data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: [DONE]\n\n"
+
+	newRouter := func(mockService *MockSessionService) *gin.Engine {
+		handler := NewSessionHandler(mockService, &MockUserService{}, getMockSessionCoreClient())
+		router := setupSessionRouter()
+		router.POST("/session/:session_id/messages/stream", func(c *gin.Context) {
+			c.Set("project", &model.Project{ID: projectID})
+			handler.StreamMessage(c)
+		})
+		return router
+	}
+
+	t.Run("assembles and stores openai stream", func(t *testing.T) {
+		mockService := &MockSessionService{}
+		mockService.On("GetByID", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: projectID}, nil)
+		mockService.On("SaveInProgressMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockService.On("DeleteInProgressMessage", mock.Anything, sessionID, mock.Anything).Return(nil)
+		mockService.On("StoreMessage", mock.Anything, mock.MatchedBy(func(in service.StoreMessageInput) bool {
+			userMeta, _ := in.MessageMeta[model.UserMetaKey].(map[string]interface{})
+			return in.MessageID != uuid.Nil &&
+				in.Role == model.RoleAssistant &&
+				len(in.Parts) == 1 && in.Parts[0].Text == "Hello" &&
+				userMeta["source"] == "web"
+		})).Return(&model.Message{ID: uuid.New(), SessionID: sessionID, Role: model.RoleAssistant}, nil)
+
+		req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/stream?format=openai&meta=%7B%22source%22%3A%22web%22%7D", bytes.NewBufferString(openaiStream))
+		req.Header.Set("Content-Type", "text/event-stream")
+		w := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("truncated stream", func(t *testing.T) {
+		mockService := &MockSessionService{}
+		mockService.On("GetByID", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: projectID}, nil)
+		mockService.On("SaveInProgressMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockService.On("DeleteInProgressMessage", mock.Anything, sessionID, mock.Anything).Return(nil).Maybe()
+
+		// The stream is cut before the chunk carrying finish_reason
+		truncated := "data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"created\":1,\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hel\"}}]}\n\n"
+		req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/stream?format=openai", bytes.NewBufferString(truncated))
+		req.Header.Set("Content-Type", "text/event-stream")
+		w := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "StoreMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("stream too large", func(t *testing.T) {
+		mockService := &MockSessionService{}
+		mockService.On("GetByID", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: projectID}, nil)
+		mockService.On("SaveInProgressMessage", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+		mockService.On("DeleteInProgressMessage", mock.Anything, sessionID, mock.Anything).Return(nil).Maybe()
+
+		defer func(size int64) { maxStreamSize = size }(maxStreamSize)
+		maxStreamSize = int64(len(openaiStream) / 2)
+
+		req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/stream?format=openai", bytes.NewBufferString(openaiStream))
+		req.Header.Set("Content-Type", "text/event-stream")
+		w := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		mockService.AssertNotCalled(t, "StoreMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("session of another project", func(t *testing.T) {
+		mockService := &MockSessionService{}
+		mockService.On("GetByID", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: uuid.New()}, nil)
+
+		req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/stream", bytes.NewBufferString(openaiStream))
+		w := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertNotCalled(t, "StoreMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("acontext format is rejected", func(t *testing.T) {
+		mockService := &MockSessionService{}
+
+		req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/stream?format=acontext", bytes.NewBufferString(openaiStream))
+		w := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("malformed chunk", func(t *testing.T) {
+		mockService := &MockSessionService{}
+		mockService.On("GetByID", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: projectID}, nil)
+		mockService.On("DeleteInProgressMessage", mock.Anything, sessionID, mock.Anything).Return(nil)
+
+		req := httptest.NewRequest("POST", "/session/"+sessionID.String()+"/messages/stream", bytes.NewBufferString("data: {not json}\n\n"))
+		w := httptest.NewRecorder()
+
+		newRouter(mockService).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "StoreMessage", mock.Anything, mock.Anything)
+	})
+}
+
 // TestOpenAI_ToolCalls_FieldPreservation 测试OpenAI tool_calls字段是否在往返过程中保留
 func TestOpenAI_ToolCalls_FieldPreservation(t *testing.T) {
 	// Initialize tokenizer for testing (required by GetMessages handler)
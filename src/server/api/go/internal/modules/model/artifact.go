@@ -12,6 +12,9 @@ const (
 	// ArtifactInfoKey is used to store artifact-related system metadata
 	// This key is reserved for storing file path, filename, mime type, size, etc.
 	ArtifactInfoKey = "__artifact_info__"
+
+	// DiskInfoKey is reserved for system metadata of a disk
+	DiskInfoKey = "__disk_info__"
 )
 
 type Disk struct {
@@ -19,6 +22,18 @@ type Disk struct {
 	ProjectID uuid.UUID  `gorm:"type:uuid;not null;index" json:"project_id"`
 	UserID    *uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
 
+	Name        string            `gorm:"type:text;not null;default:'';index" json:"name"`
+	Description string            `gorm:"type:text;not null;default:''" json:"description"`
+	Meta        datatypes.JSONMap `gorm:"type:jsonb;index:idx_disks_meta,type:gin" swaggertype:"object" json:"meta"`
+
+	// SessionID links a disk, typically a scratch disk, to a session
+	SessionID *uuid.UUID `gorm:"type:uuid;index" json:"session_id"`
+	// DeleteWithSession deletes the disk along with its session; otherwise the link is cleared
+	DeleteWithSession bool `gorm:"not null;default:false" json:"delete_with_session"`
+
+	// ChangeSeq is the sequence number of the disk's latest change
+	ChangeSeq int64 `gorm:"not null;default:0" json:"change_seq"`
+
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 
@@ -27,17 +42,26 @@ type Disk struct {
 
 	// Disk <-> User
 	User *User `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+
+	// Disk <-> Session
+	Session *Session `gorm:"foreignKey:SessionID;references:ID;constraint:OnDelete:SET NULL,OnUpdate:CASCADE;" json:"-"`
 }
 
 func (Disk) TableName() string { return "disks" }
 
+// GetReservedKeys returns a list of reserved metadata keys for Disk
+func (Disk) GetReservedKeys() []string {
+	return []string{DiskInfoKey}
+}
+
 type Artifact struct {
 	ID        uuid.UUID                 `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
 	DiskID    uuid.UUID                 `gorm:"type:uuid;not null;index;uniqueIndex:idx_disk_path_filename" json:"disk_id"`
 	Path      string                    `gorm:"type:text;not null;uniqueIndex:idx_disk_path_filename" json:"path"`
 	Filename  string                    `gorm:"type:text;not null;uniqueIndex:idx_disk_path_filename" json:"filename"`
-	Meta      datatypes.JSONMap         `gorm:"type:jsonb" swaggertype:"object" json:"meta"`
+	Meta      datatypes.JSONMap         `gorm:"type:jsonb;index:idx_artifacts_meta,type:gin" swaggertype:"object" json:"meta"`
 	AssetMeta datatypes.JSONType[Asset] `gorm:"type:jsonb;not null" swaggertype:"-" json:"-"`
+	Version   int                       `gorm:"not null;default:1" json:"version"`
 
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
@@ -52,3 +76,225 @@ func (Artifact) TableName() string { return "artifacts" }
 func (Artifact) GetReservedKeys() []string {
 	return []string{ArtifactInfoKey}
 }
+
+// ArtifactVersion is a superseded version of an artifact. The current version
+// lives on the Artifact row itself; each version row holds its own asset reference.
+type ArtifactVersion struct {
+	ID         uuid.UUID                 `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
+	ArtifactID uuid.UUID                 `gorm:"type:uuid;not null;uniqueIndex:idx_artifact_version" json:"-"`
+	DiskID     uuid.UUID                 `gorm:"type:uuid;not null;index" json:"disk_id"`
+	Version    int                       `gorm:"not null;uniqueIndex:idx_artifact_version" json:"version"`
+	Path       string                    `gorm:"type:text;not null" json:"path"`
+	Filename   string                    `gorm:"type:text;not null" json:"filename"`
+	Meta       datatypes.JSONMap         `gorm:"type:jsonb" swaggertype:"object" json:"meta"`
+	AssetMeta  datatypes.JSONType[Asset] `gorm:"type:jsonb;not null" swaggertype:"-" json:"-"`
+
+	// IsCurrent is set when the entry describes the artifact's current version
+	IsCurrent bool `gorm:"-" json:"is_current"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// ArtifactVersion <-> Artifact
+	Artifact *Artifact `gorm:"foreignKey:ArtifactID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (ArtifactVersion) TableName() string { return "artifact_versions" }
+
+// CurrentVersion describes the artifact's current content as a version entry.
+func (a *Artifact) CurrentVersion() *ArtifactVersion {
+	return &ArtifactVersion{
+		ArtifactID: a.ID,
+		DiskID:     a.DiskID,
+		Version:    a.Version,
+		Path:       a.Path,
+		Filename:   a.Filename,
+		Meta:       a.Meta,
+		AssetMeta:  a.AssetMeta,
+		IsCurrent:  true,
+		CreatedAt:  a.UpdatedAt,
+	}
+}
+
+// AsArtifact returns a detached artifact view of the version, for reusing
+// artifact helpers such as presigning and content parsing.
+func (v *ArtifactVersion) AsArtifact() *Artifact {
+	return &Artifact{
+		ID:        v.ArtifactID,
+		DiskID:    v.DiskID,
+		Path:      v.Path,
+		Filename:  v.Filename,
+		Meta:      v.Meta,
+		AssetMeta: v.AssetMeta,
+		Version:   v.Version,
+		CreatedAt: v.CreatedAt,
+		UpdatedAt: v.CreatedAt,
+	}
+}
+
+// ArtifactDirectory is an explicitly created directory. Directories are otherwise
+// implied by artifact path prefixes; explicit ones also exist while empty.
+type ArtifactDirectory struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
+	DiskID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_disk_directory_path" json:"disk_id"`
+	Path   string    `gorm:"type:text;not null;uniqueIndex:idx_disk_directory_path" json:"path"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// ArtifactDirectory <-> Disk
+	Disk *Disk `gorm:"foreignKey:DiskID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (ArtifactDirectory) TableName() string { return "artifact_directories" }
+
+// ArtifactUpload is a pending direct-to-S3 upload. The client uploads to a staging key
+// with presigned URLs; the artifact is only created once the upload is completed and verified.
+type ArtifactUpload struct {
+	ID          uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID   uuid.UUID         `gorm:"type:uuid;not null;index" json:"-"`
+	DiskID      uuid.UUID         `gorm:"type:uuid;not null;index" json:"disk_id"`
+	Path        string            `gorm:"type:text;not null" json:"path"`
+	Filename    string            `gorm:"type:text;not null" json:"filename"`
+	SizeB       int64             `gorm:"not null" json:"size_b"`
+	SHA256      string            `gorm:"type:text;not null" json:"sha256"`
+	ContentType string            `gorm:"type:text" json:"content_type"`
+	Meta        datatypes.JSONMap `gorm:"type:jsonb" swaggertype:"object" json:"meta"`
+	S3Key       string            `gorm:"type:text;not null" json:"-"`
+	// MultipartUploadID is the S3 multipart upload, empty for a single PUT
+	MultipartUploadID string `gorm:"type:text" json:"-"`
+	PartSizeB         int64  `json:"-"`
+
+	// ClaimedAt is set while a completion of the upload is in progress
+	ClaimedAt *time.Time `json:"-"`
+
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// ArtifactUpload <-> Disk
+	Disk *Disk `gorm:"foreignKey:DiskID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (ArtifactUpload) TableName() string { return "artifact_uploads" }
+
+// DiskAsset counts the references from a disk's artifacts and versions to one content hash,
+// so storage usage counts content stored several times on a disk only once.
+// Rows are maintained in the same transactions that create and delete artifacts.
+type DiskAsset struct {
+	DiskID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"disk_id"`
+	SHA256   string    `gorm:"type:char(64);primaryKey" json:"sha256"`
+	SizeB    int64     `gorm:"not null" json:"size_b"`
+	RefCount int       `gorm:"type:integer;not null;default:0;check:disk_asset_ref_count_check,ref_count >= 0" json:"ref_count"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// DiskAsset <-> Disk
+	Disk *Disk `gorm:"foreignKey:DiskID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (DiskAsset) TableName() string { return "disk_assets" }
+
+// DiskSnapshot is a point-in-time manifest of a disk's artifacts. Its entries reference
+// the assets of the artifacts, so the content outlives later deletes and overwrites
+// until the snapshot is deleted.
+type DiskSnapshot struct {
+	ID            uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DiskID        uuid.UUID `gorm:"type:uuid;not null;index" json:"disk_id"`
+	Name          string    `gorm:"type:text" json:"name"`
+	ArtifactCount int64     `gorm:"not null;default:0" json:"artifact_count"`
+	SizeB         int64     `gorm:"not null;default:0" json:"size_b"`
+	// Directories are the explicitly created directories of the disk
+	Directories datatypes.JSONType[[]string] `gorm:"type:jsonb" swaggertype:"array,string" json:"-"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// DiskSnapshot <-> Disk
+	Disk *Disk `gorm:"foreignKey:DiskID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (DiskSnapshot) TableName() string { return "disk_snapshots" }
+
+// DiskSnapshotArtifact is an artifact as it was when its snapshot was taken.
+type DiskSnapshotArtifact struct {
+	ID         uuid.UUID                 `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
+	SnapshotID uuid.UUID                 `gorm:"type:uuid;not null;index" json:"-"`
+	Path       string                    `gorm:"type:text;not null" json:"path"`
+	Filename   string                    `gorm:"type:text;not null" json:"filename"`
+	Meta       datatypes.JSONMap         `gorm:"type:jsonb" swaggertype:"object" json:"meta"`
+	AssetMeta  datatypes.JSONType[Asset] `gorm:"type:jsonb;not null" swaggertype:"-" json:"-"`
+
+	// DiskSnapshotArtifact <-> DiskSnapshot
+	Snapshot *DiskSnapshot `gorm:"foreignKey:SnapshotID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (DiskSnapshotArtifact) TableName() string { return "disk_snapshot_artifacts" }
+
+// ArtifactShare is a read-only link to an artifact or, with an empty Filename, to
+// everything under Path. Only an HMAC of the token is stored; the token itself is
+// returned once, when the share is created.
+type ArtifactShare struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ProjectID uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	DiskID    uuid.UUID `gorm:"type:uuid;not null;index" json:"disk_id"`
+	TokenHMAC string    `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	Path      string    `gorm:"type:text;not null" json:"path"`
+	Filename  string    `gorm:"type:text;not null;default:''" json:"filename"`
+
+	PasswordHashPHC   string `gorm:"type:varchar(255)" json:"-"`
+	PasswordProtected bool   `gorm:"not null;default:false" json:"password_protected"`
+	// MaxDownloads limits the files and archives served through the share; 0 is unlimited
+	MaxDownloads  int        `gorm:"not null;default:0" json:"max_downloads"`
+	DownloadCount int        `gorm:"not null;default:0" json:"download_count"`
+	ExpiresAt     *time.Time `gorm:"index" json:"expires_at"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// ArtifactShare <-> Disk
+	Disk *Disk `gorm:"foreignKey:DiskID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (ArtifactShare) TableName() string { return "artifact_shares" }
+
+// IsDirectory reports whether the share covers a directory rather than a single artifact.
+func (s *ArtifactShare) IsDirectory() bool { return s.Filename == "" }
+
+// Disk change types
+const (
+	DiskChangeCreate = "create"
+	DiskChangeUpdate = "update"
+	DiskChangeDelete = "delete"
+	DiskChangeMove   = "move"
+)
+
+// Disk change actors
+const (
+	DiskChangeActorAPI             = "api"
+	DiskChangeActorSandbox         = "sandbox"
+	DiskChangeActorSnapshotRestore = "snapshot_restore"
+)
+
+// DiskChange is an entry of a disk's change log, written in the transaction of the
+// artifact mutation it records. Seq is gapless and increases with commit order, so a
+// consumer that resumes after the last seq it saw misses nothing. A cloned disk starts
+// with an empty log.
+type DiskChange struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DiskID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_disk_change_seq" json:"disk_id"`
+	Seq    int64     `gorm:"not null;uniqueIndex:idx_disk_change_seq" json:"seq"`
+	Type   string    `gorm:"type:text;not null" json:"type"`
+	// Actor is what made the change, one of the DiskChangeActor values
+	Actor    string `gorm:"type:text;not null" json:"actor"`
+	Path     string `gorm:"type:text;not null" json:"path"`
+	Filename string `gorm:"type:text;not null" json:"filename"`
+	// OldPath and OldFilename are the previous location of a moved artifact
+	OldPath     string `gorm:"type:text" json:"old_path,omitempty"`
+	OldFilename string `gorm:"type:text" json:"old_filename,omitempty"`
+	OldSHA256   string `gorm:"type:text" json:"old_sha256,omitempty"`
+	NewSHA256   string `gorm:"type:text" json:"new_sha256,omitempty"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// DiskChange <-> Disk
+	Disk *Disk `gorm:"foreignKey:DiskID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (DiskChange) TableName() string { return "disk_changes" }
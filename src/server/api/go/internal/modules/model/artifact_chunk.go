@@ -0,0 +1,101 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ArtifactChunk is a piece of an artifact's text content with its embedding,
+// used by semantic search. Chunks are rebuilt whenever the artifact content changes.
+type ArtifactChunk struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"-"`
+	ArtifactID uuid.UUID `gorm:"type:uuid;not null;index" json:"artifact_id"`
+	DiskID     uuid.UUID `gorm:"type:uuid;not null;index:idx_artifact_chunk_disk_model,priority:1" json:"disk_id"`
+	ChunkIndex int       `gorm:"not null" json:"chunk_index"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+
+	// Character offsets in the artifact content, end excluded
+	StartOffset int `gorm:"not null" json:"start_offset"`
+	EndOffset   int `gorm:"not null" json:"end_offset"`
+	// 1-based lines, inclusive
+	StartLine int `gorm:"not null" json:"start_line"`
+	EndLine   int `gorm:"not null" json:"end_line"`
+
+	// Model identifies the embedding space; only vectors of the same model are compared
+	Model string `gorm:"type:text;not null;index:idx_artifact_chunk_disk_model,priority:2" json:"-"`
+	// Untyped so that models of any dimension can share the table
+	Embedding Vector `gorm:"type:vector;not null" json:"-"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// ArtifactChunk <-> Artifact
+	Artifact *Artifact `gorm:"foreignKey:ArtifactID;references:ID;constraint:OnDelete:CASCADE,OnUpdate:CASCADE;" json:"-"`
+}
+
+func (ArtifactChunk) TableName() string { return "artifact_chunks" }
+
+// Vector is a pgvector value, exchanged in its text form "[1,2,3]".
+type Vector []float32
+
+// Scan implements the sql.Scanner interface for Vector
+func (v *Vector) Scan(value interface{}) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+	var s string
+	switch val := value.(type) {
+	case []byte:
+		s = string(val)
+	case string:
+		s = val
+	default:
+		return errors.New("failed to scan vector value")
+	}
+
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return fmt.Errorf("invalid vector value %q", s)
+	}
+	s = s[1 : len(s)-1]
+	if s == "" {
+		*v = Vector{}
+		return nil
+	}
+
+	parts := strings.Split(s, ",")
+	out := make(Vector, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return fmt.Errorf("invalid vector value: %w", err)
+		}
+		out[i] = float32(f)
+	}
+	*v = out
+	return nil
+}
+
+// Value implements the driver.Valuer interface for Vector
+func (v Vector) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
+func (v Vector) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
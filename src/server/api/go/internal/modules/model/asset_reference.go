@@ -56,7 +56,7 @@ type Asset struct {
 	SHA256  string `json:"sha256"`
 	MIME    string `json:"mime"`
 	SizeB   int64  `json:"size_b"`
-	Content string `json:"content,omitempty"` // Text content for text-searchable files (text/*, application/json, application/x-*)
+	Content string `json:"content,omitempty"` // Text content for text-searchable files (text/*, application/json, application/x-*) and text extracted from PDF and Office documents
 }
 
 // IsOrphaned returns true if this asset has no references
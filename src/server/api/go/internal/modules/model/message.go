@@ -54,6 +54,11 @@ const (
 	PartTypeToolResult PartType = "tool-result"
 	PartTypeData       PartType = "data"
 	PartTypeThinking   PartType = "thinking"
+
+	PartTypeRedactedThinking PartType = "redacted-thinking"
+	PartTypeSearchResult     PartType = "search-result"
+	PartTypeServerToolCall   PartType = "server-tool-call"
+	PartTypeServerToolResult PartType = "server-tool-result"
 )
 
 // ---------------------------------------------------------------------------
@@ -121,6 +126,17 @@ const (
 const (
 	// MetaKeyAudioFormat stores the audio encoding format: "mp3", "wav", etc.
 	MetaKeyAudioFormat MetaKey = "format"
+
+	// MetaKeyAudioID stores the provider ID of generated audio (OpenAI assistant audio),
+	// used to refer back to it in follow-up turns.
+	MetaKeyAudioID MetaKey = "audio_id"
+)
+
+// audio and video Part Meta Keys.
+const (
+	// MetaKeyTranscript stores a text transcript of the media, used when a
+	// provider cannot receive the media natively.
+	MetaKeyTranscript MetaKey = "transcript"
 )
 
 // file Part Meta Keys.
@@ -145,6 +161,53 @@ const (
 const (
 	// MetaKeyIsRefusal indicates the text is a refusal response (bool, from OpenAI).
 	MetaKeyIsRefusal MetaKey = "is_refusal"
+
+	// MetaKeyCitations stores the citations backing the text, as a list of objects.
+	// Every citation carries "type" and "cited_text"; see Citation* keys below.
+	// Anthropic citations keep their native type and fields (char_location,
+	// web_search_result_location, ...); Gemini grounding supports use CitationTypeGrounding.
+	MetaKeyCitations MetaKey = "citations"
+)
+
+// Citation object keys (entries of MetaKeyCitations).
+const (
+	CitationKeyType       = "type"
+	CitationKeyCitedText  = "cited_text"
+	CitationKeyURL        = "url"
+	CitationKeyTitle      = "title"
+	CitationKeyStartIndex = "start_index"
+	CitationKeyEndIndex   = "end_index"
+
+	// CitationTypeGrounding marks a citation derived from Gemini grounding metadata.
+	CitationTypeGrounding = "grounding"
+)
+
+// search-result Part Meta Keys. The result body is stored in Part.Text.
+const (
+	// MetaKeySource stores the search result source (a URL or any identifier).
+	MetaKeySource MetaKey = "source"
+
+	// MetaKeyTitle stores the search result title.
+	MetaKeyTitle MetaKey = "title"
+
+	// MetaKeyCitationsEnabled records whether citations were enabled for the result (bool, from Anthropic).
+	MetaKeyCitationsEnabled MetaKey = "citations_enabled"
+)
+
+// server-tool-result Part Meta Keys.
+const (
+	// MetaKeyResults stores the results of a provider-executed tool as a list of objects
+	// with "title", "url" and, for Anthropic web search, "encrypted_content" and "page_age".
+	MetaKeyResults MetaKey = "results"
+
+	// MetaKeyErrorCode stores the error code of a failed provider-executed tool.
+	MetaKeyErrorCode MetaKey = "error_code"
+
+	// MetaKeyEncryptedContent stores opaque provider content that must be sent back verbatim.
+	MetaKeyEncryptedContent MetaKey = "encrypted_content"
+
+	// MetaKeyPageAge stores the age of a web search result page (from Anthropic).
+	MetaKeyPageAge MetaKey = "page_age"
 )
 
 // data Part Meta Keys.
@@ -171,6 +234,18 @@ const (
 	UserMetaKey = "__user_meta__"
 )
 
+// ---------------------------------------------------------------------------
+// Message status constants
+// ---------------------------------------------------------------------------
+
+const (
+	// MessageStatusInProgress marks a message that is still being streamed and
+	// has not been stored yet. Its parts reflect the chunks received so far.
+	MessageStatusInProgress = "in_progress"
+	// MessageStatusCompleted marks a stored message.
+	MessageStatusCompleted = "completed"
+)
+
 // ---------------------------------------------------------------------------
 // Message model
 // ---------------------------------------------------------------------------
@@ -193,6 +268,10 @@ type Message struct {
 
 	SessionTaskProcessStatus string `gorm:"type:text;not null;default:'pending';check:session_task_process_status IN ('success','failed','running','pending')" json:"session_task_process_status"`
 
+	// Status is not persisted: it is MessageStatusInProgress for partially streamed
+	// messages returned alongside stored ones, and empty otherwise.
+	Status string `gorm:"-" json:"status,omitempty"`
+
 	CreatedAt time.Time `gorm:"autoCreateTime;not null;default:CURRENT_TIMESTAMP;index:idx_session_created,priority:2,sort:desc" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 
@@ -221,7 +300,7 @@ func (Message) GetReservedKeys() []string {
 //
 // Canonical schema per Type:
 //
-//	text:        Text (required). Meta: cache_control?, is_refusal?
+//	text:        Text (required). Meta: cache_control?, is_refusal?, citations?
 //	image:       Asset or Meta. Meta: media_type, data (base64) | url, detail?, type?, cache_control?
 //	audio:       Asset or Meta. Meta: data (base64), format
 //	video:       Asset or Meta. Meta: media_type, data (base64) | url
@@ -230,6 +309,10 @@ func (Message) GetReservedKeys() []string {
 //	tool-result: Text + Meta (required): tool_call_id. Optional: name, is_error, cache_control
 //	data:        Meta (required): data_type
 //	thinking:    Text (required). Meta: signature?
+//	redacted-thinking:  Meta (required): data (opaque encrypted thinking)
+//	search-result:      Text (result body) + Meta: source, title, url?, citations_enabled?, cache_control?
+//	server-tool-call:   Meta (required): id, name, arguments (JSON string). Tools run by the provider, e.g. web_search
+//	server-tool-result: Meta (required): tool_call_id. Optional: name, results, error_code
 type Part struct {
 	Type string `json:"type"`
 
@@ -284,6 +367,46 @@ func (p Part) IsError() bool { return p.GetMetaBool(MetaKeyIsError) }
 // Signature returns the Anthropic thinking signature from Meta.
 func (p Part) Signature() string { return p.GetMetaString(MetaKeySignature) }
 
+// Citations returns the citations attached to a text Part.
+func (p Part) Citations() []map[string]any {
+	if p.Meta == nil {
+		return nil
+	}
+	switch v := p.Meta[MetaKeyCitations].(type) {
+	case []map[string]any:
+		return v
+	case []any:
+		citations := make([]map[string]any, 0, len(v))
+		for _, item := range v {
+			if c, ok := item.(map[string]any); ok {
+				citations = append(citations, c)
+			}
+		}
+		return citations
+	}
+	return nil
+}
+
+// Results returns the results of a server-tool-result Part.
+func (p Part) Results() []map[string]any {
+	if p.Meta == nil {
+		return nil
+	}
+	switch v := p.Meta[MetaKeyResults].(type) {
+	case []map[string]any:
+		return v
+	case []any:
+		results := make([]map[string]any, 0, len(v))
+		for _, item := range v {
+			if r, ok := item.(map[string]any); ok {
+				results = append(results, r)
+			}
+		}
+		return results
+	}
+	return nil
+}
+
 // ---------------------------------------------------------------------------
 // Part constructor helpers
 // ---------------------------------------------------------------------------
@@ -359,6 +482,17 @@ func NewFilePartBase64(mediaType, base64Data string) Part {
 	}
 }
 
+// NewVideoPartBase64 creates a video Part from base64 data.
+func NewVideoPartBase64(mediaType, base64Data string) Part {
+	return Part{
+		Type: PartTypeVideo,
+		Meta: map[string]any{
+			MetaKeyMediaType: mediaType,
+			MetaKeyData:      base64Data,
+		},
+	}
+}
+
 // NewAudioPart creates an audio Part from base64 data.
 func NewAudioPart(base64Data, format string) Part {
 	return Part{
@@ -2,42 +2,131 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/glob"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrDiskNotFound is returned when a disk does not exist in the project.
+var ErrDiskNotFound = errors.New("disk not found")
+
+// ErrArtifactConflict is returned when a transfer destination is taken and the conflict policy is fail.
+var ErrArtifactConflict = errors.New("artifact already exists at destination")
+
+// ErrArtifactExists is returned when an artifact is created at a path/filename already taken.
+var ErrArtifactExists = errors.New("artifact already exists")
+
+// ErrUploadClaimed is returned when claiming an upload another completion holds.
+var ErrUploadClaimed = errors.New("upload is being completed")
+
+// ErrArtifactModified is returned when an artifact's content no longer is the one an update was based on.
+var ErrArtifactModified = errors.New("artifact has been modified")
+
+// Conflict policies of ArtifactTransfer
+const (
+	ConflictFail      = "fail"
+	ConflictOverwrite = "overwrite"
+	ConflictSkip      = "skip"
+)
+
+// ArtifactTransfer describes a move or copy of a single artifact (SourceFilename set)
+// or of every artifact under the SourcePath prefix (SourceFilename empty).
+type ArtifactTransfer struct {
+	SourceDiskID   uuid.UUID
+	SourcePath     string
+	SourceFilename string
+	DestDiskID     uuid.UUID
+	DestPath       string
+	// DestFilename renames a single artifact; empty keeps the source filename
+	DestFilename string
+	Copy         bool
+	OnConflict   string
+	// Check, when set, admits the bytes and files a copy adds
+	Check QuotaCheck
+}
+
 type ArtifactRepo interface {
 	Create(ctx context.Context, projectID uuid.UUID, a *model.Artifact) error
-	DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error
-	Update(ctx context.Context, a *model.Artifact) error
+	AddVersion(ctx context.Context, projectID uuid.UUID, a *model.Artifact, maxVersions int) error
+	AddVersionIfMatch(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string, maxVersions int) error
+	ListVersions(ctx context.Context, artifactID uuid.UUID) ([]*model.ArtifactVersion, error)
+	GetVersion(ctx context.Context, artifactID uuid.UUID, version int) (*model.ArtifactVersion, error)
+	Transfer(ctx context.Context, projectID uuid.UUID, t ArtifactTransfer) (transferred []*model.Artifact, skipped []*model.Artifact, err error)
+	DeleteByPrefix(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, prefix string) (int64, error)
+	CreateDirectory(ctx context.Context, projectID uuid.UUID, d *model.ArtifactDirectory) error
+	CreateUpload(ctx context.Context, u *model.ArtifactUpload) error
+	ClaimUpload(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, id uuid.UUID, staleBefore time.Time) (*model.ArtifactUpload, error)
+	ReleaseUpload(ctx context.Context, id uuid.UUID) error
+	ListExpiredUploads(ctx context.Context, before time.Time, limit int) ([]*model.ArtifactUpload, error)
+	DeleteUpload(ctx context.Context, id uuid.UUID) error
+	TreeStats(ctx context.Context, diskID uuid.UUID, root string, depth int) (*ArtifactTreeStats, error)
+	CreateSnapshot(ctx context.Context, projectID uuid.UUID, s *model.DiskSnapshot) error
+	ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error)
+	DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error
+	RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID, maxVersions int, check QuotaCheck) (*SnapshotRestore, error)
+	CreateShare(ctx context.Context, projectID uuid.UUID, s *model.ArtifactShare) error
+	ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error)
+	DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error
+	GetShareByToken(ctx context.Context, tokenHMAC string) (*model.ArtifactShare, error)
+	ConsumeShareDownload(ctx context.Context, shareID uuid.UUID) error
+	DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, expectedSHA256 string) error
+	Update(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string) error
+	ListChanges(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, since int64, limit int) ([]*model.DiskChange, error)
 	GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error)
 	ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error)
+	ListByPrefix(ctx context.Context, diskID uuid.UUID, prefix string) ([]*model.Artifact, error)
+	QueryWithCursor(ctx context.Context, diskID uuid.UUID, q ArtifactQuery, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error)
 	GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error)
 	ExistsByPathAndFilename(ctx context.Context, diskID uuid.UUID, path string, filename string, excludeID *uuid.UUID) (bool, error)
 	GrepArtifacts(ctx context.Context, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error)
+	SearchContent(ctx context.Context, diskID uuid.UUID, q ContentQuery) ([]*model.Artifact, error)
 	GlobArtifacts(ctx context.Context, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error)
 }
 
 type artifactRepo struct {
 	db                 *gorm.DB
 	assetReferenceRepo AssetReferenceRepo
+	notifier           DiskChangeNotifier
 }
 
-func NewArtifactRepo(db *gorm.DB, assetReferenceRepo AssetReferenceRepo) ArtifactRepo {
-	return &artifactRepo{db: db, assetReferenceRepo: assetReferenceRepo}
+// NewArtifactRepo returns an ArtifactRepo; notifier may be nil.
+func NewArtifactRepo(db *gorm.DB, assetReferenceRepo AssetReferenceRepo, notifier DiskChangeNotifier) ArtifactRepo {
+	return &artifactRepo{db: db, assetReferenceRepo: assetReferenceRepo, notifier: notifier}
 }
 
+// Create creates a, or returns ErrArtifactExists when its path/filename was taken meanwhile.
 func (r *artifactRepo) Create(ctx context.Context, projectID uuid.UUID, a *model.Artifact) error {
 	// Save asset meta before creation for reference increment
 	asset := a.AssetMeta.Data()
 
 	// Use transaction to ensure atomicity: create artifact and increment reference
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(a).Error; err != nil {
+	var changes []*model.DiskChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A concurrent create of the same file is reported, not raised as a constraint violation
+		res := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "disk_id"}, {Name: "path"}, {Name: "filename"}},
+			DoNothing: true,
+		}).Create(a)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrArtifactExists
+		}
+		if err := addDiskAssets(tx, a.DiskID, []model.Asset{asset}); err != nil {
+			return fmt.Errorf("add disk asset: %w", err)
+		}
+		changes = []*model.DiskChange{changeOf(ctx, model.DiskChangeCreate, a)}
+		if err := recordChanges(tx, changes); err != nil {
 			return err
 		}
 
@@ -47,37 +136,243 @@ func (r *artifactRepo) Create(ctx context.Context, projectID uuid.UUID, a *model
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	r.notify(ctx, projectID, changes)
+	return nil
 }
 
-func (r *artifactRepo) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
-	var a model.Artifact
-	err := r.db.WithContext(ctx).Where("disk_id = ? AND path = ? AND filename = ?", diskID, path, filename).First(&a).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+// AddVersion replaces the content of the existing artifact a.ID with a.Meta and a.AssetMeta.
+// The superseded content is kept as a version row, which takes over its asset reference,
+// and the oldest versions beyond maxVersions (current included) are pruned. maxVersions <= 0 keeps all.
+func (r *artifactRepo) AddVersion(ctx context.Context, projectID uuid.UUID, a *model.Artifact, maxVersions int) error {
+	return r.addVersion(ctx, projectID, a, "", maxVersions)
+}
+
+// AddVersionIfMatch is AddVersion for compare-and-swap updates: it returns ErrArtifactModified
+// unless the current content still has expectedSHA256, checked under the row lock.
+func (r *artifactRepo) AddVersionIfMatch(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string, maxVersions int) error {
+	if expectedSHA256 == "" {
+		return errors.New("expected SHA256 is required")
+	}
+	return r.addVersion(ctx, projectID, a, expectedSHA256, maxVersions)
+}
+
+func (r *artifactRepo) addVersion(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string, maxVersions int) error {
+	var changes []*model.DiskChange
+	var released []model.Asset
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the current row so concurrent upserts get distinct version numbers
+		var current model.Artifact
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND disk_id = ?", a.ID, a.DiskID).
+			First(&current).Error; err != nil {
+			return err
+		}
+		if expectedSHA256 != "" && current.AssetMeta.Data().SHA256 != expectedSHA256 {
+			return ErrArtifactModified
+		}
+		change := updateChange(ctx, &current, a)
+		pruned, err := r.replaceContent(ctx, tx, projectID, &current, a, maxVersions)
+		if err != nil {
 			return err
 		}
+		released = pruned
+		changes = []*model.DiskChange{change}
+		return recordChanges(tx, changes)
+	})
+	if err != nil {
 		return err
 	}
+	r.notify(ctx, projectID, changes)
+
+	// Pruned objects are only deleted once the transaction can no longer roll back
+	if len(released) > 0 {
+		if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, released); err != nil {
+			return fmt.Errorf("decrement asset references: %w", err)
+		}
+	}
+	return nil
+}
+
+// updateChange describes the replacement of current's content or meta by a.
+func updateChange(ctx context.Context, current *model.Artifact, a *model.Artifact) *model.DiskChange {
+	c := changeOf(ctx, model.DiskChangeUpdate, current)
+	c.OldSHA256 = current.AssetMeta.Data().SHA256
+	if sha := a.AssetMeta.Data().SHA256; sha != "" {
+		c.NewSHA256 = sha
+	}
+	return c
+}
 
-	// Save asset meta before deletion for reference decrement
+// replaceContent stores a as the new content of the locked row current, within tx.
+// The superseded content becomes a version row and versions beyond maxVersions are pruned.
+// It returns the assets of the pruned versions, whose references the caller releases after commit.
+func (r *artifactRepo) replaceContent(ctx context.Context, tx *gorm.DB, projectID uuid.UUID, current *model.Artifact, a *model.Artifact, maxVersions int) ([]model.Asset, error) {
 	asset := a.AssetMeta.Data()
 
-	// Use transaction to ensure atomicity: delete artifact and decrement reference
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	prev := current.CurrentVersion()
+	prev.IsCurrent = false
+	if err := tx.Create(prev).Error; err != nil {
+		return nil, fmt.Errorf("create artifact version: %w", err)
+	}
+
+	a.Version = current.Version + 1
+	a.CreatedAt = current.CreatedAt
+	a.UpdatedAt = time.Now()
+	if err := tx.Model(current).Updates(map[string]interface{}{
+		"meta":       a.Meta,
+		"asset_meta": a.AssetMeta,
+		"version":    a.Version,
+		"updated_at": a.UpdatedAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("update artifact: %w", err)
+	}
+	if err := addDiskAssets(tx, current.DiskID, []model.Asset{asset}); err != nil {
+		return nil, fmt.Errorf("add disk asset: %w", err)
+	}
+
+	if err := r.assetReferenceRepo.IncrementAssetRef(ctx, projectID, asset); err != nil {
+		return nil, fmt.Errorf("increment asset reference: %w", err)
+	}
+
+	if maxVersions <= 0 {
+		return nil, nil
+	}
+
+	// The current version counts towards the limit
+	var pruned []model.ArtifactVersion
+	if err := tx.Where("artifact_id = ?", current.ID).
+		Order("version DESC").
+		Offset(maxVersions - 1).
+		Find(&pruned).Error; err != nil {
+		return nil, fmt.Errorf("query pruned versions: %w", err)
+	}
+	if len(pruned) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(pruned))
+	assets := make([]model.Asset, 0, len(pruned))
+	for _, v := range pruned {
+		ids = append(ids, v.ID)
+		if va := v.AssetMeta.Data(); va.SHA256 != "" {
+			assets = append(assets, va)
+		}
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&model.ArtifactVersion{}).Error; err != nil {
+		return nil, fmt.Errorf("prune artifact versions: %w", err)
+	}
+	if err := releaseDiskAssets(tx, current.DiskID, assets); err != nil {
+		return nil, fmt.Errorf("release disk assets: %w", err)
+	}
+	return assets, nil
+}
+
+// ListVersions returns the superseded versions of an artifact, newest first.
+func (r *artifactRepo) ListVersions(ctx context.Context, artifactID uuid.UUID) ([]*model.ArtifactVersion, error) {
+	var versions []*model.ArtifactVersion
+	err := r.db.WithContext(ctx).
+		Where("artifact_id = ?", artifactID).
+		Order("version DESC").
+		Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *artifactRepo) GetVersion(ctx context.Context, artifactID uuid.UUID, version int) (*model.ArtifactVersion, error) {
+	var v model.ArtifactVersion
+	err := r.db.WithContext(ctx).Where("artifact_id = ? AND version = ?", artifactID, version).First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// DeleteByPath deletes an artifact with its versions. Unless expectedSHA256 is empty, it returns
+// ErrArtifactModified when the content no longer has that hash, checked under the row lock.
+func (r *artifactRepo) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, expectedSHA256 string) error {
+	// Use transaction to ensure atomicity: delete artifact with its versions and decrement references
+	var changes []*model.DiskChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var a model.Artifact
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("disk_id = ? AND path = ? AND filename = ?", diskID, path, filename).
+			First(&a).Error; err != nil {
+			return err
+		}
+		if expectedSHA256 != "" && a.AssetMeta.Data().SHA256 != expectedSHA256 {
+			return ErrArtifactModified
+		}
+
+		var versions []model.ArtifactVersion
+		if err := tx.Where("artifact_id = ?", a.ID).Find(&versions).Error; err != nil {
+			return fmt.Errorf("query artifact versions: %w", err)
+		}
+
+		// Save asset meta before deletion for reference decrement
+		assets := []model.Asset{a.AssetMeta.Data()}
+		for _, v := range versions {
+			if va := v.AssetMeta.Data(); va.SHA256 != "" {
+				assets = append(assets, va)
+			}
+		}
+
+		if err := tx.Where("artifact_id = ?", a.ID).Delete(&model.ArtifactVersion{}).Error; err != nil {
+			return fmt.Errorf("delete artifact versions: %w", err)
+		}
 		if err := tx.Delete(&a).Error; err != nil {
 			return err
 		}
+		if err := releaseDiskAssets(tx, a.DiskID, assets); err != nil {
+			return fmt.Errorf("release disk assets: %w", err)
+		}
+		changes = []*model.DiskChange{changeOf(ctx, model.DiskChangeDelete, &a)}
+		if err := recordChanges(tx, changes); err != nil {
+			return err
+		}
 
-		if err := r.assetReferenceRepo.DecrementAssetRef(ctx, projectID, asset); err != nil {
-			return fmt.Errorf("decrement asset reference: %w", err)
+		if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, assets); err != nil {
+			return fmt.Errorf("decrement asset references: %w", err)
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	r.notify(ctx, projectID, changes)
+	return nil
 }
 
-func (r *artifactRepo) Update(ctx context.Context, a *model.Artifact) error {
-	return r.db.WithContext(ctx).Where("id = ? AND disk_id = ?", a.ID, a.DiskID).Updates(a).Error
+// Update saves the non-zero fields of a, logged as an update that keeps the content.
+// Unless expectedSHA256 is empty, it returns ErrArtifactModified when the content no longer has that hash.
+func (r *artifactRepo) Update(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string) error {
+	var changes []*model.DiskChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Artifact
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND disk_id = ?", a.ID, a.DiskID).
+			First(&current).Error; err != nil {
+			return err
+		}
+		if expectedSHA256 != "" && current.AssetMeta.Data().SHA256 != expectedSHA256 {
+			return ErrArtifactModified
+		}
+		if err := tx.Where("id = ? AND disk_id = ?", a.ID, a.DiskID).Updates(a).Error; err != nil {
+			return err
+		}
+		changes = []*model.DiskChange{updateChange(ctx, &current, a)}
+		return recordChanges(tx, changes)
+	})
+	if err != nil {
+		return err
+	}
+	r.notify(ctx, projectID, changes)
+	return nil
 }
 
 func (r *artifactRepo) GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error) {
@@ -105,13 +400,12 @@ func (r *artifactRepo) ListByPath(ctx context.Context, diskID uuid.UUID, path st
 	return artifacts, nil
 }
 
+// GetAllPaths returns every directory path holding artifacts, plus the explicitly created directories.
 func (r *artifactRepo) GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error) {
 	var paths []string
 	err := r.db.WithContext(ctx).
-		Model(&model.Artifact{}).
-		Where("disk_id = ?", diskID).
-		Distinct("path").
-		Pluck("path", &paths).Error
+		Raw("SELECT path FROM artifacts WHERE disk_id = ? UNION SELECT path FROM artifact_directories WHERE disk_id = ?", diskID, diskID).
+		Scan(&paths).Error
 	if err != nil {
 		return nil, err
 	}
@@ -140,15 +434,7 @@ func (r *artifactRepo) ExistsByPathAndFilename(ctx context.Context, diskID uuid.
 func (r *artifactRepo) GrepArtifacts(ctx context.Context, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	var artifacts []*model.Artifact
 
-	// Use regex pattern matching on text content
-	// Filter by text-searchable mime types and ensure content is not null
-	// This matches the index condition for optimal performance
-	query := r.db.WithContext(ctx).
-		Where("disk_id = ?", diskID).
-		Where("(asset_meta->>'content') IS NOT NULL").
-		Where("((asset_meta->>'mime') LIKE 'text/%' OR (asset_meta->>'mime') = 'application/json' OR (asset_meta->>'mime') LIKE 'application/x-%')").
-		Where("(asset_meta->>'content') ~ ?", pattern).
-		Limit(limit)
+	query := contentMatch(r.db.WithContext(ctx), diskID, "~", pattern).Limit(limit)
 
 	err := query.Find(&artifacts).Error
 	if err != nil {
@@ -158,34 +444,486 @@ func (r *artifactRepo) GrepArtifacts(ctx context.Context, diskID uuid.UUID, patt
 	return artifacts, nil
 }
 
-func (r *artifactRepo) GlobArtifacts(ctx context.Context, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
+// contentMatch selects the artifacts of a disk whose text content matches pattern with
+// the regex operator op. Content is stored for text files and for the text extracted
+// from documents such as PDF and DOCX, so it is filtered on rather than the MIME type.
+func contentMatch(db *gorm.DB, diskID uuid.UUID, op string, pattern string) *gorm.DB {
+	return db.Where("disk_id = ?", diskID).
+		Where("(asset_meta->>'content') IS NOT NULL").
+		Where("(asset_meta->>'content') "+op+" ?", pattern)
+}
+
+// ContentQuery selects the text artifacts whose content matches a POSIX regular expression.
+type ContentQuery struct {
+	Pattern    string
+	IgnoreCase bool
+	Limit      int
+	Offset     int
+}
+
+// SearchContent is GrepArtifacts with options, ordered by path so results can be paged.
+func (r *artifactRepo) SearchContent(ctx context.Context, diskID uuid.UUID, q ContentQuery) ([]*model.Artifact, error) {
 	var artifacts []*model.Artifact
 
-	// Convert glob pattern to PostgreSQL LIKE pattern
-	// Replace ** with % (recursive directory matching)
-	// Replace * with % (any characters)
-	// Replace ? with _ (single character)
-	// Note: ** must be replaced before * to avoid double replacement
-	sqlPattern := strings.ReplaceAll(pattern, "**", "%")
-	sqlPattern = strings.ReplaceAll(sqlPattern, "*", "%")
-	sqlPattern = strings.ReplaceAll(sqlPattern, "?", "_")
-
-	// Escape special LIKE characters that aren't glob patterns
-	// PostgreSQL LIKE uses % and _ as wildcards, \ as escape
-	// We need to escape any literal % or _ that aren't from our replacements
-	// But since we're doing full replacement, we don't need to escape
-
-	// Match against combined path/filename
-	// path already ends with '/', so no extra separator needed
-	query := r.db.WithContext(ctx).
-		Where("disk_id = ?", diskID).
-		Where("(path || filename) LIKE ?", sqlPattern).
-		Limit(limit)
+	op := "~"
+	if q.IgnoreCase {
+		op = "~*"
+	}
+	query := contentMatch(r.db.WithContext(ctx), diskID, op, q.Pattern).
+		Order("path, filename").
+		Offset(q.Offset).
+		Limit(q.Limit)
 
-	err := query.Find(&artifacts).Error
-	if err != nil {
+	if err := query.Find(&artifacts).Error; err != nil {
 		return nil, err
 	}
 
 	return artifacts, nil
 }
+
+// globBatchSize is the page size used when glob matching falls back to Go
+const globBatchSize = 500
+
+// GlobArtifacts returns the artifacts whose full path matches a doublestar glob.
+// A pattern not starting with '/' is anchored at the root, so "*.md" only
+// matches root files and "**/*.md" matches them at any depth.
+func (r *artifactRepo) GlobArtifacts(ctx context.Context, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "/" + pattern
+	}
+	where, args, exact, err := globCondition(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	query := func(offset, limit int) ([]*model.Artifact, error) {
+		var artifacts []*model.Artifact
+		err := r.db.WithContext(ctx).
+			Where("disk_id = ?", diskID).
+			Where(where, args...).
+			Order("path, filename").
+			Offset(offset).
+			Limit(limit).
+			Find(&artifacts).Error
+		return artifacts, err
+	}
+
+	if exact {
+		return query(0, limit)
+	}
+
+	// The SQL condition is a superset: filter pages with the Go matcher until the limit
+	artifacts := []*model.Artifact{}
+	for offset := 0; ; offset += globBatchSize {
+		page, err := query(offset, globBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range page {
+			ok, err := glob.Match(pattern, a.Path+a.Filename)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				artifacts = append(artifacts, a)
+				if len(artifacts) == limit {
+					return artifacts, nil
+				}
+			}
+		}
+		if len(page) < globBatchSize {
+			return artifacts, nil
+		}
+	}
+}
+
+// globCondition translates an anchored glob into an SQL condition on path and
+// filename. exact is false when SQL only narrows the candidates, which must
+// then be checked with glob.Match.
+func globCondition(pattern string) (where string, args []interface{}, exact bool, err error) {
+	patterns, err := glob.Expand(pattern)
+	if err != nil {
+		return "", nil, false, err
+	}
+
+	exact = true
+	conditions := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		cond, condArgs, ok := globSQL(p)
+		if !ok {
+			exact = false
+		}
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, exact, nil
+}
+
+// globSQL returns the SQL condition of a brace-free anchored glob. When ok is
+// false the condition is only the literal directory prefix of the pattern.
+func globSQL(pattern string) (cond string, args []interface{}, ok bool) {
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	dirs, base := segments[:len(segments)-1], segments[len(segments)-1]
+
+	// Literal leading directories
+	prefix := "/"
+	n := 0
+	for ; n < len(dirs); n++ {
+		lit, isLit := glob.Literal(dirs[n])
+		if !isLit {
+			break
+		}
+		prefix += lit + "/"
+	}
+	rest := dirs[n:]
+
+	switch {
+	case base == "**" && len(rest) == 0, base == "**" && len(rest) == 1 && rest[0] == "**":
+		// "/a/**": any file under /a/
+		return "path LIKE ? ESCAPE '\\'", []interface{}{escapeLike(prefix) + "%"}, true
+	case len(rest) == 0:
+		if like, isLike := glob.ToLike(base); isLike {
+			return "(path = ? AND filename LIKE ? ESCAPE '\\')", []interface{}{prefix, like}, true
+		}
+	case len(rest) == 1 && rest[0] == "**":
+		// "/a/**/x": x in /a/ or any directory below, as ** matches zero or more segments
+		if like, isLike := glob.ToLike(base); isLike {
+			return "(path LIKE ? ESCAPE '\\' AND filename LIKE ? ESCAPE '\\')", []interface{}{escapeLike(prefix) + "%", like}, true
+		}
+	}
+
+	return "path LIKE ? ESCAPE '\\'", []interface{}{escapeLike(prefix) + "%"}, false
+}
+
+// Transfer moves or copies artifacts in a single transaction: either every artifact
+// is transferred (or skipped by policy) or nothing changes. Moves only rewrite rows,
+// copies share the source S3 objects and add a reference per new row.
+func (r *artifactRepo) Transfer(ctx context.Context, projectID uuid.UUID, t ArtifactTransfer) ([]*model.Artifact, []*model.Artifact, error) {
+	var (
+		transferred []*model.Artifact
+		skipped     []*model.Artifact
+		released    []model.Asset
+		changes     []*model.DiskChange
+	)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Both disks must belong to the project: references are counted per project
+		diskIDs := []uuid.UUID{t.SourceDiskID}
+		if t.DestDiskID != t.SourceDiskID {
+			diskIDs = append(diskIDs, t.DestDiskID)
+		}
+		var disks int64
+		if err := tx.Model(&model.Disk{}).Where("id IN ? AND project_id = ?", diskIDs, projectID).Count(&disks).Error; err != nil {
+			return fmt.Errorf("check disks: %w", err)
+		}
+		if int(disks) != len(diskIDs) {
+			return ErrDiskNotFound
+		}
+
+		var sources []*model.Artifact
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("disk_id = ?", t.SourceDiskID)
+		if t.SourceFilename != "" {
+			q = q.Where("path = ? AND filename = ?", t.SourcePath, t.SourceFilename)
+		} else {
+			q = q.Where("path LIKE ? ESCAPE '\\'", escapeLike(t.SourcePath)+"%")
+		}
+		if err := q.Order("path, filename").Find(&sources).Error; err != nil {
+			return fmt.Errorf("query source artifacts: %w", err)
+		}
+		if len(sources) == 0 {
+			if t.SourceFilename != "" {
+				return gorm.ErrRecordNotFound
+			}
+			// A directory may hold nothing but explicitly created subdirectories
+			var dirs int64
+			if err := tx.Model(&model.ArtifactDirectory{}).
+				Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", t.SourceDiskID, escapeLike(t.SourcePath)+"%").
+				Count(&dirs).Error; err != nil {
+				return fmt.Errorf("query source directories: %w", err)
+			}
+			if dirs == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+
+		// Resolve destinations and look up what already exists there
+		type item struct {
+			src      *model.Artifact
+			path     string
+			filename string
+		}
+		items := make([]item, 0, len(sources))
+		sourceIDs := make(map[uuid.UUID]bool, len(sources))
+		for _, src := range sources {
+			it := item{src: src, path: t.DestPath, filename: src.Filename}
+			if t.SourceFilename != "" {
+				if t.DestFilename != "" {
+					it.filename = t.DestFilename
+				}
+			} else {
+				it.path = t.DestPath + strings.TrimPrefix(src.Path, t.SourcePath)
+			}
+			items = append(items, it)
+			sourceIDs[src.ID] = true
+		}
+
+		var existing []*model.Artifact
+		q = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("disk_id = ?", t.DestDiskID)
+		if t.SourceFilename != "" {
+			q = q.Where("path = ? AND filename = ?", items[0].path, items[0].filename)
+		} else {
+			q = q.Where("path LIKE ? ESCAPE '\\'", escapeLike(t.DestPath)+"%")
+		}
+		if err := q.Find(&existing).Error; err != nil {
+			return fmt.Errorf("query destination artifacts: %w", err)
+		}
+		taken := make(map[string]*model.Artifact, len(existing))
+		for _, a := range existing {
+			// A source that is moved away frees its location
+			if !t.Copy && sourceIDs[a.ID] {
+				continue
+			}
+			taken[a.Path+a.Filename] = a
+		}
+
+		var overwritten []*model.Artifact
+		pending := items[:0]
+		for _, it := range items {
+			target, ok := taken[it.path+it.filename]
+			if !ok {
+				pending = append(pending, it)
+				continue
+			}
+			switch t.OnConflict {
+			case ConflictSkip:
+				skipped = append(skipped, it.src)
+			case ConflictOverwrite:
+				overwritten = append(overwritten, target)
+				pending = append(pending, it)
+			default:
+				return fmt.Errorf("%w: %s%s", ErrArtifactConflict, it.path, it.filename)
+			}
+		}
+
+		if t.Copy && t.Check != nil {
+			// Within a disk the copies share content already counted
+			var addBytes int64
+			if t.DestDiskID != t.SourceDiskID {
+				for _, it := range pending {
+					addBytes += it.src.AssetMeta.Data().SizeB
+				}
+			}
+			if err := t.Check(addBytes, int64(len(pending)-len(overwritten))); err != nil {
+				return err
+			}
+		}
+
+		if len(overwritten) > 0 {
+			ids := make([]uuid.UUID, 0, len(overwritten))
+			for _, a := range overwritten {
+				ids = append(ids, a.ID)
+				released = append(released, a.AssetMeta.Data())
+				changes = append(changes, changeOf(ctx, model.DiskChangeDelete, a))
+			}
+			var versions []model.ArtifactVersion
+			if err := tx.Where("artifact_id IN ?", ids).Find(&versions).Error; err != nil {
+				return fmt.Errorf("query overwritten versions: %w", err)
+			}
+			for _, v := range versions {
+				released = append(released, v.AssetMeta.Data())
+			}
+			if err := tx.Where("artifact_id IN ?", ids).Delete(&model.ArtifactVersion{}).Error; err != nil {
+				return fmt.Errorf("delete overwritten versions: %w", err)
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&model.Artifact{}).Error; err != nil {
+				return fmt.Errorf("delete overwritten artifacts: %w", err)
+			}
+			if err := releaseDiskAssets(tx, t.DestDiskID, released); err != nil {
+				return fmt.Errorf("release disk assets: %w", err)
+			}
+		}
+
+		if t.SourceFilename == "" {
+			if err := transferDirectories(tx, t); err != nil {
+				return err
+			}
+		}
+
+		if len(pending) == 0 {
+			return recordChanges(tx, changes)
+		}
+
+		if t.Copy {
+			assets := make([]model.Asset, 0, len(pending))
+			for _, it := range pending {
+				a := &model.Artifact{
+					DiskID:    t.DestDiskID,
+					Path:      it.path,
+					Filename:  it.filename,
+					Meta:      withArtifactInfo(it.src.Meta, it.path, it.filename),
+					AssetMeta: it.src.AssetMeta,
+					Version:   1,
+				}
+				if err := tx.Create(a).Error; err != nil {
+					return fmt.Errorf("copy artifact: %w", err)
+				}
+				transferred = append(transferred, a)
+				assets = append(assets, a.AssetMeta.Data())
+				changes = append(changes, changeOf(ctx, model.DiskChangeCreate, a))
+			}
+			if err := addDiskAssets(tx, t.DestDiskID, assets); err != nil {
+				return fmt.Errorf("add disk assets: %w", err)
+			}
+			if err := recordChanges(tx, changes); err != nil {
+				return err
+			}
+			if err := r.assetReferenceRepo.BatchIncrementAssetRefs(ctx, projectID, assets); err != nil {
+				return fmt.Errorf("increment asset references: %w", err)
+			}
+			return nil
+		}
+
+		// Park moved rows on unique temporary names first, so moves between
+		// overlapping locations never trip the (disk, path, filename) index
+		for _, it := range pending {
+			if err := tx.Model(&model.Artifact{}).Where("id = ?", it.src.ID).
+				Update("filename", ".moving-"+it.src.ID.String()).Error; err != nil {
+				return fmt.Errorf("move artifact: %w", err)
+			}
+		}
+		now := time.Now()
+		for _, it := range pending {
+			a := it.src
+			// Across disks a move reads as a delete on one disk and a create on the other
+			if t.DestDiskID != t.SourceDiskID {
+				changes = append(changes, changeOf(ctx, model.DiskChangeDelete, a))
+			}
+			oldPath, oldFilename := a.Path, a.Filename
+			a.DiskID = t.DestDiskID
+			a.Path = it.path
+			a.Filename = it.filename
+			a.Meta = withArtifactInfo(a.Meta, it.path, it.filename)
+			a.UpdatedAt = now
+			if err := tx.Model(&model.Artifact{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+				"disk_id":    a.DiskID,
+				"path":       a.Path,
+				"filename":   a.Filename,
+				"meta":       a.Meta,
+				"updated_at": now,
+			}).Error; err != nil {
+				return fmt.Errorf("move artifact: %w", err)
+			}
+			if err := tx.Model(&model.ArtifactVersion{}).Where("artifact_id = ?", a.ID).Updates(map[string]interface{}{
+				"disk_id":  a.DiskID,
+				"path":     a.Path,
+				"filename": a.Filename,
+			}).Error; err != nil {
+				return fmt.Errorf("move artifact versions: %w", err)
+			}
+			// Chunks are searched by disk, so they follow the artifact
+			if t.DestDiskID != t.SourceDiskID {
+				if err := tx.Model(&model.ArtifactChunk{}).Where("artifact_id = ?", a.ID).
+					Update("disk_id", a.DiskID).Error; err != nil {
+					return fmt.Errorf("move artifact chunks: %w", err)
+				}
+			}
+			transferred = append(transferred, a)
+			if t.DestDiskID != t.SourceDiskID {
+				changes = append(changes, changeOf(ctx, model.DiskChangeCreate, a))
+				continue
+			}
+			c := changeOf(ctx, model.DiskChangeMove, a)
+			c.OldPath, c.OldFilename = oldPath, oldFilename
+			c.OldSHA256 = c.NewSHA256
+			changes = append(changes, c)
+		}
+
+		if t.DestDiskID != t.SourceDiskID {
+			// Moved content, history included, now counts towards the destination disk
+			ids := make([]uuid.UUID, 0, len(pending))
+			moved := make([]model.Asset, 0, len(pending))
+			for _, it := range pending {
+				ids = append(ids, it.src.ID)
+				moved = append(moved, it.src.AssetMeta.Data())
+			}
+			var versions []model.ArtifactVersion
+			if err := tx.Select("asset_meta").Where("artifact_id IN ?", ids).Find(&versions).Error; err != nil {
+				return fmt.Errorf("query moved versions: %w", err)
+			}
+			for _, v := range versions {
+				moved = append(moved, v.AssetMeta.Data())
+			}
+			if err := moveDiskAssets(tx, t.SourceDiskID, t.DestDiskID, moved); err != nil {
+				return fmt.Errorf("move disk assets: %w", err)
+			}
+		}
+
+		return recordChanges(tx, changes)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	r.notify(ctx, projectID, changes)
+
+	// Release overwritten content only once the transaction is committed,
+	// so a rollback can never delete S3 objects that are still referenced
+	if len(released) > 0 {
+		if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, released); err != nil {
+			return transferred, skipped, fmt.Errorf("decrement asset references: %w", err)
+		}
+	}
+
+	return transferred, skipped, nil
+}
+
+// transferDirectories copies or moves the explicit directories under t.SourcePath.
+// Directories already present at the destination are kept.
+func transferDirectories(tx *gorm.DB, t ArtifactTransfer) error {
+	// substr counts characters, not bytes
+	from := utf8.RuneCountInString(t.SourcePath) + 1
+	pattern := escapeLike(t.SourcePath) + "%"
+
+	if err := tx.Exec(
+		`INSERT INTO artifact_directories (disk_id, path, created_at)
+		SELECT ?, ? || substr(path, ?), NOW() FROM artifact_directories
+		WHERE disk_id = ? AND path LIKE ? ESCAPE '\'
+		ON CONFLICT (disk_id, path) DO NOTHING`,
+		t.DestDiskID, t.DestPath, from, t.SourceDiskID, pattern,
+	).Error; err != nil {
+		return fmt.Errorf("copy directories: %w", err)
+	}
+	if t.Copy {
+		return nil
+	}
+
+	if err := tx.Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", t.SourceDiskID, pattern).
+		Delete(&model.ArtifactDirectory{}).Error; err != nil {
+		return fmt.Errorf("move directories: %w", err)
+	}
+	return nil
+}
+
+// withArtifactInfo returns a copy of meta whose system info points at path and filename.
+func withArtifactInfo(meta datatypes.JSONMap, path string, filename string) datatypes.JSONMap {
+	out := make(datatypes.JSONMap, len(meta))
+	for k, v := range meta {
+		out[k] = v
+	}
+
+	info := make(map[string]interface{})
+	if existing, ok := meta[model.ArtifactInfoKey].(map[string]interface{}); ok {
+		for k, v := range existing {
+			info[k] = v
+		}
+	}
+	info["path"] = path
+	info["filename"] = filename
+	out[model.ArtifactInfoKey] = info
+
+	return out
+}
+
+// escapeLike escapes the LIKE wildcards in s, using backslash as the escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+type ArtifactChunkRepo interface {
+	Replace(ctx context.Context, artifactID uuid.UUID, version int, chunks []*model.ArtifactChunk) error
+	Search(ctx context.Context, diskID uuid.UUID, embeddingModel string, query model.Vector, pathPrefix string, limit int) ([]*ChunkHit, error)
+}
+
+type artifactChunkRepo struct {
+	db *gorm.DB
+}
+
+func NewArtifactChunkRepo(db *gorm.DB) ArtifactChunkRepo {
+	return &artifactChunkRepo{db: db}
+}
+
+// ChunkHit is a chunk matching a semantic search, with its artifact location.
+type ChunkHit struct {
+	model.ArtifactChunk
+	Path     string
+	Filename string
+	Score    float64 // Cosine similarity, higher is closer
+}
+
+// Replace swaps the chunks of an artifact for chunks computed from its given version.
+// Indexing runs in the background, so it is a no-op when the artifact has been
+// updated or deleted since: the newer indexing wins.
+func (r *artifactChunkRepo) Replace(ctx context.Context, artifactID uuid.UUID, version int, chunks []*model.ArtifactChunk) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current []int
+		if err := tx.Raw("SELECT version FROM artifacts WHERE id = ? FOR UPDATE", artifactID).Scan(&current).Error; err != nil {
+			return fmt.Errorf("lock artifact: %w", err)
+		}
+		if len(current) == 0 || current[0] != version {
+			return nil
+		}
+
+		if err := tx.Where("artifact_id = ?", artifactID).Delete(&model.ArtifactChunk{}).Error; err != nil {
+			return fmt.Errorf("delete chunks: %w", err)
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(chunks, 100).Error; err != nil {
+			return fmt.Errorf("create chunks: %w", err)
+		}
+		return nil
+	})
+}
+
+// Search returns the chunks of a disk closest to query by cosine distance.
+func (r *artifactChunkRepo) Search(ctx context.Context, diskID uuid.UUID, embeddingModel string, query model.Vector, pathPrefix string, limit int) ([]*ChunkHit, error) {
+	q := r.db.WithContext(ctx).
+		Table("artifact_chunks AS c").
+		Select(`c.artifact_id, c.disk_id, c.chunk_index, c.content, c.start_offset, c.end_offset,
+			c.start_line, c.end_line, c.created_at, a.path, a.filename,
+			1 - (c.embedding <=> ?::vector) AS score`, query).
+		Joins("JOIN artifacts AS a ON a.id = c.artifact_id").
+		Where("c.disk_id = ? AND c.model = ?", diskID, embeddingModel)
+	if pathPrefix != "" && pathPrefix != "/" {
+		q = q.Where("a.path LIKE ? ESCAPE '\\'", escapeLike(pathPrefix)+"%")
+	}
+
+	var hits []*ChunkHit
+	if err := q.Order("score DESC").Limit(limit).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
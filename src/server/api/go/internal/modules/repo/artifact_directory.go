@@ -0,0 +1,208 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DirectoryStat aggregates every artifact under a directory, at any depth.
+type DirectoryStat struct {
+	// Path is relative to the tree root, without leading or trailing '/'
+	Path      string
+	Depth     int
+	FileCount int64
+	SizeB     int64
+}
+
+// FileStat is a file listed in a tree.
+type FileStat struct {
+	Path     string
+	Filename string
+	SizeB    int64
+}
+
+// ArtifactTreeStats is the raw material of a directory tree, as computed by the database.
+type ArtifactTreeStats struct {
+	// Root aggregates everything under the tree root
+	Root DirectoryStat
+	// Dirs holds every directory down to the depth limit
+	Dirs []DirectoryStat
+	// Files holds the files of the root and of the directories above the depth limit
+	Files []FileStat
+	// ExplicitDirs holds explicitly created directories under the root, relative like Dirs.
+	// They may be deeper than the limit, or empty.
+	ExplicitDirs []string
+}
+
+// DeleteByPrefix deletes every artifact under prefix, their versions and the explicit
+// directories under it, and returns how many artifacts were deleted.
+// It returns ErrDiskNotFound unless the disk belongs to the project.
+func (r *artifactRepo) DeleteByPrefix(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, prefix string) (int64, error) {
+	pattern := escapeLike(prefix) + "%"
+
+	var (
+		deleted  int64
+		released []model.Asset
+		changes  []*model.DiskChange
+	)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkDisk(tx, projectID, diskID); err != nil {
+			return err
+		}
+
+		ids := tx.Model(&model.Artifact{}).Select("id").
+			Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", diskID, pattern)
+
+		// Only the hashes are needed to release references: skip the stored text content
+		var hashes []string
+		if err := tx.Raw(
+			`SELECT asset_meta->>'sha256' FROM artifacts WHERE id IN (?)
+			UNION ALL
+			SELECT asset_meta->>'sha256' FROM artifact_versions WHERE artifact_id IN (?)`,
+			ids, ids,
+		).Scan(&hashes).Error; err != nil {
+			return fmt.Errorf("query asset hashes: %w", err)
+		}
+		for _, h := range hashes {
+			if h != "" {
+				released = append(released, model.Asset{SHA256: h})
+			}
+		}
+
+		if err := tx.Where("artifact_id IN (?)", ids).Delete(&model.ArtifactVersion{}).Error; err != nil {
+			return fmt.Errorf("delete artifact versions: %w", err)
+		}
+		var removed []struct {
+			Path     string
+			Filename string
+			SHA256   string `gorm:"column:sha256"`
+		}
+		if err := tx.Model(&model.Artifact{}).
+			Select("path, filename, asset_meta->>'sha256' AS sha256").
+			Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", diskID, pattern).
+			Order("path, filename").
+			Scan(&removed).Error; err != nil {
+			return fmt.Errorf("query artifacts: %w", err)
+		}
+		res := tx.Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", diskID, pattern).Delete(&model.Artifact{})
+		if res.Error != nil {
+			return fmt.Errorf("delete artifacts: %w", res.Error)
+		}
+		deleted = res.RowsAffected
+		actor := actorFrom(ctx)
+		for _, a := range removed {
+			changes = append(changes, &model.DiskChange{
+				DiskID:    diskID,
+				Type:      model.DiskChangeDelete,
+				Actor:     actor,
+				Path:      a.Path,
+				Filename:  a.Filename,
+				OldSHA256: a.SHA256,
+			})
+		}
+		if err := recordChanges(tx, changes); err != nil {
+			return err
+		}
+		if err := releaseDiskAssets(tx, diskID, released); err != nil {
+			return fmt.Errorf("release disk assets: %w", err)
+		}
+		if err := tx.Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", diskID, pattern).Delete(&model.ArtifactDirectory{}).Error; err != nil {
+			return fmt.Errorf("delete directories: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	r.notify(ctx, projectID, changes)
+
+	// Release references once committed, in one batch per hash
+	if len(released) > 0 {
+		if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, released); err != nil {
+			return deleted, fmt.Errorf("decrement asset references: %w", err)
+		}
+	}
+
+	return deleted, nil
+}
+
+// CreateDirectory creates d unless it already exists; d is filled with the stored row either way.
+// It returns ErrDiskNotFound unless d's disk belongs to the project.
+func (r *artifactRepo) CreateDirectory(ctx context.Context, projectID uuid.UUID, d *model.ArtifactDirectory) error {
+	db := r.db.WithContext(ctx)
+	if err := checkDisk(db, projectID, d.DiskID); err != nil {
+		return err
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "disk_id"}, {Name: "path"}},
+		DoNothing: true,
+	}).Create(d).Error; err != nil {
+		return err
+	}
+	return db.Where("disk_id = ? AND path = ?", d.DiskID, d.Path).First(d).Error
+}
+
+// TreeStats aggregates the artifacts under root in SQL, so only one row per
+// directory down to depth, plus the files shown, leave the database.
+func (r *artifactRepo) TreeStats(ctx context.Context, diskID uuid.UUID, root string, depth int) (*ArtifactTreeStats, error) {
+	db := r.db.WithContext(ctx)
+	pattern := escapeLike(root) + "%"
+	// substr counts characters, not bytes
+	from := utf8.RuneCountInString(root) + 1
+
+	// parts splits the path below the root into directory names: "/root/a/b/" -> {a,b}
+	const rel = `
+		SELECT string_to_array(trim(trailing '/' from substr(path, @from)), '/') AS parts,
+			path, filename,
+			COALESCE((asset_meta->>'size_b')::bigint, 0) AS size_b
+		FROM artifacts
+		WHERE disk_id = @disk AND path LIKE @pattern ESCAPE '\'`
+	args := map[string]interface{}{"from": from, "disk": diskID, "pattern": pattern, "depth": depth}
+
+	stats := &ArtifactTreeStats{}
+
+	if err := db.Raw(
+		`SELECT COUNT(*) AS file_count, COALESCE(SUM(size_b), 0) AS size_b FROM (`+rel+`) rel`, args,
+	).Scan(&stats.Root).Error; err != nil {
+		return nil, fmt.Errorf("aggregate tree root: %w", err)
+	}
+
+	if depth > 0 {
+		if err := db.Raw(
+			`SELECT array_to_string(parts[1:lvl], '/') AS path, lvl AS depth,
+				COUNT(*) AS file_count, COALESCE(SUM(size_b), 0) AS size_b
+			FROM (`+rel+`) rel, generate_series(1, @depth) AS lvl
+			WHERE cardinality(parts) >= lvl
+			GROUP BY 1, 2
+			ORDER BY 1`, args,
+		).Scan(&stats.Dirs).Error; err != nil {
+			return nil, fmt.Errorf("aggregate tree directories: %w", err)
+		}
+	}
+
+	if err := db.Raw(
+		`SELECT path, filename, size_b FROM (`+rel+`) rel
+		WHERE cardinality(parts) < @depth
+		ORDER BY path, filename`, args,
+	).Scan(&stats.Files).Error; err != nil {
+		return nil, fmt.Errorf("list tree files: %w", err)
+	}
+
+	if err := db.Raw(
+		`SELECT trim(trailing '/' from substr(path, @from)) FROM artifact_directories
+		WHERE disk_id = @disk AND path LIKE @pattern ESCAPE '\' AND path <> @root
+		ORDER BY 1`,
+		map[string]interface{}{"from": from, "disk": diskID, "pattern": pattern, "root": root},
+	).Scan(&stats.ExplicitDirs).Error; err != nil {
+		return nil, fmt.Errorf("list tree directories: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,171 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+// Operators of MetaCondition
+const (
+	MetaOpEq     = "eq"
+	MetaOpNe     = "ne"
+	MetaOpGt     = "gt"
+	MetaOpGte    = "gte"
+	MetaOpLt     = "lt"
+	MetaOpLte    = "lte"
+	MetaOpExists = "exists"
+)
+
+var metaOpSQL = map[string]string{
+	MetaOpGt:  ">",
+	MetaOpGte: ">=",
+	MetaOpLt:  "<",
+	MetaOpLte: "<=",
+}
+
+// MetaCondition compares the meta value at Key, a path of nested object keys, with Value.
+// Ordering operators only match values of the same JSON type as Value; ne also matches
+// artifacts without the key; exists ignores Value.
+type MetaCondition struct {
+	Key   []string
+	Op    string
+	Value interface{}
+}
+
+// ArtifactQuery filters artifacts of a disk; zero fields don't filter.
+type ArtifactQuery struct {
+	// Meta matches artifacts whose meta contains it
+	Meta  map[string]interface{}
+	Where []MetaCondition
+	// MIME matches the MIME type exactly, or its type when ending with "/*", e.g. "image/*"
+	MIME          string
+	MinSizeB      int64
+	MaxSizeB      int64
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	PathPrefix    string
+}
+
+// QueryWithCursor returns the artifacts of a disk matching q, ordered by creation time
+// then ID, starting after the (afterCreatedAt, afterID) cursor when set.
+func (r *artifactRepo) QueryWithCursor(ctx context.Context, diskID uuid.UUID, q ArtifactQuery, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error) {
+	tx, err := applyArtifactQuery(r.db.WithContext(ctx).Where("disk_id = ?", diskID), q)
+	if err != nil {
+		return nil, err
+	}
+
+	if !afterCreatedAt.IsZero() && afterID != uuid.Nil {
+		comparisonOp := ">"
+		if timeDesc {
+			comparisonOp = "<"
+		}
+		tx = tx.Where(
+			"(created_at "+comparisonOp+" ?) OR (created_at = ? AND id "+comparisonOp+" ?)",
+			afterCreatedAt, afterCreatedAt, afterID,
+		)
+	}
+
+	orderBy := "created_at ASC, id ASC"
+	if timeDesc {
+		orderBy = "created_at DESC, id DESC"
+	}
+
+	var artifacts []*model.Artifact
+	return artifacts, tx.Order(orderBy).Limit(limit).Find(&artifacts).Error
+}
+
+func applyArtifactQuery(tx *gorm.DB, q ArtifactQuery) (*gorm.DB, error) {
+	// JSONB containment, served by the GIN index on meta
+	if len(q.Meta) > 0 {
+		jsonBytes, err := json.Marshal(q.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("marshal meta filter: %w", err)
+		}
+		tx = tx.Where("meta @> ?", string(jsonBytes))
+	}
+
+	for _, cond := range q.Where {
+		if len(cond.Key) == 0 {
+			return nil, fmt.Errorf("meta condition without key")
+		}
+		// meta -> 'a' -> 'b', with the keys bound as parameters
+		expr := "meta" + strings.Repeat(" -> ?", len(cond.Key))
+		keys := make([]interface{}, len(cond.Key))
+		for i, k := range cond.Key {
+			keys[i] = k
+		}
+
+		if cond.Op == MetaOpExists {
+			tx = tx.Where(expr+" IS NOT NULL", keys...)
+			continue
+		}
+
+		value, err := json.Marshal(cond.Value)
+		if err != nil {
+			return nil, fmt.Errorf("marshal meta condition value: %w", err)
+		}
+		switch cond.Op {
+		case MetaOpEq:
+			tx = tx.Where(expr+" = ?::jsonb", append(keys, string(value))...)
+		case MetaOpNe:
+			tx = tx.Where(expr+" IS DISTINCT FROM ?::jsonb", append(keys, string(value))...)
+		default:
+			op, ok := metaOpSQL[cond.Op]
+			if !ok {
+				return nil, fmt.Errorf("unknown meta operator %q", cond.Op)
+			}
+			// jsonb orders across types; the type guard keeps "10" out of a numeric range
+			args := append(append(append([]interface{}{}, keys...), jsonType(cond.Value)), keys...)
+			tx = tx.Where("jsonb_typeof("+expr+") = ? AND "+expr+" "+op+" ?::jsonb", append(args, string(value))...)
+		}
+	}
+
+	if q.MIME != "" {
+		if major, ok := strings.CutSuffix(q.MIME, "/*"); ok {
+			tx = tx.Where("asset_meta->>'mime' LIKE ? ESCAPE '\\'", escapeLike(major)+"/%")
+		} else {
+			tx = tx.Where("asset_meta->>'mime' = ?", q.MIME)
+		}
+	}
+	if q.MinSizeB > 0 {
+		tx = tx.Where("(asset_meta->>'size_b')::bigint >= ?", q.MinSizeB)
+	}
+	if q.MaxSizeB > 0 {
+		tx = tx.Where("(asset_meta->>'size_b')::bigint <= ?", q.MaxSizeB)
+	}
+	if !q.UpdatedAfter.IsZero() {
+		tx = tx.Where("updated_at >= ?", q.UpdatedAfter)
+	}
+	if !q.UpdatedBefore.IsZero() {
+		tx = tx.Where("updated_at < ?", q.UpdatedBefore)
+	}
+	if q.PathPrefix != "" && q.PathPrefix != "/" {
+		tx = tx.Where("path LIKE ? ESCAPE '\\'", escapeLike(q.PathPrefix)+"%")
+	}
+	return tx, nil
+}
+
+// jsonType returns the jsonb_typeof name of a decoded JSON value.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "number"
+	}
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+// ErrShareUnavailable is returned when a share can no longer serve downloads, because it
+// expired, reached its download limit or was revoked.
+var ErrShareUnavailable = errors.New("share unavailable")
+
+// CreateShare stores a share of a disk of the project.
+func (r *artifactRepo) CreateShare(ctx context.Context, projectID uuid.UUID, s *model.ArtifactShare) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkDisk(tx, projectID, s.DiskID); err != nil {
+			return err
+		}
+		s.ProjectID = projectID
+		return tx.Create(s).Error
+	})
+}
+
+// ListShares returns the shares of a disk of the project, newest first.
+func (r *artifactRepo) ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error) {
+	var shares []*model.ArtifactShare
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND disk_id = ?", projectID, diskID).
+		Order("created_at DESC, id").
+		Find(&shares).Error
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// DeleteShare revokes a share. Its token stops working immediately.
+func (r *artifactRepo) DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error {
+	res := r.db.WithContext(ctx).
+		Where("id = ? AND project_id = ? AND disk_id = ?", shareID, projectID, diskID).
+		Delete(&model.ArtifactShare{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetShareByToken returns the share whose token has the given HMAC.
+func (r *artifactRepo) GetShareByToken(ctx context.Context, tokenHMAC string) (*model.ArtifactShare, error) {
+	var s model.ArtifactShare
+	if err := r.db.WithContext(ctx).Where(&model.ArtifactShare{TokenHMAC: tokenHMAC}).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ConsumeShareDownload counts a download against a share. The check and the increment are
+// one statement, so concurrent downloads never exceed the limit.
+func (r *artifactRepo) ConsumeShareDownload(ctx context.Context, shareID uuid.UUID) error {
+	res := r.db.WithContext(ctx).Model(&model.ArtifactShare{}).
+		Where("id = ?", shareID).
+		Where("max_downloads = 0 OR download_count < max_downloads").
+		Where("expires_at IS NULL OR expires_at > NOW()").
+		Update("download_count", gorm.Expr("download_count + 1"))
+	if res.Error != nil {
+		return fmt.Errorf("consume share download: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrShareUnavailable
+	}
+	return nil
+}
+
+// ListByPrefix returns every artifact of a disk under prefix, at any depth, ordered by path.
+func (r *artifactRepo) ListByPrefix(ctx context.Context, diskID uuid.UUID, prefix string) ([]*model.Artifact, error) {
+	var artifacts []*model.Artifact
+	err := r.db.WithContext(ctx).
+		Where("disk_id = ? AND path LIKE ? ESCAPE '\\'", diskID, escapeLike(prefix)+"%").
+		Order("path, filename").
+		Find(&artifacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return artifacts, nil
+}
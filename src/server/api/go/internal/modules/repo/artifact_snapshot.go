@@ -0,0 +1,308 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SnapshotRestore counts the artifacts a restore changed.
+type SnapshotRestore struct {
+	Created   int
+	Updated   int
+	Deleted   int
+	Unchanged int
+	// Changed are the artifacts whose content was restored
+	Changed []*model.Artifact `json:"-"`
+}
+
+// CreateSnapshot records the current artifacts and explicit directories of s.DiskID as s.
+// Every entry adds a reference to its asset; no content is copied.
+func (r *artifactRepo) CreateSnapshot(ctx context.Context, projectID uuid.UUID, s *model.DiskSnapshot) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkDisk(tx, projectID, s.DiskID); err != nil {
+			return err
+		}
+
+		var dirs []string
+		if err := tx.Model(&model.ArtifactDirectory{}).Where("disk_id = ?", s.DiskID).
+			Order("path").Pluck("path", &dirs).Error; err != nil {
+			return fmt.Errorf("query directories: %w", err)
+		}
+		s.Directories = datatypes.NewJSONType(dirs)
+		if err := tx.Create(s).Error; err != nil {
+			return fmt.Errorf("create snapshot: %w", err)
+		}
+
+		// A single statement copies a consistent manifest without passing it through the server
+		if err := tx.Exec(
+			`INSERT INTO disk_snapshot_artifacts (snapshot_id, path, filename, meta, asset_meta)
+			SELECT ?, path, filename, meta, asset_meta FROM artifacts WHERE disk_id = ?`,
+			s.ID, s.DiskID,
+		).Error; err != nil {
+			return fmt.Errorf("copy artifacts: %w", err)
+		}
+
+		var entries []model.DiskSnapshotArtifact
+		if err := tx.Select("asset_meta").Where("snapshot_id = ?", s.ID).Find(&entries).Error; err != nil {
+			return fmt.Errorf("query snapshot artifacts: %w", err)
+		}
+		assets := make([]model.Asset, 0, len(entries))
+		for _, e := range entries {
+			asset := e.AssetMeta.Data()
+			s.SizeB += asset.SizeB
+			if asset.SHA256 != "" {
+				assets = append(assets, asset)
+			}
+		}
+		s.ArtifactCount = int64(len(entries))
+		if err := tx.Model(s).Updates(map[string]interface{}{
+			"artifact_count": s.ArtifactCount,
+			"size_b":         s.SizeB,
+		}).Error; err != nil {
+			return fmt.Errorf("update snapshot: %w", err)
+		}
+
+		// Incremented inside the transaction callback, as in Clone: a failed commit over-counts
+		if len(assets) > 0 {
+			if err := r.assetReferenceRepo.BatchIncrementAssetRefs(ctx, projectID, assets); err != nil {
+				return fmt.Errorf("increment asset references: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ListSnapshots returns the snapshots of a disk of the project, newest first.
+func (r *artifactRepo) ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error) {
+	var snapshots []*model.DiskSnapshot
+	err := r.db.WithContext(ctx).
+		Where("disk_id = ? AND disk_id IN (?)", diskID, r.db.Model(&model.Disk{}).Select("id").Where("project_id = ?", projectID)).
+		Order("created_at DESC, id").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot deletes a snapshot and releases the references of its entries.
+func (r *artifactRepo) DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error {
+	var released []model.Asset
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkDisk(tx, projectID, diskID); err != nil {
+			return err
+		}
+		var s model.DiskSnapshot
+		if err := tx.Where("id = ? AND disk_id = ?", snapshotID, diskID).First(&s).Error; err != nil {
+			return err
+		}
+
+		var hashes []string
+		if err := tx.Model(&model.DiskSnapshotArtifact{}).Where("snapshot_id = ?", s.ID).
+			Pluck("asset_meta->>'sha256'", &hashes).Error; err != nil {
+			return fmt.Errorf("query asset hashes: %w", err)
+		}
+		for _, h := range hashes {
+			if h != "" {
+				released = append(released, model.Asset{SHA256: h})
+			}
+		}
+
+		if err := tx.Where("snapshot_id = ?", s.ID).Delete(&model.DiskSnapshotArtifact{}).Error; err != nil {
+			return fmt.Errorf("delete snapshot artifacts: %w", err)
+		}
+		return tx.Delete(&s).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Released once committed, so a rollback never deletes referenced S3 objects
+	if len(released) > 0 {
+		if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, released); err != nil {
+			return fmt.Errorf("decrement asset references: %w", err)
+		}
+	}
+	return nil
+}
+
+// RestoreSnapshot rewrites the artifacts and explicit directories of a disk to the state of
+// a snapshot. Artifacts whose content changed get the snapshot content as a new version,
+// so a restore can itself be undone; artifacts created since the snapshot are deleted.
+// A non-nil check admits the content and files the restore adds.
+func (r *artifactRepo) RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID, maxVersions int, check QuotaCheck) (*SnapshotRestore, error) {
+	out := &SnapshotRestore{}
+	var (
+		released []model.Asset
+		changes  []*model.DiskChange
+	)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkDisk(tx, projectID, diskID); err != nil {
+			return err
+		}
+		var s model.DiskSnapshot
+		if err := tx.Where("id = ? AND disk_id = ?", snapshotID, diskID).First(&s).Error; err != nil {
+			return err
+		}
+		var entries []model.DiskSnapshotArtifact
+		if err := tx.Where("snapshot_id = ?", s.ID).Find(&entries).Error; err != nil {
+			return fmt.Errorf("query snapshot artifacts: %w", err)
+		}
+
+		var current []*model.Artifact
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("disk_id = ?", diskID).Find(&current).Error; err != nil {
+			return fmt.Errorf("query artifacts: %w", err)
+		}
+		existing := make(map[string]*model.Artifact, len(current))
+		for _, a := range current {
+			existing[a.Path+a.Filename] = a
+		}
+
+		if check != nil {
+			var addBytes int64
+			for _, e := range entries {
+				if cur, ok := existing[e.Path+e.Filename]; !ok || cur.AssetMeta.Data().SHA256 != e.AssetMeta.Data().SHA256 {
+					addBytes += e.AssetMeta.Data().SizeB
+				}
+			}
+			if err := check(addBytes, int64(len(entries)-len(current))); err != nil {
+				return err
+			}
+		}
+
+		var created []model.Asset
+		for _, e := range entries {
+			key := e.Path + e.Filename
+			cur, ok := existing[key]
+			delete(existing, key)
+
+			if !ok {
+				a := &model.Artifact{
+					DiskID:    diskID,
+					Path:      e.Path,
+					Filename:  e.Filename,
+					Meta:      e.Meta,
+					AssetMeta: e.AssetMeta,
+					Version:   1,
+				}
+				if err := tx.Create(a).Error; err != nil {
+					return fmt.Errorf("restore artifact: %w", err)
+				}
+				created = append(created, a.AssetMeta.Data())
+				changes = append(changes, changeOf(ctx, model.DiskChangeCreate, a))
+				out.Changed = append(out.Changed, a)
+				out.Created++
+				continue
+			}
+
+			if cur.AssetMeta.Data().SHA256 == e.AssetMeta.Data().SHA256 {
+				if reflect.DeepEqual(cur.Meta, e.Meta) {
+					out.Unchanged++
+					continue
+				}
+				if err := tx.Model(cur).Update("meta", e.Meta).Error; err != nil {
+					return fmt.Errorf("restore artifact meta: %w", err)
+				}
+				changes = append(changes, updateChange(ctx, cur, cur))
+				out.Updated++
+				continue
+			}
+
+			a := &model.Artifact{
+				ID:        cur.ID,
+				DiskID:    diskID,
+				Path:      e.Path,
+				Filename:  e.Filename,
+				Meta:      e.Meta,
+				AssetMeta: e.AssetMeta,
+			}
+			changes = append(changes, updateChange(ctx, cur, a))
+			pruned, err := r.replaceContent(ctx, tx, projectID, cur, a, maxVersions)
+			if err != nil {
+				return err
+			}
+			released = append(released, pruned...)
+			out.Changed = append(out.Changed, a)
+			out.Updated++
+		}
+
+		// What is left was created after the snapshot
+		if len(existing) > 0 {
+			ids := make([]uuid.UUID, 0, len(existing))
+			var removed []model.Asset
+			for _, a := range existing {
+				ids = append(ids, a.ID)
+				removed = append(removed, a.AssetMeta.Data())
+				changes = append(changes, changeOf(ctx, model.DiskChangeDelete, a))
+			}
+			var versions []model.ArtifactVersion
+			if err := tx.Select("asset_meta").Where("artifact_id IN ?", ids).Find(&versions).Error; err != nil {
+				return fmt.Errorf("query artifact versions: %w", err)
+			}
+			for _, v := range versions {
+				removed = append(removed, v.AssetMeta.Data())
+			}
+			if err := tx.Where("artifact_id IN ?", ids).Delete(&model.ArtifactVersion{}).Error; err != nil {
+				return fmt.Errorf("delete artifact versions: %w", err)
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&model.Artifact{}).Error; err != nil {
+				return fmt.Errorf("delete artifacts: %w", err)
+			}
+			if err := releaseDiskAssets(tx, diskID, removed); err != nil {
+				return fmt.Errorf("release disk assets: %w", err)
+			}
+			released = append(released, removed...)
+			out.Deleted = len(ids)
+		}
+
+		if err := tx.Where("disk_id = ?", diskID).Delete(&model.ArtifactDirectory{}).Error; err != nil {
+			return fmt.Errorf("delete directories: %w", err)
+		}
+		for _, p := range s.Directories.Data() {
+			if err := tx.Create(&model.ArtifactDirectory{DiskID: diskID, Path: p}).Error; err != nil {
+				return fmt.Errorf("restore directory: %w", err)
+			}
+		}
+
+		if len(created) > 0 {
+			if err := addDiskAssets(tx, diskID, created); err != nil {
+				return fmt.Errorf("add disk assets: %w", err)
+			}
+			if err := r.assetReferenceRepo.BatchIncrementAssetRefs(ctx, projectID, created); err != nil {
+				return fmt.Errorf("increment asset references: %w", err)
+			}
+		}
+		return recordChanges(tx, changes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.notify(ctx, projectID, changes)
+
+	if len(released) > 0 {
+		if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, released); err != nil {
+			return out, fmt.Errorf("decrement asset references: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// checkDisk returns ErrDiskNotFound unless diskID belongs to the project.
+func checkDisk(tx *gorm.DB, projectID uuid.UUID, diskID uuid.UUID) error {
+	var n int64
+	if err := tx.Model(&model.Disk{}).Where("id = ? AND project_id = ?", diskID, projectID).Count(&n).Error; err != nil {
+		return fmt.Errorf("check disk: %w", err)
+	}
+	if n == 0 {
+		return ErrDiskNotFound
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "/docs/", escapeLike("/docs/"))
+	assert.Equal(t, `/100\%\_done\\/`, escapeLike(`/100%_done\/`))
+}
+
+func TestWithArtifactInfo(t *testing.T) {
+	meta := datatypes.JSONMap{
+		model.ArtifactInfoKey: map[string]interface{}{"path": "/a/", "filename": "x.txt", "mime": "text/plain"},
+		"owner":               "alice",
+	}
+
+	out := withArtifactInfo(meta, "/b/", "y.txt")
+
+	info := out[model.ArtifactInfoKey].(map[string]interface{})
+	assert.Equal(t, "/b/", info["path"])
+	assert.Equal(t, "y.txt", info["filename"])
+	assert.Equal(t, "text/plain", info["mime"])
+	assert.Equal(t, "alice", out["owner"])
+
+	// The source meta is left untouched
+	assert.Equal(t, "/a/", meta[model.ArtifactInfoKey].(map[string]interface{})["path"])
+}
+
+func TestGlobCondition(t *testing.T) {
+	tests := []struct {
+		pattern string
+		where   string
+		args    []interface{}
+		exact   bool
+	}{
+		{
+			pattern: "/*.md",
+			where:   `((path = ? AND filename LIKE ? ESCAPE '\'))`,
+			args:    []interface{}{"/", "%.md"},
+			exact:   true,
+		},
+		{
+			pattern: "/docs/**/*_v?.md",
+			where:   `((path LIKE ? ESCAPE '\' AND filename LIKE ? ESCAPE '\'))`,
+			args:    []interface{}{"/docs/%", `%\_v_.md`},
+			exact:   true,
+		},
+		{
+			pattern: "/100%/**",
+			where:   `(path LIKE ? ESCAPE '\')`,
+			args:    []interface{}{`/100\%/%`},
+			exact:   true,
+		},
+		{
+			pattern: "/src/*.{go,md}",
+			where:   `((path = ? AND filename LIKE ? ESCAPE '\') OR (path = ? AND filename LIKE ? ESCAPE '\'))`,
+			args:    []interface{}{"/src/", "%.go", "/src/", "%.md"},
+			exact:   true,
+		},
+		{
+			pattern: "/src/*/main.go",
+			where:   `(path LIKE ? ESCAPE '\')`,
+			args:    []interface{}{"/src/%"},
+			exact:   false,
+		},
+		{
+			pattern: "/[ab].md",
+			where:   `(path LIKE ? ESCAPE '\')`,
+			args:    []interface{}{"/%"},
+			exact:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			where, args, exact, err := globCondition(tt.pattern)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.where, where)
+			assert.Equal(t, tt.args, args)
+			assert.Equal(t, tt.exact, exact)
+		})
+	}
+
+	_, _, _, err := globCondition("/{a,b")
+	assert.Error(t, err)
+}
+
+func TestApplyArtifactQuery(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	q := ArtifactQuery{
+		Meta: map[string]interface{}{"status": "draft"},
+		Where: []MetaCondition{
+			{Key: []string{"review", "score"}, Op: MetaOpGte, Value: float64(3)},
+			{Key: []string{"owner"}, Op: MetaOpNe, Value: "bob"},
+			{Key: []string{"tags"}, Op: MetaOpExists},
+		},
+		MIME:       "image/*",
+		MinSizeB:   10,
+		PathPrefix: "/100%/",
+	}
+	tx, err := applyArtifactQuery(db.Model(&model.Artifact{}), q)
+	if !assert.NoError(t, err) {
+		return
+	}
+	stmt := tx.Find(&[]*model.Artifact{}).Statement
+
+	sql := stmt.SQL.String()
+	assert.Contains(t, sql, "meta @> $1")
+	assert.Contains(t, sql, "jsonb_typeof(meta -> $2 -> $3) = $4 AND meta -> $5 -> $6 >= $7::jsonb")
+	assert.Contains(t, sql, "meta -> $8 IS DISTINCT FROM $9::jsonb")
+	assert.Contains(t, sql, "meta -> $10 IS NOT NULL")
+	assert.Contains(t, sql, "asset_meta->>'mime' LIKE $11")
+	assert.Contains(t, sql, "(asset_meta->>'size_b')::bigint >= $12")
+	assert.Contains(t, sql, "path LIKE $13")
+	assert.Equal(t, []interface{}{
+		`{"status":"draft"}`,
+		"review", "score", "number", "review", "score", "3",
+		"owner", `"bob"`,
+		"tags",
+		"image/%",
+		int64(10),
+		`/100\%/%`,
+	}, stmt.Vars)
+
+	_, err = applyArtifactQuery(db, ArtifactQuery{Where: []MetaCondition{{Key: []string{"a"}, Op: "like", Value: "x"}}})
+	assert.ErrorContains(t, err, "unknown meta operator")
+}
+
+func TestContentMatch(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	diskID := uuid.New()
+	stmt := contentMatch(db.Model(&model.Artifact{}), diskID, "~*", "invoice").Find(&[]*model.Artifact{}).Statement
+
+	// Text extracted from a PDF or DOCX is searchable like any text file
+	sql := stmt.SQL.String()
+	assert.Contains(t, sql, "(asset_meta->>'content') IS NOT NULL")
+	assert.Contains(t, sql, "(asset_meta->>'content') ~* $2")
+	assert.NotContains(t, sql, "mime")
+	assert.Equal(t, []interface{}{diskID, "invoice"}, stmt.Vars)
+}
+
+func TestJSONType(t *testing.T) {
+	assert.Equal(t, "number", jsonType(float64(1)))
+	assert.Equal(t, "string", jsonType("a"))
+	assert.Equal(t, "boolean", jsonType(true))
+	assert.Equal(t, "null", jsonType(nil))
+	assert.Equal(t, "array", jsonType([]interface{}{}))
+	assert.Equal(t, "object", jsonType(map[string]interface{}{}))
+}
+
+func TestChangeOf(t *testing.T) {
+	a := &model.Artifact{
+		DiskID:    uuid.New(),
+		Path:      "/a/",
+		Filename:  "x.txt",
+		AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: "old"}),
+	}
+
+	created := changeOf(context.Background(), model.DiskChangeCreate, a)
+	assert.Equal(t, a.DiskID, created.DiskID)
+	assert.Equal(t, model.DiskChangeActorAPI, created.Actor)
+	assert.Equal(t, "/a/", created.Path)
+	assert.Equal(t, "x.txt", created.Filename)
+	assert.Equal(t, "", created.OldSHA256)
+	assert.Equal(t, "old", created.NewSHA256)
+
+	ctx := WithActor(context.Background(), model.DiskChangeActorSandbox)
+	deleted := changeOf(ctx, model.DiskChangeDelete, a)
+	assert.Equal(t, model.DiskChangeActorSandbox, deleted.Actor)
+	assert.Equal(t, "old", deleted.OldSHA256)
+	assert.Equal(t, "", deleted.NewSHA256)
+
+	// A meta-only update keeps the content hash
+	updated := updateChange(ctx, a, &model.Artifact{})
+	assert.Equal(t, model.DiskChangeUpdate, updated.Type)
+	assert.Equal(t, "old", updated.OldSHA256)
+	assert.Equal(t, "old", updated.NewSHA256)
+
+	updated = updateChange(ctx, a, &model.Artifact{AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: "new"})})
+	assert.Equal(t, "old", updated.OldSHA256)
+	assert.Equal(t, "new", updated.NewSHA256)
+}
+
+// dryRunPool lets dry-run sessions open transactions without a database
+type dryRunPool struct{ gorm.ConnPool }
+
+func (p *dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunPool) Commit() error   { return nil }
+func (*dryRunPool) Rollback() error { return nil }
+
+func TestDeleteByPrefix_ForeignDisk(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunPool{}}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var deletes []string
+	assert.NoError(t, db.Callback().Delete().Before("gorm:delete").Register("test:record", func(tx *gorm.DB) {
+		deletes = append(deletes, tx.Statement.Table)
+	}))
+
+	// The disk is not found in the project (dry runs count nothing): nothing is deleted
+	r := NewArtifactRepo(db, nil, nil)
+	deleted, err := r.DeleteByPrefix(context.Background(), uuid.New(), uuid.New(), "/tmp/")
+	assert.ErrorIs(t, err, ErrDiskNotFound)
+	assert.Zero(t, deleted)
+	assert.Empty(t, deletes)
+}
+
+func TestCreate_TakenPath(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunPool{}}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var sql string
+	assert.NoError(t, db.Callback().Create().After("gorm:create").Register("test:record", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}))
+
+	// A concurrent create of the same file inserts nothing (as dry runs do)
+	r := NewArtifactRepo(db, nil, nil)
+	err = r.Create(context.Background(), uuid.New(), &model.Artifact{DiskID: uuid.New(), Path: "/", Filename: "a.txt"})
+	assert.ErrorIs(t, err, ErrArtifactExists)
+	assert.Contains(t, sql, `ON CONFLICT ("disk_id","path","filename") DO NOTHING`)
+}
+
+func TestClaimUpload(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunPool{}}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var claim *gorm.Statement
+	assert.NoError(t, db.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		claim = tx.Statement
+	}))
+
+	id, projectID, diskID := uuid.New(), uuid.New(), uuid.New()
+	staleBefore := time.Now().Add(-time.Minute)
+	r := NewArtifactRepo(db, nil, nil)
+	_, _ = r.ClaimUpload(context.Background(), projectID, diskID, id, staleBefore)
+
+	// Only one completion can turn an unclaimed (or stale) row into a claimed one
+	if assert.NotNil(t, claim) {
+		sql := claim.SQL.String()
+		assert.Contains(t, sql, `UPDATE "artifact_uploads" SET "claimed_at"=$1`)
+		assert.Contains(t, sql, "(claimed_at IS NULL OR claimed_at < $5)")
+		assert.Contains(t, sql, "RETURNING *")
+		assert.Equal(t, []interface{}{id, projectID, diskID, staleBefore}, claim.Vars[1:])
+	}
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm/clause"
+)
+
+func (r *artifactRepo) CreateUpload(ctx context.Context, u *model.ArtifactUpload) error {
+	return r.db.WithContext(ctx).Create(u).Error
+}
+
+// ClaimUpload marks a pending upload of the disk, scoped to the project that started it,
+// as being completed and returns it, so concurrent completions don't both store it.
+// It returns ErrUploadClaimed while a claim made after staleBefore holds the upload.
+func (r *artifactRepo) ClaimUpload(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, id uuid.UUID, staleBefore time.Time) (*model.ArtifactUpload, error) {
+	db := r.db.WithContext(ctx)
+	var u model.ArtifactUpload
+	res := db.Model(&u).Clauses(clause.Returning{}).
+		Where("id = ? AND project_id = ? AND disk_id = ?", id, projectID, diskID).
+		Where("(claimed_at IS NULL OR claimed_at < ?)", staleBefore).
+		Update("claimed_at", time.Now())
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if err := db.Where("id = ? AND project_id = ? AND disk_id = ?", id, projectID, diskID).
+			First(&model.ArtifactUpload{}).Error; err != nil {
+			return nil, err
+		}
+		return nil, ErrUploadClaimed
+	}
+	return &u, nil
+}
+
+// ReleaseUpload drops the claim on an upload whose completion failed, so it can be retried.
+func (r *artifactRepo) ReleaseUpload(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&model.ArtifactUpload{}).Where("id = ?", id).Update("claimed_at", nil).Error
+}
+
+// ListExpiredUploads returns up to limit uploads, of any project, that expired before the given time.
+func (r *artifactRepo) ListExpiredUploads(ctx context.Context, before time.Time, limit int) ([]*model.ArtifactUpload, error) {
+	var uploads []*model.ArtifactUpload
+	err := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Order("expires_at").
+		Limit(limit).
+		Find(&uploads).Error
+	if err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+func (r *artifactRepo) DeleteUpload(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.ArtifactUpload{}).Error
+}
@@ -2,31 +2,89 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrSessionNotFound is returned when a disk is linked to a session outside its project
+var ErrSessionNotFound = errors.New("session not found")
+
+// DiskFilter narrows ListWithCursor; zero fields don't filter.
+type DiskFilter struct {
+	UserIdentifier string
+	NamePrefix     string
+	// Meta matches disks whose meta contains it
+	Meta      map[string]interface{}
+	SessionID *uuid.UUID
+}
+
 type DiskRepo interface {
 	Create(ctx context.Context, d *model.Disk) error
+	Get(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error)
+	Update(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, apply func(d *model.Disk) error) (*model.Disk, error)
 	Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error
-	ListWithCursor(ctx context.Context, projectID uuid.UUID, userIdentifier string, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error)
+	Clone(ctx context.Context, projectID uuid.UUID, sourceDiskID uuid.UUID, d *model.Disk, check QuotaCheck) error
+	ListWithCursor(ctx context.Context, projectID uuid.UUID, filter DiskFilter, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error)
 }
 
 type diskRepo struct {
 	db                 *gorm.DB
 	assetReferenceRepo AssetReferenceRepo
+	notifier           DiskChangeNotifier
 }
 
-func NewDiskRepo(db *gorm.DB, assetReferenceRepo AssetReferenceRepo) DiskRepo {
-	return &diskRepo{db: db, assetReferenceRepo: assetReferenceRepo}
+// NewDiskRepo returns a DiskRepo; notifier may be nil.
+func NewDiskRepo(db *gorm.DB, assetReferenceRepo AssetReferenceRepo, notifier DiskChangeNotifier) DiskRepo {
+	return &diskRepo{db: db, assetReferenceRepo: assetReferenceRepo, notifier: notifier}
 }
 
 func (r *diskRepo) Create(ctx context.Context, d *model.Disk) error {
-	return r.db.WithContext(ctx).Create(d).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkSession(tx, d.ProjectID, d.SessionID); err != nil {
+			return err
+		}
+		return tx.Create(d).Error
+	})
+}
+
+func (r *diskRepo) Get(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	var disk model.Disk
+	if err := r.db.WithContext(ctx).Where("id = ? AND project_id = ?", diskID, projectID).First(&disk).Error; err != nil {
+		return nil, err
+	}
+	return &disk, nil
+}
+
+// Update locks a disk of the project, lets apply change its name, description, meta
+// and session link, and saves them. An error from apply aborts the update.
+func (r *diskRepo) Update(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, apply func(d *model.Disk) error) (*model.Disk, error) {
+	var disk model.Disk
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND project_id = ?", diskID, projectID).First(&disk).Error; err != nil {
+			return err
+		}
+		if err := apply(&disk); err != nil {
+			return err
+		}
+		if err := checkSession(tx, projectID, disk.SessionID); err != nil {
+			return err
+		}
+		return tx.Model(&disk).
+			Select("name", "description", "meta", "session_id", "delete_with_session").
+			Updates(&disk).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &disk, nil
 }
 
 func (r *diskRepo) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
@@ -38,48 +96,205 @@ func (r *diskRepo) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.
 			return err
 		}
 
-		// Query all artifacts before deletion to collect asset meta for reference decrement
-		// Artifacts will be automatically deleted by CASCADE when disk is deleted
-		var artifacts []model.Artifact
-		if err := tx.Where("disk_id = ?", diskID).Find(&artifacts).Error; err != nil {
-			return fmt.Errorf("query artifacts: %w", err)
+		assets, err := deleteDisk(tx, &disk)
+		if err != nil {
+			return err
+		}
+
+		// Batch decrement asset references
+		// Note: BatchDecrementAssetRefs uses its own DB connection and may involve S3 operations
+		// The database operations within BatchDecrementAssetRefs will not be part of this transaction,
+		// but the disk and artifacts deletion will be atomic
+		if len(assets) > 0 {
+			if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, assets); err != nil {
+				return fmt.Errorf("decrement asset references: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// deleteDisk deletes a disk with its artifacts, versions and snapshots, and returns
+// the assets whose references the caller releases.
+func deleteDisk(tx *gorm.DB, disk *model.Disk) ([]model.Asset, error) {
+	diskID := disk.ID
+
+	// Query all artifacts before deletion to collect asset meta for reference decrement
+	// Artifacts will be automatically deleted by CASCADE when disk is deleted
+	var artifacts []model.Artifact
+	if err := tx.Where("disk_id = ?", diskID).Find(&artifacts).Error; err != nil {
+		return nil, fmt.Errorf("query artifacts: %w", err)
+	}
+
+	// Artifact versions hold their own references and are deleted by CASCADE as well
+	var versions []model.ArtifactVersion
+	if err := tx.Where("disk_id = ?", diskID).Find(&versions).Error; err != nil {
+		return nil, fmt.Errorf("query artifact versions: %w", err)
+	}
+
+	// Snapshot entries reference their assets too
+	var snapshotArtifacts []model.DiskSnapshotArtifact
+	if err := tx.Select("asset_meta").
+		Where("snapshot_id IN (?)", tx.Model(&model.DiskSnapshot{}).Select("id").Where("disk_id = ?", diskID)).
+		Find(&snapshotArtifacts).Error; err != nil {
+		return nil, fmt.Errorf("query snapshot artifacts: %w", err)
+	}
+
+	// Collect asset meta from all artifacts, versions and snapshots for batch decrement
+	assets := make([]model.Asset, 0, len(artifacts)+len(versions)+len(snapshotArtifacts))
+	for _, artifact := range artifacts {
+		asset := artifact.AssetMeta.Data()
+		if asset.SHA256 != "" {
+			assets = append(assets, asset)
+		}
+	}
+	for _, version := range versions {
+		asset := version.AssetMeta.Data()
+		if asset.SHA256 != "" {
+			assets = append(assets, asset)
+		}
+	}
+	for _, entry := range snapshotArtifacts {
+		asset := entry.AssetMeta.Data()
+		if asset.SHA256 != "" {
+			assets = append(assets, asset)
+		}
+	}
+
+	// Delete the disk (artifacts will be deleted automatically by CASCADE)
+	if err := tx.Delete(disk).Error; err != nil {
+		return nil, fmt.Errorf("delete disk: %w", err)
+	}
+	return assets, nil
+}
+
+// Clone creates d with a copy of every artifact of the source disk. The copies
+// share the source S3 objects and add a reference each, and their search chunks
+// are copied rather than recomputed; version history is not copied.
+// A nil d.UserID inherits the source disk's user. A non-nil check admits the
+// content and files of the source, once d is created.
+func (r *diskRepo) Clone(ctx context.Context, projectID uuid.UUID, sourceDiskID uuid.UUID, d *model.Disk, check QuotaCheck) error {
+	var changes []*model.DiskChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var source model.Disk
+		if err := tx.Where("id = ? AND project_id = ?", sourceDiskID, projectID).First(&source).Error; err != nil {
+			return err
+		}
+
+		d.ProjectID = projectID
+		if d.UserID == nil {
+			d.UserID = source.UserID
+		}
+		if err := tx.Create(d).Error; err != nil {
+			return fmt.Errorf("create disk: %w", err)
+		}
+
+		if check != nil {
+			var add StorageUsage
+			if err := tx.Raw(
+				`SELECT
+					(SELECT COALESCE(SUM(size_b), 0) FROM disk_assets WHERE disk_id = ?) AS bytes,
+					(SELECT COUNT(*) FROM artifacts WHERE disk_id = ?) AS files`,
+				sourceDiskID, sourceDiskID,
+			).Scan(&add).Error; err != nil {
+				return fmt.Errorf("query source usage: %w", err)
+			}
+			if err := check(add.Bytes, add.Files); err != nil {
+				return err
+			}
+		}
+
+		// Copy the rows in SQL: large template disks never pass through the API server
+		if err := tx.Exec(
+			`INSERT INTO artifacts (disk_id, path, filename, meta, asset_meta, version, created_at, updated_at)
+			SELECT ?, path, filename, meta, asset_meta, 1, NOW(), NOW() FROM artifacts WHERE disk_id = ?`,
+			d.ID, sourceDiskID,
+		).Error; err != nil {
+			return fmt.Errorf("copy artifacts: %w", err)
+		}
+		if err := tx.Exec(
+			`INSERT INTO artifact_directories (disk_id, path, created_at)
+			SELECT ?, path, NOW() FROM artifact_directories WHERE disk_id = ?`,
+			d.ID, sourceDiskID,
+		).Error; err != nil {
+			return fmt.Errorf("copy directories: %w", err)
+		}
+		// The copies have the same content, hence the same chunks and embeddings
+		if err := tx.Exec(
+			`INSERT INTO artifact_chunks (artifact_id, disk_id, chunk_index, content, start_offset, end_offset,
+				start_line, end_line, model, embedding, created_at)
+			SELECT n.id, n.disk_id, c.chunk_index, c.content, c.start_offset, c.end_offset,
+				c.start_line, c.end_line, c.model, c.embedding, NOW()
+			FROM artifact_chunks AS c
+			JOIN artifacts AS o ON o.id = c.artifact_id
+			JOIN artifacts AS n ON n.disk_id = ? AND n.path = o.path AND n.filename = o.filename
+			WHERE o.disk_id = ?`,
+			d.ID, sourceDiskID,
+		).Error; err != nil {
+			return fmt.Errorf("copy chunks: %w", err)
 		}
 
-		// Collect asset meta from all artifacts for batch decrement
+		var artifacts []model.Artifact
+		if err := tx.Select("disk_id", "path", "filename", "asset_meta").Where("disk_id = ?", d.ID).Find(&artifacts).Error; err != nil {
+			return fmt.Errorf("query copied artifacts: %w", err)
+		}
 		assets := make([]model.Asset, 0, len(artifacts))
-		for _, artifact := range artifacts {
-			asset := artifact.AssetMeta.Data()
+		// The clone's log starts with a create per artifact, like a disk filled file by file
+		changes = make([]*model.DiskChange, 0, len(artifacts))
+		for i := range artifacts {
+			asset := artifacts[i].AssetMeta.Data()
 			if asset.SHA256 != "" {
 				assets = append(assets, asset)
 			}
+			changes = append(changes, changeOf(ctx, model.DiskChangeCreate, &artifacts[i]))
+		}
+		if err := recordChanges(tx, changes); err != nil {
+			return err
 		}
 
-		// Delete the disk (artifacts will be deleted automatically by CASCADE)
-		if err := tx.Delete(&disk).Error; err != nil {
-			return fmt.Errorf("delete disk: %w", err)
+		if err := addDiskAssets(tx, d.ID, assets); err != nil {
+			return fmt.Errorf("add disk assets: %w", err)
 		}
 
-		// Batch decrement asset references
-		// Note: BatchDecrementAssetRefs uses its own DB connection and may involve S3 operations
-		// The database operations within BatchDecrementAssetRefs will not be part of this transaction,
-		// but the disk and artifacts deletion will be atomic
+		// Incremented inside the transaction callback: should the commit fail,
+		// the references are over-counted (a leak), never under-counted
 		if len(assets) > 0 {
-			if err := r.assetReferenceRepo.BatchDecrementAssetRefs(ctx, projectID, assets); err != nil {
-				return fmt.Errorf("decrement asset references: %w", err)
+			if err := r.assetReferenceRepo.BatchIncrementAssetRefs(ctx, projectID, assets); err != nil {
+				return fmt.Errorf("increment asset references: %w", err)
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	notifyChanges(ctx, r.notifier, projectID, changes)
+	return nil
 }
 
-func (r *diskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, userIdentifier string, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error) {
+func (r *diskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, filter DiskFilter, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error) {
 	q := r.db.WithContext(ctx).Where("disks.project_id = ?", projectID)
 
 	// Filter by user identifier if provided
-	if userIdentifier != "" {
+	if filter.UserIdentifier != "" {
 		q = q.Joins("JOIN users ON users.id = disks.user_id").
-			Where("users.identifier = ?", userIdentifier)
+			Where("users.identifier = ?", filter.UserIdentifier)
+	}
+	if filter.NamePrefix != "" {
+		q = q.Where("disks.name LIKE ? ESCAPE '\\'", escapeLike(filter.NamePrefix)+"%")
+	}
+	// JSONB containment, served by the GIN index on meta
+	if len(filter.Meta) > 0 {
+		jsonBytes, err := json.Marshal(filter.Meta)
+		if err != nil {
+			return nil, fmt.Errorf("marshal meta filter: %w", err)
+		}
+		q = q.Where("disks.meta @> ?", string(jsonBytes))
+	}
+	if filter.SessionID != nil {
+		q = q.Where("disks.session_id = ?", *filter.SessionID)
 	}
 
 	// Apply cursor-based pagination filter if cursor is provided
@@ -104,3 +319,18 @@ func (r *diskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, user
 	var disks []*model.Disk
 	return disks, q.Order(orderBy).Limit(limit).Find(&disks).Error
 }
+
+// checkSession returns ErrSessionNotFound unless sessionID is nil or belongs to the project.
+func checkSession(tx *gorm.DB, projectID uuid.UUID, sessionID *uuid.UUID) error {
+	if sessionID == nil {
+		return nil
+	}
+	var n int64
+	if err := tx.Model(&model.Session{}).Where("id = ? AND project_id = ?", *sessionID, projectID).Count(&n).Error; err != nil {
+		return fmt.Errorf("check session: %w", err)
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package repo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// addDiskAssets adds a disk reference per asset, within the caller's transaction.
+func addDiskAssets(tx *gorm.DB, diskID uuid.UUID, assets []model.Asset) error {
+	counts, sizes := countAssets(assets)
+	if len(counts) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]model.DiskAsset, 0, len(counts))
+	for _, sha := range sortedKeys(counts) {
+		rows = append(rows, model.DiskAsset{
+			DiskID:    diskID,
+			SHA256:    sha,
+			SizeB:     sizes[sha],
+			RefCount:  counts[sha],
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "disk_id"}, {Name: "sha256"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"ref_count":  gorm.Expr("disk_assets.ref_count + EXCLUDED.ref_count"),
+			"updated_at": now,
+		}),
+	}).Omit(clause.Associations).Create(&rows).Error
+}
+
+// releaseDiskAssets removes a disk reference per asset, within the caller's transaction,
+// and deletes the rows no longer referenced. Only the hashes of assets are used.
+func releaseDiskAssets(tx *gorm.DB, diskID uuid.UUID, assets []model.Asset) error {
+	counts, _ := countAssets(assets)
+	if len(counts) == 0 {
+		return nil
+	}
+
+	// Sorted so concurrent releases lock rows in the same order
+	for _, sha := range sortedKeys(counts) {
+		if err := tx.Model(&model.DiskAsset{}).
+			Where("disk_id = ? AND sha256 = ?", diskID, sha).
+			Updates(map[string]any{
+				"ref_count":  gorm.Expr("GREATEST(ref_count - ?, 0)", counts[sha]),
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("disk_id = ? AND ref_count = 0", diskID).Delete(&model.DiskAsset{}).Error
+}
+
+// moveDiskAssets moves the references of assets from one disk to another.
+func moveDiskAssets(tx *gorm.DB, fromDiskID uuid.UUID, toDiskID uuid.UUID, assets []model.Asset) error {
+	if fromDiskID == toDiskID {
+		return nil
+	}
+	if err := releaseDiskAssets(tx, fromDiskID, assets); err != nil {
+		return err
+	}
+	return addDiskAssets(tx, toDiskID, assets)
+}
+
+func countAssets(assets []model.Asset) (map[string]int, map[string]int64) {
+	counts := make(map[string]int, len(assets))
+	sizes := make(map[string]int64, len(assets))
+	for _, a := range assets {
+		if a.SHA256 == "" {
+			continue
+		}
+		counts[a.SHA256]++
+		sizes[a.SHA256] = a.SizeB
+	}
+	return counts, sizes
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BackfillDiskAssets rebuilds the disk references from the existing artifacts and versions.
+// It is run once when the disk_assets table is created.
+func BackfillDiskAssets(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM disk_assets").Error; err != nil {
+			return err
+		}
+		return tx.Exec(
+			`INSERT INTO disk_assets (disk_id, sha256, size_b, ref_count, created_at, updated_at)
+			SELECT disk_id, sha256, MAX(size_b), COUNT(*), NOW(), NOW() FROM (
+				SELECT disk_id, asset_meta->>'sha256' AS sha256, COALESCE((asset_meta->>'size_b')::bigint, 0) AS size_b FROM artifacts
+				UNION ALL
+				SELECT disk_id, asset_meta->>'sha256', COALESCE((asset_meta->>'size_b')::bigint, 0) FROM artifact_versions
+			) refs
+			WHERE sha256 IS NOT NULL AND sha256 <> ''
+			GROUP BY disk_id, sha256`,
+		).Error
+	})
+}
@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+// DiskChangeNotifier receives the changes of each committed artifact mutation.
+// It runs after the commit, so it must not fail the mutation.
+type DiskChangeNotifier interface {
+	NotifyDiskChanges(ctx context.Context, projectID uuid.UUID, changes []*model.DiskChange)
+}
+
+type actorKey struct{}
+
+// WithActor returns a context whose artifact mutations are logged with actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return model.DiskChangeActorAPI
+}
+
+// changeOf describes a of type typ, by its current location and content.
+func changeOf(ctx context.Context, typ string, a *model.Artifact) *model.DiskChange {
+	c := &model.DiskChange{
+		DiskID:   a.DiskID,
+		Type:     typ,
+		Actor:    actorFrom(ctx),
+		Path:     a.Path,
+		Filename: a.Filename,
+	}
+	if typ == model.DiskChangeDelete {
+		c.OldSHA256 = a.AssetMeta.Data().SHA256
+	} else {
+		c.NewSHA256 = a.AssetMeta.Data().SHA256
+	}
+	return c
+}
+
+// recordChanges appends changes to the logs of their disks, within tx. Sequence numbers
+// come from a counter on the disk row, whose lock orders concurrent writers of a disk
+// until they commit: a change is never visible before one with a lower seq.
+func recordChanges(tx *gorm.DB, changes []*model.DiskChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	counts := make(map[uuid.UUID]int64)
+	for _, c := range changes {
+		counts[c.DiskID]++
+	}
+	// Lock disks in a fixed order against deadlocks between cross-disk transfers
+	diskIDs := make([]uuid.UUID, 0, len(counts))
+	for id := range counts {
+		diskIDs = append(diskIDs, id)
+	}
+	sort.Slice(diskIDs, func(i, j int) bool { return diskIDs[i].String() < diskIDs[j].String() })
+
+	next := make(map[uuid.UUID]int64, len(diskIDs))
+	for _, id := range diskIDs {
+		var last int64
+		if err := tx.Raw(
+			"UPDATE disks SET change_seq = change_seq + ? WHERE id = ? RETURNING change_seq",
+			counts[id], id,
+		).Scan(&last).Error; err != nil {
+			return fmt.Errorf("advance change seq: %w", err)
+		}
+		next[id] = last - counts[id] + 1
+	}
+	for _, c := range changes {
+		c.Seq = next[c.DiskID]
+		next[c.DiskID]++
+	}
+
+	if err := tx.CreateInBatches(changes, 500).Error; err != nil {
+		return fmt.Errorf("record changes: %w", err)
+	}
+	return nil
+}
+
+// notifyChanges hands committed changes to notifier, if any.
+func notifyChanges(ctx context.Context, notifier DiskChangeNotifier, projectID uuid.UUID, changes []*model.DiskChange) {
+	if notifier == nil || len(changes) == 0 {
+		return
+	}
+	notifier.NotifyDiskChanges(ctx, projectID, changes)
+}
+
+func (r *artifactRepo) notify(ctx context.Context, projectID uuid.UUID, changes []*model.DiskChange) {
+	notifyChanges(ctx, r.notifier, projectID, changes)
+}
+
+// ListChanges returns up to limit changes of a disk with a seq above since, oldest first.
+func (r *artifactRepo) ListChanges(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, since int64, limit int) ([]*model.DiskChange, error) {
+	if err := checkDisk(r.db.WithContext(ctx), projectID, diskID); err != nil {
+		return nil, err
+	}
+
+	var changes []*model.DiskChange
+	if err := r.db.WithContext(ctx).
+		Where("disk_id = ? AND seq > ?", diskID, since).
+		Order("seq ASC").
+		Limit(limit).
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -93,6 +93,21 @@ func (r *sessionRepo) Delete(ctx context.Context, projectID uuid.UUID, sessionID
 			}
 		}
 
+		// Disks flagged delete_with_session go with the session; other linked disks
+		// are unlinked by ON DELETE SET NULL
+		var disks []model.Disk
+		if err := tx.Where("project_id = ? AND session_id = ? AND delete_with_session", projectID, sessionID).
+			Find(&disks).Error; err != nil {
+			return fmt.Errorf("query session disks: %w", err)
+		}
+		for i := range disks {
+			diskAssets, err := deleteDisk(tx, &disks[i])
+			if err != nil {
+				return err
+			}
+			assets = append(assets, diskAssets...)
+		}
+
 		// Delete the session (messages will be automatically deleted by CASCADE)
 		if err := tx.Delete(&session).Error; err != nil {
 			return fmt.Errorf("delete session: %w", err)
@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"gorm.io/gorm"
+)
+
+// StorageUsage is the stored content of a scope. Content stored several times
+// within the scope is counted once in Bytes; Files counts current artifacts.
+// PendingBytes is the declared size of direct uploads not completed yet.
+type StorageUsage struct {
+	Bytes        int64 `json:"bytes"`
+	Files        int64 `json:"files"`
+	PendingBytes int64 `json:"pending_bytes"`
+}
+
+// DiskStorageUsage is the usage of one disk.
+type DiskStorageUsage struct {
+	DiskID uuid.UUID `json:"disk_id"`
+	StorageUsage
+}
+
+// QuotaCheck is called once within a write transaction, when the bytes and files
+// the write adds are known; an error aborts the write.
+type QuotaCheck func(addBytes int64, addFiles int64) error
+
+type UsageRepo interface {
+	// DiskUsage returns the usage of a disk of the project, and of the user owning it (nil without one).
+	DiskUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (disk *StorageUsage, user *StorageUsage, err error)
+	// UserUsage returns the usage across the disks of a user of the project.
+	UserUsage(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (*StorageUsage, error)
+	// ProjectUsage returns the usage of a project. Bytes include message assets.
+	ProjectUsage(ctx context.Context, projectID uuid.UUID) (*StorageUsage, error)
+	// TopDisks returns the disks of a project using the most bytes, and the project's disk count.
+	TopDisks(ctx context.Context, projectID uuid.UUID, limit int) ([]DiskStorageUsage, int64, error)
+}
+
+type usageRepo struct{ db *gorm.DB }
+
+func NewUsageRepo(db *gorm.DB) UsageRepo {
+	return &usageRepo{db: db}
+}
+
+func (r *usageRepo) DiskUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*StorageUsage, *StorageUsage, error) {
+	db := r.db.WithContext(ctx)
+
+	var disk model.Disk
+	if err := db.Where("id = ? AND project_id = ?", diskID, projectID).First(&disk).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var du StorageUsage
+	if err := db.Raw(
+		`SELECT
+			(SELECT COALESCE(SUM(size_b), 0) FROM disk_assets WHERE disk_id = ?) AS bytes,
+			(SELECT COUNT(*) FROM artifacts WHERE disk_id = ?) AS files,
+			(SELECT COALESCE(SUM(size_b), 0) FROM artifact_uploads WHERE disk_id = ?) AS pending_bytes`,
+		diskID, diskID, diskID,
+	).Scan(&du).Error; err != nil {
+		return nil, nil, err
+	}
+	if disk.UserID == nil {
+		return &du, nil, nil
+	}
+
+	uu, err := r.UserUsage(ctx, projectID, *disk.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &du, uu, nil
+}
+
+func (r *usageRepo) UserUsage(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (*StorageUsage, error) {
+	db := r.db.WithContext(ctx)
+
+	// Content shared by several disks of the user counts once
+	var uu StorageUsage
+	userDisks := db.Model(&model.Disk{}).Select("id").Where("project_id = ? AND user_id = ?", projectID, userID)
+	if err := db.Raw(
+		`SELECT
+			(SELECT COALESCE(SUM(size_b), 0) FROM (
+				SELECT DISTINCT ON (sha256) size_b FROM disk_assets WHERE disk_id IN (?) ORDER BY sha256
+			) dedup) AS bytes,
+			(SELECT COUNT(*) FROM artifacts WHERE disk_id IN (?)) AS files,
+			(SELECT COALESCE(SUM(size_b), 0) FROM artifact_uploads WHERE disk_id IN (?)) AS pending_bytes`,
+		userDisks, userDisks, userDisks,
+	).Scan(&uu).Error; err != nil {
+		return nil, err
+	}
+	return &uu, nil
+}
+
+func (r *usageRepo) ProjectUsage(ctx context.Context, projectID uuid.UUID) (*StorageUsage, error) {
+	// Asset references are deduplicated per project and cover artifacts and messages alike
+	var u StorageUsage
+	if err := r.db.WithContext(ctx).Raw(
+		`SELECT
+			(SELECT COALESCE(SUM((asset_meta->>'size_b')::bigint), 0) FROM asset_references WHERE project_id = ?) AS bytes,
+			(SELECT COUNT(*) FROM artifacts JOIN disks ON disks.id = artifacts.disk_id WHERE disks.project_id = ?) AS files,
+			(SELECT COALESCE(SUM(size_b), 0) FROM artifact_uploads WHERE project_id = ?) AS pending_bytes`,
+		projectID, projectID, projectID,
+	).Scan(&u).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *usageRepo) TopDisks(ctx context.Context, projectID uuid.UUID, limit int) ([]DiskStorageUsage, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var count int64
+	if err := db.Model(&model.Disk{}).Where("project_id = ?", projectID).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var disks []DiskStorageUsage
+	if err := db.Raw(
+		`SELECT disks.id AS disk_id,
+			COALESCE((SELECT SUM(size_b) FROM disk_assets WHERE disk_id = disks.id), 0) AS bytes,
+			(SELECT COUNT(*) FROM artifacts WHERE disk_id = disks.id) AS files,
+			COALESCE((SELECT SUM(size_b) FROM artifact_uploads WHERE disk_id = disks.id), 0) AS pending_bytes
+		FROM disks WHERE disks.project_id = ?
+		ORDER BY bytes DESC, disks.id
+		LIMIT ?`,
+		projectID, limit,
+	).Scan(&disks).Error; err != nil {
+		return nil, 0, err
+	}
+	return disks, count, nil
+}
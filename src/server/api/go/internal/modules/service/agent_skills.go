@@ -1,7 +1,6 @@
 package service
 
 import (
-	"archive/zip"
 	"bytes"
 	"context"
 	"errors"
@@ -14,6 +13,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/pkg/paging"
@@ -27,7 +27,7 @@ type AgentSkillsService interface {
 	GetByID(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*model.AgentSkills, error)
 	Delete(ctx context.Context, projectID uuid.UUID, id uuid.UUID) error
 	List(ctx context.Context, in ListAgentSkillsInput) (*ListAgentSkillsOutput, error)
-	GetFile(ctx context.Context, projectID uuid.UUID, skillID uuid.UUID, filePath string, expire time.Duration) (*GetFileOutput, error)
+	GetFile(ctx context.Context, projectID uuid.UUID, skillID uuid.UUID, filePath string, expire time.Duration, cond Preconditions) (*GetFileOutput, error)
 	ListFiles(ctx context.Context, projectID uuid.UUID, id uuid.UUID) (*ListFilesOutput, error)
 }
 
@@ -175,47 +175,28 @@ func (s *agentSkillsService) Create(ctx context.Context, in CreateAgentSkillsInp
 		return nil, fmt.Errorf("read zip file: %w", err)
 	}
 
-	zipReader, err := zip.NewReader(bytes.NewReader(zipContent), int64(len(zipContent)))
-	if err != nil {
-		return nil, fmt.Errorf("open zip archive: %w", err)
-	}
-
 	var skillName, skillDescription string
 	var skillMetadataFound bool
-	var rootPrefix string
 	var fileNames []string
 	filesToUpload := make([]*zipFileData, 0)
 
-	for _, file := range zipReader.File {
-		if file.FileInfo().IsDir() {
-			continue
-		}
-
-		if isMacOSSystemFile(file.Name) {
-			continue
-		}
-
-		fileReader, err := file.Open()
+	// Entries escaping the skill root are rejected, macOS metadata is skipped
+	err = walkArchive(bytes.NewReader(zipContent), int64(len(zipContent)), ArchiveZip, archiveLimits{}, func(name string, body io.Reader) error {
+		fileContent, err := io.ReadAll(body)
 		if err != nil {
-			return nil, fmt.Errorf("open file in zip: %w", err)
+			return fmt.Errorf("read file in zip: %w", err)
 		}
 
-		fileContent, err := io.ReadAll(fileReader)
-		fileReader.Close()
-		if err != nil {
-			return nil, fmt.Errorf("read file in zip: %w", err)
-		}
-
-		fileName := filepath.Base(file.Name)
+		fileName := stdpath.Base(name)
 		if strings.EqualFold(fileName, "SKILL.md") && !skillMetadataFound {
 			yamlContent := extractYAMLFrontMatter(fileContent)
 			if yamlContent == "" {
-				return nil, errors.New("SKILL.md must contain YAML front matter")
+				return errors.New("SKILL.md must contain YAML front matter")
 			}
 
 			var metadata SkillMetadata
 			if err := yaml.Unmarshal([]byte(yamlContent), &metadata); err != nil {
-				return nil, fmt.Errorf("parse SKILL.md YAML: %w", err)
+				return fmt.Errorf("parse SKILL.md YAML: %w", err)
 			}
 
 			skillName = metadata.Name
@@ -223,50 +204,32 @@ func (s *agentSkillsService) Create(ctx context.Context, in CreateAgentSkillsInp
 			skillMetadataFound = true
 
 			if skillName == "" {
-				return nil, errors.New("name is required in SKILL.md")
+				return errors.New("name is required in SKILL.md")
 			}
 			if skillDescription == "" {
-				return nil, errors.New("description is required in SKILL.md")
+				return errors.New("description is required in SKILL.md")
 			}
 		}
 
-		fileNames = append(fileNames, file.Name)
+		fileNames = append(fileNames, name)
 		filesToUpload = append(filesToUpload, &zipFileData{
-			name:    file.Name,
+			name:    name,
 			content: fileContent,
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	if !skillMetadataFound {
 		return nil, errors.New("SKILL.md file is required in the zip package")
 	}
 
-	// Detect root prefix for stripping
-	if len(fileNames) > 0 {
-		firstFile := fileNames[0]
-		parts := strings.Split(firstFile, "/")
-		if len(parts) > 1 && parts[0] != "" {
-			outermostDir := parts[0]
-			allUnderSameRoot := true
-			for _, fileName := range fileNames {
-				fileParts := strings.Split(fileName, "/")
-				if len(fileParts) == 0 || fileParts[0] != outermostDir {
-					allUnderSameRoot = false
-					break
-				}
-			}
-			if allUnderSameRoot {
-				rootPrefix = outermostDir + "/"
-			}
-		}
-	}
-
+	// Strip the outermost directory when every file is inside it
+	rootPrefix := archiveRoot(fileNames)
 	for _, fileData := range filesToUpload {
-		relativePath := fileData.name
-		if rootPrefix != "" && strings.HasPrefix(fileData.name, rootPrefix) {
-			relativePath = strings.TrimPrefix(fileData.name, rootPrefix)
-		}
-		fileData.relativePath = relativePath
+		fileData.relativePath = strings.TrimPrefix(fileData.name, rootPrefix)
 	}
 
 	// Sanitize skill name for DB storage and sandbox paths
@@ -274,7 +237,7 @@ func (s *agentSkillsService) Create(ctx context.Context, in CreateAgentSkillsInp
 
 	// ── Phase 2: Disk + Artifact uploads (cleanup required on failure) ──
 
-	disk, err := s.diskSvc.Create(ctx, in.ProjectID, in.UserID)
+	disk, err := s.diskSvc.Create(ctx, CreateDiskInput{ProjectID: in.ProjectID, UserID: in.UserID})
 	if err != nil {
 		return nil, fmt.Errorf("create disk: %w", err)
 	}
@@ -449,9 +412,15 @@ type GetFileOutput struct {
 	MIME    string                  `json:"mime"`
 	Content *fileparser.FileContent `json:"content,omitempty"` // Present if file is text-based and parseable
 	URL     *string                 `json:"url,omitempty"`     // Present if file is not text-based or not parseable
+	ETag    string                  `json:"-"`
+
+	// Why url is missing for a file that is not parseable, e.g. "encrypted"
+	URLUnavailable string `json:"url_unavailable,omitempty"`
 }
 
-func (s *agentSkillsService) GetFile(ctx context.Context, projectID uuid.UUID, skillID uuid.UUID, filePath string, expire time.Duration) (*GetFileOutput, error) {
+// GetFile returns a file of a skill. When cond.IfNoneMatch matches the file, the output only
+// holds the path, MIME type and ETag, along with ErrNotModified.
+func (s *agentSkillsService) GetFile(ctx context.Context, projectID uuid.UUID, skillID uuid.UUID, filePath string, expire time.Duration, cond Preconditions) (*GetFileOutput, error) {
 	// Fetch skill record directly (no FileIndex population needed — just need DiskID)
 	skill, err := s.r.GetByID(ctx, projectID, skillID)
 	if err != nil {
@@ -467,16 +436,21 @@ func (s *agentSkillsService) GetFile(ctx context.Context, projectID uuid.UUID, s
 		return nil, fmt.Errorf("file path '%s' not found in agent_skills: %w", filePath, err)
 	}
 
-	mimeType := artifact.AssetMeta.Data().MIME
-
-	parser := fileparser.NewFileParser()
-	canParse := parser.CanParseFile(fname, mimeType)
+	assetData := artifact.AssetMeta.Data()
+	mimeType := assetData.MIME
 
 	output := &GetFileOutput{
 		Path: filePath,
 		MIME: mimeType,
+		ETag: ETag(assetData.SHA256),
+	}
+	if cond.NotModified(assetData.SHA256) {
+		return output, ErrNotModified
 	}
 
+	parser := fileparser.NewFileParser()
+	canParse := parser.CanParseFile(fname, mimeType)
+
 	if canParse {
 		// Download and parse file content via ArtifactService
 		fileContent, err := s.artifactSvc.GetFileContent(ctx, artifact)
@@ -487,10 +461,14 @@ func (s *agentSkillsService) GetFile(ctx context.Context, projectID uuid.UUID, s
 	} else {
 		// Generate presigned URL for non-text files via ArtifactService
 		url, err := s.artifactSvc.GetPresignedURL(ctx, artifact, expire)
-		if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrEncryptedObject):
+			output.URLUnavailable = PublicURLEncrypted
+		case err != nil:
 			return nil, fmt.Errorf("failed to generate presigned URL: %w", err)
+		default:
+			output.URL = &url
 		}
-		output.URL = &url
 	}
 
 	return output, nil
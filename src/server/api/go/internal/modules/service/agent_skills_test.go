@@ -5,11 +5,13 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io"
 	"mime/multipart"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
 	"github.com/stretchr/testify/assert"
@@ -64,8 +66,16 @@ type MockDiskService struct {
 	mock.Mock
 }
 
-func (m *MockDiskService) Create(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
-	args := m.Called(ctx, projectID, userID)
+func (m *MockDiskService) Create(ctx context.Context, in CreateDiskInput) (*model.Disk, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+func (m *MockDiskService) Update(ctx context.Context, in UpdateDiskInput) (*model.Disk, error) {
+	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -77,6 +87,14 @@ func (m *MockDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskI
 	return args.Error(0)
 }
 
+func (m *MockDiskService) Clone(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, diskID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
 func (m *MockDiskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOutput, error) {
 	args := m.Called(ctx, in)
 	if args.Get(0) == nil {
@@ -85,6 +103,22 @@ func (m *MockDiskService) List(ctx context.Context, in ListDisksInput) (*ListDis
 	return args.Get(0).(*ListDisksOutput), args.Error(1)
 }
 
+func (m *MockDiskService) GetUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DiskUsageOutput, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*DiskUsageOutput), args.Error(1)
+}
+
+func (m *MockDiskService) GetProjectUsage(ctx context.Context, projectID uuid.UUID, topDisks int) (*ProjectUsageOutput, error) {
+	args := m.Called(ctx, projectID, topDisks)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ProjectUsageOutput), args.Error(1)
+}
+
 // ── Mock: ArtifactService ──
 
 type MockArtifactService struct {
@@ -107,8 +141,29 @@ func (m *MockArtifactService) CreateFromBytes(ctx context.Context, in CreateArti
 	return args.Get(0).(*model.Artifact), args.Error(1)
 }
 
-func (m *MockArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
-	args := m.Called(ctx, projectID, diskID, path, filename)
+func (m *MockArtifactService) CreateUpload(ctx context.Context, in CreateUploadInput) (*UploadTarget, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UploadTarget), args.Error(1)
+}
+
+func (m *MockArtifactService) CompleteUpload(ctx context.Context, in CompleteUploadInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) SweepExpiredUploads(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, cond Preconditions) error {
+	args := m.Called(ctx, projectID, diskID, path, filename, cond)
 	return args.Error(0)
 }
 
@@ -133,8 +188,16 @@ func (m *MockArtifactService) GetFileContent(ctx context.Context, artifact *mode
 	return args.Get(0).(*fileparser.FileContent), args.Error(1)
 }
 
-func (m *MockArtifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error) {
-	args := m.Called(ctx, diskID, path, filename, userMeta)
+func (m *MockArtifactService) ReadFileContent(ctx context.Context, artifact *model.Artifact, in ReadContentInput) (*fileparser.FileContent, *ContentRange, error) {
+	args := m.Called(ctx, artifact, in)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*fileparser.FileContent), args.Get(1).(*ContentRange), args.Error(2)
+}
+
+func (m *MockArtifactService) UpdateArtifactMetaByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}, cond Preconditions) (*model.Artifact, error) {
+	args := m.Called(ctx, diskID, path, filename, userMeta, cond)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -165,6 +228,14 @@ func (m *MockArtifactService) GrepArtifacts(ctx context.Context, projectID uuid.
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactService) GrepContent(ctx context.Context, in GrepContentInput) (*GrepContentOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*GrepContentOutput), args.Error(1)
+}
+
 func (m *MockArtifactService) GlobArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	args := m.Called(ctx, projectID, diskID, pattern, limit)
 	if args.Get(0) == nil {
@@ -173,6 +244,210 @@ func (m *MockArtifactService) GlobArtifacts(ctx context.Context, projectID uuid.
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactService) ListVersions(ctx context.Context, diskID uuid.UUID, path string, filename string) ([]*model.ArtifactVersion, error) {
+	args := m.Called(ctx, diskID, path, filename)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactVersion), args.Error(1)
+}
+
+func (m *MockArtifactService) GetVersion(ctx context.Context, diskID uuid.UUID, path string, filename string, version int) (*model.ArtifactVersion, error) {
+	args := m.Called(ctx, diskID, path, filename, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactVersion), args.Error(1)
+}
+
+func (m *MockArtifactService) DiffVersions(ctx context.Context, diskID uuid.UUID, path string, filename string, fromVersion int, toVersion int) (*ArtifactDiff, error) {
+	args := m.Called(ctx, diskID, path, filename, fromVersion, toVersion)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ArtifactDiff), args.Error(1)
+}
+
+func (m *MockArtifactService) RestoreVersion(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, version int) (*model.Artifact, error) {
+	args := m.Called(ctx, projectID, diskID, path, filename, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) EditArtifact(ctx context.Context, in EditArtifactInput) (*model.Artifact, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactService) MoveArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransferArtifactsOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) CopyArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransferArtifactsOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (int64, error) {
+	args := m.Called(ctx, projectID, diskID, path)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (*model.ArtifactDirectory, error) {
+	args := m.Called(ctx, projectID, diskID, path)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactDirectory), args.Error(1)
+}
+
+func (m *MockArtifactService) GetTree(ctx context.Context, diskID uuid.UUID, path string, depth int) (*ArtifactTreeNode, error) {
+	args := m.Called(ctx, diskID, path, depth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ArtifactTreeNode), args.Error(1)
+}
+
+func (m *MockArtifactService) SemanticSearch(ctx context.Context, in SemanticSearchInput) ([]*SemanticSearchHit, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*SemanticSearchHit), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, name string) (*model.DiskSnapshot, error) {
+	args := m.Called(ctx, projectID, diskID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.DiskSnapshot), args.Error(1)
+}
+
+func (m *MockArtifactService) ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DiskSnapshot), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error {
+	args := m.Called(ctx, projectID, diskID, snapshotID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactService) RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) (*RestoreSnapshotOutput, error) {
+	args := m.Called(ctx, projectID, diskID, snapshotID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RestoreSnapshotOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) CreateShare(ctx context.Context, in CreateShareInput) (*CreateShareOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CreateShareOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactShare), args.Error(1)
+}
+
+func (m *MockArtifactService) DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error {
+	args := m.Called(ctx, projectID, diskID, shareID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactService) OpenShare(ctx context.Context, token string, password string) (*model.ArtifactShare, error) {
+	args := m.Called(ctx, token, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactShare), args.Error(1)
+}
+
+func (m *MockArtifactService) ListShared(ctx context.Context, share *model.ArtifactShare, dir string) (*SharedListing, error) {
+	args := m.Called(ctx, share, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*SharedListing), args.Error(1)
+}
+
+func (m *MockArtifactService) OpenSharedFile(ctx context.Context, share *model.ArtifactShare, file string) (*model.Artifact, io.ReadCloser, error) {
+	args := m.Called(ctx, share, file)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*model.Artifact), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockArtifactService) OpenSharedArchive(ctx context.Context, share *model.ArtifactShare, dir string) (*ArtifactArchive, error) {
+	args := m.Called(ctx, share, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ArtifactArchive), args.Error(1)
+}
+
+func (m *MockArtifactService) ExtractArchive(ctx context.Context, in ExtractArchiveInput) (*ExtractArchiveOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ExtractArchiveOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) GetArchive(ctx context.Context, diskID uuid.UUID, dir string) (*ArtifactArchive, error) {
+	args := m.Called(ctx, diskID, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ArtifactArchive), args.Error(1)
+}
+
+func (m *MockArtifactService) QueryArtifacts(ctx context.Context, in QueryArtifactsInput) (*QueryArtifactsOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*QueryArtifactsOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) ListChanges(ctx context.Context, in ListDiskChangesInput) (*ListDiskChangesOutput, error) {
+	args := m.Called(ctx, in)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ListDiskChangesOutput), args.Error(1)
+}
+
+func (m *MockArtifactService) WriteZip(ctx context.Context, archive *ArtifactArchive, w io.Writer) error {
+	args := m.Called(ctx, archive, w)
+	return args.Error(0)
+}
+
 // ── Helpers ──
 
 func createTestZipFile(files map[string]string) ([]byte, error) {
@@ -375,7 +650,7 @@ func TestAgentSkillsService_Create_Success(t *testing.T) {
 	t.Run("basic creation", func(t *testing.T) {
 		m := newTestMocks()
 
-		m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+		m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 			Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 
 		m.artifact.On("CreateFromBytes", mock.Anything, mock.MatchedBy(func(in CreateArtifactFromBytesInput) bool {
@@ -425,7 +700,7 @@ func TestAgentSkillsService_Create_FileIndexIsArrayNotNull(t *testing.T) {
 	})
 
 	m := newTestMocks()
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 	m.artifact.On("CreateFromBytes", mock.Anything, mock.Anything).
 		Return(makeArtifact(diskID, "/", "SKILL.md", "text/markdown", "disks/hash"), nil)
@@ -454,7 +729,7 @@ func TestAgentSkillsService_Create_NameSanitization(t *testing.T) {
 	})
 
 	m := newTestMocks()
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 	m.artifact.On("CreateFromBytes", mock.Anything, mock.Anything).
 		Return(makeArtifact(diskID, "/", "SKILL.md", "text/markdown", "disks/hash"), nil)
@@ -536,7 +811,7 @@ func TestAgentSkillsService_Create_FailureMidUpload(t *testing.T) {
 	})
 
 	m := newTestMocks()
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 	m.artifact.On("CreateFromBytes", mock.Anything, mock.Anything).
 		Return(nil, errors.New("S3 upload failed"))
@@ -565,7 +840,7 @@ func TestAgentSkillsService_Create_FailureOnDBInsert(t *testing.T) {
 	})
 
 	m := newTestMocks()
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 	m.artifact.On("CreateFromBytes", mock.Anything, mock.Anything).
 		Return(makeArtifact(diskID, "/", "SKILL.md", "text/markdown", "disks/hash"), nil)
@@ -599,7 +874,7 @@ func TestAgentSkillsService_Create_MacOSFiltering(t *testing.T) {
 	})
 
 	m := newTestMocks()
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 
 	artifactCount := 0
@@ -635,7 +910,7 @@ func TestAgentSkillsService_Create_RootPrefixStripping(t *testing.T) {
 	})
 
 	m := newTestMocks()
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 
 	m.artifact.On("CreateFromBytes", mock.Anything, mock.MatchedBy(func(in CreateArtifactFromBytesInput) bool {
@@ -823,7 +1098,7 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		m.artifact.On("GetByPath", ctx, diskID, "/", "file1.json").Return(artifact, nil)
 		m.artifact.On("GetFileContent", ctx, artifact).Return(&fileparser.FileContent{Raw: `{"key": "value"}`}, nil)
 
-		result, err := m.service().GetFile(ctx, projectID, skillID, "file1.json", time.Hour)
+		result, err := m.service().GetFile(ctx, projectID, skillID, "file1.json", time.Hour, Preconditions{})
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -831,6 +1106,22 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		assert.NotNil(t, result.Content)
 	})
 
+	t.Run("file not modified", func(t *testing.T) {
+		m := newTestMocks()
+		m.repo.On("GetByID", ctx, projectID, skillID).Return(skill, nil)
+
+		artifact := makeArtifact(diskID, "/", "file1.json", "application/json", "disks/hash")
+		artifact.AssetMeta = datatypes.NewJSONType(model.Asset{S3Key: "disks/hash", MIME: "application/json", SHA256: "abc"})
+		m.artifact.On("GetByPath", ctx, diskID, "/", "file1.json").Return(artifact, nil)
+
+		result, err := m.service().GetFile(ctx, projectID, skillID, "file1.json", time.Hour, Preconditions{IfNoneMatch: `W/"abc"`})
+
+		assert.ErrorIs(t, err, ErrNotModified)
+		assert.Equal(t, ETag("abc"), result.ETag)
+		assert.Nil(t, result.Content)
+		m.artifact.AssertNotCalled(t, "GetFileContent", mock.Anything, mock.Anything)
+	})
+
 	t.Run("file with presigned URL (binary)", func(t *testing.T) {
 		m := newTestMocks()
 		m.repo.On("GetByID", ctx, projectID, skillID).Return(skill, nil)
@@ -839,7 +1130,7 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		m.artifact.On("GetByPath", ctx, diskID, "/", "image.png").Return(artifact, nil)
 		m.artifact.On("GetPresignedURL", ctx, artifact, time.Hour).Return("https://s3.example.com/url", nil)
 
-		result, err := m.service().GetFile(ctx, projectID, skillID, "image.png", time.Hour)
+		result, err := m.service().GetFile(ctx, projectID, skillID, "image.png", time.Hour, Preconditions{})
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -848,6 +1139,21 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		assert.Equal(t, "https://s3.example.com/url", *result.URL)
 	})
 
+	t.Run("encrypted binary file", func(t *testing.T) {
+		m := newTestMocks()
+		m.repo.On("GetByID", ctx, projectID, skillID).Return(skill, nil)
+
+		artifact := makeArtifact(diskID, "/", "image.png", "image/png", "disks/hash")
+		m.artifact.On("GetByPath", ctx, diskID, "/", "image.png").Return(artifact, nil)
+		m.artifact.On("GetPresignedURL", ctx, artifact, time.Hour).Return("", blob.ErrEncryptedObject)
+
+		result, err := m.service().GetFile(ctx, projectID, skillID, "image.png", time.Hour, Preconditions{})
+
+		assert.NoError(t, err)
+		assert.Nil(t, result.URL)
+		assert.Equal(t, PublicURLEncrypted, result.URLUnavailable)
+	})
+
 	t.Run("nested path resolves correctly", func(t *testing.T) {
 		m := newTestMocks()
 		m.repo.On("GetByID", ctx, projectID, skillID).Return(skill, nil)
@@ -856,7 +1162,7 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		m.artifact.On("GetByPath", ctx, diskID, "/scripts/sub/", "file.py").Return(artifact, nil)
 		m.artifact.On("GetFileContent", ctx, artifact).Return(&fileparser.FileContent{Raw: "print('hello')"}, nil)
 
-		result, err := m.service().GetFile(ctx, projectID, skillID, "scripts/sub/file.py", time.Hour)
+		result, err := m.service().GetFile(ctx, projectID, skillID, "scripts/sub/file.py", time.Hour, Preconditions{})
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -867,7 +1173,7 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		m := newTestMocks()
 		m.repo.On("GetByID", ctx, projectID, skillID).Return(nil, errors.New("not found"))
 
-		result, err := m.service().GetFile(ctx, projectID, skillID, "file1.json", time.Hour)
+		result, err := m.service().GetFile(ctx, projectID, skillID, "file1.json", time.Hour, Preconditions{})
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -879,7 +1185,7 @@ func TestAgentSkillsService_GetFile(t *testing.T) {
 		m.artifact.On("GetByPath", ctx, diskID, "/", "nonexistent.txt").
 			Return(nil, errors.New("not found"))
 
-		result, err := m.service().GetFile(ctx, projectID, skillID, "nonexistent.txt", time.Hour)
+		result, err := m.service().GetFile(ctx, projectID, skillID, "nonexistent.txt", time.Hour, Preconditions{})
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -947,7 +1253,7 @@ func TestAgentSkillsService_CreateAndDelete(t *testing.T) {
 	m := newTestMocks()
 
 	// Create
-	m.disk.On("Create", mock.Anything, projectID, mock.Anything).
+	m.disk.On("Create", mock.Anything, CreateDiskInput{ProjectID: projectID}).
 		Return(&model.Disk{ID: diskID, ProjectID: projectID}, nil)
 	m.artifact.On("CreateFromBytes", mock.Anything, mock.Anything).
 		Return(makeArtifact(diskID, "/", "SKILL.md", "text/markdown", "disks/hash"), nil)
@@ -1,42 +1,99 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
 	"github.com/memodb-io/Acontext/internal/infra/blob"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/embedding"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/diff"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/glob"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/grep"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/mime"
+	pathutil "github.com/memodb-io/Acontext/internal/pkg/utils/path"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/textrange"
+	"go.uber.org/zap"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type ArtifactService interface {
 	Create(ctx context.Context, in CreateArtifactInput) (*model.Artifact, error)
 	CreateFromBytes(ctx context.Context, in CreateArtifactFromBytesInput) (*model.Artifact, error)
-	DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error
+	CreateUpload(ctx context.Context, in CreateUploadInput) (*UploadTarget, error)
+	CompleteUpload(ctx context.Context, in CompleteUploadInput) (*model.Artifact, error)
+	SweepExpiredUploads(ctx context.Context) (int, error)
+	DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, cond Preconditions) error
 	GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error)
 	GetPresignedURL(ctx context.Context, artifact *model.Artifact, expire time.Duration) (string, error)
 	GetFileContent(ctx context.Context, artifact *model.Artifact) (*fileparser.FileContent, error)
-	UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error)
+	ReadFileContent(ctx context.Context, artifact *model.Artifact, in ReadContentInput) (*fileparser.FileContent, *ContentRange, error)
+	ListChanges(ctx context.Context, in ListDiskChangesInput) (*ListDiskChangesOutput, error)
+	UpdateArtifactMetaByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}, cond Preconditions) (*model.Artifact, error)
 	ListByPath(ctx context.Context, diskID uuid.UUID, path string) ([]*model.Artifact, error)
 	GetAllPaths(ctx context.Context, diskID uuid.UUID) ([]string, error)
 	GrepArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error)
 	GlobArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error)
+	GrepContent(ctx context.Context, in GrepContentInput) (*GrepContentOutput, error)
+	ListVersions(ctx context.Context, diskID uuid.UUID, path string, filename string) ([]*model.ArtifactVersion, error)
+	GetVersion(ctx context.Context, diskID uuid.UUID, path string, filename string, version int) (*model.ArtifactVersion, error)
+	DiffVersions(ctx context.Context, diskID uuid.UUID, path string, filename string, fromVersion int, toVersion int) (*ArtifactDiff, error)
+	RestoreVersion(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, version int) (*model.Artifact, error)
+	EditArtifact(ctx context.Context, in EditArtifactInput) (*model.Artifact, error)
+	MoveArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error)
+	CopyArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error)
+	DeleteDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (int64, error)
+	CreateDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (*model.ArtifactDirectory, error)
+	GetTree(ctx context.Context, diskID uuid.UUID, path string, depth int) (*ArtifactTreeNode, error)
+	SemanticSearch(ctx context.Context, in SemanticSearchInput) ([]*SemanticSearchHit, error)
+	CreateSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, name string) (*model.DiskSnapshot, error)
+	ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error)
+	DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error
+	RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) (*RestoreSnapshotOutput, error)
+	CreateShare(ctx context.Context, in CreateShareInput) (*CreateShareOutput, error)
+	ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error)
+	DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error
+	OpenShare(ctx context.Context, token string, password string) (*model.ArtifactShare, error)
+	ListShared(ctx context.Context, share *model.ArtifactShare, dir string) (*SharedListing, error)
+	OpenSharedFile(ctx context.Context, share *model.ArtifactShare, file string) (*model.Artifact, io.ReadCloser, error)
+	OpenSharedArchive(ctx context.Context, share *model.ArtifactShare, dir string) (*ArtifactArchive, error)
+	ExtractArchive(ctx context.Context, in ExtractArchiveInput) (*ExtractArchiveOutput, error)
+	GetArchive(ctx context.Context, diskID uuid.UUID, dir string) (*ArtifactArchive, error)
+	QueryArtifacts(ctx context.Context, in QueryArtifactsInput) (*QueryArtifactsOutput, error)
+	WriteZip(ctx context.Context, archive *ArtifactArchive, w io.Writer) error
 }
 
 type artifactService struct {
-	r  repo.ArtifactRepo
-	s3 *blob.S3Deps
+	r      repo.ArtifactRepo
+	chunks repo.ArtifactChunkRepo
+	s3     *blob.S3Deps
+	cfg    *config.Config
+	log    *zap.Logger
+	// embedder is nil when semantic search is disabled
+	embedder embedding.Embedder
+	// usage is nil when quotas are not enforced
+	usage repo.UsageRepo
 }
 
-func NewArtifactService(r repo.ArtifactRepo, s3 *blob.S3Deps) ArtifactService {
-	return &artifactService{r: r, s3: s3}
+func NewArtifactService(r repo.ArtifactRepo, chunks repo.ArtifactChunkRepo, usage repo.UsageRepo, s3 *blob.S3Deps, cfg *config.Config, log *zap.Logger, embedder embedding.Embedder) ArtifactService {
+	return &artifactService{r: r, chunks: chunks, usage: usage, s3: s3, cfg: cfg, log: log, embedder: embedder}
 }
 
 type CreateArtifactInput struct {
@@ -46,6 +103,8 @@ type CreateArtifactInput struct {
 	Filename   string
 	FileHeader *multipart.FileHeader
 	UserMeta   map[string]interface{}
+	// Preconditions.IfMatch, if set, must match the artifact being replaced
+	Preconditions Preconditions
 }
 
 type CreateArtifactFromBytesInput struct {
@@ -54,18 +113,15 @@ type CreateArtifactFromBytesInput struct {
 	Path      string
 	Filename  string
 	Content   []byte
+	// Actor is logged with the change; empty means the API
+	Actor string
+	// quotaAdmitted skips the quota check, done once by callers storing many files
+	quotaAdmitted bool
 }
 
 func (s *artifactService) Create(ctx context.Context, in CreateArtifactInput) (*model.Artifact, error) {
-	// Check if artifact with same path and filename already exists in the same disk
-	exists, err := s.r.ExistsByPathAndFilename(ctx, in.DiskID, in.Path, in.Filename, nil)
-	if err != nil {
-		return nil, fmt.Errorf("check artifact existence: %w", err)
-	}
-	if exists {
-		if err := s.r.DeleteByPath(ctx, in.ProjectID, in.DiskID, in.Path, in.Filename); err != nil {
-			return nil, fmt.Errorf("upsert existing artifact: %w", err)
-		}
+	if err := s.checkQuota(ctx, in.ProjectID, in.DiskID, in.Path, in.Filename, in.FileHeader.Size); err != nil {
+		return nil, err
 	}
 
 	asset, err := s.s3.UploadFormFile(ctx, "disks/"+in.ProjectID.String(), in.FileHeader)
@@ -77,7 +133,7 @@ func (s *artifactService) Create(ctx context.Context, in CreateArtifactInput) (*
 	// This enables grep search functionality
 	parser := fileparser.NewFileParser()
 	var textContent string
-	if parser.CanParseFile(in.FileHeader.Filename, asset.MIME) {
+	if parser.CanParseFile(in.FileHeader.Filename, asset.MIME) && parser.CheckSize(in.FileHeader.Filename, asset.MIME, in.FileHeader.Size) == nil {
 		// Read file content to extract text
 		file, err := in.FileHeader.Open()
 		if err == nil {
@@ -114,22 +170,20 @@ func (s *artifactService) Create(ctx context.Context, in CreateArtifactInput) (*
 		AssetMeta: datatypes.NewJSONType(*asset),
 	}
 
-	if err := s.r.Create(ctx, in.ProjectID, artifact); err != nil {
-		return nil, fmt.Errorf("create artifact record: %w", err)
+	if err := s.save(ctx, in.ProjectID, artifact, in.Preconditions); err != nil {
+		return nil, err
 	}
 
 	return artifact, nil
 }
 
 func (s *artifactService) CreateFromBytes(ctx context.Context, in CreateArtifactFromBytesInput) (*model.Artifact, error) {
-	// Check if artifact with same path and filename already exists in the same disk
-	exists, err := s.r.ExistsByPathAndFilename(ctx, in.DiskID, in.Path, in.Filename, nil)
-	if err != nil {
-		return nil, fmt.Errorf("check artifact existence: %w", err)
+	if in.Actor != "" {
+		ctx = repo.WithActor(ctx, in.Actor)
 	}
-	if exists {
-		if err := s.r.DeleteByPath(ctx, in.ProjectID, in.DiskID, in.Path, in.Filename); err != nil {
-			return nil, fmt.Errorf("upsert existing artifact: %w", err)
+	if !in.quotaAdmitted {
+		if err := s.checkQuota(ctx, in.ProjectID, in.DiskID, in.Path, in.Filename, int64(len(in.Content))); err != nil {
+			return nil, err
 		}
 	}
 
@@ -169,18 +223,445 @@ func (s *artifactService) CreateFromBytes(ctx context.Context, in CreateArtifact
 		AssetMeta: datatypes.NewJSONType(*asset),
 	}
 
-	if err := s.r.Create(ctx, in.ProjectID, artifact); err != nil {
-		return nil, fmt.Errorf("create artifact record: %w", err)
+	if err := s.save(ctx, in.ProjectID, artifact, Preconditions{}); err != nil {
+		return nil, err
+	}
+
+	return artifact, nil
+}
+
+var (
+	// ErrInvalidUpload is returned for presigned upload requests that can never succeed
+	ErrInvalidUpload = errors.New("invalid upload")
+	// ErrUploadNotFound is returned when completing an upload that doesn't exist or was already completed
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadExpired is returned when completing an upload after its URLs expired
+	ErrUploadExpired = errors.New("upload has expired")
+	// ErrUploadInProgress is returned when completing an upload another request is completing
+	ErrUploadInProgress = repo.ErrUploadClaimed
+	// ErrUploadMismatch is returned when the uploaded object doesn't have the declared size or SHA256.
+	// The upload is discarded and must be started again.
+	ErrUploadMismatch = errors.New("uploaded object does not match the declared size and sha256")
+)
+
+const (
+	// uploadCompleteGrace lets an upload that started before its URLs expired be completed
+	uploadCompleteGrace = time.Hour
+	// uploadClaimTimeout lets an upload be completed again when a completion died holding it
+	uploadClaimTimeout = 15 * time.Minute
+	// uploadSweepBatch bounds the uploads discarded by one sweep
+	uploadSweepBatch = 500
+	// S3 multipart upload limits
+	maxUploadParts  = 10000
+	minPartSizeB    = 5 << 20
+	defaultPartSize = 64 << 20
+)
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type CreateUploadInput struct {
+	ProjectID   uuid.UUID
+	DiskID      uuid.UUID
+	Path        string
+	Filename    string
+	SizeB       int64
+	SHA256      string
+	ContentType string
+	UserMeta    map[string]interface{}
+	Expire      time.Duration
+}
+
+type UploadPartURL struct {
+	PartNumber int32  `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+// UploadTarget tells the client where to PUT the file: a single URL, or one URL
+// per part of PartSizeB bytes (the last part holds the rest).
+type UploadTarget struct {
+	UploadID  uuid.UUID         `json:"upload_id"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"` // Headers the PUT must send, as they are signed
+	PartSizeB int64             `json:"part_size_b,omitempty"`
+	Parts     []UploadPartURL   `json:"parts,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type CompleteUploadInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	UploadID  uuid.UUID
+	// Parts are the parts of a multipart upload with the ETags S3 returned for them
+	Parts []blob.UploadedPart
+}
+
+// CreateUpload starts a direct-to-S3 upload: the file is PUT to presigned URLs under a
+// staging key, large files in parts, and becomes an artifact once CompleteUpload verifies it.
+func (s *artifactService) CreateUpload(ctx context.Context, in CreateUploadInput) (*UploadTarget, error) {
+	if in.Path == "" || in.Filename == "" {
+		return nil, errors.New("path and filename are required")
+	}
+	in.SHA256 = strings.ToLower(in.SHA256)
+	if !sha256Pattern.MatchString(in.SHA256) {
+		return nil, fmt.Errorf("%w: sha256 must be 64 hex characters", ErrInvalidUpload)
+	}
+	if in.SizeB <= 0 {
+		return nil, fmt.Errorf("%w: size must be positive", ErrInvalidUpload)
+	}
+	if limit := s.cfgArtifact().MaxDirectUploadSizeBytes; limit > 0 && in.SizeB > limit {
+		return nil, fmt.Errorf("%w: size exceeds the maximum of %d bytes", ErrInvalidUpload, limit)
+	}
+	for _, reservedKey := range (model.Artifact{}).GetReservedKeys() {
+		if _, exists := in.UserMeta[reservedKey]; exists {
+			return nil, fmt.Errorf("%w: reserved key '%s' is not allowed in user meta", ErrInvalidUpload, reservedKey)
+		}
+	}
+	if in.ContentType == "" {
+		in.ContentType = "application/octet-stream"
+	}
+	if in.Expire <= 0 {
+		in.Expire = time.Hour
+	}
+	if err := s.checkQuota(ctx, in.ProjectID, in.DiskID, in.Path, in.Filename, in.SizeB); err != nil {
+		return nil, err
+	}
+
+	upload := &model.ArtifactUpload{
+		ID:          uuid.New(),
+		ProjectID:   in.ProjectID,
+		DiskID:      in.DiskID,
+		Path:        in.Path,
+		Filename:    in.Filename,
+		SizeB:       in.SizeB,
+		SHA256:      in.SHA256,
+		ContentType: in.ContentType,
+		Meta:        in.UserMeta,
+		ExpiresAt:   time.Now().Add(in.Expire),
+	}
+	// Staged outside "disks/" so deduplication never picks up an unverified object
+	upload.S3Key = fmt.Sprintf("uploads/%s/%s", in.ProjectID, upload.ID)
+	target := &UploadTarget{UploadID: upload.ID, ExpiresAt: upload.ExpiresAt}
+
+	if threshold := s.cfgArtifact().MultipartThresholdBytes; threshold > 0 && in.SizeB > threshold {
+		partSize := partSizeFor(in.SizeB, s.cfgArtifact().MultipartPartSizeBytes)
+		uploadID, err := s.s3.CreateMultipartUpload(ctx, upload.S3Key, in.ContentType)
+		if err != nil {
+			return nil, err
+		}
+		upload.MultipartUploadID, upload.PartSizeB = uploadID, partSize
+		target.PartSizeB = partSize
+
+		for n := int32(1); int64(n-1)*partSize < in.SizeB; n++ {
+			url, err := s.s3.PresignUploadPart(ctx, upload.S3Key, uploadID, n, in.Expire)
+			if err != nil {
+				_ = s.s3.AbortMultipartUpload(ctx, upload.S3Key, uploadID)
+				return nil, fmt.Errorf("presign upload part: %w", err)
+			}
+			target.Parts = append(target.Parts, UploadPartURL{PartNumber: n, URL: url})
+		}
+	} else {
+		url, err := s.s3.PresignPut(ctx, upload.S3Key, in.ContentType, in.Expire)
+		if err != nil {
+			return nil, fmt.Errorf("presign upload: %w", err)
+		}
+		target.URL = url
+		target.Headers = map[string]string{"Content-Type": in.ContentType}
+	}
+
+	if err := s.r.CreateUpload(ctx, upload); err != nil {
+		if upload.MultipartUploadID != "" {
+			_ = s.s3.AbortMultipartUpload(ctx, upload.S3Key, upload.MultipartUploadID)
+		}
+		return nil, fmt.Errorf("create upload record: %w", err)
+	}
+
+	return target, nil
+}
+
+// partSizeFor returns the part size for a multipart upload of size bytes,
+// grown when needed to stay within S3's part count limit.
+func partSizeFor(size int64, partSize int64) int64 {
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	if partSize < minPartSizeB {
+		partSize = minPartSizeB
+	}
+	for (size+partSize-1)/partSize > maxUploadParts {
+		partSize *= 2
+	}
+	return partSize
+}
+
+// CompleteUpload verifies the size and SHA256 of an uploaded object, moves it to its
+// content-addressed key, extracts its text and creates the artifact (or a new version).
+func (s *artifactService) CompleteUpload(ctx context.Context, in CompleteUploadInput) (*model.Artifact, error) {
+	upload, err := s.r.ClaimUpload(ctx, in.ProjectID, in.DiskID, in.UploadID, time.Now().Add(-uploadClaimTimeout))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		if errors.Is(err, ErrUploadInProgress) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("claim upload: %w", err)
+	}
+	// Unless the upload is completed or discarded, a failure hands it back for a retry
+	settled := false
+	defer func() {
+		if !settled {
+			if err := s.r.ReleaseUpload(context.WithoutCancel(ctx), upload.ID); err != nil {
+				s.log.Warn("release upload", zap.String("upload_id", upload.ID.String()), zap.Error(err))
+			}
+		}
+	}()
+
+	if time.Now().After(upload.ExpiresAt.Add(uploadCompleteGrace)) {
+		settled = true
+		s.discardUpload(ctx, upload)
+		return nil, ErrUploadExpired
+	}
+
+	if upload.MultipartUploadID != "" {
+		if len(in.Parts) == 0 {
+			return nil, fmt.Errorf("%w: parts are required to complete a multipart upload", ErrInvalidUpload)
+		}
+		if err := s.s3.CompleteMultipartUpload(ctx, upload.S3Key, upload.MultipartUploadID, in.Parts); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUpload, err)
+		}
+	}
+
+	size, err := s.s3.StatObject(ctx, upload.S3Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the file has not been uploaded: %v", ErrInvalidUpload, err)
+	}
+	if size != upload.SizeB {
+		settled = true
+		s.discardUpload(ctx, upload)
+		return nil, fmt.Errorf("%w: got %d bytes, expected %d", ErrUploadMismatch, size, upload.SizeB)
+	}
+
+	sumHex, contentType, textContent, err := s.scanUpload(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+	if sumHex != upload.SHA256 {
+		settled = true
+		s.discardUpload(ctx, upload)
+		return nil, fmt.Errorf("%w: got sha256 %s", ErrUploadMismatch, sumHex)
+	}
+
+	ext := strings.ToLower(filepath.Ext(upload.Filename))
+	asset, created, err := s.s3.PromoteObject(ctx, upload.S3Key, "disks/"+in.ProjectID.String(), sumHex, contentType, ext, size)
+	if err != nil {
+		return nil, fmt.Errorf("store uploaded object: %w", err)
+	}
+	// The staged object is gone: from here on the upload can't be retried
+	settled = true
+	asset.Content = textContent
+
+	meta := map[string]interface{}{
+		model.ArtifactInfoKey: map[string]interface{}{
+			"path":     upload.Path,
+			"filename": upload.Filename,
+			"mime":     asset.MIME,
+			"size":     asset.SizeB,
+		},
+	}
+	for k, v := range upload.Meta {
+		meta[k] = v
+	}
+
+	artifact := &model.Artifact{
+		DiskID:    upload.DiskID,
+		Path:      upload.Path,
+		Filename:  upload.Filename,
+		Meta:      meta,
+		AssetMeta: datatypes.NewJSONType(*asset),
+	}
+	// The bytes were admitted by CreateUpload and held as pending since, so they aren't checked again
+	if err := s.save(ctx, in.ProjectID, artifact, Preconditions{}); err != nil {
+		// Nothing references an object this call stored
+		if created {
+			if delErr := s.s3.DeleteObject(ctx, asset.S3Key); delErr != nil {
+				s.log.Warn("delete promoted object", zap.String("s3_key", asset.S3Key), zap.Error(delErr))
+			}
+		}
+		s.discardUpload(ctx, upload)
+		return nil, err
 	}
 
+	if err := s.r.DeleteUpload(ctx, upload.ID); err != nil {
+		s.log.Warn("delete completed upload", zap.String("upload_id", upload.ID.String()), zap.Error(err))
+	}
 	return artifact, nil
 }
 
-func (s *artifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
+// scanUpload streams the staged object once to hash it, detect its MIME type and,
+// for parsable files within the parser limits, extract its text.
+func (s *artifactService) scanUpload(ctx context.Context, upload *model.ArtifactUpload) (sumHex string, contentType string, textContent string, err error) {
+	body, err := s.s3.OpenObject(ctx, upload.S3Key, 0)
+	if err != nil {
+		return "", "", "", err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	head := make([]byte, 3072)
+	n, err := io.ReadFull(io.TeeReader(body, h), head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", "", fmt.Errorf("read uploaded object: %w", err)
+	}
+	head = head[:n]
+	contentType = mime.DetectMimeType(head, upload.Filename)
+
+	parser := fileparser.NewFileParser()
+	var content *bytes.Buffer
+	w := io.Writer(h)
+	if parser.CanParseFile(upload.Filename, contentType) && parser.CheckSize(upload.Filename, contentType, upload.SizeB) == nil {
+		content = bytes.NewBuffer(make([]byte, 0, upload.SizeB))
+		content.Write(head)
+		w = io.MultiWriter(h, content)
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return "", "", "", fmt.Errorf("read uploaded object: %w", err)
+	}
+	sumHex = hex.EncodeToString(h.Sum(nil))
+
+	if content != nil {
+		if fileContent, parseErr := parser.ParseFile(upload.Filename, contentType, content.Bytes()); parseErr == nil && fileContent != nil {
+			textContent = fileContent.Raw
+		}
+	}
+	return sumHex, contentType, textContent, nil
+}
+
+// SweepExpiredUploads discards uploads that can no longer be completed: their multipart
+// uploads are aborted and their staged objects and rows deleted, so abandoned uploads
+// neither keep S3 storage nor count against quotas. It returns the number discarded,
+// at most uploadSweepBatch per call.
+func (s *artifactService) SweepExpiredUploads(ctx context.Context) (int, error) {
+	uploads, err := s.r.ListExpiredUploads(ctx, time.Now().Add(-uploadCompleteGrace), uploadSweepBatch)
+	if err != nil {
+		return 0, fmt.Errorf("list expired uploads: %w", err)
+	}
+	for _, u := range uploads {
+		s.discardUpload(ctx, u)
+	}
+	return len(uploads), nil
+}
+
+// discardUpload deletes a failed upload and its staged object, best effort
+func (s *artifactService) discardUpload(ctx context.Context, upload *model.ArtifactUpload) {
+	if upload.MultipartUploadID != "" {
+		_ = s.s3.AbortMultipartUpload(ctx, upload.S3Key, upload.MultipartUploadID)
+	}
+	_ = s.s3.DeleteObject(ctx, upload.S3Key)
+	if err := s.r.DeleteUpload(ctx, upload.ID); err != nil {
+		s.log.Warn("delete failed upload", zap.String("upload_id", upload.ID.String()), zap.Error(err))
+	}
+}
+
+// cfgArtifact returns the artifact config, zero when unset
+func (s *artifactService) cfgArtifact() config.ArtifactCfg {
+	if s.cfg == nil {
+		return config.ArtifactCfg{}
+	}
+	return s.cfg.Artifact
+}
+
+// save creates the artifact, or stores it as a new version of the artifact
+// already at the same path so the previous content stays available.
+func (s *artifactService) save(ctx context.Context, projectID uuid.UUID, artifact *model.Artifact, cond Preconditions) error {
+	existing, err := s.r.GetByPath(ctx, artifact.DiskID, artifact.Path, artifact.Filename)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("check artifact existence: %w", err)
+		}
+		if err := cond.checkWrite(""); err != nil {
+			return err
+		}
+		artifact.Version = 1
+		err := s.r.Create(ctx, projectID, artifact)
+		if err == nil {
+			s.reindex(ctx, artifact)
+			return nil
+		}
+		if !errors.Is(err, repo.ErrArtifactExists) {
+			return fmt.Errorf("create artifact record: %w", err)
+		}
+		// A concurrent create won the path: this write becomes its next version
+		if existing, err = s.r.GetByPath(ctx, artifact.DiskID, artifact.Path, artifact.Filename); err != nil {
+			return fmt.Errorf("check artifact existence: %w", err)
+		}
+	}
+
+	artifact.ID = existing.ID
+	if cond.IfMatch != "" {
+		// The content checked here must still be current once the row is locked
+		current := existing.AssetMeta.Data().SHA256
+		if err := cond.checkWrite(current); err != nil {
+			return err
+		}
+		if err := s.r.AddVersionIfMatch(ctx, projectID, artifact, current, s.maxVersions()); err != nil {
+			if errors.Is(err, ErrArtifactModified) {
+				return err
+			}
+			return fmt.Errorf("create artifact version: %w", err)
+		}
+	} else if err := s.r.AddVersion(ctx, projectID, artifact, s.maxVersions()); err != nil {
+		return fmt.Errorf("create artifact version: %w", err)
+	}
+	s.reindex(ctx, artifact)
+	return nil
+}
+
+// checkQuota returns ErrQuotaExceeded when storing size bytes at path/filename would exceed a quota.
+// Replacing an existing artifact adds a version, which counts bytes but no file.
+func (s *artifactService) checkQuota(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, size int64) error {
+	if s.usage == nil || s.cfg == nil || s.cfg.Quota == (config.QuotaCfg{}) {
+		return nil
+	}
+	files := int64(1)
+	if _, err := s.r.GetByPath(ctx, diskID, path, filename); err == nil {
+		files = 0
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("check artifact existence: %w", err)
+	}
+	return checkQuota(ctx, s.usage, s.cfg.Quota, projectID, &diskID, size, files)
+}
+
+// quotaCheck is diskQuotaCheck with the service's configuration.
+func (s *artifactService) quotaCheck(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) repo.QuotaCheck {
+	if s.cfg == nil {
+		return nil
+	}
+	return diskQuotaCheck(ctx, s.usage, s.cfg.Quota, projectID, diskID)
+}
+
+func (s *artifactService) maxVersions() int {
+	return s.cfgArtifact().MaxVersions
+}
+
+// DeleteByPath deletes an artifact; with cond.IfMatch, only if its content still matches.
+func (s *artifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, cond Preconditions) error {
 	if path == "" || filename == "" {
 		return errors.New("path and filename are required")
 	}
-	return s.r.DeleteByPath(ctx, projectID, diskID, path, filename)
+	expected := ""
+	if cond.IfMatch != "" {
+		a, err := s.r.GetByPath(ctx, diskID, path, filename)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if a != nil {
+			expected = a.AssetMeta.Data().SHA256
+		}
+		if err := cond.checkWrite(expected); err != nil {
+			return err
+		}
+	}
+	return s.r.DeleteByPath(ctx, projectID, diskID, path, filename, expected)
 }
 
 func (s *artifactService) GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error) {
@@ -218,6 +699,9 @@ func (s *artifactService) GetFileContent(ctx context.Context, artifact *model.Ar
 	if !parser.CanParseFile(artifact.Filename, assetData.MIME) {
 		return nil, fmt.Errorf("unsupported file type: %s (mime: %s)", artifact.Filename, assetData.MIME)
 	}
+	if err := parser.CheckSize(artifact.Filename, assetData.MIME, assetData.SizeB); err != nil {
+		return nil, err
+	}
 
 	// Download file content from S3
 	content, err := s.s3.DownloadFile(ctx, assetData.S3Key)
@@ -234,7 +718,245 @@ func (s *artifactService) GetFileContent(ctx context.Context, artifact *model.Ar
 	return fileContent, nil
 }
 
-func (s *artifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error) {
+// ErrInvalidReadRange is returned for partial reads with inconsistent options
+var ErrInvalidReadRange = errors.New("invalid read range")
+
+// Units and modes of partial reads
+const (
+	ReadUnitLines = "lines"
+	ReadUnitBytes = "bytes"
+	ReadUnitRows  = "rows" // CSV rows after the header, which is always returned
+
+	ReadModeRange = "range" // Limit units from Offset
+	ReadModeHead  = "head"  // The first Limit units
+	ReadModeTail  = "tail"  // The last Limit units
+)
+
+// Bounds of partial reads
+const (
+	DefaultReadLines = 200
+	MaxReadLines     = 10000
+	DefaultReadBytes = 64 << 10
+	MaxReadBytes     = 10 << 20
+
+	// tailChunkSize is the first suffix fetched for a tail of lines, growing until it holds enough lines
+	tailChunkSize = 64 << 10
+)
+
+type ReadContentInput struct {
+	Unit   string // ReadUnitLines (default), ReadUnitBytes or ReadUnitRows
+	Mode   string // ReadModeRange (default), ReadModeHead or ReadModeTail
+	Offset int64  // First unit of a range, 0-based
+	Limit  int64  // Units to return, defaulting by unit
+}
+
+// ContentRange describes the part of an artifact's content returned by a partial read
+type ContentRange struct {
+	Unit      string `json:"unit"`
+	Offset    int64  `json:"offset"`   // First unit returned, 0-based
+	Count     int64  `json:"count"`    // Units returned
+	HasMore   bool   `json:"has_more"` // Units follow the ones returned
+	Truncated bool   `json:"truncated,omitempty"`
+	// Totals for pagination; lines and rows are only known when counted without extra reads
+	TotalLines *int64 `json:"total_lines,omitempty"`
+	TotalRows  *int64 `json:"total_rows,omitempty"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+func normalizeReadInput(in ReadContentInput) (ReadContentInput, error) {
+	if in.Unit == "" {
+		in.Unit = ReadUnitLines
+	}
+	if in.Mode == "" {
+		in.Mode = ReadModeRange
+	}
+
+	defaultLimit, maxLimit := int64(DefaultReadLines), int64(MaxReadLines)
+	switch in.Unit {
+	case ReadUnitLines, ReadUnitRows:
+	case ReadUnitBytes:
+		defaultLimit, maxLimit = DefaultReadBytes, MaxReadBytes
+	default:
+		return in, fmt.Errorf("%w: unknown unit %q", ErrInvalidReadRange, in.Unit)
+	}
+
+	switch in.Mode {
+	case ReadModeRange:
+	case ReadModeHead, ReadModeTail:
+		if in.Offset != 0 {
+			return in, fmt.Errorf("%w: offset can't be combined with %s", ErrInvalidReadRange, in.Mode)
+		}
+	default:
+		return in, fmt.Errorf("%w: unknown mode %q", ErrInvalidReadRange, in.Mode)
+	}
+
+	if in.Offset < 0 || in.Limit < 0 {
+		return in, fmt.Errorf("%w: offset and limit can't be negative", ErrInvalidReadRange)
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultLimit
+	}
+	if in.Limit > maxLimit {
+		return in, fmt.Errorf("%w: limit is at most %d %s", ErrInvalidReadRange, maxLimit, in.Unit)
+	}
+	return in, nil
+}
+
+// ReadFileContent returns part of an artifact's content. Files stored as text are read from S3
+// with ranged GETs, stopping once the range is read; the text of documents comes from the
+// extracted content.
+func (s *artifactService) ReadFileContent(ctx context.Context, artifact *model.Artifact, in ReadContentInput) (*fileparser.FileContent, *ContentRange, error) {
+	if artifact == nil {
+		return nil, nil, errors.New("artifact is nil")
+	}
+	in, err := normalizeReadInput(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	assetData := artifact.AssetMeta.Data()
+	contentType, plain := fileparser.NewFileParser().ContentType(artifact.Filename, assetData.MIME)
+	if contentType == "" {
+		return nil, nil, fmt.Errorf("unsupported file type: %s (mime: %s)", artifact.Filename, assetData.MIME)
+	}
+	if in.Unit == ReadUnitRows && contentType != "csv" {
+		return nil, nil, fmt.Errorf("%w: rows can only be read from CSV files", ErrInvalidReadRange)
+	}
+	if !plain {
+		return s.readExtractedContent(ctx, artifact, contentType, in)
+	}
+	if assetData.S3Key == "" {
+		return nil, nil, errors.New("artifact has no S3 key")
+	}
+
+	rng := &ContentRange{Unit: in.Unit, TotalBytes: assetData.SizeB}
+	// Text files keep their whole text in the asset, which counts lines without reading S3
+	if assetData.Content != "" {
+		total := textrange.CountLines(assetData.Content)
+		rng.TotalLines = &total
+	}
+
+	var res *textrange.Result
+	switch in.Unit {
+	case ReadUnitBytes:
+		start, end := byteRange(in, assetData.SizeB)
+		data, err := s.s3.DownloadRange(ctx, assetData.S3Key, start, end-start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to download file content: %w", err)
+		}
+		res = &textrange.Result{Text: string(data), Offset: start, Count: int64(len(data)), HasMore: end < assetData.SizeB}
+	case ReadUnitLines:
+		if in.Mode == ReadModeTail && rng.TotalLines != nil {
+			res, err = s.tailLines(ctx, assetData.S3Key, assetData.SizeB, in.Limit, *rng.TotalLines)
+		} else {
+			res, err = s.readObject(ctx, assetData.S3Key, func(r io.Reader) (*textrange.Result, error) {
+				if in.Mode == ReadModeTail {
+					return textrange.TailLines(r, in.Limit, MaxReadBytes)
+				}
+				return textrange.Lines(r, in.Offset, in.Limit, MaxReadBytes)
+			})
+		}
+		if err == nil && res.Complete {
+			rng.TotalLines = &res.Total
+		}
+	case ReadUnitRows:
+		res, err = s.readObject(ctx, assetData.S3Key, func(r io.Reader) (*textrange.Result, error) {
+			if in.Mode == ReadModeTail {
+				return textrange.TailRows(r, in.Limit, MaxReadBytes)
+			}
+			return textrange.Rows(r, in.Offset, in.Limit, MaxReadBytes)
+		})
+		if err == nil && res.Complete {
+			rng.TotalRows = &res.Total
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rng.Offset, rng.Count, rng.HasMore, rng.Truncated = res.Offset, res.Count, res.HasMore, res.Truncated
+	return &fileparser.FileContent{Type: contentType, Raw: res.Text}, rng, nil
+}
+
+// readExtractedContent reads a range of the text extracted from a document, which the asset
+// keeps from upload; older artifacts without it are parsed again
+func (s *artifactService) readExtractedContent(ctx context.Context, artifact *model.Artifact, contentType string, in ReadContentInput) (*fileparser.FileContent, *ContentRange, error) {
+	text := artifact.AssetMeta.Data().Content
+	if text == "" {
+		content, err := s.GetFileContent(ctx, artifact)
+		if err != nil {
+			return nil, nil, err
+		}
+		text = content.Raw
+	}
+
+	total := textrange.CountLines(text)
+	rng := &ContentRange{Unit: in.Unit, TotalLines: &total, TotalBytes: int64(len(text))}
+
+	var res *textrange.Result
+	switch in.Unit {
+	case ReadUnitBytes:
+		start, end := byteRange(in, int64(len(text)))
+		res = &textrange.Result{Text: text[start:end], Offset: start, Count: end - start, HasMore: end < int64(len(text))}
+	default:
+		var err error
+		if in.Mode == ReadModeTail {
+			res, err = textrange.TailLines(strings.NewReader(text), in.Limit, MaxReadBytes)
+		} else {
+			res, err = textrange.Lines(strings.NewReader(text), in.Offset, in.Limit, MaxReadBytes)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rng.Offset, rng.Count, rng.HasMore, rng.Truncated = res.Offset, res.Count, res.HasMore, res.Truncated
+	return &fileparser.FileContent{Type: contentType, Raw: res.Text}, rng, nil
+}
+
+// byteRange returns the [start, end) bytes a read selects from size bytes
+func byteRange(in ReadContentInput, size int64) (int64, int64) {
+	start := in.Offset
+	if in.Mode == ReadModeTail {
+		start = size - in.Limit
+	}
+	start = min(max(start, 0), size)
+	return start, min(start+in.Limit, size)
+}
+
+// readObject streams an object through read, closing it as soon as read returns
+func (s *artifactService) readObject(ctx context.Context, key string, read func(io.Reader) (*textrange.Result, error)) (*textrange.Result, error) {
+	body, err := s.s3.OpenObject(ctx, key, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file content: %w", err)
+	}
+	defer body.Close()
+
+	res, err := read(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file content: %w", err)
+	}
+	return res, nil
+}
+
+// tailLines reads the last n lines with ranged GETs of growing suffixes, which needs
+// the total line count to number them
+func (s *artifactService) tailLines(ctx context.Context, key string, size int64, n int64, total int64) (*textrange.Result, error) {
+	for chunk := int64(tailChunkSize); ; chunk *= 4 {
+		start := max(size-chunk, 0)
+		data, err := s.s3.DownloadRange(ctx, key, start, size-start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file content: %w", err)
+		}
+		if res, ok := textrange.LastLines(data, start, n, MaxReadBytes); ok {
+			res.Offset = max(total-res.Count, 0)
+			res.HasMore = false
+			return res, nil
+		}
+	}
+}
+
+func (s *artifactService) UpdateArtifactMetaByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}, cond Preconditions) (*model.Artifact, error) {
 	// Get existing artifact
 	artifact, err := s.GetByPath(ctx, diskID, path, filename)
 	if err != nil {
@@ -265,7 +987,17 @@ func (s *artifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID u
 	// Update artifact meta
 	artifact.Meta = newMeta
 
-	if err := s.r.Update(ctx, artifact); err != nil {
+	expected := ""
+	if cond.IfMatch != "" {
+		expected = artifact.AssetMeta.Data().SHA256
+		if err := cond.checkWrite(expected); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.r.Update(ctx, projectID, artifact, expected); err != nil {
+		if errors.Is(err, ErrArtifactModified) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("update artifact meta: %w", err)
 	}
 
@@ -293,15 +1025,919 @@ func (s *artifactService) GrepArtifacts(ctx context.Context, projectID uuid.UUID
 	return s.r.GrepArtifacts(ctx, diskID, pattern, limit)
 }
 
-func (s *artifactService) GlobArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
-	// Set default limit if not provided
-	if limit <= 0 {
-		limit = 100
+var (
+	// ErrInvalidGrepPattern is returned when a grep pattern does not compile.
+	ErrInvalidGrepPattern = errors.New("invalid grep pattern")
+	// ErrInvalidGlobPattern is returned for malformed glob patterns.
+	ErrInvalidGlobPattern = errors.New("invalid glob pattern")
+)
+
+type GrepContentInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	Pattern   string
+	// IgnoreCase matches letters regardless of case
+	IgnoreCase bool
+	// FixedStrings matches Pattern literally instead of as a regular expression
+	FixedStrings bool
+	// Include and Exclude filter files by glob. A glob without '/' matches the
+	// filename, any other glob the full path. Exclude wins over Include.
+	Include []string
+	Exclude []string
+	// Before and After are the context lines around each matching line
+	Before int
+	After  int
+	// MaxCount is the maximum number of matching lines per file
+	MaxCount int
+	// Limit is the maximum number of files
+	Limit int
+}
+
+// GrepFile holds the hits of one file.
+type GrepFile struct {
+	Path    string      `json:"path"`
+	Matches int         `json:"matches"`
+	Lines   []grep.Line `json:"lines"`
+	// Truncated reports that max_count hid further matches of the file
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type GrepStats struct {
+	Files   int `json:"files"`
+	Matches int `json:"matches"`
+	// Truncated reports that the file limit hid further matching files
+	Truncated bool `json:"truncated"`
+}
+
+// GrepContentOutput mirrors ripgrep: matching files with their matching and context lines.
+type GrepContentOutput struct {
+	Files []*GrepFile `json:"files"`
+	Stats GrepStats   `json:"stats"`
+}
+
+// GrepContent searches text artifacts line by line. The database selects the
+// candidate files, the hits are then located with Go's regexp engine.
+func (s *artifactService) GrepContent(ctx context.Context, in GrepContentInput) (*GrepContentOutput, error) {
+	if in.Limit <= 0 {
+		in.Limit = 100
 	}
-	// Cap at 1000 results
-	if limit > 1000 {
-		limit = 1000
+	if in.Limit > 1000 {
+		in.Limit = 1000
+	}
+	if in.MaxCount <= 0 {
+		in.MaxCount = 100
+	}
+
+	pattern := in.Pattern
+	if in.FixedStrings {
+		// QuoteMeta only escapes punctuation, which POSIX advanced regexps accept escaped too
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	goPattern := pattern
+	if in.IgnoreCase {
+		goPattern = "(?i)" + goPattern
+	}
+	re, err := regexp.Compile(goPattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGrepPattern, err)
+	}
+	for _, pattern := range append(append([]string{}, in.Include...), in.Exclude...) {
+		if err := glob.Validate(pattern); err != nil {
+			return nil, fmt.Errorf("%w: glob %q: %v", ErrInvalidGrepPattern, pattern, err)
+		}
+	}
+
+	out := &GrepContentOutput{Files: []*GrepFile{}}
+	q := repo.ContentQuery{Pattern: pattern, IgnoreCase: in.IgnoreCase, Limit: in.Limit}
+	for {
+		artifacts, err := s.r.SearchContent(ctx, in.DiskID, q)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range artifacts {
+			fullPath := a.Path + a.Filename
+			if !matchGlobFilters(fullPath, a.Filename, in.Include, in.Exclude) {
+				continue
+			}
+			res := grep.Search(re, a.AssetMeta.Data().Content, grep.Options{
+				Before:   in.Before,
+				After:    in.After,
+				MaxCount: in.MaxCount,
+			})
+			// The pattern may span lines in the database but match no single line
+			if res.Matches == 0 {
+				continue
+			}
+			if len(out.Files) == in.Limit {
+				out.Stats.Truncated = true
+				break
+			}
+			out.Files = append(out.Files, &GrepFile{
+				Path:      fullPath,
+				Matches:   res.Matches,
+				Lines:     res.Lines,
+				Truncated: res.Truncated,
+			})
+			out.Stats.Matches += res.Matches
+		}
+
+		if out.Stats.Truncated || len(artifacts) < q.Limit {
+			break
+		}
+		q.Offset += len(artifacts)
+	}
+	out.Stats.Files = len(out.Files)
+
+	return out, nil
+}
+
+// matchGlobFilters reports whether a file passes the include and exclude globs.
+func matchGlobFilters(fullPath string, filename string, include []string, exclude []string) bool {
+	matches := func(pattern string) bool {
+		target := fullPath
+		if !strings.Contains(pattern, "/") {
+			target = filename
+		} else if !strings.HasPrefix(pattern, "/") {
+			pattern = "/" + pattern
+		}
+		ok, _ := glob.Match(pattern, target)
+		return ok
+	}
+
+	for _, pattern := range exclude {
+		if matches(pattern) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if matches(pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *artifactService) GlobArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
+	// Set default limit if not provided
+	if limit <= 0 {
+		limit = 100
+	}
+	// Cap at 1000 results
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	if err := glob.Validate(pattern); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGlobPattern, err)
 	}
 
 	return s.r.GlobArtifacts(ctx, diskID, pattern, limit)
 }
+
+// ListVersions returns every version of an artifact, newest (current) first.
+func (s *artifactService) ListVersions(ctx context.Context, diskID uuid.UUID, path string, filename string) ([]*model.ArtifactVersion, error) {
+	artifact, err := s.GetByPath(ctx, diskID, path, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.r.ListVersions(ctx, artifact.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list artifact versions: %w", err)
+	}
+
+	return append([]*model.ArtifactVersion{artifact.CurrentVersion()}, versions...), nil
+}
+
+func (s *artifactService) GetVersion(ctx context.Context, diskID uuid.UUID, path string, filename string, version int) (*model.ArtifactVersion, error) {
+	artifact, err := s.GetByPath(ctx, diskID, path, filename)
+	if err != nil {
+		return nil, err
+	}
+	return s.getVersion(ctx, artifact, version)
+}
+
+func (s *artifactService) getVersion(ctx context.Context, artifact *model.Artifact, version int) (*model.ArtifactVersion, error) {
+	if version == artifact.Version {
+		return artifact.CurrentVersion(), nil
+	}
+
+	v, err := s.r.GetVersion(ctx, artifact.ID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("artifact version %d not found", version)
+		}
+		return nil, fmt.Errorf("get artifact version: %w", err)
+	}
+	return v, nil
+}
+
+type ArtifactDiff struct {
+	FromVersion int    `json:"from_version"`
+	ToVersion   int    `json:"to_version"`
+	Diff        string `json:"diff"`
+}
+
+// DiffVersions returns a unified diff between the parsed text of two versions.
+// A toVersion <= 0 compares against the current version.
+func (s *artifactService) DiffVersions(ctx context.Context, diskID uuid.UUID, path string, filename string, fromVersion int, toVersion int) (*ArtifactDiff, error) {
+	artifact, err := s.GetByPath(ctx, diskID, path, filename)
+	if err != nil {
+		return nil, err
+	}
+	if toVersion <= 0 {
+		toVersion = artifact.Version
+	}
+
+	from, err := s.getVersion(ctx, artifact, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getVersion(ctx, artifact, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	fromContent, err := s.GetFileContent(ctx, from.AsArtifact())
+	if err != nil {
+		return nil, err
+	}
+	toContent, err := s.GetFileContent(ctx, to.AsArtifact())
+	if err != nil {
+		return nil, err
+	}
+
+	name := path + filename
+	out, err := diff.Unified(
+		fmt.Sprintf("%s@v%d", name, from.Version),
+		fmt.Sprintf("%s@v%d", name, to.Version),
+		fromContent.Raw, toContent.Raw, diff.DefaultContext,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("diff artifact versions: %w", err)
+	}
+
+	return &ArtifactDiff{FromVersion: from.Version, ToVersion: to.Version, Diff: out}, nil
+}
+
+// RestoreVersion makes the content and meta of an old version current again.
+// The restore is recorded as a new version, so no history is lost.
+func (s *artifactService) RestoreVersion(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, version int) (*model.Artifact, error) {
+	artifact, err := s.GetByPath(ctx, diskID, path, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.getVersion(ctx, artifact, version)
+	if err != nil {
+		return nil, err
+	}
+	if v.IsCurrent {
+		return artifact, nil
+	}
+
+	restored := &model.Artifact{
+		ID:        artifact.ID,
+		DiskID:    artifact.DiskID,
+		Path:      artifact.Path,
+		Filename:  artifact.Filename,
+		Meta:      v.Meta,
+		AssetMeta: v.AssetMeta,
+	}
+	if err := s.r.AddVersion(ctx, projectID, restored, s.maxVersions()); err != nil {
+		return nil, fmt.Errorf("restore artifact version: %w", err)
+	}
+	s.reindex(ctx, restored)
+
+	return restored, nil
+}
+
+// Edit operations of EditArtifactInput
+const (
+	EditOpStrReplace   = "str_replace"
+	EditOpInsert       = "insert"
+	EditOpReplaceLines = "replace_lines"
+	EditOpApplyDiff    = "apply_diff"
+)
+
+// MaxEditSize is the largest file that can be edited in place
+const MaxEditSize = 10 << 20
+
+// ErrInvalidEdit is returned for edits that can't be applied to the current content,
+// such as an old string that isn't found or a diff whose context doesn't match.
+var ErrInvalidEdit = errors.New("invalid edit")
+
+// ErrArtifactModified is returned when the artifact changed since the version an edit expects.
+var ErrArtifactModified = repo.ErrArtifactModified
+
+type EditArtifactInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	Path      string
+	Filename  string
+	Op        string
+	// OldStr must occur exactly once and is replaced by NewStr (str_replace)
+	OldStr string
+	NewStr string
+	// Line is the 1-based line Text is inserted after, 0 inserts at the start (insert)
+	Line int
+	// StartLine through EndLine, 1-based and inclusive, are replaced by Text (replace_lines)
+	StartLine int
+	EndLine   int
+	Text      string
+	// Diff is a unified diff against the current content (apply_diff)
+	Diff string
+	// ExpectedSHA256 and ExpectedETag, an entity tag as returned in the ETag header,
+	// must match the current content when set
+	ExpectedSHA256 string
+	ExpectedETag   string
+}
+
+// EditArtifact edits the text of an artifact in place and stores the result as a new version.
+// The edit is applied to the content that was read, so it fails with ErrArtifactModified
+// rather than overwrite a concurrent change.
+func (s *artifactService) EditArtifact(ctx context.Context, in EditArtifactInput) (*model.Artifact, error) {
+	artifact, err := s.GetByPath(ctx, in.DiskID, in.Path, in.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	assetData := artifact.AssetMeta.Data()
+	if in.ExpectedSHA256 != "" && !strings.EqualFold(in.ExpectedSHA256, assetData.SHA256) {
+		return nil, ErrArtifactModified
+	}
+	if in.ExpectedETag != "" && !matchETag(in.ExpectedETag, assetData.SHA256, false) {
+		return nil, ErrArtifactModified
+	}
+
+	parser := fileparser.NewFileParser()
+	if _, plain := parser.ContentType(artifact.Filename, assetData.MIME); !plain {
+		return nil, fmt.Errorf("%w: only text files can be edited: %s (mime: %s)", ErrInvalidEdit, artifact.Filename, assetData.MIME)
+	}
+	if assetData.SizeB > MaxEditSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrInvalidEdit, MaxEditSize)
+	}
+	if assetData.S3Key == "" {
+		return nil, errors.New("artifact has no S3 key")
+	}
+
+	content, err := s.s3.DownloadFile(ctx, assetData.S3Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file content: %w", err)
+	}
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("%w: file is not valid UTF-8 text", ErrInvalidEdit)
+	}
+
+	text, err := applyEdit(string(content), in)
+	if err != nil {
+		return nil, err
+	}
+	if text == string(content) {
+		return artifact, nil
+	}
+	if err := s.checkQuota(ctx, in.ProjectID, in.DiskID, in.Path, in.Filename, int64(len(text))); err != nil {
+		return nil, err
+	}
+
+	asset, err := s.s3.UploadBytes(ctx, "disks/"+in.ProjectID.String(), artifact.Filename, []byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("upload bytes to S3: %w", err)
+	}
+	if parser.CanParseFile(artifact.Filename, asset.MIME) {
+		if fileContent, parseErr := parser.ParseFile(artifact.Filename, asset.MIME, []byte(text)); parseErr == nil && fileContent != nil {
+			asset.Content = fileContent.Raw
+		}
+	}
+
+	// User meta is kept; the system meta describes the new content
+	meta := make(map[string]interface{}, len(artifact.Meta))
+	for k, v := range artifact.Meta {
+		meta[k] = v
+	}
+	meta[model.ArtifactInfoKey] = map[string]interface{}{
+		"path":     artifact.Path,
+		"filename": artifact.Filename,
+		"mime":     asset.MIME,
+		"size":     asset.SizeB,
+	}
+
+	edited := &model.Artifact{
+		ID:        artifact.ID,
+		DiskID:    artifact.DiskID,
+		Path:      artifact.Path,
+		Filename:  artifact.Filename,
+		Meta:      meta,
+		AssetMeta: datatypes.NewJSONType(*asset),
+	}
+	if err := s.r.AddVersionIfMatch(ctx, in.ProjectID, edited, assetData.SHA256, s.maxVersions()); err != nil {
+		if errors.Is(err, ErrArtifactModified) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("create artifact version: %w", err)
+	}
+	s.reindex(ctx, edited)
+
+	return edited, nil
+}
+
+// applyEdit returns text with the edit of in applied.
+func applyEdit(text string, in EditArtifactInput) (string, error) {
+	switch in.Op {
+	case EditOpStrReplace:
+		if in.OldStr == "" {
+			return "", fmt.Errorf("%w: old_str is required", ErrInvalidEdit)
+		}
+		switch n := strings.Count(text, in.OldStr); n {
+		case 0:
+			return "", fmt.Errorf("%w: old_str was not found", ErrInvalidEdit)
+		case 1:
+			return strings.Replace(text, in.OldStr, in.NewStr, 1), nil
+		default:
+			return "", fmt.Errorf("%w: old_str occurs %d times; include more context to make it unique", ErrInvalidEdit, n)
+		}
+	case EditOpInsert:
+		lines, eol := splitTextLines(text)
+		if in.Line < 0 || in.Line > len(lines) {
+			return "", fmt.Errorf("%w: line must be between 0 and %d", ErrInvalidEdit, len(lines))
+		}
+		return spliceLines(lines, eol, in.Line, in.Line, in.Text), nil
+	case EditOpReplaceLines:
+		lines, eol := splitTextLines(text)
+		if in.StartLine < 1 || in.EndLine < in.StartLine || in.EndLine > len(lines) {
+			return "", fmt.Errorf("%w: lines must satisfy 1 <= start_line <= end_line <= %d", ErrInvalidEdit, len(lines))
+		}
+		return spliceLines(lines, eol, in.StartLine-1, in.EndLine, in.Text), nil
+	case EditOpApplyDiff:
+		if strings.TrimSpace(in.Diff) == "" {
+			return "", fmt.Errorf("%w: diff is required", ErrInvalidEdit)
+		}
+		out, err := diff.Apply(text, in.Diff)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidEdit, err)
+		}
+		return out, nil
+	default:
+		return "", fmt.Errorf("%w: unknown op %q", ErrInvalidEdit, in.Op)
+	}
+}
+
+// splitTextLines splits text into lines without their newline and reports whether it ends with one.
+func splitTextLines(text string) ([]string, bool) {
+	if text == "" {
+		return nil, true
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), strings.HasSuffix(text, "\n")
+}
+
+// spliceLines replaces lines[from:to] with the lines of replacement and joins the result.
+func spliceLines(lines []string, eol bool, from int, to int, replacement string) string {
+	repl, _ := splitTextLines(replacement)
+	out := make([]string, 0, len(lines)-(to-from)+len(repl))
+	out = append(out, lines[:from]...)
+	out = append(out, repl...)
+	out = append(out, lines[to:]...)
+	if len(out) == 0 {
+		return ""
+	}
+	text := strings.Join(out, "\n")
+	if eol {
+		text += "\n"
+	}
+	return text
+}
+
+// ErrInvalidTransfer is returned for move and copy requests that can never succeed,
+// such as moving a directory into itself.
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+// ErrArtifactConflict is returned when a destination is taken under the fail conflict policy.
+var ErrArtifactConflict = repo.ErrArtifactConflict
+
+type TransferArtifactsInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	// SourcePath is a file path, or a directory when it ends with '/'
+	SourcePath string
+	// DestinationPath is the new file path, or the target directory when it ends with '/'.
+	// Directories are always transferred into DestinationPath as a directory.
+	DestinationPath string
+	// DestinationDiskID copies into another disk of the project; uuid.Nil means DiskID.
+	// Only copies may cross disks.
+	DestinationDiskID uuid.UUID
+	OnConflict        string
+}
+
+type TransferArtifactsOutput struct {
+	Artifacts []*model.Artifact `json:"artifacts"`
+	Skipped   []string          `json:"skipped"`
+}
+
+func (s *artifactService) MoveArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error) {
+	return s.transfer(ctx, in, false)
+}
+
+func (s *artifactService) CopyArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error) {
+	return s.transfer(ctx, in, true)
+}
+
+func (s *artifactService) transfer(ctx context.Context, in TransferArtifactsInput, isCopy bool) (*TransferArtifactsOutput, error) {
+	t, err := buildTransfer(in, isCopy)
+	if err != nil {
+		return nil, err
+	}
+	if isCopy {
+		t.Check = s.quotaCheck(ctx, in.ProjectID, t.DestDiskID)
+	}
+
+	transferred, skipped, err := s.r.Transfer(ctx, in.ProjectID, t)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("source %s not found", in.SourcePath)
+		}
+		return nil, err
+	}
+
+	// Moves keep their artifacts, hence their chunks; copies are new artifacts
+	if isCopy {
+		s.reindex(ctx, transferred...)
+	}
+
+	out := &TransferArtifactsOutput{Artifacts: transferred, Skipped: make([]string, 0, len(skipped))}
+	if out.Artifacts == nil {
+		out.Artifacts = []*model.Artifact{}
+	}
+	for _, a := range skipped {
+		out.Skipped = append(out.Skipped, a.Path+a.Filename)
+	}
+	return out, nil
+}
+
+// buildTransfer validates the source and destination of a move or copy.
+func buildTransfer(in TransferArtifactsInput, isCopy bool) (repo.ArtifactTransfer, error) {
+	t := repo.ArtifactTransfer{
+		SourceDiskID: in.DiskID,
+		DestDiskID:   in.DiskID,
+		Copy:         isCopy,
+		OnConflict:   in.OnConflict,
+	}
+	if in.DestinationDiskID != uuid.Nil {
+		if !isCopy && in.DestinationDiskID != in.DiskID {
+			return t, fmt.Errorf("%w: artifacts can only be copied between disks, not moved", ErrInvalidTransfer)
+		}
+		t.DestDiskID = in.DestinationDiskID
+	}
+	if t.OnConflict == "" {
+		t.OnConflict = repo.ConflictFail
+	}
+	switch t.OnConflict {
+	case repo.ConflictFail, repo.ConflictOverwrite, repo.ConflictSkip:
+	default:
+		return t, fmt.Errorf("%w: unknown conflict policy %q", ErrInvalidTransfer, t.OnConflict)
+	}
+
+	t.SourcePath, t.SourceFilename = pathutil.SplitFilePath(in.SourcePath)
+	if err := pathutil.ValidatePath(t.SourcePath); err != nil {
+		return t, fmt.Errorf("%w: source_path: %v", ErrInvalidTransfer, err)
+	}
+	dest := in.DestinationPath
+	if t.SourceFilename == "" && !strings.HasSuffix(dest, "/") {
+		dest += "/"
+	}
+	t.DestPath, t.DestFilename = pathutil.SplitFilePath(dest)
+	if err := pathutil.ValidatePath(t.DestPath); err != nil {
+		return t, fmt.Errorf("%w: destination_path: %v", ErrInvalidTransfer, err)
+	}
+
+	if t.SourceDiskID != t.DestDiskID {
+		return t, nil
+	}
+	if t.SourceFilename == "" {
+		if strings.HasPrefix(t.DestPath, t.SourcePath) {
+			return t, fmt.Errorf("%w: cannot transfer directory %s into itself", ErrInvalidTransfer, t.SourcePath)
+		}
+		return t, nil
+	}
+	destFilename := t.DestFilename
+	if destFilename == "" {
+		destFilename = t.SourceFilename
+	}
+	if t.DestPath == t.SourcePath && destFilename == t.SourceFilename {
+		return t, fmt.Errorf("%w: source and destination are the same", ErrInvalidTransfer)
+	}
+	return t, nil
+}
+
+const (
+	// DefaultTreeDepth is the tree depth used when none is requested
+	DefaultTreeDepth = 3
+	// MaxTreeDepth bounds the requested tree depth
+	MaxTreeDepth = 20
+)
+
+// DeleteDirectory deletes everything under path and returns the number of artifacts deleted.
+func (s *artifactService) DeleteDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (int64, error) {
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		return 0, errors.New("both ends of the path must be '/'")
+	}
+	return s.r.DeleteByPrefix(ctx, projectID, diskID, path)
+}
+
+// CreateDirectory creates an explicit directory, which is listed even when it holds no artifact.
+func (s *artifactService) CreateDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (*model.ArtifactDirectory, error) {
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		return nil, errors.New("both ends of the path must be '/'")
+	}
+	if path == "/" {
+		return nil, errors.New("the root directory always exists")
+	}
+
+	d := &model.ArtifactDirectory{DiskID: diskID, Path: path}
+	if err := s.r.CreateDirectory(ctx, projectID, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Tree node types
+const (
+	TreeNodeDirectory = "directory"
+	TreeNodeFile      = "file"
+)
+
+// ArtifactTreeNode is a directory or a file of a disk tree.
+type ArtifactTreeNode struct {
+	Name string `json:"name"`
+	// Path of a directory ends with '/'; path of a file includes its filename
+	Path string `json:"path"`
+	Type string `json:"type"`
+	// SizeB of a directory sums every file under it, at any depth
+	SizeB int64 `json:"size_b"`
+	// FileCount of a directory counts every file under it, at any depth
+	FileCount int64               `json:"file_count"`
+	Children  []*ArtifactTreeNode `json:"children,omitempty"`
+	// Truncated is set on directories whose content lies beyond the depth limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// GetTree returns the tree under path down to depth directory levels.
+func (s *artifactService) GetTree(ctx context.Context, diskID uuid.UUID, path string, depth int) (*ArtifactTreeNode, error) {
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		return nil, errors.New("both ends of the path must be '/'")
+	}
+	if depth <= 0 {
+		depth = DefaultTreeDepth
+	}
+	if depth > MaxTreeDepth {
+		depth = MaxTreeDepth
+	}
+
+	stats, err := s.r.TreeStats(ctx, diskID, path, depth)
+	if err != nil {
+		return nil, err
+	}
+	return buildArtifactTree(path, depth, stats), nil
+}
+
+// buildArtifactTree nests the flat statistics of TreeStats under root.
+func buildArtifactTree(root string, depth int, stats *repo.ArtifactTreeStats) *ArtifactTreeNode {
+	rootNode := &ArtifactTreeNode{
+		Name:      treeNodeName(root),
+		Path:      root,
+		Type:      TreeNodeDirectory,
+		SizeB:     stats.Root.SizeB,
+		FileCount: stats.Root.FileCount,
+	}
+
+	dirs := map[string]*ArtifactTreeNode{"": rootNode}
+	// dir returns the node of the directory rel, relative to root, creating its ancestors as needed
+	var dir func(rel string) *ArtifactTreeNode
+	dir = func(rel string) *ArtifactTreeNode {
+		if node, ok := dirs[rel]; ok {
+			return node
+		}
+		parent := ""
+		if idx := strings.LastIndex(rel, "/"); idx >= 0 {
+			parent = rel[:idx]
+		}
+		node := &ArtifactTreeNode{Name: rel[strings.LastIndex(rel, "/")+1:], Path: root + rel + "/", Type: TreeNodeDirectory}
+		p := dir(parent)
+		p.Children = append(p.Children, node)
+		dirs[rel] = node
+		return node
+	}
+
+	for _, st := range stats.Dirs {
+		node := dir(st.Path)
+		node.SizeB = st.SizeB
+		node.FileCount = st.FileCount
+		// Files of the deepest directories are not listed
+		if st.Depth >= depth && st.FileCount > 0 {
+			node.Truncated = true
+		}
+	}
+	for _, rel := range stats.ExplicitDirs {
+		if rel == "" {
+			continue
+		}
+		parts := strings.Split(rel, "/")
+		if len(parts) > depth {
+			dir(strings.Join(parts[:depth], "/")).Truncated = true
+			continue
+		}
+		dir(rel)
+	}
+	for _, f := range stats.Files {
+		parent := dir(strings.Trim(strings.TrimPrefix(f.Path, root), "/"))
+		parent.Children = append(parent.Children, &ArtifactTreeNode{
+			Name:  f.Filename,
+			Path:  f.Path + f.Filename,
+			Type:  TreeNodeFile,
+			SizeB: f.SizeB,
+		})
+	}
+
+	sortTree(rootNode)
+	return rootNode
+}
+
+// sortTree orders children with directories first, then by name.
+func sortTree(node *ArtifactTreeNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.Type != b.Type {
+			return a.Type == TreeNodeDirectory
+		}
+		return a.Name < b.Name
+	})
+	for _, child := range node.Children {
+		if child.Type == TreeNodeDirectory {
+			sortTree(child)
+		}
+	}
+}
+
+// treeNodeName returns the last segment of a directory path, "/" for the root.
+func treeNodeName(path string) string {
+	trimmed := strings.Trim(path, "/")
+	if trimmed == "" {
+		return "/"
+	}
+	return trimmed[strings.LastIndex(trimmed, "/")+1:]
+}
+
+// ErrSemanticSearchDisabled is returned by SemanticSearch when no embedding provider is configured.
+var ErrSemanticSearchDisabled = errors.New("semantic search is disabled: no embedding provider is configured")
+
+// indexTimeout bounds the background indexing of a batch of artifacts
+const indexTimeout = 5 * time.Minute
+
+// reindex rebuilds the search chunks of artifacts in the background, so that
+// writes never wait for the embedding provider nor fail because of it.
+func (s *artifactService) reindex(ctx context.Context, artifacts ...*model.Artifact) {
+	if s.embedder == nil || s.chunks == nil || len(artifacts) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), indexTimeout)
+		defer cancel()
+		for _, a := range artifacts {
+			if err := s.indexArtifact(ctx, a); err != nil {
+				s.log.Warn("failed to index artifact for semantic search",
+					zap.String("artifact_id", a.ID.String()), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// indexArtifact chunks and embeds the text content of an artifact version.
+// Artifacts without text content end up with no chunk.
+func (s *artifactService) indexArtifact(ctx context.Context, artifact *model.Artifact) error {
+	size, overlap := embedding.DefaultChunkSize, embedding.DefaultChunkOverlap
+	if s.cfg != nil && s.cfg.Embedding.ChunkSize > 0 {
+		size, overlap = s.cfg.Embedding.ChunkSize, s.cfg.Embedding.ChunkOverlap
+	}
+
+	pieces := embedding.Split(artifact.AssetMeta.Data().Content, size, overlap)
+	texts := make([]string, len(pieces))
+	for i, p := range pieces {
+		texts[i] = p.Text
+	}
+
+	var vectors [][]float32
+	if len(texts) > 0 {
+		var err error
+		if vectors, err = s.embedder.Embed(ctx, texts); err != nil {
+			return fmt.Errorf("embed chunks: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embed chunks: got %d vectors for %d chunks", len(vectors), len(texts))
+		}
+	}
+
+	chunks := make([]*model.ArtifactChunk, 0, len(pieces))
+	for i, p := range pieces {
+		// A zero vector has no direction to compare (e.g. punctuation only with the hashing embedder)
+		if isZeroVector(vectors[i]) {
+			continue
+		}
+		chunks = append(chunks, &model.ArtifactChunk{
+			ArtifactID:  artifact.ID,
+			DiskID:      artifact.DiskID,
+			ChunkIndex:  i,
+			Content:     p.Text,
+			StartOffset: p.Start,
+			EndOffset:   p.End,
+			StartLine:   p.StartLine,
+			EndLine:     p.EndLine,
+			Model:       s.embedder.Model(),
+			Embedding:   model.Vector(vectors[i]),
+		})
+	}
+
+	return s.chunks.Replace(ctx, artifact.ID, artifact.Version, chunks)
+}
+
+type SemanticSearchInput struct {
+	DiskID uuid.UUID
+	Query  string
+	// Path restricts the search to a directory and its subdirectories
+	Path  string
+	Limit int
+}
+
+// SemanticSearchHit is a chunk of artifact content matching a semantic search.
+type SemanticSearchHit struct {
+	ArtifactID uuid.UUID `json:"artifact_id"`
+	Path       string    `json:"path"`
+	Filename   string    `json:"filename"`
+	ChunkIndex int       `json:"chunk_index"`
+	Content    string    `json:"content"`
+	// Character offsets in the artifact content, end excluded
+	StartOffset int `json:"start_offset"`
+	EndOffset   int `json:"end_offset"`
+	// 1-based lines, inclusive
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Score is the cosine similarity with the query, higher is closer
+	Score float64 `json:"score"`
+}
+
+// SemanticSearch returns the indexed chunks closest in meaning to the query, best first.
+func (s *artifactService) SemanticSearch(ctx context.Context, in SemanticSearchInput) ([]*SemanticSearchHit, error) {
+	if s.embedder == nil || s.chunks == nil {
+		return nil, ErrSemanticSearchDisabled
+	}
+	if strings.TrimSpace(in.Query) == "" {
+		return nil, errors.New("query is required")
+	}
+	if in.Limit <= 0 {
+		in.Limit = 10
+	}
+	if in.Limit > 100 {
+		in.Limit = 100
+	}
+
+	vectors, err := s.embedder.Embed(ctx, []string{in.Query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	out := []*SemanticSearchHit{}
+	if len(vectors) != 1 || isZeroVector(vectors[0]) {
+		return out, nil
+	}
+
+	hits, err := s.chunks.Search(ctx, in.DiskID, s.embedder.Model(), model.Vector(vectors[0]), in.Path, in.Limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hits {
+		out = append(out, &SemanticSearchHit{
+			ArtifactID:  h.ArtifactID,
+			Path:        h.Path,
+			Filename:    h.Filename,
+			ChunkIndex:  h.ChunkIndex,
+			Content:     h.Content,
+			StartOffset: h.StartOffset,
+			EndOffset:   h.EndOffset,
+			StartLine:   h.StartLine,
+			EndLine:     h.EndLine,
+			Score:       h.Score,
+		})
+	}
+	return out, nil
+}
+
+func isZeroVector(v []float32) bool {
+	for _, x := range v {
+		if x != 0 {
+			return false
+		}
+	}
+	return true
+}
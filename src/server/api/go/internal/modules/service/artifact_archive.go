@@ -0,0 +1,374 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	stdpath "path"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"golang.org/x/sync/errgroup"
+)
+
+// Archive formats accepted by ExtractArchive
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+var (
+	// ErrInvalidArchive is returned for archives that can't be read or hold unsafe paths
+	ErrInvalidArchive = errors.New("invalid archive")
+	// ErrArchiveTooLarge is returned for archives above the configured file count or expanded size
+	ErrArchiveTooLarge = errors.New("archive too large")
+)
+
+// archiveUploadConcurrency bounds the files of an archive read into memory and uploaded at once
+const archiveUploadConcurrency = 8
+
+// ArchiveFormat returns the format of an archive from its file name, "" when unsupported.
+func ArchiveFormat(filename string) string {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return ArchiveZip
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return ArchiveTarGz
+	}
+	return ""
+}
+
+// archiveLimits bounds what walkArchive accepts; zero values are unlimited.
+type archiveLimits struct {
+	maxFiles         int
+	maxFileBytes     int64
+	maxExpandedBytes int64
+}
+
+// archivePath cleans the name of an archive entry into a relative, slash separated path.
+// Names escaping the extraction root ("zip slip") are rejected.
+func archivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\x00") {
+		return "", fmt.Errorf("%w: unsafe path %q", ErrInvalidArchive, name)
+	}
+	clean := stdpath.Clean(name)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w: unsafe path %q", ErrInvalidArchive, name)
+	}
+	return clean, nil
+}
+
+// walkArchive calls fn for every regular file of a zip or tar.gz archive, in archive order,
+// with its path as cleaned by archivePath. Directories, links and macOS metadata are skipped;
+// an unsafe path or a limit exceeded fails the walk.
+func walkArchive(r io.ReaderAt, size int64, format string, limits archiveLimits, fn func(name string, body io.Reader) error) error {
+	var (
+		files    int
+		expanded int64
+	)
+	// archive/zip and archive/tar fail reads beyond the declared sizes, so these can be trusted
+	visit := func(name string, declared int64, body io.Reader) error {
+		if isMacOSSystemFile(name) {
+			return nil
+		}
+		clean, err := archivePath(name)
+		if err != nil {
+			return err
+		}
+		files++
+		if limits.maxFiles > 0 && files > limits.maxFiles {
+			return fmt.Errorf("%w: more than %d files", ErrArchiveTooLarge, limits.maxFiles)
+		}
+		if limits.maxFileBytes > 0 && declared > limits.maxFileBytes {
+			return fmt.Errorf("%w: %s exceeds %d bytes", ErrArchiveTooLarge, clean, limits.maxFileBytes)
+		}
+		expanded += declared
+		if limits.maxExpandedBytes > 0 && expanded > limits.maxExpandedBytes {
+			return fmt.Errorf("%w: files exceed %d bytes", ErrArchiveTooLarge, limits.maxExpandedBytes)
+		}
+		return fn(clean, body)
+	}
+
+	switch format {
+	case ArchiveZip:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		// The central directory lists every name upfront: reject unsafe ones before extracting anything
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() && !isMacOSSystemFile(f.Name) {
+				if _, err := archivePath(f.Name); err != nil {
+					return err
+				}
+			}
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			body, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("%w: open %s: %v", ErrInvalidArchive, f.Name, err)
+			}
+			err = visit(f.Name, int64(f.UncompressedSize64), body)
+			body.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case ArchiveTarGz:
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer gz.Close()
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			// Links are skipped rather than followed, so they can't point outside the root either
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := visit(hdr.Name, hdr.Size, tr); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("%w: unsupported format %q", ErrInvalidArchive, format)
+}
+
+// archiveRoot returns the directory, with a trailing '/', holding every name, or "" when
+// the names don't share a single top-level directory.
+func archiveRoot(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	first, _, ok := strings.Cut(names[0], "/")
+	if !ok || first == "" {
+		return ""
+	}
+	for _, name := range names[1:] {
+		if !strings.HasPrefix(name, first+"/") {
+			return ""
+		}
+	}
+	return first + "/"
+}
+
+// lastEntries returns the indexes of the names to store: when several entries have
+// the same path, only the last one is, as it would overwrite the others when unpacked.
+func lastEntries(names []string) map[int]bool {
+	last := make(map[string]int, len(names))
+	for i, name := range names {
+		last[name] = i
+	}
+	keep := make(map[int]bool, len(last))
+	for _, i := range last {
+		keep[i] = true
+	}
+	return keep
+}
+
+type ExtractArchiveInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	// Path is the directory the archive is expanded under
+	Path string
+	// Format is ArchiveZip or ArchiveTarGz, detected from the file name when empty
+	Format string
+	File   *multipart.FileHeader
+	// StripRoot drops the top-level directory when every file of the archive is inside it
+	StripRoot bool
+}
+
+type ExtractArchiveOutput struct {
+	Artifacts []*model.Artifact `json:"artifacts"`
+}
+
+// ExtractArchive expands a zip or tar.gz archive into a disk. Each file is stored like an
+// upload, as a new version when an artifact already exists at its path. Files stored
+// before an error are kept.
+func (s *artifactService) ExtractArchive(ctx context.Context, in ExtractArchiveInput) (*ExtractArchiveOutput, error) {
+	format := in.Format
+	if format == "" {
+		format = ArchiveFormat(in.File.Filename)
+	}
+	if format != ArchiveZip && format != ArchiveTarGz {
+		return nil, fmt.Errorf("%w: expected a .zip, .tar.gz or .tgz file", ErrInvalidArchive)
+	}
+
+	f, err := in.File.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer f.Close()
+
+	cfg := s.cfgArtifact()
+	limits := archiveLimits{
+		maxFiles:         cfg.MaxArchiveFiles,
+		maxFileBytes:     cfg.MaxUploadSizeBytes,
+		maxExpandedBytes: cfg.MaxArchiveExpandedBytes,
+	}
+
+	// A first pass finds the root and the expanded size, which tar.gz only tells once read
+	var (
+		names    []string
+		expanded int64
+	)
+	if err := walkArchive(f, in.File.Size, format, limits, func(name string, body io.Reader) error {
+		n, err := io.Copy(io.Discard, body)
+		if err != nil {
+			return fmt.Errorf("%w: read %s: %v", ErrInvalidArchive, name, err)
+		}
+		names = append(names, name)
+		expanded += n
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	root := ""
+	if in.StripRoot {
+		root = archiveRoot(names)
+	}
+	// Duplicate paths would otherwise race to store the same artifact
+	keep := lastEntries(names)
+
+	// The files are stored concurrently, so the archive is admitted as a whole
+	if check := s.quotaCheck(ctx, in.ProjectID, in.DiskID); check != nil {
+		if err := check(expanded, int64(len(keep))); err != nil {
+			return nil, err
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(archiveUploadConcurrency)
+	var (
+		mu      sync.Mutex
+		created = map[int]*model.Artifact{}
+		n       int
+		entry   int
+	)
+	walkErr := walkArchive(f, in.File.Size, format, limits, func(name string, body io.Reader) error {
+		entry++
+		if !keep[entry-1] {
+			return nil
+		}
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("%w: read %s: %v", ErrInvalidArchive, name, err)
+		}
+		dir, filename := splitSkillPath(strings.TrimPrefix(name, root))
+		i := n
+		n++
+
+		g.Go(func() error {
+			artifact, err := s.CreateFromBytes(gctx, CreateArtifactFromBytesInput{
+				ProjectID: in.ProjectID,
+				DiskID:    in.DiskID,
+				Path:      in.Path + strings.TrimPrefix(dir, "/"),
+				Filename:  filename,
+				Content:   content,
+
+				quotaAdmitted: true,
+			})
+			if err != nil {
+				return fmt.Errorf("create artifact for %s: %w", name, err)
+			}
+			mu.Lock()
+			created[i] = artifact
+			mu.Unlock()
+			return nil
+		})
+		// Stop reading once an upload failed
+		return gctx.Err()
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	// In archive order
+	artifacts := make([]*model.Artifact, n)
+	for i, a := range created {
+		artifacts[i] = a
+	}
+	return &ExtractArchiveOutput{Artifacts: artifacts}, nil
+}
+
+// ArtifactArchive is a directory of a disk to be downloaded as a single archive.
+type ArtifactArchive struct {
+	// Name is the suggested file name of the archive, without extension
+	Name string
+	// Root is the directory the archive entries are relative to
+	Root      string
+	Artifacts []*model.Artifact
+}
+
+// GetArchive collects the artifacts under dir, at any depth, for WriteZip.
+func (s *artifactService) GetArchive(ctx context.Context, diskID uuid.UUID, dir string) (*ArtifactArchive, error) {
+	artifacts, err := s.r.ListByPrefix(ctx, diskID, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(artifacts) == 0 && dir != "/" {
+		return nil, fmt.Errorf("path not found")
+	}
+
+	name := treeNodeName(dir)
+	if name == "/" {
+		name = diskID.String()
+	}
+	return &ArtifactArchive{Name: name, Root: dir, Artifacts: artifacts}, nil
+}
+
+// WriteZip streams the artifacts of an archive to w as a zip file, named by their path below the archive root.
+func (s *artifactService) WriteZip(ctx context.Context, archive *ArtifactArchive, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, a := range archive.Artifacts {
+		hw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     strings.TrimPrefix(a.Path, archive.Root) + a.Filename,
+			Method:   zip.Deflate,
+			Modified: a.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("create zip entry: %w", err)
+		}
+		if err := s.copyObject(ctx, a.AssetMeta.Data().S3Key, hw); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// copyObject streams an object to w.
+func (s *artifactService) copyObject(ctx context.Context, key string, w io.Writer) error {
+	body, err := s.s3.OpenObject(ctx, key, 0)
+	if err != nil {
+		return fmt.Errorf("open object: %w", err)
+	}
+	defer body.Close()
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("copy object: %w", err)
+	}
+	return nil
+}
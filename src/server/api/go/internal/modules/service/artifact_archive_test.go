@@ -0,0 +1,261 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime/multipart"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// createTestTarGz builds a tar.gz of regular files, plus a symlink and a directory entry.
+func createTestTarGz(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"}))
+	for name, content := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func walkNames(data []byte, format string, limits archiveLimits) (map[string]string, error) {
+	files := map[string]string{}
+	err := walkArchive(bytes.NewReader(data), int64(len(data)), format, limits, func(name string, body io.Reader) error {
+		content, err := io.ReadAll(body)
+		files[name] = string(content)
+		return err
+	})
+	return files, err
+}
+
+func TestArchivePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "a.txt", want: "a.txt"},
+		{name: "src/./main.go", want: "src/main.go"},
+		{name: "src/lib/../main.go", want: "src/main.go"},
+		{name: `win\dir\a.txt`, want: "win/dir/a.txt"},
+		{name: "../evil.sh", wantErr: true},
+		{name: "src/../../evil.sh", wantErr: true},
+		{name: `..\evil.sh`, wantErr: true},
+		{name: "/etc/passwd", wantErr: true},
+		{name: "a\x00.txt", wantErr: true},
+		{name: ".", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := archivePath(tt.name)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidArchive)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWalkArchive_Zip(t *testing.T) {
+	data, err := createTestZipFile(map[string]string{
+		"proj/README.md":      "# readme",
+		"proj/src/main.go":    "package main",
+		"__MACOSX/proj/._a":   "junk",
+		"proj/.DS_Store":      "junk",
+		"proj/src/._main.go":  "junk",
+		"proj/docs/guide.txt": "guide",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	files, err := walkNames(data, ArchiveZip, archiveLimits{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]string{
+		"proj/README.md":      "# readme",
+		"proj/src/main.go":    "package main",
+		"proj/docs/guide.txt": "guide",
+	}, files)
+
+	t.Run("zip slip is rejected before extracting", func(t *testing.T) {
+		data, err := createTestZipFile(map[string]string{"a.txt": "a", "../../evil.sh": "rm -rf /"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		called := false
+		err = walkArchive(bytes.NewReader(data), int64(len(data)), ArchiveZip, archiveLimits{}, func(string, io.Reader) error {
+			called = true
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrInvalidArchive)
+		assert.False(t, called)
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		_, err := walkNames([]byte("plain text"), ArchiveZip, archiveLimits{})
+		assert.ErrorIs(t, err, ErrInvalidArchive)
+	})
+}
+
+func TestWalkArchive_TarGz(t *testing.T) {
+	data := createTestTarGz(t, map[string]string{"dir/a.txt": "a", "b.txt": "bb"})
+
+	files, err := walkNames(data, ArchiveTarGz, archiveLimits{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	// The directory and the symlink are skipped
+	assert.Equal(t, map[string]string{"dir/a.txt": "a", "b.txt": "bb"}, files)
+
+	t.Run("tar slip", func(t *testing.T) {
+		data := createTestTarGz(t, map[string]string{"../evil.sh": "x"})
+		_, err := walkNames(data, ArchiveTarGz, archiveLimits{})
+		assert.ErrorIs(t, err, ErrInvalidArchive)
+	})
+}
+
+func TestWalkArchive_Limits(t *testing.T) {
+	data, err := createTestZipFile(map[string]string{"a.txt": "aaaa", "b.txt": "bbbb"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name    string
+		limits  archiveLimits
+		wantErr bool
+	}{
+		{name: "within limits", limits: archiveLimits{maxFiles: 2, maxFileBytes: 4, maxExpandedBytes: 8}},
+		{name: "too many files", limits: archiveLimits{maxFiles: 1}, wantErr: true},
+		{name: "file too large", limits: archiveLimits{maxFileBytes: 3}, wantErr: true},
+		{name: "expanded too large", limits: archiveLimits{maxExpandedBytes: 7}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := walkNames(data, ArchiveZip, tt.limits)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrArchiveTooLarge)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestArchiveRoot(t *testing.T) {
+	assert.Equal(t, "proj/", archiveRoot([]string{"proj/a.txt", "proj/src/b.go"}))
+	assert.Equal(t, "", archiveRoot([]string{"proj/a.txt", "other/b.go"}))
+	assert.Equal(t, "", archiveRoot([]string{"a.txt"}))
+	assert.Equal(t, "", archiveRoot([]string{"proj/a.txt", "projx/b.txt"}))
+	assert.Equal(t, "", archiveRoot(nil))
+}
+
+func TestLastEntries(t *testing.T) {
+	assert.Equal(t, map[int]bool{1: true, 2: true}, lastEntries([]string{"a.txt", "b.txt", "a.txt"}))
+	assert.Equal(t, map[int]bool{0: true}, lastEntries([]string{"a.txt"}))
+	assert.Empty(t, lastEntries(nil))
+}
+
+func TestArtifactService_ExtractArchive_Rejected(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	slip, err := createTestZipFile(map[string]string{"ok.txt": "ok", "../evil.sh": "x"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	big, err := createTestZipFile(map[string]string{"big.bin": "0123456789"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name    string
+		file    *multipart.FileHeader
+		format  string
+		wantErr error
+	}{
+		{name: "unsupported format", file: createTestMultipartFileHeader("files.rar", slip), wantErr: ErrInvalidArchive},
+		{name: "zip slip", file: createTestMultipartFileHeader("files.zip", slip), wantErr: ErrInvalidArchive},
+		{name: "format overrides the file name", file: createTestMultipartFileHeader("upload.bin", slip), format: ArchiveZip, wantErr: ErrInvalidArchive},
+		{name: "file above upload limit", file: createTestMultipartFileHeader("files.zip", big), wantErr: ErrArchiveTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Rejected before any upload, so no S3 client nor repo call is needed
+			r := new(MockArtifactRepo)
+			svc := &artifactService{r: r, cfg: &config.Config{Artifact: config.ArtifactCfg{MaxUploadSizeBytes: 5}}}
+
+			_, err := svc.ExtractArchive(context.Background(), ExtractArchiveInput{
+				ProjectID: projectID,
+				DiskID:    diskID,
+				Path:      "/",
+				Format:    tt.format,
+				File:      tt.file,
+			})
+			assert.ErrorIs(t, err, tt.wantErr)
+			r.AssertExpectations(t)
+		})
+	}
+}
+
+func TestArtifactService_GetArchive(t *testing.T) {
+	diskID := uuid.New()
+	artifacts := []*model.Artifact{{Path: "/src/", Filename: "main.go"}}
+
+	r := new(MockArtifactRepo)
+	r.On("ListByPrefix", mock.Anything, diskID, "/src/").Return(artifacts, nil)
+	r.On("ListByPrefix", mock.Anything, diskID, "/").Return(nil, nil)
+	r.On("ListByPrefix", mock.Anything, diskID, "/nope/").Return(nil, nil)
+	svc := &artifactService{r: r}
+
+	archive, err := svc.GetArchive(context.Background(), diskID, "/src/")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &ArtifactArchive{Name: "src", Root: "/src/", Artifacts: artifacts}, archive)
+	}
+
+	// An empty disk is still an archive, named after the disk
+	archive, err = svc.GetArchive(context.Background(), diskID, "/")
+	if assert.NoError(t, err) {
+		assert.Equal(t, diskID.String(), archive.Name)
+	}
+
+	_, err = svc.GetArchive(context.Background(), diskID, "/nope/")
+	assert.EqualError(t, err, "path not found")
+}
+
+func TestWriteZip_Empty(t *testing.T) {
+	buf := new(bytes.Buffer)
+	svc := &artifactService{}
+
+	if !assert.NoError(t, svc.WriteZip(context.Background(), &ArtifactArchive{Root: "/"}, buf)) {
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if assert.NoError(t, err) {
+		assert.Empty(t, zr.File)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
+)
+
+// ErrInvalidArtifactQuery is returned for malformed artifact queries
+var ErrInvalidArtifactQuery = errors.New("invalid artifact query")
+
+// maxMetaConditions bounds the key comparisons of a query
+const maxMetaConditions = 20
+
+// MetaCondition compares the meta value at Key with Value. Key is a top-level key, or a
+// dotted path into nested objects such as "review.status".
+type MetaCondition struct {
+	Key   string      `json:"key" example:"priority"`
+	Op    string      `json:"op" example:"gte"` // eq, ne, gt, gte, lt, lte or exists
+	Value interface{} `json:"value"`
+}
+
+type QueryArtifactsInput struct {
+	DiskID        uuid.UUID
+	Meta          map[string]interface{}
+	Where         []MetaCondition
+	MIME          string
+	MinSizeB      int64
+	MaxSizeB      int64
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	PathPrefix    string
+	Limit         int
+	Cursor        string
+	TimeDesc      bool
+}
+
+type QueryArtifactsOutput struct {
+	Items      []*model.Artifact `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// QueryArtifacts returns the artifacts of a disk matching the meta, MIME type, size,
+// update time and path filters of in, a page at a time.
+func (s *artifactService) QueryArtifacts(ctx context.Context, in QueryArtifactsInput) (*QueryArtifactsOutput, error) {
+	q := repo.ArtifactQuery{
+		Meta:          in.Meta,
+		MIME:          in.MIME,
+		MinSizeB:      in.MinSizeB,
+		MaxSizeB:      in.MaxSizeB,
+		UpdatedAfter:  in.UpdatedAfter,
+		UpdatedBefore: in.UpdatedBefore,
+		PathPrefix:    in.PathPrefix,
+	}
+	if _, exists := in.Meta[model.ArtifactInfoKey]; exists {
+		return nil, fmt.Errorf("%w: reserved key '%s' cannot be queried", ErrInvalidArtifactQuery, model.ArtifactInfoKey)
+	}
+	if len(in.Where) > maxMetaConditions {
+		return nil, fmt.Errorf("%w: at most %d meta conditions", ErrInvalidArtifactQuery, maxMetaConditions)
+	}
+	for _, cond := range in.Where {
+		c, err := metaCondition(cond)
+		if err != nil {
+			return nil, err
+		}
+		q.Where = append(q.Where, c)
+	}
+	if in.MaxSizeB > 0 && in.MinSizeB > in.MaxSizeB {
+		return nil, fmt.Errorf("%w: min_size exceeds max_size", ErrInvalidArtifactQuery)
+	}
+
+	// Parse cursor (createdAt, id); an empty cursor indicates starting from the first page
+	var afterT time.Time
+	var afterID uuid.UUID
+	if in.Cursor != "" {
+		var err error
+		afterT, afterID, err = paging.DecodeCursor(in.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad cursor", ErrInvalidArtifactQuery)
+		}
+	}
+
+	// Query limit+1 is used to determine has_more
+	artifacts, err := s.r.QueryWithCursor(ctx, in.DiskID, q, afterT, afterID, in.Limit+1, in.TimeDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &QueryArtifactsOutput{Items: artifacts}
+	if out.Items == nil {
+		out.Items = []*model.Artifact{}
+	}
+	if len(artifacts) > in.Limit {
+		out.HasMore = true
+		out.Items = artifacts[:in.Limit]
+		last := out.Items[len(out.Items)-1]
+		out.NextCursor = paging.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return out, nil
+}
+
+func metaCondition(cond MetaCondition) (repo.MetaCondition, error) {
+	key := strings.Split(cond.Key, ".")
+	for _, k := range key {
+		if k == "" {
+			return repo.MetaCondition{}, fmt.Errorf("%w: invalid meta key %q", ErrInvalidArtifactQuery, cond.Key)
+		}
+	}
+	if key[0] == model.ArtifactInfoKey {
+		return repo.MetaCondition{}, fmt.Errorf("%w: reserved key '%s' cannot be queried", ErrInvalidArtifactQuery, model.ArtifactInfoKey)
+	}
+
+	switch cond.Op {
+	case repo.MetaOpExists, repo.MetaOpEq, repo.MetaOpNe:
+	case repo.MetaOpGt, repo.MetaOpGte, repo.MetaOpLt, repo.MetaOpLte:
+		switch cond.Value.(type) {
+		case float64, string:
+		default:
+			return repo.MetaCondition{}, fmt.Errorf("%w: %s on %q needs a number or a string", ErrInvalidArtifactQuery, cond.Op, cond.Key)
+		}
+	default:
+		return repo.MetaCondition{}, fmt.Errorf("%w: unknown operator %q", ErrInvalidArtifactQuery, cond.Op)
+	}
+	return repo.MetaCondition{Key: key, Op: cond.Op, Value: cond.Value}, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestArtifactService_QueryArtifacts(t *testing.T) {
+	diskID := uuid.New()
+	now := time.Now().UTC()
+	a1 := &model.Artifact{ID: uuid.New(), DiskID: diskID, Filename: "a.md", CreatedAt: now}
+	a2 := &model.Artifact{ID: uuid.New(), DiskID: diskID, Filename: "b.md", CreatedAt: now.Add(time.Second)}
+	a3 := &model.Artifact{ID: uuid.New(), DiskID: diskID, Filename: "c.md", CreatedAt: now.Add(2 * time.Second)}
+
+	t.Run("first page", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("QueryWithCursor", mock.Anything, diskID, mock.MatchedBy(func(q repo.ArtifactQuery) bool {
+			return q.Meta["status"] == "draft" && q.MIME == "text/markdown" && len(q.Where) == 1 &&
+				assert.ObjectsAreEqual([]string{"review", "score"}, q.Where[0].Key) && q.Where[0].Op == repo.MetaOpGte
+		}), time.Time{}, uuid.Nil, 3, false).Return([]*model.Artifact{a1, a2, a3}, nil)
+		s := &artifactService{r: r}
+
+		out, err := s.QueryArtifacts(context.Background(), QueryArtifactsInput{
+			DiskID: diskID,
+			Meta:   map[string]interface{}{"status": "draft"},
+			Where:  []MetaCondition{{Key: "review.score", Op: "gte", Value: float64(3)}},
+			MIME:   "text/markdown",
+			Limit:  2,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []*model.Artifact{a1, a2}, out.Items)
+		assert.True(t, out.HasMore)
+		assert.Equal(t, paging.EncodeCursor(a2.CreatedAt, a2.ID), out.NextCursor)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("next page", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("QueryWithCursor", mock.Anything, diskID, mock.Anything, a2.CreatedAt, a2.ID, 3, false).
+			Return([]*model.Artifact{a3}, nil)
+		s := &artifactService{r: r}
+
+		out, err := s.QueryArtifacts(context.Background(), QueryArtifactsInput{
+			DiskID: diskID,
+			Limit:  2,
+			Cursor: paging.EncodeCursor(a2.CreatedAt, a2.ID),
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []*model.Artifact{a3}, out.Items)
+		assert.False(t, out.HasMore)
+		assert.Empty(t, out.NextCursor)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("QueryWithCursor", mock.Anything, diskID, mock.Anything, time.Time{}, uuid.Nil, 21, true).
+			Return(nil, nil)
+		s := &artifactService{r: r}
+
+		out, err := s.QueryArtifacts(context.Background(), QueryArtifactsInput{DiskID: diskID, Limit: 20, TimeDesc: true})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotNil(t, out.Items)
+		assert.Empty(t, out.Items)
+	})
+
+	invalid := []struct {
+		name string
+		in   QueryArtifactsInput
+		msg  string
+	}{
+		{"unknown operator", QueryArtifactsInput{Where: []MetaCondition{{Key: "a", Op: "like", Value: "x"}}}, "unknown operator"},
+		{"ordering on a boolean", QueryArtifactsInput{Where: []MetaCondition{{Key: "a", Op: "gt", Value: true}}}, "needs a number or a string"},
+		{"empty key segment", QueryArtifactsInput{Where: []MetaCondition{{Key: "a..b", Op: "exists"}}}, "invalid meta key"},
+		{"reserved key condition", QueryArtifactsInput{Where: []MetaCondition{{Key: model.ArtifactInfoKey + ".mime", Op: "eq", Value: "x"}}}, "reserved key"},
+		{"reserved key containment", QueryArtifactsInput{Meta: map[string]interface{}{model.ArtifactInfoKey: map[string]interface{}{}}}, "reserved key"},
+		{"size range", QueryArtifactsInput{MinSizeB: 10, MaxSizeB: 5}, "min_size exceeds max_size"},
+		{"bad cursor", QueryArtifactsInput{Cursor: "!!"}, "bad cursor"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &artifactService{r: &MockArtifactRepo{}}
+			tt.in.Limit = 20
+			_, err := s.QueryArtifacts(context.Background(), tt.in)
+			assert.ErrorIs(t, err, ErrInvalidArtifactQuery)
+			assert.ErrorContains(t, err, tt.msg)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	pathutil "github.com/memodb-io/Acontext/internal/pkg/utils/path"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/tokens"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrShareNotFound is returned for unknown or revoked share tokens
+	ErrShareNotFound = errors.New("share not found")
+	// ErrShareUnavailable is returned once a share expired or reached its download limit
+	ErrShareUnavailable = errors.New("share has expired or reached its download limit")
+	// ErrSharePassword is returned when the password of a protected share is missing or wrong
+	ErrSharePassword = errors.New("share password is missing or incorrect")
+	// ErrInvalidSharePath is returned for paths outside the shared directory
+	ErrInvalidSharePath = errors.New("invalid share path")
+)
+
+// shareTokenBytes is the entropy of share tokens
+const shareTokenBytes = 32
+
+type CreateShareInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	// Path is the directory of the shared artifact, or the shared directory when Filename is empty
+	Path     string
+	Filename string
+	// Password is optional; it is required to access the share when set
+	Password string
+	// ExpiresIn is the lifetime of the share, 0 for no expiry
+	ExpiresIn time.Duration
+	// MaxDownloads limits the downloads through the share, 0 for no limit
+	MaxDownloads int
+}
+
+type CreateShareOutput struct {
+	Share *model.ArtifactShare `json:"share"`
+	// Token is only returned when the share is created
+	Token string `json:"token"`
+}
+
+// SharedFile is an artifact as seen through a share. Paths are relative to the shared directory.
+type SharedFile struct {
+	Path      string    `json:"path"`
+	Filename  string    `json:"filename"`
+	MIME      string    `json:"mime"`
+	SizeB     int64     `json:"size_b"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SharedListing is a directory of a share.
+type SharedListing struct {
+	Path        string       `json:"path"`
+	Files       []SharedFile `json:"files"`
+	Directories []string     `json:"directories"`
+}
+
+// CreateShare creates a read-only link to an artifact or a directory of a disk.
+func (s *artifactService) CreateShare(ctx context.Context, in CreateShareInput) (*CreateShareOutput, error) {
+	if in.Filename != "" {
+		if _, err := s.r.GetByPath(ctx, in.DiskID, in.Path, in.Filename); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("artifact not found")
+			}
+			return nil, err
+		}
+	}
+
+	raw := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("generate share token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	share := &model.ArtifactShare{
+		DiskID:       in.DiskID,
+		TokenHMAC:    tokens.HMAC256Hex(s.cfg.Root.SecretPepper, token),
+		Path:         in.Path,
+		Filename:     in.Filename,
+		MaxDownloads: in.MaxDownloads,
+	}
+	if in.Password != "" {
+		phc, err := secrets.HashSecret(in.Password, s.cfg.Root.SecretPepper)
+		if err != nil {
+			return nil, fmt.Errorf("hash share password: %w", err)
+		}
+		share.PasswordHashPHC = phc
+		share.PasswordProtected = true
+	}
+	if in.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(in.ExpiresIn)
+		share.ExpiresAt = &expiresAt
+	}
+
+	if err := s.r.CreateShare(ctx, in.ProjectID, share); err != nil {
+		return nil, err
+	}
+	return &CreateShareOutput{Share: share, Token: token}, nil
+}
+
+func (s *artifactService) ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error) {
+	shares, err := s.r.ListShares(ctx, projectID, diskID)
+	if err != nil {
+		return nil, err
+	}
+	if shares == nil {
+		shares = []*model.ArtifactShare{}
+	}
+	return shares, nil
+}
+
+func (s *artifactService) DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error {
+	if err := s.r.DeleteShare(ctx, projectID, diskID, shareID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrShareNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// OpenShare resolves a share token, checking its expiry, download limit and password.
+func (s *artifactService) OpenShare(ctx context.Context, token string, password string) (*model.ArtifactShare, error) {
+	if token == "" {
+		return nil, ErrShareNotFound
+	}
+	share, err := s.r.GetShareByToken(ctx, tokens.HMAC256Hex(s.cfg.Root.SecretPepper, token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+
+	if share.ExpiresAt != nil && !time.Now().Before(*share.ExpiresAt) {
+		return nil, ErrShareUnavailable
+	}
+	if share.MaxDownloads > 0 && share.DownloadCount >= share.MaxDownloads {
+		return nil, ErrShareUnavailable
+	}
+	if share.PasswordProtected {
+		if password == "" {
+			return nil, ErrSharePassword
+		}
+		ok, err := secrets.VerifySecret(password, s.cfg.Root.SecretPepper, share.PasswordHashPHC)
+		if err != nil {
+			return nil, fmt.Errorf("verify share password: %w", err)
+		}
+		if !ok {
+			return nil, ErrSharePassword
+		}
+	}
+	return share, nil
+}
+
+// ListShared lists a directory of a directory share; dir is relative to the shared directory.
+// A file share lists its single file.
+func (s *artifactService) ListShared(ctx context.Context, share *model.ArtifactShare, dir string) (*SharedListing, error) {
+	if !share.IsDirectory() {
+		a, err := s.r.GetByPath(ctx, share.DiskID, share.Path, share.Filename)
+		if err != nil {
+			return nil, sharedArtifactErr(err)
+		}
+		return &SharedListing{Path: "/", Files: []SharedFile{sharedFile(share, a)}, Directories: []string{}}, nil
+	}
+
+	full, err := sharedDir(share, dir)
+	if err != nil {
+		return nil, err
+	}
+	artifacts, err := s.r.ListByPath(ctx, share.DiskID, full)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := s.r.GetAllPaths(ctx, share.DiskID)
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &SharedListing{
+		Path:        "/" + strings.TrimPrefix(full, share.Path),
+		Files:       make([]SharedFile, 0, len(artifacts)),
+		Directories: pathutil.GetDirectoriesFromPaths(full, paths),
+	}
+	for _, a := range artifacts {
+		listing.Files = append(listing.Files, sharedFile(share, a))
+	}
+	return listing, nil
+}
+
+// OpenSharedFile counts a download against the share and opens the content of a shared file.
+// file is relative to the shared directory and ignored by file shares. The caller closes the body.
+func (s *artifactService) OpenSharedFile(ctx context.Context, share *model.ArtifactShare, file string) (*model.Artifact, io.ReadCloser, error) {
+	dir, filename := share.Path, share.Filename
+	if share.IsDirectory() {
+		rel, name := pathutil.SplitFilePath(file)
+		if name == "" {
+			return nil, nil, fmt.Errorf("%w: %q is not a file", ErrInvalidSharePath, file)
+		}
+		full, err := sharedDir(share, rel)
+		if err != nil {
+			return nil, nil, err
+		}
+		dir, filename = full, name
+	}
+
+	a, err := s.r.GetByPath(ctx, share.DiskID, dir, filename)
+	if err != nil {
+		return nil, nil, sharedArtifactErr(err)
+	}
+	if err := s.consumeShare(ctx, share); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := s.s3.OpenObject(ctx, a.AssetMeta.Data().S3Key, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open shared file: %w", err)
+	}
+	return a, body, nil
+}
+
+// OpenSharedArchive counts a download against a directory share and collects the
+// artifacts under dir for WriteZip.
+func (s *artifactService) OpenSharedArchive(ctx context.Context, share *model.ArtifactShare, dir string) (*ArtifactArchive, error) {
+	if !share.IsDirectory() {
+		return nil, fmt.Errorf("%w: only directory shares can be downloaded as an archive", ErrInvalidSharePath)
+	}
+	full, err := sharedDir(share, dir)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := s.GetArchive(ctx, share.DiskID, full)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.consumeShare(ctx, share); err != nil {
+		return nil, err
+	}
+	if full == "/" {
+		// Not the disk ID
+		archive.Name = "share"
+	}
+	return archive, nil
+}
+
+func (s *artifactService) consumeShare(ctx context.Context, share *model.ArtifactShare) error {
+	if err := s.r.ConsumeShareDownload(ctx, share.ID); err != nil {
+		if errors.Is(err, repo.ErrShareUnavailable) {
+			return ErrShareUnavailable
+		}
+		return err
+	}
+	return nil
+}
+
+// sharedDir resolves a directory relative to a directory share to a disk path.
+func sharedDir(share *model.ArtifactShare, dir string) (string, error) {
+	dir = strings.Trim(dir, "/")
+	full := share.Path
+	if dir != "" {
+		full += dir + "/"
+	}
+	if err := pathutil.ValidatePath(full); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSharePath, err)
+	}
+	return full, nil
+}
+
+func sharedFile(share *model.ArtifactShare, a *model.Artifact) SharedFile {
+	asset := a.AssetMeta.Data()
+	return SharedFile{
+		Path:      "/" + strings.TrimPrefix(a.Path, share.Path),
+		Filename:  a.Filename,
+		MIME:      asset.MIME,
+		SizeB:     asset.SizeB,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+func sharedArtifactErr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("artifact not found")
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/secrets"
+	"github.com/memodb-io/Acontext/internal/pkg/utils/tokens"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func shareTestConfig() *config.Config {
+	return &config.Config{Root: config.RootCfg{SecretPepper: "pepper"}}
+}
+
+func TestArtifactService_CreateShare(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	cfg := shareTestConfig()
+
+	t.Run("file share with password and limits", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("GetByPath", mock.Anything, diskID, "/reports/", "q1.pdf").Return(&model.Artifact{}, nil)
+		r.On("CreateShare", mock.Anything, projectID, mock.Anything).Return(nil)
+		svc := &artifactService{r: r, cfg: cfg}
+
+		out, err := svc.CreateShare(context.Background(), CreateShareInput{
+			ProjectID:    projectID,
+			DiskID:       diskID,
+			Path:         "/reports/",
+			Filename:     "q1.pdf",
+			Password:     "pw",
+			ExpiresIn:    time.Hour,
+			MaxDownloads: 3,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotEmpty(t, out.Token)
+		// Only the HMAC of the token is stored
+		assert.Equal(t, tokens.HMAC256Hex("pepper", out.Token), out.Share.TokenHMAC)
+		assert.True(t, out.Share.PasswordProtected)
+		ok, err := secrets.VerifySecret("pw", "pepper", out.Share.PasswordHashPHC)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, 3, out.Share.MaxDownloads)
+		if assert.NotNil(t, out.Share.ExpiresAt) {
+			assert.WithinDuration(t, time.Now().Add(time.Hour), *out.Share.ExpiresAt, time.Minute)
+		}
+		r.AssertExpectations(t)
+	})
+
+	t.Run("directory share", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("CreateShare", mock.Anything, projectID, mock.MatchedBy(func(s *model.ArtifactShare) bool {
+			return s.Path == "/reports/" && s.IsDirectory() && !s.PasswordProtected && s.ExpiresAt == nil
+		})).Return(nil)
+		svc := &artifactService{r: r, cfg: cfg}
+
+		_, err := svc.CreateShare(context.Background(), CreateShareInput{ProjectID: projectID, DiskID: diskID, Path: "/reports/"})
+		assert.NoError(t, err)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("artifact not found", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("GetByPath", mock.Anything, diskID, "/", "nope.txt").Return(nil, gorm.ErrRecordNotFound)
+		svc := &artifactService{r: r, cfg: cfg}
+
+		_, err := svc.CreateShare(context.Background(), CreateShareInput{ProjectID: projectID, DiskID: diskID, Path: "/", Filename: "nope.txt"})
+		assert.EqualError(t, err, "artifact not found")
+	})
+}
+
+func TestArtifactService_OpenShare(t *testing.T) {
+	cfg := shareTestConfig()
+	lookup := tokens.HMAC256Hex("pepper", "tok")
+	phc, err := secrets.HashSecret("pw", "pepper")
+	if !assert.NoError(t, err) {
+		return
+	}
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		share    *model.ArtifactShare
+		password string
+		wantErr  error
+	}{
+		{name: "open share", share: &model.ArtifactShare{ExpiresAt: &future, MaxDownloads: 2, DownloadCount: 1}},
+		{name: "unknown token", wantErr: ErrShareNotFound},
+		{name: "expired", share: &model.ArtifactShare{ExpiresAt: &past}, wantErr: ErrShareUnavailable},
+		{name: "download limit reached", share: &model.ArtifactShare{MaxDownloads: 2, DownloadCount: 2}, wantErr: ErrShareUnavailable},
+		{name: "password missing", share: &model.ArtifactShare{PasswordProtected: true, PasswordHashPHC: phc}, wantErr: ErrSharePassword},
+		{name: "password wrong", share: &model.ArtifactShare{PasswordProtected: true, PasswordHashPHC: phc}, password: "nope", wantErr: ErrSharePassword},
+		{name: "password right", share: &model.ArtifactShare{PasswordProtected: true, PasswordHashPHC: phc}, password: "pw"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(MockArtifactRepo)
+			if tt.share != nil {
+				r.On("GetShareByToken", mock.Anything, lookup).Return(tt.share, nil)
+			} else {
+				r.On("GetShareByToken", mock.Anything, lookup).Return(nil, gorm.ErrRecordNotFound)
+			}
+			svc := &artifactService{r: r, cfg: cfg}
+
+			share, err := svc.OpenShare(context.Background(), "tok", tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.share, share)
+		})
+	}
+}
+
+func TestArtifactService_ListShared(t *testing.T) {
+	diskID := uuid.New()
+	share := &model.ArtifactShare{DiskID: diskID, Path: "/reports/"}
+
+	r := new(MockArtifactRepo)
+	r.On("ListByPath", mock.Anything, diskID, "/reports/q1/").Return([]*model.Artifact{{
+		Path:      "/reports/q1/",
+		Filename:  "a.csv",
+		AssetMeta: datatypes.NewJSONType(model.Asset{MIME: "text/csv", SizeB: 12}),
+	}}, nil)
+	r.On("GetAllPaths", mock.Anything, diskID).Return([]string{"/reports/q1/", "/reports/q1/raw/", "/private/"}, nil)
+	svc := &artifactService{r: r}
+
+	listing, err := svc.ListShared(context.Background(), share, "/q1/")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "/q1/", listing.Path)
+	assert.Equal(t, []SharedFile{{Path: "/q1/", Filename: "a.csv", MIME: "text/csv", SizeB: 12}}, listing.Files)
+	assert.Equal(t, []string{"raw"}, listing.Directories)
+
+	t.Run("traversal", func(t *testing.T) {
+		_, err := svc.ListShared(context.Background(), share, "/../private/")
+		assert.ErrorIs(t, err, ErrInvalidSharePath)
+	})
+}
+
+func TestArtifactService_OpenSharedFile_Limit(t *testing.T) {
+	diskID := uuid.New()
+	share := &model.ArtifactShare{ID: uuid.New(), DiskID: diskID, Path: "/reports/"}
+
+	r := new(MockArtifactRepo)
+	r.On("GetByPath", mock.Anything, diskID, "/reports/q1/", "a.csv").Return(&model.Artifact{}, nil)
+	r.On("ConsumeShareDownload", mock.Anything, share.ID).Return(repo.ErrShareUnavailable)
+	svc := &artifactService{r: r}
+
+	// Rejected before opening the object, so no S3 client is needed
+	_, _, err := svc.OpenSharedFile(context.Background(), share, "/q1/a.csv")
+	assert.ErrorIs(t, err, ErrShareUnavailable)
+	r.AssertExpectations(t)
+
+	t.Run("directory path", func(t *testing.T) {
+		_, _, err := svc.OpenSharedFile(context.Background(), share, "/q1/")
+		assert.ErrorIs(t, err, ErrInvalidSharePath)
+	})
+}
+
+func TestArtifactService_OpenSharedArchive(t *testing.T) {
+	diskID := uuid.New()
+	share := &model.ArtifactShare{ID: uuid.New(), DiskID: diskID, Path: "/reports/"}
+	artifacts := []*model.Artifact{{Path: "/reports/q1/", Filename: "a.csv"}}
+
+	r := new(MockArtifactRepo)
+	r.On("ListByPrefix", mock.Anything, diskID, "/reports/q1/").Return(artifacts, nil)
+	r.On("ConsumeShareDownload", mock.Anything, share.ID).Return(nil)
+	svc := &artifactService{r: r}
+
+	archive, err := svc.OpenSharedArchive(context.Background(), share, "q1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &ArtifactArchive{Name: "q1", Root: "/reports/q1/", Artifacts: artifacts}, archive)
+	r.AssertExpectations(t)
+
+	t.Run("file share", func(t *testing.T) {
+		_, err := svc.OpenSharedArchive(context.Background(), &model.ArtifactShare{Path: "/", Filename: "a.txt"}, "")
+		assert.ErrorIs(t, err, ErrInvalidSharePath)
+	})
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"gorm.io/gorm"
+)
+
+// CreateSnapshot captures the current artifacts of a disk. Snapshots reference the
+// existing assets, so they are cheap to take and keep the content until deleted.
+func (s *artifactService) CreateSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, name string) (*model.DiskSnapshot, error) {
+	snapshot := &model.DiskSnapshot{DiskID: diskID, Name: name}
+	if err := s.r.CreateSnapshot(ctx, projectID, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *artifactService) ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error) {
+	snapshots, err := s.r.ListSnapshots(ctx, projectID, diskID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshots == nil {
+		snapshots = []*model.DiskSnapshot{}
+	}
+	return snapshots, nil
+}
+
+func (s *artifactService) DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error {
+	if err := s.r.DeleteSnapshot(ctx, projectID, diskID, snapshotID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("snapshot not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// RestoreSnapshotOutput counts the artifacts a restore created, updated and deleted.
+type RestoreSnapshotOutput struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// RestoreSnapshot brings the disk back to the state of a snapshot. Overwritten
+// artifacts keep their current content as a version.
+func (s *artifactService) RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) (*RestoreSnapshotOutput, error) {
+	ctx = repo.WithActor(ctx, model.DiskChangeActorSnapshotRestore)
+	res, err := s.r.RestoreSnapshot(ctx, projectID, diskID, snapshotID, s.maxVersions(), s.quotaCheck(ctx, projectID, diskID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("snapshot not found")
+		}
+		return nil, err
+	}
+	s.reindex(ctx, res.Changed...)
+	return &RestoreSnapshotOutput{
+		Created:   res.Created,
+		Updated:   res.Updated,
+		Deleted:   res.Deleted,
+		Unchanged: res.Unchanged,
+	}, nil
+}
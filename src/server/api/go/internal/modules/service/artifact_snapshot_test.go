@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestArtifactService_CreateSnapshot(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	t.Run("snapshot of disk", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("CreateSnapshot", mock.Anything, projectID, mock.MatchedBy(func(s *model.DiskSnapshot) bool {
+			return s.DiskID == diskID && s.Name == "before-run"
+		})).Return(nil)
+		svc := &artifactService{r: r}
+
+		snapshot, err := svc.CreateSnapshot(context.Background(), projectID, diskID, "before-run")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, diskID, snapshot.DiskID)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("disk not found", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("CreateSnapshot", mock.Anything, projectID, mock.Anything).Return(repo.ErrDiskNotFound)
+		svc := &artifactService{r: r}
+
+		_, err := svc.CreateSnapshot(context.Background(), projectID, diskID, "")
+		assert.ErrorIs(t, err, repo.ErrDiskNotFound)
+	})
+}
+
+func TestArtifactService_ListSnapshots_Empty(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	r := new(MockArtifactRepo)
+	r.On("ListSnapshots", mock.Anything, projectID, diskID).Return(nil, nil)
+	svc := &artifactService{r: r}
+
+	snapshots, err := svc.ListSnapshots(context.Background(), projectID, diskID)
+	assert.NoError(t, err)
+	assert.NotNil(t, snapshots)
+	assert.Empty(t, snapshots)
+}
+
+func TestArtifactService_DeleteSnapshot_NotFound(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	snapshotID := uuid.New()
+
+	r := new(MockArtifactRepo)
+	r.On("DeleteSnapshot", mock.Anything, projectID, diskID, snapshotID).Return(gorm.ErrRecordNotFound)
+	svc := &artifactService{r: r}
+
+	err := svc.DeleteSnapshot(context.Background(), projectID, diskID, snapshotID)
+	assert.EqualError(t, err, "snapshot not found")
+}
+
+func TestArtifactService_RestoreSnapshot(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	snapshotID := uuid.New()
+
+	t.Run("restore keeps configured versions", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("RestoreSnapshot", mock.Anything, projectID, diskID, snapshotID, 5).Return(&repo.SnapshotRestore{
+			Created:   1,
+			Updated:   2,
+			Deleted:   3,
+			Unchanged: 4,
+			Changed:   []*model.Artifact{{ID: uuid.New()}},
+		}, nil)
+		svc := &artifactService{r: r, cfg: &config.Config{Artifact: config.ArtifactCfg{MaxVersions: 5}}}
+
+		out, err := svc.RestoreSnapshot(context.Background(), projectID, diskID, snapshotID)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, &RestoreSnapshotOutput{Created: 1, Updated: 2, Deleted: 3, Unchanged: 4}, out)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("snapshot not found", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("RestoreSnapshot", mock.Anything, projectID, diskID, snapshotID, 0).Return(nil, gorm.ErrRecordNotFound)
+		svc := &artifactService{r: r}
+
+		_, err := svc.RestoreSnapshot(context.Background(), projectID, diskID, snapshotID)
+		assert.EqualError(t, err, "snapshot not found")
+	})
+}
@@ -1,18 +1,32 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"mime/multipart"
+	"strings"
 	"testing"
 	"time"
 
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/infra/blob/blobtest"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/pkg/embedding"
 	"github.com/memodb-io/Acontext/internal/pkg/utils/fileparser"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // MockArtifactRepo is a mock implementation of ArtifactRepo
@@ -25,13 +39,13 @@ func (m *MockArtifactRepo) Create(ctx context.Context, projectID uuid.UUID, f *m
 	return args.Error(0)
 }
 
-func (m *MockArtifactRepo) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
-	args := m.Called(ctx, projectID, diskID, path, filename)
+func (m *MockArtifactRepo) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, expectedSHA256 string) error {
+	args := m.Called(ctx, projectID, diskID, path, filename, expectedSHA256)
 	return args.Error(0)
 }
 
-func (m *MockArtifactRepo) Update(ctx context.Context, f *model.Artifact) error {
-	args := m.Called(ctx, f)
+func (m *MockArtifactRepo) Update(ctx context.Context, projectID uuid.UUID, f *model.Artifact, expectedSHA256 string) error {
+	args := m.Called(ctx, f, expectedSHA256)
 	return args.Error(0)
 }
 
@@ -72,6 +86,14 @@ func (m *MockArtifactRepo) GrepArtifacts(ctx context.Context, diskID uuid.UUID,
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactRepo) SearchContent(ctx context.Context, diskID uuid.UUID, q repo.ContentQuery) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, q)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
 func (m *MockArtifactRepo) GlobArtifacts(ctx context.Context, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	args := m.Called(ctx, diskID, pattern, limit)
 	if args.Get(0) == nil {
@@ -80,6 +102,192 @@ func (m *MockArtifactRepo) GlobArtifacts(ctx context.Context, diskID uuid.UUID,
 	return args.Get(0).([]*model.Artifact), args.Error(1)
 }
 
+func (m *MockArtifactRepo) AddVersion(ctx context.Context, projectID uuid.UUID, a *model.Artifact, maxVersions int) error {
+	args := m.Called(ctx, projectID, a, maxVersions)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) AddVersionIfMatch(ctx context.Context, projectID uuid.UUID, a *model.Artifact, expectedSHA256 string, maxVersions int) error {
+	args := m.Called(ctx, projectID, a, expectedSHA256, maxVersions)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) ListVersions(ctx context.Context, artifactID uuid.UUID) ([]*model.ArtifactVersion, error) {
+	args := m.Called(ctx, artifactID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactVersion), args.Error(1)
+}
+
+func (m *MockArtifactRepo) GetVersion(ctx context.Context, artifactID uuid.UUID, version int) (*model.ArtifactVersion, error) {
+	args := m.Called(ctx, artifactID, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactVersion), args.Error(1)
+}
+
+func (m *MockArtifactRepo) Transfer(ctx context.Context, projectID uuid.UUID, t repo.ArtifactTransfer) ([]*model.Artifact, []*model.Artifact, error) {
+	args := m.Called(ctx, projectID, t)
+	var transferred, skipped []*model.Artifact
+	if args.Get(0) != nil {
+		transferred = args.Get(0).([]*model.Artifact)
+	}
+	if args.Get(1) != nil {
+		skipped = args.Get(1).([]*model.Artifact)
+	}
+	return transferred, skipped, args.Error(2)
+}
+
+func (m *MockArtifactRepo) DeleteByPrefix(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, prefix string) (int64, error) {
+	args := m.Called(ctx, projectID, diskID, prefix)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockArtifactRepo) CreateDirectory(ctx context.Context, projectID uuid.UUID, d *model.ArtifactDirectory) error {
+	args := m.Called(ctx, projectID, d)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) CreateUpload(ctx context.Context, u *model.ArtifactUpload) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) ClaimUpload(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, id uuid.UUID, staleBefore time.Time) (*model.ArtifactUpload, error) {
+	args := m.Called(ctx, projectID, diskID, id, staleBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactUpload), args.Error(1)
+}
+
+func (m *MockArtifactRepo) ReleaseUpload(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) ListExpiredUploads(ctx context.Context, before time.Time, limit int) ([]*model.ArtifactUpload, error) {
+	args := m.Called(ctx, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactUpload), args.Error(1)
+}
+
+func (m *MockArtifactRepo) DeleteUpload(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) TreeStats(ctx context.Context, diskID uuid.UUID, root string, depth int) (*repo.ArtifactTreeStats, error) {
+	args := m.Called(ctx, diskID, root, depth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.ArtifactTreeStats), args.Error(1)
+}
+
+func (m *MockArtifactRepo) CreateSnapshot(ctx context.Context, projectID uuid.UUID, s *model.DiskSnapshot) error {
+	args := m.Called(ctx, projectID, s)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DiskSnapshot), args.Error(1)
+}
+
+func (m *MockArtifactRepo) DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error {
+	args := m.Called(ctx, projectID, diskID, snapshotID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID, maxVersions int, check repo.QuotaCheck) (*repo.SnapshotRestore, error) {
+	args := m.Called(ctx, projectID, diskID, snapshotID, maxVersions)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.SnapshotRestore), args.Error(1)
+}
+
+func (m *MockArtifactRepo) CreateShare(ctx context.Context, projectID uuid.UUID, s *model.ArtifactShare) error {
+	args := m.Called(ctx, projectID, s)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.ArtifactShare), args.Error(1)
+}
+
+func (m *MockArtifactRepo) DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error {
+	args := m.Called(ctx, projectID, diskID, shareID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) GetShareByToken(ctx context.Context, tokenHMAC string) (*model.ArtifactShare, error) {
+	args := m.Called(ctx, tokenHMAC)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ArtifactShare), args.Error(1)
+}
+
+func (m *MockArtifactRepo) ConsumeShareDownload(ctx context.Context, shareID uuid.UUID) error {
+	args := m.Called(ctx, shareID)
+	return args.Error(0)
+}
+
+func (m *MockArtifactRepo) ListByPrefix(ctx context.Context, diskID uuid.UUID, prefix string) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, prefix)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) QueryWithCursor(ctx context.Context, diskID uuid.UUID, q repo.ArtifactQuery, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Artifact, error) {
+	args := m.Called(ctx, diskID, q, afterCreatedAt, afterID, limit, timeDesc)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.Artifact), args.Error(1)
+}
+
+func (m *MockArtifactRepo) ListChanges(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, since int64, limit int) ([]*model.DiskChange, error) {
+	args := m.Called(ctx, projectID, diskID, since, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*model.DiskChange), args.Error(1)
+}
+
+// MockArtifactChunkRepo is a mock implementation of repo.ArtifactChunkRepo
+type MockArtifactChunkRepo struct {
+	mock.Mock
+}
+
+func (m *MockArtifactChunkRepo) Replace(ctx context.Context, artifactID uuid.UUID, version int, chunks []*model.ArtifactChunk) error {
+	args := m.Called(ctx, artifactID, version, chunks)
+	return args.Error(0)
+}
+
+func (m *MockArtifactChunkRepo) Search(ctx context.Context, diskID uuid.UUID, embeddingModel string, query model.Vector, pathPrefix string, limit int) ([]*repo.ChunkHit, error) {
+	args := m.Called(ctx, diskID, embeddingModel, query, pathPrefix, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*repo.ChunkHit), args.Error(1)
+}
+
 // MockArtifactS3Deps is a mock implementation of blob.S3Deps for file service
 type MockArtifactS3Deps struct {
 	mock.Mock
@@ -217,11 +425,11 @@ func (s *testArtifactService) Create(ctx context.Context, in CreateArtifactInput
 	return file, nil
 }
 
-func (s *testArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string) error {
+func (s *testArtifactService) DeleteByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, cond Preconditions) error {
 	if path == "" || filename == "" {
 		return errors.New("path and filename are required")
 	}
-	return s.r.DeleteByPath(ctx, projectID, diskID, path, filename)
+	return s.r.DeleteByPath(ctx, projectID, diskID, path, filename, "")
 }
 
 func (s *testArtifactService) GetByPath(ctx context.Context, diskID uuid.UUID, path string, filename string) (*model.Artifact, error) {
@@ -252,7 +460,7 @@ func (s *testArtifactService) GetAllPaths(ctx context.Context, diskID uuid.UUID)
 	return s.r.GetAllPaths(ctx, diskID)
 }
 
-func (s *testArtifactService) UpdateArtifactMetaByPath(ctx context.Context, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}) (*model.Artifact, error) {
+func (s *testArtifactService) UpdateArtifactMetaByPath(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, userMeta map[string]interface{}, cond Preconditions) (*model.Artifact, error) {
 	// Get existing artifact
 	artifact, err := s.GetByPath(ctx, diskID, path, filename)
 	if err != nil {
@@ -283,7 +491,7 @@ func (s *testArtifactService) UpdateArtifactMetaByPath(ctx context.Context, disk
 	// Update artifact meta
 	artifact.Meta = newMeta
 
-	if err := s.r.Update(ctx, artifact); err != nil {
+	if err := s.r.Update(ctx, projectID, artifact, ""); err != nil {
 		return nil, err
 	}
 
@@ -303,11 +511,19 @@ func (s *testArtifactService) GetFileContent(ctx context.Context, artifact *mode
 	}, nil
 }
 
+func (s *testArtifactService) ReadFileContent(ctx context.Context, artifact *model.Artifact, in ReadContentInput) (*fileparser.FileContent, *ContentRange, error) {
+	return nil, nil, errors.New("not implemented in test service")
+}
+
 func (s *testArtifactService) GrepArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	// Test implementation - return empty list for now
 	return []*model.Artifact{}, nil
 }
 
+func (s *testArtifactService) GrepContent(ctx context.Context, in GrepContentInput) (*GrepContentOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
 func (s *testArtifactService) GlobArtifacts(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, pattern string, limit int) ([]*model.Artifact, error) {
 	// Test implementation - return empty list for now
 	return []*model.Artifact{}, nil
@@ -348,6 +564,126 @@ func (s *testArtifactService) CreateFromBytes(ctx context.Context, in CreateArti
 	return artifact, nil
 }
 
+func (s *testArtifactService) CreateUpload(ctx context.Context, in CreateUploadInput) (*UploadTarget, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) CompleteUpload(ctx context.Context, in CompleteUploadInput) (*model.Artifact, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) SweepExpiredUploads(ctx context.Context) (int, error) {
+	return 0, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) ListVersions(ctx context.Context, diskID uuid.UUID, path string, filename string) ([]*model.ArtifactVersion, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) GetVersion(ctx context.Context, diskID uuid.UUID, path string, filename string, version int) (*model.ArtifactVersion, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) DiffVersions(ctx context.Context, diskID uuid.UUID, path string, filename string, fromVersion int, toVersion int) (*ArtifactDiff, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) RestoreVersion(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string, filename string, version int) (*model.Artifact, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) EditArtifact(ctx context.Context, in EditArtifactInput) (*model.Artifact, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) MoveArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) CopyArtifacts(ctx context.Context, in TransferArtifactsInput) (*TransferArtifactsOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) DeleteDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (int64, error) {
+	return 0, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) CreateDirectory(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, path string) (*model.ArtifactDirectory, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) GetTree(ctx context.Context, diskID uuid.UUID, path string, depth int) (*ArtifactTreeNode, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) SemanticSearch(ctx context.Context, in SemanticSearchInput) ([]*SemanticSearchHit, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) CreateSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, name string) (*model.DiskSnapshot, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) ListSnapshots(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.DiskSnapshot, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) DeleteSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) error {
+	return errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) RestoreSnapshot(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, snapshotID uuid.UUID) (*RestoreSnapshotOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) CreateShare(ctx context.Context, in CreateShareInput) (*CreateShareOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) ListShares(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) ([]*model.ArtifactShare, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) DeleteShare(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, shareID uuid.UUID) error {
+	return errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) OpenShare(ctx context.Context, token string, password string) (*model.ArtifactShare, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) ListShared(ctx context.Context, share *model.ArtifactShare, dir string) (*SharedListing, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) OpenSharedFile(ctx context.Context, share *model.ArtifactShare, file string) (*model.Artifact, io.ReadCloser, error) {
+	return nil, nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) OpenSharedArchive(ctx context.Context, share *model.ArtifactShare, dir string) (*ArtifactArchive, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) ExtractArchive(ctx context.Context, in ExtractArchiveInput) (*ExtractArchiveOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) GetArchive(ctx context.Context, diskID uuid.UUID, dir string) (*ArtifactArchive, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) QueryArtifacts(ctx context.Context, in QueryArtifactsInput) (*QueryArtifactsOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) ListChanges(ctx context.Context, in ListDiskChangesInput) (*ListDiskChangesOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testArtifactService) WriteZip(ctx context.Context, archive *ArtifactArchive, w io.Writer) error {
+	return errors.New("not implemented in test service")
+}
+
 // Test cases for Create method
 func TestArtifactService_Create(t *testing.T) {
 	projectID := uuid.New()
@@ -482,7 +818,7 @@ func TestArtifactService_UpdateArtifactMetaByPath(t *testing.T) {
 						return false
 					}
 					return true
-				})).Return(nil)
+				}), "").Return(nil)
 			},
 			expectError: false,
 		},
@@ -527,7 +863,7 @@ func TestArtifactService_UpdateArtifactMetaByPath(t *testing.T) {
 				existingArtifact.Filename = filename
 
 				repo.On("GetByPath", mock.Anything, diskID, path, filename).Return(existingArtifact, nil)
-				repo.On("Update", mock.Anything, mock.Anything).Return(errors.New("update error"))
+				repo.On("Update", mock.Anything, mock.Anything, "").Return(errors.New("update error"))
 			},
 			expectError: true,
 			errorMsg:    "update error",
@@ -541,7 +877,7 @@ func TestArtifactService_UpdateArtifactMetaByPath(t *testing.T) {
 
 			service := newTestArtifactService(mockRepo, &MockArtifactS3Deps{})
 
-			artifact, err := service.UpdateArtifactMetaByPath(context.Background(), diskID, path, filename, tt.userMeta)
+			artifact, err := service.UpdateArtifactMetaByPath(context.Background(), uuid.New(), diskID, path, filename, tt.userMeta, Preconditions{})
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -640,6 +976,79 @@ func TestArtifactService_GrepArtifacts(t *testing.T) {
 	}
 }
 
+func TestArtifactService_GrepContent(t *testing.T) {
+	diskID := uuid.New()
+	textArtifact := func(path, filename, content string) *model.Artifact {
+		return &model.Artifact{
+			Path:      path,
+			Filename:  filename,
+			AssetMeta: datatypes.NewJSONType(model.Asset{MIME: "text/plain", Content: content}),
+		}
+	}
+
+	t.Run("hits with context and filters", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("SearchContent", mock.Anything, diskID, repo.ContentQuery{Pattern: `a\.b`, IgnoreCase: true, Limit: 100}).
+			Return([]*model.Artifact{
+				textArtifact("/src/", "main.py", "import os\nx = A.B\ny = 1\n"),
+				textArtifact("/src/", "main.go", "A.B\n"),
+				textArtifact("/vendor/", "lib.py", "a.b\n"),
+			}, nil)
+		svc := &artifactService{r: r}
+
+		out, err := svc.GrepContent(context.Background(), GrepContentInput{
+			DiskID:       diskID,
+			Pattern:      "a.b",
+			IgnoreCase:   true,
+			FixedStrings: true,
+			Include:      []string{"*.py"},
+			Exclude:      []string{"/vendor/*"},
+			Before:       1,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, out.Files, 1)
+		assert.Equal(t, "/src/main.py", out.Files[0].Path)
+		assert.Equal(t, 1, out.Files[0].Matches)
+		assert.Len(t, out.Files[0].Lines, 2)
+		assert.Equal(t, 2, out.Files[0].Lines[1].LineNumber)
+		assert.Equal(t, GrepStats{Files: 1, Matches: 1}, out.Stats)
+	})
+
+	t.Run("pages until the file limit", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("SearchContent", mock.Anything, diskID, repo.ContentQuery{Pattern: "x", Limit: 2}).
+			Return([]*model.Artifact{
+				textArtifact("/", "a.md", "x"),
+				textArtifact("/", "b.txt", "x"),
+			}, nil)
+		r.On("SearchContent", mock.Anything, diskID, repo.ContentQuery{Pattern: "x", Limit: 2, Offset: 2}).
+			Return([]*model.Artifact{
+				textArtifact("/", "c.md", "x"),
+				textArtifact("/", "d.md", "x"),
+			}, nil)
+		svc := &artifactService{r: r}
+
+		out, err := svc.GrepContent(context.Background(), GrepContentInput{
+			DiskID:  diskID,
+			Pattern: "x",
+			Include: []string{"*.md"},
+			Limit:   2,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, out.Files, 2)
+		assert.Equal(t, "/c.md", out.Files[1].Path)
+		assert.True(t, out.Stats.Truncated)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		svc := &artifactService{r: new(MockArtifactRepo)}
+
+		_, err := svc.GrepContent(context.Background(), GrepContentInput{DiskID: diskID, Pattern: "(unclosed"})
+		assert.ErrorIs(t, err, ErrInvalidGrepPattern)
+	})
+}
+
 func TestArtifactService_GlobArtifacts(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -698,6 +1107,14 @@ func TestArtifactService_GlobArtifacts(t *testing.T) {
 			wantCount: 1,
 			wantErr:   false,
 		},
+
+		{
+			name:      "malformed pattern",
+			pattern:   "*.[ch",
+			limit:     100,
+			setupMock: func(repo *MockArtifactRepo) {},
+			wantErr:   true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -725,3 +1142,899 @@ func TestArtifactService_GlobArtifacts(t *testing.T) {
 		})
 	}
 }
+
+func TestArtifactService_Save(t *testing.T) {
+	projectID := uuid.New()
+	cfg := &config.Config{Artifact: config.ArtifactCfg{MaxVersions: 5}}
+
+	t.Run("creates new artifact", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		svc := &artifactService{r: repo, cfg: cfg}
+		artifact := createTestArtifact()
+
+		repo.On("GetByPath", mock.Anything, artifact.DiskID, artifact.Path, artifact.Filename).Return(nil, gorm.ErrRecordNotFound)
+		repo.On("Create", mock.Anything, projectID, artifact).Return(nil)
+
+		assert.NoError(t, svc.save(context.Background(), projectID, artifact, Preconditions{}))
+		assert.Equal(t, 1, artifact.Version)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("existing artifact gets a new version", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		svc := &artifactService{r: repo, cfg: cfg}
+		existing := createTestArtifact()
+		artifact := &model.Artifact{DiskID: existing.DiskID, Path: existing.Path, Filename: existing.Filename}
+
+		repo.On("GetByPath", mock.Anything, existing.DiskID, existing.Path, existing.Filename).Return(existing, nil)
+		repo.On("AddVersion", mock.Anything, projectID, artifact, 5).Return(nil)
+
+		assert.NoError(t, svc.save(context.Background(), projectID, artifact, Preconditions{}))
+		assert.Equal(t, existing.ID, artifact.ID)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+		repo.AssertNotCalled(t, "DeleteByPath", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lookup error", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		svc := &artifactService{r: repo, cfg: cfg}
+		artifact := createTestArtifact()
+
+		repo.On("GetByPath", mock.Anything, artifact.DiskID, artifact.Path, artifact.Filename).Return(nil, errors.New("db down"))
+
+		err := svc.save(context.Background(), projectID, artifact, Preconditions{})
+		assert.ErrorContains(t, err, "check artifact existence")
+	})
+}
+
+func TestArtifactService_CreateUpload_Validation(t *testing.T) {
+	svc := &artifactService{cfg: &config.Config{Artifact: config.ArtifactCfg{MaxDirectUploadSizeBytes: 1 << 30}}}
+	valid := CreateUploadInput{
+		Path:     "/videos/",
+		Filename: "demo.mp4",
+		SizeB:    1024,
+		SHA256:   strings.Repeat("ab", 32),
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*CreateUploadInput)
+		wantErr string
+	}{
+		{name: "bad sha256", modify: func(in *CreateUploadInput) { in.SHA256 = "xyz" }, wantErr: "sha256 must be 64 hex characters"},
+		{name: "empty file", modify: func(in *CreateUploadInput) { in.SizeB = 0 }, wantErr: "size must be positive"},
+		{name: "too large", modify: func(in *CreateUploadInput) { in.SizeB = 2 << 30 }, wantErr: "exceeds the maximum"},
+		{
+			name:    "reserved meta key",
+			modify:  func(in *CreateUploadInput) { in.UserMeta = map[string]interface{}{model.ArtifactInfoKey: "x"} },
+			wantErr: "reserved key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := valid
+			tt.modify(&in)
+			_, err := svc.CreateUpload(context.Background(), in)
+			assert.ErrorIs(t, err, ErrInvalidUpload)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPartSizeFor(t *testing.T) {
+	assert.Equal(t, int64(64<<20), partSizeFor(1<<30, 64<<20))
+	assert.Equal(t, int64(defaultPartSize), partSizeFor(1<<30, 0))
+	assert.Equal(t, int64(minPartSizeB), partSizeFor(1<<30, 1<<20))
+
+	// 1TB in 64MB parts would need 16384 parts
+	size := partSizeFor(1<<40, 64<<20)
+	assert.Equal(t, int64(128<<20), size)
+	assert.LessOrEqual(t, (int64(1<<40)+size-1)/size, int64(maxUploadParts))
+}
+
+func TestArtifactService_CompleteUpload_NotFound(t *testing.T) {
+	projectID, diskID, uploadID := uuid.New(), uuid.New(), uuid.New()
+	repo := new(MockArtifactRepo)
+	repo.On("ClaimUpload", mock.Anything, projectID, diskID, uploadID, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	svc := &artifactService{r: repo}
+
+	_, err := svc.CompleteUpload(context.Background(), CompleteUploadInput{ProjectID: projectID, DiskID: diskID, UploadID: uploadID})
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+	repo.AssertExpectations(t)
+}
+
+func TestArtifactService_CompleteUpload_Claim(t *testing.T) {
+	ctx := context.Background()
+	projectID, diskID := uuid.New(), uuid.New()
+	data := []byte("hello upload")
+	sum := sha256.Sum256(data)
+	newUpload := func() *model.ArtifactUpload {
+		return &model.ArtifactUpload{
+			ID:        uuid.New(),
+			ProjectID: projectID,
+			DiskID:    diskID,
+			Path:      "/",
+			Filename:  "a.txt",
+			SizeB:     int64(len(data)),
+			SHA256:    hex.EncodeToString(sum[:]),
+			S3Key:     "uploads/" + projectID.String() + "/a.txt",
+			ExpiresAt: time.Now().Add(time.Hour),
+		}
+	}
+
+	t.Run("another completion holds the upload", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("ClaimUpload", mock.Anything, projectID, diskID, mock.Anything, mock.MatchedBy(func(staleBefore time.Time) bool {
+			return time.Until(staleBefore) <= -uploadClaimTimeout
+		})).Return(nil, repo.ErrUploadClaimed)
+		svc := &artifactService{r: r}
+
+		_, err := svc.CompleteUpload(ctx, CompleteUploadInput{ProjectID: projectID, DiskID: diskID, UploadID: uuid.New()})
+		assert.ErrorIs(t, err, ErrUploadInProgress)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("a failure before the object is stored releases the claim", func(t *testing.T) {
+		s3, _ := blobtest.New(t)
+		upload := newUpload()
+		r := new(MockArtifactRepo)
+		r.On("ClaimUpload", mock.Anything, projectID, diskID, upload.ID, mock.Anything).Return(upload, nil)
+		r.On("ReleaseUpload", mock.Anything, upload.ID).Return(nil)
+		svc := &artifactService{r: r, s3: s3, log: zap.NewNop()}
+
+		// Nothing was uploaded to the staging key yet
+		_, err := svc.CompleteUpload(ctx, CompleteUploadInput{ProjectID: projectID, DiskID: diskID, UploadID: upload.ID})
+		assert.ErrorIs(t, err, ErrInvalidUpload)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("a failure after the object is stored deletes it", func(t *testing.T) {
+		s3, srv := blobtest.New(t)
+		upload := newUpload()
+		_, err := s3.Client.PutObject(ctx, &awss3.PutObjectInput{Bucket: &s3.Bucket, Key: &upload.S3Key, Body: bytes.NewReader(data)})
+		require.NoError(t, err)
+
+		r := new(MockArtifactRepo)
+		r.On("ClaimUpload", mock.Anything, projectID, diskID, upload.ID, mock.Anything).Return(upload, nil)
+		r.On("GetByPath", mock.Anything, diskID, "/", "a.txt").Return(nil, gorm.ErrRecordNotFound)
+		r.On("Create", mock.Anything, projectID, mock.Anything).Return(errors.New("db down"))
+		r.On("DeleteUpload", mock.Anything, upload.ID).Return(nil)
+		svc := &artifactService{r: r, s3: s3, log: zap.NewNop()}
+
+		_, err = svc.CompleteUpload(ctx, CompleteUploadInput{ProjectID: projectID, DiskID: diskID, UploadID: upload.ID})
+		assert.ErrorContains(t, err, "db down")
+		assert.Empty(t, srv.Keys(), "neither the staged nor the promoted object is left")
+		r.AssertExpectations(t)
+		r.AssertNotCalled(t, "ReleaseUpload", mock.Anything, mock.Anything)
+	})
+
+	t.Run("a failure after deduplicating keeps the shared object", func(t *testing.T) {
+		s3, srv := blobtest.New(t)
+		upload := newUpload()
+		shared := "disks/" + projectID.String() + "/" + upload.SHA256 + ".txt"
+		for _, key := range []string{upload.S3Key, shared} {
+			_, err := s3.Client.PutObject(ctx, &awss3.PutObjectInput{Bucket: &s3.Bucket, Key: &key, Body: bytes.NewReader(data)})
+			require.NoError(t, err)
+		}
+
+		r := new(MockArtifactRepo)
+		r.On("ClaimUpload", mock.Anything, projectID, diskID, upload.ID, mock.Anything).Return(upload, nil)
+		r.On("GetByPath", mock.Anything, diskID, "/", "a.txt").Return(nil, gorm.ErrRecordNotFound)
+		r.On("Create", mock.Anything, projectID, mock.Anything).Return(errors.New("db down"))
+		r.On("DeleteUpload", mock.Anything, upload.ID).Return(nil)
+		svc := &artifactService{r: r, s3: s3, log: zap.NewNop()}
+
+		_, err := svc.CompleteUpload(ctx, CompleteUploadInput{ProjectID: projectID, DiskID: diskID, UploadID: upload.ID})
+		assert.ErrorContains(t, err, "db down")
+		assert.Equal(t, []string{shared}, srv.Keys())
+	})
+}
+
+func TestArtifactService_SweepExpiredUploads(t *testing.T) {
+	t.Run("only uploads past the completion grace", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		repo.On("ListExpiredUploads", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+			return time.Until(before) <= -uploadCompleteGrace
+		}), uploadSweepBatch).Return([]*model.ArtifactUpload{}, nil)
+		svc := &artifactService{r: repo}
+
+		n, err := svc.SweepExpiredUploads(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("list error", func(t *testing.T) {
+		repo := new(MockArtifactRepo)
+		repo.On("ListExpiredUploads", mock.Anything, mock.Anything, uploadSweepBatch).Return(nil, errors.New("db down"))
+		svc := &artifactService{r: repo}
+
+		_, err := svc.SweepExpiredUploads(context.Background())
+		assert.ErrorContains(t, err, "db down")
+	})
+}
+
+func TestArtifactService_Versions(t *testing.T) {
+	projectID := uuid.New()
+	current := createTestArtifact()
+	current.Version = 3
+	v2 := &model.ArtifactVersion{
+		ArtifactID: current.ID,
+		DiskID:     current.DiskID,
+		Version:    2,
+		Path:       current.Path,
+		Filename:   current.Filename,
+		Meta:       map[string]interface{}{"rev": "two"},
+		AssetMeta:  datatypes.NewJSONType(model.Asset{S3Key: "disks/old", SHA256: "old-sha256", MIME: "text/plain"}),
+	}
+
+	newRepo := func() *MockArtifactRepo {
+		repo := new(MockArtifactRepo)
+		repo.On("GetByPath", mock.Anything, current.DiskID, current.Path, current.Filename).Return(current, nil)
+		return repo
+	}
+
+	t.Run("list starts with current version", func(t *testing.T) {
+		repo := newRepo()
+		repo.On("ListVersions", mock.Anything, current.ID).Return([]*model.ArtifactVersion{v2}, nil)
+		svc := &artifactService{r: repo}
+
+		versions, err := svc.ListVersions(context.Background(), current.DiskID, current.Path, current.Filename)
+		assert.NoError(t, err)
+		assert.Len(t, versions, 2)
+		assert.Equal(t, 3, versions[0].Version)
+		assert.True(t, versions[0].IsCurrent)
+		assert.Equal(t, 2, versions[1].Version)
+		assert.False(t, versions[1].IsCurrent)
+	})
+
+	t.Run("get current version without query", func(t *testing.T) {
+		repo := newRepo()
+		svc := &artifactService{r: repo}
+
+		v, err := svc.GetVersion(context.Background(), current.DiskID, current.Path, current.Filename, 3)
+		assert.NoError(t, err)
+		assert.True(t, v.IsCurrent)
+		repo.AssertNotCalled(t, "GetVersion", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("get missing version", func(t *testing.T) {
+		repo := newRepo()
+		repo.On("GetVersion", mock.Anything, current.ID, 7).Return(nil, gorm.ErrRecordNotFound)
+		svc := &artifactService{r: repo}
+
+		_, err := svc.GetVersion(context.Background(), current.DiskID, current.Path, current.Filename, 7)
+		assert.ErrorContains(t, err, "artifact version 7 not found")
+	})
+
+	t.Run("restore records old content as new version", func(t *testing.T) {
+		repo := newRepo()
+		repo.On("GetVersion", mock.Anything, current.ID, 2).Return(v2, nil)
+		repo.On("AddVersion", mock.Anything, projectID, mock.MatchedBy(func(a *model.Artifact) bool {
+			return a.ID == current.ID &&
+				a.AssetMeta.Data().SHA256 == "old-sha256" &&
+				a.Meta["rev"] == "two"
+		}), 10).Return(nil)
+		svc := &artifactService{r: repo, cfg: &config.Config{Artifact: config.ArtifactCfg{MaxVersions: 10}}}
+
+		restored, err := svc.RestoreVersion(context.Background(), projectID, current.DiskID, current.Path, current.Filename, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "disks/old", restored.AssetMeta.Data().S3Key)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("restore current version is a no-op", func(t *testing.T) {
+		repo := newRepo()
+		svc := &artifactService{r: repo}
+
+		restored, err := svc.RestoreVersion(context.Background(), projectID, current.DiskID, current.Path, current.Filename, 3)
+		assert.NoError(t, err)
+		assert.Same(t, current, restored)
+		repo.AssertNotCalled(t, "AddVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestApplyEdit(t *testing.T) {
+	const text = "one\ntwo\nthree\n"
+
+	tests := []struct {
+		name    string
+		text    string
+		in      EditArtifactInput
+		want    string
+		wantErr string
+	}{
+		{
+			name: "str_replace unique match",
+			text: text,
+			in:   EditArtifactInput{Op: EditOpStrReplace, OldStr: "two", NewStr: "2"},
+			want: "one\n2\nthree\n",
+		},
+		{
+			name:    "str_replace not found",
+			text:    text,
+			in:      EditArtifactInput{Op: EditOpStrReplace, OldStr: "four", NewStr: "4"},
+			wantErr: "not found",
+		},
+		{
+			name:    "str_replace ambiguous",
+			text:    text,
+			in:      EditArtifactInput{Op: EditOpStrReplace, OldStr: "o", NewStr: "0"},
+			wantErr: "occurs 2 times",
+		},
+		{
+			name: "insert at start",
+			text: text,
+			in:   EditArtifactInput{Op: EditOpInsert, Line: 0, Text: "zero\n"},
+			want: "zero\none\ntwo\nthree\n",
+		},
+		{
+			name: "insert at end without trailing newline",
+			text: "one\ntwo",
+			in:   EditArtifactInput{Op: EditOpInsert, Line: 2, Text: "three"},
+			want: "one\ntwo\nthree",
+		},
+		{
+			name:    "insert past end",
+			text:    text,
+			in:      EditArtifactInput{Op: EditOpInsert, Line: 4, Text: "x"},
+			wantErr: "between 0 and 3",
+		},
+		{
+			name: "replace lines",
+			text: text,
+			in:   EditArtifactInput{Op: EditOpReplaceLines, StartLine: 2, EndLine: 3, Text: "2\n3\n3.5\n"},
+			want: "one\n2\n3\n3.5\n",
+		},
+		{
+			name: "replace lines with nothing deletes them",
+			text: text,
+			in:   EditArtifactInput{Op: EditOpReplaceLines, StartLine: 1, EndLine: 2},
+			want: "three\n",
+		},
+		{
+			name:    "replace lines out of range",
+			text:    text,
+			in:      EditArtifactInput{Op: EditOpReplaceLines, StartLine: 3, EndLine: 4},
+			wantErr: "end_line <= 3",
+		},
+		{
+			name: "apply diff",
+			text: text,
+			in:   EditArtifactInput{Op: EditOpApplyDiff, Diff: "--- a\n+++ b\n@@ -2 +2 @@\n-two\n+TWO\n"},
+			want: "one\nTWO\nthree\n",
+		},
+		{
+			name:    "apply diff with stale context",
+			text:    text,
+			in:      EditArtifactInput{Op: EditOpApplyDiff, Diff: "@@ -2 +2 @@\n-deux\n+TWO\n"},
+			wantErr: "hunk 1 does not match",
+		},
+		{
+			name:    "unknown op",
+			text:    text,
+			in:      EditArtifactInput{Op: "append"},
+			wantErr: "unknown op",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := applyEdit(tt.text, tt.in)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrInvalidEdit)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.want, out)
+		})
+	}
+}
+
+func TestArtifactService_EditArtifact_Preconditions(t *testing.T) {
+	artifact := &model.Artifact{
+		ID:       uuid.New(),
+		DiskID:   uuid.New(),
+		Path:     "/src/",
+		Filename: "main.go",
+		AssetMeta: datatypes.NewJSONType(model.Asset{
+			S3Key: "disks/p/main.go", SHA256: "abc123", ETag: "etag-1", MIME: "text/plain", SizeB: 12,
+		}),
+	}
+	image := &model.Artifact{
+		DiskID:    artifact.DiskID,
+		Path:      "/img/",
+		Filename:  "logo.png",
+		AssetMeta: datatypes.NewJSONType(model.Asset{S3Key: "disks/p/logo.png", SHA256: "def", MIME: "image/png"}),
+	}
+
+	tests := []struct {
+		name    string
+		in      EditArtifactInput
+		wantErr error
+	}{
+		{
+			name:    "stale sha256",
+			in:      EditArtifactInput{Path: "/src/", Filename: "main.go", Op: EditOpStrReplace, OldStr: "a", ExpectedSHA256: "other"},
+			wantErr: ErrArtifactModified,
+		},
+		{
+			name:    "stale etag",
+			in:      EditArtifactInput{Path: "/src/", Filename: "main.go", Op: EditOpStrReplace, OldStr: "a", ExpectedETag: `"etag-0"`},
+			wantErr: ErrArtifactModified,
+		},
+		{
+			// Entity tags are those of the ETag header, never the S3 ETag of the object
+			name:    "storage etag",
+			in:      EditArtifactInput{Path: "/src/", Filename: "main.go", Op: EditOpStrReplace, OldStr: "a", ExpectedETag: `"etag-1"`},
+			wantErr: ErrArtifactModified,
+		},
+		{
+			name:    "binary file",
+			in:      EditArtifactInput{Path: "/img/", Filename: "logo.png", Op: EditOpStrReplace, OldStr: "a"},
+			wantErr: ErrInvalidEdit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockArtifactRepo)
+			repo.On("GetByPath", mock.Anything, artifact.DiskID, "/src/", "main.go").Return(artifact, nil).Maybe()
+			repo.On("GetByPath", mock.Anything, artifact.DiskID, "/img/", "logo.png").Return(image, nil).Maybe()
+			svc := &artifactService{r: repo}
+
+			tt.in.DiskID = artifact.DiskID
+			_, err := svc.EditArtifact(context.Background(), tt.in)
+			assert.ErrorIs(t, err, tt.wantErr)
+			repo.AssertNotCalled(t, "AddVersionIfMatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestBuildTransfer(t *testing.T) {
+	diskID := uuid.New()
+
+	tests := []struct {
+		name        string
+		source      string
+		dest        string
+		onConflict  string
+		expected    repo.ArtifactTransfer
+		expectError string
+	}{
+		{
+			name:   "rename file",
+			source: "/docs/a.md",
+			dest:   "/docs/b.md",
+			expected: repo.ArtifactTransfer{
+				SourcePath: "/docs/", SourceFilename: "a.md",
+				DestPath: "/docs/", DestFilename: "b.md",
+				OnConflict: repo.ConflictFail,
+			},
+		},
+		{
+			name:       "file into directory keeps filename",
+			source:     "/docs/a.md",
+			dest:       "/archive/",
+			onConflict: "skip",
+			expected: repo.ArtifactTransfer{
+				SourcePath: "/docs/", SourceFilename: "a.md",
+				DestPath:   "/archive/",
+				OnConflict: repo.ConflictSkip,
+			},
+		},
+		{
+			name:   "directory destination gets trailing slash",
+			source: "/docs/",
+			dest:   "/archive/docs",
+			expected: repo.ArtifactTransfer{
+				SourcePath: "/docs/",
+				DestPath:   "/archive/docs/",
+				OnConflict: repo.ConflictFail,
+			},
+		},
+		{
+			name:        "directory into itself",
+			source:      "/docs/",
+			dest:        "/docs/old/",
+			expectError: "into itself",
+		},
+		{
+			name:        "same file",
+			source:      "/docs/a.md",
+			dest:        "/docs/",
+			expectError: "source and destination are the same",
+		},
+		{
+			name:        "unknown policy",
+			source:      "/docs/a.md",
+			dest:        "/b.md",
+			onConflict:  "merge",
+			expectError: "unknown conflict policy",
+		},
+		{
+			name:        "path traversal",
+			source:      "/docs/a.md",
+			dest:        "/../b.md",
+			expectError: "destination_path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildTransfer(TransferArtifactsInput{
+				DiskID:          diskID,
+				SourcePath:      tt.source,
+				DestinationPath: tt.dest,
+				OnConflict:      tt.onConflict,
+			}, false)
+			if tt.expectError != "" {
+				assert.ErrorIs(t, err, ErrInvalidTransfer)
+				assert.ErrorContains(t, err, tt.expectError)
+				return
+			}
+			assert.NoError(t, err)
+			tt.expected.SourceDiskID = diskID
+			tt.expected.DestDiskID = diskID
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestBuildTransfer_CrossDisk(t *testing.T) {
+	diskID := uuid.New()
+	otherDiskID := uuid.New()
+
+	t.Run("copy into another disk", func(t *testing.T) {
+		got, err := buildTransfer(TransferArtifactsInput{
+			DiskID:            diskID,
+			SourcePath:        "/",
+			DestinationPath:   "/",
+			DestinationDiskID: otherDiskID,
+		}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, diskID, got.SourceDiskID)
+		assert.Equal(t, otherDiskID, got.DestDiskID)
+		assert.True(t, got.Copy)
+	})
+
+	t.Run("move into another disk", func(t *testing.T) {
+		_, err := buildTransfer(TransferArtifactsInput{
+			DiskID:            diskID,
+			SourcePath:        "/docs/",
+			DestinationPath:   "/docs/",
+			DestinationDiskID: otherDiskID,
+		}, false)
+		assert.ErrorIs(t, err, ErrInvalidTransfer)
+	})
+
+	t.Run("root into itself on the same disk", func(t *testing.T) {
+		_, err := buildTransfer(TransferArtifactsInput{
+			DiskID:            diskID,
+			SourcePath:        "/",
+			DestinationPath:   "/",
+			DestinationDiskID: diskID,
+		}, true)
+		assert.ErrorIs(t, err, ErrInvalidTransfer)
+	})
+}
+
+func TestArtifactService_MoveCopyArtifacts(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	in := TransferArtifactsInput{ProjectID: projectID, DiskID: diskID, SourcePath: "/src/", DestinationPath: "/dst/", OnConflict: "skip"}
+
+	t.Run("copy reports transferred and skipped", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("Transfer", mock.Anything, projectID, mock.MatchedBy(func(tr repo.ArtifactTransfer) bool {
+			return tr.Copy && tr.SourcePath == "/src/" && tr.DestPath == "/dst/" && tr.OnConflict == repo.ConflictSkip
+		})).Return(
+			[]*model.Artifact{{Path: "/dst/", Filename: "a.txt"}},
+			[]*model.Artifact{{Path: "/src/sub/", Filename: "b.txt"}},
+			nil,
+		)
+		svc := &artifactService{r: r}
+
+		out, err := svc.CopyArtifacts(context.Background(), in)
+		assert.NoError(t, err)
+		assert.Len(t, out.Artifacts, 1)
+		assert.Equal(t, []string{"/src/sub/b.txt"}, out.Skipped)
+	})
+
+	t.Run("move of missing source", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("Transfer", mock.Anything, projectID, mock.MatchedBy(func(tr repo.ArtifactTransfer) bool {
+			return !tr.Copy
+		})).Return(nil, nil, gorm.ErrRecordNotFound)
+		svc := &artifactService{r: r}
+
+		_, err := svc.MoveArtifacts(context.Background(), in)
+		assert.ErrorContains(t, err, "source /src/ not found")
+	})
+
+	t.Run("conflict is passed through", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("Transfer", mock.Anything, projectID, mock.Anything).
+			Return(nil, nil, fmt.Errorf("%w: /dst/a.txt", repo.ErrArtifactConflict))
+		svc := &artifactService{r: r}
+
+		_, err := svc.MoveArtifacts(context.Background(), in)
+		assert.ErrorIs(t, err, ErrArtifactConflict)
+	})
+}
+
+func TestBuildArtifactTree(t *testing.T) {
+	stats := &repo.ArtifactTreeStats{
+		Root: repo.DirectoryStat{FileCount: 4, SizeB: 100},
+		Dirs: []repo.DirectoryStat{
+			{Path: "docs", Depth: 1, FileCount: 3, SizeB: 90},
+			{Path: "docs/api", Depth: 2, FileCount: 2, SizeB: 60},
+		},
+		Files: []repo.FileStat{
+			{Path: "/", Filename: "README.md", SizeB: 10},
+			{Path: "/docs/", Filename: "index.md", SizeB: 30},
+		},
+		ExplicitDirs: []string{"empty", "docs/api/v1/deep"},
+	}
+
+	tree := buildArtifactTree("/", 2, stats)
+
+	assert.Equal(t, "/", tree.Name)
+	assert.Equal(t, int64(4), tree.FileCount)
+	assert.Equal(t, int64(100), tree.SizeB)
+	// Directories first, then files, each by name
+	assert.Len(t, tree.Children, 3)
+	assert.Equal(t, "docs", tree.Children[0].Name)
+	assert.Equal(t, "empty", tree.Children[1].Name)
+	assert.Equal(t, "README.md", tree.Children[2].Name)
+	assert.Equal(t, "/README.md", tree.Children[2].Path)
+
+	docs := tree.Children[0]
+	assert.Equal(t, "/docs/", docs.Path)
+	assert.Equal(t, int64(3), docs.FileCount)
+	assert.False(t, docs.Truncated)
+	assert.Len(t, docs.Children, 2)
+
+	api := docs.Children[0]
+	assert.Equal(t, "/docs/api/", api.Path)
+	assert.Equal(t, int64(60), api.SizeB)
+	assert.True(t, api.Truncated)
+	assert.Empty(t, api.Children)
+	assert.Equal(t, "index.md", docs.Children[1].Name)
+
+	empty := tree.Children[1]
+	assert.Equal(t, TreeNodeDirectory, empty.Type)
+	assert.Equal(t, int64(0), empty.FileCount)
+	assert.False(t, empty.Truncated)
+}
+
+func TestBuildArtifactTree_Subdirectory(t *testing.T) {
+	stats := &repo.ArtifactTreeStats{
+		Root:         repo.DirectoryStat{FileCount: 1, SizeB: 5},
+		Files:        []repo.FileStat{{Path: "/a/b/", Filename: "x.txt", SizeB: 5}},
+		ExplicitDirs: []string{"c/d"},
+	}
+
+	tree := buildArtifactTree("/a/b/", 1, stats)
+
+	assert.Equal(t, "b", tree.Name)
+	assert.Len(t, tree.Children, 2)
+	assert.Equal(t, "/a/b/c/", tree.Children[0].Path)
+	assert.True(t, tree.Children[0].Truncated)
+	assert.Equal(t, "/a/b/x.txt", tree.Children[1].Path)
+}
+
+func TestArtifactService_Directories(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	t.Run("delete directory", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("DeleteByPrefix", mock.Anything, projectID, diskID, "/tmp/").Return(int64(3), nil)
+		svc := &artifactService{r: r}
+
+		deleted, err := svc.DeleteDirectory(context.Background(), projectID, diskID, "/tmp/")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("delete rejects a file path", func(t *testing.T) {
+		svc := &artifactService{r: new(MockArtifactRepo)}
+
+		_, err := svc.DeleteDirectory(context.Background(), projectID, diskID, "/tmp/a.txt")
+		assert.Error(t, err)
+	})
+
+	t.Run("create directory", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("CreateDirectory", mock.Anything, projectID, mock.MatchedBy(func(d *model.ArtifactDirectory) bool {
+			return d.DiskID == diskID && d.Path == "/new/"
+		})).Return(nil)
+		svc := &artifactService{r: r}
+
+		d, err := svc.CreateDirectory(context.Background(), projectID, diskID, "/new/")
+		assert.NoError(t, err)
+		assert.Equal(t, "/new/", d.Path)
+	})
+
+	t.Run("create root directory", func(t *testing.T) {
+		svc := &artifactService{r: new(MockArtifactRepo)}
+
+		_, err := svc.CreateDirectory(context.Background(), projectID, diskID, "/")
+		assert.Error(t, err)
+	})
+
+	t.Run("tree depth is bounded", func(t *testing.T) {
+		r := new(MockArtifactRepo)
+		r.On("TreeStats", mock.Anything, diskID, "/", MaxTreeDepth).Return(&repo.ArtifactTreeStats{}, nil)
+		r.On("TreeStats", mock.Anything, diskID, "/", DefaultTreeDepth).Return(&repo.ArtifactTreeStats{}, nil)
+		svc := &artifactService{r: r}
+
+		_, err := svc.GetTree(context.Background(), diskID, "/", 100)
+		assert.NoError(t, err)
+		_, err = svc.GetTree(context.Background(), diskID, "/", 0)
+		assert.NoError(t, err)
+		r.AssertExpectations(t)
+	})
+}
+
+func TestArtifactService_SemanticSearch(t *testing.T) {
+	diskID := uuid.New()
+
+	t.Run("disabled without an embedder", func(t *testing.T) {
+		svc := &artifactService{chunks: new(MockArtifactChunkRepo)}
+
+		_, err := svc.SemanticSearch(context.Background(), SemanticSearchInput{DiskID: diskID, Query: "hello"})
+		assert.ErrorIs(t, err, ErrSemanticSearchDisabled)
+	})
+
+	t.Run("searches with the embedder model", func(t *testing.T) {
+		chunks := new(MockArtifactChunkRepo)
+		embedder := embedding.NewHashing(64)
+		artifactID := uuid.New()
+		chunks.On("Search", mock.Anything, diskID, embedder.Model(), mock.Anything, "/docs/", 10).Return([]*repo.ChunkHit{
+			{
+				ArtifactChunk: model.ArtifactChunk{ArtifactID: artifactID, ChunkIndex: 2, Content: "hello world", StartLine: 3, EndLine: 4},
+				Path:          "/docs/",
+				Filename:      "a.md",
+				Score:         0.9,
+			},
+		}, nil)
+		svc := &artifactService{chunks: chunks, embedder: embedder}
+
+		hits, err := svc.SemanticSearch(context.Background(), SemanticSearchInput{DiskID: diskID, Query: "hello", Path: "/docs/"})
+		assert.NoError(t, err)
+		assert.Len(t, hits, 1)
+		assert.Equal(t, artifactID, hits[0].ArtifactID)
+		assert.Equal(t, "a.md", hits[0].Filename)
+		assert.Equal(t, 2, hits[0].ChunkIndex)
+		assert.Equal(t, 3, hits[0].StartLine)
+		assert.Equal(t, 0.9, hits[0].Score)
+		chunks.AssertExpectations(t)
+	})
+
+	t.Run("query without words", func(t *testing.T) {
+		chunks := new(MockArtifactChunkRepo)
+		svc := &artifactService{chunks: chunks, embedder: embedding.NewHashing(64)}
+
+		hits, err := svc.SemanticSearch(context.Background(), SemanticSearchInput{DiskID: diskID, Query: "?!"})
+		assert.NoError(t, err)
+		assert.Empty(t, hits)
+		chunks.AssertNotCalled(t, "Search")
+	})
+}
+
+func TestArtifactService_IndexArtifact(t *testing.T) {
+	artifact := &model.Artifact{
+		ID:       uuid.New(),
+		DiskID:   uuid.New(),
+		Path:     "/",
+		Filename: "notes.md",
+		Version:  4,
+		AssetMeta: datatypes.NewJSONType(model.Asset{
+			Content: "first paragraph about cats\n\nsecond paragraph about dogs",
+		}),
+	}
+
+	chunks := new(MockArtifactChunkRepo)
+	chunks.On("Replace", mock.Anything, artifact.ID, 4, mock.MatchedBy(func(cs []*model.ArtifactChunk) bool {
+		return len(cs) == 1 && cs[0].DiskID == artifact.DiskID && cs[0].StartLine == 1 && cs[0].EndLine == 3 &&
+			cs[0].Model == "hashing-64" && len(cs[0].Embedding) == 64
+	})).Return(nil)
+	svc := &artifactService{chunks: chunks, embedder: embedding.NewHashing(64)}
+
+	assert.NoError(t, svc.indexArtifact(context.Background(), artifact))
+	chunks.AssertExpectations(t)
+}
+
+func TestArtifactService_GetFileContent_TooLarge(t *testing.T) {
+	svc := &artifactService{}
+	artifact := &model.Artifact{
+		Filename: "scan.pdf",
+		AssetMeta: datatypes.NewJSONType(model.Asset{
+			S3Key: "disks/p/scan.pdf",
+			MIME:  "application/pdf",
+			SizeB: fileparser.DefaultLimits.MaxFileSize + 1,
+		}),
+	}
+
+	// Refused before downloading, so no S3 client is needed
+	_, err := svc.GetFileContent(context.Background(), artifact)
+	assert.ErrorIs(t, err, fileparser.ErrFileTooLarge)
+}
+
+func TestNormalizeReadInput(t *testing.T) {
+	in, err := normalizeReadInput(ReadContentInput{})
+	assert.NoError(t, err)
+	assert.Equal(t, ReadContentInput{Unit: ReadUnitLines, Mode: ReadModeRange, Limit: DefaultReadLines}, in)
+
+	in, err = normalizeReadInput(ReadContentInput{Unit: ReadUnitBytes, Mode: ReadModeTail})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(DefaultReadBytes), in.Limit)
+
+	for _, bad := range []ReadContentInput{
+		{Unit: "pages"},
+		{Mode: "middle"},
+		{Mode: ReadModeHead, Offset: 5},
+		{Offset: -1},
+		{Limit: MaxReadLines + 1},
+		{Unit: ReadUnitBytes, Limit: MaxReadBytes + 1},
+	} {
+		_, err := normalizeReadInput(bad)
+		assert.ErrorIs(t, err, ErrInvalidReadRange, "%+v", bad)
+	}
+}
+
+func TestByteRange(t *testing.T) {
+	start, end := byteRange(ReadContentInput{Mode: ReadModeRange, Offset: 10, Limit: 5}, 100)
+	assert.Equal(t, []int64{10, 15}, []int64{start, end})
+
+	start, end = byteRange(ReadContentInput{Mode: ReadModeRange, Offset: 98, Limit: 5}, 100)
+	assert.Equal(t, []int64{98, 100}, []int64{start, end})
+
+	start, end = byteRange(ReadContentInput{Mode: ReadModeTail, Limit: 30}, 100)
+	assert.Equal(t, []int64{70, 100}, []int64{start, end})
+
+	start, end = byteRange(ReadContentInput{Mode: ReadModeTail, Limit: 300}, 100)
+	assert.Equal(t, []int64{0, 100}, []int64{start, end})
+
+	start, end = byteRange(ReadContentInput{Mode: ReadModeRange, Offset: 500, Limit: 5}, 100)
+	assert.Equal(t, []int64{100, 100}, []int64{start, end})
+}
+
+func TestArtifactService_ReadFileContent_Document(t *testing.T) {
+	// Extracted text kept in the asset is sliced without reading S3
+	svc := &artifactService{}
+	artifact := &model.Artifact{
+		Filename: "report.pdf",
+		AssetMeta: datatypes.NewJSONType(model.Asset{
+			S3Key:   "disks/p/report.pdf",
+			MIME:    "application/pdf",
+			SizeB:   50000,
+			Content: "p1\np2\np3\np4",
+		}),
+	}
+
+	content, rng, err := svc.ReadFileContent(context.Background(), artifact, ReadContentInput{Offset: 1, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, "pdf", content.Type)
+	assert.Equal(t, "p2\np3\n", content.Raw)
+	assert.Equal(t, int64(1), rng.Offset)
+	assert.Equal(t, int64(2), rng.Count)
+	assert.True(t, rng.HasMore)
+	assert.Equal(t, int64(4), *rng.TotalLines)
+	assert.Equal(t, int64(11), rng.TotalBytes)
+
+	content, rng, err = svc.ReadFileContent(context.Background(), artifact, ReadContentInput{Mode: ReadModeTail, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "p4", content.Raw)
+	assert.Equal(t, int64(3), rng.Offset)
+
+	_, _, err = svc.ReadFileContent(context.Background(), artifact, ReadContentInput{Unit: ReadUnitRows})
+	assert.ErrorIs(t, err, ErrInvalidReadRange)
+}
@@ -7,27 +7,64 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/pkg/paging"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type DiskService interface {
-	Create(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*model.Disk, error)
+	Create(ctx context.Context, in CreateDiskInput) (*model.Disk, error)
+	Update(ctx context.Context, in UpdateDiskInput) (*model.Disk, error)
 	Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error
+	Clone(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, userID *uuid.UUID) (*model.Disk, error)
 	List(ctx context.Context, in ListDisksInput) (*ListDisksOutput, error)
+	GetUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DiskUsageOutput, error)
+	GetProjectUsage(ctx context.Context, projectID uuid.UUID, topDisks int) (*ProjectUsageOutput, error)
 }
 
-type diskService struct{ r repo.DiskRepo }
+type diskService struct {
+	r     repo.DiskRepo
+	usage repo.UsageRepo
+	cfg   *config.Config
+}
 
-func NewDiskService(r repo.DiskRepo) DiskService {
-	return &diskService{r: r}
+func NewDiskService(r repo.DiskRepo, usage repo.UsageRepo, cfg *config.Config) DiskService {
+	return &diskService{r: r, usage: usage, cfg: cfg}
 }
 
-func (s *diskService) Create(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
+// ErrInvalidDisk is returned for disk fields that fail validation
+var ErrInvalidDisk = errors.New("invalid disk")
+
+type CreateDiskInput struct {
+	ProjectID   uuid.UUID
+	UserID      *uuid.UUID
+	Name        string
+	Description string
+	Meta        map[string]interface{}
+	// SessionID optionally links the disk to a session of the project
+	SessionID         *uuid.UUID
+	DeleteWithSession bool
+}
+
+func (s *diskService) Create(ctx context.Context, in CreateDiskInput) (*model.Disk, error) {
+	if err := validateDiskMeta(in.Meta); err != nil {
+		return nil, err
+	}
+	if in.DeleteWithSession && in.SessionID == nil {
+		return nil, fmt.Errorf("%w: delete_with_session requires a session", ErrInvalidDisk)
+	}
+
 	disk := &model.Disk{
-		ProjectID: projectID,
-		UserID:    userID,
+		ProjectID:         in.ProjectID,
+		UserID:            in.UserID,
+		Name:              in.Name,
+		Description:       in.Description,
+		Meta:              datatypes.JSONMap(in.Meta),
+		SessionID:         in.SessionID,
+		DeleteWithSession: in.DeleteWithSession,
 	}
 
 	if err := s.r.Create(ctx, disk); err != nil {
@@ -37,6 +74,85 @@ func (s *diskService) Create(ctx context.Context, projectID uuid.UUID, userID *u
 	return disk, nil
 }
 
+// UpdateDiskInput changes the fields that are set; nil fields are left alone.
+type UpdateDiskInput struct {
+	ProjectID   uuid.UUID
+	DiskID      uuid.UUID
+	Name        *string
+	Description *string
+	// Meta is merged into the disk meta; a nil value deletes its key
+	Meta map[string]interface{}
+	// SessionID links the disk to a session, uuid.Nil unlinks it
+	SessionID         *uuid.UUID
+	DeleteWithSession *bool
+}
+
+// Update changes the name, description, meta and session link of a disk.
+func (s *diskService) Update(ctx context.Context, in UpdateDiskInput) (*model.Disk, error) {
+	if err := validateDiskMeta(in.Meta); err != nil {
+		return nil, err
+	}
+
+	disk, err := s.r.Update(ctx, in.ProjectID, in.DiskID, func(d *model.Disk) error {
+		if in.Name != nil {
+			d.Name = *in.Name
+		}
+		if in.Description != nil {
+			d.Description = *in.Description
+		}
+		if len(in.Meta) > 0 {
+			meta := make(datatypes.JSONMap, len(d.Meta)+len(in.Meta))
+			for k, v := range d.Meta {
+				meta[k] = v
+			}
+			for k, v := range in.Meta {
+				if v == nil {
+					delete(meta, k) // null value = delete key
+				} else {
+					meta[k] = v // add or update key
+				}
+			}
+			d.Meta = meta
+		}
+		if in.SessionID != nil {
+			if *in.SessionID == uuid.Nil {
+				d.SessionID = nil
+				d.DeleteWithSession = false
+			} else {
+				sessionID := *in.SessionID
+				d.SessionID = &sessionID
+			}
+		}
+		if in.DeleteWithSession != nil {
+			d.DeleteWithSession = *in.DeleteWithSession
+		}
+		if d.DeleteWithSession && d.SessionID == nil {
+			return fmt.Errorf("%w: delete_with_session requires a session", ErrInvalidDisk)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("disk not found")
+		}
+		if errors.Is(err, ErrInvalidDisk) || errors.Is(err, repo.ErrSessionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("update disk: %w", err)
+	}
+	return disk, nil
+}
+
+// validateDiskMeta rejects user meta holding system reserved keys.
+func validateDiskMeta(meta map[string]interface{}) error {
+	for _, reservedKey := range (model.Disk{}).GetReservedKeys() {
+		if _, exists := meta[reservedKey]; exists {
+			return fmt.Errorf("%w: reserved key '%s' is not allowed in meta", ErrInvalidDisk, reservedKey)
+		}
+	}
+	return nil
+}
+
 func (s *diskService) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
 	if len(diskID) == 0 {
 		return errors.New("disk id is empty")
@@ -44,12 +160,54 @@ func (s *diskService) Delete(ctx context.Context, projectID uuid.UUID, diskID uu
 	return s.r.Delete(ctx, projectID, diskID)
 }
 
+// Clone creates a new disk holding a copy of every artifact of diskID without
+// duplicating S3 content. A nil userID keeps the source disk's user.
+func (s *diskService) Clone(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
+	disk := &model.Disk{UserID: userID}
+	if err := s.r.Clone(ctx, projectID, diskID, disk, s.cloneQuotaCheck(ctx, projectID, disk)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("disk not found")
+		}
+		return nil, fmt.Errorf("clone disk: %w", err)
+	}
+	return disk, nil
+}
+
+// cloneQuotaCheck admits the content of a clone: the new disk starts empty, so it is
+// checked against the disk quota alone, and the project and the clone's user grow by it.
+func (s *diskService) cloneQuotaCheck(ctx context.Context, projectID uuid.UUID, disk *model.Disk) repo.QuotaCheck {
+	if s.usage == nil || s.cfg == nil || s.cfg.Quota == (config.QuotaCfg{}) {
+		return nil
+	}
+	q := s.cfg.Quota
+	return func(addBytes int64, addFiles int64) error {
+		if err := exceedsQuota("disk", &repo.StorageUsage{}, q.DiskMaxBytes, q.DiskMaxFiles, addBytes, addFiles); err != nil {
+			return err
+		}
+		if err := checkQuota(ctx, s.usage, q, projectID, nil, addBytes, addFiles); err != nil {
+			return err
+		}
+		// The user is known once the disk is created
+		if disk.UserID == nil || (q.UserMaxBytes <= 0 && q.UserMaxFiles <= 0) {
+			return nil
+		}
+		uu, err := s.usage.UserUsage(ctx, projectID, *disk.UserID)
+		if err != nil {
+			return fmt.Errorf("get user usage: %w", err)
+		}
+		return exceedsQuota("user", uu, q.UserMaxBytes, q.UserMaxFiles, addBytes, addFiles)
+	}
+}
+
 type ListDisksInput struct {
-	ProjectID uuid.UUID `json:"project_id"`
-	User      string    `json:"user"`
-	Limit     int       `json:"limit"`
-	Cursor    string    `json:"cursor"`
-	TimeDesc  bool      `json:"time_desc"`
+	ProjectID    uuid.UUID              `json:"project_id"`
+	User         string                 `json:"user"`
+	NamePrefix   string                 `json:"name_prefix"`
+	FilterByMeta map[string]interface{} `json:"filter_by_meta"` // Filter by meta JSONB containment
+	SessionID    *uuid.UUID             `json:"session_id"`
+	Limit        int                    `json:"limit"`
+	Cursor       string                 `json:"cursor"`
+	TimeDesc     bool                   `json:"time_desc"`
 }
 
 type ListDisksOutput struct {
@@ -71,7 +229,13 @@ func (s *diskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOu
 	}
 
 	// Query limit+1 is used to determine has_more
-	disks, err := s.r.ListWithCursor(ctx, in.ProjectID, in.User, afterT, afterID, in.Limit+1, in.TimeDesc)
+	filter := repo.DiskFilter{
+		UserIdentifier: in.User,
+		NamePrefix:     in.NamePrefix,
+		Meta:           in.FilterByMeta,
+		SessionID:      in.SessionID,
+	}
+	disks, err := s.r.ListWithCursor(ctx, in.ProjectID, filter, afterT, afterID, in.Limit+1, in.TimeDesc)
 	if err != nil {
 		return nil, err
 	}
@@ -89,3 +253,54 @@ func (s *diskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOu
 
 	return out, nil
 }
+
+// GetUsage returns the storage used by a disk and by its user, with their quotas.
+func (s *diskService) GetUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DiskUsageOutput, error) {
+	du, uu, err := s.usage.DiskUsage(ctx, projectID, diskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("disk not found")
+		}
+		return nil, fmt.Errorf("get disk usage: %w", err)
+	}
+
+	q := s.quota()
+	out := &DiskUsageOutput{
+		DiskID: diskID,
+		Disk:   Usage{Bytes: du.Bytes, Files: du.Files, PendingBytes: du.PendingBytes, MaxBytes: q.DiskMaxBytes, MaxFiles: q.DiskMaxFiles},
+	}
+	if uu != nil {
+		out.User = &Usage{Bytes: uu.Bytes, Files: uu.Files, PendingBytes: uu.PendingBytes, MaxBytes: q.UserMaxBytes, MaxFiles: q.UserMaxFiles}
+	}
+	return out, nil
+}
+
+// GetProjectUsage returns the storage used by a project, message assets included,
+// and the topDisks disks using the most bytes.
+func (s *diskService) GetProjectUsage(ctx context.Context, projectID uuid.UUID, topDisks int) (*ProjectUsageOutput, error) {
+	pu, err := s.usage.ProjectUsage(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get project usage: %w", err)
+	}
+	disks, count, err := s.usage.TopDisks(ctx, projectID, topDisks)
+	if err != nil {
+		return nil, fmt.Errorf("get disk usage: %w", err)
+	}
+	if disks == nil {
+		disks = []repo.DiskStorageUsage{}
+	}
+
+	q := s.quota()
+	return &ProjectUsageOutput{
+		Project:   Usage{Bytes: pu.Bytes, Files: pu.Files, PendingBytes: pu.PendingBytes, MaxBytes: q.ProjectMaxBytes, MaxFiles: q.ProjectMaxFiles},
+		DiskCount: count,
+		TopDisks:  disks,
+	}, nil
+}
+
+func (s *diskService) quota() config.QuotaCfg {
+	if s.cfg == nil {
+		return config.QuotaCfg{}
+	}
+	return s.cfg.Quota
+}
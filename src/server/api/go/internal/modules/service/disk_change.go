@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	mq "github.com/memodb-io/Acontext/internal/infra/queue"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"go.uber.org/zap"
+)
+
+type ListDiskChangesInput struct {
+	ProjectID uuid.UUID
+	DiskID    uuid.UUID
+	Since     int64
+	Limit     int
+}
+
+type ListDiskChangesOutput struct {
+	Items []*model.DiskChange `json:"items"`
+	// NextSince is the seq to resume from: the last returned one, or since when none is
+	NextSince int64 `json:"next_since"`
+	HasMore   bool  `json:"has_more"`
+}
+
+// ListChanges returns the changes of a disk after the seq in.Since, oldest first.
+func (s *artifactService) ListChanges(ctx context.Context, in ListDiskChangesInput) (*ListDiskChangesOutput, error) {
+	changes, err := s.r.ListChanges(ctx, in.ProjectID, in.DiskID, in.Since, in.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ListDiskChangesOutput{Items: changes, NextSince: in.Since}
+	if len(changes) > in.Limit {
+		out.HasMore = true
+		out.Items = changes[:in.Limit]
+	}
+	if out.Items == nil {
+		out.Items = []*model.DiskChange{}
+	}
+	if len(out.Items) > 0 {
+		out.NextSince = out.Items[len(out.Items)-1].Seq
+	}
+	return out, nil
+}
+
+// DiskChangeMQPublishJSON is the message published for each disk change.
+type DiskChangeMQPublishJSON struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	*model.DiskChange
+}
+
+type jsonPublisher interface {
+	PublishJSON(ctx context.Context, exchangeName string, routingKey string, body any) error
+}
+
+type diskChangePublisher struct {
+	publisher jsonPublisher
+	cfg       *config.Config
+	log       *zap.Logger
+}
+
+// NewDiskChangePublisher returns a notifier publishing disk changes to RabbitMQ,
+// or nil when no disk change exchange is configured.
+func NewDiskChangePublisher(publisher *mq.Publisher, cfg *config.Config, log *zap.Logger) repo.DiskChangeNotifier {
+	if publisher == nil || cfg.RabbitMQ.ExchangeName.DiskChange == "" {
+		return nil
+	}
+	return &diskChangePublisher{publisher: publisher, cfg: cfg, log: log}
+}
+
+// NotifyDiskChanges publishes each change with the routing key "<prefix>.<type>".
+// The change log stays the source of truth: failures are logged, not returned.
+func (p *diskChangePublisher) NotifyDiskChanges(ctx context.Context, projectID uuid.UUID, changes []*model.DiskChange) {
+	for _, c := range changes {
+		routingKey := p.cfg.RabbitMQ.RoutingKey.DiskChange + "." + c.Type
+		if err := p.publisher.PublishJSON(ctx, p.cfg.RabbitMQ.ExchangeName.DiskChange, routingKey, DiskChangeMQPublishJSON{
+			ProjectID:  projectID,
+			DiskChange: c,
+		}); err != nil {
+			p.log.Error("publish disk change", zap.String("disk_id", c.DiskID.String()), zap.Int64("seq", c.Seq), zap.Error(err))
+		}
+	}
+}
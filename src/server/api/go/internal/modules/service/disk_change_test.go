@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestArtifactService_ListChanges(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	c1 := &model.DiskChange{DiskID: diskID, Seq: 5, Type: model.DiskChangeCreate}
+	c2 := &model.DiskChange{DiskID: diskID, Seq: 6, Type: model.DiskChangeUpdate}
+	c3 := &model.DiskChange{DiskID: diskID, Seq: 7, Type: model.DiskChangeDelete}
+
+	t.Run("page with more", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("ListChanges", mock.Anything, projectID, diskID, int64(4), 3).Return([]*model.DiskChange{c1, c2, c3}, nil)
+		s := &artifactService{r: r}
+
+		out, err := s.ListChanges(context.Background(), ListDiskChangesInput{ProjectID: projectID, DiskID: diskID, Since: 4, Limit: 2})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []*model.DiskChange{c1, c2}, out.Items)
+		assert.True(t, out.HasMore)
+		assert.Equal(t, int64(6), out.NextSince)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("caught up", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("ListChanges", mock.Anything, projectID, diskID, int64(7), 3).Return(nil, nil)
+		s := &artifactService{r: r}
+
+		out, err := s.ListChanges(context.Background(), ListDiskChangesInput{ProjectID: projectID, DiskID: diskID, Since: 7, Limit: 2})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []*model.DiskChange{}, out.Items)
+		assert.False(t, out.HasMore)
+		assert.Equal(t, int64(7), out.NextSince)
+	})
+
+	t.Run("disk not found", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("ListChanges", mock.Anything, projectID, diskID, int64(0), 3).Return(nil, repo.ErrDiskNotFound)
+		s := &artifactService{r: r}
+
+		_, err := s.ListChanges(context.Background(), ListDiskChangesInput{ProjectID: projectID, DiskID: diskID, Limit: 2})
+		assert.ErrorIs(t, err, repo.ErrDiskNotFound)
+	})
+}
+
+func TestNewDiskChangePublisher_Disabled(t *testing.T) {
+	cfg := &config.Config{}
+	assert.Nil(t, NewDiskChangePublisher(nil, cfg, zap.NewNop()))
+}
+
+func TestDiskChangePublisher_NotifyDiskChanges(t *testing.T) {
+	projectID := uuid.New()
+	cfg := &config.Config{}
+	cfg.RabbitMQ.ExchangeName.DiskChange = "disk.change"
+	cfg.RabbitMQ.RoutingKey.DiskChange = "disk.change"
+
+	created := &model.DiskChange{DiskID: uuid.New(), Seq: 1, Type: model.DiskChangeCreate}
+	moved := &model.DiskChange{DiskID: created.DiskID, Seq: 2, Type: model.DiskChangeMove}
+
+	pub := &MockPublisher{}
+	pub.On("PublishJSON", mock.Anything, "disk.change", "disk.change.create", DiskChangeMQPublishJSON{ProjectID: projectID, DiskChange: created}).
+		Return(errors.New("channel closed"))
+	pub.On("PublishJSON", mock.Anything, "disk.change", "disk.change.move", DiskChangeMQPublishJSON{ProjectID: projectID, DiskChange: moved}).
+		Return(nil)
+	p := &diskChangePublisher{publisher: pub, cfg: cfg, log: zap.NewNop()}
+
+	// A failed publication does not stop the others
+	p.NotifyDiskChanges(context.Background(), projectID, []*model.DiskChange{created, moved})
+	pub.AssertExpectations(t)
+}
@@ -8,8 +8,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 // MockDiskRepo is a mock implementation of DiskRepo
@@ -22,13 +24,39 @@ func (m *MockDiskRepo) Create(ctx context.Context, a *model.Disk) error {
 	return args.Error(0)
 }
 
+func (m *MockDiskRepo) Get(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Disk), args.Error(1)
+}
+
+// Update applies fn to the disk returned by the mock, as the repo does under its row lock
+func (m *MockDiskRepo) Update(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, apply func(d *model.Disk) error) (*model.Disk, error) {
+	args := m.Called(ctx, projectID, diskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	d := args.Get(0).(*model.Disk)
+	if err := apply(d); err != nil {
+		return nil, err
+	}
+	return d, args.Error(1)
+}
+
 func (m *MockDiskRepo) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
 	args := m.Called(ctx, projectID, diskID)
 	return args.Error(0)
 }
 
-func (m *MockDiskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error) {
-	args := m.Called(ctx, projectID, afterCreatedAt, afterID, limit, timeDesc)
+func (m *MockDiskRepo) Clone(ctx context.Context, projectID uuid.UUID, sourceDiskID uuid.UUID, d *model.Disk, check repo.QuotaCheck) error {
+	args := m.Called(ctx, projectID, sourceDiskID, d)
+	return args.Error(0)
+}
+
+func (m *MockDiskRepo) ListWithCursor(ctx context.Context, projectID uuid.UUID, filter repo.DiskFilter, afterCreatedAt time.Time, afterID uuid.UUID, limit int, timeDesc bool) ([]*model.Disk, error) {
+	args := m.Called(ctx, projectID, filter, afterCreatedAt, afterID, limit, timeDesc)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -63,11 +91,11 @@ func newTestDiskService(r *MockDiskRepo, s3 *MockS3Deps) DiskService {
 	return &testDiskService{r: r, s3: s3}
 }
 
-func (s *testDiskService) Create(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
+func (s *testDiskService) Create(ctx context.Context, in CreateDiskInput) (*model.Disk, error) {
 	disk := &model.Disk{
 		ID:        uuid.New(),
-		ProjectID: projectID,
-		UserID:    userID,
+		ProjectID: in.ProjectID,
+		UserID:    in.UserID,
 	}
 
 	if err := s.r.Create(ctx, disk); err != nil {
@@ -77,6 +105,10 @@ func (s *testDiskService) Create(ctx context.Context, projectID uuid.UUID, userI
 	return disk, nil
 }
 
+func (s *testDiskService) Update(ctx context.Context, in UpdateDiskInput) (*model.Disk, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
 func (s *testDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) error {
 	if diskID == uuid.Nil {
 		return errors.New("disk id is empty")
@@ -84,14 +116,33 @@ func (s *testDiskService) Delete(ctx context.Context, projectID uuid.UUID, diskI
 	return s.r.Delete(ctx, projectID, diskID)
 }
 
+func (s *testDiskService) Clone(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID, userID *uuid.UUID) (*model.Disk, error) {
+	disk := &model.Disk{UserID: userID}
+	if err := s.r.Clone(ctx, projectID, diskID, disk, nil); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("disk not found")
+		}
+		return nil, err
+	}
+	return disk, nil
+}
+
 func (s *testDiskService) List(ctx context.Context, in ListDisksInput) (*ListDisksOutput, error) {
-	disks, err := s.r.ListWithCursor(ctx, in.ProjectID, time.Time{}, uuid.UUID{}, in.Limit, in.TimeDesc)
+	disks, err := s.r.ListWithCursor(ctx, in.ProjectID, repo.DiskFilter{UserIdentifier: in.User}, time.Time{}, uuid.UUID{}, in.Limit, in.TimeDesc)
 	if err != nil {
 		return nil, err
 	}
 	return &ListDisksOutput{Items: disks, HasMore: false}, nil
 }
 
+func (s *testDiskService) GetUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*DiskUsageOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
+func (s *testDiskService) GetProjectUsage(ctx context.Context, projectID uuid.UUID, topDisks int) (*ProjectUsageOutput, error) {
+	return nil, errors.New("not implemented in test service")
+}
+
 func createTestDisk() *model.Disk {
 	projectID := uuid.New()
 	diskID := uuid.New()
@@ -139,7 +190,7 @@ func TestDiskService_Create(t *testing.T) {
 
 			service := newTestDiskService(mockRepo, &MockS3Deps{})
 
-			disk, err := service.Create(context.Background(), projectID, nil)
+			disk, err := service.Create(context.Background(), CreateDiskInput{ProjectID: projectID})
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -179,7 +230,7 @@ func TestDiskService_List(t *testing.T) {
 				Limit:     10,
 			},
 			setup: func(repo *MockDiskRepo) {
-				repo.On("ListWithCursor", mock.Anything, projectID, time.Time{}, uuid.UUID{}, 10, false).Return([]*model.Disk{disk1, disk2}, nil)
+				repo.On("ListWithCursor", mock.Anything, projectID, mock.Anything, time.Time{}, uuid.UUID{}, 10, false).Return([]*model.Disk{disk1, disk2}, nil)
 			},
 			expectError: false,
 			expectCount: 2,
@@ -191,7 +242,7 @@ func TestDiskService_List(t *testing.T) {
 				Limit:     10,
 			},
 			setup: func(repo *MockDiskRepo) {
-				repo.On("ListWithCursor", mock.Anything, projectID, time.Time{}, uuid.UUID{}, 10, false).Return([]*model.Disk{}, nil)
+				repo.On("ListWithCursor", mock.Anything, projectID, mock.Anything, time.Time{}, uuid.UUID{}, 10, false).Return([]*model.Disk{}, nil)
 			},
 			expectError: false,
 			expectCount: 0,
@@ -203,7 +254,7 @@ func TestDiskService_List(t *testing.T) {
 				Limit:     10,
 			},
 			setup: func(repo *MockDiskRepo) {
-				repo.On("ListWithCursor", mock.Anything, projectID, time.Time{}, uuid.UUID{}, 10, false).Return(nil, errors.New("list error"))
+				repo.On("ListWithCursor", mock.Anything, projectID, mock.Anything, time.Time{}, uuid.UUID{}, 10, false).Return(nil, errors.New("list error"))
 			},
 			expectError: true,
 			errorMsg:    "list error",
@@ -299,3 +350,209 @@ func TestDiskService_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestDiskService_Clone(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name        string
+		userID      *uuid.UUID
+		setup       func(*MockDiskRepo)
+		expectError string
+	}{
+		{
+			name:   "successful clone",
+			userID: &userID,
+			setup: func(repo *MockDiskRepo) {
+				repo.On("Clone", mock.Anything, projectID, diskID, mock.MatchedBy(func(d *model.Disk) bool {
+					return d.UserID != nil && *d.UserID == userID
+				})).Return(nil)
+			},
+		},
+		{
+			name: "source disk not found",
+			setup: func(repo *MockDiskRepo) {
+				repo.On("Clone", mock.Anything, projectID, diskID, mock.Anything).Return(gorm.ErrRecordNotFound)
+			},
+			expectError: "disk not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockDiskRepo{}
+			tt.setup(repo)
+			service := newTestDiskService(repo, nil)
+
+			disk, err := service.Clone(context.Background(), projectID, diskID, tt.userID)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, disk)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskService_CreateValidation(t *testing.T) {
+	projectID := uuid.New()
+	sessionID := uuid.New()
+
+	tests := []struct {
+		name        string
+		in          CreateDiskInput
+		setup       func(*MockDiskRepo)
+		expectError string
+	}{
+		{
+			name: "named disk linked to a session",
+			in: CreateDiskInput{
+				ProjectID:         projectID,
+				Name:              "scratch",
+				Meta:              map[string]interface{}{"team": "research"},
+				SessionID:         &sessionID,
+				DeleteWithSession: true,
+			},
+			setup: func(r *MockDiskRepo) {
+				r.On("Create", mock.Anything, mock.MatchedBy(func(d *model.Disk) bool {
+					return d.Name == "scratch" && d.Meta["team"] == "research" &&
+						d.SessionID != nil && *d.SessionID == sessionID && d.DeleteWithSession
+				})).Return(nil)
+			},
+		},
+		{
+			name:        "reserved meta key",
+			in:          CreateDiskInput{ProjectID: projectID, Meta: map[string]interface{}{model.DiskInfoKey: 1}},
+			setup:       func(r *MockDiskRepo) {},
+			expectError: "reserved key",
+		},
+		{
+			name:        "delete with session requires a session",
+			in:          CreateDiskInput{ProjectID: projectID, DeleteWithSession: true},
+			setup:       func(r *MockDiskRepo) {},
+			expectError: "requires a session",
+		},
+		{
+			name: "session of another project",
+			in:   CreateDiskInput{ProjectID: projectID, SessionID: &sessionID},
+			setup: func(r *MockDiskRepo) {
+				r.On("Create", mock.Anything, mock.Anything).Return(repo.ErrSessionNotFound)
+			},
+			expectError: "session not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockDiskRepo{}
+			tt.setup(mockRepo)
+			svc := &diskService{r: mockRepo}
+
+			disk, err := svc.Create(context.Background(), tt.in)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				assert.Nil(t, disk)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, disk)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskService_Update(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	sessionID := uuid.New()
+	name := "renamed"
+	deleteWithSession := true
+	nilSession := uuid.Nil
+
+	existing := func() *model.Disk {
+		return &model.Disk{
+			ID:                diskID,
+			ProjectID:         projectID,
+			Name:              "scratch",
+			Description:       "kept",
+			Meta:              map[string]interface{}{"team": "research", "stage": "draft"},
+			SessionID:         &sessionID,
+			DeleteWithSession: true,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		in          UpdateDiskInput
+		found       bool
+		expectError string
+		check       func(*testing.T, *model.Disk)
+	}{
+		{
+			name: "merge meta and rename",
+			in: UpdateDiskInput{
+				Name: &name,
+				Meta: map[string]interface{}{"stage": nil, "owner": "alice"},
+			},
+			found: true,
+			check: func(t *testing.T, d *model.Disk) {
+				assert.Equal(t, "renamed", d.Name)
+				assert.Equal(t, "kept", d.Description)
+				assert.Equal(t, map[string]interface{}{"team": "research", "owner": "alice"}, map[string]interface{}(d.Meta))
+				assert.Equal(t, &sessionID, d.SessionID)
+			},
+		},
+		{
+			name:  "unlink session",
+			in:    UpdateDiskInput{SessionID: &nilSession},
+			found: true,
+			check: func(t *testing.T, d *model.Disk) {
+				assert.Nil(t, d.SessionID)
+				assert.False(t, d.DeleteWithSession)
+			},
+		},
+		{
+			name:        "delete with session requires a session",
+			in:          UpdateDiskInput{SessionID: &nilSession, DeleteWithSession: &deleteWithSession},
+			found:       true,
+			expectError: "requires a session",
+		},
+		{
+			name:        "reserved meta key",
+			in:          UpdateDiskInput{Meta: map[string]interface{}{model.DiskInfoKey: "x"}},
+			expectError: "reserved key",
+		},
+		{
+			name:        "disk not found",
+			in:          UpdateDiskInput{Name: &name},
+			expectError: "disk not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockDiskRepo{}
+			if tt.found {
+				mockRepo.On("Update", mock.Anything, projectID, diskID).Return(existing(), nil)
+			} else if tt.expectError == "disk not found" {
+				mockRepo.On("Update", mock.Anything, projectID, diskID).Return(nil, gorm.ErrRecordNotFound)
+			}
+			svc := &diskService{r: mockRepo}
+
+			tt.in.ProjectID = projectID
+			tt.in.DiskID = diskID
+			disk, err := svc.Update(context.Background(), tt.in)
+			if tt.expectError != "" {
+				assert.ErrorContains(t, err, tt.expectError)
+				assert.Nil(t, disk)
+			} else if assert.NoError(t, err) {
+				tt.check(t, disk)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
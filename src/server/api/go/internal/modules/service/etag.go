@@ -0,0 +1,93 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// ErrNotModified is returned by conditional reads when the content still has an ETag the client holds.
+var ErrNotModified = errors.New("not modified")
+
+// ETag returns the entity tag of content with hash sha256. It only changes with the content:
+// meta updates, moves and copies keep it.
+func ETag(sha256 string) string {
+	return `"` + sha256 + `"`
+}
+
+// RepresentationTag returns the tag of a read of a with the query string query, for
+// responses that hold the artifact row as well as its content. Unlike ETag, it changes
+// with meta updates, moves and the read options. It starts with the content hash, so
+// writes with If-Match accept it as the content it was read with.
+func RepresentationTag(a *model.Artifact, query string) string {
+	row, _ := json.Marshal(a)
+	h := sha256.New()
+	h.Write(row)
+	h.Write([]byte{0})
+	h.Write([]byte(query))
+	return a.AssetMeta.Data().SHA256 + "." + hex.EncodeToString(h.Sum(nil))
+}
+
+// contentTags reduces the representation tags listed in header to the content tags they
+// start with. Weak or not, a representation tag is exact about the content.
+func contentTags(header string) string {
+	tags := strings.Split(header, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if sha, _, ok := strings.Cut(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`), "."); ok {
+			tag = ETag(sha)
+		}
+		tags[i] = tag
+	}
+	return strings.Join(tags, ",")
+}
+
+// Preconditions are the If-Match and If-None-Match request headers, as sent.
+type Preconditions struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+// NotModified reports whether If-None-Match matches content with hash sha256, using the
+// weak comparison reads call for.
+func (p Preconditions) NotModified(sha256 string) bool {
+	return p.IfNoneMatch != "" && matchETag(p.IfNoneMatch, sha256, true)
+}
+
+// checkWrite returns ErrArtifactModified unless If-Match, if any, matches the current
+// content, by its ETag or the RepresentationTag of a read. sha256 is empty when the
+// artifact does not exist, which only "*" does not allow.
+func (p Preconditions) checkWrite(sha256 string) error {
+	if p.IfMatch == "" {
+		return nil
+	}
+	if sha256 == "" || !matchETag(contentTags(p.IfMatch), sha256, false) {
+		return ErrArtifactModified
+	}
+	return nil
+}
+
+// matchETag reports whether the header, "*" or a list of entity tags, matches sha256.
+// Weak tags only match in a weak comparison.
+func matchETag(header string, sha256 string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if strings.EqualFold(strings.Trim(tag, `"`), sha256) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		weak   bool
+		want   bool
+	}{
+		{"exact", `"abc"`, false, true},
+		{"case insensitive", `"ABC"`, false, true},
+		{"wildcard", "*", false, true},
+		{"list", `"x", "abc"`, false, true},
+		{"other tag", `"x"`, false, false},
+		{"weak tag in strong comparison", `W/"abc"`, false, false},
+		{"weak tag in weak comparison", `W/"abc"`, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchETag(tt.header, "abc", tt.weak))
+		})
+	}
+}
+
+func TestPreconditions(t *testing.T) {
+	assert.False(t, Preconditions{}.NotModified("abc"))
+	assert.True(t, Preconditions{IfNoneMatch: ETag("abc")}.NotModified("abc"))
+	assert.False(t, Preconditions{IfNoneMatch: ETag("abc")}.NotModified("def"))
+
+	assert.NoError(t, Preconditions{}.checkWrite(""))
+	assert.NoError(t, Preconditions{IfMatch: ETag("abc")}.checkWrite("abc"))
+	assert.ErrorIs(t, Preconditions{IfMatch: ETag("abc")}.checkWrite("def"), ErrArtifactModified)
+
+	// The tag of a read stands for the content it was read with, even weak
+	read := RepresentationTag(&model.Artifact{AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: "abc"})}, "with_public_url=true")
+	assert.NoError(t, Preconditions{IfMatch: ETag(read)}.checkWrite("abc"))
+	assert.NoError(t, Preconditions{IfMatch: `"x", W/` + ETag(read)}.checkWrite("abc"))
+	assert.ErrorIs(t, Preconditions{IfMatch: ETag(read)}.checkWrite("def"), ErrArtifactModified)
+	assert.ErrorIs(t, Preconditions{IfMatch: `W/"abc"`}.checkWrite("abc"), ErrArtifactModified)
+	assert.ErrorIs(t, Preconditions{IfMatch: "*"}.checkWrite(""), ErrArtifactModified)
+}
+
+func TestArtifactService_ConditionalWrites(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	diskID := uuid.New()
+	existing := &model.Artifact{
+		ID:        uuid.New(),
+		DiskID:    diskID,
+		Path:      "/",
+		Filename:  "a.txt",
+		AssetMeta: datatypes.NewJSONType(model.Asset{SHA256: "abc"}),
+	}
+
+	t.Run("save checks the current content under the lock", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(existing, nil)
+		r.On("AddVersionIfMatch", ctx, projectID, mock.Anything, "abc", mock.Anything).Return(nil)
+		svc := &artifactService{r: r}
+
+		err := svc.save(ctx, projectID, &model.Artifact{DiskID: diskID, Path: "/", Filename: "a.txt"}, Preconditions{IfMatch: ETag("abc")})
+		assert.NoError(t, err)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("save with a stale tag", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(existing, nil)
+		svc := &artifactService{r: r}
+
+		err := svc.save(ctx, projectID, &model.Artifact{DiskID: diskID, Path: "/", Filename: "a.txt"}, Preconditions{IfMatch: ETag("old")})
+		assert.ErrorIs(t, err, ErrArtifactModified)
+		r.AssertNotCalled(t, "AddVersionIfMatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("save changed before the lock", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(existing, nil)
+		r.On("AddVersionIfMatch", ctx, projectID, mock.Anything, "abc", mock.Anything).Return(ErrArtifactModified)
+		svc := &artifactService{r: r}
+
+		err := svc.save(ctx, projectID, &model.Artifact{DiskID: diskID, Path: "/", Filename: "a.txt"}, Preconditions{IfMatch: "*"})
+		assert.ErrorIs(t, err, ErrArtifactModified)
+	})
+
+	t.Run("save of a new artifact with If-Match", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(nil, gorm.ErrRecordNotFound)
+		svc := &artifactService{r: r}
+
+		err := svc.save(ctx, projectID, &model.Artifact{DiskID: diskID, Path: "/", Filename: "a.txt"}, Preconditions{IfMatch: "*"})
+		assert.ErrorIs(t, err, ErrArtifactModified)
+		r.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("save racing another create adds a version", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(nil, gorm.ErrRecordNotFound).Once()
+		r.On("Create", ctx, projectID, mock.Anything).Return(repo.ErrArtifactExists)
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(existing, nil).Once()
+		r.On("AddVersion", ctx, projectID, mock.MatchedBy(func(a *model.Artifact) bool { return a.ID == existing.ID }), mock.Anything).Return(nil)
+		svc := &artifactService{r: r}
+
+		err := svc.save(ctx, projectID, &model.Artifact{DiskID: diskID, Path: "/", Filename: "a.txt"}, Preconditions{})
+		assert.NoError(t, err)
+		r.AssertExpectations(t)
+	})
+
+	t.Run("delete passes the checked content to the repo", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(existing, nil)
+		r.On("DeleteByPath", ctx, projectID, diskID, "/", "a.txt", "abc").Return(nil)
+		svc := &artifactService{r: r}
+
+		assert.NoError(t, svc.DeleteByPath(ctx, projectID, diskID, "/", "a.txt", Preconditions{IfMatch: ETag("abc")}))
+		r.AssertExpectations(t)
+	})
+
+	t.Run("delete with a stale tag", func(t *testing.T) {
+		r := &MockArtifactRepo{}
+		r.On("GetByPath", ctx, diskID, "/", "a.txt").Return(existing, nil)
+		svc := &artifactService{r: r}
+
+		err := svc.DeleteByPath(ctx, projectID, diskID, "/", "a.txt", Preconditions{IfMatch: ETag("old")})
+		assert.ErrorIs(t, err, ErrArtifactModified)
+		r.AssertNotCalled(t, "DeleteByPath", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
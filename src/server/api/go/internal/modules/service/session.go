@@ -2,10 +2,15 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"mime"
 	"mime/multipart"
+	"net/url"
+	"path"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/bytedance/sonic"
@@ -17,6 +22,7 @@ import (
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/repo"
 	"github.com/memodb-io/Acontext/internal/pkg/editor"
+	"github.com/memodb-io/Acontext/internal/pkg/imagefetch"
 	"github.com/memodb-io/Acontext/internal/pkg/paging"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
@@ -31,6 +37,8 @@ type SessionService interface {
 	GetByID(ctx context.Context, ss *model.Session) (*model.Session, error)
 	List(ctx context.Context, in ListSessionsInput) (*ListSessionsOutput, error)
 	StoreMessage(ctx context.Context, in StoreMessageInput) (*model.Message, error)
+	SaveInProgressMessage(ctx context.Context, in StoreMessageInput, startedAt time.Time) error
+	DeleteInProgressMessage(ctx context.Context, sessionID uuid.UUID, messageID uuid.UUID) error
 	GetMessages(ctx context.Context, in GetMessagesInput) (*GetMessagesOutput, error)
 	GetAllMessages(ctx context.Context, sessionID uuid.UUID) ([]model.Message, error)
 	GetSessionObservingStatus(ctx context.Context, sessionID string) (*model.MessageObservingStatus, error)
@@ -46,6 +54,9 @@ type sessionService struct {
 	publisher          *mq.Publisher
 	cfg                *config.Config
 	redis              *redis.Client
+	imageFetcher       *imagefetch.Fetcher
+	// usage is nil when quotas are not enforced
+	usage repo.UsageRepo
 }
 
 const (
@@ -53,17 +64,23 @@ const (
 	redisKeyPrefixParts = "message:parts:"
 	// Default TTL for message parts cache (1 hour)
 	defaultPartsCacheTTL = time.Hour
+	// Redis key prefix for partially streamed messages, one hash per session
+	redisKeyPrefixInProgress = "message:in_progress:"
+	// TTL for partially streamed messages, refreshed on every snapshot
+	defaultInProgressTTL = 10 * time.Minute
 )
 
-func NewSessionService(sessionRepo repo.SessionRepo, assetReferenceRepo repo.AssetReferenceRepo, log *zap.Logger, s3 *blob.S3Deps, publisher *mq.Publisher, cfg *config.Config, redis *redis.Client) SessionService {
+func NewSessionService(sessionRepo repo.SessionRepo, assetReferenceRepo repo.AssetReferenceRepo, usage repo.UsageRepo, log *zap.Logger, s3 *blob.S3Deps, publisher *mq.Publisher, cfg *config.Config, redis *redis.Client, imageFetcher *imagefetch.Fetcher) SessionService {
 	return &sessionService{
 		sessionRepo:        sessionRepo,
 		assetReferenceRepo: assetReferenceRepo,
+		usage:              usage,
 		log:                log,
 		s3:                 s3,
 		publisher:          publisher,
 		cfg:                cfg,
 		redis:              redis,
+		imageFetcher:       imageFetcher,
 	}
 }
 
@@ -144,6 +161,7 @@ func (s *sessionService) List(ctx context.Context, in ListSessionsInput) (*ListS
 type StoreMessageInput struct {
 	ProjectID   uuid.UUID
 	SessionID   uuid.UUID
+	MessageID   uuid.UUID // [Optional] pre-allocated message ID, e.g. for streamed messages
 	Role        string
 	Parts       []PartIn
 	Format      model.MessageFormat    // Message format (acontext, openai, anthropic, gemini)
@@ -158,10 +176,10 @@ type StoreMQPublishJSON struct {
 }
 
 type PartIn struct {
-	Type      string                 `json:"type" validate:"required,oneof=text image audio video file tool-call tool-result data thinking"` // "text" | "image" | ...
-	Text      string                 `json:"text,omitempty"`                                                                        // Text sharding
-	FileField string                 `json:"file_field,omitempty"`                                                                  // File field name in the form
-	Meta      map[string]interface{} `json:"meta,omitempty"`                                                                        // [Optional] metadata
+	Type      string                 `json:"type" validate:"required,oneof=text image audio video file tool-call tool-result data thinking redacted-thinking search-result server-tool-call server-tool-result"` // "text" | "image" | ...
+	Text      string                 `json:"text,omitempty"`                                                                                                                                                     // Text sharding
+	FileField string                 `json:"file_field,omitempty"`                                                                                                                                               // File field name in the form
+	Meta      map[string]interface{} `json:"meta,omitempty"`                                                                                                                                                     // [Optional] metadata
 }
 
 func (p *PartIn) Validate() error {
@@ -199,6 +217,24 @@ func (p *PartIn) Validate() error {
 		if _, ok := p.Meta[model.MetaKeyToolCallID]; !ok {
 			return errors.New("tool-result part requires 'tool_call_id' in meta")
 		}
+	case model.PartTypeRedactedThinking:
+		if _, ok := p.Meta[model.MetaKeyData].(string); !ok {
+			return errors.New("redacted-thinking part requires 'data' in meta")
+		}
+	case model.PartTypeServerToolCall:
+		if p.Meta == nil {
+			return errors.New("server-tool-call part requires meta field")
+		}
+		if _, ok := p.Meta[model.MetaKeyName]; !ok {
+			return errors.New("server-tool-call part requires 'name' in meta")
+		}
+	case model.PartTypeServerToolResult:
+		if p.Meta == nil {
+			return errors.New("server-tool-result part requires meta field")
+		}
+		if _, ok := p.Meta[model.MetaKeyToolCallID]; !ok {
+			return errors.New("server-tool-result part requires 'tool_call_id' in meta")
+		}
 	case model.PartTypeData:
 		if p.Meta == nil {
 			return errors.New("data part requires meta field")
@@ -278,6 +314,31 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 		return nil, fmt.Errorf("session does not belong to project")
 	}
 
+	// Reject uploads over the project quota before anything is stored
+	if s.usage != nil && s.cfg != nil {
+		var size int64
+		for _, p := range in.Parts {
+			if fh := in.Files[p.FileField]; p.FileField != "" && fh != nil {
+				size += fh.Size
+			}
+		}
+		if err := checkQuota(ctx, s.usage, s.cfg.Quota, in.ProjectID, nil, size, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// Prefetched images are referenced before the message is inserted; release them if it never is
+	var prefetched []model.Asset
+	stored := false
+	defer func() {
+		if stored || len(prefetched) == 0 {
+			return
+		}
+		if err := s.assetReferenceRepo.BatchDecrementAssetRefs(context.WithoutCancel(ctx), in.ProjectID, prefetched); err != nil {
+			s.log.Warn("failed to release prefetched images", zap.Error(err))
+		}
+	}()
+
 	parts := make([]model.Part, 0, len(in.Parts))
 
 	for idx := range in.Parts {
@@ -316,6 +377,15 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 			part.Filename = fh.Filename
 		}
 
+		// Keep a copy of URL images so later conversions use the asset instead of re-downloading
+		if part.Asset == nil && part.Type == model.PartTypeImage && s.prefetchImagesOnStore() {
+			if asset, filename := s.prefetchImage(ctx, in.ProjectID, part.GetMetaString(model.MetaKeyURL)); asset != nil {
+				part.Asset = asset
+				part.Filename = filename
+				prefetched = append(prefetched, *asset)
+			}
+		}
+
 		if partIn.Text != "" {
 			part.Text = partIn.Text
 		}
@@ -348,6 +418,7 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 	}
 
 	msg := model.Message{
+		ID:             in.MessageID,
 		SessionID:      in.SessionID,
 		Role:           in.Role,
 		Meta:           datatypes.NewJSONType(messageMeta), // Store message-level metadata
@@ -358,6 +429,7 @@ func (s *sessionService) StoreMessage(ctx context.Context, in StoreMessageInput)
 	if err := s.sessionRepo.CreateMessageWithAssets(ctx, &msg); err != nil {
 		return nil, err
 	}
+	stored = true
 
 	// Check if task tracking is disabled for this session
 	disableTaskTracking, err := s.sessionRepo.GetDisableTaskTracking(ctx, in.SessionID)
@@ -387,6 +459,10 @@ type GetMessagesInput struct {
 	TimeDesc                      bool                    `json:"time_desc"`
 	EditStrategies                []editor.StrategyConfig `json:"edit_strategies,omitempty"`
 	PinEditingStrategiesAtMessage string                  `json:"pin_editing_strategies_at_message,omitempty"`
+	IncludeInProgress             bool                    `json:"include_in_progress,omitempty"`
+	// Encrypted assets have no public URL: give the image, audio and video ones a data URL in
+	// PublicURLs instead, for conversions that embed them
+	InlineEncryptedAssets bool `json:"inline_encrypted_assets,omitempty"`
 }
 
 type PublicURL struct {
@@ -394,12 +470,18 @@ type PublicURL struct {
 	ExpireAt time.Time `json:"expire_at"`
 }
 
+// PublicURLEncrypted is why an asset has no public URL when it is encrypted client-side
+const PublicURLEncrypted = "encrypted"
+
 type GetMessagesOutput struct {
 	Items           []model.Message      `json:"items"`
 	NextCursor      string               `json:"next_cursor,omitempty"`
 	HasMore         bool                 `json:"has_more"`
 	PublicURLs      map[string]PublicURL `json:"public_urls,omitempty"` // file_name -> url
 	EditAtMessageID string               `json:"edit_at_message_id,omitempty"`
+
+	// Assets left out of PublicURLs by SHA-256, with the reason
+	PublicURLsUnavailable map[string]string `json:"public_urls_unavailable,omitempty"`
 }
 
 func (s *sessionService) GetMessages(ctx context.Context, in GetMessagesInput) (*GetMessagesOutput, error) {
@@ -475,6 +557,15 @@ func (s *sessionService) GetMessages(ctx context.Context, in GetMessagesInput) (
 		out.EditAtMessageID = out.Items[len(out.Items)-1].ID.String()
 	}
 
+	// Append partially streamed messages when this page holds the newest messages
+	if in.IncludeInProgress && (!out.HasMore || (in.TimeDesc && in.Cursor == "")) {
+		inProgress, err := s.listInProgressMessages(ctx, in.SessionID)
+		if err != nil {
+			s.log.Warn("failed to list in-progress messages", zap.String("session_id", in.SessionID.String()), zap.Error(err))
+		}
+		out.Items = append(out.Items, inProgress...)
+	}
+
 	// Generate presigned URLs for assets if requested
 	if in.WithAssetPublicURL && s.s3 != nil {
 		out.PublicURLs = make(map[string]PublicURL)
@@ -483,7 +574,20 @@ func (s *sessionService) GetMessages(ctx context.Context, in GetMessagesInput) (
 				if p.Asset == nil {
 					continue
 				}
+				if _, ok := out.PublicURLs[p.Asset.SHA256]; ok {
+					continue
+				}
 				url, err := s.s3.PresignGet(ctx, p.Asset.S3Key, in.AssetExpire)
+				if errors.Is(err, blob.ErrEncryptedObject) && in.InlineEncryptedAssets && embedsAsset(p) {
+					url, err = s.assetDataURL(ctx, p.Asset)
+				}
+				if errors.Is(err, blob.ErrEncryptedObject) {
+					if out.PublicURLsUnavailable == nil {
+						out.PublicURLsUnavailable = make(map[string]string)
+					}
+					out.PublicURLsUnavailable[p.Asset.SHA256] = PublicURLEncrypted
+					continue
+				}
 				if err != nil {
 					return nil, fmt.Errorf("get presigned url for asset %s: %w", p.Asset.S3Key, err)
 				}
@@ -498,6 +602,187 @@ func (s *sessionService) GetMessages(ctx context.Context, in GetMessagesInput) (
 	return out, nil
 }
 
+// embedsAsset reports whether conversions embed the asset of a part rather than link it.
+func embedsAsset(p model.Part) bool {
+	switch p.Type {
+	case model.PartTypeImage, model.PartTypeAudio, model.PartTypeVideo:
+		return true
+	}
+	return false
+}
+
+// assetDataURL returns the decrypted content of an asset as a data URL.
+func (s *sessionService) assetDataURL(ctx context.Context, asset *model.Asset) (string, error) {
+	data, err := s.s3.DownloadFile(ctx, asset.S3Key)
+	if err != nil {
+		return "", fmt.Errorf("download asset %s: %w", asset.S3Key, err)
+	}
+	return "data:" + asset.MIME + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (s *sessionService) prefetchImagesOnStore() bool {
+	return s.imageFetcher != nil && s.s3 != nil && s.cfg != nil && s.cfg.ImageFetch.PrefetchOnStore
+}
+
+// prefetchImage downloads an image URL according to the fetch policy and
+// stores it as a project asset. Failures are logged and leave the part
+// URL-only, so a bad image never fails the message.
+func (s *sessionService) prefetchImage(ctx context.Context, projectID uuid.UUID, imageURL string) (*model.Asset, string) {
+	if !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") {
+		return nil, ""
+	}
+
+	img, err := s.imageFetcher.Fetch(ctx, imageURL)
+	if err != nil {
+		s.log.Warn("failed to prefetch image", zap.String("url", imageURL), zap.Error(err))
+		return nil, ""
+	}
+
+	filename := imageFilename(imageURL, img.MediaType)
+	asset, err := s.s3.UploadBytes(ctx, "assets/"+projectID.String(), filename, img.Data)
+	if err != nil {
+		s.log.Warn("failed to upload prefetched image", zap.String("url", imageURL), zap.Error(err))
+		return nil, ""
+	}
+
+	if err := s.assetReferenceRepo.IncrementAssetRef(ctx, projectID, *asset); err != nil {
+		s.log.Warn("failed to reference prefetched image", zap.String("url", imageURL), zap.Error(err))
+		return nil, ""
+	}
+
+	return asset, filename
+}
+
+// imageFilename derives a filename for a fetched image from its URL path,
+// falling back to the media type for the extension.
+func imageFilename(imageURL string, mediaType string) string {
+	name := "image"
+	if u, err := url.Parse(imageURL); err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" && base != "" {
+			name = base
+		}
+	}
+	if path.Ext(name) != "" {
+		return name
+	}
+	if ext, ok := imageExtensions[mediaType]; ok {
+		return name + ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return name + exts[0]
+	}
+	return name
+}
+
+// imageExtensions maps common image media types to their usual extension;
+// mime.ExtensionsByType returns them in alphabetical order (e.g. ".jfif" for JPEG).
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// SaveInProgressMessage stores a snapshot of a message that is still being
+// streamed, so readers can see it before it is stored. Snapshots live in Redis
+// only; without Redis this is a no-op. Parts referencing uploaded files are not supported.
+func (s *sessionService) SaveInProgressMessage(ctx context.Context, in StoreMessageInput, startedAt time.Time) error {
+	if s.redis == nil {
+		return nil
+	}
+
+	parts := make([]model.Part, 0, len(in.Parts))
+	for idx, partIn := range in.Parts {
+		if partIn.FileField != "" {
+			return fmt.Errorf("parts[%d]: file uploads are not supported for in-progress messages", idx)
+		}
+		parts = append(parts, model.Part{
+			Type: partIn.Type,
+			Text: partIn.Text,
+			Meta: partIn.Meta,
+		})
+	}
+
+	messageMeta := in.MessageMeta
+	if messageMeta == nil {
+		messageMeta = make(map[string]interface{})
+	}
+
+	msg := model.Message{
+		ID:                       in.MessageID,
+		SessionID:                in.SessionID,
+		Role:                     in.Role,
+		Meta:                     datatypes.NewJSONType(messageMeta),
+		Parts:                    parts,
+		SessionTaskProcessStatus: "pending",
+		Status:                   model.MessageStatusInProgress,
+		CreatedAt:                startedAt,
+		UpdatedAt:                time.Now(),
+	}
+
+	jsonData, err := sonic.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal in-progress message: %w", err)
+	}
+
+	redisKey := redisKeyPrefixInProgress + in.SessionID.String()
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, redisKey, in.MessageID.String(), jsonData)
+	pipe.Expire(ctx, redisKey, defaultInProgressTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("save in-progress message %s: %w", in.MessageID, err)
+	}
+
+	return nil
+}
+
+// DeleteInProgressMessage removes the snapshot of a streamed message once it
+// has been stored or the stream was aborted.
+func (s *sessionService) DeleteInProgressMessage(ctx context.Context, sessionID uuid.UUID, messageID uuid.UUID) error {
+	if s.redis == nil {
+		return nil
+	}
+
+	redisKey := redisKeyPrefixInProgress + sessionID.String()
+	if err := s.redis.HDel(ctx, redisKey, messageID.String()).Err(); err != nil {
+		return fmt.Errorf("delete in-progress message %s: %w", messageID, err)
+	}
+
+	return nil
+}
+
+// listInProgressMessages returns the snapshots of messages still being
+// streamed into the session, oldest first.
+func (s *sessionService) listInProgressMessages(ctx context.Context, sessionID uuid.UUID) ([]model.Message, error) {
+	if s.redis == nil {
+		return nil, nil
+	}
+
+	values, err := s.redis.HGetAll(ctx, redisKeyPrefixInProgress+sessionID.String()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get in-progress messages: %w", err)
+	}
+
+	msgs := make([]model.Message, 0, len(values))
+	for _, val := range values {
+		var msg model.Message
+		if err := sonic.Unmarshal([]byte(val), &msg); err != nil {
+			return nil, fmt.Errorf("unmarshal in-progress message: %w", err)
+		}
+		msg.Status = model.MessageStatusInProgress
+		msgs = append(msgs, msg)
+	}
+
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].CreatedAt.Equal(msgs[j].CreatedAt) {
+			return msgs[i].ID.String() < msgs[j].ID.String()
+		}
+		return msgs[i].CreatedAt.Before(msgs[j].CreatedAt)
+	})
+
+	return msgs, nil
+}
+
 // cachePartsInRedis stores message parts in Redis with a fixed TTL
 func (s *sessionService) cachePartsInRedis(ctx context.Context, sha256 string, parts []model.Part) error {
 	if s.redis == nil {
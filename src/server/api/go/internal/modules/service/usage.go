@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"gorm.io/gorm"
+)
+
+// ErrQuotaExceeded is returned when storing content would exceed a project, user or disk quota.
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Usage is the storage used by a scope and its quota; a zero maximum is unlimited.
+// PendingBytes, reserved by direct uploads not completed yet, count towards MaxBytes.
+type Usage struct {
+	Bytes        int64 `json:"bytes"`
+	Files        int64 `json:"files"`
+	PendingBytes int64 `json:"pending_bytes"`
+	MaxBytes     int64 `json:"max_bytes"`
+	MaxFiles     int64 `json:"max_files"`
+}
+
+type DiskUsageOutput struct {
+	DiskID uuid.UUID `json:"disk_id"`
+	Disk   Usage     `json:"disk"`
+	// User is the usage across the disks of the disk's user, nil for disks without a user
+	User *Usage `json:"user,omitempty"`
+}
+
+type ProjectUsageOutput struct {
+	Project   Usage                   `json:"project"`
+	DiskCount int64                   `json:"disk_count"`
+	TopDisks  []repo.DiskStorageUsage `json:"top_disks"`
+}
+
+// checkQuota returns ErrQuotaExceeded when adding bytes and files would exceed the project quota
+// or, with a diskID, the quotas of the disk and its user. Added bytes are counted even when the
+// content turns out to be stored already, so the check errs on the side of rejecting.
+func checkQuota(ctx context.Context, usage repo.UsageRepo, q config.QuotaCfg, projectID uuid.UUID, diskID *uuid.UUID, addBytes int64, addFiles int64) error {
+	if q.ProjectMaxBytes > 0 || q.ProjectMaxFiles > 0 {
+		pu, err := usage.ProjectUsage(ctx, projectID)
+		if err != nil {
+			return fmt.Errorf("get project usage: %w", err)
+		}
+		if err := exceedsQuota("project", pu, q.ProjectMaxBytes, q.ProjectMaxFiles, addBytes, addFiles); err != nil {
+			return err
+		}
+	}
+
+	if diskID == nil || (q.DiskMaxBytes <= 0 && q.DiskMaxFiles <= 0 && q.UserMaxBytes <= 0 && q.UserMaxFiles <= 0) {
+		return nil
+	}
+	du, uu, err := usage.DiskUsage(ctx, projectID, *diskID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Left to the operation itself to report
+			return nil
+		}
+		return fmt.Errorf("get disk usage: %w", err)
+	}
+	if err := exceedsQuota("disk", du, q.DiskMaxBytes, q.DiskMaxFiles, addBytes, addFiles); err != nil {
+		return err
+	}
+	if uu != nil {
+		return exceedsQuota("user", uu, q.UserMaxBytes, q.UserMaxFiles, addBytes, addFiles)
+	}
+	return nil
+}
+
+// diskQuotaCheck returns the check of writes to a disk run by the repository, nil without quotas.
+func diskQuotaCheck(ctx context.Context, usage repo.UsageRepo, q config.QuotaCfg, projectID uuid.UUID, diskID uuid.UUID) repo.QuotaCheck {
+	if usage == nil || q == (config.QuotaCfg{}) {
+		return nil
+	}
+	return func(addBytes int64, addFiles int64) error {
+		return checkQuota(ctx, usage, q, projectID, &diskID, addBytes, addFiles)
+	}
+}
+
+// exceedsQuota counts pending uploads as used, so concurrent uploads can't each pass on their own.
+func exceedsQuota(scope string, u *repo.StorageUsage, maxBytes int64, maxFiles int64, addBytes int64, addFiles int64) error {
+	if used := u.Bytes + u.PendingBytes + addBytes; maxBytes > 0 && addBytes > 0 && used > maxBytes {
+		return fmt.Errorf("%w: %s would use %d of %d bytes", ErrQuotaExceeded, scope, used, maxBytes)
+	}
+	if maxFiles > 0 && addFiles > 0 && u.Files+addFiles > maxFiles {
+		return fmt.Errorf("%w: %s would hold %d of %d files", ErrQuotaExceeded, scope, u.Files+addFiles, maxFiles)
+	}
+	return nil
+}
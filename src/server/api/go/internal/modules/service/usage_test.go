@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockUsageRepo is a mock implementation of UsageRepo
+type MockUsageRepo struct {
+	mock.Mock
+}
+
+func (m *MockUsageRepo) DiskUsage(ctx context.Context, projectID uuid.UUID, diskID uuid.UUID) (*repo.StorageUsage, *repo.StorageUsage, error) {
+	args := m.Called(ctx, projectID, diskID)
+	var disk, user *repo.StorageUsage
+	if args.Get(0) != nil {
+		disk = args.Get(0).(*repo.StorageUsage)
+	}
+	if args.Get(1) != nil {
+		user = args.Get(1).(*repo.StorageUsage)
+	}
+	return disk, user, args.Error(2)
+}
+
+func (m *MockUsageRepo) UserUsage(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) (*repo.StorageUsage, error) {
+	args := m.Called(ctx, projectID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.StorageUsage), args.Error(1)
+}
+
+func (m *MockUsageRepo) ProjectUsage(ctx context.Context, projectID uuid.UUID) (*repo.StorageUsage, error) {
+	args := m.Called(ctx, projectID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*repo.StorageUsage), args.Error(1)
+}
+
+func (m *MockUsageRepo) TopDisks(ctx context.Context, projectID uuid.UUID, limit int) ([]repo.DiskStorageUsage, int64, error) {
+	args := m.Called(ctx, projectID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]repo.DiskStorageUsage), args.Get(1).(int64), args.Error(2)
+}
+
+func TestCheckQuota(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+
+	tests := []struct {
+		name     string
+		quota    config.QuotaCfg
+		diskID   *uuid.UUID
+		addBytes int64
+		addFiles int64
+		setup    func(*MockUsageRepo)
+		wantErr  string
+	}{
+		{
+			name:     "unlimited skips usage queries",
+			diskID:   &diskID,
+			addBytes: 1 << 40,
+			addFiles: 1,
+			setup:    func(r *MockUsageRepo) {},
+		},
+		{
+			name:     "project bytes within quota",
+			quota:    config.QuotaCfg{ProjectMaxBytes: 100},
+			addBytes: 40,
+			setup: func(r *MockUsageRepo) {
+				r.On("ProjectUsage", mock.Anything, projectID).Return(&repo.StorageUsage{Bytes: 60}, nil)
+			},
+		},
+		{
+			name:     "project bytes exceeded",
+			quota:    config.QuotaCfg{ProjectMaxBytes: 100},
+			addBytes: 41,
+			setup: func(r *MockUsageRepo) {
+				r.On("ProjectUsage", mock.Anything, projectID).Return(&repo.StorageUsage{Bytes: 60}, nil)
+			},
+			wantErr: "project would use 101 of 100 bytes",
+		},
+		{
+			name:     "pending uploads count as used",
+			quota:    config.QuotaCfg{ProjectMaxBytes: 100},
+			addBytes: 30,
+			setup: func(r *MockUsageRepo) {
+				r.On("ProjectUsage", mock.Anything, projectID).Return(&repo.StorageUsage{Bytes: 60, PendingBytes: 20}, nil)
+			},
+			wantErr: "project would use 110 of 100 bytes",
+		},
+		{
+			name:     "disk files exceeded",
+			quota:    config.QuotaCfg{DiskMaxFiles: 2},
+			diskID:   &diskID,
+			addBytes: 1,
+			addFiles: 1,
+			setup: func(r *MockUsageRepo) {
+				r.On("DiskUsage", mock.Anything, projectID, diskID).Return(&repo.StorageUsage{Files: 2}, nil, nil)
+			},
+			wantErr: "disk would hold 3 of 2 files",
+		},
+		{
+			name:     "new version adds no file",
+			quota:    config.QuotaCfg{DiskMaxFiles: 2},
+			diskID:   &diskID,
+			addBytes: 1,
+			setup: func(r *MockUsageRepo) {
+				r.On("DiskUsage", mock.Anything, projectID, diskID).Return(&repo.StorageUsage{Files: 2}, nil, nil)
+			},
+		},
+		{
+			name:     "user bytes exceeded",
+			quota:    config.QuotaCfg{UserMaxBytes: 100},
+			diskID:   &diskID,
+			addBytes: 10,
+			addFiles: 1,
+			setup: func(r *MockUsageRepo) {
+				r.On("DiskUsage", mock.Anything, projectID, diskID).
+					Return(&repo.StorageUsage{Bytes: 10}, &repo.StorageUsage{Bytes: 95}, nil)
+			},
+			wantErr: "user would use 105 of 100 bytes",
+		},
+		{
+			name:     "disk quota ignored without disk",
+			quota:    config.QuotaCfg{DiskMaxBytes: 1},
+			addBytes: 10,
+			setup:    func(r *MockUsageRepo) {},
+		},
+		{
+			name:     "missing disk is left to the operation",
+			quota:    config.QuotaCfg{DiskMaxBytes: 1},
+			diskID:   &diskID,
+			addBytes: 10,
+			setup: func(r *MockUsageRepo) {
+				r.On("DiskUsage", mock.Anything, projectID, diskID).Return(nil, nil, gorm.ErrRecordNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(MockUsageRepo)
+			tt.setup(r)
+
+			err := checkQuota(context.Background(), r, tt.quota, projectID, tt.diskID, tt.addBytes, tt.addFiles)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrQuotaExceeded)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			r.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskService_CloneQuotaCheck(t *testing.T) {
+	projectID := uuid.New()
+	userID := uuid.New()
+
+	tests := []struct {
+		name     string
+		quota    config.QuotaCfg
+		addBytes int64
+		addFiles int64
+		setup    func(*MockUsageRepo)
+		wantErr  string
+	}{
+		{
+			name:     "the clone starts empty",
+			quota:    config.QuotaCfg{DiskMaxBytes: 100},
+			addBytes: 100,
+			addFiles: 3,
+			setup:    func(r *MockUsageRepo) {},
+		},
+		{
+			name:     "source larger than the disk quota",
+			quota:    config.QuotaCfg{DiskMaxFiles: 2},
+			addBytes: 10,
+			addFiles: 3,
+			setup:    func(r *MockUsageRepo) {},
+			wantErr:  "disk would hold 3 of 2 files",
+		},
+		{
+			name:     "user quota",
+			quota:    config.QuotaCfg{UserMaxBytes: 100},
+			addBytes: 50,
+			addFiles: 1,
+			setup: func(r *MockUsageRepo) {
+				r.On("UserUsage", mock.Anything, projectID, userID).Return(&repo.StorageUsage{Bytes: 60}, nil)
+			},
+			wantErr: "user would use 110 of 100 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(MockUsageRepo)
+			tt.setup(r)
+			svc := &diskService{usage: r, cfg: &config.Config{Quota: tt.quota}}
+
+			check := svc.cloneQuotaCheck(context.Background(), projectID, &model.Disk{UserID: &userID})
+			err := check(tt.addBytes, tt.addFiles)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrQuotaExceeded)
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			r.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDiskService_GetUsage(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	cfg := &config.Config{Quota: config.QuotaCfg{DiskMaxBytes: 1000, UserMaxFiles: 50}}
+
+	t.Run("disk with user", func(t *testing.T) {
+		r := new(MockUsageRepo)
+		r.On("DiskUsage", mock.Anything, projectID, diskID).
+			Return(&repo.StorageUsage{Bytes: 300, Files: 4}, &repo.StorageUsage{Bytes: 500, Files: 9}, nil)
+		svc := &diskService{usage: r, cfg: cfg}
+
+		out, err := svc.GetUsage(context.Background(), projectID, diskID)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, Usage{Bytes: 300, Files: 4, MaxBytes: 1000}, out.Disk)
+		assert.Equal(t, &Usage{Bytes: 500, Files: 9, MaxFiles: 50}, out.User)
+	})
+
+	t.Run("disk without user", func(t *testing.T) {
+		r := new(MockUsageRepo)
+		r.On("DiskUsage", mock.Anything, projectID, diskID).Return(&repo.StorageUsage{}, nil, nil)
+		svc := &diskService{usage: r}
+
+		out, err := svc.GetUsage(context.Background(), projectID, diskID)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Nil(t, out.User)
+	})
+
+	t.Run("disk not found", func(t *testing.T) {
+		r := new(MockUsageRepo)
+		r.On("DiskUsage", mock.Anything, projectID, diskID).Return(nil, nil, gorm.ErrRecordNotFound)
+		svc := &diskService{usage: r}
+
+		_, err := svc.GetUsage(context.Background(), projectID, diskID)
+		assert.EqualError(t, err, "disk not found")
+	})
+}
+
+func TestDiskService_GetProjectUsage(t *testing.T) {
+	projectID := uuid.New()
+	top := []repo.DiskStorageUsage{{DiskID: uuid.New(), StorageUsage: repo.StorageUsage{Bytes: 70, Files: 2}}}
+
+	r := new(MockUsageRepo)
+	r.On("ProjectUsage", mock.Anything, projectID).Return(&repo.StorageUsage{Bytes: 120, Files: 3}, nil)
+	r.On("TopDisks", mock.Anything, projectID, 5).Return(top, int64(2), nil)
+	svc := &diskService{usage: r, cfg: &config.Config{Quota: config.QuotaCfg{ProjectMaxBytes: 1 << 20}}}
+
+	out, err := svc.GetProjectUsage(context.Background(), projectID, 5)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Usage{Bytes: 120, Files: 3, MaxBytes: 1 << 20}, out.Project)
+	assert.Equal(t, int64(2), out.DiskCount)
+	assert.Equal(t, top, out.TopDisks)
+
+	t.Run("repo error", func(t *testing.T) {
+		r := new(MockUsageRepo)
+		r.On("ProjectUsage", mock.Anything, projectID).Return(nil, errors.New("db down"))
+		svc := &diskService{usage: r}
+
+		_, err := svc.GetProjectUsage(context.Background(), projectID, 5)
+		assert.Error(t, err)
+	})
+}
+
+func TestArtifactService_Create_QuotaExceeded(t *testing.T) {
+	projectID := uuid.New()
+	diskID := uuid.New()
+	cfg := &config.Config{Quota: config.QuotaCfg{DiskMaxBytes: 100}}
+
+	r := new(MockArtifactRepo)
+	r.On("GetByPath", mock.Anything, diskID, "/", "a.txt").Return(&model.Artifact{}, nil)
+	usage := new(MockUsageRepo)
+	usage.On("DiskUsage", mock.Anything, projectID, diskID).Return(&repo.StorageUsage{Bytes: 90}, nil, nil)
+	svc := &artifactService{r: r, usage: usage, cfg: cfg}
+
+	// Rejected before the upload, so no S3 client is needed
+	_, err := svc.Create(context.Background(), CreateArtifactInput{
+		ProjectID:  projectID,
+		DiskID:     diskID,
+		Path:       "/",
+		Filename:   "a.txt",
+		FileHeader: &multipart.FileHeader{Filename: "a.txt", Size: 20},
+	})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	r.AssertExpectations(t)
+	usage.AssertExpectations(t)
+}
+
+func TestSessionService_StoreMessage_QuotaExceeded(t *testing.T) {
+	projectID := uuid.New()
+	sessionID := uuid.New()
+
+	sessionRepo := &MockSessionRepo{}
+	sessionRepo.On("Get", mock.Anything, mock.Anything).Return(&model.Session{ID: sessionID, ProjectID: projectID}, nil)
+	usage := new(MockUsageRepo)
+	usage.On("ProjectUsage", mock.Anything, projectID).Return(&repo.StorageUsage{Bytes: 1000}, nil)
+	cfg := &config.Config{Quota: config.QuotaCfg{ProjectMaxBytes: 1024}}
+	svc := NewSessionService(sessionRepo, nil, usage, nil, nil, nil, cfg, nil, nil)
+
+	_, err := svc.StoreMessage(context.Background(), StoreMessageInput{
+		ProjectID: projectID,
+		SessionID: sessionID,
+		Role:      "user",
+		Parts:     []PartIn{{Type: "file", FileField: "doc"}},
+		Files:     map[string]*multipart.FileHeader{"doc": {Filename: "doc.pdf", Size: 100}},
+	})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	usage.AssertExpectations(t)
+}
@@ -0,0 +1,174 @@
+package assembler
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AnthropicAssembler assembles Anthropic Messages API stream events into a
+// MessageParam. Content blocks are kept as raw JSON objects so every block
+// type the normalizer understands (citations, server tools, redacted
+// thinking, ...) survives the round trip.
+type AnthropicAssembler struct {
+	role        string
+	blocks      map[int64]map[string]any
+	partialJSON map[int64]*strings.Builder
+	done        bool
+}
+
+type anthropicStreamEvent struct {
+	Type         string          `json:"type"`
+	Index        int64           `json:"index"`
+	Message      json.RawMessage `json:"message"`
+	ContentBlock map[string]any  `json:"content_block"`
+	Delta        struct {
+		Type        string         `json:"type"`
+		Text        string         `json:"text"`
+		PartialJSON string         `json:"partial_json"`
+		Thinking    string         `json:"thinking"`
+		Signature   string         `json:"signature"`
+		Citation    map[string]any `json:"citation"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (a *AnthropicAssembler) AddChunk(chunk json.RawMessage) error {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return fmt.Errorf("failed to unmarshal Anthropic stream event: %w", err)
+	}
+
+	if a.blocks == nil {
+		a.blocks = make(map[int64]map[string]any)
+		a.partialJSON = make(map[int64]*strings.Builder)
+	}
+
+	switch event.Type {
+	case "message_start":
+		var message struct {
+			Role    string           `json:"role"`
+			Content []map[string]any `json:"content"`
+		}
+		if err := json.Unmarshal(event.Message, &message); err != nil {
+			return fmt.Errorf("failed to unmarshal Anthropic message_start: %w", err)
+		}
+		a.role = message.Role
+		for i, block := range message.Content {
+			a.blocks[int64(i)] = block
+		}
+
+	case "content_block_start":
+		if event.ContentBlock == nil {
+			return fmt.Errorf("content_block_start at index %d has no content_block", event.Index)
+		}
+		a.blocks[event.Index] = event.ContentBlock
+		if blockType, _ := event.ContentBlock["type"].(string); blockType == "tool_use" || blockType == "server_tool_use" {
+			a.partialJSON[event.Index] = &strings.Builder{}
+		}
+
+	case "content_block_delta":
+		block, ok := a.blocks[event.Index]
+		if !ok {
+			return fmt.Errorf("content_block_delta for unknown block index %d", event.Index)
+		}
+		switch event.Delta.Type {
+		case "text_delta":
+			text, _ := block["text"].(string)
+			block["text"] = text + event.Delta.Text
+		case "input_json_delta":
+			if builder, ok := a.partialJSON[event.Index]; ok {
+				builder.WriteString(event.Delta.PartialJSON)
+			}
+		case "thinking_delta":
+			thinking, _ := block["thinking"].(string)
+			block["thinking"] = thinking + event.Delta.Thinking
+		case "signature_delta":
+			signature, _ := block["signature"].(string)
+			block["signature"] = signature + event.Delta.Signature
+		case "citations_delta":
+			citations, _ := block["citations"].([]any)
+			block["citations"] = append(citations, event.Delta.Citation)
+		}
+
+	case "content_block_stop":
+		block, ok := a.blocks[event.Index]
+		if !ok {
+			return fmt.Errorf("content_block_stop for unknown block index %d", event.Index)
+		}
+		if builder, ok := a.partialJSON[event.Index]; ok {
+			input, err := parseToolInput(builder.String())
+			if err != nil {
+				return fmt.Errorf("invalid tool input for block %d: %w", event.Index, err)
+			}
+			block["input"] = input
+			delete(a.partialJSON, event.Index)
+		}
+
+	case "message_stop":
+		a.done = true
+
+	case "error":
+		return fmt.Errorf("anthropic stream error (%s): %s", event.Error.Type, event.Error.Message)
+	}
+
+	return nil
+}
+
+func (a *AnthropicAssembler) Done() bool {
+	return a.done
+}
+
+func (a *AnthropicAssembler) Message() (json.RawMessage, error) {
+	role := a.role
+	if role == "" {
+		role = "assistant"
+	}
+
+	indexes := make([]int64, 0, len(a.blocks))
+	for idx := range a.blocks {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	content := make([]map[string]any, 0, len(indexes))
+	for _, idx := range indexes {
+		block := a.blocks[idx]
+		if builder, open := a.partialJSON[idx]; open {
+			// Tool input still streaming: expose what parses, otherwise an empty object
+			partial := make(map[string]any, len(block)+1)
+			for k, v := range block {
+				partial[k] = v
+			}
+			input, err := parseToolInput(builder.String())
+			if err != nil {
+				input = map[string]any{}
+			}
+			partial["input"] = input
+			block = partial
+		}
+		content = append(content, block)
+	}
+
+	return json.Marshal(map[string]any{
+		"role":    role,
+		"content": content,
+	})
+}
+
+// parseToolInput parses concatenated input_json_delta fragments.
+// An empty input means the tool was called without arguments.
+func parseToolInput(raw string) (any, error) {
+	if strings.TrimSpace(raw) == "" {
+		return map[string]any{}, nil
+	}
+	var input any
+	if err := json.Unmarshal([]byte(raw), &input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
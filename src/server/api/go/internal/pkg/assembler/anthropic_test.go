@@ -0,0 +1,133 @@
+package assembler
+
+import (
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const anthropicToolStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4-5","stop_reason":null,"usage":{"input_tokens":10,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":"","signature":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the weather."}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig123"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Let me "}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"check."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: content_block_start
+data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"city\": \"Pa"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"ris\"}"}}
+`
+
+const anthropicStreamEnd = `
+event: content_block_stop
+data: {"type":"content_block_stop","index":2}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+
+func TestAnthropicAssembler_ToolUse(t *testing.T) {
+	asm := assemble(t, model.FormatAnthropic, anthropicToolStream+anthropicStreamEnd)
+	assert.True(t, asm.Done())
+
+	blob, err := asm.Message()
+	require.NoError(t, err)
+
+	role, parts, _, err := (&normalizer.AnthropicNormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleAssistant, role)
+	require.Len(t, parts, 3)
+
+	assert.Equal(t, model.PartTypeThinking, parts[0].Type)
+	assert.Equal(t, "Need the weather.", parts[0].Text)
+
+	assert.Equal(t, model.PartTypeText, parts[1].Type)
+	assert.Equal(t, "Let me check.", parts[1].Text)
+
+	assert.Equal(t, model.PartTypeToolCall, parts[2].Type)
+	assert.Equal(t, "toolu_1", parts[2].Meta[model.MetaKeyID])
+	assert.JSONEq(t, `{"city":"Paris"}`, parts[2].Meta[model.MetaKeyArguments].(string))
+}
+
+func TestAnthropicAssembler_PartialToolInput(t *testing.T) {
+	asm := assemble(t, model.FormatAnthropic, anthropicToolStream)
+	assert.False(t, asm.Done())
+
+	// The tool input is complete JSON already, but the block is still open
+	blob, err := asm.Message()
+	require.NoError(t, err)
+	_, parts, _, err := (&normalizer.AnthropicNormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	require.Len(t, parts, 3)
+	assert.JSONEq(t, `{"city":"Paris"}`, parts[2].Meta[model.MetaKeyArguments].(string))
+
+	// Truncated tool input falls back to an empty object
+	require.NoError(t, asm.AddChunk([]byte(`{"type":"content_block_start","index":3,"content_block":{"type":"tool_use","id":"toolu_2","name":"get_time","input":{}}}`)))
+	require.NoError(t, asm.AddChunk([]byte(`{"type":"content_block_delta","index":3,"delta":{"type":"input_json_delta","partial_json":"{\"tz\": \"Eu"}}`)))
+	blob, err = asm.Message()
+	require.NoError(t, err)
+	_, parts, _, err = (&normalizer.AnthropicNormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	require.Len(t, parts, 4)
+	assert.JSONEq(t, `{}`, parts[3].Meta[model.MetaKeyArguments].(string))
+}
+
+func TestAnthropicAssembler_Citations(t *testing.T) {
+	stream := `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[]}}
+{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","cited_text":"Paris is the capital","url":"https://example.com","title":"Example","encrypted_index":"abc"}}}
+{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Paris is the capital of France."}}
+{"type":"content_block_stop","index":0}
+{"type":"message_stop"}
+`
+	asm := assemble(t, model.FormatAnthropic, stream)
+
+	blob, err := asm.Message()
+	require.NoError(t, err)
+
+	_, parts, _, err := (&normalizer.AnthropicNormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "Paris is the capital of France.", parts[0].Text)
+	citations, ok := parts[0].Meta[model.MetaKeyCitations].([]interface{})
+	require.True(t, ok)
+	require.Len(t, citations, 1)
+	assert.Equal(t, "https://example.com", citations[0].(map[string]interface{})[model.CitationKeyURL])
+}
+
+func TestAnthropicAssembler_ErrorEvent(t *testing.T) {
+	asm := &AnthropicAssembler{}
+	err := asm.AddChunk([]byte(`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`))
+	assert.ErrorContains(t, err, "Overloaded")
+}
@@ -0,0 +1,76 @@
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// maxChunkSize bounds a single stream line (SSE data line or NDJSON record).
+const maxChunkSize = 4 * 1024 * 1024
+
+// StreamAssembler accumulates provider-native streaming chunks into a single
+// provider-native message blob that the normalizer of the same format accepts.
+type StreamAssembler interface {
+	// AddChunk consumes one stream chunk (the JSON payload of an SSE data line or NDJSON record).
+	AddChunk(chunk json.RawMessage) error
+	// Done reports whether the provider signaled the end of the message.
+	Done() bool
+	// Message returns the message assembled so far. It is valid to call
+	// Message before the stream ends to get a partial message.
+	Message() (json.RawMessage, error)
+}
+
+// GetAssembler returns a new StreamAssembler for the given format.
+func GetAssembler(format model.MessageFormat) (StreamAssembler, error) {
+	switch format {
+	case model.FormatOpenAI:
+		return &OpenAIAssembler{}, nil
+	case model.FormatAnthropic:
+		return &AnthropicAssembler{}, nil
+	case model.FormatGemini:
+		return &GeminiAssembler{}, nil
+	default:
+		return nil, fmt.Errorf("streaming is not supported for format: %s", format)
+	}
+}
+
+// ReadChunks reads a provider stream from r and calls fn with each chunk payload.
+// Both Server-Sent Events ("data: {...}" lines, as sent by the providers) and
+// newline-delimited JSON are accepted. SSE comments, "event:" lines and the
+// OpenAI "[DONE]" sentinel are skipped.
+func ReadChunks(r io.Reader, fn func(chunk json.RawMessage) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxChunkSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == ':' {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("event:")) || bytes.HasPrefix(line, []byte("id:")) || bytes.HasPrefix(line, []byte("retry:")) {
+			continue
+		}
+		if after, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			line = bytes.TrimSpace(after)
+		}
+		if len(line) == 0 || bytes.Equal(line, []byte("[DONE]")) {
+			continue
+		}
+		if !json.Valid(line) {
+			return fmt.Errorf("invalid stream chunk: %.100s", line)
+		}
+
+		chunk := make(json.RawMessage, len(line))
+		copy(chunk, line)
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
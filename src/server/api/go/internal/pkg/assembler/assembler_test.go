@@ -0,0 +1,65 @@
+package assembler
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectChunks(t *testing.T, stream string) []string {
+	t.Helper()
+	var chunks []string
+	err := ReadChunks(strings.NewReader(stream), func(chunk json.RawMessage) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	})
+	require.NoError(t, err)
+	return chunks
+}
+
+// assemble feeds every chunk of the stream to a new assembler of the given format.
+func assemble(t *testing.T, format model.MessageFormat, stream string) StreamAssembler {
+	t.Helper()
+	asm, err := GetAssembler(format)
+	require.NoError(t, err)
+	err = ReadChunks(strings.NewReader(stream), asm.AddChunk)
+	require.NoError(t, err)
+	return asm
+}
+
+func TestReadChunks_SSE(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		"event: message_start\n" +
+		"data: {\"a\":1}\n\n" +
+		"id: 2\n" +
+		"data:{\"b\":2}\n\n" +
+		"data: [DONE]\n\n"
+
+	assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, collectChunks(t, stream))
+}
+
+func TestReadChunks_NDJSON(t *testing.T) {
+	stream := "{\"a\":1}\n\n{\"b\":2}\n"
+
+	assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, collectChunks(t, stream))
+}
+
+func TestReadChunks_InvalidChunk(t *testing.T) {
+	err := ReadChunks(strings.NewReader("data: {oops\n"), func(json.RawMessage) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestGetAssembler(t *testing.T) {
+	for _, format := range []model.MessageFormat{model.FormatOpenAI, model.FormatAnthropic, model.FormatGemini} {
+		asm, err := GetAssembler(format)
+		require.NoError(t, err)
+		assert.NotNil(t, asm)
+	}
+
+	_, err := GetAssembler(model.FormatAcontext)
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package assembler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// GeminiAssembler assembles Gemini streamGenerateContent responses (candidate 0)
+// into a Content. Consecutive text parts are concatenated; function calls
+// arrive whole and are appended as-is. Grounding metadata from the last chunk
+// that carries it is attached next to role/parts, as the Gemini normalizer expects.
+type GeminiAssembler struct {
+	role              string
+	parts             []*genai.Part
+	groundingMetadata *genai.GroundingMetadata
+	done              bool
+}
+
+func (a *GeminiAssembler) AddChunk(chunk json.RawMessage) error {
+	var response genai.GenerateContentResponse
+	if err := json.Unmarshal(chunk, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal Gemini stream response: %w", err)
+	}
+
+	if len(response.Candidates) == 0 || response.Candidates[0] == nil {
+		return nil
+	}
+	candidate := response.Candidates[0]
+
+	if candidate.Content != nil {
+		if candidate.Content.Role != "" {
+			a.role = candidate.Content.Role
+		}
+		for _, part := range candidate.Content.Parts {
+			a.addPart(part)
+		}
+	}
+	if candidate.GroundingMetadata != nil {
+		a.groundingMetadata = candidate.GroundingMetadata
+	}
+	if candidate.FinishReason != "" {
+		a.done = true
+	}
+
+	return nil
+}
+
+func (a *GeminiAssembler) addPart(part *genai.Part) {
+	if part == nil {
+		return
+	}
+
+	if len(a.parts) > 0 && part.Text != "" && isPlainTextPart(part) {
+		last := a.parts[len(a.parts)-1]
+		if last.Text != "" && isPlainTextPart(last) && last.Thought == part.Thought {
+			last.Text += part.Text
+			if len(part.ThoughtSignature) > 0 {
+				last.ThoughtSignature = part.ThoughtSignature
+			}
+			return
+		}
+	}
+
+	// Empty text parts carry at most a signature for the preceding part
+	if part.Text == "" && isPlainTextPart(part) {
+		if len(part.ThoughtSignature) > 0 && len(a.parts) > 0 {
+			a.parts[len(a.parts)-1].ThoughtSignature = part.ThoughtSignature
+		}
+		return
+	}
+
+	copied := *part
+	a.parts = append(a.parts, &copied)
+}
+
+// isPlainTextPart reports whether the part carries nothing but (possibly empty) text.
+func isPlainTextPart(part *genai.Part) bool {
+	return part.InlineData == nil && part.FileData == nil && part.FunctionCall == nil &&
+		part.FunctionResponse == nil && part.ExecutableCode == nil && part.CodeExecutionResult == nil
+}
+
+func (a *GeminiAssembler) Done() bool {
+	return a.done
+}
+
+func (a *GeminiAssembler) Message() (json.RawMessage, error) {
+	role := a.role
+	if role == "" {
+		role = "model"
+	}
+
+	parts := a.parts
+	if parts == nil {
+		parts = []*genai.Part{}
+	}
+
+	return json.Marshal(struct {
+		Role              string                   `json:"role"`
+		Parts             []*genai.Part            `json:"parts"`
+		GroundingMetadata *genai.GroundingMetadata `json:"groundingMetadata,omitempty"`
+	}{
+		Role:              role,
+		Parts:             parts,
+		GroundingMetadata: a.groundingMetadata,
+	})
+}
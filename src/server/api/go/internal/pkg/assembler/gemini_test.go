@@ -0,0 +1,52 @@
+package assembler
+
+import (
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeminiAssembler_MergesText(t *testing.T) {
+	stream := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Thinking...","thought":true}]},"index":0}]}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"The answer "}]},"index":0}]}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"is 42."}]},"index":0}]}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{"q":"42"}}}]},"finishReason":"STOP","index":0}]}
+`
+	asm := assemble(t, model.FormatGemini, stream)
+	assert.True(t, asm.Done())
+
+	blob, err := asm.Message()
+	require.NoError(t, err)
+
+	role, parts, _, err := (&normalizer.GeminiNormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleAssistant, role)
+	require.Len(t, parts, 3)
+	assert.Equal(t, model.PartTypeThinking, parts[0].Type)
+	assert.Equal(t, model.PartTypeText, parts[1].Type)
+	assert.Equal(t, "The answer is 42.", parts[1].Text)
+	assert.Equal(t, model.PartTypeToolCall, parts[2].Type)
+	assert.Equal(t, "lookup", parts[2].Meta[model.MetaKeyName])
+}
+
+func TestGeminiAssembler_Grounding(t *testing.T) {
+	stream := `{"candidates":[{"content":{"role":"model","parts":[{"text":"Paris is the capital."}]},"index":0}]}
+{"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP","groundingMetadata":{"groundingChunks":[{"web":{"uri":"https://example.com","title":"Example"}}],"groundingSupports":[{"segment":{"startIndex":0,"endIndex":21,"text":"Paris is the capital."},"groundingChunkIndices":[0]}]},"index":0}]}
+`
+	asm := assemble(t, model.FormatGemini, stream)
+
+	blob, err := asm.Message()
+	require.NoError(t, err)
+
+	_, parts, _, err := (&normalizer.GeminiNormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	require.NotEmpty(t, parts)
+	assert.Equal(t, "Paris is the capital.", parts[0].Text)
+	assert.NotEmpty(t, parts[0].Meta[model.MetaKeyCitations])
+}
@@ -0,0 +1,105 @@
+package assembler
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	openai "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+// OpenAIAssembler assembles OpenAI chat completion chunks (choice 0) into a
+// ChatCompletionAssistantMessageParam.
+type OpenAIAssembler struct {
+	content   strings.Builder
+	refusal   strings.Builder
+	toolCalls map[int64]*openAIToolCall
+	done      bool
+}
+
+type openAIToolCall struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+func (a *OpenAIAssembler) AddChunk(chunk json.RawMessage) error {
+	var completionChunk openai.ChatCompletionChunk
+	if err := completionChunk.UnmarshalJSON(chunk); err != nil {
+		return fmt.Errorf("failed to unmarshal OpenAI chunk: %w", err)
+	}
+
+	for _, choice := range completionChunk.Choices {
+		// Only the first choice is stored, matching non-streaming usage
+		if choice.Index != 0 {
+			continue
+		}
+
+		a.content.WriteString(choice.Delta.Content)
+		a.refusal.WriteString(choice.Delta.Refusal)
+
+		for _, delta := range choice.Delta.ToolCalls {
+			if a.toolCalls == nil {
+				a.toolCalls = make(map[int64]*openAIToolCall)
+			}
+			call, ok := a.toolCalls[delta.Index]
+			if !ok {
+				call = &openAIToolCall{}
+				a.toolCalls[delta.Index] = call
+			}
+			if delta.ID != "" {
+				call.id = delta.ID
+			}
+			if delta.Function.Name != "" {
+				call.name = delta.Function.Name
+			}
+			call.arguments.WriteString(delta.Function.Arguments)
+		}
+
+		if choice.FinishReason != "" {
+			a.done = true
+		}
+	}
+
+	return nil
+}
+
+func (a *OpenAIAssembler) Done() bool {
+	return a.done
+}
+
+func (a *OpenAIAssembler) Message() (json.RawMessage, error) {
+	assistantParam := openai.ChatCompletionAssistantMessageParam{}
+
+	if a.content.Len() > 0 {
+		assistantParam.Content = openai.ChatCompletionAssistantMessageParamContentUnion{
+			OfString: param.NewOpt(a.content.String()),
+		}
+	}
+	if a.refusal.Len() > 0 {
+		assistantParam.Refusal = param.NewOpt(a.refusal.String())
+	}
+
+	indexes := make([]int64, 0, len(a.toolCalls))
+	for idx := range a.toolCalls {
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	for _, idx := range indexes {
+		call := a.toolCalls[idx]
+		assistantParam.ToolCalls = append(assistantParam.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+			OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+				ID: call.id,
+				Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+					Name:      call.name,
+					Arguments: call.arguments.String(),
+				},
+			},
+		})
+	}
+
+	return json.Marshal(openai.ChatCompletionMessageParamUnion{OfAssistant: &assistantParam})
+}
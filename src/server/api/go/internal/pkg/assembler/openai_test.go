@@ -0,0 +1,54 @@
+package assembler
+
+import (
+	"testing"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/pkg/normalizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIAssembler_Text(t *testing.T) {
+	stream := `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hello"}}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}]}
+
+data: [DONE]
+`
+	asm := assemble(t, model.FormatOpenAI, stream)
+	assert.True(t, asm.Done())
+
+	blob, err := asm.Message()
+	require.NoError(t, err)
+
+	role, parts, _, err := (&normalizer.OpenAINormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleAssistant, role)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "Hello world", parts[0].Text)
+}
+
+func TestOpenAIAssembler_ToolCalls(t *testing.T) {
+	stream := `{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}
+{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}
+{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}
+{"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}
+`
+	asm := assemble(t, model.FormatOpenAI, stream)
+	assert.False(t, asm.Done())
+
+	blob, err := asm.Message()
+	require.NoError(t, err)
+
+	_, parts, _, err := (&normalizer.OpenAINormalizer{}).Normalize(blob)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	assert.Equal(t, model.PartTypeToolCall, parts[0].Type)
+	assert.Equal(t, "call_1", parts[0].Meta[model.MetaKeyID])
+	assert.Equal(t, "get_weather", parts[0].Meta[model.MetaKeyName])
+	assert.Equal(t, `{"city":"Paris"}`, parts[0].Meta[model.MetaKeyArguments])
+	assert.Equal(t, "call_2", parts[1].Meta[model.MetaKeyID])
+}
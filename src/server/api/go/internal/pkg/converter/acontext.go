@@ -20,8 +20,9 @@ type AcontextMessage struct {
 	SessionTaskProcessStatus string         `json:"session_task_process_status"` // Task processing state
 	Meta                     map[string]any `json:"meta,omitempty"`
 	TaskID                   *string        `json:"task_id"`
-	CreatedAt                string         `json:"created_at"` // ISO 8601 timestamp for UI compatibility
-	UpdatedAt                string         `json:"updated_at"` // ISO 8601 timestamp
+	CreatedAt                string         `json:"created_at"`       // ISO 8601 timestamp for UI compatibility
+	UpdatedAt                string         `json:"updated_at"`       // ISO 8601 timestamp
+	Status                   string         `json:"status,omitempty"` // "in_progress" for messages still being streamed
 }
 
 // Convert converts internal model.Message to Acontext format
@@ -37,6 +38,7 @@ func (c *AcontextConverter) Convert(messages []model.Message, publicURLs map[str
 			SessionTaskProcessStatus: msg.SessionTaskProcessStatus,
 			CreatedAt:                msg.CreatedAt.Format("2006-01-02T15:04:05.999999Z07:00"), // ISO 8601 / RFC3339
 			UpdatedAt:                msg.UpdatedAt.Format("2006-01-02T15:04:05.999999Z07:00"),
+			Status:                   msg.Status,
 		}
 
 		// Convert ParentID if present
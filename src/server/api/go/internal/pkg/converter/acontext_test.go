@@ -44,16 +44,17 @@ func TestAcontextConverter_Convert_WithAsset(t *testing.T) {
 				Type:     "image",
 				Filename: "test.jpg",
 				Asset: &model.Asset{
-					S3Key: "assets/test.jpg",
-					MIME:  "image/jpeg",
-					SizeB: 1024,
+					S3Key:  "assets/test.jpg",
+					SHA256: "test-sha",
+					MIME:   "image/jpeg",
+					SizeB:  1024,
 				},
 			},
 		}, nil),
 	}
 
 	publicURLs := map[string]service.PublicURL{
-		"assets/test.jpg": {URL: "https://example.com/test.jpg"},
+		"test-sha": {URL: "https://example.com/test.jpg"},
 	}
 
 	result, err := converter.Convert(messages, publicURLs)
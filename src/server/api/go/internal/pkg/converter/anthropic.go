@@ -1,6 +1,7 @@
 package converter
 
 import (
+	"encoding/json"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -26,7 +27,7 @@ func (c *AnthropicConverter) Convert(messages []model.Message, publicURLs map[st
 
 func (c *AnthropicConverter) convertMessage(msg model.Message, publicURLs map[string]service.PublicURL) anthropic.MessageParam {
 	role := c.convertRole(msg.Role)
-	contentBlocks := c.convertParts(msg.Parts, publicURLs)
+	contentBlocks := c.convertParts(role, msg.Parts, publicURLs)
 
 	if role == model.RoleUser {
 		return anthropic.NewUserMessage(contentBlocks...)
@@ -45,17 +46,22 @@ func (c *AnthropicConverter) convertRole(role string) string {
 	}
 }
 
-func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[string]service.PublicURL) []anthropic.ContentBlockParamUnion {
+func (c *AnthropicConverter) convertParts(role string, parts []model.Part, publicURLs map[string]service.PublicURL) []anthropic.ContentBlockParamUnion {
 	contentBlocks := make([]anthropic.ContentBlockParamUnion, 0, len(parts))
 
 	for _, part := range parts {
 		switch part.Type {
 		case model.PartTypeText:
 			if part.Text != "" {
-				if cacheControl := normalizer.BuildAnthropicCacheControl(part.Meta); cacheControl != nil {
+				cacheControl := normalizer.BuildAnthropicCacheControl(part.Meta)
+				citations := c.convertCitations(part.Citations())
+				if cacheControl != nil || len(citations) > 0 {
 					blockParam := anthropic.TextBlockParam{
-						Text:         part.Text,
-						CacheControl: *cacheControl,
+						Text:      part.Text,
+						Citations: citations,
+					}
+					if cacheControl != nil {
+						blockParam.CacheControl = *cacheControl
 					}
 					result := anthropic.ContentBlockParamUnion{}
 					result.OfText = &blockParam
@@ -71,6 +77,12 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 				contentBlocks = append(contentBlocks, *imageBlock)
 			}
 
+		case model.PartTypeAudio, model.PartTypeVideo:
+			// Anthropic has no audio or video input
+			if text := DegradePartToText(part); text != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(text))
+			}
+
 		case model.PartTypeToolCall:
 			if part.Meta != nil {
 				toolUseBlock := c.convertToolCallPart(part)
@@ -99,12 +111,136 @@ func (c *AnthropicConverter) convertParts(parts []model.Part, publicURLs map[str
 				block := anthropic.NewThinkingBlock(signature, part.Text)
 				contentBlocks = append(contentBlocks, block)
 			}
+
+		case model.PartTypeRedactedThinking:
+			if data := part.GetMetaString(model.MetaKeyData); data != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewRedactedThinkingBlock(data))
+			}
+
+		case model.PartTypeSearchResult:
+			// search_result blocks are only accepted in user content
+			if block := c.convertSearchResultPart(part); role == model.RoleUser && block != nil {
+				contentBlocks = append(contentBlocks, *block)
+			} else if text := DegradePartToText(part); text != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(text))
+			}
+
+		case model.PartTypeServerToolCall:
+			if block := c.convertServerToolCallPart(part); block != nil {
+				contentBlocks = append(contentBlocks, *block)
+			} else if text := DegradePartToText(part); text != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(text))
+			}
+
+		case model.PartTypeServerToolResult:
+			if block := c.convertServerToolResultPart(part); block != nil {
+				contentBlocks = append(contentBlocks, *block)
+			} else if text := DegradePartToText(part); text != "" {
+				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(text))
+			}
 		}
 	}
 
 	return contentBlocks
 }
 
+// convertCitations rebuilds native Anthropic citations. Citations without an
+// Anthropic counterpart (e.g. Gemini grounding) are skipped.
+func (c *AnthropicConverter) convertCitations(citations []map[string]any) []anthropic.TextCitationParamUnion {
+	if len(citations) == 0 {
+		return nil
+	}
+
+	result := make([]anthropic.TextCitationParamUnion, 0, len(citations))
+	for _, citation := range citations {
+		if citationType, _ := citation[model.CitationKeyType].(string); citationType == "" || citationType == model.CitationTypeGrounding {
+			continue
+		}
+		citationBytes, err := json.Marshal(citation)
+		if err != nil {
+			continue
+		}
+		var param anthropic.TextCitationParamUnion
+		if err := param.UnmarshalJSON(citationBytes); err != nil {
+			continue
+		}
+		result = append(result, param)
+	}
+	return result
+}
+
+func (c *AnthropicConverter) convertSearchResultPart(part model.Part) *anthropic.ContentBlockParamUnion {
+	source := part.GetMetaString(model.MetaKeySource)
+	title := part.GetMetaString(model.MetaKeyTitle)
+	if source == "" || part.Text == "" {
+		return nil
+	}
+
+	block := anthropic.NewSearchResultBlock([]anthropic.TextBlockParam{{Text: part.Text}}, source, title)
+	if part.Meta != nil {
+		if enabled, ok := part.Meta[model.MetaKeyCitationsEnabled].(bool); ok {
+			block.OfSearchResult.Citations = anthropic.CitationsConfigParam{Enabled: anthropic.Bool(enabled)}
+		}
+		if cacheControl := normalizer.BuildAnthropicCacheControl(part.Meta); cacheControl != nil {
+			block.OfSearchResult.CacheControl = *cacheControl
+		}
+	}
+	return &block
+}
+
+// convertServerToolCallPart emits a server_tool_use block. Anthropic only
+// runs web_search server-side, so other server tools are degraded to text.
+func (c *AnthropicConverter) convertServerToolCallPart(part model.Part) *anthropic.ContentBlockParamUnion {
+	id := part.ID()
+	if id == "" || part.Name() != "web_search" {
+		return nil
+	}
+
+	input := ParseToolArguments(part.Meta[model.MetaKeyArguments])
+	block := anthropic.NewServerToolUseBlock(id, input)
+	return &block
+}
+
+// convertServerToolResultPart emits a web_search_tool_result block. Results
+// must carry the encrypted content Anthropic returned, otherwise the block
+// is rejected, so results from other providers are degraded to text.
+func (c *AnthropicConverter) convertServerToolResultPart(part model.Part) *anthropic.ContentBlockParamUnion {
+	toolUseID := part.ToolCallID()
+	if toolUseID == "" || part.Name() != "web_search" {
+		return nil
+	}
+
+	if errorCode := part.GetMetaString(model.MetaKeyErrorCode); errorCode != "" {
+		block := anthropic.NewWebSearchToolResultBlock(anthropic.WebSearchToolRequestErrorParam{
+			ErrorCode: anthropic.WebSearchToolRequestErrorErrorCode(errorCode),
+		}, toolUseID)
+		return &block
+	}
+
+	results := part.Results()
+	items := make([]anthropic.WebSearchResultBlockParam, 0, len(results))
+	for _, result := range results {
+		encryptedContent, _ := result[model.MetaKeyEncryptedContent].(string)
+		if encryptedContent == "" {
+			return nil
+		}
+		title, _ := result[model.MetaKeyTitle].(string)
+		url, _ := result[model.MetaKeyURL].(string)
+		item := anthropic.WebSearchResultBlockParam{
+			EncryptedContent: encryptedContent,
+			Title:            title,
+			URL:              url,
+		}
+		if pageAge, ok := result[model.MetaKeyPageAge].(string); ok && pageAge != "" {
+			item.PageAge = anthropic.String(pageAge)
+		}
+		items = append(items, item)
+	}
+
+	block := anthropic.NewWebSearchToolResultBlock(items, toolUseID)
+	return &block
+}
+
 func (c *AnthropicConverter) convertImagePart(part model.Part, publicURLs map[string]service.PublicURL) *anthropic.ContentBlockParamUnion {
 	imageURL := GetAssetURL(part.Asset, publicURLs)
 	if imageURL == "" && part.Meta != nil {
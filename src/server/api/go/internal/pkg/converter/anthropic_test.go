@@ -3,6 +3,7 @@ package converter
 import (
 	"testing"
 
+	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
 	"github.com/stretchr/testify/assert"
@@ -125,19 +126,166 @@ func TestAnthropicConverter_Convert_Image(t *testing.T) {
 				Type:     model.PartTypeImage,
 				Filename: "image.jpg",
 				Asset: &model.Asset{
-					S3Key: "assets/image.jpg",
-					MIME:  "image/jpeg",
-					SizeB: 2048,
+					S3Key:  "assets/image.jpg",
+					SHA256: "image-sha",
+					MIME:   "image/jpeg",
+					SizeB:  2048,
 				},
 			},
 		}, nil),
 	}
 
 	publicURLs := map[string]service.PublicURL{
-		"assets/image.jpg": {URL: "https://example.com/image.jpg"},
+		"image-sha": {URL: "https://example.com/image.jpg"},
 	}
 
 	result, err := converter.Convert(messages, publicURLs)
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestAnthropicConverter_Convert_CitationsAndServerTools(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{
+			{
+				Type: model.PartTypeSearchResult,
+				Text: "The API supports pagination.",
+				Meta: map[string]any{
+					model.MetaKeySource: "https://docs.example.com/api",
+					model.MetaKeyTitle:  "API docs",
+				},
+			},
+		}, nil),
+		createTestMessage(model.RoleAssistant, []model.Part{
+			{Type: model.PartTypeRedactedThinking, Meta: map[string]any{model.MetaKeyData: "opaque"}},
+			{
+				Type: model.PartTypeServerToolCall,
+				Meta: map[string]any{
+					model.MetaKeyID:        "srvtoolu_1",
+					model.MetaKeyName:      "web_search",
+					model.MetaKeyArguments: `{"query":"weather paris"}`,
+				},
+			},
+			{
+				Type: model.PartTypeServerToolResult,
+				Meta: map[string]any{
+					model.MetaKeyToolCallID: "srvtoolu_1",
+					model.MetaKeyName:       "web_search",
+					model.MetaKeyResults: []any{
+						map[string]any{
+							model.MetaKeyTitle:            "Paris weather",
+							model.MetaKeyURL:              "https://example.com/paris",
+							model.MetaKeyEncryptedContent: "enc_1",
+						},
+					},
+				},
+			},
+			{
+				Type: model.PartTypeText,
+				Text: "It is sunny.",
+				Meta: map[string]any{
+					model.MetaKeyCitations: []any{
+						map[string]any{
+							model.CitationKeyType:      "web_search_result_location",
+							model.CitationKeyCitedText: "Sunny, 25C",
+							model.CitationKeyURL:       "https://example.com/paris",
+							model.CitationKeyTitle:     "Paris weather",
+							"encrypted_index":          "idx_1",
+						},
+						map[string]any{
+							model.CitationKeyType:      model.CitationTypeGrounding,
+							model.CitationKeyCitedText: "Sunny",
+						},
+					},
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	params := result.([]anthropic.MessageParam)
+	require.Len(t, params, 2)
+
+	require.Len(t, params[0].Content, 1)
+	require.NotNil(t, params[0].Content[0].OfSearchResult)
+	assert.Equal(t, "https://docs.example.com/api", params[0].Content[0].OfSearchResult.Source)
+
+	content := params[1].Content
+	require.Len(t, content, 4)
+	require.NotNil(t, content[0].OfRedactedThinking)
+	assert.Equal(t, "opaque", content[0].OfRedactedThinking.Data)
+	require.NotNil(t, content[1].OfServerToolUse)
+	assert.Equal(t, "srvtoolu_1", content[1].OfServerToolUse.ID)
+	require.NotNil(t, content[2].OfWebSearchToolResult)
+	require.Len(t, content[2].OfWebSearchToolResult.Content.OfWebSearchToolResultBlockItem, 1)
+	assert.Equal(t, "enc_1", content[2].OfWebSearchToolResult.Content.OfWebSearchToolResultBlockItem[0].EncryptedContent)
+	require.NotNil(t, content[3].OfText)
+	require.Len(t, content[3].OfText.Citations, 1, "grounding citations have no Anthropic counterpart")
+	require.NotNil(t, content[3].OfText.Citations[0].OfWebSearchResultLocation)
+	assert.Equal(t, "idx_1", content[3].OfText.Citations[0].OfWebSearchResultLocation.EncryptedIndex)
+}
+
+func TestAnthropicConverter_Convert_DegradesForeignServerTools(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	messages := []model.Message{
+		createTestMessage(model.RoleAssistant, []model.Part{
+			{
+				Type: model.PartTypeSearchResult,
+				Meta: map[string]any{
+					model.MetaKeySource: "https://example.com/paris",
+					model.MetaKeyTitle:  "example.com",
+					model.MetaKeyURL:    "https://example.com/paris",
+				},
+			},
+			{
+				Type: model.PartTypeServerToolResult,
+				Meta: map[string]any{
+					model.MetaKeyToolCallID: "srv_1",
+					model.MetaKeyName:       "web_search",
+					model.MetaKeyResults: []any{
+						map[string]any{model.MetaKeyTitle: "No encrypted content", model.MetaKeyURL: "https://example.org"},
+					},
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	content := result.([]anthropic.MessageParam)[0].Content
+	require.Len(t, content, 2)
+	require.NotNil(t, content[0].OfText)
+	assert.Equal(t, "[Search result] example.com (https://example.com/paris)", content[0].OfText.Text)
+	require.NotNil(t, content[1].OfText)
+	assert.Contains(t, content[1].OfText.Text, "No encrypted content (https://example.org)")
+}
+
+func TestAnthropicConverter_Convert_AudioAndVideoFallBackToText(t *testing.T) {
+	converter := &AnthropicConverter{}
+
+	audio := model.NewAudioPart("UklGRg==", "wav")
+	audio.Meta[model.MetaKeyTranscript] = "what is this song?"
+	video := model.NewVideoPartBase64("video/mp4", "AAAA")
+	video.Filename = "clip.mp4"
+
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{audio, video}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	params := result.([]anthropic.MessageParam)
+	require.Len(t, params, 1)
+	require.Len(t, params[0].Content, 2)
+	require.NotNil(t, params[0].Content[0].OfText)
+	assert.Equal(t, "[Audio] transcript:\nwhat is this song?", params[0].Content[0].OfText.Text)
+	require.NotNil(t, params[0].Content[1].OfText)
+	assert.Equal(t, "[Video] clip.mp4 (not supported by this provider)", params[0].Content[1].OfText.Text)
+}
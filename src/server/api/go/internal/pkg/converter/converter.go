@@ -80,6 +80,11 @@ type GetMessagesOutput struct {
 	ThisTimeTokens  int                          `json:"this_time_tokens"`             // Token count for returned messages
 	EditAtMessageID string                       `json:"edit_at_message_id,omitempty"` // Message ID where edit strategies were applied
 	PublicURLs      map[string]service.PublicURL `json:"public_urls,omitempty"`        // Asset public URLs (only for acontext format)
+	Repair          *RepairReport                `json:"repair,omitempty"`             // Changes made when repair=true
+	Statuses        []string                     `json:"statuses,omitempty"`           // Per-message status, only present when some messages are still streaming
+
+	// Assets without a public URL by SHA-256, with the reason, e.g. "encrypted"
+	PublicURLsUnavailable map[string]string `json:"public_urls_unavailable,omitempty"`
 }
 
 // GetConvertedMessagesOutput wraps the converted messages with metadata
@@ -104,10 +109,17 @@ func GetConvertedMessagesOutput(
 	// Extracting message IDs and user metas
 	messageIDs := make([]string, len(messages))
 	metas := make([]map[string]interface{}, len(messages))
+	statuses := make([]string, len(messages))
+	hasInProgress := false
 	for i := range len(messages) {
 		messageIDs[i] = messages[i].ID.String()
 		// Extract user meta from __user_meta__ field
 		metas[i] = ExtractUserMeta(messages[i].Meta.Data())
+		statuses[i] = model.MessageStatusCompleted
+		if messages[i].Status == model.MessageStatusInProgress {
+			statuses[i] = model.MessageStatusInProgress
+			hasInProgress = true
+		}
 	}
 
 	result := &GetMessagesOutput{
@@ -122,6 +134,11 @@ func GetConvertedMessagesOutput(
 		result.NextCursor = nextCursor
 	}
 
+	// Include statuses only when some messages are still streaming
+	if hasInProgress {
+		result.Statuses = statuses
+	}
+
 	// Include edit_at_message_id if provided
 	if editAtMessageID != "" {
 		result.EditAtMessageID = editAtMessageID
@@ -216,6 +216,24 @@ func TestGetConvertedMessagesOutput_NonAcontextFormat(t *testing.T) {
 	assert.Nil(t, result.PublicURLs)
 }
 
+func TestGetConvertedMessagesOutput_InProgressStatuses(t *testing.T) {
+	stored := createTestMessage(model.RoleUser, []model.Part{
+		{Type: model.PartTypeText, Text: "Hi"},
+	}, nil)
+	streaming := createTestMessage(model.RoleAssistant, []model.Part{
+		{Type: model.PartTypeText, Text: "Hel"},
+	}, nil)
+	streaming.Status = model.MessageStatusInProgress
+
+	result, err := GetConvertedMessagesOutput([]model.Message{stored}, model.FormatOpenAI, nil, "", false, 0, "")
+	require.NoError(t, err)
+	assert.Nil(t, result.Statuses, "statuses should be omitted when nothing is streaming")
+
+	result, err = GetConvertedMessagesOutput([]model.Message{stored, streaming}, model.FormatOpenAI, nil, "", false, 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.MessageStatusCompleted, model.MessageStatusInProgress}, result.Statuses)
+}
+
 func TestGetConvertedMessagesOutput_EmptyMessages(t *testing.T) {
 	// Test with empty message list
 	messages := []model.Message{}
@@ -111,12 +111,40 @@ func (c *GeminiConverter) convertParts(parts []model.Part, publicURLs map[string
 				geminiParts = append(geminiParts, gPart)
 			}
 
+		case model.PartTypeSearchResult, model.PartTypeServerToolCall, model.PartTypeServerToolResult:
+			// Grounding metadata belongs to response candidates, not Content; keep it as text
+			if text := DegradePartToText(part); text != "" {
+				geminiParts = append(geminiParts, &genai.Part{
+					Text: text,
+				})
+			}
+
 		case model.PartTypeImage:
 			imagePart := c.convertImagePart(part, publicURLs)
 			if imagePart != nil {
 				geminiParts = append(geminiParts, imagePart)
 			}
 
+		case model.PartTypeAudio, model.PartTypeVideo:
+			if mediaPart := c.convertMediaPart(part, publicURLs); mediaPart != nil {
+				geminiParts = append(geminiParts, mediaPart)
+			} else if text := DegradePartToText(part); text != "" {
+				geminiParts = append(geminiParts, &genai.Part{
+					Text: text,
+				})
+			}
+
+		case model.PartTypeFile:
+			// Only files Gemini can resolve by URI; other documents have no Gemini form here
+			if uri := part.GetMetaString(model.MetaKeyURL); isGeminiFileURI(uri) {
+				geminiParts = append(geminiParts, &genai.Part{
+					FileData: &genai.FileData{
+						FileURI:  uri,
+						MIMEType: part.GetMetaString(model.MetaKeyMediaType),
+					},
+				})
+			}
+
 		case model.PartTypeToolCall:
 			if part.Meta != nil {
 				functionCall := c.convertToolCallPart(part)
@@ -180,6 +208,51 @@ func (c *GeminiConverter) convertImagePart(part model.Part, publicURLs map[strin
 	}
 }
 
+// convertMediaPart emits audio or video as inlineData when the bytes are
+// available, or as fileData for URIs Gemini resolves itself (Files API,
+// Cloud Storage, YouTube).
+func (c *GeminiConverter) convertMediaPart(part model.Part, publicURLs map[string]service.PublicURL) *genai.Part {
+	mediaType := part.GetMetaString(model.MetaKeyMediaType)
+	if mediaType == "" && part.Type == model.PartTypeAudio {
+		mediaType = AudioMediaType(part.GetMetaString(model.MetaKeyAudioFormat))
+	}
+
+	if uri := part.GetMetaString(model.MetaKeyURL); part.Asset == nil && part.GetMetaString(model.MetaKeyData) == "" && isGeminiFileURI(uri) {
+		return &genai.Part{
+			FileData: &genai.FileData{
+				FileURI:  uri,
+				MIMEType: mediaType,
+			},
+		}
+	}
+
+	base64Data, resolvedType := ResolveMediaData(part, publicURLs)
+	if base64Data == "" || resolvedType == "" {
+		return nil
+	}
+
+	dataBytes, err := base64.StdEncoding.DecodeString(base64Data)
+	if err != nil {
+		return nil
+	}
+
+	return &genai.Part{
+		InlineData: &genai.Blob{
+			MIMEType: resolvedType,
+			Data:     dataBytes,
+		},
+	}
+}
+
+// isGeminiFileURI reports whether uri can be passed to Gemini as fileData
+// instead of being downloaded and inlined.
+func isGeminiFileURI(uri string) bool {
+	return strings.HasPrefix(uri, "gs://") ||
+		strings.HasPrefix(uri, "https://generativelanguage.googleapis.com/") ||
+		strings.HasPrefix(uri, "https://www.youtube.com/") ||
+		strings.HasPrefix(uri, "https://youtu.be/")
+}
+
 func (c *GeminiConverter) convertToolCallPart(part model.Part) *genai.FunctionCall {
 	if part.Meta == nil {
 		return nil
@@ -137,16 +137,17 @@ func TestGeminiConverter_Convert_Image(t *testing.T) {
 				Type:     model.PartTypeImage,
 				Filename: "image.jpg",
 				Asset: &model.Asset{
-					S3Key: "assets/image.jpg",
-					MIME:  "image/jpeg",
-					SizeB: 2048,
+					S3Key:  "assets/image.jpg",
+					SHA256: "image-sha",
+					MIME:   "image/jpeg",
+					SizeB:  2048,
 				},
 			},
 		}, nil),
 	}
 
 	publicURLs := map[string]service.PublicURL{
-		"assets/image.jpg": {URL: "https://example.com/image.jpg"},
+		"image-sha": {URL: "https://example.com/image.jpg"},
 	}
 
 	result, err := converter.Convert(messages, publicURLs)
@@ -164,16 +165,17 @@ func TestGeminiConverter_Convert_MultipleParts(t *testing.T) {
 				Type:     model.PartTypeImage,
 				Filename: "image.jpg",
 				Asset: &model.Asset{
-					S3Key: "assets/image.jpg",
-					MIME:  "image/jpeg",
-					SizeB: 2048,
+					S3Key:  "assets/image.jpg",
+					SHA256: "image-sha",
+					MIME:   "image/jpeg",
+					SizeB:  2048,
 				},
 			},
 		}, nil),
 	}
 
 	publicURLs := map[string]service.PublicURL{
-		"assets/image.jpg": {URL: "https://example.com/image.jpg"},
+		"image-sha": {URL: "https://example.com/image.jpg"},
 	}
 
 	result, err := converter.Convert(messages, publicURLs)
@@ -195,3 +197,78 @@ func TestGeminiConverter_Convert_InvalidRole(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestGeminiConverter_Convert_SearchResultAsText(t *testing.T) {
+	converter := &GeminiConverter{}
+
+	messages := []model.Message{
+		createTestMessage(model.RoleAssistant, []model.Part{
+			{
+				Type: model.PartTypeText,
+				Text: "Paris is sunny.",
+				Meta: map[string]any{
+					model.MetaKeyCitations: []any{
+						map[string]any{model.CitationKeyType: model.CitationTypeGrounding, model.CitationKeyCitedText: "Paris is sunny."},
+					},
+				},
+			},
+			{
+				Type: model.PartTypeSearchResult,
+				Meta: map[string]any{
+					model.MetaKeyTitle: "example.com",
+					model.MetaKeyURL:   "https://example.com/paris",
+				},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	contents := result.([]*genai.Content)
+	require.Len(t, contents, 1)
+	require.Len(t, contents[0].Parts, 2)
+	assert.Equal(t, "Paris is sunny.", contents[0].Parts[0].Text)
+	assert.Equal(t, "[Search result] example.com (https://example.com/paris)", contents[0].Parts[1].Text)
+}
+
+func TestGeminiConverter_Convert_AudioAndVideo(t *testing.T) {
+	converter := &GeminiConverter{}
+
+	wav := []byte("RIFF....WAVE")
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{
+			model.NewAudioPart(base64.StdEncoding.EncodeToString(wav), "wav"),
+			{
+				Type: model.PartTypeVideo,
+				Meta: map[string]any{
+					model.MetaKeyURL:       "https://www.youtube.com/watch?v=abc",
+					model.MetaKeyMediaType: "video/mp4",
+				},
+			},
+			{
+				Type: model.PartTypeVideo,
+				Meta: map[string]any{model.MetaKeyTranscript: "a cat jumps"},
+			},
+		}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	contents := result.([]*genai.Content)
+	require.Len(t, contents, 1)
+	parts := contents[0].Parts
+	require.Len(t, parts, 3)
+
+	require.NotNil(t, parts[0].InlineData)
+	assert.Equal(t, "audio/wav", parts[0].InlineData.MIMEType)
+	assert.Equal(t, wav, parts[0].InlineData.Data)
+
+	require.NotNil(t, parts[1].FileData)
+	assert.Equal(t, "https://www.youtube.com/watch?v=abc", parts[1].FileData.FileURI)
+	assert.Equal(t, "video/mp4", parts[1].FileData.MIMEType)
+
+	// Nothing to send natively: fall back to the transcript
+	assert.Equal(t, "[Video] transcript:\na cat jumps", parts[2].Text)
+}
@@ -75,14 +75,15 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 				contentParts = append(contentParts, openai.ImageContentPart(imgParam))
 			}
 		case model.PartTypeAudio:
-			if part.Meta != nil {
-				data := part.GetMetaString(model.MetaKeyData)
-				format := part.GetMetaString(model.MetaKeyAudioFormat)
-				audioParam := openai.ChatCompletionContentPartInputAudioInputAudioParam{
-					Data:   data,
-					Format: format,
-				}
-				contentParts = append(contentParts, openai.InputAudioContentPart(audioParam))
+			if audioPart := c.convertInputAudioPart(part, publicURLs); audioPart != nil {
+				contentParts = append(contentParts, *audioPart)
+			} else if text := DegradePartToText(part); text != "" {
+				contentParts = append(contentParts, openai.TextContentPart(text))
+			}
+		case model.PartTypeVideo:
+			// Chat Completions has no video input
+			if text := DegradePartToText(part); text != "" {
+				contentParts = append(contentParts, openai.TextContentPart(text))
 			}
 		case model.PartTypeFile:
 			if part.Meta != nil {
@@ -106,6 +107,10 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 					contentParts = append(contentParts, openai.FileContentPart(fileParam))
 				}
 			}
+		case model.PartTypeSearchResult, model.PartTypeServerToolCall, model.PartTypeServerToolResult:
+			if text := DegradePartToText(part); text != "" {
+				contentParts = append(contentParts, openai.TextContentPart(text))
+			}
 		}
 	}
 
@@ -126,9 +131,33 @@ func (c *OpenAIConverter) convertToUserMessage(msg model.Message, publicURLs map
 	}
 }
 
+// convertInputAudioPart emits an input_audio part. OpenAI only accepts
+// base64 wav or mp3, so other audio returns nil.
+func (c *OpenAIConverter) convertInputAudioPart(part model.Part, publicURLs map[string]service.PublicURL) *openai.ChatCompletionContentPartUnionParam {
+	data, mediaType := ResolveMediaData(part, publicURLs)
+	if data == "" {
+		return nil
+	}
+
+	format := part.GetMetaString(model.MetaKeyAudioFormat)
+	if format != "wav" && format != "mp3" {
+		format = AudioFormat(mediaType)
+	}
+	if format == "" {
+		return nil
+	}
+
+	audioPart := openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{
+		Data:   data,
+		Format: format,
+	})
+	return &audioPart
+}
+
 func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.ChatCompletionMessageParamUnion {
 	var contentParts []openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion
 	var toolCalls []openai.ChatCompletionMessageToolCallUnionParam
+	var audioRef string
 
 	for _, part := range msg.Parts {
 		switch part.Type {
@@ -145,6 +174,22 @@ func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.Ch
 					OfText: &openai.ChatCompletionContentPartTextParam{Text: part.Text},
 				})
 			}
+		case model.PartTypeAudio:
+			// Generated audio is referenced by ID; anything else can only be sent as text
+			if audioID := part.GetMetaString(model.MetaKeyAudioID); audioID != "" && audioRef == "" {
+				audioRef = audioID
+			} else if text := DegradePartToText(part); text != "" {
+				contentParts = append(contentParts, openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{
+					OfText: &openai.ChatCompletionContentPartTextParam{Text: text},
+				})
+			}
+		case model.PartTypeSearchResult, model.PartTypeServerToolCall, model.PartTypeServerToolResult, model.PartTypeVideo:
+			// OpenAI has no native blocks for provider-executed tools, search results or assistant video
+			if text := DegradePartToText(part); text != "" {
+				contentParts = append(contentParts, openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{
+					OfText: &openai.ChatCompletionContentPartTextParam{Text: text},
+				})
+			}
 		case model.PartTypeToolCall:
 			if part.Meta != nil {
 				toolCall := c.convertToToolCall(part)
@@ -173,6 +218,10 @@ func (c *OpenAIConverter) convertToAssistantMessage(msg model.Message) openai.Ch
 		assistantParam.ToolCalls = toolCalls
 	}
 
+	if audioRef != "" {
+		assistantParam.Audio = openai.ChatCompletionAssistantMessageParamAudio{ID: audioRef}
+	}
+
 	if metaData := msg.Meta.Data(); len(metaData) > 0 {
 		if name, ok := metaData[model.MetaKeyName].(string); ok && name != "" {
 			assistantParam.Name = param.NewOpt(name)
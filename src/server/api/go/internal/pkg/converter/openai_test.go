@@ -130,3 +130,81 @@ func TestOpenAIConverter_Convert_ToolResult(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotNil(t, result)
 }
+
+func TestOpenAIConverter_Convert_DegradesServerToolsToText(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	messages := []model.Message{
+		createTestMessage(model.RoleAssistant, []model.Part{
+			{Type: model.PartTypeRedactedThinking, Meta: map[string]any{model.MetaKeyData: "opaque"}},
+			{
+				Type: model.PartTypeServerToolCall,
+				Meta: map[string]any{
+					model.MetaKeyID:        "srvtoolu_1",
+					model.MetaKeyName:      "web_search",
+					model.MetaKeyArguments: `{"query":"q"}`,
+				},
+			},
+			{Type: model.PartTypeText, Text: "Answer"},
+		}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 1)
+	require.NotNil(t, msgs[0].OfAssistant)
+	parts := msgs[0].OfAssistant.Content.OfArrayOfContentParts
+	require.Len(t, parts, 2)
+	assert.Equal(t, `[Server tool call] web_search({"query":"q"})`, parts[0].OfText.Text)
+	assert.Equal(t, "Answer", parts[1].OfText.Text)
+}
+
+func TestOpenAIConverter_Convert_AudioAndVideo(t *testing.T) {
+	converter := &OpenAIConverter{}
+
+	oggAudio := model.Part{
+		Type: model.PartTypeAudio,
+		Meta: map[string]any{
+			model.MetaKeyMediaType:  "audio/ogg",
+			model.MetaKeyData:       "T2dnUw==",
+			model.MetaKeyTranscript: "hello there",
+		},
+	}
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{
+			model.NewAudioPart("UklGRg==", "wav"),
+			{Type: model.PartTypeAudio, Meta: map[string]any{model.MetaKeyMediaType: "audio/mpeg", model.MetaKeyData: "SUQz"}},
+			oggAudio,
+			model.NewVideoPartBase64("video/mp4", "AAAA"),
+		}, nil),
+		createTestMessage(model.RoleAssistant, []model.Part{
+			{Type: model.PartTypeAudio, Meta: map[string]any{model.MetaKeyAudioID: "audio_abc", model.MetaKeyTranscript: "Hi!"}},
+		}, nil),
+	}
+
+	result, err := converter.Convert(messages, nil)
+	require.NoError(t, err)
+
+	msgs := result.([]openai.ChatCompletionMessageParamUnion)
+	require.Len(t, msgs, 2)
+
+	userParts := msgs[0].OfUser.Content.OfArrayOfContentParts
+	require.Len(t, userParts, 4)
+	require.NotNil(t, userParts[0].OfInputAudio)
+	assert.Equal(t, "wav", userParts[0].OfInputAudio.InputAudio.Format)
+	assert.Equal(t, "UklGRg==", userParts[0].OfInputAudio.InputAudio.Data)
+	require.NotNil(t, userParts[1].OfInputAudio)
+	assert.Equal(t, "mp3", userParts[1].OfInputAudio.InputAudio.Format)
+
+	// ogg is not accepted by OpenAI: the transcript is sent instead
+	require.NotNil(t, userParts[2].OfText)
+	assert.Equal(t, "[Audio] transcript:\nhello there", userParts[2].OfText.Text)
+	require.NotNil(t, userParts[3].OfText)
+	assert.Equal(t, "[Video] (not supported by this provider)", userParts[3].OfText.Text)
+
+	// Generated audio is referenced by ID
+	require.NotNil(t, msgs[1].OfAssistant)
+	assert.Equal(t, "audio_abc", msgs[1].OfAssistant.Audio.ID)
+}
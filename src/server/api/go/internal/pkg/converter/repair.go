@@ -0,0 +1,218 @@
+package converter
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/memodb-io/Acontext/internal/modules/model"
+)
+
+// DanglingToolCallPlaceholder is the text of a synthesized tool-result that
+// answers a tool-call whose result was never stored.
+const DanglingToolCallPlaceholder = "Error: tool call was interrupted before a result was recorded."
+
+// RepairReport describes the changes made by RepairMessages.
+type RepairReport struct {
+	SynthesizedToolResults []string `json:"synthesized_tool_results,omitempty"` // Tool-call IDs that received a synthesized error result
+	DroppedToolResults     []string `json:"dropped_tool_results,omitempty"`     // Tool-call IDs of orphaned tool-results that were dropped
+	DroppedMessageIDs      []string `json:"dropped_message_ids,omitempty"`      // Messages removed because they became empty
+	MergedMessageIDs       []string `json:"merged_message_ids,omitempty"`       // Messages merged into the preceding message of the same role
+}
+
+// Changed reports whether the repair modified the message history.
+func (r *RepairReport) Changed() bool {
+	return r != nil && (len(r.SynthesizedToolResults) > 0 || len(r.DroppedToolResults) > 0 ||
+		len(r.DroppedMessageIDs) > 0 || len(r.MergedMessageIDs) > 0)
+}
+
+// requiresAlternation reports whether the target format rejects consecutive
+// messages with the same role.
+func requiresAlternation(format model.MessageFormat) bool {
+	return format == model.FormatAnthropic || format == model.FormatGemini
+}
+
+// RepairMessages makes a message history acceptable to the target provider:
+//  1. every tool-call gets a matching tool-result in the following user turn;
+//     missing results are synthesized as error results
+//  2. tool-results without a matching tool-call in the preceding assistant
+//     message (or duplicated results) are dropped
+//  3. for formats that require role alternation (anthropic, gemini),
+//     consecutive messages with the same role are merged
+//
+// The input slice and its parts are not modified.
+func RepairMessages(messages []model.Message, format model.MessageFormat) ([]model.Message, *RepairReport) {
+	report := &RepairReport{}
+
+	repaired := repairToolPairs(messages, report)
+	if requiresAlternation(format) {
+		repaired = mergeConsecutiveRoles(repaired, report)
+	}
+
+	return repaired, report
+}
+
+// repairToolPairs synthesizes missing tool-results and drops orphaned ones.
+func repairToolPairs(messages []model.Message, report *RepairReport) []model.Message {
+	result := make([]model.Message, 0, len(messages))
+
+	// pending holds the tool-calls of the latest assistant message that are
+	// still waiting for a result, in call order. Synthesized results are
+	// inserted at insertAt: right after the assistant message and any
+	// tool-result-only messages answering it, so they stay ahead of other content.
+	var pending []model.Part
+	var pendingFrom *model.Message
+	insertAt := 0
+	answered := make(map[string]bool)
+
+	flushPending := func() {
+		if pendingFrom == nil {
+			return
+		}
+		var synthesized []model.Message
+		for _, call := range pending {
+			if answered[call.ID()] {
+				continue
+			}
+			synthesized = append(synthesized, synthesizeToolResultMessage(*pendingFrom, call))
+			report.SynthesizedToolResults = append(report.SynthesizedToolResults, call.ID())
+		}
+		result = slices.Insert(result, insertAt, synthesized...)
+		pending = nil
+		pendingFrom = nil
+		answered = make(map[string]bool)
+	}
+
+	for i := range messages {
+		msg := messages[i]
+
+		if msg.Role == model.RoleAssistant {
+			// Results for the previous assistant turn must come before this one
+			flushPending()
+
+			var calls []model.Part
+			for _, part := range msg.Parts {
+				if part.Type == model.PartTypeToolCall && part.ID() != "" {
+					calls = append(calls, part)
+				}
+			}
+			result = append(result, msg)
+			if len(calls) > 0 {
+				pending = calls
+				pendingFrom = &messages[i]
+				insertAt = len(result)
+			}
+			continue
+		}
+
+		hasToolResult := false
+		for _, part := range msg.Parts {
+			if part.Type == model.PartTypeToolResult {
+				hasToolResult = true
+				break
+			}
+		}
+		if !hasToolResult {
+			// A plain user turn ends the window for the previous tool-calls
+			flushPending()
+			result = append(result, msg)
+			continue
+		}
+
+		parts := make([]model.Part, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			if part.Type != model.PartTypeToolResult {
+				parts = append(parts, part)
+				continue
+			}
+			id := part.ToolCallID()
+			if !isPendingCall(pending, id) || answered[id] {
+				report.DroppedToolResults = append(report.DroppedToolResults, id)
+				continue
+			}
+			answered[id] = true
+			parts = append(parts, part)
+		}
+
+		if len(parts) == 0 {
+			report.DroppedMessageIDs = append(report.DroppedMessageIDs, msg.ID.String())
+			continue
+		}
+		msg.Parts = parts
+		result = append(result, msg)
+
+		// Any non tool-result content closes the window for the tool-calls
+		if isToolResultOnly(parts) {
+			insertAt = len(result)
+		} else {
+			flushPending()
+		}
+	}
+	flushPending()
+
+	return result
+}
+
+func isToolResultOnly(parts []model.Part) bool {
+	for _, part := range parts {
+		if part.Type != model.PartTypeToolResult {
+			return false
+		}
+	}
+	return true
+}
+
+func isPendingCall(pending []model.Part, id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, call := range pending {
+		if call.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// synthesizeToolResultMessage builds a user message carrying an error
+// tool-result for the given tool-call. The message ID is derived from the
+// assistant message and the tool-call ID so repeated reads are stable.
+func synthesizeToolResultMessage(from model.Message, call model.Part) model.Message {
+	part := model.NewToolResultPart(call.ID(), DanglingToolCallPlaceholder)
+	part.Meta[model.MetaKeyIsError] = true
+	if name := call.Name(); name != "" {
+		part.Meta[model.MetaKeyName] = name
+	}
+
+	return model.Message{
+		ID:                       uuid.NewSHA1(from.ID, []byte(call.ID())),
+		SessionID:                from.SessionID,
+		ParentID:                 &from.ID,
+		Role:                     model.RoleUser,
+		Parts:                    []model.Part{part},
+		SessionTaskProcessStatus: from.SessionTaskProcessStatus,
+		CreatedAt:                from.CreatedAt,
+		UpdatedAt:                from.UpdatedAt,
+	}
+}
+
+// mergeConsecutiveRoles merges each message into its predecessor when both
+// have the same role. The merged message keeps the first message's ID and meta.
+func mergeConsecutiveRoles(messages []model.Message, report *RepairReport) []model.Message {
+	result := make([]model.Message, 0, len(messages))
+
+	for _, msg := range messages {
+		if len(result) > 0 && result[len(result)-1].Role == msg.Role {
+			last := &result[len(result)-1]
+			parts := make([]model.Part, 0, len(last.Parts)+len(msg.Parts))
+			parts = append(parts, last.Parts...)
+			parts = append(parts, msg.Parts...)
+			last.Parts = parts
+			report.MergedMessageIDs = append(report.MergedMessageIDs, msg.ID.String())
+			continue
+		}
+		result = append(result, msg)
+	}
+
+	return result
+}
@@ -0,0 +1,168 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairMessages_NoChanges(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("What's the weather?")}, nil),
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewToolCallPart("call_1", "get_weather", `{"city":"Paris"}`)}, nil),
+		createTestMessage(model.RoleUser, []model.Part{model.NewToolResultPart("call_1", "sunny")}, nil),
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewTextPart("It's sunny.")}, nil),
+	}
+
+	repaired, report := RepairMessages(messages, model.FormatOpenAI)
+
+	assert.False(t, report.Changed())
+	assert.Equal(t, messages, repaired)
+}
+
+func TestRepairMessages_SynthesizesDanglingToolCall(t *testing.T) {
+	assistant := createTestMessage(model.RoleAssistant, []model.Part{
+		model.NewToolCallPart("call_1", "get_weather", `{"city":"Paris"}`),
+		model.NewToolCallPart("call_2", "get_time", `{}`),
+	}, nil)
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("Weather and time?")}, nil),
+		assistant,
+		createTestMessage(model.RoleUser, []model.Part{model.NewToolResultPart("call_1", "sunny")}, nil),
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("Still there?")}, nil),
+	}
+
+	repaired, report := RepairMessages(messages, model.FormatOpenAI)
+
+	assert.Equal(t, []string{"call_2"}, report.SynthesizedToolResults)
+	require.Len(t, repaired, 5)
+
+	// Synthesized result goes after the existing result and before the next user turn
+	synthesized := repaired[3]
+	assert.Equal(t, model.RoleUser, synthesized.Role)
+	require.Len(t, synthesized.Parts, 1)
+	assert.Equal(t, model.PartTypeToolResult, synthesized.Parts[0].Type)
+	assert.Equal(t, "call_2", synthesized.Parts[0].ToolCallID())
+	assert.Equal(t, "get_time", synthesized.Parts[0].Name())
+	assert.True(t, synthesized.Parts[0].IsError())
+	assert.Equal(t, DanglingToolCallPlaceholder, synthesized.Parts[0].Text)
+	assert.Equal(t, "Still there?", repaired[4].Parts[0].Text)
+
+	// IDs are stable across repeated repairs
+	again, _ := RepairMessages(messages, model.FormatOpenAI)
+	assert.Equal(t, synthesized.ID, again[3].ID)
+	assert.Equal(t, assistant.ID, *synthesized.ParentID)
+}
+
+func TestRepairMessages_TrailingToolCall(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("Run it")}, nil),
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewToolCallPart("call_1", "run", `{}`)}, nil),
+	}
+
+	repaired, report := RepairMessages(messages, model.FormatAnthropic)
+
+	assert.Equal(t, []string{"call_1"}, report.SynthesizedToolResults)
+	require.Len(t, repaired, 3)
+	assert.Equal(t, "call_1", repaired[2].Parts[0].ToolCallID())
+}
+
+func TestRepairMessages_DropsOrphanedToolResults(t *testing.T) {
+	orphan := createTestMessage(model.RoleUser, []model.Part{model.NewToolResultPart("call_unknown", "stale")}, nil)
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("Hi")}, nil),
+		orphan,
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewToolCallPart("call_1", "run", `{}`)}, nil),
+		createTestMessage(model.RoleUser, []model.Part{
+			model.NewToolResultPart("call_1", "done"),
+			model.NewToolResultPart("call_1", "duplicate"),
+			model.NewTextPart("and then?"),
+		}, nil),
+	}
+
+	repaired, report := RepairMessages(messages, model.FormatOpenAI)
+
+	assert.Equal(t, []string{"call_unknown", "call_1"}, report.DroppedToolResults)
+	assert.Equal(t, []string{orphan.ID.String()}, report.DroppedMessageIDs)
+	assert.Empty(t, report.SynthesizedToolResults)
+	require.Len(t, repaired, 3)
+	require.Len(t, repaired[2].Parts, 2)
+	assert.Equal(t, "done", repaired[2].Parts[0].Text)
+	assert.Equal(t, "and then?", repaired[2].Parts[1].Text)
+
+	// Input is left untouched
+	assert.Len(t, messages[3].Parts, 3)
+}
+
+func TestRepairMessages_LateResultIsOrphaned(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewToolCallPart("call_1", "run", `{}`)}, nil),
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewTextPart("moving on")}, nil),
+		createTestMessage(model.RoleUser, []model.Part{model.NewToolResultPart("call_1", "late")}, nil),
+	}
+
+	repaired, report := RepairMessages(messages, model.FormatOpenAI)
+
+	assert.Equal(t, []string{"call_1"}, report.SynthesizedToolResults)
+	assert.Equal(t, []string{"call_1"}, report.DroppedToolResults)
+	require.Len(t, repaired, 3)
+	assert.Equal(t, model.RoleAssistant, repaired[0].Role)
+	assert.True(t, repaired[1].Parts[0].IsError())
+	assert.Equal(t, "moving on", repaired[2].Parts[0].Text)
+}
+
+func TestRepairMessages_MergesConsecutiveRoles(t *testing.T) {
+	first := createTestMessage(model.RoleAssistant, []model.Part{
+		model.NewToolCallPart("call_1", "a", `{}`),
+		model.NewToolCallPart("call_2", "b", `{}`),
+	}, nil)
+	result1 := createTestMessage(model.RoleUser, []model.Part{model.NewToolResultPart("call_1", "one")}, nil)
+	result2 := createTestMessage(model.RoleUser, []model.Part{model.NewToolResultPart("call_2", "two")}, nil)
+	messages := []model.Message{
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("go")}, nil),
+		first,
+		result1,
+		result2,
+	}
+
+	t.Run("anthropic merges", func(t *testing.T) {
+		repaired, report := RepairMessages(messages, model.FormatAnthropic)
+
+		assert.Equal(t, []string{result2.ID.String()}, report.MergedMessageIDs)
+		require.Len(t, repaired, 3)
+		assert.Equal(t, result1.ID, repaired[2].ID)
+		require.Len(t, repaired[2].Parts, 2)
+
+		converted, err := (&AnthropicConverter{}).Convert(repaired, nil)
+		require.NoError(t, err)
+		params := converted.([]anthropic.MessageParam)
+		require.Len(t, params, 3)
+		assert.Len(t, params[2].Content, 2)
+	})
+
+	t.Run("openai keeps separate tool messages", func(t *testing.T) {
+		repaired, report := RepairMessages(messages, model.FormatOpenAI)
+
+		assert.False(t, report.Changed())
+		assert.Len(t, repaired, 4)
+	})
+}
+
+func TestRepairMessages_SynthesizedResultsComeFirstWhenMerged(t *testing.T) {
+	messages := []model.Message{
+		createTestMessage(model.RoleAssistant, []model.Part{model.NewToolCallPart("call_1", "run", `{}`)}, nil),
+		createTestMessage(model.RoleUser, []model.Part{model.NewTextPart("I interrupted you")}, nil),
+	}
+
+	repaired, report := RepairMessages(messages, model.FormatGemini)
+
+	assert.Equal(t, []string{"call_1"}, report.SynthesizedToolResults)
+	assert.Len(t, report.MergedMessageIDs, 1)
+	require.Len(t, repaired, 2)
+	require.Len(t, repaired[1].Parts, 2)
+	assert.Equal(t, model.PartTypeToolResult, repaired[1].Parts[0].Type)
+	assert.Equal(t, model.PartTypeText, repaired[1].Parts[1].Type)
+}
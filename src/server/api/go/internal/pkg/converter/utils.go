@@ -1,29 +1,36 @@
 package converter
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"io"
-	"net/http"
 	"strings"
-	"time"
 
 	"github.com/memodb-io/Acontext/internal/modules/model"
 	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/imagefetch"
 )
 
-// httpClient is a shared HTTP client with a sensible timeout for downloading
-// external resources (images, files). Using a package-level client avoids
-// creating a new transport per request and prevents unbounded hangs on slow or malicious URLs.
-var httpClient = &http.Client{Timeout: 15 * time.Second}
+// imageFetcher downloads external images referenced by URL. It enforces the
+// configured fetch policy (hosts, size, timeout, private-IP blocking) and
+// caches results; SetImageFetcher replaces the default policy at startup.
+var imageFetcher = imagefetch.New(imagefetch.DefaultPolicy(), nil)
 
-// GetAssetURL returns the public URL for a given asset using the provided URL mapping.
+// SetImageFetcher sets the fetcher used by converters to download URL images.
+func SetImageFetcher(f *imagefetch.Fetcher) {
+	if f != nil {
+		imageFetcher = f
+	}
+}
+
+// GetAssetURL returns the public URL for a given asset using the provided URL mapping,
+// keyed by SHA-256 as GetMessages returns it.
 // Returns empty string if asset is nil or not found in the mapping.
 func GetAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) string {
 	if asset == nil {
 		return ""
 	}
-	if publicURL, ok := publicURLs[asset.S3Key]; ok {
+	if publicURL, ok := publicURLs[asset.SHA256]; ok {
 		return publicURL.URL
 	}
 	return ""
@@ -31,29 +38,80 @@ func GetAssetURL(asset *model.Asset, publicURLs map[string]service.PublicURL) st
 
 // DownloadImageAsBase64 downloads an image from the given URL and returns
 // the base64-encoded data and its MIME type.
-// Returns empty strings on any error.
+// Returns empty strings on any error, including URLs rejected by the fetch policy.
 func DownloadImageAsBase64(imageURL string) (base64Data string, mediaType string) {
-	resp, err := httpClient.Get(imageURL)
+	return DownloadMediaAsBase64(imageURL, "image/")
+}
+
+// DownloadMediaAsBase64 is DownloadImageAsBase64 for any media whose MIME
+// type starts with mediaPrefix, e.g. "audio/" or "video/".
+func DownloadMediaAsBase64(mediaURL string, mediaPrefix string) (base64Data string, mediaType string) {
+	media, err := imageFetcher.FetchMedia(context.Background(), mediaURL, mediaPrefix)
 	if err != nil {
 		return "", ""
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", ""
+	return base64.StdEncoding.EncodeToString(media.Data), media.MediaType
+}
+
+// ResolveMediaData returns the base64 data and MIME type of an audio or video
+// part, looking at inline data, data URLs, then the asset or meta URL (downloaded).
+// Returns empty strings when the media is not available inline or by download.
+func ResolveMediaData(part model.Part, publicURLs map[string]service.PublicURL) (base64Data string, mediaType string) {
+	mediaPrefix := part.Type + "/"
+
+	mediaType = part.GetMetaString(model.MetaKeyMediaType)
+	if mediaType == "" && part.Asset != nil {
+		mediaType = part.Asset.MIME
+	}
+	if mediaType == "" && part.Type == model.PartTypeAudio {
+		mediaType = AudioMediaType(part.GetMetaString(model.MetaKeyAudioFormat))
 	}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
+	if data := part.GetMetaString(model.MetaKeyData); data != "" {
+		return data, mediaType
+	}
+
+	mediaURL := GetAssetURL(part.Asset, publicURLs)
+	if mediaURL == "" {
+		mediaURL = part.GetMetaString(model.MetaKeyURL)
+	}
+	if mediaURL == "" {
 		return "", ""
 	}
+	if strings.HasPrefix(mediaURL, "data:") {
+		dataMediaType, data := ParseDataURL(mediaURL)
+		if !strings.HasPrefix(dataMediaType, mediaPrefix) {
+			dataMediaType = mediaType
+		}
+		return data, dataMediaType
+	}
 
-	mediaType = resp.Header.Get("Content-Type")
-	if mediaType == "" {
-		mediaType = "image/png" // default
+	return DownloadMediaAsBase64(mediaURL, mediaPrefix)
+}
+
+// AudioFormat maps an audio MIME type to the OpenAI input_audio format.
+// Returns "" for formats OpenAI does not accept (only wav and mp3).
+func AudioFormat(mediaType string) string {
+	switch strings.ToLower(mediaType) {
+	case "audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave":
+		return "wav"
+	case "audio/mpeg", "audio/mp3", "audio/mpeg3", "audio/x-mpeg-3":
+		return "mp3"
 	}
+	return ""
+}
 
-	return base64.StdEncoding.EncodeToString(data), mediaType
+// AudioMediaType maps an audio format ("wav", "mp3", ...) to a MIME type.
+func AudioMediaType(format string) string {
+	switch strings.ToLower(format) {
+	case "":
+		return ""
+	case "mp3":
+		return "audio/mp3"
+	default:
+		return "audio/" + strings.ToLower(format)
+	}
 }
 
 // ParseDataURL parses a data URL (e.g., "data:image/png;base64,<data>") and
@@ -117,3 +175,61 @@ func ParseToolArgumentsMap(arguments interface{}) map[string]interface{} {
 	}
 	return make(map[string]interface{})
 }
+
+// DegradePartToText renders parts that the target format cannot represent
+// natively (search results, provider-executed tool calls and their results,
+// audio and video) as plain text so the information is not lost.
+// Returns empty string for parts that have no meaningful text form, such as
+// redacted thinking.
+func DegradePartToText(part model.Part) string {
+	switch part.Type {
+	case model.PartTypeSearchResult:
+		var b strings.Builder
+		b.WriteString("[Search result] ")
+		b.WriteString(part.GetMetaString(model.MetaKeyTitle))
+		source := part.GetMetaString(model.MetaKeyURL)
+		if source == "" {
+			source = part.GetMetaString(model.MetaKeySource)
+		}
+		if source != "" {
+			b.WriteString(" (" + source + ")")
+		}
+		if part.Text != "" {
+			b.WriteString("\n" + part.Text)
+		}
+		return b.String()
+	case model.PartTypeAudio, model.PartTypeVideo:
+		label := "[Audio]"
+		if part.Type == model.PartTypeVideo {
+			label = "[Video]"
+		}
+		if part.Filename != "" {
+			label += " " + part.Filename
+		}
+		if transcript := part.GetMetaString(model.MetaKeyTranscript); transcript != "" {
+			return label + " transcript:\n" + transcript
+		}
+		return label + " (not supported by this provider)"
+	case model.PartTypeServerToolCall:
+		return "[Server tool call] " + part.Name() + "(" + part.Arguments() + ")"
+	case model.PartTypeServerToolResult:
+		var b strings.Builder
+		b.WriteString("[Server tool result]")
+		if name := part.Name(); name != "" {
+			b.WriteString(" " + name)
+		}
+		if errorCode := part.GetMetaString(model.MetaKeyErrorCode); errorCode != "" {
+			b.WriteString("\nerror: " + errorCode)
+		}
+		for _, result := range part.Results() {
+			title, _ := result[model.MetaKeyTitle].(string)
+			url, _ := result[model.MetaKeyURL].(string)
+			b.WriteString("\n- " + title)
+			if url != "" {
+				b.WriteString(" (" + url + ")")
+			}
+		}
+		return b.String()
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/memodb-io/Acontext/internal/config"
+	"github.com/memodb-io/Acontext/internal/infra/blob"
+	"github.com/memodb-io/Acontext/internal/infra/blob/blobtest"
+	"github.com/memodb-io/Acontext/internal/modules/model"
+	"github.com/memodb-io/Acontext/internal/modules/repo"
+	"github.com/memodb-io/Acontext/internal/modules/service"
+	"github.com/memodb-io/Acontext/internal/pkg/imagefetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetAssetURL(t *testing.T) {
+	publicURLs := map[string]service.PublicURL{"abc": {URL: "https://example.com/a.png"}}
+
+	assert.Equal(t, "https://example.com/a.png", GetAssetURL(&model.Asset{S3Key: "assets/p/abc.png", SHA256: "abc"}, publicURLs))
+	assert.Equal(t, "", GetAssetURL(&model.Asset{S3Key: "abc", SHA256: "def"}, publicURLs))
+	assert.Equal(t, "", GetAssetURL(nil, publicURLs))
+}
+
+// sessionRepoStub keeps the message stored in memory, for the methods StoreMessage and GetMessages use
+type sessionRepoStub struct {
+	repo.SessionRepo
+	session  *model.Session
+	messages []model.Message
+}
+
+func (r *sessionRepoStub) Get(ctx context.Context, s *model.Session) (*model.Session, error) {
+	return r.session, nil
+}
+
+func (r *sessionRepoStub) GetDisableTaskTracking(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (r *sessionRepoStub) CreateMessageWithAssets(ctx context.Context, msg *model.Message) error {
+	r.messages = append(r.messages, *msg)
+	return nil
+}
+
+func (r *sessionRepoStub) ListAllMessagesBySession(ctx context.Context, sessionID uuid.UUID) ([]model.Message, error) {
+	return append([]model.Message(nil), r.messages...), nil
+}
+
+type assetReferenceRepoStub struct {
+	repo.AssetReferenceRepo
+}
+
+func (assetReferenceRepoStub) IncrementAssetRef(ctx context.Context, projectID uuid.UUID, asset model.Asset) error {
+	return nil
+}
+
+func TestPrefetchedImageURL(t *testing.T) {
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(img.Bytes())
+	}))
+	defer origin.Close()
+	originURL := origin.URL + "/cat.png"
+
+	s3, _ := blobtest.New(t)
+	projectID := uuid.New()
+	sessions := &sessionRepoStub{session: &model.Session{ID: uuid.New(), ProjectID: projectID}}
+	policy := imagefetch.DefaultPolicy()
+	policy.BlockPrivateIPs = false
+	fetcher := imagefetch.New(policy, nil)
+	svc := service.NewSessionService(sessions, assetReferenceRepoStub{}, nil, zap.NewNop(), s3, nil,
+		&config.Config{ImageFetch: config.ImageFetchCfg{PrefetchOnStore: true}}, nil, fetcher)
+	defer func(f *imagefetch.Fetcher) { imageFetcher = f }(imageFetcher)
+	imageFetcher = fetcher
+
+	msg, err := svc.StoreMessage(context.Background(), service.StoreMessageInput{
+		ProjectID: projectID,
+		SessionID: sessions.session.ID,
+		MessageID: uuid.New(),
+		Role:      model.RoleUser,
+		Format:    model.FormatOpenAI,
+		Parts: []service.PartIn{{
+			Type: model.PartTypeImage,
+			Meta: map[string]interface{}{model.MetaKeyURL: originURL},
+		}},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, msg.Parts[0].Asset, "the image is prefetched")
+	origin.Close() // Conversions must not download the original again
+
+	out, err := svc.GetMessages(context.Background(), service.GetMessagesInput{
+		SessionID:          sessions.session.ID,
+		WithAssetPublicURL: true,
+		AssetExpire:        time.Hour,
+	})
+	require.NoError(t, err)
+	presigned := out.PublicURLs[msg.Parts[0].Asset.SHA256].URL
+	require.Contains(t, presigned, msg.Parts[0].Asset.S3Key)
+
+	for _, format := range []model.MessageFormat{model.FormatOpenAI, model.FormatAnthropic, model.FormatGemini} {
+		t.Run(string(format), func(t *testing.T) {
+			converted, err := GetConvertedMessagesOutput(out.Items, format, out.PublicURLs, "", false, 0, "")
+			require.NoError(t, err)
+			var body strings.Builder
+			enc := json.NewEncoder(&body)
+			enc.SetEscapeHTML(false)
+			require.NoError(t, enc.Encode(converted.Items))
+			if format == model.FormatOpenAI {
+				assert.Contains(t, body.String(), presigned)
+			} else {
+				// Anthropic and Gemini inline the image, downloaded from the presigned URL
+				assert.Contains(t, body.String(), base64.StdEncoding.EncodeToString(img.Bytes()))
+			}
+			assert.NotContains(t, body.String(), originURL)
+		})
+	}
+}
+
+func TestEncryptedImageInlined(t *testing.T) {
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	encoded := base64.StdEncoding.EncodeToString(img.Bytes())
+
+	s3, srv := blobtest.New(t)
+	require.NoError(t, s3.SetEncryption(config.S3Cfg{
+		EncryptionKeySource: blob.KeySourceMaster,
+		EncryptionMasterKey: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+	}))
+	projectID := uuid.New()
+	sessions := &sessionRepoStub{session: &model.Session{ID: uuid.New(), ProjectID: projectID}}
+	svc := service.NewSessionService(sessions, assetReferenceRepoStub{}, nil, zap.NewNop(), s3, nil, &config.Config{}, nil, nil)
+	defer func(f *imagefetch.Fetcher) { imageFetcher = f }(imageFetcher)
+	imageFetcher = imagefetch.New(imagefetch.DefaultPolicy(), nil)
+
+	msg, err := svc.StoreMessage(context.Background(), service.StoreMessageInput{
+		ProjectID: projectID,
+		SessionID: sessions.session.ID,
+		MessageID: uuid.New(),
+		Role:      model.RoleUser,
+		Format:    model.FormatOpenAI,
+		Parts:     []service.PartIn{{Type: model.PartTypeImage, FileField: "img"}},
+		Files:     map[string]*multipart.FileHeader{"img": fileHeader(t, "cat.png", img.Bytes())},
+	})
+	require.NoError(t, err)
+	asset := msg.Parts[0].Asset
+	require.NotNil(t, asset)
+	assert.NotContains(t, string(srv.Object(asset.S3Key).Data), string(img.Bytes()), "the image is stored encrypted")
+	parts := srv.Object(msg.PartsAssetMeta.Data().S3Key)
+	assert.NotContains(t, string(parts.Data), asset.SHA256, "the parts are stored encrypted")
+
+	t.Run("acontext", func(t *testing.T) {
+		out, err := svc.GetMessages(context.Background(), service.GetMessagesInput{
+			SessionID:          sessions.session.ID,
+			WithAssetPublicURL: true,
+			AssetExpire:        time.Hour,
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, out.PublicURLs, asset.SHA256)
+		assert.Equal(t, service.PublicURLEncrypted, out.PublicURLsUnavailable[asset.SHA256])
+	})
+
+	out, err := svc.GetMessages(context.Background(), service.GetMessagesInput{
+		SessionID:             sessions.session.ID,
+		WithAssetPublicURL:    true,
+		AssetExpire:           time.Hour,
+		InlineEncryptedAssets: true,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, out.PublicURLsUnavailable)
+
+	for _, format := range []model.MessageFormat{model.FormatOpenAI, model.FormatAnthropic, model.FormatGemini} {
+		t.Run(string(format), func(t *testing.T) {
+			converted, err := GetConvertedMessagesOutput(out.Items, format, out.PublicURLs, "", false, 0, "")
+			require.NoError(t, err)
+			body, err := json.Marshal(converted.Items)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), encoded, "the decrypted image is inlined")
+		})
+	}
+}
+
+func fileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	h.Set("Content-Type", "image/png")
+	part, err := writer.CreatePart(h)
+	require.NoError(t, err)
+	_, _ = part.Write(content)
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(10 << 20)
+	require.NoError(t, err)
+	return form.File["file"][0]
+}
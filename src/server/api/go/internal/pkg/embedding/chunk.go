@@ -0,0 +1,97 @@
+package embedding
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultChunkSize is the chunk length in characters when none is configured
+	DefaultChunkSize = 1000
+	// DefaultChunkOverlap is the number of characters consecutive chunks share when none is configured
+	DefaultChunkOverlap = 200
+)
+
+// Chunk is a piece of a text. Offsets count characters (runes), End excluded;
+// lines are 1-based and inclusive.
+type Chunk struct {
+	Text      string
+	Start     int
+	End       int
+	StartLine int
+	EndLine   int
+}
+
+// Split cuts text into chunks of at most size characters, consecutive chunks
+// sharing about overlap characters. Cuts prefer line breaks, then spaces, in
+// the last fifth of a chunk. Blank chunks are dropped.
+func Split(text string, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	runes := []rune(text)
+	// lineAt[i] is the line of rune i
+	lineAt := make([]int, len(runes)+1)
+	line := 1
+	for i, r := range runes {
+		lineAt[i] = line
+		if r == '\n' {
+			line++
+		}
+	}
+	lineAt[len(runes)] = line
+
+	var chunks []Chunk
+	for start := 0; start < len(runes); {
+		end := min(start+size, len(runes))
+		if end < len(runes) {
+			end = cutPoint(runes, start, end, size)
+		}
+
+		piece := string(runes[start:end])
+		if strings.TrimSpace(piece) != "" {
+			// A chunk ending with a line break ends on the line before it
+			last := max(end-1, start)
+			chunks = append(chunks, Chunk{
+				Text:      piece,
+				Start:     start,
+				End:       end,
+				StartLine: lineAt[start],
+				EndLine:   lineAt[last],
+			})
+		}
+
+		if end == len(runes) {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+// cutPoint moves end back to just after a line break or space in the last fifth of the window.
+func cutPoint(runes []rune, start, end, size int) int {
+	floor := end - size/5
+	if floor <= start {
+		return end
+	}
+	for i := end - 1; i >= floor; i-- {
+		if runes[i] == '\n' {
+			return i + 1
+		}
+	}
+	for i := end - 1; i >= floor; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i + 1
+		}
+	}
+	return end
+}
@@ -0,0 +1,45 @@
+package embedding
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	t.Run("short text is one chunk", func(t *testing.T) {
+		chunks := Split("line one\nline two\n", 100, 10)
+		assert.Equal(t, []Chunk{{Text: "line one\nline two\n", Start: 0, End: 18, StartLine: 1, EndLine: 2}}, chunks)
+	})
+
+	t.Run("cuts on line breaks with overlap", func(t *testing.T) {
+		text := strings.Repeat("abcdefghi\n", 10) // 100 runes, 10 lines
+		chunks := Split(text, 45, 10)
+
+		assert.Equal(t, 0, chunks[0].Start)
+		assert.Equal(t, 40, chunks[0].End)
+		assert.Equal(t, 1, chunks[0].StartLine)
+		assert.Equal(t, 4, chunks[0].EndLine)
+		assert.Equal(t, 30, chunks[1].Start)
+		assert.Equal(t, 4, chunks[1].StartLine)
+		// Offsets index the text, and the last chunk reaches its end
+		for _, c := range chunks {
+			assert.Equal(t, text[c.Start:c.End], c.Text)
+		}
+		assert.Equal(t, len(text), chunks[len(chunks)-1].End)
+	})
+
+	t.Run("offsets count characters", func(t *testing.T) {
+		chunks := Split("héllo wörld", 6, 0)
+		assert.Equal(t, "héllo ", chunks[0].Text)
+		assert.Equal(t, 6, chunks[0].End)
+		assert.Equal(t, "wörld", chunks[1].Text)
+		assert.Equal(t, 11, chunks[1].End)
+	})
+
+	t.Run("blank text has no chunk", func(t *testing.T) {
+		assert.Empty(t, Split("  \n\n ", 100, 0))
+		assert.Empty(t, Split("", 100, 0))
+	})
+}
@@ -0,0 +1,30 @@
+package embedding
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder turns texts into embedding vectors.
+type Embedder interface {
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model identifies the embedding space. Vectors of different models must never be compared.
+	Model() string
+}
+
+// normalize scales v to unit length, so cosine similarity equals the dot product.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
@@ -0,0 +1,58 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// DefaultHashingDimensions is the vector size of the hashing embedder when none is configured
+const DefaultHashingDimensions = 256
+
+// Hashing is a deterministic embedder that needs no model nor network: every
+// word is hashed to a signed dimension (the "hashing trick"). It only captures
+// shared vocabulary, which is enough for tests and offline deployments.
+type Hashing struct {
+	dims int
+}
+
+// NewHashing creates a hashing embedder producing vectors of dims dimensions.
+func NewHashing(dims int) *Hashing {
+	if dims <= 0 {
+		dims = DefaultHashingDimensions
+	}
+	return &Hashing{dims: dims}
+}
+
+func (h *Hashing) Model() string {
+	return fmt.Sprintf("hashing-%d", h.dims)
+}
+
+func (h *Hashing) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = h.embed(text)
+	}
+	return out, nil
+}
+
+func (h *Hashing) embed(text string) []float32 {
+	v := make([]float32, h.dims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(word))
+		sum := hash.Sum64()
+		// The top bit picks the sign, so collisions cancel out instead of piling up
+		if sum>>63 == 1 {
+			v[sum%uint64(h.dims)]--
+		} else {
+			v[sum%uint64(h.dims)]++
+		}
+	}
+	return normalize(v)
+}
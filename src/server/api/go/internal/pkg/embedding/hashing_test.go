@@ -0,0 +1,43 @@
+package embedding
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func TestHashing(t *testing.T) {
+	e := NewHashing(0)
+	assert.Equal(t, "hashing-256", e.Model())
+
+	vectors, err := e.Embed(context.Background(), []string{
+		"Deploy the API server with Docker",
+		"how to deploy the api server",
+		"chocolate cake recipe",
+		"",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, vectors, 4)
+	assert.Len(t, vectors[0], DefaultHashingDimensions)
+
+	// Unit length, deterministic
+	assert.InDelta(t, 1.0, math.Sqrt(dot(vectors[0], vectors[0])), 1e-5)
+	again, _ := e.Embed(context.Background(), []string{"Deploy the API server with Docker"})
+	assert.Equal(t, vectors[0], again[0])
+
+	// Shared vocabulary ranks higher
+	assert.Greater(t, dot(vectors[0], vectors[1]), dot(vectors[0], vectors[2]))
+
+	// Empty text yields a zero vector
+	assert.Equal(t, 0.0, dot(vectors[3], vectors[3]))
+}
@@ -0,0 +1,143 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	// DefaultOpenAIBaseURL is the API root used when none is configured
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	// DefaultOpenAIModel is the model used when none is configured
+	DefaultOpenAIModel = "text-embedding-3-small"
+	// openAIBatchSize bounds the number of inputs sent in one request
+	openAIBatchSize = 64
+)
+
+// OpenAIConfig configures an OpenAI-compatible embedding API.
+type OpenAIConfig struct {
+	// BaseURL is the API root, e.g. "https://api.openai.com/v1"; requests go to BaseURL + "/embeddings"
+	BaseURL string
+	APIKey  string
+	Model   string
+	// Dimensions asks models supporting it for shorter vectors; zero keeps the model's size
+	Dimensions int
+	Timeout    time.Duration
+}
+
+// OpenAI calls the /embeddings endpoint of an OpenAI-compatible API.
+type OpenAI struct {
+	cfg    OpenAIConfig
+	client *http.Client
+}
+
+// NewOpenAI creates an embedder for an OpenAI-compatible API.
+func NewOpenAI(cfg OpenAIConfig) *OpenAI {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultOpenAIBaseURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.Model == "" {
+		cfg.Model = DefaultOpenAIModel
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &OpenAI{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (e *OpenAI) Model() string {
+	if e.cfg.Dimensions > 0 {
+		return fmt.Sprintf("%s-%d", e.cfg.Model, e.cfg.Dimensions)
+	}
+	return e.cfg.Model
+}
+
+type openAIRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (e *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		end := min(start+openAIBatchSize, len(texts))
+		vectors, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+func (e *OpenAI) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := sonic.Marshal(openAIRequest{
+		Model:          e.cfg.Model,
+		Input:          texts,
+		Dimensions:     e.cfg.Dimensions,
+		EncodingFormat: "float",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal embedding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if len(raw) > 512 {
+			raw = raw[:512]
+		}
+		return nil, fmt.Errorf("embedding request: unexpected status %d: %s", resp.StatusCode, raw)
+	}
+
+	var parsed openAIResponse
+	if err := sonic.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("decode embedding response: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding response: got %d vectors for %d inputs", len(parsed.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding response: index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
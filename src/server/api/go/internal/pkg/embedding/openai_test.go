@@ -0,0 +1,65 @@
+package embedding
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAI_Embed(t *testing.T) {
+	var requests []openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+
+		body, _ := io.ReadAll(r.Body)
+		var req openAIRequest
+		assert.NoError(t, sonic.Unmarshal(body, &req))
+		requests = append(requests, req)
+
+		// Answer out of order: the index decides the position
+		data := make([]map[string]interface{}, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{float32(len(req.Input[i])), 1}})
+		}
+		out, _ := sonic.Marshal(map[string]interface{}{"data": data})
+		_, _ = w.Write(out)
+	}))
+	defer server.Close()
+
+	e := NewOpenAI(OpenAIConfig{BaseURL: server.URL + "/v1/", APIKey: "sk-test", Model: "embed-small", Dimensions: 2})
+	assert.Equal(t, "embed-small-2", e.Model())
+
+	texts := make([]string, openAIBatchSize+1)
+	for i := range texts {
+		texts[i] = string(make([]byte, i))
+	}
+	vectors, err := e.Embed(context.Background(), texts)
+	assert.NoError(t, err)
+	assert.Len(t, vectors, len(texts))
+	assert.Equal(t, []float32{3, 1}, vectors[3])
+	assert.Equal(t, []float32{float32(openAIBatchSize), 1}, vectors[openAIBatchSize])
+
+	// Split into batches, with the requested model and dimensions
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "embed-small", requests[0].Model)
+	assert.Equal(t, 2, requests[0].Dimensions)
+}
+
+func TestOpenAI_EmbedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"bad key"}}`))
+	}))
+	defer server.Close()
+
+	e := NewOpenAI(OpenAIConfig{BaseURL: server.URL})
+	_, err := e.Embed(context.Background(), []string{"hello"})
+	assert.ErrorContains(t, err, "unexpected status 401")
+	assert.ErrorContains(t, err, "bad key")
+}
@@ -0,0 +1,53 @@
+package imagefetch
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis key prefix for fetched images, followed by the URL hash
+const redisKeyPrefixImage = "image:fetch:"
+
+// RedisCache caches fetched images in Redis. Errors are treated as cache
+// misses: the cache never fails a fetch.
+type RedisCache struct {
+	client        *redis.Client
+	ttl           time.Duration
+	maxEntryBytes int64
+}
+
+// NewRedisCache creates a Redis-backed Cache. Images larger than
+// maxEntryBytes are not cached; zero means no limit.
+func NewRedisCache(client *redis.Client, ttl time.Duration, maxEntryBytes int64) *RedisCache {
+	return &RedisCache{client: client, ttl: ttl, maxEntryBytes: maxEntryBytes}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (*Image, bool) {
+	val, err := c.client.Get(ctx, redisKeyPrefixImage+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var img Image
+	if err := sonic.Unmarshal(val, &img); err != nil {
+		return nil, false
+	}
+
+	return &img, true
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, img *Image) {
+	if c.maxEntryBytes > 0 && int64(len(img.Data)) > c.maxEntryBytes {
+		return
+	}
+
+	val, err := sonic.Marshal(img)
+	if err != nil {
+		return
+	}
+
+	_ = c.client.Set(ctx, redisKeyPrefixImage+key, val, c.ttl).Err()
+}
@@ -0,0 +1,293 @@
+package imagefetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultMaxBytes is the default maximum size of a fetched image (20MB)
+	DefaultMaxBytes = 20 * 1024 * 1024
+	// DefaultTimeout is the default timeout for a whole fetch, including redirects
+	DefaultTimeout = 15 * time.Second
+	// maxRedirects bounds redirect chains; every hop is checked against the policy
+	maxRedirects = 5
+)
+
+var (
+	// ErrHostNotAllowed is returned when the URL host is denied by the policy.
+	ErrHostNotAllowed = errors.New("image host is not allowed")
+	// ErrPrivateAddress is returned when the URL resolves to a private or local address.
+	ErrPrivateAddress = errors.New("image host resolves to a private address")
+	// ErrTooLarge is returned when the image exceeds the configured maximum size.
+	ErrTooLarge = errors.New("image exceeds maximum size")
+)
+
+// Policy controls which remote images may be fetched.
+type Policy struct {
+	// AllowHosts, when non-empty, restricts fetching to these hosts.
+	// An entry "example.com" matches the host and its subdomains.
+	AllowHosts []string
+	// DenyHosts are never fetched, with the same matching rules. Deny wins over allow.
+	DenyHosts []string
+	// MaxBytes is the maximum accepted image size. Zero means DefaultMaxBytes.
+	MaxBytes int64
+	// Timeout bounds the whole fetch. Zero means DefaultTimeout.
+	Timeout time.Duration
+	// BlockPrivateIPs rejects loopback, private, link-local and other
+	// non-public addresses to prevent SSRF. The check is done on the
+	// addresses actually dialed, so DNS rebinding does not bypass it.
+	BlockPrivateIPs bool
+	// TrustedHosts bypass the host lists and the private-IP check, e.g. the
+	// object storage endpoint serving presigned asset URLs. Exact match only.
+	TrustedHosts []string
+}
+
+// DefaultPolicy returns the policy used when none is configured:
+// any public host, DefaultMaxBytes and DefaultTimeout.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxBytes:        DefaultMaxBytes,
+		Timeout:         DefaultTimeout,
+		BlockPrivateIPs: true,
+	}
+}
+
+// Image is a fetched image (or other media fetched with FetchMedia).
+type Image struct {
+	Data      []byte `json:"data"`
+	MediaType string `json:"media_type"`
+}
+
+// Cache stores fetched images by key. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(ctx context.Context, key string) (*Image, bool)
+	Set(ctx context.Context, key string, img *Image)
+}
+
+// Fetcher downloads remote images according to a Policy.
+type Fetcher struct {
+	policy Policy
+	client *http.Client
+	cache  Cache
+}
+
+// New creates a Fetcher. cache may be nil to disable caching.
+func New(policy Policy, cache Cache) *Fetcher {
+	if policy.MaxBytes <= 0 {
+		policy.MaxBytes = DefaultMaxBytes
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = DefaultTimeout
+	}
+
+	f := &Fetcher{policy: policy, cache: cache}
+
+	dialer := &net.Dialer{Timeout: policy.Timeout}
+	guardedDialer := &net.Dialer{Timeout: policy.Timeout}
+	if policy.BlockPrivateIPs {
+		guardedDialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		}
+	}
+	dialContext := func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && f.isTrusted(host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return guardedDialer.DialContext(ctx, network, address)
+	}
+
+	f.client = &http.Client{
+		Timeout: policy.Timeout,
+		Transport: &http.Transport{
+			// No proxy: the dialed address must be the image host for the IP check to hold
+			Proxy:                 nil,
+			DialContext:           dialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: policy.Timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.CheckURL(req.URL.String())
+		},
+	}
+
+	return f
+}
+
+// CacheKey returns the cache key of an image URL.
+func CacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckURL validates the scheme and host of rawURL against the policy without fetching it.
+func (f *Fetcher) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid image url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported image url scheme %q", u.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("invalid image url: missing host")
+	}
+	if f.isTrusted(host) {
+		return nil
+	}
+	if matchHost(host, f.policy.DenyHosts) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	if len(f.policy.AllowHosts) > 0 && !matchHost(host, f.policy.AllowHosts) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	if f.policy.BlockPrivateIPs {
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+		if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+	}
+
+	return nil
+}
+
+// Fetch downloads the image at rawURL, serving it from the cache when possible.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Image, error) {
+	return f.FetchMedia(ctx, rawURL, "image/")
+}
+
+// FetchMedia downloads the media at rawURL under the same policy as Fetch,
+// accepting only content whose media type starts with mediaPrefix ("audio/", "video/", ...).
+func (f *Fetcher) FetchMedia(ctx context.Context, rawURL string, mediaPrefix string) (*Image, error) {
+	if err := f.CheckURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	// Trusted hosts serve presigned, short-lived URLs: caching them would never hit
+	key := CacheKey(rawURL)
+	useCache := f.cache != nil
+	if u, err := url.Parse(rawURL); err == nil && f.isTrusted(u.Hostname()) {
+		useCache = false
+	}
+	if useCache {
+		if img, ok := f.cache.Get(ctx, key); ok && strings.HasPrefix(img.MediaType, mediaPrefix) {
+			return img, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build image request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch image: unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.policy.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
+	}
+
+	// Read one byte past the limit to detect oversized bodies without a Content-Length
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.policy.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	if int64(len(data)) > f.policy.MaxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, f.policy.MaxBytes)
+	}
+
+	mediaType := resp.Header.Get("Content-Type")
+	if idx := strings.Index(mediaType, ";"); idx >= 0 {
+		mediaType = mediaType[:idx]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+	if !strings.HasPrefix(mediaType, mediaPrefix) {
+		mediaType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mediaType, mediaPrefix) {
+		return nil, fmt.Errorf("fetch media: unexpected content type %q", mediaType)
+	}
+
+	img := &Image{Data: data, MediaType: mediaType}
+	if useCache {
+		f.cache.Set(ctx, key, img)
+	}
+
+	return img, nil
+}
+
+// isTrusted reports whether host is one of the policy's trusted hosts.
+func (f *Fetcher) isTrusted(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, trusted := range f.policy.TrustedHosts {
+		if strings.EqualFold(strings.TrimSuffix(trusted, "."), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost reports whether host equals one of patterns or is a subdomain of one.
+func matchHost(host string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(p), "*."), "."))
+		if p == "" {
+			continue
+		}
+		if host == p || strings.HasSuffix(host, "."+p) {
+			return true
+		}
+	}
+	return false
+}
+
+// cgnatRange is the carrier-grade NAT shared address space (RFC 6598).
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicIP reports whether ip is a globally routable unicast address.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if cgnatRange.Contains(ip) {
+		return false
+	}
+	return true
+}
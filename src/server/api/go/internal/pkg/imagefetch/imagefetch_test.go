@@ -0,0 +1,170 @@
+package imagefetch
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough for http.DetectContentType to report image/png
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type memoryCache struct {
+	mu    sync.Mutex
+	items map[string]*Image
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) (*Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img, ok := c.items[key]
+	return img, ok
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, img *Image) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = img
+}
+
+func newImageServer(t *testing.T, hits *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			*hits++
+		}
+		switch r.URL.Path {
+		case "/image.png":
+			w.Write(pngHeader)
+		case "/typed.png":
+			w.Header().Set("Content-Type", "image/png; charset=binary")
+			w.Write(pngHeader)
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("not an image"))
+		case "/large.png":
+			w.Write(append(pngHeader, make([]byte, 1024)...))
+		case "/redirect":
+			http.Redirect(w, r, "http://localhost/image.png", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	srv := newImageServer(t, nil)
+	f := New(Policy{}, nil)
+
+	img, err := f.Fetch(context.Background(), srv.URL+"/image.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.MediaType)
+	assert.Equal(t, pngHeader, img.Data)
+
+	img, err = f.Fetch(context.Background(), srv.URL+"/typed.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", img.MediaType)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/text")
+	assert.Error(t, err)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/missing")
+	assert.Error(t, err)
+}
+
+func TestFetcher_MaxBytes(t *testing.T) {
+	srv := newImageServer(t, nil)
+	f := New(Policy{MaxBytes: 512}, nil)
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/large.png")
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/image.png")
+	assert.NoError(t, err)
+}
+
+func TestFetcher_BlockPrivateIPs(t *testing.T) {
+	srv := newImageServer(t, nil)
+	f := New(DefaultPolicy(), nil)
+
+	_, err := f.Fetch(context.Background(), srv.URL+"/image.png")
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+
+	for _, rawURL := range []string{
+		"http://localhost/a.png",
+		"http://10.0.0.1/a.png",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/a.png",
+		"http://100.64.0.1/a.png",
+	} {
+		assert.ErrorIs(t, f.CheckURL(rawURL), ErrPrivateAddress, rawURL)
+	}
+	assert.NoError(t, f.CheckURL("https://example.com/a.png"))
+}
+
+func TestFetcher_TrustedHosts(t *testing.T) {
+	srv := newImageServer(t, nil)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	policy := DefaultPolicy()
+	policy.TrustedHosts = []string{u.Hostname()}
+	f := New(policy, nil)
+
+	_, err = f.Fetch(context.Background(), srv.URL+"/image.png")
+	require.NoError(t, err)
+
+	// Redirects leaving the trusted host are checked again
+	_, err = f.Fetch(context.Background(), srv.URL+"/redirect")
+	assert.ErrorIs(t, err, ErrPrivateAddress)
+}
+
+func TestFetcher_HostLists(t *testing.T) {
+	f := New(Policy{
+		AllowHosts: []string{"images.example.com", "*.cdn.example.net"},
+		DenyHosts:  []string{"private.images.example.com"},
+	}, nil)
+
+	assert.NoError(t, f.CheckURL("https://images.example.com/a.png"))
+	assert.NoError(t, f.CheckURL("https://eu.images.example.com/a.png"))
+	assert.NoError(t, f.CheckURL("https://a.cdn.example.net/a.png"))
+	assert.ErrorIs(t, f.CheckURL("https://private.images.example.com/a.png"), ErrHostNotAllowed)
+	assert.ErrorIs(t, f.CheckURL("https://example.com/a.png"), ErrHostNotAllowed)
+	assert.ErrorIs(t, f.CheckURL("https://badimages.example.com/a.png"), ErrHostNotAllowed)
+	assert.Error(t, f.CheckURL("file:///etc/passwd"))
+	assert.Error(t, f.CheckURL("ftp://images.example.com/a.png"))
+}
+
+func TestFetcher_Cache(t *testing.T) {
+	hits := 0
+	srv := newImageServer(t, &hits)
+	cache := &memoryCache{items: map[string]*Image{}}
+	f := New(Policy{}, cache)
+
+	for range 3 {
+		img, err := f.Fetch(context.Background(), srv.URL+"/image.png")
+		require.NoError(t, err)
+		assert.Equal(t, pngHeader, img.Data)
+	}
+	assert.Equal(t, 1, hits)
+	assert.Contains(t, cache.items, CacheKey(srv.URL+"/image.png"))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	assert.True(t, IsPublicIP(net.ParseIP("8.8.8.8")))
+	assert.True(t, IsPublicIP(net.ParseIP("2606:4700:4700::1111")))
+	assert.False(t, IsPublicIP(net.ParseIP("127.0.0.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("192.168.1.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("::ffff:10.0.0.1")))
+	assert.False(t, IsPublicIP(net.ParseIP("fd00::1")))
+	assert.False(t, IsPublicIP(net.ParseIP("0.0.0.0")))
+}
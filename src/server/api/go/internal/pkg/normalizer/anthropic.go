@@ -64,6 +64,17 @@ func normalizeAnthropicContentBlock(blockUnion anthropic.ContentBlockParamUnion)
 			}
 		}
 
+		if len(blockUnion.OfText.Citations) > 0 {
+			citations, err := extractAnthropicCitations(blockUnion.OfText.Citations)
+			if err != nil {
+				return service.PartIn{}, err
+			}
+			if part.Meta == nil {
+				part.Meta = map[string]interface{}{}
+			}
+			part.Meta[model.MetaKeyCitations] = citations
+		}
+
 		return part, nil
 	} else if blockUnion.OfImage != nil {
 		meta := map[string]interface{}{}
@@ -159,12 +170,116 @@ func normalizeAnthropicContentBlock(blockUnion anthropic.ContentBlockParamUnion)
 				model.MetaKeySignature: blockUnion.OfThinking.Signature,
 			},
 		}, nil
+	} else if blockUnion.OfRedactedThinking != nil {
+		return service.PartIn{
+			Type: model.PartTypeRedactedThinking,
+			Meta: map[string]interface{}{
+				model.MetaKeyData: blockUnion.OfRedactedThinking.Data,
+			},
+		}, nil
+	} else if blockUnion.OfSearchResult != nil {
+		var contentText string
+		for i, content := range blockUnion.OfSearchResult.Content {
+			if i > 0 {
+				contentText += "\n"
+			}
+			contentText += content.Text
+		}
+
+		meta := map[string]interface{}{
+			model.MetaKeySource: blockUnion.OfSearchResult.Source,
+			model.MetaKeyTitle:  blockUnion.OfSearchResult.Title,
+		}
+		if !param.IsOmitted(blockUnion.OfSearchResult.Citations.Enabled) {
+			meta[model.MetaKeyCitationsEnabled] = blockUnion.OfSearchResult.Citations.Enabled.Value
+		}
+		if blockUnion.OfSearchResult.CacheControl.Type != "" {
+			meta[model.MetaKeyCacheControl] = ExtractAnthropicCacheControl(blockUnion.OfSearchResult.CacheControl)
+		}
+
+		return service.PartIn{
+			Type: model.PartTypeSearchResult,
+			Text: contentText,
+			Meta: meta,
+		}, nil
+	} else if blockUnion.OfServerToolUse != nil {
+		argsBytes, err := json.Marshal(blockUnion.OfServerToolUse.Input)
+		if err != nil {
+			return service.PartIn{}, fmt.Errorf("failed to marshal server tool input: %w", err)
+		}
+
+		meta := map[string]interface{}{
+			model.MetaKeyID:         blockUnion.OfServerToolUse.ID,
+			model.MetaKeyName:       string(blockUnion.OfServerToolUse.Name),
+			model.MetaKeyArguments:  string(argsBytes),
+			model.MetaKeySourceType: "server_tool_use",
+		}
+
+		if blockUnion.OfServerToolUse.CacheControl.Type != "" {
+			meta[model.MetaKeyCacheControl] = ExtractAnthropicCacheControl(blockUnion.OfServerToolUse.CacheControl)
+		}
+
+		return service.PartIn{
+			Type: model.PartTypeServerToolCall,
+			Meta: meta,
+		}, nil
+	} else if blockUnion.OfWebSearchToolResult != nil {
+		block := blockUnion.OfWebSearchToolResult
+		meta := map[string]interface{}{
+			model.MetaKeyToolCallID: block.ToolUseID,
+			model.MetaKeyName:       "web_search",
+		}
+
+		if block.Content.OfRequestWebSearchToolResultError != nil {
+			meta[model.MetaKeyErrorCode] = string(block.Content.OfRequestWebSearchToolResultError.ErrorCode)
+		} else {
+			results := make([]interface{}, 0, len(block.Content.OfWebSearchToolResultBlockItem))
+			for _, item := range block.Content.OfWebSearchToolResultBlockItem {
+				result := map[string]interface{}{
+					model.MetaKeyTitle:            item.Title,
+					model.MetaKeyURL:              item.URL,
+					model.MetaKeyEncryptedContent: item.EncryptedContent,
+				}
+				if !param.IsOmitted(item.PageAge) {
+					result[model.MetaKeyPageAge] = item.PageAge.Value
+				}
+				results = append(results, result)
+			}
+			meta[model.MetaKeyResults] = results
+		}
+
+		if block.CacheControl.Type != "" {
+			meta[model.MetaKeyCacheControl] = ExtractAnthropicCacheControl(block.CacheControl)
+		}
+
+		return service.PartIn{
+			Type: model.PartTypeServerToolResult,
+			Meta: meta,
+		}, nil
 	}
 
 	// Skip unsupported block types
 	return service.PartIn{}, nil
 }
 
+// extractAnthropicCitations converts text block citations into their JSON
+// object form, keeping the native Anthropic type and fields.
+func extractAnthropicCitations(citations []anthropic.TextCitationParamUnion) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(citations))
+	for _, citation := range citations {
+		citationBytes, err := json.Marshal(citation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal citation: %w", err)
+		}
+		var citationMap map[string]interface{}
+		if err := json.Unmarshal(citationBytes, &citationMap); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal citation: %w", err)
+		}
+		result = append(result, citationMap)
+	}
+	return result, nil
+}
+
 // CacheControl represents cache control configuration.
 type CacheControl struct {
 	Type string `json:"type"` // "ephemeral"
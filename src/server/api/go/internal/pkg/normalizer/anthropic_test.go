@@ -216,7 +216,7 @@ func TestAnthropicNormalizer_NormalizeFromAnthropicMessage(t *testing.T) {
 			wantErr:     false,
 		},
 		{
-			name: "assistant message with redacted thinking block (skipped)",
+			name: "assistant message with redacted thinking block",
 			input: `{
 				"role": "assistant",
 				"content": [
@@ -225,7 +225,7 @@ func TestAnthropicNormalizer_NormalizeFromAnthropicMessage(t *testing.T) {
 				]
 			}`,
 			wantRole:    model.RoleAssistant,
-			wantPartCnt: 1,
+			wantPartCnt: 2,
 			wantErr:     false,
 		},
 		{
@@ -430,7 +430,7 @@ func TestAnthropicNormalizer_ThinkingBlock(t *testing.T) {
 		assert.Equal(t, "sig_abc123", parts[0].Meta[model.MetaKeySignature])
 	})
 
-	t.Run("redacted thinking block is skipped", func(t *testing.T) {
+	t.Run("redacted thinking block is preserved", func(t *testing.T) {
 		input := `{
 			"role": "assistant",
 			"content": [
@@ -444,11 +444,13 @@ func TestAnthropicNormalizer_ThinkingBlock(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Equal(t, model.RoleAssistant, role)
-		assert.Len(t, parts, 2)
+		assert.Len(t, parts, 3)
 		assert.Equal(t, model.PartTypeThinking, parts[0].Type)
 		assert.Equal(t, "Visible thinking", parts[0].Text)
-		assert.Equal(t, model.PartTypeText, parts[1].Type)
-		assert.Equal(t, "Final answer", parts[1].Text)
+		assert.Equal(t, model.PartTypeRedactedThinking, parts[1].Type)
+		assert.Equal(t, "opaque-data", parts[1].Meta[model.MetaKeyData])
+		assert.Equal(t, model.PartTypeText, parts[2].Type)
+		assert.Equal(t, "Final answer", parts[2].Text)
 	})
 }
 
@@ -554,3 +556,99 @@ func TestBuildAnthropicCacheControl(t *testing.T) {
 		})
 	}
 }
+
+func TestAnthropicNormalizer_CitationsAndServerTools(t *testing.T) {
+	normalizer := &AnthropicNormalizer{}
+
+	input := `{
+		"role": "assistant",
+		"content": [
+			{"type": "redacted_thinking", "data": "opaque"},
+			{"type": "server_tool_use", "id": "srvtoolu_1", "name": "web_search", "input": {"query": "weather paris"}},
+			{
+				"type": "web_search_tool_result",
+				"tool_use_id": "srvtoolu_1",
+				"content": [
+					{"type": "web_search_result", "title": "Paris weather", "url": "https://example.com/paris", "encrypted_content": "enc_1", "page_age": "1 day"}
+				]
+			},
+			{
+				"type": "text",
+				"text": "It is sunny in Paris.",
+				"citations": [
+					{
+						"type": "web_search_result_location",
+						"cited_text": "Sunny, 25C",
+						"url": "https://example.com/paris",
+						"title": "Paris weather",
+						"encrypted_index": "idx_1"
+					}
+				]
+			}
+		]
+	}`
+
+	role, parts, _, err := normalizer.Normalize(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAssistant, role)
+	assert.Len(t, parts, 4)
+
+	assert.Equal(t, model.PartTypeRedactedThinking, parts[0].Type)
+	assert.Equal(t, "opaque", parts[0].Meta[model.MetaKeyData])
+
+	assert.Equal(t, model.PartTypeServerToolCall, parts[1].Type)
+	assert.Equal(t, "srvtoolu_1", parts[1].Meta[model.MetaKeyID])
+	assert.Equal(t, "web_search", parts[1].Meta[model.MetaKeyName])
+	assert.JSONEq(t, `{"query":"weather paris"}`, parts[1].Meta[model.MetaKeyArguments].(string))
+
+	assert.Equal(t, model.PartTypeServerToolResult, parts[2].Type)
+	assert.Equal(t, "srvtoolu_1", parts[2].Meta[model.MetaKeyToolCallID])
+	results := parts[2].Meta[model.MetaKeyResults].([]interface{})
+	assert.Len(t, results, 1)
+	result := results[0].(map[string]interface{})
+	assert.Equal(t, "enc_1", result[model.MetaKeyEncryptedContent])
+	assert.Equal(t, "1 day", result[model.MetaKeyPageAge])
+
+	assert.Equal(t, model.PartTypeText, parts[3].Type)
+	citations := parts[3].Meta[model.MetaKeyCitations].([]interface{})
+	assert.Len(t, citations, 1)
+	citation := citations[0].(map[string]interface{})
+	assert.Equal(t, "web_search_result_location", citation[model.CitationKeyType])
+	assert.Equal(t, "Sunny, 25C", citation[model.CitationKeyCitedText])
+	assert.Equal(t, "idx_1", citation["encrypted_index"])
+}
+
+func TestAnthropicNormalizer_SearchResultAndToolError(t *testing.T) {
+	normalizer := &AnthropicNormalizer{}
+
+	input := `{
+		"role": "user",
+		"content": [
+			{
+				"type": "search_result",
+				"source": "https://docs.example.com/api",
+				"title": "API docs",
+				"content": [{"type": "text", "text": "line one"}, {"type": "text", "text": "line two"}],
+				"citations": {"enabled": true}
+			},
+			{
+				"type": "web_search_tool_result",
+				"tool_use_id": "srvtoolu_2",
+				"content": {"type": "web_search_tool_result_error", "error_code": "max_uses_exceeded"}
+			}
+		]
+	}`
+
+	_, parts, _, err := normalizer.Normalize(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Len(t, parts, 2)
+
+	assert.Equal(t, model.PartTypeSearchResult, parts[0].Type)
+	assert.Equal(t, "line one\nline two", parts[0].Text)
+	assert.Equal(t, "https://docs.example.com/api", parts[0].Meta[model.MetaKeySource])
+	assert.Equal(t, "API docs", parts[0].Meta[model.MetaKeyTitle])
+	assert.Equal(t, true, parts[0].Meta[model.MetaKeyCitationsEnabled])
+
+	assert.Equal(t, model.PartTypeServerToolResult, parts[1].Type)
+	assert.Equal(t, "max_uses_exceeded", parts[1].Meta[model.MetaKeyErrorCode])
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"google.golang.org/genai"
@@ -41,6 +42,17 @@ func (n *GeminiNormalizer) Normalize(messageJSON json.RawMessage) (string, []ser
 		}
 	}
 
+	// Grounding metadata lives on the response candidate; accept it next to role/parts
+	var grounding struct {
+		GroundingMetadata *genai.GroundingMetadata `json:"groundingMetadata,omitempty"`
+	}
+	if err := json.Unmarshal(messageJSON, &grounding); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to unmarshal Gemini grounding metadata: %w", err)
+	}
+	if grounding.GroundingMetadata != nil {
+		parts = applyGeminiGrounding(parts, grounding.GroundingMetadata)
+	}
+
 	messageMeta := map[string]interface{}{
 		model.MsgMetaSourceFormat: "gemini",
 	}
@@ -95,7 +107,7 @@ func normalizeGeminiPart(part *genai.Part) (service.PartIn, map[string]interface
 		}, nil, nil
 	}
 
-	// Handle image part (InlineData)
+	// Handle inline media part (InlineData): audio and video by MIME type, images otherwise
 	if part.InlineData != nil {
 		dataBase64 := base64.StdEncoding.EncodeToString(part.InlineData.Data)
 		meta := map[string]interface{}{
@@ -104,7 +116,23 @@ func normalizeGeminiPart(part *genai.Part) (service.PartIn, map[string]interface
 			model.MetaKeyData:       dataBase64,
 		}
 		return service.PartIn{
-			Type: model.PartTypeImage,
+			Type: geminiMediaPartType(part.InlineData.MIMEType, model.PartTypeImage),
+			Meta: meta,
+		}, nil, nil
+	}
+
+	// Handle URI media part (FileData): Files API, Cloud Storage or YouTube URIs
+	if part.FileData != nil {
+		meta := map[string]interface{}{
+			model.MetaKeySourceType: "url",
+			model.MetaKeyURL:        part.FileData.FileURI,
+			model.MetaKeyMediaType:  part.FileData.MIMEType,
+		}
+		if part.FileData.DisplayName != "" {
+			meta[model.MetaKeyFilename] = part.FileData.DisplayName
+		}
+		return service.PartIn{
+			Type: geminiMediaPartType(part.FileData.MIMEType, model.PartTypeFile),
 			Meta: meta,
 		}, nil, nil
 	}
@@ -168,3 +196,86 @@ func normalizeGeminiPart(part *genai.Part) (service.PartIn, map[string]interface
 
 	return service.PartIn{}, nil, fmt.Errorf("unsupported Gemini part type")
 }
+
+// geminiMediaPartType maps a MIME type to the matching media part type,
+// returning fallback for types that are not image, audio or video.
+func geminiMediaPartType(mimeType string, fallback string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return model.PartTypeImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return model.PartTypeAudio
+	case strings.HasPrefix(mimeType, "video/"):
+		return model.PartTypeVideo
+	}
+	return fallback
+}
+
+// applyGeminiGrounding attaches grounding supports as citations on the text parts
+// they refer to, and appends one search-result part per grounding chunk.
+func applyGeminiGrounding(parts []service.PartIn, metadata *genai.GroundingMetadata) []service.PartIn {
+	type chunkInfo struct {
+		url   string
+		title string
+		text  string
+	}
+	chunks := make([]chunkInfo, len(metadata.GroundingChunks))
+	for i, chunk := range metadata.GroundingChunks {
+		if chunk == nil {
+			continue
+		}
+		if chunk.Web != nil {
+			chunks[i] = chunkInfo{url: chunk.Web.URI, title: chunk.Web.Title}
+		} else if chunk.RetrievedContext != nil {
+			chunks[i] = chunkInfo{url: chunk.RetrievedContext.URI, title: chunk.RetrievedContext.Title, text: chunk.RetrievedContext.Text}
+		}
+	}
+
+	for _, support := range metadata.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		partIdx := int(support.Segment.PartIndex)
+		if partIdx < 0 || partIdx >= len(parts) || parts[partIdx].Type != model.PartTypeText {
+			continue
+		}
+		part := &parts[partIdx]
+		if part.Meta == nil {
+			part.Meta = map[string]interface{}{}
+		}
+		citations, _ := part.Meta[model.MetaKeyCitations].([]interface{})
+		for _, chunkIdx := range support.GroundingChunkIndices {
+			if int(chunkIdx) < 0 || int(chunkIdx) >= len(chunks) {
+				continue
+			}
+			citations = append(citations, map[string]interface{}{
+				model.CitationKeyType:       model.CitationTypeGrounding,
+				model.CitationKeyCitedText:  support.Segment.Text,
+				model.CitationKeyStartIndex: support.Segment.StartIndex,
+				model.CitationKeyEndIndex:   support.Segment.EndIndex,
+				model.CitationKeyURL:        chunks[chunkIdx].url,
+				model.CitationKeyTitle:      chunks[chunkIdx].title,
+			})
+		}
+		if len(citations) > 0 {
+			part.Meta[model.MetaKeyCitations] = citations
+		}
+	}
+
+	for _, chunk := range chunks {
+		if chunk.url == "" && chunk.title == "" {
+			continue
+		}
+		parts = append(parts, service.PartIn{
+			Type: model.PartTypeSearchResult,
+			Text: chunk.text,
+			Meta: map[string]interface{}{
+				model.MetaKeySource: chunk.url,
+				model.MetaKeyTitle:  chunk.title,
+				model.MetaKeyURL:    chunk.url,
+			},
+		})
+	}
+
+	return parts
+}
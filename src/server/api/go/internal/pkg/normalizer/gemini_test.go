@@ -595,3 +595,75 @@ func TestGeminiNormalizer_MixedFunctionCalls(t *testing.T) {
 	assert.True(t, foundProvided, "provided_func should be in call info")
 	assert.True(t, foundGenerated, "generated_func should be in call info")
 }
+
+func TestGeminiNormalizer_GroundingMetadata(t *testing.T) {
+	normalizer := &GeminiNormalizer{}
+
+	input := `{
+		"role": "model",
+		"parts": [{"text": "Paris is sunny today."}],
+		"groundingMetadata": {
+			"webSearchQueries": ["weather paris"],
+			"groundingChunks": [
+				{"web": {"uri": "https://example.com/paris", "title": "example.com"}},
+				{"web": {"uri": "https://weather.example.org", "title": "weather.example.org"}}
+			],
+			"groundingSupports": [
+				{
+					"segment": {"partIndex": 0, "startIndex": 0, "endIndex": 21, "text": "Paris is sunny today."},
+					"groundingChunkIndices": [0, 1]
+				}
+			]
+		}
+	}`
+
+	role, parts, _, err := normalizer.Normalize(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAssistant, role)
+	assert.Len(t, parts, 3)
+
+	assert.Equal(t, model.PartTypeText, parts[0].Type)
+	citations := parts[0].Meta[model.MetaKeyCitations].([]interface{})
+	assert.Len(t, citations, 2)
+	citation := citations[1].(map[string]interface{})
+	assert.Equal(t, model.CitationTypeGrounding, citation[model.CitationKeyType])
+	assert.Equal(t, "Paris is sunny today.", citation[model.CitationKeyCitedText])
+	assert.Equal(t, "https://weather.example.org", citation[model.CitationKeyURL])
+	assert.EqualValues(t, 21, citation[model.CitationKeyEndIndex])
+
+	assert.Equal(t, model.PartTypeSearchResult, parts[1].Type)
+	assert.Equal(t, "https://example.com/paris", parts[1].Meta[model.MetaKeyURL])
+	assert.Equal(t, "example.com", parts[1].Meta[model.MetaKeyTitle])
+	assert.Equal(t, model.PartTypeSearchResult, parts[2].Type)
+}
+
+func TestGeminiNormalizer_AudioAndVideo(t *testing.T) {
+	normalizer := &GeminiNormalizer{}
+
+	input := `{
+		"role": "user",
+		"parts": [
+			{"inlineData": {"mimeType": "audio/wav", "data": "UklGRg=="}},
+			{"inlineData": {"mimeType": "video/mp4", "data": "AAAA"}},
+			{"fileData": {"mimeType": "video/mp4", "fileUri": "https://www.youtube.com/watch?v=abc"}},
+			{"fileData": {"mimeType": "application/pdf", "fileUri": "gs://bucket/report.pdf", "displayName": "report.pdf"}}
+		]
+	}`
+
+	_, parts, _, err := normalizer.Normalize(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Len(t, parts, 4)
+
+	assert.Equal(t, model.PartTypeAudio, parts[0].Type)
+	assert.Equal(t, "audio/wav", parts[0].Meta[model.MetaKeyMediaType])
+	assert.Equal(t, "UklGRg==", parts[0].Meta[model.MetaKeyData])
+
+	assert.Equal(t, model.PartTypeVideo, parts[1].Type)
+
+	assert.Equal(t, model.PartTypeVideo, parts[2].Type)
+	assert.Equal(t, "https://www.youtube.com/watch?v=abc", parts[2].Meta[model.MetaKeyURL])
+
+	assert.Equal(t, model.PartTypeFile, parts[3].Type)
+	assert.Equal(t, "gs://bucket/report.pdf", parts[3].Meta[model.MetaKeyURL])
+	assert.Equal(t, "report.pdf", parts[3].Meta[model.MetaKeyFilename])
+}
@@ -24,7 +24,7 @@ func (n *OpenAINormalizer) Normalize(messageJSON json.RawMessage) (string, []ser
 	if message.OfUser != nil {
 		return normalizeOpenAIUserMessage(*message.OfUser)
 	} else if message.OfAssistant != nil {
-		return normalizeOpenAIAssistantMessage(*message.OfAssistant)
+		return normalizeOpenAIAssistantMessage(*message.OfAssistant, messageJSON)
 	} else if message.OfSystem != nil {
 		return "", nil, nil, fmt.Errorf("system messages are not supported. Use session-level or skill-level configuration for system prompts")
 	} else if message.OfTool != nil {
@@ -75,7 +75,7 @@ func normalizeOpenAIUserMessage(msg openai.ChatCompletionUserMessageParam) (stri
 	return model.RoleUser, parts, messageMeta, nil
 }
 
-func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessageParam) (string, []service.PartIn, map[string]interface{}, error) {
+func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessageParam, messageJSON json.RawMessage) (string, []service.PartIn, map[string]interface{}, error) {
 	parts := []service.PartIn{}
 
 	if !param.IsOmitted(msg.Content.OfString) {
@@ -95,6 +95,14 @@ func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessagePa
 		}
 	}
 
+	audioPart, err := normalizeOpenAIAssistantAudio(messageJSON)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if audioPart != nil {
+		parts = append(parts, *audioPart)
+	}
+
 	for _, toolCall := range msg.ToolCalls {
 		if toolCall.OfFunction != nil {
 			parts = append(parts, service.PartIn{
@@ -131,6 +139,45 @@ func normalizeOpenAIAssistantMessage(msg openai.ChatCompletionAssistantMessagePa
 	return model.RoleAssistant, parts, messageMeta, nil
 }
 
+// normalizeOpenAIAssistantAudio captures the audio of an assistant message.
+// The request param only carries the audio ID, but a response message stored
+// as-is also has the base64 data and transcript, which the SDK param drops.
+func normalizeOpenAIAssistantAudio(messageJSON json.RawMessage) (*service.PartIn, error) {
+	var raw struct {
+		Audio *struct {
+			ID         string `json:"id"`
+			Data       string `json:"data"`
+			Transcript string `json:"transcript"`
+			Format     string `json:"format"`
+		} `json:"audio"`
+	}
+	if err := json.Unmarshal(messageJSON, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal OpenAI assistant audio: %w", err)
+	}
+	if raw.Audio == nil || (raw.Audio.ID == "" && raw.Audio.Data == "") {
+		return nil, nil
+	}
+
+	meta := map[string]interface{}{}
+	if raw.Audio.ID != "" {
+		meta[model.MetaKeyAudioID] = raw.Audio.ID
+	}
+	if raw.Audio.Data != "" {
+		meta[model.MetaKeyData] = raw.Audio.Data
+	}
+	if raw.Audio.Transcript != "" {
+		meta[model.MetaKeyTranscript] = raw.Audio.Transcript
+	}
+	if raw.Audio.Format != "" {
+		meta[model.MetaKeyAudioFormat] = raw.Audio.Format
+	}
+
+	return &service.PartIn{
+		Type: model.PartTypeAudio,
+		Meta: meta,
+	}, nil
+}
+
 func normalizeOpenAIToolMessage(msg openai.ChatCompletionToolMessageParam) (string, []service.PartIn, map[string]interface{}, error) {
 	var content string
 	if !param.IsOmitted(msg.Content.OfString) {
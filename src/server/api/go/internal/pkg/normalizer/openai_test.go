@@ -407,3 +407,33 @@ func TestOpenAINormalizer_MessageWithName(t *testing.T) {
 	assert.Equal(t, "openai", messageMeta[model.MsgMetaSourceFormat])
 	assert.Equal(t, "Alice", messageMeta[model.MetaKeyName])
 }
+
+func TestOpenAINormalizer_AssistantAudio(t *testing.T) {
+	normalizer := &OpenAINormalizer{}
+
+	// A response message stored as-is carries data and transcript next to the ID
+	input := `{
+		"role": "assistant",
+		"content": null,
+		"audio": {"id": "audio_abc", "data": "UklGRg==", "transcript": "Hello!", "expires_at": 1729018505}
+	}`
+
+	role, parts, _, err := normalizer.Normalize(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Equal(t, model.RoleAssistant, role)
+	assert.Len(t, parts, 1)
+	assert.Equal(t, model.PartTypeAudio, parts[0].Type)
+	assert.Equal(t, "audio_abc", parts[0].Meta[model.MetaKeyAudioID])
+	assert.Equal(t, "UklGRg==", parts[0].Meta[model.MetaKeyData])
+	assert.Equal(t, "Hello!", parts[0].Meta[model.MetaKeyTranscript])
+
+	// A request param only references the audio, next to text content
+	input = `{"role": "assistant", "content": "Sure.", "audio": {"id": "audio_def"}}`
+
+	_, parts, _, err = normalizer.Normalize(json.RawMessage(input))
+	assert.NoError(t, err)
+	assert.Len(t, parts, 2)
+	assert.Equal(t, "Sure.", parts[0].Text)
+	assert.Equal(t, "audio_def", parts[1].Meta[model.MetaKeyAudioID])
+	assert.NotContains(t, parts[1].Meta, model.MetaKeyData)
+}
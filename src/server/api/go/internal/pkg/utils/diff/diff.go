@@ -0,0 +1,43 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change
+const DefaultContext = 3
+
+// Unified returns a unified diff between a and b, labelled fromName and toName.
+// It returns an empty string when the texts are equal.
+func Unified(fromName, toName, a, b string, context int) (string, error) {
+	if a == b {
+		return "", nil
+	}
+	if context < 0 {
+		context = DefaultContext
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(a),
+		B:        splitLines(b),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  context,
+	})
+}
+
+// splitLines splits s into lines that keep their trailing newline, as difflib expects.
+// A missing newline at the end of the text is added so the last line diffs cleanly.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	t.Run("equal texts", func(t *testing.T) {
+		out, err := Unified("a", "b", "same\n", "same\n", DefaultContext)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("changed line", func(t *testing.T) {
+		out, err := Unified("notes.txt@v1", "notes.txt@v2", "one\ntwo\nthree\n", "one\n2\nthree\n", DefaultContext)
+		require.NoError(t, err)
+		assert.Equal(t, "--- notes.txt@v1\n+++ notes.txt@v2\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n", out)
+	})
+
+	t.Run("missing trailing newline", func(t *testing.T) {
+		out, err := Unified("a", "b", "one", "one\ntwo", 0)
+		require.NoError(t, err)
+		assert.Equal(t, "--- a\n+++ b\n@@ -1,0 +2 @@\n+two\n", out)
+	})
+
+	t.Run("from empty", func(t *testing.T) {
+		out, err := Unified("a", "b", "", "new\n", DefaultContext)
+		require.NoError(t, err)
+		assert.Contains(t, out, "+new\n")
+	})
+}
+
+func TestSplitLines(t *testing.T) {
+	assert.Nil(t, splitLines(""))
+	assert.Equal(t, []string{"a\n", "b\n"}, splitLines("a\nb\n"))
+	assert.Equal(t, []string{"a\n", "b\n"}, splitLines("a\nb"))
+}
@@ -0,0 +1,198 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrPatchFailed is returned when a patch is malformed or doesn't match the text it's applied to.
+var ErrPatchFailed = errors.New("patch does not apply")
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// hunk is one @@ section of a unified diff, with lines stripped of their newline.
+type hunk struct {
+	oldStart int
+	old      []string
+	new      []string
+	// newNoEOL is set when the last new line is marked as having no newline
+	newNoEOL bool
+}
+
+// Apply applies a unified diff, as produced by Unified or diff -u, to original.
+// File headers are optional and only the first file of a multi-file diff is used.
+// Every context and removed line must match; a hunk whose line numbers are off
+// is applied at the nearest position where it matches, as patch does.
+func Apply(original string, patch string) (string, error) {
+	hunks, err := parseHunks(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines, eol := textLines(original)
+	out := make([]string, 0, len(lines))
+	pos := 0
+	drift := 0
+	for i, h := range hunks {
+		want := h.oldStart - 1 + drift
+		if len(h.old) == 0 {
+			// An empty old range gives the line the insertion follows
+			want = h.oldStart + drift
+		}
+		at := locate(lines, h.old, want, pos)
+		if at < 0 {
+			return "", fmt.Errorf("%w: hunk %d does not match at line %d", ErrPatchFailed, i+1, h.oldStart)
+		}
+		drift = at - (h.oldStart - 1)
+		if len(h.old) == 0 {
+			drift = at - h.oldStart
+		}
+
+		out = append(out, lines[pos:at]...)
+		out = append(out, h.new...)
+		pos = at + len(h.old)
+		if pos == len(lines) {
+			eol = !h.newNoEOL
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	if len(out) == 0 {
+		return "", nil
+	}
+	text := strings.Join(out, "\n")
+	if eol {
+		text += "\n"
+	}
+	return text, nil
+}
+
+// textLines splits s into lines without their newline and reports whether s ends with one.
+func textLines(s string) ([]string, bool) {
+	if s == "" {
+		return nil, true
+	}
+	eol := strings.HasSuffix(s, "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n"), eol
+}
+
+// locate returns the index nearest to want, and not before from, where old appears in lines, or -1.
+func locate(lines []string, old []string, want int, from int) int {
+	last := len(lines) - len(old)
+	if want < from {
+		want = from
+	}
+	if want > last {
+		want = last
+	}
+	for d := 0; want-d >= from || want+d <= last; d++ {
+		if at := want - d; at >= from && at <= last && matchAt(lines, old, at) {
+			return at
+		}
+		if at := want + d; d > 0 && at >= from && at <= last && matchAt(lines, old, at) {
+			return at
+		}
+	}
+	return -1
+}
+
+func matchAt(lines []string, old []string, at int) bool {
+	for i, l := range old {
+		if lines[at+i] != l {
+			return false
+		}
+	}
+	return true
+}
+
+func parseHunks(patch string) ([]*hunk, error) {
+	lines, _ := textLines(patch)
+
+	var hunks []*hunk
+	var cur *hunk
+	oldLeft, newLeft := 0, 0
+	last := byte(0) // Kind of the previous hunk line
+	for n, line := range lines {
+		if cur != nil && (oldLeft > 0 || newLeft > 0) {
+			if line == "" {
+				// Some editors strip the space of empty context lines
+				line = " "
+			}
+			switch line[0] {
+			case ' ':
+				cur.old = append(cur.old, line[1:])
+				cur.new = append(cur.new, line[1:])
+				oldLeft--
+				newLeft--
+			case '-':
+				cur.old = append(cur.old, line[1:])
+				oldLeft--
+			case '+':
+				cur.new = append(cur.new, line[1:])
+				newLeft--
+			case '\\':
+				continue
+			default:
+				return nil, fmt.Errorf("%w: unexpected line %d in hunk: %q", ErrPatchFailed, n+1, line)
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return nil, fmt.Errorf("%w: hunk %d is longer than its header", ErrPatchFailed, len(hunks))
+			}
+			last = line[0]
+			continue
+		}
+
+		if strings.HasPrefix(line, `\`) && cur != nil {
+			// "\ No newline at end of file" after the last line of a hunk
+			if last == '+' || last == ' ' {
+				cur.newNoEOL = true
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "--- ") && len(hunks) > 0 {
+			// The next file of a multi-file diff
+			break
+		}
+
+		m := hunkHeader.FindStringSubmatch(line)
+		if m == nil {
+			if cur != nil && strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "diff ") && !strings.HasPrefix(line, "index ") {
+				return nil, fmt.Errorf("%w: unexpected line %d after hunk: %q", ErrPatchFailed, n+1, line)
+			}
+			// Headers before the first hunk
+			continue
+		}
+		cur = &hunk{oldStart: atoi(m[1])}
+		oldLeft, newLeft = count(m[2]), count(m[4])
+		hunks = append(hunks, cur)
+	}
+
+	if cur != nil && (oldLeft > 0 || newLeft > 0) {
+		return nil, fmt.Errorf("%w: hunk %d is shorter than its header", ErrPatchFailed, len(hunks))
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("%w: no hunks found", ErrPatchFailed)
+	}
+	for i := 1; i < len(hunks); i++ {
+		if hunks[i].oldStart < hunks[i-1].oldStart {
+			return nil, fmt.Errorf("%w: hunk %d is out of order", ErrPatchFailed, i+1)
+		}
+	}
+	return hunks, nil
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// count parses the length of a hunk range, which defaults to 1 when omitted.
+func count(s string) int {
+	if s == "" {
+		return 1
+	}
+	return atoi(s)
+}
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	t.Run("round trips Unified", func(t *testing.T) {
+		a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+		b := "one\n2\nthree\nfour\nfive\nsix\nseven\neight\nnine\n10\neleven\n"
+		patch, err := Unified("a", "b", a, b, DefaultContext)
+		require.NoError(t, err)
+
+		out, err := Apply(a, patch)
+		require.NoError(t, err)
+		assert.Equal(t, b, out)
+	})
+
+	t.Run("without file headers", func(t *testing.T) {
+		out, err := Apply("a\nb\nc\n", "@@ -2 +2,2 @@\n-b\n+B\n+B2\n")
+		require.NoError(t, err)
+		assert.Equal(t, "a\nB\nB2\nc\n", out)
+	})
+
+	t.Run("insert into empty text", func(t *testing.T) {
+		out, err := Apply("", "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n")
+		require.NoError(t, err)
+		assert.Equal(t, "x\ny\n", out)
+	})
+
+	t.Run("shifted hunk applies where it matches", func(t *testing.T) {
+		out, err := Apply("new\nnew\na\nb\nc\n", "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n")
+		require.NoError(t, err)
+		assert.Equal(t, "new\nnew\na\nB\nc\n", out)
+	})
+
+	t.Run("no newline at end of file", func(t *testing.T) {
+		out, err := Apply("a\nb\n", "@@ -1,2 +1,2 @@\n a\n-b\n+c\n\\ No newline at end of file\n")
+		require.NoError(t, err)
+		assert.Equal(t, "a\nc", out)
+
+		out, err = Apply("a\nb", "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n")
+		require.NoError(t, err)
+		assert.Equal(t, "a\nc\n", out)
+	})
+
+	t.Run("keeps a missing newline the patch doesn't touch", func(t *testing.T) {
+		out, err := Apply("a\nb\nc\nd\ne", "@@ -1,2 +1,2 @@\n-a\n+A\n b\n")
+		require.NoError(t, err)
+		assert.Equal(t, "A\nb\nc\nd\ne", out)
+	})
+
+	t.Run("stops at the next file", func(t *testing.T) {
+		patch := "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n--- a/y\n+++ b/y\n@@ -1 +1 @@\n-q\n+r\n"
+		out, err := Apply("a\n", patch)
+		require.NoError(t, err)
+		assert.Equal(t, "b\n", out)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			original string
+			patch    string
+		}{
+			{name: "no hunks", original: "a\n", patch: "just text\n"},
+			{name: "context mismatch", original: "a\nb\n", patch: "@@ -1,2 +1,2 @@\n a\n-x\n+y\n"},
+			{name: "short hunk", original: "a\nb\n", patch: "@@ -1,2 +1,2 @@\n a\n-b\n"},
+			{name: "garbage in hunk", original: "a\n", patch: "@@ -1 +1 @@\n*a\n"},
+			{name: "out of order", original: "a\nb\n", patch: "@@ -2 +2 @@\n-b\n+B\n@@ -1 +1 @@\n-a\n+A\n"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := Apply(tt.original, tt.patch)
+				assert.ErrorIs(t, err, ErrPatchFailed)
+			})
+		}
+	})
+}
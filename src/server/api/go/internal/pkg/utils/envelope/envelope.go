@@ -0,0 +1,350 @@
+// Package envelope implements client-side envelope encryption of stored objects:
+// random data keys wrapped by a key-encryption key, and a chunked AES-256-GCM
+// format that can be written as a stream and read back by ranges.
+//
+// A ciphertext is a header (magic and base nonce) followed by chunks of ChunkSize
+// plaintext bytes, the last one shorter and possibly empty, each sealed with its own
+// nonce and a flag marking the last chunk, so chunks can't be reordered or dropped.
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	KeySize    = 32       // AES-256 keys
+	ChunkSize  = 64 << 10 // Plaintext bytes per chunk
+	nonceSize  = 12
+	tagSize    = 16
+	HeaderSize = len(magic) + nonceSize
+	sealedSize = ChunkSize + tagSize
+)
+
+const magic = "ACE1"
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// NewDataKey returns a random data key.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// DeriveKEK derives the key-encryption key of a project from a master secret.
+func DeriveKEK(secret []byte, projectID string) ([]byte, error) {
+	kek := make([]byte, KeySize)
+	r := hkdf.New(sha256.New, secret, []byte(projectID), []byte("acontext data key wrap"))
+	if _, err := io.ReadFull(r, kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
+
+// Wrap encrypts a data key of a project with kek.
+func Wrap(kek []byte, dataKey []byte, projectID string) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(projectID)), nil
+}
+
+// Unwrap decrypts a data key wrapped by Wrap. It fails if kek or projectID differ.
+func Unwrap(kek []byte, wrapped []byte, projectID string) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < nonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+	key, err := aead.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", ErrInvalidCiphertext)
+	}
+	return key, nil
+}
+
+// CiphertextSize returns the size of the ciphertext of plaintextSize bytes.
+func CiphertextSize(plaintextSize int64) int64 {
+	return int64(HeaderSize) + chunkCount(plaintextSize)*tagSize + plaintextSize
+}
+
+// PlaintextSize returns the size of the plaintext of a ciphertextSize bytes ciphertext.
+func PlaintextSize(ciphertextSize int64) (int64, error) {
+	body := ciphertextSize - int64(HeaderSize)
+	if body < tagSize {
+		return 0, ErrInvalidCiphertext
+	}
+	n := (body + sealedSize - 1) / sealedSize
+	if body-(n-1)*sealedSize < tagSize {
+		return 0, ErrInvalidCiphertext
+	}
+	return body - n*tagSize, nil
+}
+
+// chunkCount is the number of chunks of plaintextSize bytes; empty plaintexts have one empty chunk.
+func chunkCount(plaintextSize int64) int64 {
+	if plaintextSize == 0 {
+		return 1
+	}
+	return (plaintextSize + ChunkSize - 1) / ChunkSize
+}
+
+// Encrypt encrypts plaintext in memory.
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	r, err := NewEncryptReader(key, bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	out := bytes.NewBuffer(make([]byte, 0, CiphertextSize(int64(len(plaintext)))))
+	if _, err := io.Copy(out, r); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decrypt decrypts a ciphertext in memory.
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	r, err := NewDecryptReader(key, bytes.NewReader(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// NewEncryptReader returns a reader of the ciphertext of the plaintext read from r.
+func NewEncryptReader(key []byte, r io.Reader) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, HeaderSize)
+	copy(header, magic)
+	if _, err := rand.Read(header[len(magic):]); err != nil {
+		return nil, err
+	}
+	return &encryptReader{
+		aead:  aead,
+		nonce: header[len(magic):],
+		src:   bufio.NewReaderSize(r, ChunkSize),
+		buf:   header,
+		chunk: make([]byte, ChunkSize),
+		out:   make([]byte, 0, sealedSize),
+	}, nil
+}
+
+type encryptReader struct {
+	aead  cipher.AEAD
+	nonce []byte
+	src   *bufio.Reader
+	index uint64
+	buf   []byte // Sealed bytes not read yet
+	chunk []byte
+	out   []byte
+	done  bool
+	err   error
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.buf) == 0 {
+		if e.err != nil {
+			return 0, e.err
+		}
+		if e.done {
+			return 0, io.EOF
+		}
+		e.seal()
+	}
+	n := copy(p, e.buf)
+	e.buf = e.buf[n:]
+	return n, nil
+}
+
+// seal reads and seals the next chunk, which is the last one once the source is drained.
+func (e *encryptReader) seal() {
+	n, err := io.ReadFull(e.src, e.chunk)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		e.err = err
+		return
+	}
+	last := n < ChunkSize
+	if !last {
+		if _, perr := e.src.Peek(1); perr != nil {
+			if !errors.Is(perr, io.EOF) {
+				e.err = perr
+				return
+			}
+			last = true
+		}
+	}
+	e.buf = e.aead.Seal(e.out[:0], chunkNonce(e.nonce, e.index), e.chunk[:n], chunkAAD(last))
+	e.index++
+	e.done = last
+}
+
+// NewDecryptReader returns a reader of the plaintext of the ciphertext read from r.
+func NewDecryptReader(key []byte, r io.Reader) (io.Reader, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("read header: %w", ErrInvalidCiphertext)
+	}
+	return NewRangeDecryptReader(key, header, 0, -1, r)
+}
+
+// NewRangeDecryptReader returns a reader of the plaintext of the chunks read from r,
+// which starts at chunk first of a ciphertext with the given header. last is the index
+// of the last chunk of the ciphertext, or -1 to find it at the end of r.
+func NewRangeDecryptReader(key []byte, header []byte, first int64, last int64, r io.Reader) (io.Reader, error) {
+	if len(header) != HeaderSize || string(header[:len(magic)]) != magic {
+		return nil, fmt.Errorf("bad header: %w", ErrInvalidCiphertext)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		aead:   aead,
+		nonce:  header[len(magic):],
+		src:    bufio.NewReaderSize(r, sealedSize),
+		index:  uint64(first),
+		last:   last,
+		sealed: make([]byte, sealedSize),
+	}, nil
+}
+
+type decryptReader struct {
+	aead   cipher.AEAD
+	nonce  []byte
+	src    *bufio.Reader
+	index  uint64
+	last   int64
+	buf    []byte // Opened bytes not read yet
+	sealed []byte
+	done   bool
+	err    error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.done {
+			return 0, io.EOF
+		}
+		d.open()
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk.
+func (d *decryptReader) open() {
+	n, err := io.ReadFull(d.src, d.sealed)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		d.err = err
+		return
+	}
+	var last bool
+	if d.last >= 0 {
+		last = int64(d.index) == d.last
+		if n == 0 && !last {
+			// The range ended before the last chunk of the ciphertext
+			d.done = true
+			return
+		}
+	} else {
+		last = n < sealedSize
+		if !last {
+			if _, perr := d.src.Peek(1); perr != nil {
+				if !errors.Is(perr, io.EOF) {
+					d.err = perr
+					return
+				}
+				last = true
+			}
+		}
+	}
+	if n < tagSize {
+		d.err = fmt.Errorf("truncated chunk %d: %w", d.index, ErrInvalidCiphertext)
+		return
+	}
+	plain, err := d.aead.Open(d.sealed[:0], chunkNonce(d.nonce, d.index), d.sealed[:n], chunkAAD(last))
+	if err != nil {
+		d.err = fmt.Errorf("open chunk %d: %w", d.index, ErrInvalidCiphertext)
+		return
+	}
+	d.buf = plain
+	d.index++
+	d.done = last
+}
+
+// ChunkRange maps length plaintext bytes from offset, within a plaintext of
+// plaintextSize bytes, to the chunks holding them: the first and last chunk indexes,
+// the ciphertext byte range to fetch, and the plaintext bytes to skip in the first chunk.
+// length must be positive and the range must lie within the plaintext.
+func ChunkRange(plaintextSize int64, offset int64, length int64) (first int64, last int64, start int64, end int64, skip int64) {
+	first = offset / ChunkSize
+	last = (offset + length - 1) / ChunkSize
+	start = int64(HeaderSize) + first*sealedSize
+	end = int64(HeaderSize) + (last+1)*sealedSize
+	if size := CiphertextSize(plaintextSize); end > size {
+		end = size
+	}
+	return first, last, start, end, offset - first*ChunkSize
+}
+
+// LastChunk is the index of the last chunk of a plaintext of plaintextSize bytes.
+func LastChunk(plaintextSize int64) int64 {
+	return chunkCount(plaintextSize) - 1
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the base nonce with its last 8 bytes XORed with the chunk index.
+func chunkNonce(base []byte, index uint64) []byte {
+	nonce := make([]byte, nonceSize)
+	copy(nonce, base)
+	var idx [8]byte
+	binary.BigEndian.PutUint64(idx[:], index)
+	for i := range idx {
+		nonce[nonceSize-8+i] ^= idx[i]
+	}
+	return nonce
+}
+
+func chunkAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
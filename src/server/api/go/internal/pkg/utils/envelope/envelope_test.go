@@ -0,0 +1,130 @@
+package envelope
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := NewDataKey()
+	require.NoError(t, err)
+	return key
+}
+
+func pattern(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	return b
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 5} {
+		plaintext := pattern(size)
+		ciphertext, err := Encrypt(key, plaintext)
+		require.NoError(t, err)
+		assert.Equal(t, CiphertextSize(int64(size)), int64(len(ciphertext)), "size %d", size)
+
+		plainSize, err := PlaintextSize(int64(len(ciphertext)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(size), plainSize)
+
+		got, err := Decrypt(key, ciphertext)
+		require.NoError(t, err)
+		assert.True(t, bytes.Equal(plaintext, got), "size %d", size)
+	}
+}
+
+func TestDecrypt_Tampering(t *testing.T) {
+	key := testKey(t)
+	ciphertext, err := Encrypt(key, pattern(2*ChunkSize+10))
+	require.NoError(t, err)
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := Decrypt(testKey(t), ciphertext)
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+
+	t.Run("flipped bit", func(t *testing.T) {
+		tampered := bytes.Clone(ciphertext)
+		tampered[HeaderSize+10] ^= 1
+		_, err := Decrypt(key, tampered)
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+
+	t.Run("truncated at a chunk boundary", func(t *testing.T) {
+		_, err := Decrypt(key, ciphertext[:HeaderSize+sealedSize])
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+
+	t.Run("swapped chunks", func(t *testing.T) {
+		swapped := bytes.Clone(ciphertext)
+		a := swapped[HeaderSize : HeaderSize+sealedSize]
+		b := bytes.Clone(swapped[HeaderSize+sealedSize : HeaderSize+2*sealedSize])
+		copy(swapped[HeaderSize+sealedSize:], a)
+		copy(swapped[HeaderSize:], b)
+		_, err := Decrypt(key, swapped)
+		assert.ErrorIs(t, err, ErrInvalidCiphertext)
+	})
+}
+
+func TestRangeDecrypt(t *testing.T) {
+	key := testKey(t)
+	size := int64(3*ChunkSize + 100)
+	plaintext := pattern(int(size))
+	ciphertext, err := Encrypt(key, plaintext)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		offset int64
+		length int64
+	}{
+		{"first bytes", 0, 10},
+		{"within a chunk", ChunkSize + 5, 100},
+		{"across chunks", ChunkSize - 10, ChunkSize + 20},
+		{"tail", size - 50, 50},
+		{"everything", 0, size},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, _, start, end, skip := ChunkRange(size, tt.offset, tt.length)
+			r, err := NewRangeDecryptReader(key, ciphertext[:HeaderSize], first, LastChunk(size), bytes.NewReader(ciphertext[start:end]))
+			require.NoError(t, err)
+			_, err = io.CopyN(io.Discard, r, skip)
+			require.NoError(t, err)
+			got := make([]byte, tt.length)
+			_, err = io.ReadFull(r, got)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(plaintext[tt.offset:tt.offset+tt.length], got))
+		})
+	}
+}
+
+func TestWrapUnwrap(t *testing.T) {
+	kek, err := DeriveKEK([]byte("project-secret"), "project-1")
+	require.NoError(t, err)
+	dataKey := testKey(t)
+
+	wrapped, err := Wrap(kek, dataKey, "project-1")
+	require.NoError(t, err)
+
+	got, err := Unwrap(kek, wrapped, "project-1")
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, got)
+
+	_, err = Unwrap(kek, wrapped, "project-2")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	other, err := DeriveKEK([]byte("other-secret"), "project-1")
+	require.NoError(t, err)
+	_, err = Unwrap(other, wrapped, "project-1")
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
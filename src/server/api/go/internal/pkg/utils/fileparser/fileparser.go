@@ -3,18 +3,30 @@ package fileparser
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bytedance/sonic"
 )
 
 // FileContent represents the parsed content of a file
 type FileContent struct {
-	Type string `json:"type"` // "text", "json", "csv", "code"
+	Type string `json:"type"` // "text", "json", "csv", "code", "pdf", "docx", "xlsx", "pptx"
 	Raw  string `json:"raw"`  // Raw text content
+	// Sheets holds each worksheet of a spreadsheet as CSV, in workbook order
+	Sheets []Sheet `json:"sheets,omitempty"`
+	// Truncated is set when a document hit one of the extraction limits
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Sheet is one worksheet of a spreadsheet
+type Sheet struct {
+	Name string `json:"name"`
+	CSV  string `json:"csv"`
 }
 
 // Parser interface for different file types
@@ -23,6 +35,94 @@ type Parser interface {
 	Parse(content []byte) (*FileContent, error)
 }
 
+// ErrFileTooLarge is returned for documents above Limits.MaxFileSize
+var ErrFileTooLarge = errors.New("file too large to parse")
+
+// Limits bound the memory and work spent extracting text from documents
+type Limits struct {
+	MaxFileSize         int64 // Larger documents are not parsed
+	MaxDecompressedSize int64 // Bytes inflated from a single zip entry or PDF stream
+	MaxTextSize         int   // Extracted text is cut at this many bytes
+	MaxPages            int   // PDF pages or PPTX slides read
+}
+
+// DefaultLimits are used for any limit left at zero
+var DefaultLimits = Limits{
+	MaxFileSize:         50 << 20,
+	MaxDecompressedSize: 100 << 20,
+	MaxTextSize:         10 << 20,
+	MaxPages:            1000,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxFileSize <= 0 {
+		l.MaxFileSize = DefaultLimits.MaxFileSize
+	}
+	if l.MaxDecompressedSize <= 0 {
+		l.MaxDecompressedSize = DefaultLimits.MaxDecompressedSize
+	}
+	if l.MaxTextSize <= 0 {
+		l.MaxTextSize = DefaultLimits.MaxTextSize
+	}
+	if l.MaxPages <= 0 {
+		l.MaxPages = DefaultLimits.MaxPages
+	}
+	return l
+}
+
+// sizeLimited is implemented by parsers that refuse inputs above a size
+type sizeLimited interface {
+	maxFileSize() int64
+}
+
+// textWriter accumulates extracted text up to a byte budget
+type textWriter struct {
+	b         strings.Builder
+	max       int
+	truncated bool
+}
+
+func newTextWriter(max int) *textWriter {
+	return &textWriter{max: max}
+}
+
+func (w *textWriter) WriteString(s string) {
+	if w.truncated || s == "" {
+		return
+	}
+	if room := w.max - w.b.Len(); len(s) > room {
+		// Cut on a rune boundary
+		for room > 0 && !utf8.RuneStart(s[room]) {
+			room--
+		}
+		s = s[:room]
+		w.truncated = true
+	}
+	w.b.WriteString(s)
+}
+
+// newline ends the current line unless the text is empty or already ends one
+func (w *textWriter) newline() {
+	if w.b.Len() > 0 && !strings.HasSuffix(w.b.String(), "\n") {
+		w.WriteString("\n")
+	}
+}
+
+// space separates words unless the text already ends with whitespace
+func (w *textWriter) space() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.WriteString(" ")
+	}
+}
+
+func (w *textWriter) full() bool {
+	return w.truncated
+}
+
+func (w *textWriter) String() string {
+	return w.b.String()
+}
+
 // TextParser handles plain text files
 type TextParser struct{}
 
@@ -157,6 +257,10 @@ type FileParser struct {
 func NewFileParser() *FileParser {
 	return &FileParser{
 		parsers: []Parser{
+			&PDFParser{},
+			&DOCXParser{},
+			&XLSXParser{},
+			&PPTXParser{},
 			&JSONParser{},
 			&CSVParser{},
 			&CodeParser{},
@@ -175,6 +279,48 @@ func (fp *FileParser) CanParseFile(filename string, mimeType string) bool {
 	return false
 }
 
+// ContentType returns the FileContent type a file parses to, and whether its stored bytes
+// are that text as is rather than a document the text is extracted from. It returns "" for unsupported files.
+func (fp *FileParser) ContentType(filename string, mimeType string) (string, bool) {
+	for _, parser := range fp.parsers {
+		if parser.CanParse(filename, mimeType) {
+			switch parser.(type) {
+			case *PDFParser:
+				return "pdf", false
+			case *DOCXParser:
+				return "docx", false
+			case *XLSXParser:
+				return "xlsx", false
+			case *PPTXParser:
+				return "pptx", false
+			case *JSONParser:
+				return "json", true
+			case *CSVParser:
+				return "csv", true
+			case *CodeParser:
+				return "code", true
+			case *TextParser:
+				return "text", true
+			}
+		}
+	}
+	return "", false
+}
+
+// CheckSize returns ErrFileTooLarge if the parser for the file refuses inputs of the given size,
+// so callers can skip reading large documents at all
+func (fp *FileParser) CheckSize(filename string, mimeType string, size int64) error {
+	for _, parser := range fp.parsers {
+		if parser.CanParse(filename, mimeType) {
+			if l, ok := parser.(sizeLimited); ok && size > l.maxFileSize() {
+				return ErrFileTooLarge
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
 // ParseFile attempts to parse file content based on filename and MIME type
 func (fp *FileParser) ParseFile(filename string, mimeType string, content []byte) (*FileContent, error) {
 	// Try each parser in order
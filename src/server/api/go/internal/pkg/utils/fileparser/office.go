@@ -0,0 +1,527 @@
+package fileparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Office Open XML documents are zip packages of XML parts. Parts are streamed through
+// encoding/xml and every part is inflated through a reader capped at Limits.MaxDecompressedSize,
+// so a zip bomb costs at most that many bytes of work per part.
+
+const (
+	mimeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+// maxSheetColumns is the widest sheet Excel supports (XFD)
+const maxSheetColumns = 16384
+
+// DOCXParser extracts the body text of Word documents, one line per paragraph
+type DOCXParser struct {
+	Limits Limits
+}
+
+func (p *DOCXParser) CanParse(filename string, mimeType string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".docx" || strings.HasPrefix(mimeType, mimeDOCX)
+}
+
+func (p *DOCXParser) maxFileSize() int64 {
+	return p.Limits.withDefaults().MaxFileSize
+}
+
+func (p *DOCXParser) Parse(content []byte) (*FileContent, error) {
+	limits := p.Limits.withDefaults()
+	pkg, err := openOOXML(content, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX: %w", err)
+	}
+
+	w := newTextWriter(limits.MaxTextSize)
+	inText := false
+	runs := 0
+	err = pkg.walk(pkg.mainPart("word/document.xml"), func(tok xml.Token) bool {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "r":
+				runs++
+			case "t":
+				inText = true
+			case "tab":
+				// Outside runs, tab elements are tab stop definitions
+				if runs > 0 {
+					w.WriteString("\t")
+				}
+			case "br", "cr":
+				if runs > 0 {
+					w.WriteString("\n")
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "r":
+				runs--
+			case "t":
+				inText = false
+			case "p":
+				w.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				w.WriteString(string(t))
+			}
+		}
+		return !w.full()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX: %w", err)
+	}
+
+	return &FileContent{
+		Type:      "docx",
+		Raw:       strings.TrimSpace(w.String()),
+		Truncated: w.full() || pkg.truncated,
+	}, nil
+}
+
+// PPTXParser extracts the text of PowerPoint slides in presentation order
+type PPTXParser struct {
+	Limits Limits
+}
+
+func (p *PPTXParser) CanParse(filename string, mimeType string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".pptx" || strings.HasPrefix(mimeType, mimePPTX)
+}
+
+func (p *PPTXParser) maxFileSize() int64 {
+	return p.Limits.withDefaults().MaxFileSize
+}
+
+func (p *PPTXParser) Parse(content []byte) (*FileContent, error) {
+	limits := p.Limits.withDefaults()
+	pkg, err := openOOXML(content, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PPTX: %w", err)
+	}
+
+	presentation := pkg.mainPart("ppt/presentation.xml")
+	rels, err := pkg.relationships(presentation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PPTX: %w", err)
+	}
+
+	var slides []string
+	err = pkg.walk(presentation, func(tok xml.Token) bool {
+		if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "sldId" {
+			if target, ok := rels[relID(t)]; ok {
+				slides = append(slides, target)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PPTX: %w", err)
+	}
+
+	truncated := len(slides) > limits.MaxPages
+	if truncated {
+		slides = slides[:limits.MaxPages]
+	}
+
+	w := newTextWriter(limits.MaxTextSize)
+	for i, slide := range slides {
+		if i > 0 {
+			w.WriteString("\n")
+		}
+		w.WriteString(fmt.Sprintf("## Slide %d\n", i+1))
+
+		inText := false
+		err := pkg.walk(slide, func(tok xml.Token) bool {
+			switch t := tok.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "t":
+					inText = true
+				case "br":
+					w.WriteString("\n")
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "t":
+					inText = false
+				case "p":
+					w.newline()
+				}
+			case xml.CharData:
+				if inText {
+					w.WriteString(string(t))
+				}
+			}
+			return !w.full()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PPTX slide %d: %w", i+1, err)
+		}
+		if w.full() {
+			break
+		}
+	}
+
+	return &FileContent{
+		Type:      "pptx",
+		Raw:       strings.TrimSpace(w.String()),
+		Truncated: truncated || w.full() || pkg.truncated,
+	}, nil
+}
+
+// XLSXParser extracts each worksheet of an Excel workbook as CSV.
+// Cells keep their column position; formulas yield their cached value and dates their serial number.
+type XLSXParser struct {
+	Limits Limits
+}
+
+func (p *XLSXParser) CanParse(filename string, mimeType string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".xlsx" || strings.HasPrefix(mimeType, mimeXLSX)
+}
+
+func (p *XLSXParser) maxFileSize() int64 {
+	return p.Limits.withDefaults().MaxFileSize
+}
+
+func (p *XLSXParser) Parse(content []byte) (*FileContent, error) {
+	limits := p.Limits.withDefaults()
+	pkg, err := openOOXML(content, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XLSX: %w", err)
+	}
+
+	workbook := pkg.mainPart("xl/workbook.xml")
+	rels, err := pkg.relationships(workbook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XLSX: %w", err)
+	}
+
+	type sheetRef struct{ name, part string }
+	var sheets []sheetRef
+	err = pkg.walk(workbook, func(tok xml.Token) bool {
+		if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "sheet" {
+			if target, ok := rels[relID(t)]; ok {
+				sheets = append(sheets, sheetRef{name: attr(t, "name"), part: target})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XLSX: %w", err)
+	}
+
+	shared, err := pkg.sharedStrings(path.Join(path.Dir(workbook), "sharedStrings.xml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XLSX: %w", err)
+	}
+
+	// Both Raw and Sheets hold the CSV, so each gets half of the text budget
+	budget := newTextWriter(limits.MaxTextSize / 2)
+	out := &FileContent{Type: "xlsx", Sheets: []Sheet{}}
+	raw := &strings.Builder{}
+	for _, sheet := range sheets {
+		data, err := pkg.sheetCSV(sheet.part, shared, budget)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse XLSX sheet %q: %w", sheet.name, err)
+		}
+		out.Sheets = append(out.Sheets, Sheet{Name: sheet.name, CSV: data})
+		if raw.Len() > 0 {
+			raw.WriteString("\n")
+		}
+		raw.WriteString("## " + sheet.name + "\n" + data)
+		if budget.full() {
+			break
+		}
+	}
+
+	out.Raw = strings.TrimSpace(raw.String())
+	out.Truncated = budget.full() || pkg.truncated
+	return out, nil
+}
+
+// sheetCSV renders a worksheet as CSV, charging its size to budget
+func (pkg *ooxmlPackage) sheetCSV(part string, shared []string, budget *textWriter) (string, error) {
+	var (
+		sheet    strings.Builder
+		row      []string
+		cellCol  int
+		cellType string
+		value    strings.Builder
+		inValue  bool
+		line     bytes.Buffer
+	)
+	lineWriter := csv.NewWriter(&line)
+
+	err := pkg.walk(part, func(tok xml.Token) bool {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = row[:0]
+			case "c":
+				cellCol = len(row)
+				if ref := attr(t, "r"); ref != "" {
+					if col, ok := columnIndex(ref); ok {
+						cellCol = col
+					}
+				}
+				cellType = attr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if cellCol >= maxSheetColumns || cellCol < len(row) {
+					return true
+				}
+				for len(row) < cellCol {
+					row = append(row, "")
+				}
+				row = append(row, cellValue(value.String(), cellType, shared))
+			case "row":
+				line.Reset()
+				_ = lineWriter.Write(row)
+				lineWriter.Flush()
+				s := line.String()
+				budget.WriteString(s)
+				if budget.full() {
+					return false
+				}
+				sheet.WriteString(s)
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+		return true
+	})
+	return sheet.String(), err
+}
+
+func cellValue(v string, typ string, shared []string) string {
+	switch typ {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "b":
+		if strings.TrimSpace(v) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return v
+}
+
+// columnIndex returns the zero-based column of a cell reference like "AB12"
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+		if col > maxSheetColumns {
+			return 0, false
+		}
+	}
+	if i == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// sharedStrings reads the workbook's shared string table, which is absent when no cell holds text
+func (pkg *ooxmlPackage) sharedStrings(part string) ([]string, error) {
+	if _, ok := pkg.files[part]; !ok {
+		return nil, nil
+	}
+
+	var (
+		strs     []string
+		item     strings.Builder
+		inText   bool
+		phonetic bool
+	)
+	err := pkg.walk(part, func(tok xml.Token) bool {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				item.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				// Phonetic guides repeat the reading of the text
+				phonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, item.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic = false
+			}
+		case xml.CharData:
+			if inText && !phonetic {
+				item.Write(t)
+			}
+		}
+		return true
+	})
+	return strs, err
+}
+
+type ooxmlPackage struct {
+	files     map[string]*zip.File
+	limits    Limits
+	truncated bool // A part hit Limits.MaxDecompressedSize
+}
+
+func openOOXML(content []byte, limits Limits) (*ooxmlPackage, error) {
+	if int64(len(content)) > limits.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &ooxmlPackage{files: make(map[string]*zip.File, len(zr.File)), limits: limits}
+	for _, f := range zr.File {
+		pkg.files[f.Name] = f
+	}
+	return pkg, nil
+}
+
+// walk streams the tokens of a part to fn until fn returns false
+func (pkg *ooxmlPackage) walk(part string, fn func(xml.Token) bool) error {
+	f, ok := pkg.files[part]
+	if !ok {
+		return fmt.Errorf("missing part %s", part)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	limited := &io.LimitedReader{R: rc, N: pkg.limits.MaxDecompressedSize}
+	dec := xml.NewDecoder(limited)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if limited.N <= 0 {
+			// The part was cut by the decompression limit, likely mid-element
+			pkg.truncated = true
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if pkg.truncated {
+				return nil
+			}
+			return err
+		}
+		if !fn(tok) {
+			return nil
+		}
+	}
+}
+
+// mainPart returns the document part named by the package relationships, or fallback
+func (pkg *ooxmlPackage) mainPart(fallback string) string {
+	rels, err := pkg.relationshipsOf("_rels/.rels", "")
+	if err == nil {
+		for _, rel := range rels.all {
+			if strings.HasSuffix(rel.typ, "/officeDocument") {
+				if _, ok := pkg.files[rel.part]; ok {
+					return rel.part
+				}
+			}
+		}
+	}
+	return fallback
+}
+
+type ooxmlRel struct {
+	typ  string
+	part string
+}
+
+type ooxmlRels struct {
+	byID map[string]string
+	all  []ooxmlRel
+}
+
+// relationships maps the relationship IDs of a part to the parts they target
+func (pkg *ooxmlPackage) relationships(part string) (map[string]string, error) {
+	dir, name := path.Split(part)
+	rels, err := pkg.relationshipsOf(path.Join(dir, "_rels", name+".rels"), dir)
+	if err != nil {
+		return nil, err
+	}
+	return rels.byID, nil
+}
+
+func (pkg *ooxmlPackage) relationshipsOf(relsPart string, dir string) (*ooxmlRels, error) {
+	rels := &ooxmlRels{byID: map[string]string{}}
+	err := pkg.walk(relsPart, func(tok xml.Token) bool {
+		t, ok := tok.(xml.StartElement)
+		if !ok || t.Name.Local != "Relationship" || attr(t, "TargetMode") == "External" {
+			return true
+		}
+		target := attr(t, "Target")
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+		rels.byID[attr(t, "Id")] = target
+		rels.all = append(rels.all, ooxmlRel{typ: attr(t, "Type"), part: target})
+		return true
+	})
+	return rels, err
+}
+
+// relID returns the r:id attribute linking an element to another part
+func relID(t xml.StartElement) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == "id" && a.Name.Space != "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name && a.Name.Space == "" {
+			return a.Value
+		}
+	}
+	return ""
+}
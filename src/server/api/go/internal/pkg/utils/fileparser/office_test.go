@@ -0,0 +1,147 @@
+package fileparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func buildZip(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDOCXParser(t *testing.T) {
+	doc := buildZip(t, map[string]string{
+		"_rels/.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Quarterly</w:t></w:r><w:r><w:t xml:space="preserve"> report</w:t></w:r></w:p>
+<w:p><w:r><w:t>Revenue</w:t><w:tab/><w:t>42 &amp; rising</w:t><w:br/><w:t>Next line</w:t></w:r></w:p>
+</w:body></w:document>`,
+	})
+
+	parser := NewFileParser()
+	if !parser.CanParseFile("report.docx", "application/zip") {
+		t.Fatal("CanParseFile() should accept .docx")
+	}
+	result, err := parser.ParseFile("report.docx", "", doc)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if result.Type != "docx" {
+		t.Errorf("ParseFile() type = %v, want docx", result.Type)
+	}
+	if want := "Quarterly report\nRevenue\t42 & rising\nNext line"; result.Raw != want {
+		t.Errorf("ParseFile() raw = %q, want %q", result.Raw, want)
+	}
+
+	if _, err := (&DOCXParser{}).Parse([]byte("not a zip")); err == nil {
+		t.Error("Parse() should fail for a file that is not a zip package")
+	}
+}
+
+func TestXLSXParser(t *testing.T) {
+	doc := buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sales" sheetId="1" r:id="rId2"/><sheet name="Notes" sheetId="2" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet2.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Region</t></si><si><t>Total</t></si><si><r><t>North, </t></r><r><t>East</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="C2"><f>SUM(1,2)</f><v>3</v></c></row>
+<row r="3"><c r="B3" t="b"><v>1</v></c><c r="C3" t="inlineStr"><is><t>inline</t></is></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="str"><v>note</v></c></row></sheetData></worksheet>`,
+	})
+
+	result, err := NewFileParser().ParseFile("sales.xlsx", "", doc)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if result.Type != "xlsx" {
+		t.Errorf("ParseFile() type = %v, want xlsx", result.Type)
+	}
+	if len(result.Sheets) != 2 {
+		t.Fatalf("ParseFile() sheets = %d, want 2", len(result.Sheets))
+	}
+	if result.Sheets[0].Name != "Sales" || result.Sheets[1].Name != "Notes" {
+		t.Errorf("ParseFile() sheet names = %q, %q", result.Sheets[0].Name, result.Sheets[1].Name)
+	}
+	if want := "Region,Total\n\"North, East\",,3\n,TRUE,inline\n"; result.Sheets[0].CSV != want {
+		t.Errorf("ParseFile() sheet CSV = %q, want %q", result.Sheets[0].CSV, want)
+	}
+	if want := "## Sales\nRegion,Total\n\"North, East\",,3\n,TRUE,inline\n\n## Notes\nnote"; result.Raw != want {
+		t.Errorf("ParseFile() raw = %q, want %q", result.Raw, want)
+	}
+
+	limited, err := (&XLSXParser{Limits: Limits{MaxTextSize: 30}}).Parse(doc)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !limited.Truncated || len(limited.Sheets) != 1 || limited.Sheets[0].CSV != "Region,Total\n" {
+		t.Errorf("Parse() with a text limit = %+v", limited)
+	}
+}
+
+func TestPPTXParser(t *testing.T) {
+	doc := buildZip(t, map[string]string{
+		"ppt/presentation.xml": `<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml": `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+<p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>Roadmap</a:t></a:r></a:p><a:p><a:r><a:t>Q1</a:t></a:r><a:br/><a:r><a:t>Q2</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`,
+		"ppt/slides/slide2.xml": `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+<p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>Questions</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`,
+	})
+
+	result, err := NewFileParser().ParseFile("deck.pptx", "", doc)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if want := "## Slide 1\nRoadmap\nQ1\nQ2\n\n## Slide 2\nQuestions"; result.Raw != want {
+		t.Errorf("ParseFile() raw = %q, want %q", result.Raw, want)
+	}
+
+	limited, err := (&PPTXParser{Limits: Limits{MaxPages: 1}}).Parse(doc)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := "## Slide 1\nRoadmap\nQ1\nQ2"; limited.Raw != want || !limited.Truncated {
+		t.Errorf("Parse() with a slide limit = %q truncated=%v", limited.Raw, limited.Truncated)
+	}
+}
+
+func TestFileParser_CheckSize(t *testing.T) {
+	parser := NewFileParser()
+
+	if err := parser.CheckSize("big.pdf", "application/pdf", DefaultLimits.MaxFileSize+1); err != ErrFileTooLarge {
+		t.Errorf("CheckSize() error = %v, want %v", err, ErrFileTooLarge)
+	}
+	if err := parser.CheckSize("small.docx", "", 1024); err != nil {
+		t.Errorf("CheckSize() error = %v, want nil", err)
+	}
+	// Plain text has no document limit
+	if err := parser.CheckSize("big.txt", "text/plain", DefaultLimits.MaxFileSize+1); err != nil {
+		t.Errorf("CheckSize() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,1018 @@
+package fileparser
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	errNotPDF       = errors.New("not a PDF document")
+	errEncryptedPDF = errors.New("encrypted PDF documents are not supported")
+)
+
+// PDFParser extracts the text of PDF documents.
+// It reads the page tree and interprets the text operators of each page's content streams,
+// mapping glyphs to Unicode through the fonts' ToUnicode CMaps, or WinAnsi for simple fonts without one.
+// Only FlateDecode, ASCIIHexDecode and ASCII85Decode streams are read; encrypted documents are rejected.
+type PDFParser struct {
+	Limits Limits
+}
+
+func (p *PDFParser) CanParse(filename string, mimeType string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".pdf" || strings.HasPrefix(mimeType, "application/pdf")
+}
+
+func (p *PDFParser) maxFileSize() int64 {
+	return p.Limits.withDefaults().MaxFileSize
+}
+
+func (p *PDFParser) Parse(content []byte) (*FileContent, error) {
+	limits := p.Limits.withDefaults()
+	if int64(len(content)) > limits.MaxFileSize {
+		return nil, ErrFileTooLarge
+	}
+
+	doc, err := loadPDF(content, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PDF: %w", err)
+	}
+
+	pages, truncated := doc.pages(limits.MaxPages)
+	w := newTextWriter(limits.MaxTextSize)
+	for i, page := range pages {
+		if i > 0 {
+			w.WriteString("\n\n")
+		}
+		doc.runContent(doc.contents(page.dict["Contents"]), page.resources, w, 0)
+		if w.full() {
+			break
+		}
+	}
+
+	return &FileContent{
+		Type:      "pdf",
+		Raw:       strings.TrimSpace(w.String()),
+		Truncated: truncated || w.full(),
+	}, nil
+}
+
+// PDF object model
+type (
+	pdfName    string
+	pdfString  string // raw bytes, decoded through the current font
+	pdfKeyword string // operators, delimiters and other bare words
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte // still encoded
+	}
+)
+
+// maxPDFNesting bounds array and dictionary nesting while parsing
+const maxPDFNesting = 64
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token returns the next number, name, string or keyword; false at the end of data
+func (l *pdfLexer) token() (any, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+
+	c := l.data[l.pos]
+	switch c {
+	case '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+			l.pos++
+		}
+		return decodePDFName(l.data[start:l.pos]), true
+	case '(':
+		return l.literalString(), true
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), true
+		}
+		return l.hexString(), true
+	case '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), true
+		}
+		l.pos++
+		return pdfKeyword(">"), true
+	case '[', ']', '{', '}', ')':
+		l.pos++
+		return pdfKeyword(c), true
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if strings.IndexByte("+-.0123456789", word[0]) >= 0 {
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, true
+		}
+	}
+	return pdfKeyword(word), true
+}
+
+func decodePDFName(b []byte) pdfName {
+	if bytes.IndexByte(b, '#') < 0 {
+		return pdfName(b)
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return pdfName(out)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return pdfString(b)
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	n, _ := hex.Decode(b, digits)
+	return pdfString(b[:n])
+}
+
+// object reads the next complete object, including arrays, dictionaries and references
+func (l *pdfLexer) object(depth int) (any, bool) {
+	tok, ok := l.token()
+	if !ok {
+		return nil, false
+	}
+	return l.complete(tok, depth), true
+}
+
+// complete turns a token into an object, reading the rest of an array, dictionary or reference
+func (l *pdfLexer) complete(tok any, depth int) any {
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			arr := pdfArray{}
+			for depth < maxPDFNesting {
+				tok, ok := l.token()
+				if !ok || tok == pdfKeyword("]") {
+					break
+				}
+				arr = append(arr, l.complete(tok, depth+1))
+			}
+			return arr
+		case "<<":
+			dict := pdfDict{}
+			for depth < maxPDFNesting {
+				tok, ok := l.token()
+				if !ok || tok == pdfKeyword(">>") {
+					break
+				}
+				name, isName := tok.(pdfName)
+				if !isName {
+					continue
+				}
+				val, ok := l.object(depth + 1)
+				if !ok {
+					break
+				}
+				dict[name] = val
+			}
+			return dict
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+	case float64:
+		// "num gen R" is a reference
+		save := l.pos
+		if gen, ok := l.token(); ok {
+			if g, isNum := gen.(float64); isNum {
+				if r, ok := l.token(); ok && r == pdfKeyword("R") {
+					return pdfRef{num: int(t), gen: int(g)}
+				}
+			}
+		}
+		l.pos = save
+	}
+	return tok
+}
+
+type pdfDoc struct {
+	objects  map[int]any
+	trailers []pdfDict
+	limits   Limits
+	fonts    map[pdfRef]*pdfFont
+}
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// loadPDF indexes every object by scanning for "n g obj" headers rather than trusting the xref table,
+// which also recovers documents with damaged cross-reference data
+func loadPDF(data []byte, limits Limits) (*pdfDoc, error) {
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if !bytes.Contains(head, []byte("%PDF-")) {
+		return nil, errNotPDF
+	}
+
+	d := &pdfDoc{objects: map[int]any{}, limits: limits, fonts: map[pdfRef]*pdfFont{}}
+
+	end := 0
+	for _, m := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] < end {
+			// Inside the previous object's stream data
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		lex := &pdfLexer{data: data, pos: m[1]}
+		obj, ok := lex.object(0)
+		if !ok {
+			break
+		}
+		end = lex.pos
+		if dict, isDict := obj.(pdfDict); isDict {
+			save := lex.pos
+			if tok, ok := lex.token(); ok && tok == pdfKeyword("stream") {
+				stream, stop := readPDFStream(data, lex.pos, dict)
+				obj, end = stream, stop
+			} else {
+				lex.pos = save
+			}
+		}
+		d.objects[num] = obj
+	}
+
+	// Classic trailers, and cross-reference streams which carry the trailer entries themselves
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		lex := &pdfLexer{data: data, pos: i + j + len("trailer")}
+		if obj, ok := lex.object(0); ok {
+			if dict, isDict := obj.(pdfDict); isDict {
+				d.trailers = append(d.trailers, dict)
+			}
+		}
+		i += j + len("trailer")
+	}
+	for _, obj := range d.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("XRef") {
+			d.trailers = append(d.trailers, s.dict)
+		}
+	}
+	for _, t := range d.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			return nil, errEncryptedPDF
+		}
+	}
+
+	d.loadObjectStreams()
+	return d, nil
+}
+
+// readPDFStream returns the stream starting after the "stream" keyword at pos and the offset where it ends
+func readPDFStream(data []byte, pos int, dict pdfDict) (*pdfStream, int) {
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+
+	// Trust a direct /Length only when "endstream" follows it
+	if n, ok := dict["Length"].(float64); ok && n >= 0 && pos+int(n) <= len(data) {
+		stop := pos + int(n)
+		rest := data[stop:min(stop+32, len(data))]
+		if bytes.HasPrefix(bytes.TrimLeft(rest, " \t\r\n"), []byte("endstream")) {
+			return &pdfStream{dict: dict, data: data[pos:stop]}, stop
+		}
+	}
+
+	i := bytes.Index(data[pos:], []byte("endstream"))
+	if i < 0 {
+		return &pdfStream{dict: dict, data: data[pos:]}, len(data)
+	}
+	stop := pos + i
+	for stop > pos && (data[stop-1] == '\n' || data[stop-1] == '\r') {
+		stop--
+	}
+	return &pdfStream{dict: dict, data: data[pos:stop]}, pos + i
+}
+
+// loadObjectStreams adds the objects compressed in object streams (PDF 1.5+)
+func (d *pdfDoc) loadObjectStreams() {
+	var streams []*pdfStream
+	for _, obj := range d.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, s)
+		}
+	}
+
+	for _, s := range streams {
+		n, _ := d.resolve(s.dict["N"]).(float64)
+		first, _ := d.resolve(s.dict["First"]).(float64)
+		data, err := d.decode(s)
+		if err != nil || int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:int(first)]}
+		for i := 0; i < int(n); i++ {
+			numTok, ok1 := header.token()
+			offTok, ok2 := header.token()
+			num, isNum := numTok.(float64)
+			off, isOff := offTok.(float64)
+			if !ok1 || !ok2 || !isNum || !isOff {
+				break
+			}
+			if _, exists := d.objects[int(num)]; exists {
+				continue
+			}
+			lex := &pdfLexer{data: data, pos: int(first) + int(off)}
+			if lex.pos >= len(data) {
+				continue
+			}
+			if obj, ok := lex.object(0); ok {
+				d.objects[int(num)] = obj
+			}
+		}
+	}
+}
+
+// resolve follows references to their objects
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch t := d.resolve(v).(type) {
+	case pdfDict:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+// decode applies the stream's filters
+func (d *pdfDoc) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case pdfArray:
+		filters = f
+	}
+
+	data := s.data
+	for _, f := range filters {
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data = inflate(data, d.limits.MaxDecompressedSize)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			lex := &pdfLexer{data: append([]byte{'<'}, data...)}
+			data = []byte(lex.hexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+			if i := bytes.Index(data, []byte("~>")); i >= 0 {
+				data = data[:i]
+			}
+			out, err := io.ReadAll(io.LimitReader(ascii85.NewDecoder(bytes.NewReader(data)), d.limits.MaxDecompressedSize))
+			if err != nil && len(out) == 0 {
+				return nil, err
+			}
+			data = out
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", f)
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib (or raw deflate) data, keeping whatever was read before a corruption
+func inflate(data []byte, limit int64) []byte {
+	var r io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		r = zr
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	out, _ := io.ReadAll(io.LimitReader(r, limit))
+	return out
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages walks the page tree in order, returning at most max pages and whether more exist
+func (d *pdfDoc) pages(max int) ([]pdfPage, bool) {
+	var root any
+	for i := len(d.trailers) - 1; i >= 0 && root == nil; i-- {
+		if catalog := d.dict(d.trailers[i]["Root"]); catalog != nil {
+			root = catalog["Pages"]
+		}
+	}
+	if root == nil {
+		for _, num := range d.objectNumbers() {
+			if dict, ok := d.objects[num].(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+				root = dict["Pages"]
+				break
+			}
+		}
+	}
+
+	var pages []pdfPage
+	complete := d.walkPages(root, nil, map[pdfRef]bool{}, &pages, max, 0)
+	if len(pages) == 0 {
+		// No usable page tree: fall back to the page objects in numbering order
+		for _, num := range d.objectNumbers() {
+			if dict, ok := d.objects[num].(pdfDict); ok && dict["Type"] == pdfName("Page") {
+				if len(pages) == max {
+					return pages, true
+				}
+				pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+			}
+		}
+		return pages, false
+	}
+	return pages, !complete
+}
+
+// walkPages appends the pages under node, returning false once more than max pages were found
+func (d *pdfDoc) walkPages(node any, resources pdfDict, seen map[pdfRef]bool, pages *[]pdfPage, max int, depth int) bool {
+	if ref, ok := node.(pdfRef); ok {
+		if seen[ref] {
+			return true
+		}
+		seen[ref] = true
+	}
+	dict := d.dict(node)
+	if dict == nil || depth > maxPDFNesting {
+		return true
+	}
+	if r := d.dict(dict["Resources"]); r != nil {
+		resources = r
+	}
+
+	if kids, ok := d.resolve(dict["Kids"]).(pdfArray); ok && dict["Type"] != pdfName("Page") {
+		for _, kid := range kids {
+			if !d.walkPages(kid, resources, seen, pages, max, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(*pages) == max {
+		return false
+	}
+	*pages = append(*pages, pdfPage{dict: dict, resources: resources})
+	return true
+}
+
+func (d *pdfDoc) objectNumbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// contents returns the decoded content of a page, whose streams may be split in an array
+func (d *pdfDoc) contents(v any) []byte {
+	switch t := d.resolve(v).(type) {
+	case *pdfStream:
+		data, _ := d.decode(t)
+		return data
+	case pdfArray:
+		var out []byte
+		for _, part := range t {
+			if s, ok := d.resolve(part).(*pdfStream); ok {
+				if data, err := d.decode(s); err == nil {
+					out = append(append(out, data...), '\n')
+				}
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// runContent interprets the text operators of a content stream
+func (d *pdfDoc) runContent(data []byte, resources pdfDict, w *textWriter, depth int) {
+	lex := &pdfLexer{data: data}
+	var operands []any
+	var font *pdfFont
+	var lineY float64
+	haveLine := false
+
+	for !w.full() {
+		tok, ok := lex.token()
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp || op == "[" || op == "<<" {
+			if len(operands) < 32 {
+				operands = append(operands, lex.complete(tok, 0))
+			}
+			continue
+		}
+
+		switch op {
+		case "BI":
+			lex.skipInlineImage()
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = d.font(resources, name)
+				}
+			}
+		case "Tj":
+			if s, ok := lastOperand(operands).(pdfString); ok {
+				w.WriteString(font.decode(s))
+			}
+		case "'", "\"":
+			w.newline()
+			if s, ok := lastOperand(operands).(pdfString); ok {
+				w.WriteString(font.decode(s))
+			}
+		case "TJ":
+			arr, _ := lastOperand(operands).(pdfArray)
+			for _, el := range arr {
+				switch v := el.(type) {
+				case pdfString:
+					w.WriteString(font.decode(v))
+				case float64:
+					// A large negative adjustment (in thousandths of an em) moves on by a word gap
+					if v < -200 {
+						w.space()
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[len(operands)-2].(float64)
+				ty, _ := operands[len(operands)-1].(float64)
+				if ty != 0 {
+					w.newline()
+				} else if tx > 0 {
+					w.space()
+				}
+			}
+		case "T*":
+			w.newline()
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[5].(float64)
+				if !haveLine || y != lineY {
+					w.newline()
+				} else {
+					w.space()
+				}
+				lineY, haveLine = y, true
+			}
+		case "Do":
+			if name, ok := lastOperand(operands).(pdfName); ok && depth < 8 {
+				xobj, _ := d.resolve(d.dict(resources["XObject"])[name]).(*pdfStream)
+				if xobj != nil && xobj.dict["Subtype"] == pdfName("Form") {
+					formResources := d.dict(xobj.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					if data, err := d.decode(xobj); err == nil {
+						w.newline()
+						d.runContent(data, formResources, w, depth+1)
+						w.newline()
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func lastOperand(operands []any) any {
+	if len(operands) == 0 {
+		return nil
+	}
+	return operands[len(operands)-1]
+}
+
+// skipInlineImage moves past the binary data of an inline image (BI ... ID data EI)
+func (l *pdfLexer) skipInlineImage() {
+	for {
+		tok, ok := l.token()
+		if !ok {
+			return
+		}
+		if tok == pdfKeyword("ID") {
+			break
+		}
+	}
+	for i := l.pos + 1; i+1 < len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isPDFSpace(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFSpace(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// pdfFont maps the character codes of a font to text
+type pdfFont struct {
+	toUnicode  *pdfCMap
+	twoByte    bool          // Type0 fonts use multi-byte codes
+	difference map[byte]rune // Encoding /Differences of a simple font
+}
+
+func (d *pdfDoc) font(resources pdfDict, name pdfName) *pdfFont {
+	ref, isRef := d.dict(resources["Font"])[name].(pdfRef)
+	if isRef {
+		if f, ok := d.fonts[ref]; ok {
+			return f
+		}
+	}
+
+	dict := d.dict(d.dict(resources["Font"])[name])
+	if dict == nil {
+		return nil
+	}
+	f := &pdfFont{twoByte: dict["Subtype"] == pdfName("Type0")}
+	if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(s); err == nil {
+			f.toUnicode = parseCMap(data)
+		}
+	}
+	if enc := d.dict(dict["Encoding"]); enc != nil {
+		if diffs, ok := d.resolve(enc["Differences"]).(pdfArray); ok {
+			f.difference = map[byte]rune{}
+			code := 0
+			for _, v := range diffs {
+				switch t := d.resolve(v).(type) {
+				case float64:
+					code = int(t)
+				case pdfName:
+					if r, ok := glyphRune(string(t)); ok && code >= 0 && code < 256 {
+						f.difference[byte(code)] = r
+					}
+					code++
+				}
+			}
+		}
+	}
+
+	if isRef {
+		d.fonts[ref] = f
+	}
+	return f
+}
+
+func (f *pdfFont) decode(s pdfString) string {
+	if f != nil && f.toUnicode != nil {
+		return f.toUnicode.decode([]byte(s), f.twoByte, f.simpleRune)
+	}
+	if f != nil && f.twoByte {
+		// CIDs without a ToUnicode map carry no recoverable text
+		return ""
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if r := f.simpleRune(s[i]); r != 0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// simpleRune maps a single-byte code through the font's differences, falling back to WinAnsi
+func (f *pdfFont) simpleRune(c byte) rune {
+	if f != nil {
+		if r, ok := f.difference[c]; ok {
+			return r
+		}
+	}
+	switch {
+	case c >= 0x80 && c < 0xa0:
+		return winAnsiHigh[c-0x80]
+	case c < 0x20 && c != '\t' && c != '\n' && c != '\r':
+		return 0
+	}
+	return rune(c)
+}
+
+// winAnsiHigh holds the WinAnsiEncoding characters for 0x80-0x9f; the rest matches Latin-1
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// glyphNames covers the common glyph names used in /Differences besides single letters and uniXXXX
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$', "percent": '%',
+	"ampersand": '&', "quotesingle": '\'', "quoteright": '’', "quoteleft": '‘', "parenleft": '(',
+	"parenright": ')', "asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "period": '.',
+	"slash": '/', "colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>',
+	"question": '?', "at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']',
+	"underscore": '_', "braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6',
+	"seven": '7', "eight": '8', "nine": '9', "endash": '–', "emdash": '—', "bullet": '•',
+	"quotedblleft": '“', "quotedblright": '”', "ellipsis": '…', "fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ',
+	"ffi": 'ﬃ', "ffl": 'ﬄ', "dieresis": '¨', "acute": '´', "grave": '`', "circumflex": 'ˆ',
+	"tilde": '˜', "degree": '°', "copyright": '©', "registered": '®', "trademark": '™',
+}
+
+func glyphRune(name string) (rune, bool) {
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if hexCode, ok := strings.CutPrefix(name, "uni"); ok && len(hexCode) == 4 {
+		if v, err := strconv.ParseUint(hexCode, 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	if hexCode, ok := strings.CutPrefix(name, "u"); ok && len(hexCode) >= 4 && len(hexCode) <= 6 {
+		if v, err := strconv.ParseUint(hexCode, 16, 32); err == nil {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
+
+// pdfCMap is a parsed ToUnicode CMap
+type pdfCMap struct {
+	codespaces []pdfCodespace
+	mappings   map[uint32]string
+}
+
+type pdfCodespace struct {
+	n      int
+	lo, hi uint32
+}
+
+// maxCMapRange bounds the codes expanded from a single bfrange entry
+const maxCMapRange = 1 << 16
+
+func parseCMap(data []byte) *pdfCMap {
+	m := &pdfCMap{mappings: map[uint32]string{}}
+	lex := &pdfLexer{data: data}
+
+	for {
+		tok, ok := lex.token()
+		if !ok {
+			break
+		}
+		switch tok {
+		case pdfKeyword("begincodespacerange"):
+			for {
+				lo, ok1 := lex.object(0)
+				hi, ok2 := lex.object(0)
+				los, isLo := lo.(pdfString)
+				his, isHi := hi.(pdfString)
+				if !ok1 || !ok2 || !isLo || !isHi {
+					break
+				}
+				m.codespaces = append(m.codespaces, pdfCodespace{n: len(los), lo: codeValue(los), hi: codeValue(his)})
+			}
+		case pdfKeyword("beginbfchar"):
+			for {
+				src, ok1 := lex.object(0)
+				dst, ok2 := lex.object(0)
+				srcs, isSrc := src.(pdfString)
+				dsts, isDst := dst.(pdfString)
+				if !ok1 || !ok2 || !isSrc || !isDst {
+					break
+				}
+				m.mappings[codeValue(srcs)] = utf16BE(dsts)
+			}
+		case pdfKeyword("beginbfrange"):
+			for {
+				lo, ok1 := lex.object(0)
+				hi, ok2 := lex.object(0)
+				dst, ok3 := lex.object(0)
+				los, isLo := lo.(pdfString)
+				his, isHi := hi.(pdfString)
+				if !ok1 || !ok2 || !ok3 || !isLo || !isHi {
+					break
+				}
+				start, stop := codeValue(los), codeValue(his)
+				if stop < start || stop-start >= maxCMapRange {
+					continue
+				}
+				switch t := dst.(type) {
+				case pdfString:
+					base := []rune(utf16BE(t))
+					if len(base) == 0 {
+						continue
+					}
+					for code := start; code <= stop; code++ {
+						runes := append([]rune{}, base...)
+						runes[len(runes)-1] += rune(code - start)
+						m.mappings[code] = string(runes)
+					}
+				case pdfArray:
+					for i, v := range t {
+						if s, ok := v.(pdfString); ok && start+uint32(i) <= stop {
+							m.mappings[start+uint32(i)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+func codeValue(s pdfString) uint32 {
+	var v uint32
+	for i := 0; i < len(s) && i < 4; i++ {
+		v = v<<8 | uint32(s[i])
+	}
+	return v
+}
+
+func utf16BE(s pdfString) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	if len(s) == 1 {
+		return string(rune(s[0]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// codeLength returns how many bytes the code starting at b takes
+func (m *pdfCMap) codeLength(b []byte, twoByte bool) int {
+	for _, cs := range m.codespaces {
+		if cs.n <= len(b) {
+			if v := codeValue(pdfString(b[:cs.n])); v >= cs.lo && v <= cs.hi {
+				return cs.n
+			}
+		}
+	}
+	if twoByte && len(b) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+func (m *pdfCMap) decode(b []byte, twoByte bool, fallback func(byte) rune) string {
+	var out strings.Builder
+	for i := 0; i < len(b); {
+		n := m.codeLength(b[i:], twoByte)
+		if s, ok := m.mappings[codeValue(pdfString(b[i:i+n]))]; ok {
+			out.WriteString(s)
+		} else if n == 1 && !twoByte {
+			if r := fallback(b[i]); r != 0 {
+				out.WriteRune(r)
+			}
+		}
+		i += n
+	}
+	return out.String()
+}
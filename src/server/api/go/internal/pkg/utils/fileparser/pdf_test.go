@@ -0,0 +1,173 @@
+package fileparser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF lays out numbered objects (starting at 1, the catalog) and a trailer; empty objects are skipped
+func buildPDF(objects ...string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		if obj == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return []byte(b.String())
+}
+
+func pdfStreamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestPDFParser(t *testing.T) {
+	helvetica := "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"
+
+	t.Run("simple font", func(t *testing.T) {
+		content := "BT /F1 12 Tf 72 720 Td (Hello \\(PDF\\) World) Tj 0 -14 Td [(Sec) -20 (ond) -400 (line)] TJ ET"
+		doc := buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R >> >> >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			pdfStreamObject("", []byte(content)),
+			helvetica,
+		)
+
+		result, err := (&PDFParser{}).Parse(doc)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if result.Type != "pdf" {
+			t.Errorf("Parse() type = %v, want pdf", result.Type)
+		}
+		if want := "Hello (PDF) World\nSecond line"; result.Raw != want {
+			t.Errorf("Parse() raw = %q, want %q", result.Raw, want)
+		}
+	})
+
+	t.Run("compressed content with a ToUnicode CMap", func(t *testing.T) {
+		cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+			"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+			"2 beginbfchar <0001> <0048> <0002> <00E9> endbfchar\n" +
+			"1 beginbfrange <0010> <0012> <0061> endbfrange\n" +
+			"endcmap CMapName currentdict /CMap defineresource pop end end"
+		doc := buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents [4 0 R 7 0 R] /Resources << /Font << /F2 5 0 R >> >> >>",
+			pdfStreamObject("/Filter /FlateDecode", deflate("BT /F2 10 Tf <00010002> Tj ET")),
+			"<< /Type /Font /Subtype /Type0 /BaseFont /ABC+Font /Encoding /Identity-H /ToUnicode 6 0 R >>",
+			pdfStreamObject("/Filter /FlateDecode", deflate(cmap)),
+			pdfStreamObject("/Filter /FlateDecode", deflate("BT /F2 10 Tf 1 0 0 1 72 600 Tm <001000110012> Tj ET")),
+		)
+
+		result, err := (&PDFParser{}).Parse(doc)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if want := "Hé\nabc"; result.Raw != want {
+			t.Errorf("Parse() raw = %q, want %q", result.Raw, want)
+		}
+	})
+
+	t.Run("pages in an object stream", func(t *testing.T) {
+		pages := "<< /Type /Pages /Kids [4 0 R] /Count 1 /Resources << /Font << /F1 6 0 R >> >> >>\n"
+		page := "<< /Type /Page /Parent 3 0 R /Contents 5 0 R >>"
+		header := fmt.Sprintf("3 0 4 %d ", len(pages))
+		doc := buildPDF(
+			"<< /Type /Catalog /Pages 3 0 R >>",
+			pdfStreamObject(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), deflate(header+pages+page)),
+			"",
+			"",
+			pdfStreamObject("", []byte("BT /F1 12 Tf (Packed) Tj ET")),
+			helvetica,
+		)
+
+		result, err := (&PDFParser{}).Parse(doc)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if result.Raw != "Packed" {
+			t.Errorf("Parse() raw = %q, want %q", result.Raw, "Packed")
+		}
+	})
+
+	t.Run("page limit", func(t *testing.T) {
+		doc := buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 7 0 R >> >> >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 6 0 R >>",
+			pdfStreamObject("", []byte("BT /F1 12 Tf (one) Tj ET")),
+			pdfStreamObject("", []byte("BT /F1 12 Tf (two) Tj ET")),
+			helvetica,
+		)
+
+		result, err := (&PDFParser{Limits: Limits{MaxPages: 1}}).Parse(doc)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if result.Raw != "one" || !result.Truncated {
+			t.Errorf("Parse() = %q truncated=%v, want first page only and truncated", result.Raw, result.Truncated)
+		}
+
+		result, err = (&PDFParser{}).Parse(doc)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if result.Raw != "one\n\ntwo" || result.Truncated {
+			t.Errorf("Parse() = %q truncated=%v, want both pages", result.Raw, result.Truncated)
+		}
+	})
+
+	t.Run("text limit", func(t *testing.T) {
+		doc := buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			pdfStreamObject("", []byte("BT (abcdefghij) Tj ET")),
+		)
+
+		result, err := (&PDFParser{Limits: Limits{MaxTextSize: 4}}).Parse(doc)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if result.Raw != "abcd" || !result.Truncated {
+			t.Errorf("Parse() = %q truncated=%v, want %q truncated", result.Raw, result.Truncated, "abcd")
+		}
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		doc := []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R /Encrypt 2 0 R >>\n")
+		if _, err := (&PDFParser{}).Parse(doc); !errors.Is(err, errEncryptedPDF) {
+			t.Errorf("Parse() error = %v, want %v", err, errEncryptedPDF)
+		}
+	})
+
+	t.Run("not a PDF", func(t *testing.T) {
+		if _, err := (&PDFParser{}).Parse([]byte("hello")); !errors.Is(err, errNotPDF) {
+			t.Errorf("Parse() error = %v, want %v", err, errNotPDF)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		if _, err := (&PDFParser{Limits: Limits{MaxFileSize: 4}}).Parse([]byte("%PDF-1.7")); !errors.Is(err, ErrFileTooLarge) {
+			t.Errorf("Parse() error = %v, want %v", err, ErrFileTooLarge)
+		}
+	})
+}
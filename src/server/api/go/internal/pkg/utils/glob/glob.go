@@ -0,0 +1,271 @@
+package glob
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ErrBadPattern is returned for malformed patterns: an unclosed class or brace, or a trailing '\'.
+var ErrBadPattern = errors.New("syntax error in glob pattern")
+
+// maxExpansions bounds the number of patterns a brace alternation may expand to
+const maxExpansions = 256
+
+// Match reports whether name matches pattern. Both are '/'-separated paths.
+//
+// The syntax follows doublestar:
+//
+//	*       any sequence of characters except '/'
+//	**      as a whole segment, zero or more segments; elsewhere, like '*'
+//	?       any single character except '/'
+//	[abc]   one character of the class; [!abc] and [^abc] negate it, [a-z] is a range
+//	{a,b}   one of the comma-separated alternatives, which may nest
+//	\x      the literal character x
+func Match(pattern, name string) (bool, error) {
+	patterns, err := Expand(pattern)
+	if err != nil {
+		return false, err
+	}
+	nameSegments := strings.Split(name, "/")
+	for _, p := range patterns {
+		ok, err := matchSegments(strings.Split(p, "/"), nameSegments)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Validate returns ErrBadPattern if pattern is malformed.
+func Validate(pattern string) error {
+	patterns, err := Expand(pattern)
+	if err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		for _, segment := range strings.Split(p, "/") {
+			if _, err := path.Match(normalizeSegment(segment), ""); err != nil {
+				return ErrBadPattern
+			}
+		}
+	}
+	return nil
+}
+
+// Expand expands the brace alternations of pattern, so that the returned patterns hold none.
+func Expand(pattern string) ([]string, error) {
+	out := []string{}
+	if err := expand(pattern, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func expand(pattern string, out *[]string) error {
+	open, close, alternatives, err := findBraces(pattern)
+	if err != nil {
+		return err
+	}
+	if open < 0 {
+		if len(*out) >= maxExpansions {
+			return ErrBadPattern
+		}
+		*out = append(*out, pattern)
+		return nil
+	}
+	for _, alt := range alternatives {
+		if err := expand(pattern[:open]+alt+pattern[close+1:], out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findBraces locates the first top-level brace group of pattern and splits its
+// alternatives. open is -1 when pattern has no braces.
+func findBraces(pattern string) (open, close int, alternatives []string, err error) {
+	open = -1
+	depth := 0
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return -1, -1, nil, ErrBadPattern
+			}
+		case '[':
+			// Braces and commas are literal inside a class
+			end := classEnd(pattern, i)
+			if end < 0 {
+				return -1, -1, nil, ErrBadPattern
+			}
+			i = end
+		case '{':
+			if depth == 0 {
+				open = i
+				start = i + 1
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[start:i])
+				start = i + 1
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				return open, i, append(alternatives, pattern[start:i]), nil
+			}
+		}
+	}
+	if depth > 0 {
+		return -1, -1, nil, ErrBadPattern
+	}
+	return -1, -1, nil, nil
+}
+
+// classEnd returns the index of the ']' closing the class opened at start, or -1.
+func classEnd(pattern string, start int) int {
+	i := start + 1
+	if i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^') {
+		i++
+	}
+	// A ']' right after the opening bracket is part of the class
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// matchSegments matches brace-free pattern segments against name segments.
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive ** segments, then try every split of the rest of name
+			for len(pattern) > 1 && pattern[1] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true, nil
+			}
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern[1:], name[i:])
+				if err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(normalizeSegment(pattern[0]), name[0])
+		if err != nil {
+			return false, ErrBadPattern
+		}
+		if !ok {
+			return false, nil
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// normalizeSegment rewrites a segment into path.Match syntax: "**" inside a
+// segment behaves like '*', and "[!" negates a class like "[^".
+func normalizeSegment(segment string) string {
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch {
+		case c == '\\' && i+1 < len(segment):
+			b.WriteByte(c)
+			i++
+			b.WriteByte(segment[i])
+		case c == '*':
+			b.WriteByte('*')
+			for i+1 < len(segment) && segment[i+1] == '*' {
+				i++
+			}
+		case c == '[' && i+1 < len(segment) && segment[i+1] == '!':
+			b.WriteString("[^")
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ToLike translates a brace-free pattern segment into an SQL LIKE pattern using
+// '\' as escape character. ok is false when the segment uses a class, which
+// LIKE cannot express. The segment must not contain '/'.
+func ToLike(segment string) (like string, ok bool) {
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch c {
+		case '\\':
+			if i+1 < len(segment) {
+				i++
+				c = segment[i]
+			}
+			writeLikeLiteral(&b, c)
+		case '*':
+			b.WriteByte('%')
+			for i+1 < len(segment) && segment[i+1] == '*' {
+				i++
+			}
+		case '?':
+			b.WriteByte('_')
+		case '[':
+			return "", false
+		default:
+			writeLikeLiteral(&b, c)
+		}
+	}
+	return b.String(), true
+}
+
+// Literal returns the unescaped text of a brace-free pattern segment, and
+// false if it holds any wildcard.
+func Literal(segment string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch c {
+		case '\\':
+			if i+1 < len(segment) {
+				i++
+				c = segment[i]
+			}
+		case '*', '?', '[':
+			return "", false
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), true
+}
+
+func writeLikeLiteral(b *strings.Builder, c byte) {
+	if c == '%' || c == '_' || c == '\\' {
+		b.WriteByte('\\')
+	}
+	b.WriteByte(c)
+}
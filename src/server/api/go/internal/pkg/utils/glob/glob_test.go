@@ -0,0 +1,148 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Cases follow doublestar's match tests
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{"abc", "abc", true, false},
+		{"*", "abc", true, false},
+		{"*c", "abc", true, false},
+		{"a*", "a", true, false},
+		{"a*", "abc", true, false},
+		{"a*", "ab/c", false, false},
+		{"a*/b", "abc/b", true, false},
+		{"a*/b", "a/c/b", false, false},
+		{"a*b*c*d*e*/f", "axbxcxdxe/f", true, false},
+		{"a*b*c*d*e*/f", "axbxcxdxexxx/f", true, false},
+		{"a*b*c*d*e*/f", "axbxcxdxe/xxx/f", false, false},
+		{"a*b?c*x", "abxbbxdbxebxczzx", true, false},
+		{"a*b?c*x", "abxbbxdbxebxczzy", false, false},
+		{"ab[c]", "abc", true, false},
+		{"ab[b-d]", "abc", true, false},
+		{"ab[e-g]", "abc", false, false},
+		{"ab[^c]", "abc", false, false},
+		{"ab[!c]", "abc", false, false},
+		{"ab[^b-d]", "abc", false, false},
+		{"ab[!e-g]", "abc", true, false},
+		{"a\\*b", "a*b", true, false},
+		{"a\\*b", "ab", false, false},
+		{"a?b", "a☺b", true, false},
+		{"a[^a]b", "a☺b", true, false},
+		{"a???b", "a☺b", false, false},
+		{"a?b", "a/b", false, false},
+		{"a*b", "a/b", false, false},
+		{"[\\]a]", "]", true, false},
+		{"[\\-]", "-", true, false},
+		{"[x\\-]", "z", false, false},
+		{"[", "a", false, true},
+		{"[^", "a", false, true},
+		{"a[", "a", false, true},
+		{"*x", "xxx", true, false},
+		{"**", "", true, false},
+		{"**", "a", true, false},
+		{"**", "a/b/c", true, false},
+		{"**/c", "c", true, false},
+		{"**/c", "a/b/c", true, false},
+		{"**/*.md", "README.md", true, false},
+		{"**/*.md", "docs/api/index.md", true, false},
+		{"a/**", "a/b", true, false},
+		{"a/**", "a/b/c", true, false},
+		{"a/**/c", "a/c", true, false},
+		{"a/**/c", "a/b/x/c", true, false},
+		{"a/**/c", "a/b/x/d", false, false},
+		{"a/**/**/c", "a/b/c", true, false},
+		{"a/**b/c", "a/xb/c", true, false},
+		{"a/**b/c", "a/x/b/c", false, false},
+		{"/*.md", "/README.md", true, false},
+		{"/*.md", "/docs/README.md", false, false},
+		{"/**/*.md", "/docs/README.md", true, false},
+		{"{a,b}", "a", true, false},
+		{"{a,b}", "c", false, false},
+		{"{a,b}", "", false, false},
+		{"{,a}", "", true, false},
+		{"a{,.txt}", "a.txt", true, false},
+		{"*.{md,txt}", "notes.txt", true, false},
+		{"*.{md,txt}", "notes.go", false, false},
+		{"{a/b,c/**}/d", "c/x/y/d", true, false},
+		{"{a,{b,c}}", "c", true, false},
+		{"[{]", "{", true, false},
+		{"a\\{b", "a{b", true, false},
+		{"{a,b", "a", false, true},
+		{"a\\", "a", false, true},
+		{"%_.txt", "%_.txt", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := Match(tt.pattern, tt.name)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrBadPattern)
+				assert.ErrorIs(t, Validate(tt.pattern), ErrBadPattern)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, Validate(tt.pattern))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	got, err := Expand("/src/{a,b{1,2}}/*.{go,md}")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/src/a/*.go", "/src/a/*.md",
+		"/src/b1/*.go", "/src/b1/*.md",
+		"/src/b2/*.go", "/src/b2/*.md",
+	}, got)
+
+	got, err = Expand("[{,}]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"[{,}]"}, got)
+
+	_, err = Expand("{a,b}{c,d}{e,f}{g,h}{i,j}{k,l}{m,n}{o,p}{q,r}")
+	assert.ErrorIs(t, err, ErrBadPattern)
+}
+
+func TestToLike(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    string
+		ok      bool
+	}{
+		{"*.md", "%.md", true},
+		{"a?c", "a_c", true},
+		{"**x", "%x", true},
+		{"100%_done.txt", "100\\%\\_done.txt", true},
+		{"a\\*b", "a*b", true},
+		{"a\\\\b", "a\\\\b", true},
+		{"[ab].md", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.segment, func(t *testing.T) {
+			got, ok := ToLike(tt.segment)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	got, ok := Literal("a\\*b")
+	assert.True(t, ok)
+	assert.Equal(t, "a*b", got)
+
+	_, ok = Literal("a*b")
+	assert.False(t, ok)
+}
@@ -0,0 +1,101 @@
+package grep
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Line types, as in ripgrep's JSON output
+const (
+	LineMatch   = "match"
+	LineContext = "context"
+)
+
+// Submatch is one match within a line. Start and End are byte offsets in the line.
+type Submatch struct {
+	Match string `json:"match"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// Line is a matching line or a context line around one.
+type Line struct {
+	Type       string     `json:"type"`
+	LineNumber int        `json:"line_number"`
+	Text       string     `json:"text"`
+	Submatches []Submatch `json:"submatches,omitempty"`
+}
+
+// Options controls which lines Search returns.
+type Options struct {
+	// Before and After are the numbers of context lines around each match
+	Before int
+	After  int
+	// MaxCount stops after that many matching lines; zero means no limit
+	MaxCount int
+}
+
+// Result holds the lines of one file, in order. Overlapping context is merged,
+// so every line appears at most once.
+type Result struct {
+	Lines   []Line
+	Matches int
+	// Truncated reports that MaxCount stopped the search before the end of the content
+	Truncated bool
+}
+
+// Search returns the lines of content matching re, with their context.
+func Search(re *regexp.Regexp, content string, opts Options) Result {
+	lines := strings.Split(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var (
+		res          Result
+		lastEmitted  = -1
+		pendingAfter int
+	)
+	emit := func(i int, typ string, submatches []Submatch) {
+		res.Lines = append(res.Lines, Line{
+			Type:       typ,
+			LineNumber: i + 1,
+			Text:       strings.TrimSuffix(lines[i], "\r"),
+			Submatches: submatches,
+		})
+		lastEmitted = i
+	}
+
+	for i, line := range lines {
+		full := opts.MaxCount > 0 && res.Matches >= opts.MaxCount
+		if full && pendingAfter == 0 {
+			res.Truncated = true
+			break
+		}
+
+		var locs [][]int
+		if !full {
+			locs = re.FindAllStringIndex(strings.TrimSuffix(line, "\r"), -1)
+		}
+		if len(locs) > 0 {
+			for j := max(i-opts.Before, lastEmitted+1); j < i; j++ {
+				emit(j, LineContext, nil)
+			}
+			submatches := make([]Submatch, 0, len(locs))
+			for _, loc := range locs {
+				submatches = append(submatches, Submatch{Match: line[loc[0]:loc[1]], Start: loc[0], End: loc[1]})
+			}
+			emit(i, LineMatch, submatches)
+			res.Matches++
+			pendingAfter = opts.After
+			continue
+		}
+
+		if pendingAfter > 0 {
+			emit(i, LineContext, nil)
+			pendingAfter--
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,62 @@
+package grep
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lineNumbers(lines []Line) []int {
+	nums := make([]int, 0, len(lines))
+	for _, l := range lines {
+		nums = append(nums, l.LineNumber)
+	}
+	return nums
+}
+
+func TestSearch(t *testing.T) {
+	content := "one\nTODO two\nthree\nfour\nfive TODO\nsix\nseven\n"
+	re := regexp.MustCompile("TODO")
+
+	t.Run("matches only", func(t *testing.T) {
+		res := Search(re, content, Options{})
+		assert.Equal(t, 2, res.Matches)
+		assert.Equal(t, []int{2, 5}, lineNumbers(res.Lines))
+		assert.Equal(t, []Submatch{{Match: "TODO", Start: 5, End: 9}}, res.Lines[1].Submatches)
+		assert.False(t, res.Truncated)
+	})
+
+	t.Run("context is merged", func(t *testing.T) {
+		res := Search(re, content, Options{Before: 2, After: 1})
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, lineNumbers(res.Lines))
+		assert.Equal(t, LineContext, res.Lines[0].Type)
+		assert.Equal(t, LineMatch, res.Lines[1].Type)
+		assert.Equal(t, LineContext, res.Lines[3].Type)
+		assert.Equal(t, LineMatch, res.Lines[4].Type)
+	})
+
+	t.Run("max count keeps trailing context", func(t *testing.T) {
+		res := Search(re, content, Options{After: 1, MaxCount: 1})
+		assert.Equal(t, 1, res.Matches)
+		assert.Equal(t, []int{2, 3}, lineNumbers(res.Lines))
+		assert.True(t, res.Truncated)
+	})
+
+	t.Run("case insensitive and carriage returns", func(t *testing.T) {
+		res := Search(regexp.MustCompile("(?i)todo"), "a todo\r\nb\r\n", Options{})
+		assert.Equal(t, 1, res.Matches)
+		assert.Equal(t, "a todo", res.Lines[0].Text)
+	})
+
+	t.Run("several matches on a line", func(t *testing.T) {
+		res := Search(regexp.MustCompile("a"), "banana", Options{})
+		assert.Len(t, res.Lines[0].Submatches, 3)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		res := Search(re, "nothing here", Options{Before: 3})
+		assert.Empty(t, res.Lines)
+		assert.Equal(t, 0, res.Matches)
+	})
+}
@@ -0,0 +1,315 @@
+// Package textrange selects ranges of lines or CSV rows from a stream
+// while holding at most the selected range in memory.
+package textrange
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Result is the selected range of an input.
+type Result struct {
+	Text      string
+	Offset    int64 // Index of the first line or row returned, 0-based
+	Count     int64 // Lines or rows returned
+	HasMore   bool  // More lines or rows follow the range
+	Total     int64 // Lines or rows in the whole input, set when Complete
+	Complete  bool  // The input was read to its end
+	Truncated bool  // Text was cut at the byte budget
+}
+
+// CountLines returns the number of lines in s; a final line without a newline counts.
+func CountLines(s string) int64 {
+	n := int64(strings.Count(s, "\n"))
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+// budget accumulates text up to maxBytes.
+type budget struct {
+	b   strings.Builder
+	max int
+}
+
+// write appends p, returning false if it had to be cut
+func (w *budget) write(p []byte) bool {
+	if room := w.max - w.b.Len(); len(p) > room {
+		for room > 0 && !utf8.RuneStart(p[room]) {
+			room--
+		}
+		w.b.Write(p[:room])
+		return false
+	}
+	w.b.Write(p)
+	return true
+}
+
+// Lines returns lines [offset, offset+limit) of r, stopping as soon as the range is read.
+func Lines(r io.Reader, offset int64, limit int64, maxBytes int) (*Result, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	out := &budget{max: maxBytes}
+	res := &Result{Offset: offset}
+
+	var line int64
+	open := false // Bytes of the current line were read
+	for {
+		frag, err := br.ReadSlice('\n')
+		if len(frag) > 0 {
+			if line >= offset+limit {
+				res.HasMore = true
+				break
+			}
+			if line >= offset {
+				if !open {
+					res.Count++
+				}
+				if !out.write(frag) {
+					res.Truncated, res.HasMore = true, true
+					break
+				}
+			}
+			open = frag[len(frag)-1] != '\n'
+			if !open {
+				line++
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			if open {
+				line++
+			}
+			res.Total, res.Complete = line, true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res.Text = out.b.String()
+	return res, nil
+}
+
+// TailLines returns the last n lines of r, reading it to the end.
+func TailLines(r io.Reader, n int64, maxBytes int) (*Result, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	ring := &tail{n: n, maxBytes: maxBytes}
+
+	var total int64
+	var cur []byte
+	for {
+		frag, err := br.ReadSlice('\n')
+		if len(cur)+len(frag) <= maxBytes {
+			cur = append(cur, frag...)
+		} else {
+			ring.truncated = true
+		}
+		if len(frag) > 0 && frag[len(frag)-1] == '\n' {
+			ring.push(string(cur))
+			cur = cur[:0]
+			total++
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			if len(cur) > 0 {
+				ring.push(string(cur))
+				total++
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ring.result(total), nil
+}
+
+// LastLines returns the last n lines of data, the end of an input starting at byte start,
+// dropping leading lines beyond maxBytes. When start > 0 the first line of data may be partial
+// and is dropped; ok is false if that leaves fewer than n lines, so more of the input is needed.
+// The caller sets Offset and Total, which a suffix can't tell.
+func LastLines(data []byte, start int64, n int64, maxBytes int) (res *Result, ok bool) {
+	if start > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			data = nil
+		} else {
+			data = data[i+1:]
+		}
+	}
+
+	end := len(data)
+	pos := end
+	if pos > 0 && data[pos-1] == '\n' {
+		pos--
+	}
+	var count int64
+	for count < n && pos > 0 {
+		i := bytes.LastIndexByte(data[:pos], '\n')
+		if end-(i+1) > maxBytes {
+			break
+		}
+		count++
+		if i < 0 {
+			pos = 0
+			break
+		}
+		pos = i
+	}
+	if pos > 0 {
+		pos++ // Past the newline ending the previous line
+	}
+
+	truncated := count < n && pos > 0
+	if count == 0 && end > maxBytes {
+		// A single line longer than the budget keeps its end
+		pos = end - maxBytes
+		for pos < end && !utf8.RuneStart(data[pos]) {
+			pos++
+		}
+		count, truncated = 1, true
+	}
+	res = &Result{Text: string(data[pos:end]), Count: count, Truncated: truncated}
+	return res, count == n || start == 0 || truncated
+}
+
+// Rows returns the header and rows [offset, offset+limit) of CSV data, re-encoded as CSV.
+// Offset counts data rows, so row 0 is the first row after the header.
+func Rows(r io.Reader, offset int64, limit int64, maxBytes int) (*Result, error) {
+	cr := newCSVReader(r)
+	out := &budget{max: maxBytes}
+	res := &Result{Offset: offset}
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		res.Complete = true
+		return res, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.write(encodeRow(header))
+
+	var row int64
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			res.Total, res.Complete = row, true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row >= offset+limit {
+			res.HasMore = true
+			break
+		}
+		if row >= offset {
+			res.Count++
+			if !out.write(encodeRow(record)) {
+				res.Truncated, res.HasMore = true, true
+				break
+			}
+		}
+		row++
+	}
+
+	res.Text = out.b.String()
+	return res, nil
+}
+
+// TailRows returns the header and the last n rows of CSV data, reading it to the end.
+func TailRows(r io.Reader, n int64, maxBytes int) (*Result, error) {
+	cr := newCSVReader(r)
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return &Result{Complete: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	head := encodeRow(header)
+	ring := &tail{n: n, maxBytes: maxBytes - len(head)}
+
+	var total int64
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ring.push(string(encodeRow(record)))
+		total++
+	}
+
+	res := ring.result(total)
+	res.Text = string(head) + res.Text
+	return res, nil
+}
+
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	return cr
+}
+
+func encodeRow(record []string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(record)
+	w.Flush()
+	return buf.Bytes()
+}
+
+// tail keeps the last n items pushed, dropping the oldest beyond maxBytes.
+type tail struct {
+	n         int64
+	maxBytes  int
+	items     []string
+	size      int
+	truncated bool
+}
+
+func (t *tail) push(s string) {
+	if t.n <= 0 {
+		return
+	}
+	t.items = append(t.items, s)
+	t.size += len(s)
+	for int64(len(t.items)) > t.n || t.size > t.maxBytes && len(t.items) > 0 {
+		if int64(len(t.items)) <= t.n {
+			t.truncated = true
+		}
+		t.size -= len(t.items[0])
+		t.items = t.items[1:]
+	}
+}
+
+func (t *tail) result(total int64) *Result {
+	count := int64(len(t.items))
+	return &Result{
+		Text:      strings.Join(t.items, ""),
+		Offset:    total - count,
+		Count:     count,
+		Total:     total,
+		Complete:  true,
+		Truncated: t.truncated,
+	}
+}
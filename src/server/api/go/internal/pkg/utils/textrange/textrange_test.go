@@ -0,0 +1,149 @@
+package textrange
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountLines(t *testing.T) {
+	assert.Equal(t, int64(0), CountLines(""))
+	assert.Equal(t, int64(1), CountLines("a"))
+	assert.Equal(t, int64(1), CountLines("a\n"))
+	assert.Equal(t, int64(3), CountLines("a\n\nb"))
+}
+
+func TestLines(t *testing.T) {
+	input := "l0\nl1\nl2\nl3\nl4"
+
+	tests := []struct {
+		name          string
+		offset, limit int64
+		maxBytes      int
+		want          Result
+	}{
+		{
+			name: "head", offset: 0, limit: 2, maxBytes: 100,
+			want: Result{Text: "l0\nl1\n", Offset: 0, Count: 2, HasMore: true},
+		},
+		{
+			name: "middle", offset: 2, limit: 2, maxBytes: 100,
+			want: Result{Text: "l2\nl3\n", Offset: 2, Count: 2, HasMore: true},
+		},
+		{
+			name: "up to the unterminated last line", offset: 3, limit: 5, maxBytes: 100,
+			want: Result{Text: "l3\nl4", Offset: 3, Count: 2, Total: 5, Complete: true},
+		},
+		{
+			name: "exactly to the end", offset: 3, limit: 2, maxBytes: 100,
+			want: Result{Text: "l3\nl4", Offset: 3, Count: 2, Total: 5, Complete: true},
+		},
+		{
+			name: "past the end", offset: 9, limit: 2, maxBytes: 100,
+			want: Result{Offset: 9, Total: 5, Complete: true},
+		},
+		{
+			name: "byte budget", offset: 0, limit: 5, maxBytes: 4,
+			want: Result{Text: "l0\nl", Offset: 0, Count: 2, HasMore: true, Truncated: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lines(strings.NewReader(input), tt.offset, tt.limit, tt.maxBytes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func TestLines_LongLines(t *testing.T) {
+	long := strings.Repeat("x", 100<<10)
+	input := long + "\nshort\n" + long
+
+	got, err := Lines(strings.NewReader(input), 1, 1, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, "short\n", got.Text)
+	assert.True(t, got.HasMore)
+
+	got, err = Lines(strings.NewReader(input), 0, 10, 1<<20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), got.Count)
+	assert.Equal(t, int64(3), got.Total)
+}
+
+func TestTailLines(t *testing.T) {
+	got, err := TailLines(strings.NewReader("l0\nl1\nl2\nl3\n"), 2, 100)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Text: "l2\nl3\n", Offset: 2, Count: 2, Total: 4, Complete: true}, *got)
+
+	got, err = TailLines(strings.NewReader("l0\nl1"), 5, 100)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Text: "l0\nl1", Offset: 0, Count: 2, Total: 2, Complete: true}, *got)
+
+	// The budget drops the oldest lines
+	got, err = TailLines(strings.NewReader("l0\nl1\nl2\n"), 3, 7)
+	require.NoError(t, err)
+	assert.Equal(t, "l1\nl2\n", got.Text)
+	assert.Equal(t, int64(1), got.Offset)
+	assert.True(t, got.Truncated)
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		start     int64
+		n         int64
+		maxBytes  int
+		text      string
+		count     int64
+		ok        bool
+		truncated bool
+	}{
+		{name: "unterminated last line", data: "a\nb\nc", n: 2, maxBytes: 100, text: "b\nc", count: 2, ok: true},
+		{name: "terminated last line", data: "a\nb\n", n: 1, maxBytes: 100, text: "b\n", count: 1, ok: true},
+		{name: "fewer lines than asked", data: "a\nb\n", n: 5, maxBytes: 100, text: "a\nb\n", count: 2, ok: true},
+		{name: "suffix needs more data", data: "tial\nb\nc\n", start: 10, n: 3, maxBytes: 100, text: "b\nc\n", count: 2},
+		{name: "suffix has enough lines", data: "tial\nb\nc\n", start: 10, n: 2, maxBytes: 100, text: "b\nc\n", count: 2, ok: true},
+		{name: "byte budget", data: "aa\nbb\ncc\n", n: 3, maxBytes: 6, text: "bb\ncc\n", count: 2, ok: true, truncated: true},
+		{name: "line longer than the budget", data: "a\nbbbbbb", n: 2, maxBytes: 3, text: "bbb", count: 1, ok: true, truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LastLines([]byte(tt.data), tt.start, tt.n, tt.maxBytes)
+			assert.Equal(t, tt.text, got.Text)
+			assert.Equal(t, tt.count, got.Count)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.truncated, got.Truncated)
+		})
+	}
+}
+
+func TestRows(t *testing.T) {
+	input := "name,note\nann,\"multi\nline\"\nbob,x\ncid,y\n"
+
+	got, err := Rows(strings.NewReader(input), 0, 1, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Text: "name,note\nann,\"multi\nline\"\n", Offset: 0, Count: 1, HasMore: true}, *got)
+
+	got, err = Rows(strings.NewReader(input), 1, 5, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Text: "name,note\nbob,x\ncid,y\n", Offset: 1, Count: 2, Total: 3, Complete: true}, *got)
+
+	got, err = Rows(strings.NewReader(""), 0, 5, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, "", got.Text)
+	assert.True(t, got.Complete)
+}
+
+func TestTailRows(t *testing.T) {
+	input := "name,note\nann,a\nbob,b\ncid,c\n"
+
+	got, err := TailRows(strings.NewReader(input), 2, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Text: "name,note\nbob,b\ncid,c\n", Offset: 1, Count: 2, Total: 3, Complete: true}, *got)
+}
@@ -54,6 +54,9 @@ func NewRouter(d RouterDeps) *gin.Engine {
 	})
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// share links authenticate with their own token instead of the project bearer token
+	r.GET("/api/v1/share/:token", d.ArtifactHandler.GetShared)
+
 	v1 := r.Group("/api/v1")
 	{
 		v1.Use(middleware.ProjectAuth(d.Config, d.DB))
@@ -72,6 +75,7 @@ func NewRouter(d RouterDeps) *gin.Engine {
 			session.GET("/:session_id/configs", d.SessionHandler.GetConfigs)
 
 			session.POST("/:session_id/messages", d.SessionHandler.StoreMessage)
+			session.POST("/:session_id/messages/stream", d.SessionHandler.StreamMessage)
 			session.GET("/:session_id/messages", d.SessionHandler.GetMessages)
 			session.PATCH("/:session_id/messages/:message_id/meta", d.SessionHandler.PatchMessageMeta)
 
@@ -91,18 +95,44 @@ func NewRouter(d RouterDeps) *gin.Engine {
 		{
 			disk.GET("", d.DiskHandler.ListDisks)
 			disk.POST("", d.DiskHandler.CreateDisk)
+			disk.PATCH("/:disk_id", d.DiskHandler.UpdateDisk)
 			disk.DELETE("/:disk_id", d.DiskHandler.DeleteDisk)
+			disk.POST("/:disk_id/clone", d.DiskHandler.CloneDisk)
+			disk.GET("/:disk_id/usage", d.DiskHandler.GetDiskUsage)
+			disk.GET("/:disk_id/changes", d.ArtifactHandler.ListDiskChanges)
+			disk.POST("/:disk_id/snapshot", d.ArtifactHandler.CreateSnapshot)
+			disk.GET("/:disk_id/snapshot", d.ArtifactHandler.ListSnapshots)
+			disk.DELETE("/:disk_id/snapshot/:snapshot_id", d.ArtifactHandler.DeleteSnapshot)
+			disk.POST("/:disk_id/restore", d.ArtifactHandler.RestoreSnapshot)
+			disk.POST("/:disk_id/share", d.ArtifactHandler.CreateShare)
+			disk.GET("/:disk_id/share", d.ArtifactHandler.ListShares)
+			disk.DELETE("/:disk_id/share/:share_id", d.ArtifactHandler.DeleteShare)
 
 			artifact := disk.Group("/:disk_id/artifact")
 			{
 				artifact.POST("", d.ArtifactHandler.UpsertArtifact)
+				artifact.POST("/upload_url", d.ArtifactHandler.CreateUploadURL)
+				artifact.POST("/upload_complete", d.ArtifactHandler.CompleteUpload)
+				artifact.POST("/archive", d.ArtifactHandler.UploadArchive)
+				artifact.GET("/archive", d.ArtifactHandler.DownloadArchive)
 				artifact.GET("", d.ArtifactHandler.GetArtifact)
 				artifact.PUT("", d.ArtifactHandler.UpdateArtifact)
 				artifact.DELETE("", d.ArtifactHandler.DeleteArtifact)
 				artifact.GET("/ls", d.ArtifactHandler.ListArtifacts)
+				artifact.GET("/tree", d.ArtifactHandler.GetArtifactTree)
+				artifact.GET("/query", d.ArtifactHandler.QueryArtifacts)
+				artifact.POST("/mkdir", d.ArtifactHandler.CreateDirectory)
 
 				artifact.GET("/grep", d.ArtifactHandler.GrepArtifacts)
 				artifact.GET("/glob", d.ArtifactHandler.GlobArtifacts)
+				artifact.GET("/search", d.ArtifactHandler.SemanticSearchArtifacts)
+				artifact.GET("/versions", d.ArtifactHandler.ListArtifactVersions)
+				artifact.GET("/version", d.ArtifactHandler.GetArtifactVersion)
+				artifact.GET("/diff", d.ArtifactHandler.DiffArtifactVersions)
+				artifact.POST("/restore", d.ArtifactHandler.RestoreArtifactVersion)
+				artifact.POST("/edit", d.ArtifactHandler.EditArtifact)
+				artifact.POST("/move", d.ArtifactHandler.MoveArtifacts)
+				artifact.POST("/copy", d.ArtifactHandler.CopyArtifacts)
 				artifact.POST("/download_to_sandbox", d.ArtifactHandler.DownloadToSandbox)
 				artifact.POST("/upload_from_sandbox", d.ArtifactHandler.UploadFromSandbox)
 			}
@@ -118,6 +148,12 @@ func NewRouter(d RouterDeps) *gin.Engine {
 			agentSkills.POST("/:id/download_to_sandbox", d.AgentSkillsHandler.DownloadToSandbox)
 		}
 
+		project := v1.Group("/project")
+		{
+			project.GET("/usage", d.DiskHandler.GetProjectUsage)
+			project.POST("/encryption/migrate", d.ArtifactHandler.EncryptProjectObjects)
+		}
+
 		user := v1.Group("/user")
 		{
 			user.GET("/ls", d.UserHandler.ListUsers)
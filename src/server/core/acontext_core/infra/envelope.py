@@ -0,0 +1,106 @@
+"""
+Decryption of objects the API encrypts client-side (see the Go package envelope).
+
+A ciphertext is a header (magic and base nonce) followed by chunks of CHUNK_SIZE
+plaintext bytes, the last one shorter and possibly empty, each sealed with AES-256-GCM
+under its own nonce and a flag marking the last chunk. Data keys are stored wrapped by
+a key-encryption key derived from the master key and the project ID.
+"""
+
+import hashlib
+import hmac
+
+KEY_SIZE = 32
+CHUNK_SIZE = 64 << 10
+NONCE_SIZE = 12
+TAG_SIZE = 16
+MAGIC = b"ACE1"
+HEADER_SIZE = len(MAGIC) + NONCE_SIZE
+SEALED_SIZE = CHUNK_SIZE + TAG_SIZE
+
+# Object metadata marking objects encrypted client-side, with the format version
+META_ENCRYPTION = "acontext-encryption"
+ENCRYPTION_V1 = "v1"
+
+# Prefixes of the objects encrypted client-side: "<prefix>/<project ID>/..."
+ENCRYPTED_PREFIXES = ("assets", "disks", "parts")
+
+
+class InvalidCiphertext(ValueError):
+    pass
+
+
+def _aesgcm(key: bytes):
+    try:
+        from cryptography.hazmat.primitives.ciphers.aead import AESGCM
+    except ImportError as e:
+        raise RuntimeError(
+            "reading objects encrypted client-side requires the cryptography package"
+        ) from e
+    if len(key) != KEY_SIZE:
+        raise ValueError(f"key must be {KEY_SIZE} bytes")
+    return AESGCM(key)
+
+
+def derive_kek(master: bytes, project_id: str) -> bytes:
+    """HKDF-SHA256 of the master key, salted with the project ID."""
+    prk = hmac.new(project_id.encode(), master, hashlib.sha256).digest()
+    return hmac.new(prk, b"acontext data key wrap\x01", hashlib.sha256).digest()
+
+
+def unwrap(kek: bytes, wrapped: bytes, project_id: str) -> bytes:
+    """Decrypt a data key of a project wrapped by kek."""
+    if len(wrapped) < NONCE_SIZE:
+        raise InvalidCiphertext("wrapped data key is too short")
+    aead = _aesgcm(kek)
+    try:
+        return aead.decrypt(
+            wrapped[:NONCE_SIZE], wrapped[NONCE_SIZE:], project_id.encode()
+        )
+    except Exception as e:
+        raise InvalidCiphertext("unwrap data key") from e
+
+
+def _chunk_nonce(base: bytes, index: int) -> bytes:
+    idx = index.to_bytes(8, "big")
+    return base[: NONCE_SIZE - 8] + bytes(
+        b ^ i for b, i in zip(base[NONCE_SIZE - 8 :], idx)
+    )
+
+
+def decrypt(key: bytes, ciphertext: bytes) -> bytes:
+    """Decrypt a whole ciphertext."""
+    if len(ciphertext) < HEADER_SIZE or ciphertext[: len(MAGIC)] != MAGIC:
+        raise InvalidCiphertext("bad header")
+    aead = _aesgcm(key)
+    base = ciphertext[len(MAGIC) : HEADER_SIZE]
+    body = memoryview(ciphertext)[HEADER_SIZE:]
+    out = bytearray()
+    index, offset = 0, 0
+    while True:
+        sealed = body[offset : offset + SEALED_SIZE]
+        last = offset + SEALED_SIZE >= len(body)
+        if len(sealed) < TAG_SIZE:
+            raise InvalidCiphertext(f"truncated chunk {index}")
+        try:
+            out += aead.decrypt(
+                _chunk_nonce(base, index), bytes(sealed), b"\x01" if last else b"\x00"
+            )
+        except Exception as e:
+            raise InvalidCiphertext(f"open chunk {index}") from e
+        if last:
+            return bytes(out)
+        index += 1
+        offset += SEALED_SIZE
+
+
+def project_of_key(key: str) -> str | None:
+    """The project owning an object of context data, "<prefix>/<project ID>/..."."""
+    parts = key.split("/", 2)
+    if len(parts) < 3 or parts[0] not in ENCRYPTED_PREFIXES:
+        return None
+    return parts[1]
+
+
+def data_key_key(project_id: str) -> str:
+    return f"keys/{project_id}/data-key.json"
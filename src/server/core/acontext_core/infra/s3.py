@@ -1,4 +1,6 @@
 import asyncio
+import base64
+import json
 from typing import Optional, Dict, Any, AsyncGenerator
 from contextlib import asynccontextmanager
 
@@ -11,6 +13,7 @@ from botocore.exceptions import ClientError, NoCredentialsError
 
 from ..env import LOG as logger
 from ..env import DEFAULT_CORE_CONFIG
+from . import envelope
 
 
 def _handle_s3_client_error(
@@ -85,6 +88,14 @@ class S3Client:
         self.read_timeout = s3_config.get(
             "read_timeout", DEFAULT_CORE_CONFIG.s3_read_timeout
         )
+        # Master key of the objects the API encrypts client-side
+        encryption_master_key = s3_config.get(
+            "encryption_master_key", DEFAULT_CORE_CONFIG.s3_encryption_master_key
+        )
+        self.encryption_master_key = (
+            base64.b64decode(encryption_master_key) if encryption_master_key else None
+        )
+        self._data_keys: Dict[str, bytes] = {}
 
         if not self.bucket:
             raise ValueError("S3 bucket name is required")
@@ -194,7 +205,7 @@ class S3Client:
             bucket: Optional bucket name (uses default if not specified)
 
         Returns:
-            bytes: The object content
+            bytes: The object content, decrypted if the API encrypted it client-side
 
         Raises:
             ClientError: If the object doesn't exist or other S3 errors
@@ -209,13 +220,57 @@ class S3Client:
                 logger.debug(
                     f"Downloaded object - bucket: {bucket_name}, key: {key}, size: {len(content)} bytes"
                 )
-                return content
+            metadata = response.get("Metadata") or {}
+            if metadata.get(envelope.META_ENCRYPTION) == envelope.ENCRYPTION_V1:
+                data_key = await self._data_key(key, bucket_name)
+                content = envelope.decrypt(data_key, content)
+            return content
 
         except ClientError as e:
             _handle_s3_client_error(e, bucket_name, key)
         except Exception as e:
             _handle_unexpected_error(e, bucket_name, key)
 
+    async def _data_key(self, key: str, bucket_name: str) -> bytes:
+        """
+        Get the data key an object encrypted client-side by the API was encrypted with.
+
+        Args:
+            key: The S3 object key
+            bucket_name: S3 bucket name
+
+        Returns:
+            bytes: The unwrapped data key of the project owning the object
+        """
+        project_id = envelope.project_of_key(key)
+        if project_id is None:
+            raise ValueError(
+                f"object {key} is encrypted client-side outside of a project prefix"
+            )
+        if self.encryption_master_key is None:
+            raise ValueError(
+                f"object {key} is encrypted client-side, but no s3_encryption_master_key is configured"
+            )
+        data_key = self._data_keys.get(project_id)
+        if data_key is not None:
+            return data_key
+
+        async with self.get_client() as client:
+            response = await client.get_object(
+                Bucket=bucket_name, Key=envelope.data_key_key(project_id)
+            )
+            stored = json.loads(await response["Body"].read())
+        if stored.get("source") != "master":
+            raise ValueError(
+                f"data key of project {project_id} is wrapped by the {stored.get('source')} key source, not master"
+            )
+        kek = envelope.derive_kek(self.encryption_master_key, project_id)
+        data_key = envelope.unwrap(
+            kek, base64.b64decode(stored["wrapped"]), project_id
+        )
+        self._data_keys[project_id] = data_key
+        return data_key
+
     async def upload_object(
         self,
         key: str,
@@ -66,6 +66,8 @@ class CoreConfig(BaseModel):
     s3_max_pool_connections: int = 32
     s3_connection_timeout: float = 60.0
     s3_read_timeout: float = 60.0
+    # Master key of the client-side encryption of the API (base64), to read encrypted parts and artifacts
+    s3_encryption_master_key: Optional[str] = None
 
     # otel
     otel_exporter_otlp_endpoint: str = "http://localhost:4317"
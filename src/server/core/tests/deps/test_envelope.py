@@ -0,0 +1,66 @@
+import base64
+import os
+
+import pytest
+from cryptography.hazmat.primitives.ciphers.aead import AESGCM
+
+from acontext_core.infra import envelope
+
+# Written by the Go envelope package of the API
+MASTER = base64.b64decode("AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+PROJECT_ID = "0b8f3c9e-6f7a-4d2b-9a51-3f3f1d2e4c5a"
+WRAPPED = base64.b64decode(
+    "keDig80KxpI/8f34kXkcrB3LspbGMkKR6vsb7DgQP6+r0t3rHAL0on2IhfVKAquynoHOJXuYEcZbFGfC"
+)
+CIPHERTEXT = base64.b64decode(
+    "QUNFMf91qmTZgVizGwXrgw2sLez1IGVpL3KjiyFNAf0QXhtdiyig1pSMylE07I2SiEK6hLUcPoc2cJqnDvaXkQ=="
+)
+DATA_KEY = bytes(range(100, 132))
+
+
+def encrypt(key: bytes, plaintext: bytes) -> bytes:
+    base = os.urandom(envelope.NONCE_SIZE)
+    chunks = [
+        plaintext[i : i + envelope.CHUNK_SIZE]
+        for i in range(0, len(plaintext), envelope.CHUNK_SIZE)
+    ] or [b""]
+    out = envelope.MAGIC + base
+    for i, chunk in enumerate(chunks):
+        last = b"\x01" if i == len(chunks) - 1 else b"\x00"
+        out += AESGCM(key).encrypt(envelope._chunk_nonce(base, i), chunk, last)
+    return out
+
+
+def test_decrypt_go_ciphertext():
+    kek = envelope.derive_kek(MASTER, PROJECT_ID)
+    assert (
+        kek.hex() == "1fcaf154588f8b5defeda1e1f4154a3d26ea1d08ad432715e4a9aa33900d3b9e"
+    )
+    data_key = envelope.unwrap(kek, WRAPPED, PROJECT_ID)
+    assert data_key == DATA_KEY
+    assert envelope.decrypt(data_key, CIPHERTEXT) == b'[{"type":"text","text":"hello"}]'
+
+    with pytest.raises(envelope.InvalidCiphertext):
+        envelope.unwrap(kek, WRAPPED, "another-project")
+
+
+@pytest.mark.parametrize(
+    "size", [0, 1, envelope.CHUNK_SIZE, envelope.CHUNK_SIZE + 1, 3 * envelope.CHUNK_SIZE]
+)
+def test_decrypt_chunks(size):
+    plaintext = os.urandom(size)
+    ciphertext = encrypt(DATA_KEY, plaintext)
+    assert envelope.decrypt(DATA_KEY, ciphertext) == plaintext
+
+    if size > envelope.CHUNK_SIZE:
+        # Dropping the last chunk leaves a chunk not marked last
+        with pytest.raises(envelope.InvalidCiphertext):
+            envelope.decrypt(
+                DATA_KEY, ciphertext[: envelope.HEADER_SIZE + envelope.SEALED_SIZE]
+            )
+
+
+def test_project_of_key():
+    assert envelope.project_of_key(f"parts/{PROJECT_ID}/2025/01/01/a.json") == PROJECT_ID
+    assert envelope.project_of_key(f"sandbox_tmp/{PROJECT_ID}/a.txt") is None
+    assert envelope.project_of_key("parts/a.json") is None